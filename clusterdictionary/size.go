@@ -5,6 +5,8 @@
 package clusterdictionary
 
 import (
+	"sort"
+
 	"github.com/mattermost/mattermost-cloud/model"
 	"github.com/pkg/errors"
 )
@@ -22,16 +24,9 @@ const (
 	SizeAlef10000 = "SizeAlef10000"
 )
 
-type size struct {
-	MasterInstanceType string
-	MasterCount        int64
-	NodeInstanceType   string
-	NodeMinCount       int64
-	NodeMaxCount       int64
-}
-
 // ValidSizes is a mapping of a size keyword to kops cluster configuration.
-var ValidSizes = map[string]size{
+// Besides the built-in sizes, it contains the sizes registered with AddSizes.
+var ValidSizes = map[string]model.ClusterSize{
 	SizeAlefDev:   sizeAlefDev,
 	SizeAlef500:   sizeAlef500,
 	SizeAlef1000:  sizeAlef1000,
@@ -40,7 +35,8 @@ var ValidSizes = map[string]size{
 }
 
 // sizeAlefDev is a cluster sized for development and testing.
-var sizeAlefDev = size{
+var sizeAlefDev = model.ClusterSize{
+	Name:               SizeAlefDev,
 	MasterInstanceType: "t3.medium",
 	MasterCount:        1,
 	NodeInstanceType:   "t3.medium",
@@ -49,7 +45,8 @@ var sizeAlefDev = size{
 }
 
 // sizeAlef500 is a cluster sized for 500 users.
-var sizeAlef500 = size{
+var sizeAlef500 = model.ClusterSize{
+	Name:               SizeAlef500,
 	MasterInstanceType: "t3.medium",
 	MasterCount:        1,
 	NodeInstanceType:   "m5.large",
@@ -58,7 +55,8 @@ var sizeAlef500 = size{
 }
 
 // sizeAlef1000 is a cluster sized for 1000 users.
-var sizeAlef1000 = size{
+var sizeAlef1000 = model.ClusterSize{
+	Name:               SizeAlef1000,
 	MasterInstanceType: "t3.large",
 	MasterCount:        1,
 	NodeInstanceType:   "m5.large",
@@ -67,7 +65,8 @@ var sizeAlef1000 = size{
 }
 
 // sizeAlef5000 is a cluster sized for 5000 users.
-var sizeAlef5000 = size{
+var sizeAlef5000 = model.ClusterSize{
+	Name:               SizeAlef5000,
 	MasterInstanceType: "t3.large",
 	MasterCount:        1,
 	NodeInstanceType:   "m5.large",
@@ -76,7 +75,8 @@ var sizeAlef5000 = size{
 }
 
 // sizeAlef10000 is a cluster sized for 10000 users.
-var sizeAlef10000 = size{
+var sizeAlef10000 = model.ClusterSize{
+	Name:               SizeAlef10000,
 	MasterInstanceType: "t3.large",
 	MasterCount:        3,
 	NodeInstanceType:   "m5.large",
@@ -84,6 +84,43 @@ var sizeAlef10000 = size{
 	NodeMaxCount:       10,
 }
 
+// AddSizes registers the given cluster sizes. Sizes with the name of an
+// already registered size replace it. This is not safe for concurrent use and
+// is expected to be called on startup before any size is used.
+func AddSizes(sizes []*model.ClusterSize) {
+	for _, size := range sizes {
+		ValidSizes[size.Name] = *size
+	}
+}
+
+// GetSize returns a copy of the cluster size with the given name or nil if
+// the size is not registered.
+func GetSize(name string) *model.ClusterSize {
+	size, ok := ValidSizes[name]
+	if !ok {
+		return nil
+	}
+	size.AdditionalNodeGroups = size.AdditionalNodeGroups.Copy()
+
+	return &size
+}
+
+// Sizes returns all registered cluster sizes sorted by name.
+func Sizes() []*model.ClusterSize {
+	names := make([]string, 0, len(ValidSizes))
+	for name := range ValidSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sizes := make([]*model.ClusterSize, 0, len(names))
+	for _, name := range names {
+		sizes = append(sizes, GetSize(name))
+	}
+
+	return sizes
+}
+
 // IsValidClusterSize returns true if the given size string is supported.
 func IsValidClusterSize(size string) bool {
 	_, ok := ValidSizes[size]
@@ -101,12 +138,13 @@ func ApplyToCreateClusterRequest(size string, request *model.CreateClusterReques
 		return errors.Errorf("%s is not a valid size", size)
 	}
 
-	values := ValidSizes[size]
+	values := GetSize(size)
 	request.MasterInstanceType = values.MasterInstanceType
 	request.MasterCount = values.MasterCount
 	request.NodeInstanceType = values.NodeInstanceType
 	request.NodeMinCount = values.NodeMinCount
 	request.NodeMaxCount = values.NodeMaxCount
+	request.AdditionalNodeGroups = values.AdditionalNodeGroups
 
 	return nil
 }
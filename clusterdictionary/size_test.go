@@ -188,3 +188,37 @@ func stringToPointer(s string) *string {
 func int64ToPointer(i int64) *int64 {
 	return &i
 }
+
+func TestAddSizes(t *testing.T) {
+	custom := &model.ClusterSize{
+		Name:               "custom",
+		MasterInstanceType: "t3.large",
+		MasterCount:        1,
+		NodeInstanceType:   "m5.xlarge",
+		NodeMinCount:       3,
+		NodeMaxCount:       3,
+		AdditionalNodeGroups: model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 2, NodeMaxCount: 2},
+		},
+	}
+	AddSizes([]*model.ClusterSize{custom})
+	defer delete(ValidSizes, custom.Name)
+
+	assert.True(t, IsValidClusterSize(custom.Name))
+	assert.Len(t, Sizes(), 6)
+
+	size := GetSize(custom.Name)
+	assert.Equal(t, custom, size)
+
+	// Modifying the returned size must not modify the registered size.
+	size.AdditionalNodeGroups["compute"] = model.KopsInstanceGroupMetadata{}
+	assert.Len(t, GetSize(custom.Name).AdditionalNodeGroups, 1)
+
+	assert.Nil(t, GetSize("unknown"))
+
+	request := &model.CreateClusterRequest{}
+	err := ApplyToCreateClusterRequest(custom.Name, request)
+	assert.NoError(t, err)
+	assert.Equal(t, "m5.xlarge", request.NodeInstanceType)
+	assert.Equal(t, custom.AdditionalNodeGroups, request.AdditionalNodeGroups)
+}
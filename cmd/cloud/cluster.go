@@ -22,9 +22,12 @@ func init() {
 	clusterCmd.PersistentFlags().Bool("dry-run", false, "When set to true, only print the API request without sending it.")
 
 	clusterCreateCmd.Flags().String("provider", "aws", "Cloud provider hosting the cluster.")
+	clusterCreateCmd.Flags().String("provisioner", "kops", "The provisioner managing the cluster: kops or eks. Installations are not yet supported on eks clusters.")
+	clusterCreateCmd.Flags().String("eks-cluster-role-arn", "", "The IAM role ARN of the EKS control plane. Required for eks clusters.")
+	clusterCreateCmd.Flags().String("eks-node-role-arn", "", "The IAM role ARN of the EKS worker nodes. Required for eks clusters.")
 	clusterCreateCmd.Flags().String("version", "latest", "The Kubernetes version to target. Use 'latest' or versions such as '1.16.10'.")
 	clusterCreateCmd.Flags().String("kops-ami", "", "The AMI to use for the cluster hosts. Leave empty for the default kops image.")
-	clusterCreateCmd.Flags().String("size", "SizeAlef500", "The size constant describing the cluster")
+	clusterCreateCmd.Flags().String("size", "SizeAlef500", "The name of the cluster size describing the cluster. See 'cloud cluster size list' for the available sizes.")
 	clusterCreateCmd.Flags().String("size-master-instance-type", "", "The instance type describing the k8s master nodes. Overwrites value from 'size'.")
 	clusterCreateCmd.Flags().Int64("size-master-count", 0, "The number of k8s master nodes. Overwrites value from 'size'.")
 	clusterCreateCmd.Flags().String("size-node-instance-type", "", "The instance type describing the k8s worker nodes. Overwrites value from 'size'.")
@@ -54,6 +57,7 @@ func init() {
 	clusterCreateCmd.Flags().String("networking", "amazon-vpc-routed-eni", "Networking mode to use, for example: weave, calico, canal, amazon-vpc-routed-eni")
 	clusterCreateCmd.Flags().String("vpc", "", "Set to use a shared VPC")
 	clusterCreateCmd.Flags().String("cluster", "", "The id of the cluster. If provided and the cluster exists the creation will be retried ignoring other parameters.")
+	clusterCreateCmd.Flags().String("template", "", "The name or id of a cluster template to create the cluster from. Only explicitly provided flags override the template values.")
 
 	clusterCreateCmd.Flags().StringArray("annotation", []string{}, "Additional annotations for the cluster. Accepts multiple values, for example: '... --annotation abc --annotation def'")
 
@@ -100,7 +104,8 @@ func init() {
 	clusterUpgradeCmd.MarkFlagRequired("cluster")
 
 	clusterResizeCmd.Flags().String("cluster", "", "The id of the cluster to be resized.")
-	clusterResizeCmd.Flags().String("size", "", "The size constant describing the cluster")
+	clusterResizeCmd.Flags().String("size", "", "The name of the cluster size describing the cluster. See 'cloud cluster size list' for the available sizes.")
+	clusterResizeCmd.Flags().String("node-group", "", "The name of the additional worker node group to resize. The default worker nodes are resized if not set.")
 	clusterResizeCmd.Flags().String("size-node-instance-type", "", "The instance type describing the k8s worker nodes. Overwrites value from 'size'.")
 	clusterResizeCmd.Flags().Int64("size-node-min-count", 0, "The minimum number of k8s worker nodes. Overwrites value from 'size'.")
 	clusterResizeCmd.Flags().Int64("size-node-max-count", 0, "The maximum number of k8s worker nodes. Overwrites value from 'size'.")
@@ -118,6 +123,9 @@ func init() {
 	clusterUtilitiesCmd.Flags().String("cluster", "", "The id of the cluster whose utilities are to be fetched.")
 	clusterUtilitiesCmd.MarkFlagRequired("cluster")
 
+	clusterUpgradeReportCmd.Flags().String("cluster", "", "The id of the cluster whose latest upgrade report is to be fetched.")
+	clusterUpgradeReportCmd.MarkFlagRequired("cluster")
+
 	clusterCmd.AddCommand(clusterCreateCmd)
 	clusterCmd.AddCommand(clusterProvisionCmd)
 	clusterCmd.AddCommand(clusterUpdateCmd)
@@ -129,8 +137,12 @@ func init() {
 	clusterCmd.AddCommand(clusterInstallationCmd)
 	clusterCmd.AddCommand(clusterShowStateReport)
 	clusterCmd.AddCommand(clusterUtilitiesCmd)
+	clusterCmd.AddCommand(clusterUtilityCmd)
+	clusterCmd.AddCommand(clusterUpgradeReportCmd)
 	clusterCmd.AddCommand(clusterShowSizeDictionary)
 	clusterCmd.AddCommand(clusterAnnotationCmd)
+	clusterCmd.AddCommand(clusterTemplateCmd)
+	clusterCmd.AddCommand(clusterSizeCmd)
 }
 
 var clusterCmd = &cobra.Command{
@@ -162,7 +174,18 @@ var clusterCreateCmd = &cobra.Command{
 			return nil
 		}
 
+		template, _ := command.Flags().GetString("template")
+		if len(template) != 0 {
+			request := &model.CreateClusterRequest{Template: template}
+			applyChangedClusterFlags(command, request)
+
+			return createCluster(command, client, request)
+		}
+
 		provider, _ := command.Flags().GetString("provider")
+		provisioner, _ := command.Flags().GetString("provisioner")
+		eksClusterRoleARN, _ := command.Flags().GetString("eks-cluster-role-arn")
+		eksNodeRoleARN, _ := command.Flags().GetString("eks-node-role-arn")
 		version, _ := command.Flags().GetString("version")
 		kopsAMI, _ := command.Flags().GetString("kops-ami")
 		zones, _ := command.Flags().GetString("zones")
@@ -173,6 +196,9 @@ var clusterCreateCmd = &cobra.Command{
 
 		request := &model.CreateClusterRequest{
 			Provider:               provider,
+			Provisioner:            provisioner,
+			EKSClusterRoleARN:      eksClusterRoleARN,
+			EKSNodeRoleARN:         eksNodeRoleARN,
 			Version:                version,
 			KopsAMI:                kopsAMI,
 			Zones:                  strings.Split(zones, ","),
@@ -182,12 +208,15 @@ var clusterCreateCmd = &cobra.Command{
 			Networking:             networking,
 			VPC:                    vpc,
 		}
+		if provisioner == model.ProvisionerEKS && !command.Flags().Changed("allow-installations") {
+			// Installations are not yet supported on EKS clusters.
+			request.AllowInstallations = false
+		}
 
+		// The values of the size are applied by the server to the values
+		// which are not overwritten.
 		size, _ := command.Flags().GetString("size")
-		err := clusterdictionary.ApplyToCreateClusterRequest(size, request)
-		if err != nil {
-			return errors.Wrap(err, "failed to apply size values")
-		}
+		request.Size = size
 		masterInstanceType, _ := command.Flags().GetString("size-master-instance-type")
 		if len(masterInstanceType) != 0 {
 			request.MasterInstanceType = masterInstanceType
@@ -208,28 +237,32 @@ var clusterCreateCmd = &cobra.Command{
 			request.NodeMaxCount = nodeCount
 		}
 
-		dryRun, _ := command.Flags().GetBool("dry-run")
-		if dryRun {
-			err = printJSON(request)
-			if err != nil {
-				return errors.Wrap(err, "failed to print API request")
-			}
-
-			return nil
-		}
-
-		cluster, err := client.CreateCluster(request)
-		if err != nil {
-			return errors.Wrap(err, "failed to create cluster")
-		}
+		return createCluster(command, client, request)
+	},
+}
 
-		err = printJSON(cluster)
+func createCluster(command *cobra.Command, client *model.Client, request *model.CreateClusterRequest) error {
+	dryRun, _ := command.Flags().GetBool("dry-run")
+	if dryRun {
+		err := printJSON(request)
 		if err != nil {
-			return errors.Wrap(err, "failed to print cluster response")
+			return errors.Wrap(err, "failed to print API request")
 		}
 
 		return nil
-	},
+	}
+
+	cluster, err := client.CreateCluster(request)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster")
+	}
+
+	err = printJSON(cluster)
+	if err != nil {
+		return errors.Wrap(err, "failed to print cluster response")
+	}
+
+	return nil
 }
 
 var clusterProvisionCmd = &cobra.Command{
@@ -382,13 +415,11 @@ var clusterResizeCmd = &cobra.Command{
 
 		clusterID, _ := command.Flags().GetString("cluster")
 
-		// Apply values from 'size' constant and then apply overrides.
-		request := &model.PatchClusterSizeRequest{}
+		// The values of the size are applied by the server to the values
+		// which are not overwritten.
 		size, _ := command.Flags().GetString("size")
-		err := clusterdictionary.ApplyToPatchClusterSizeRequest(size, request)
-		if err != nil {
-			return errors.Wrap(err, "failed to apply size values")
-		}
+		nodeGroup, _ := command.Flags().GetString("node-group")
+		request := &model.PatchClusterSizeRequest{Size: size, NodeGroup: nodeGroup}
 		nodeInstanceType, _ := command.Flags().GetString("size-node-instance-type")
 		if len(nodeInstanceType) != 0 {
 			request.NodeInstanceType = &nodeInstanceType
@@ -404,7 +435,7 @@ var clusterResizeCmd = &cobra.Command{
 
 		dryRun, _ := command.Flags().GetBool("dry-run")
 		if dryRun {
-			err = printJSON(request)
+			err := printJSON(request)
 			if err != nil {
 				return errors.Wrap(err, "failed to print API request")
 			}
@@ -530,6 +561,20 @@ func defaultClustersTableData(clusters []*model.ClusterDTO) ([]string, [][]strin
 		if cluster.AllowInstallations {
 			status = "online"
 		}
+		if cluster.IsEKS() {
+			values = append(values, []string{
+				cluster.ID,
+				cluster.State,
+				cluster.ProvisionerMetadataEKS.Version,
+				"eks",
+				fmt.Sprintf("%d x %s (max %d)", cluster.ProvisionerMetadataEKS.NodeMinCount, cluster.ProvisionerMetadataEKS.NodeInstanceType, cluster.ProvisionerMetadataEKS.NodeMaxCount),
+				"",
+				"",
+				cluster.ProvisionerMetadataEKS.VPC,
+				status,
+			})
+			continue
+		}
 		values = append(values, []string{
 			cluster.ID,
 			cluster.State,
@@ -572,6 +617,36 @@ var clusterUtilitiesCmd = &cobra.Command{
 	},
 }
 
+var clusterUpgradeReportCmd = &cobra.Command{
+	Use:   "upgrade-report",
+	Short: "Show the report of the latest kubernetes upgrade of a cluster.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+		clusterID, _ := command.Flags().GetString("cluster")
+
+		cluster, err := client.GetCluster(clusterID)
+		if err != nil {
+			return errors.Wrap(err, "failed to query cluster")
+		}
+		if cluster == nil {
+			return errors.Errorf("cluster %s not found", clusterID)
+		}
+		if cluster.UpgradeReport == nil {
+			return errors.Errorf("cluster %s has not been upgraded yet", clusterID)
+		}
+
+		err = printJSON(cluster.UpgradeReport)
+		if err != nil {
+			return errors.Wrap(err, "failed to print cluster upgrade report")
+		}
+
+		return nil
+	},
+}
+
 var clusterShowSizeDictionary = &cobra.Command{
 	Use:   "dictionary",
 	Short: "Shows predefined cluster size templates.",
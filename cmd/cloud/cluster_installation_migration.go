@@ -0,0 +1,204 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	clusterMigrationCreateCmd.Flags().String("source-cluster", "", "The source cluster for the migration to migrate cluster installations from.")
+	clusterMigrationCreateCmd.MarkFlagRequired("source-cluster")
+	clusterMigrationCreateCmd.Flags().String("target-cluster", "", "The target cluster for the migration to migrate cluster installation to.")
+	clusterMigrationCreateCmd.MarkFlagRequired("target-cluster")
+	clusterMigrationCreateCmd.Flags().String("installation", "", "The specific installation ID to migrate from source cluster, default is ALL.")
+	clusterMigrationCreateCmd.Flags().Bool("lock-installation", true, "The installation's lock flag during DNS migration process.")
+	clusterMigrationCreateCmd.Flags().Bool("switch-cluster-roles", false, "Mark the target cluster as primary and the source cluster as secondary once the migration is complete.")
+
+	clusterMigrationListCmd.Flags().String("installation", "", "The id of the installation to query migrations.")
+	clusterMigrationListCmd.Flags().String("source-cluster", "", "The id of the source cluster to query migrations.")
+	clusterMigrationListCmd.Flags().String("target-cluster", "", "The id of the target cluster to query migrations.")
+	clusterMigrationListCmd.Flags().String("state", "", "The state to filter migrations by.")
+	registerTableOutputFlags(clusterMigrationListCmd)
+	registerPagingFlags(clusterMigrationListCmd)
+
+	clusterMigrationGetCmd.Flags().String("migration", "", "The id of the cluster migration operation.")
+	clusterMigrationGetCmd.MarkFlagRequired("migration")
+
+	clusterMigrationRollbackCmd.Flags().String("migration", "", "The id of the cluster migration operation to roll back.")
+	clusterMigrationRollbackCmd.MarkFlagRequired("migration")
+
+	clusterInstallationsMigrationCmd.AddCommand(clusterMigrationCreateCmd)
+	clusterInstallationsMigrationCmd.AddCommand(clusterMigrationListCmd)
+	clusterInstallationsMigrationCmd.AddCommand(clusterMigrationGetCmd)
+	clusterInstallationsMigrationCmd.AddCommand(clusterMigrationRollbackCmd)
+}
+
+var clusterInstallationsMigrationCmd = &cobra.Command{
+	Use:   "migration",
+	Short: "Migrate installation(s) to the target cluster.",
+}
+
+var clusterMigrationCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Request migration of installation(s) from the source cluster to the target cluster.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		sourceCluster, _ := command.Flags().GetString("source-cluster")
+		targetCluster, _ := command.Flags().GetString("target-cluster")
+		installation, _ := command.Flags().GetString("installation")
+		lockInstallation, _ := command.Flags().GetBool("lock-installation")
+		switchClusterRoles, _ := command.Flags().GetBool("switch-cluster-roles")
+
+		operation, err := client.CreateClusterMigration(&model.CreateClusterMigrationRequest{
+			SourceClusterID:    sourceCluster,
+			TargetClusterID:    targetCluster,
+			InstallationID:     installation,
+			LockInstallations:  lockInstallation,
+			SwitchClusterRoles: switchClusterRoles,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to request cluster migration")
+		}
+
+		err = printJSON(operation)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+var clusterMigrationListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cluster migration operations.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		installationID, _ := command.Flags().GetString("installation")
+		sourceClusterID, _ := command.Flags().GetString("source-cluster")
+		targetClusterID, _ := command.Flags().GetString("target-cluster")
+		state, _ := command.Flags().GetString("state")
+		paging := parsePagingFlags(command)
+
+		operations, err := client.GetClusterMigrationOperations(&model.GetClusterMigrationOperationsRequest{
+			Paging:          paging,
+			InstallationID:  installationID,
+			SourceClusterID: sourceClusterID,
+			TargetClusterID: targetClusterID,
+			State:           state,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to list cluster migration operations")
+		}
+
+		if enabled, customCols := tableOutputEnabled(command); enabled {
+			var keys []string
+			var vals [][]string
+
+			if len(customCols) > 0 {
+				data := make([]interface{}, 0, len(operations))
+				for _, elem := range operations {
+					data = append(data, elem)
+				}
+				keys, vals, err = prepareTableData(customCols, data)
+				if err != nil {
+					return errors.Wrap(err, "failed to prepare table output")
+				}
+			} else {
+				keys, vals = defaultClusterMigrationOperationTableData(operations)
+			}
+
+			printTable(keys, vals)
+			return nil
+		}
+
+		err = printJSON(operations)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+func defaultClusterMigrationOperationTableData(ops []*model.ClusterMigrationOperation) ([]string, [][]string) {
+	keys := []string{"ID", "SOURCE CLUSTER", "TARGET CLUSTER", "INSTALLATIONS", "STATE", "REQUEST AT"}
+	vals := make([][]string, 0, len(ops))
+
+	for _, migration := range ops {
+		vals = append(vals, []string{
+			migration.ID,
+			migration.SourceClusterID,
+			migration.TargetClusterID,
+			fmt.Sprintf("%d", len(migration.ClusterInstallations)),
+			string(migration.State),
+			model.TimeFromMillis(migration.RequestAt).Format("2006-01-02 15:04:05 -0700 MST"),
+		})
+	}
+	return keys, vals
+}
+
+var clusterMigrationGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Fetches given cluster migration operation.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		migrationID, _ := command.Flags().GetString("migration")
+
+		operation, err := client.GetClusterMigrationOperation(migrationID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get cluster migration")
+		}
+
+		err = printJSON(operation)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+var clusterMigrationRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Rolls back given cluster migration operation.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		migrationID, _ := command.Flags().GetString("migration")
+
+		operation, err := client.RollbackClusterMigration(migrationID)
+		if err != nil {
+			return errors.Wrap(err, "failed to rollback cluster migration")
+		}
+
+		err = printJSON(operation)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
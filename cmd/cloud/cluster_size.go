@@ -0,0 +1,299 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+func init() {
+	clusterSizeCreateCmd.Flags().String("name", "", "The unique name of the cluster size.")
+	clusterSizeCreateCmd.Flags().String("master-instance-type", "", "The instance type describing the k8s master nodes.")
+	clusterSizeCreateCmd.Flags().Int64("master-count", 1, "The number of k8s master nodes.")
+	clusterSizeCreateCmd.Flags().String("node-instance-type", "", "The instance type describing the k8s worker nodes.")
+	clusterSizeCreateCmd.Flags().Int64("node-min-count", 0, "The minimum number of k8s worker nodes.")
+	clusterSizeCreateCmd.Flags().Int64("node-max-count", 0, "The maximum number of k8s worker nodes. Defaults to the minimum number.")
+	clusterSizeCreateCmd.Flags().StringArray("node-group", []string{}, "Additional worker node group in the format name:instance-type:min-count[:max-count]. Accepts multiple values, for example: '... --node-group memory:r5.xlarge:2 --node-group compute:c5.2xlarge:2:4'")
+	clusterSizeCreateCmd.Flags().StringArray("node-group-label", []string{}, "Node label of an additional worker node group in the format name:key=value. Accepts multiple values.")
+	clusterSizeCreateCmd.Flags().StringArray("node-group-taint", []string{}, "Node taint of an additional worker node group in the format name:key=value:effect. Accepts multiple values.")
+	clusterSizeCreateCmd.Flags().StringArray("node-group-max-price", []string{}, "Maximum hourly spot price of an additional worker node group in the format name:price. Node groups with a max price use spot instances.")
+	clusterSizeCreateCmd.Flags().StringArray("node-group-instance-types", []string{}, "Additional instance types of the mixed instances policy of an additional worker node group in the format name:type1,type2.")
+	clusterSizeCreateCmd.Flags().StringArray("node-group-on-demand", []string{}, "On-demand split of the mixed instances policy of an additional worker node group in the format name:base:percentage-above-base[:spot-allocation-strategy]. Instances above the on-demand percentage are spot instances.")
+	clusterSizeCreateCmd.MarkFlagRequired("name")
+	clusterSizeCreateCmd.MarkFlagRequired("master-instance-type")
+	clusterSizeCreateCmd.MarkFlagRequired("node-instance-type")
+	clusterSizeCreateCmd.MarkFlagRequired("node-min-count")
+
+	registerTableOutputFlags(clusterSizeListCmd)
+
+	clusterSizeCmd.AddCommand(clusterSizeCreateCmd)
+	clusterSizeCmd.AddCommand(clusterSizeListCmd)
+}
+
+var clusterSizeCmd = &cobra.Command{
+	Use:   "size",
+	Short: "Manipulate cluster sizes available on the provisioning server.",
+}
+
+var clusterSizeCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a cluster size.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		name, _ := command.Flags().GetString("name")
+		masterInstanceType, _ := command.Flags().GetString("master-instance-type")
+		masterCount, _ := command.Flags().GetInt64("master-count")
+		nodeInstanceType, _ := command.Flags().GetString("node-instance-type")
+		nodeMinCount, _ := command.Flags().GetInt64("node-min-count")
+		nodeMaxCount, _ := command.Flags().GetInt64("node-max-count")
+		nodeGroups, _ := command.Flags().GetStringArray("node-group")
+
+		nodeGroupLabels, _ := command.Flags().GetStringArray("node-group-label")
+		nodeGroupTaints, _ := command.Flags().GetStringArray("node-group-taint")
+		nodeGroupMaxPrices, _ := command.Flags().GetStringArray("node-group-max-price")
+		nodeGroupInstanceTypes, _ := command.Flags().GetStringArray("node-group-instance-types")
+		nodeGroupOnDemand, _ := command.Flags().GetStringArray("node-group-on-demand")
+
+		additionalNodeGroups, err := parseNodeGroups(nodeGroups)
+		if err != nil {
+			return err
+		}
+		err = applyNodeGroupScheduling(additionalNodeGroups, nodeGroupLabels, nodeGroupTaints, nodeGroupMaxPrices)
+		if err != nil {
+			return err
+		}
+		err = applyNodeGroupMixedInstancesPolicies(additionalNodeGroups, nodeGroupInstanceTypes, nodeGroupOnDemand)
+		if err != nil {
+			return err
+		}
+
+		request := &model.CreateClusterSizeRequest{
+			Name:                 name,
+			MasterInstanceType:   masterInstanceType,
+			MasterCount:          masterCount,
+			NodeInstanceType:     nodeInstanceType,
+			NodeMinCount:         nodeMinCount,
+			NodeMaxCount:         nodeMaxCount,
+			AdditionalNodeGroups: additionalNodeGroups,
+		}
+
+		dryRun, _ := command.Flags().GetBool("dry-run")
+		if dryRun {
+			err = printJSON(request)
+			if err != nil {
+				return errors.Wrap(err, "failed to print API request")
+			}
+
+			return nil
+		}
+
+		clusterSize, err := client.CreateClusterSize(request)
+		if err != nil {
+			return errors.Wrap(err, "failed to create cluster size")
+		}
+
+		err = printJSON(clusterSize)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+var clusterSizeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the built-in and created cluster sizes.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		clusterSizes, err := client.GetClusterSizes()
+		if err != nil {
+			return errors.Wrap(err, "failed to query cluster sizes")
+		}
+
+		if enabled, customCols := tableOutputEnabled(command); enabled {
+			var keys []string
+			var vals [][]string
+
+			if len(customCols) > 0 {
+				data := make([]interface{}, 0, len(clusterSizes))
+				for _, clusterSize := range clusterSizes {
+					data = append(data, clusterSize)
+				}
+				keys, vals, err = prepareTableData(customCols, data)
+				if err != nil {
+					return errors.Wrap(err, "failed to prepare table output")
+				}
+			} else {
+				keys = []string{"NAME", "MASTERS", "NODES", "NODE GROUPS"}
+				for _, clusterSize := range clusterSizes {
+					vals = append(vals, []string{
+						clusterSize.Name,
+						fmt.Sprintf("%d x %s", clusterSize.MasterCount, clusterSize.MasterInstanceType),
+						fmt.Sprintf("%d-%d x %s", clusterSize.NodeMinCount, clusterSize.NodeMaxCount, clusterSize.NodeInstanceType),
+						fmt.Sprintf("%d", len(clusterSize.AdditionalNodeGroups)),
+					})
+				}
+			}
+
+			printTable(keys, vals)
+			return nil
+		}
+
+		err = printJSON(clusterSizes)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+// parseNodeGroups parses node group flag values in the format
+// name:instance-type:min-count[:max-count].
+func parseNodeGroups(values []string) (model.KopsInstanceGroupsMetadata, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	nodeGroups := make(model.KopsInstanceGroupsMetadata, len(values))
+	for _, value := range values {
+		parts := strings.Split(value, ":")
+		if len(parts) != 3 && len(parts) != 4 {
+			return nil, errors.Errorf("node group %q must have the format name:instance-type:min-count[:max-count]", value)
+		}
+		minCount, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid min count of node group %q", value)
+		}
+		maxCount := minCount
+		if len(parts) == 4 {
+			maxCount, err = strconv.ParseInt(parts[3], 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid max count of node group %q", value)
+			}
+		}
+		if _, ok := nodeGroups[parts[0]]; ok {
+			return nil, errors.Errorf("duplicate node group %s", parts[0])
+		}
+
+		nodeGroups[parts[0]] = model.KopsInstanceGroupMetadata{
+			NodeInstanceType: parts[1],
+			NodeMinCount:     minCount,
+			NodeMaxCount:     maxCount,
+		}
+	}
+
+	return nodeGroups, nil
+}
+
+// applyNodeGroupScheduling sets the labels, taints and max prices from flag
+// values in the format name:value on the parsed node groups.
+func splitNodeGroupValue(nodeGroups model.KopsInstanceGroupsMetadata, value string) (string, string, model.KopsInstanceGroupMetadata, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return "", "", model.KopsInstanceGroupMetadata{}, errors.Errorf("value %q must be prefixed with a node group name", value)
+	}
+	nodeGroup, ok := nodeGroups[parts[0]]
+	if !ok {
+		return "", "", model.KopsInstanceGroupMetadata{}, errors.Errorf("value %q references unknown node group %s", value, parts[0])
+	}
+	return parts[0], parts[1], nodeGroup, nil
+}
+
+func applyNodeGroupScheduling(nodeGroups model.KopsInstanceGroupsMetadata, labels, taints, maxPrices []string) error {
+
+	for _, label := range labels {
+		name, value, nodeGroup, err := splitNodeGroupValue(nodeGroups, label)
+		if err != nil {
+			return err
+		}
+		keyValue := strings.SplitN(value, "=", 2)
+		if len(keyValue) != 2 {
+			return errors.Errorf("node group label %q must have the format name:key=value", label)
+		}
+		if nodeGroup.Labels == nil {
+			nodeGroup.Labels = make(map[string]string)
+		}
+		nodeGroup.Labels[keyValue[0]] = keyValue[1]
+		nodeGroups[name] = nodeGroup
+	}
+	for _, taint := range taints {
+		name, value, nodeGroup, err := splitNodeGroupValue(nodeGroups, taint)
+		if err != nil {
+			return err
+		}
+		nodeGroup.Taints = append(nodeGroup.Taints, value)
+		nodeGroups[name] = nodeGroup
+	}
+	for _, maxPrice := range maxPrices {
+		name, value, nodeGroup, err := splitNodeGroupValue(nodeGroups, maxPrice)
+		if err != nil {
+			return err
+		}
+		nodeGroup.MaxPrice = value
+		nodeGroups[name] = nodeGroup
+	}
+
+	return nil
+}
+
+func applyNodeGroupMixedInstancesPolicies(nodeGroups model.KopsInstanceGroupsMetadata, instanceTypes, onDemand []string) error {
+	for _, types := range instanceTypes {
+		name, value, nodeGroup, err := splitNodeGroupValue(nodeGroups, types)
+		if err != nil {
+			return err
+		}
+		if nodeGroup.MixedInstancesPolicy == nil {
+			nodeGroup.MixedInstancesPolicy = &model.KopsMixedInstancesPolicy{}
+		}
+		nodeGroup.MixedInstancesPolicy.Instances = append(nodeGroup.MixedInstancesPolicy.Instances, strings.Split(value, ",")...)
+		nodeGroups[name] = nodeGroup
+	}
+	for _, split := range onDemand {
+		name, value, nodeGroup, err := splitNodeGroupValue(nodeGroups, split)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(value, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return errors.Errorf("node group on-demand split %q must have the format name:base:percentage-above-base[:spot-allocation-strategy]", split)
+		}
+		base, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid on-demand base in %q", split)
+		}
+		aboveBase, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return errors.Wrapf(err, "invalid on-demand percentage above base in %q", split)
+		}
+		if nodeGroup.MixedInstancesPolicy == nil {
+			nodeGroup.MixedInstancesPolicy = &model.KopsMixedInstancesPolicy{}
+		}
+		nodeGroup.MixedInstancesPolicy.OnDemandBase = &base
+		nodeGroup.MixedInstancesPolicy.OnDemandAboveBase = &aboveBase
+		if len(parts) == 3 {
+			nodeGroup.MixedInstancesPolicy.SpotAllocationStrategy = parts[2]
+		}
+		nodeGroups[name] = nodeGroup
+	}
+
+	return nil
+}
@@ -0,0 +1,383 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+// utilityFlagPrefixes are the prefixes of the version and values flags of
+// every utility, as handled by processUtilityFlags.
+var utilityFlagPrefixes = []string{
+	"prometheus-operator",
+	"thanos",
+	"fluentbit",
+	"nginx",
+	"nginx-internal",
+	"teleport",
+	"pgbouncer",
+	"promtail",
+	"kubecost",
+	"node-problem-detector",
+}
+
+func init() {
+	clusterTemplateCreateCmd.Flags().String("name", "", "The unique name of the cluster template.")
+	clusterTemplateCreateCmd.Flags().String("description", "", "An optional description of the cluster template.")
+	registerClusterTemplateParameterFlags(clusterTemplateCreateCmd)
+	clusterTemplateCreateCmd.MarkFlagRequired("name")
+
+	clusterTemplateUpdateCmd.Flags().String("cluster-template", "", "The id of the cluster template to be updated.")
+	clusterTemplateUpdateCmd.Flags().String("name", "", "The new name of the cluster template.")
+	clusterTemplateUpdateCmd.Flags().String("description", "", "The new description of the cluster template.")
+	registerClusterTemplateParameterFlags(clusterTemplateUpdateCmd)
+	clusterTemplateUpdateCmd.MarkFlagRequired("cluster-template")
+
+	clusterTemplateGetCmd.Flags().String("cluster-template", "", "The id of the cluster template to be fetched.")
+	clusterTemplateGetCmd.MarkFlagRequired("cluster-template")
+
+	clusterTemplateListCmd.Flags().String("name", "", "The name by which to filter cluster templates.")
+	registerPagingFlags(clusterTemplateListCmd)
+	registerTableOutputFlags(clusterTemplateListCmd)
+
+	clusterTemplateDeleteCmd.Flags().String("cluster-template", "", "The id of the cluster template to be deleted.")
+	clusterTemplateDeleteCmd.MarkFlagRequired("cluster-template")
+
+	clusterTemplateCmd.AddCommand(clusterTemplateCreateCmd)
+	clusterTemplateCmd.AddCommand(clusterTemplateUpdateCmd)
+	clusterTemplateCmd.AddCommand(clusterTemplateGetCmd)
+	clusterTemplateCmd.AddCommand(clusterTemplateListCmd)
+	clusterTemplateCmd.AddCommand(clusterTemplateDeleteCmd)
+}
+
+var clusterTemplateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manipulate cluster templates managed by the provisioning server.",
+}
+
+var clusterTemplateCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a cluster template.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		name, _ := command.Flags().GetString("name")
+		description, _ := command.Flags().GetString("description")
+
+		cluster := &model.CreateClusterRequest{}
+		applyChangedClusterFlags(command, cluster)
+
+		request := &model.CreateClusterTemplateRequest{
+			Name:        name,
+			Description: description,
+			Cluster:     cluster,
+		}
+
+		dryRun, _ := command.Flags().GetBool("dry-run")
+		if dryRun {
+			return runDryRun(request)
+		}
+
+		clusterTemplate, err := client.CreateClusterTemplate(request)
+		if err != nil {
+			return errors.Wrap(err, "failed to create cluster template")
+		}
+
+		err = printJSON(clusterTemplate)
+		if err != nil {
+			return errors.Wrap(err, "failed to print cluster template response")
+		}
+
+		return nil
+	},
+}
+
+var clusterTemplateUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update a cluster template. Clusters created from the template are not modified.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		clusterTemplateID, _ := command.Flags().GetString("cluster-template")
+		clusterTemplate, err := client.GetClusterTemplate(clusterTemplateID)
+		if err != nil {
+			return errors.Wrap(err, "failed to query cluster template")
+		}
+		if clusterTemplate == nil {
+			return errors.Errorf("cluster template %s not found", clusterTemplateID)
+		}
+
+		request := &model.PatchClusterTemplateRequest{
+			Name:        getStringFlagPointer(command, "name"),
+			Description: getStringFlagPointer(command, "description"),
+		}
+
+		// Changed cluster parameters are applied on top of the current ones, as
+		// the API replaces all of them.
+		cluster := clusterTemplate.Cluster
+		if cluster == nil {
+			cluster = &model.CreateClusterRequest{}
+		}
+		if clusterParameterFlagsChanged(command) {
+			applyChangedClusterFlags(command, cluster)
+			request.Cluster = cluster
+		}
+
+		dryRun, _ := command.Flags().GetBool("dry-run")
+		if dryRun {
+			return runDryRun(request)
+		}
+
+		clusterTemplate, err = client.UpdateClusterTemplate(clusterTemplateID, request)
+		if err != nil {
+			return errors.Wrap(err, "failed to update cluster template")
+		}
+
+		err = printJSON(clusterTemplate)
+		if err != nil {
+			return errors.Wrap(err, "failed to print cluster template response")
+		}
+
+		return nil
+	},
+}
+
+var clusterTemplateGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Get a particular cluster template.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		clusterTemplateID, _ := command.Flags().GetString("cluster-template")
+		clusterTemplate, err := client.GetClusterTemplate(clusterTemplateID)
+		if err != nil {
+			return errors.Wrap(err, "failed to query cluster template")
+		}
+		if clusterTemplate == nil {
+			return nil
+		}
+
+		err = printJSON(clusterTemplate)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+var clusterTemplateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List created cluster templates.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		name, _ := command.Flags().GetString("name")
+		paging := parsePagingFlags(command)
+		clusterTemplates, err := client.GetClusterTemplates(&model.GetClusterTemplatesRequest{
+			Paging: paging,
+			Name:   name,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to query cluster templates")
+		}
+
+		if enabled, customCols := tableOutputEnabled(command); enabled {
+			var keys []string
+			var vals [][]string
+
+			if len(customCols) > 0 {
+				data := make([]interface{}, 0, len(clusterTemplates))
+				for _, clusterTemplate := range clusterTemplates {
+					data = append(data, clusterTemplate)
+				}
+				keys, vals, err = prepareTableData(customCols, data)
+				if err != nil {
+					return errors.Wrap(err, "failed to prepare table output")
+				}
+			} else {
+				keys = []string{"ID", "NAME", "DESCRIPTION"}
+				for _, clusterTemplate := range clusterTemplates {
+					vals = append(vals, []string{clusterTemplate.ID, clusterTemplate.Name, clusterTemplate.Description})
+				}
+			}
+
+			printTable(keys, vals)
+			return nil
+		}
+
+		err = printJSON(clusterTemplates)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+var clusterTemplateDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a cluster template. Clusters created from the template are not modified.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		clusterTemplateID, _ := command.Flags().GetString("cluster-template")
+
+		err := client.DeleteClusterTemplate(clusterTemplateID)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete cluster template")
+		}
+
+		return nil
+	},
+}
+
+// registerClusterTemplateParameterFlags registers the cluster parameter flags
+// of cluster templates. They match the flags of cluster create, but have no
+// defaults as only provided values are stored in the template.
+func registerClusterTemplateParameterFlags(command *cobra.Command) {
+	command.Flags().String("provider", "", "Cloud provider hosting the cluster.")
+	command.Flags().String("provisioner", "", "The provisioner managing the clusters: kops or eks.")
+	command.Flags().String("eks-cluster-role-arn", "", "The IAM role ARN of the EKS control plane. Required for eks clusters.")
+	command.Flags().String("eks-node-role-arn", "", "The IAM role ARN of the EKS worker nodes. Required for eks clusters.")
+	command.Flags().String("version", "", "The Kubernetes version to target. Use 'latest' or versions such as '1.16.10'.")
+	command.Flags().String("kops-ami", "", "The AMI to use for the cluster hosts.")
+	command.Flags().String("size", "", "The name of the cluster size describing the cluster. See 'cloud cluster size list' for the available sizes.")
+	command.Flags().String("size-master-instance-type", "", "The instance type describing the k8s master nodes. Overwrites value from 'size'.")
+	command.Flags().Int64("size-master-count", 0, "The number of k8s master nodes. Overwrites value from 'size'.")
+	command.Flags().String("size-node-instance-type", "", "The instance type describing the k8s worker nodes. Overwrites value from 'size'.")
+	command.Flags().Int64("size-node-count", 0, "The number of k8s worker nodes. Overwrites value from 'size'.")
+	command.Flags().String("zones", "", "The zones where the cluster will be deployed. Use commas to separate multiple zones.")
+	command.Flags().Bool("allow-installations", false, "Whether clusters will allow for new installations to be scheduled.")
+	command.Flags().String("networking", "", "Networking mode to use, for example: weave, calico, canal, amazon-vpc-routed-eni")
+	command.Flags().String("vpc", "", "Set to use a shared VPC")
+	command.Flags().StringArray("annotation", []string{}, "Annotations for the clusters. Accepts multiple values, for example: '... --annotation abc --annotation def'")
+	for _, prefix := range utilityFlagPrefixes {
+		command.Flags().String(prefix+"-version", "", "The version of the "+prefix+" Helm chart")
+		command.Flags().String(prefix+"-values", "", "The full Git URL of the desired chart values for "+prefix)
+	}
+}
+
+// clusterParameterFlagsChanged returns true if any cluster parameter flag was
+// provided.
+func clusterParameterFlagsChanged(command *cobra.Command) bool {
+	for _, name := range []string{
+		"provider", "provisioner", "eks-cluster-role-arn", "eks-node-role-arn", "version", "kops-ami", "size", "size-master-instance-type",
+		"size-master-count", "size-node-instance-type", "size-node-count", "zones",
+		"allow-installations", "networking", "vpc", "annotation",
+	} {
+		if command.Flags().Changed(name) {
+			return true
+		}
+	}
+	for _, prefix := range utilityFlagPrefixes {
+		if command.Flags().Changed(prefix+"-version") || command.Flags().Changed(prefix+"-values") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyChangedClusterFlags sets the values of the cluster parameter flags which
+// were provided on the given request. The flags are shared by cluster create
+// and the cluster template commands.
+func applyChangedClusterFlags(command *cobra.Command, request *model.CreateClusterRequest) {
+	flags := command.Flags()
+
+	if flags.Changed("provider") {
+		request.Provider, _ = flags.GetString("provider")
+	}
+	if flags.Changed("provisioner") {
+		request.Provisioner, _ = flags.GetString("provisioner")
+	}
+	if flags.Changed("eks-cluster-role-arn") {
+		request.EKSClusterRoleARN, _ = flags.GetString("eks-cluster-role-arn")
+	}
+	if flags.Changed("eks-node-role-arn") {
+		request.EKSNodeRoleARN, _ = flags.GetString("eks-node-role-arn")
+	}
+	if flags.Changed("version") {
+		request.Version, _ = flags.GetString("version")
+	}
+	if flags.Changed("kops-ami") {
+		request.KopsAMI, _ = flags.GetString("kops-ami")
+	}
+	if flags.Changed("zones") {
+		zones, _ := flags.GetString("zones")
+		request.Zones = strings.Split(zones, ",")
+	}
+	if flags.Changed("allow-installations") {
+		request.AllowInstallations, _ = flags.GetBool("allow-installations")
+	}
+	if flags.Changed("networking") {
+		request.Networking, _ = flags.GetString("networking")
+	}
+	if flags.Changed("vpc") {
+		request.VPC, _ = flags.GetString("vpc")
+	}
+	if flags.Changed("annotation") {
+		request.Annotations, _ = flags.GetStringArray("annotation")
+	}
+
+	if flags.Changed("size") {
+		request.Size, _ = flags.GetString("size")
+	}
+	if flags.Changed("size-master-instance-type") {
+		request.MasterInstanceType, _ = flags.GetString("size-master-instance-type")
+	}
+	if flags.Changed("size-master-count") {
+		request.MasterCount, _ = flags.GetInt64("size-master-count")
+	}
+	if flags.Changed("size-node-instance-type") {
+		request.NodeInstanceType, _ = flags.GetString("size-node-instance-type")
+	}
+	if flags.Changed("size-node-count") {
+		nodeCount, _ := flags.GetInt64("size-node-count")
+		request.NodeMinCount = nodeCount
+		request.NodeMaxCount = nodeCount
+	}
+
+	for utilityName, version := range processUtilityFlags(command) {
+		if len(version.Chart) == 0 && len(version.ValuesPath) == 0 {
+			continue
+		}
+		if request.DesiredUtilityVersions == nil {
+			request.DesiredUtilityVersions = make(map[string]*model.HelmUtilityVersion)
+		}
+		currentVersion, ok := request.DesiredUtilityVersions[utilityName]
+		if !ok || currentVersion == nil {
+			request.DesiredUtilityVersions[utilityName] = version
+			continue
+		}
+		if len(version.Chart) != 0 {
+			currentVersion.Chart = version.Chart
+		}
+		if len(version.ValuesPath) != 0 {
+			currentVersion.ValuesPath = version.ValuesPath
+		}
+	}
+
+}
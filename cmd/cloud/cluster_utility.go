@@ -0,0 +1,292 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	clusterUtilityUpgradeCmd.Flags().String("cluster", "", "The id of the cluster whose utility is to be upgraded.")
+	clusterUtilityUpgradeCmd.Flags().String("utility", "", "The canonical name of the utility to upgrade, for example: nginx, promtail.")
+	clusterUtilityUpgradeCmd.Flags().String("chart", "", "The version of the utility Helm chart to upgrade to.")
+	clusterUtilityUpgradeCmd.Flags().String("values-path", "", "The location of the desired chart values: a local path or a file, http(s), git+https, git+ssh or s3 URL. The values of the deployed version are kept if not set.")
+	clusterUtilityUpgradeCmd.Flags().Bool("preview", false, "Only compute the Helm diff of the upgrade without applying it.")
+	clusterUtilityUpgradeCmd.MarkFlagRequired("cluster")
+	clusterUtilityUpgradeCmd.MarkFlagRequired("utility")
+	clusterUtilityUpgradeCmd.MarkFlagRequired("chart")
+
+	clusterUtilityRolloutCmd.Flags().StringArray("cluster", []string{}, "The ids of the clusters to roll the utility version to. All clusters are targeted if not set. Accepts multiple values, for example: '... --cluster abc --cluster def'")
+	clusterUtilityRolloutCmd.Flags().String("utility", "", "The canonical name of the utility to roll out, for example: nginx, promtail.")
+	clusterUtilityRolloutCmd.Flags().String("chart", "", "The version of the utility Helm chart to roll out.")
+	clusterUtilityRolloutCmd.Flags().String("values-path", "", "The location of the desired chart values: a local path or a file, http(s), git+https, git+ssh or s3 URL. The values of the deployed version are kept if not set.")
+	clusterUtilityRolloutCmd.Flags().Bool("preview", false, "Only compute the Helm diffs of the upgrades without applying them.")
+	clusterUtilityRolloutCmd.Flags().Int("batch-size", 5, "The number of clusters upgraded at the same time.")
+	clusterUtilityRolloutCmd.Flags().Duration("poll-interval", 30*time.Second, "The interval at which the clusters of a batch are checked for completion.")
+	clusterUtilityRolloutCmd.Flags().Duration("batch-timeout", 30*time.Minute, "The maximum time to wait for the clusters of a batch to complete.")
+	clusterUtilityRolloutCmd.MarkFlagRequired("utility")
+	clusterUtilityRolloutCmd.MarkFlagRequired("chart")
+
+	clusterUtilityValuesCmd.Flags().String("cluster", "", "The id of the cluster whose utility values are to be rendered.")
+	clusterUtilityValuesCmd.Flags().String("utility", "", "The canonical name of the utility, for example: nginx, promtail.")
+	clusterUtilityValuesCmd.MarkFlagRequired("cluster")
+	clusterUtilityValuesCmd.MarkFlagRequired("utility")
+
+	clusterUtilityCmd.AddCommand(clusterUtilityUpgradeCmd)
+	clusterUtilityCmd.AddCommand(clusterUtilityRolloutCmd)
+	clusterUtilityCmd.AddCommand(clusterUtilityValuesCmd)
+}
+
+var clusterUtilityCmd = &cobra.Command{
+	Use:   "utility",
+	Short: "Upgrade single utilities of clusters managed by the provisioning server and inspect their values.",
+}
+
+var clusterUtilityUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade or preview the upgrade of a single utility of a cluster.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		clusterID, _ := command.Flags().GetString("cluster")
+		utility, _ := command.Flags().GetString("utility")
+		request := newUpgradeClusterUtilityRequest(command)
+
+		dryRun, _ := command.Flags().GetBool("dry-run")
+		if dryRun {
+			return runDryRun(request)
+		}
+
+		cluster, err := client.UpgradeClusterUtility(clusterID, utility, request)
+		if err != nil {
+			return errors.Wrap(err, "failed to upgrade cluster utility")
+		}
+
+		err = printJSON(cluster)
+		if err != nil {
+			return errors.Wrap(err, "failed to print cluster response")
+		}
+
+		return nil
+	},
+}
+
+var clusterUtilityValuesCmd = &cobra.Command{
+	Use:   "values",
+	Short: "Show the values of a cluster utility rendered with the cluster context.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		clusterID, _ := command.Flags().GetString("cluster")
+		utility, _ := command.Flags().GetString("utility")
+
+		values, err := client.GetClusterUtilityValues(clusterID, utility)
+		if err != nil {
+			return errors.Wrap(err, "failed to get cluster utility values")
+		}
+
+		err = printJSON(values)
+		if err != nil {
+			return errors.Wrap(err, "failed to print cluster utility values")
+		}
+
+		return nil
+	},
+}
+
+// utilityRolloutResult is the outcome of a utility rollout on a cluster.
+type utilityRolloutResult struct {
+	ClusterID      string
+	ClusterState   string
+	UtilityUpgrade *model.UtilityUpgrade `json:"UtilityUpgrade,omitempty"`
+	Skipped        string                `json:"Skipped,omitempty"`
+}
+
+var clusterUtilityRolloutCmd = &cobra.Command{
+	Use:   "rollout",
+	Short: "Roll a utility version across many clusters in batches, stopping at the first failed batch.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		clusterIDs, _ := command.Flags().GetStringArray("cluster")
+		utility, _ := command.Flags().GetString("utility")
+		batchSize, _ := command.Flags().GetInt("batch-size")
+		pollInterval, _ := command.Flags().GetDuration("poll-interval")
+		batchTimeout, _ := command.Flags().GetDuration("batch-timeout")
+		request := newUpgradeClusterUtilityRequest(command)
+
+		if batchSize < 1 {
+			return errors.New("batch size must be at least 1")
+		}
+
+		clusters, err := getRolloutClusters(client, clusterIDs)
+		if err != nil {
+			return err
+		}
+
+		var targets []*model.ClusterDTO
+		var results []*utilityRolloutResult
+		for _, cluster := range clusters {
+			reason := utilityRolloutSkipReason(cluster, utility, request)
+			if len(reason) != 0 {
+				results = append(results, &utilityRolloutResult{ClusterID: cluster.ID, ClusterState: cluster.State, Skipped: reason})
+				continue
+			}
+			targets = append(targets, cluster)
+		}
+
+		dryRun, _ := command.Flags().GetBool("dry-run")
+		if dryRun {
+			for _, cluster := range targets {
+				results = append(results, &utilityRolloutResult{ClusterID: cluster.ID, ClusterState: cluster.State})
+			}
+			return printJSON(results)
+		}
+
+		var rolloutErr error
+		for start := 0; start < len(targets) && rolloutErr == nil; start += batchSize {
+			end := start + batchSize
+			if end > len(targets) {
+				end = len(targets)
+			}
+
+			var batchResults []*utilityRolloutResult
+			batchResults, rolloutErr = rolloutUtilityBatch(client, targets[start:end], utility, request, pollInterval, batchTimeout)
+			results = append(results, batchResults...)
+		}
+
+		err = printJSON(results)
+		if err != nil {
+			return errors.Wrap(err, "failed to print rollout results")
+		}
+
+		return rolloutErr
+	},
+}
+
+func newUpgradeClusterUtilityRequest(command *cobra.Command) *model.UpgradeClusterUtilityRequest {
+	chart, _ := command.Flags().GetString("chart")
+	valuesPath, _ := command.Flags().GetString("values-path")
+	preview, _ := command.Flags().GetBool("preview")
+
+	return &model.UpgradeClusterUtilityRequest{
+		Chart:      chart,
+		ValuesPath: valuesPath,
+		Preview:    preview,
+	}
+}
+
+func getRolloutClusters(client *model.Client, clusterIDs []string) ([]*model.ClusterDTO, error) {
+	if len(clusterIDs) == 0 {
+		clusters, err := client.GetClusters(&model.GetClustersRequest{Paging: model.AllPagesNotDeleted()})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to query clusters")
+		}
+		return clusters, nil
+	}
+
+	var clusters []*model.ClusterDTO
+	for _, clusterID := range clusterIDs {
+		cluster, err := client.GetCluster(clusterID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get cluster %s", clusterID)
+		}
+		if cluster == nil {
+			return nil, errors.Errorf("cluster %s not found", clusterID)
+		}
+		clusters = append(clusters, cluster)
+	}
+
+	return clusters, nil
+}
+
+// utilityRolloutSkipReason returns why the utility of the cluster should not
+// be upgraded by the rollout, or an empty string if it should be.
+func utilityRolloutSkipReason(cluster *model.ClusterDTO, utility string, request *model.UpgradeClusterUtilityRequest) string {
+	if cluster.IsEKS() {
+		return "utilities of EKS clusters can't be upgraded"
+	}
+	if cluster.State != model.ClusterStateStable {
+		return fmt.Sprintf("cluster is %s", cluster.State)
+	}
+
+	actualVersion := cluster.ActualUtilityVersion(utility)
+	if !request.Preview && actualVersion != nil && actualVersion.Chart == request.Chart &&
+		(len(request.ValuesPath) == 0 || actualVersion.ValuesPath == request.ValuesPath) {
+		return "utility already runs the requested version"
+	}
+
+	return ""
+}
+
+// rolloutUtilityBatch requests the utility upgrade of a batch of clusters
+// and waits until all of them completed. An error is returned if any upgrade
+// of the batch failed.
+func rolloutUtilityBatch(client *model.Client, clusters []*model.ClusterDTO, utility string, request *model.UpgradeClusterUtilityRequest, pollInterval, timeout time.Duration) ([]*utilityRolloutResult, error) {
+	var results []*utilityRolloutResult
+	var failed int
+	for _, cluster := range clusters {
+		_, err := client.UpgradeClusterUtility(cluster.ID, utility, request)
+		if err != nil {
+			failed++
+			results = append(results, &utilityRolloutResult{ClusterID: cluster.ID, ClusterState: cluster.State, Skipped: err.Error()})
+			continue
+		}
+		results = append(results, &utilityRolloutResult{ClusterID: cluster.ID, ClusterState: model.ClusterStateUtilityUpgradeRequested})
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := 0
+		for _, result := range results {
+			if len(result.Skipped) != 0 || result.ClusterState != model.ClusterStateUtilityUpgradeRequested {
+				continue
+			}
+
+			cluster, err := client.GetCluster(result.ClusterID)
+			if err != nil {
+				return results, errors.Wrapf(err, "failed to get cluster %s", result.ClusterID)
+			}
+			result.ClusterState = cluster.State
+			result.UtilityUpgrade = cluster.GetUtilityUpgrade(utility)
+
+			if cluster.State == model.ClusterStateUtilityUpgradeRequested {
+				pending++
+				continue
+			}
+			if result.UtilityUpgrade == nil || result.UtilityUpgrade.Failed() || cluster.State != model.ClusterStateStable {
+				failed++
+			}
+		}
+
+		if pending == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			return results, errors.Errorf("timed out waiting for %d clusters to upgrade utility %s", pending, utility)
+		}
+		time.Sleep(pollInterval)
+	}
+
+	if failed > 0 {
+		return results, errors.Errorf("utility %s failed to upgrade on %d clusters, stopping rollout", utility, failed)
+	}
+
+	return results, nil
+}
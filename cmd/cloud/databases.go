@@ -36,10 +36,16 @@ func init() {
 	multitenantDatabaseDeleteCmd.Flags().Bool("force", false, "Specifies whether to delete record even if database cluster exists.")
 	multitenantDatabaseDeleteCmd.MarkFlagRequired("multitenant-database")
 
+	multitenantDatabaseUpgradeCmd.Flags().String("multitenant-database", "", "The id of the multitenant database to be upgraded.")
+	multitenantDatabaseUpgradeCmd.Flags().String("engine-version", "", "The database engine version to upgrade to.")
+	multitenantDatabaseUpgradeCmd.MarkFlagRequired("multitenant-database")
+	multitenantDatabaseUpgradeCmd.MarkFlagRequired("engine-version")
+
 	multitenantDatabaseCmd.AddCommand(multitenantDatabaseListCmd)
 	multitenantDatabaseCmd.AddCommand(multitenantDatabaseGetCmd)
 	multitenantDatabaseCmd.AddCommand(multitenantDatabaseUpdateCmd)
 	multitenantDatabaseCmd.AddCommand(multitenantDatabaseDeleteCmd)
+	multitenantDatabaseCmd.AddCommand(multitenantDatabaseUpgradeCmd)
 
 	// Logical Databases
 	logicalDatabaseListCmd.Flags().String("multitenant-database-id", "", "The multitenant database ID by which to filter logical databases.")
@@ -216,6 +222,40 @@ var multitenantDatabaseDeleteCmd = &cobra.Command{
 	},
 }
 
+var multitenantDatabaseUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the engine version of a multitenant database.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		multitenantDatabaseID, _ := command.Flags().GetString("multitenant-database")
+		engineVersion, _ := command.Flags().GetString("engine-version")
+		request := &model.UpgradeMultitenantDatabaseRequest{
+			EngineVersion: engineVersion,
+		}
+
+		dryRun, _ := command.Flags().GetBool("dry-run")
+		if dryRun {
+			err := printJSON(request)
+			if err != nil {
+				return errors.Wrap(err, "failed to print API request")
+			}
+
+			return nil
+		}
+
+		multitenantDatabase, err := client.UpgradeMultitenantDatabase(multitenantDatabaseID, request)
+		if err != nil {
+			return errors.Wrap(err, "failed to upgrade multitenant database")
+		}
+
+		return printJSON(multitenantDatabase)
+	},
+}
+
 var logicalDatabaseCmd = &cobra.Command{
 	Use:   "logical",
 	Short: "Manage and view logical database resources.",
@@ -30,14 +30,20 @@ func init() {
 	installationCreateCmd.Flags().String("size", model.InstallationDefaultSize, "The size of the installation. Accepts 100users, 1000users, 5000users, 10000users, 25000users, miniSingleton, or miniHA. Defaults to 100users.")
 	installationCreateCmd.Flags().String("affinity", model.InstallationAffinityIsolated, "How other installations may be co-located in the same cluster.")
 	installationCreateCmd.Flags().String("license", "", "The Mattermost License to use in the server.")
-	installationCreateCmd.Flags().String("database", model.InstallationDatabaseMysqlOperator, "The Mattermost server database type. Accepts mysql-operator, aws-rds, aws-rds-postgres, aws-multitenant-rds, or aws-multitenant-rds-postgres")
-	installationCreateCmd.Flags().String("filestore", model.InstallationFilestoreMinioOperator, "The Mattermost server filestore type. Accepts minio-operator, aws-s3, bifrost, or aws-multitenant-s3")
+	installationCreateCmd.Flags().String("database", model.InstallationDatabaseMysqlOperator, "The Mattermost server database type. Accepts mysql-operator, aws-rds, aws-rds-postgres, aws-multitenant-rds, aws-multitenant-rds-postgres, or external-postgres")
+	installationCreateCmd.Flags().String("filestore", model.InstallationFilestoreMinioOperator, "The Mattermost server filestore type. Accepts minio-operator, aws-s3, bifrost, aws-multitenant-s3, or s3-compatible")
 	installationCreateCmd.Flags().StringArray("mattermost-env", []string{}, "Env vars to add to the Mattermost App. Accepts format: KEY_NAME=VALUE. Use the flag multiple times to set multiple env vars.")
 	installationCreateCmd.Flags().StringArray("priority-env", []string{}, "Env vars to add to the Mattermost App that take priority over group config. Accepts format: KEY_NAME=VALUE. Use the flag multiple times to set multiple env vars.")
 	installationCreateCmd.Flags().StringArray("annotation", []string{}, "Additional annotations for the installation. Accepts multiple values, for example: '... --annotation abc --annotation def'")
 	installationCreateCmd.Flags().String("rds-primary-instance", "", "The machine instance type used for primary replica of database cluster. Works only with single tenant RDS databases.")
 	installationCreateCmd.Flags().String("rds-replica-instance", "", "The machine instance type used for reader replicas of database cluster. Works only with single tenant RDS databases.")
 	installationCreateCmd.Flags().Int("rds-replicas-count", 0, "The number of reader replicas of database cluster. Min: 0, Max: 15. Works only with single tenant RDS databases.")
+	installationCreateCmd.Flags().StringArray("required-cluster-annotation", []string{}, "Annotations a cluster must have for the installation to be scheduled on it. Accepts multiple values, for example: '... --required-cluster-annotation abc --required-cluster-annotation def'")
+	installationCreateCmd.Flags().StringArray("preferred-cluster-annotation", []string{}, "Annotations of clusters that should be preferred when scheduling the installation. Accepts multiple values.")
+	installationCreateCmd.Flags().String("node-group", "", "The name of the additional worker node group the installation is scheduled on. Only clusters with the node group are used.")
+	installationCreateCmd.Flags().Bool("owner-anti-affinity", false, "When set to true, the installation will not share a cluster with installations of other owners.")
+	installationCreateCmd.Flags().Int("database-pool-size", 0, "The number of database proxy server connections kept for the installation. Works only with aws-multitenant-rds-postgres-pgbouncer databases.")
+	installationCreateCmd.Flags().Int("database-max-connections", 0, "The maximum number of database proxy server connections of the installation. Works only with aws-multitenant-rds-postgres-pgbouncer databases.")
 	installationCreateCmd.MarkFlagRequired("owner")
 	installationCreateCmd.MarkFlagRequired("dns")
 
@@ -51,6 +57,8 @@ func init() {
 	installationUpdateCmd.Flags().StringArray("priority-env", []string{}, "Env vars to add to the Mattermost App that take priority over group config. Accepts format: KEY_NAME=VALUE. Use the flag multiple times to set multiple env vars.")
 	installationUpdateCmd.Flags().Bool("mattermost-env-clear", false, "Clears all env var data.")
 	installationUpdateCmd.Flags().Bool("priority-env-clear", false, "Clears all priority env var data.")
+	installationUpdateCmd.Flags().Int("database-pool-size", 0, "The number of database proxy server connections kept for the installation. Setting either pool flag replaces all database pool overrides.")
+	installationUpdateCmd.Flags().Int("database-max-connections", 0, "The maximum number of database proxy server connections of the installation. Setting either pool flag replaces all database pool overrides.")
 	installationUpdateCmd.MarkFlagRequired("installation")
 
 	installationGetCmd.Flags().String("installation", "", "The id of the installation to be fetched.")
@@ -74,6 +82,12 @@ func init() {
 	installationHibernateCmd.Flags().String("installation", "", "The id of the installation to put into hibernation.")
 	installationHibernateCmd.MarkFlagRequired("installation")
 
+	installationRotateDatabaseCredentialsCmd.Flags().String("installation", "", "The id of the installation to rotate the database credentials of.")
+	installationRotateDatabaseCredentialsCmd.MarkFlagRequired("installation")
+
+	installationRotateFilestoreAccessKeyCmd.Flags().String("installation", "", "The id of the installation to rotate the filestore access key of.")
+	installationRotateFilestoreAccessKeyCmd.MarkFlagRequired("installation")
+
 	installationWakeupCmd.Flags().String("installation", "", "The id of the installation to wake up from hibernation.")
 	installationWakeupCmd.Flags().String("owner", "", "The new owner value of this installation.")
 	installationWakeupCmd.Flags().String("image", "mattermost/mattermost-enterprise-edition", "The Mattermost container image to use.")
@@ -101,6 +115,8 @@ func init() {
 	installationCmd.AddCommand(installationDeleteCmd)
 	installationCmd.AddCommand(installationHibernateCmd)
 	installationCmd.AddCommand(installationWakeupCmd)
+	installationCmd.AddCommand(installationRotateDatabaseCredentialsCmd)
+	installationCmd.AddCommand(installationRotateFilestoreAccessKeyCmd)
 	installationCmd.AddCommand(installationGetCmd)
 	installationCmd.AddCommand(installationListCmd)
 	installationCmd.AddCommand(installationShowStateReport)
@@ -179,6 +195,30 @@ var installationCreateCmd = &cobra.Command{
 			request.SingleTenantDatabaseConfig = dbConfig
 		}
 
+		requiredClusterAnnotations, _ := command.Flags().GetStringArray("required-cluster-annotation")
+		preferredClusterAnnotations, _ := command.Flags().GetStringArray("preferred-cluster-annotation")
+		ownerAntiAffinity, _ := command.Flags().GetBool("owner-anti-affinity")
+		nodeGroup, _ := command.Flags().GetString("node-group")
+		placement := &model.InstallationPlacement{
+			RequiredClusterAnnotations:  requiredClusterAnnotations,
+			PreferredClusterAnnotations: preferredClusterAnnotations,
+			OwnerAntiAffinity:           ownerAntiAffinity,
+			NodeGroup:                   nodeGroup,
+		}
+		if !placement.IsEmpty() {
+			request.Placement = placement
+		}
+
+		databasePoolSize, _ := command.Flags().GetInt("database-pool-size")
+		databaseMaxConnections, _ := command.Flags().GetInt("database-max-connections")
+		databasePoolConfig := &model.InstallationDatabasePoolConfig{
+			PoolSize:       databasePoolSize,
+			MaxConnections: databaseMaxConnections,
+		}
+		if !databasePoolConfig.IsEmpty() {
+			request.DatabasePoolConfig = databasePoolConfig
+		}
+
 		dryRun, _ := command.Flags().GetBool("dry-run")
 		if dryRun {
 			err = printJSON(request)
@@ -232,6 +272,15 @@ var installationUpdateCmd = &cobra.Command{
 			PriorityEnv:   priorityEnvVarMap,
 		}
 
+		if command.Flags().Changed("database-pool-size") || command.Flags().Changed("database-max-connections") {
+			databasePoolSize, _ := command.Flags().GetInt("database-pool-size")
+			databaseMaxConnections, _ := command.Flags().GetInt("database-max-connections")
+			request.DatabasePoolConfig = &model.InstallationDatabasePoolConfig{
+				PoolSize:       databasePoolSize,
+				MaxConnections: databaseMaxConnections,
+			}
+		}
+
 		dryRun, _ := command.Flags().GetBool("dry-run")
 		if dryRun {
 			err = printJSON(request)
@@ -296,6 +345,56 @@ var installationHibernateCmd = &cobra.Command{
 	},
 }
 
+var installationRotateDatabaseCredentialsCmd = &cobra.Command{
+	Use:   "rotate-database-credentials",
+	Short: "Rotate the database credentials of an installation.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		installationID, _ := command.Flags().GetString("installation")
+
+		installation, err := client.RotateInstallationDatabaseCredentials(installationID)
+		if err != nil {
+			return errors.Wrap(err, "failed to request installation database credential rotation")
+		}
+
+		err = printJSON(installation)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
+var installationRotateFilestoreAccessKeyCmd = &cobra.Command{
+	Use:   "rotate-filestore-access-key",
+	Short: "Rotate the filestore access key of an installation.",
+	RunE: func(command *cobra.Command, args []string) error {
+		command.SilenceUsage = true
+
+		serverAddress, _ := command.Flags().GetString("server")
+		client := model.NewClient(serverAddress)
+
+		installationID, _ := command.Flags().GetString("installation")
+
+		installation, err := client.RotateInstallationFilestoreAccessKey(installationID)
+		if err != nil {
+			return errors.Wrap(err, "failed to request installation filestore access key rotation")
+		}
+
+		err = printJSON(installation)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	},
+}
+
 var installationWakeupCmd = &cobra.Command{
 	Use:   "wake-up",
 	Short: "Wake an installation from hibernation.",
@@ -23,6 +23,7 @@ import (
 	sdkAWS "github.com/aws/aws-sdk-go/aws"
 	"github.com/gorilla/mux"
 	awat "github.com/mattermost/awat/model"
+	"github.com/mattermost/mattermost-cloud/clusterdictionary"
 	"github.com/mattermost/mattermost-cloud/internal/api"
 	"github.com/mattermost/mattermost-cloud/internal/events"
 	"github.com/mattermost/mattermost-cloud/internal/metrics"
@@ -32,6 +33,8 @@ import (
 	toolsAWS "github.com/mattermost/mattermost-cloud/internal/tools/aws"
 	"github.com/mattermost/mattermost-cloud/internal/tools/helm"
 	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
+	"github.com/mattermost/mattermost-cloud/internal/tools/postgres"
+	"github.com/mattermost/mattermost-cloud/internal/tools/s3compat"
 	"github.com/mattermost/mattermost-cloud/internal/tools/terraform"
 	"github.com/mattermost/mattermost-cloud/internal/tools/utils"
 	"github.com/mattermost/mattermost-cloud/model"
@@ -60,6 +63,8 @@ func init() {
 	serverCmd.PersistentFlags().Bool("dev", false, "Set sane defaults for development")
 	serverCmd.PersistentFlags().String("backup-restore-tool-image", "mattermost/backup-restore-tool:latest", "Image of Backup Restore Tool to use.")
 	serverCmd.PersistentFlags().Int32("backup-job-ttl-seconds", 3600, "Number of seconds after which finished backup jobs will be cleaned up. Set to negative value to not cleanup or 0 to cleanup immediately.")
+	serverCmd.PersistentFlags().String("db-engine-migration-assist-image", "", "Image providing the Mattermost migration-assist tool used to migrate installation databases from MySQL to Postgres. Required for database engine migrations.")
+	serverCmd.PersistentFlags().String("db-engine-migration-pgloader-image", "", "Image providing pgloader used to copy installation data from MySQL to Postgres. Required for database engine migrations.")
 	serverCmd.PersistentFlags().Bool("deploy-mysql-operator", true, "Whether to deploy the mysql operator.")
 	serverCmd.PersistentFlags().Bool("deploy-minio-operator", true, "Whether to deploy the minio operator.")
 	serverCmd.PersistentFlags().Int64("default-max-schemas-per-logical-database", 10, "When importing and creating new proxy multitenant databases, this value is used for MaxInstallationsPerLogicalDatabase.")
@@ -75,11 +80,40 @@ func init() {
 	serverCmd.PersistentFlags().String("awat", "http://localhost:8077", "The location of the Automatic Workspace Archive Translator if the import supervisor is being used.")
 	serverCmd.PersistentFlags().Bool("installation-db-restoration-supervisor", false, "Whether this server will run an installation db restoration supervisor or not.")
 	serverCmd.PersistentFlags().Bool("installation-db-migration-supervisor", false, "Whether this server will run an installation db migration supervisor or not.")
+	serverCmd.PersistentFlags().Bool("cluster-migration-supervisor", false, "Whether this server will run a cluster migration supervisor or not.")
+	serverCmd.PersistentFlags().Bool("cluster-rebalancer", false, "Whether this server will run a cluster rebalancer or not.")
+	serverCmd.PersistentFlags().Bool("multitenant-database-rebalancer", false, "Whether this server will run a multitenant database rebalancer or not.")
+	serverCmd.PersistentFlags().Bool("installation-db-metrics-supervisor", false, "Whether this server will run an installation database metrics supervisor collecting database usage of installations or not.")
+	serverCmd.PersistentFlags().Bool("installation-db-credential-rotation-supervisor", false, "Whether this server will run an installation database credential rotation supervisor rotating expired installation database credentials or not.")
+	serverCmd.PersistentFlags().Bool("multitenant-database-upgrade-supervisor", false, "Whether this server will run a multitenant database upgrade supervisor upgrading the engine version of multitenant databases or not.")
+	serverCmd.PersistentFlags().Bool("multitenant-database-capacity-supervisor", false, "Whether this server will run a multitenant database capacity supervisor creating new multitenant RDS clusters when the existing ones fill up or not. Requires --multitenant-database-auto-provisioning-threshold.")
+	serverCmd.PersistentFlags().Bool("cluster-pool-supervisor", false, "Whether this server will run a cluster pool supervisor creating new clusters for installations without compatible clusters or not.")
 
 	// Scheduling and installation options
 	serverCmd.PersistentFlags().Bool("balanced-installation-scheduling", false, "Whether to schedule installations on the cluster with the greatest percentage of available resources or not. (slows down scheduling speed as cluster count increases)")
 	serverCmd.PersistentFlags().Int("cluster-resource-threshold", 80, "The percent threshold where new installations won't be scheduled on a multi-tenant cluster.")
 	serverCmd.PersistentFlags().Int("cluster-resource-threshold-scale-value", 0, "The number of worker nodes to scale up by when the threshold is passed. Set to 0 for no scaling. Scaling will never exceed the cluster max worker configuration value.")
+	serverCmd.PersistentFlags().String("cluster-sizes", "", "The path to a JSON file with cluster sizes which are available in addition to the built-in sizes.")
+	serverCmd.PersistentFlags().StringSlice("cluster-pool-templates", []string{}, "The names of the cluster templates used by the cluster pool supervisor to create new clusters. Templates listed first are preferred.")
+	serverCmd.PersistentFlags().Int("cluster-pool-max-clusters-per-template", 10, "The maximum number of clusters allowing installations the cluster pool supervisor keeps per cluster template. Set to 0 for no limit.")
+	serverCmd.PersistentFlags().Int("cluster-pool-stable-cluster-grace-period", 900, "The time in seconds after its creation during which a stable cluster created from a cluster template prevents the cluster pool supervisor from creating another cluster from the template.")
+	serverCmd.PersistentFlags().Bool("cluster-autoscaling", false, "Whether the cluster supervisor will scale worker nodes of stable clusters based on the requested resources of scheduled installations or not.")
+	serverCmd.PersistentFlags().Int("cluster-autoscaling-scale-up-threshold", 80, "The percent of requested installation resources above which the cluster autoscaler adds worker nodes.")
+	serverCmd.PersistentFlags().Int("cluster-autoscaling-scale-down-threshold", 40, "The percent of requested installation resources below which the cluster autoscaler removes worker nodes.")
+	serverCmd.PersistentFlags().Int64("cluster-autoscaling-min-node-count", 2, "The lowest worker node count the cluster autoscaler will scale a cluster down to. Scaling never crosses the cluster min and max worker configuration values.")
+	serverCmd.PersistentFlags().Int("cluster-autoscaling-cooldown", 900, "The minimum time in seconds between two autoscaling decisions for the same cluster.")
+	serverCmd.PersistentFlags().Bool("cluster-rebalancing-execute", false, "Whether the cluster rebalancer will request installation cluster migrations or only log the proposed moves.")
+	serverCmd.PersistentFlags().Int("cluster-rebalancing-threshold", 90, "The percent threshold above which the cluster rebalancer considers a cluster overloaded.")
+	serverCmd.PersistentFlags().Int("cluster-rebalancing-max-concurrent-migrations", 2, "The maximum number of installation cluster migrations the cluster rebalancer will keep in progress.")
+	serverCmd.PersistentFlags().Int("cluster-rebalancing-interval", 600, "The interval in seconds between cluster rebalancing checks.")
+	serverCmd.PersistentFlags().Bool("multitenant-database-rebalancing-execute", false, "Whether the multitenant database rebalancer will request installation DB migrations or only log the proposed moves.")
+	serverCmd.PersistentFlags().Int("multitenant-database-rebalancing-tolerance", 10, "The percent of a multitenant database capacity by which its weight may exceed the average of its VPC before installations are moved away from it.")
+	serverCmd.PersistentFlags().Int("multitenant-database-rebalancing-max-concurrent-migrations", 1, "The maximum number of installation DB migrations the multitenant database rebalancer will keep in progress.")
+	serverCmd.PersistentFlags().Int("multitenant-database-rebalancing-interval", 3600, "The interval in seconds between multitenant database rebalancing checks.")
+	serverCmd.PersistentFlags().Int("installation-db-metrics-interval", 900, "The interval in seconds between collections of installation database metrics.")
+	serverCmd.PersistentFlags().Int("installation-db-credential-rotation-max-age", 90, "The age in days of installation database credentials after which they are rotated.")
+	serverCmd.PersistentFlags().Int("installation-db-credential-rotation-max-concurrent", 5, "The maximum number of installations rotating their database credentials at the same time.")
+	serverCmd.PersistentFlags().Int("installation-db-credential-rotation-interval", 3600, "The interval in seconds between checks for expired installation database credentials.")
 	serverCmd.PersistentFlags().Bool("use-existing-aws-resources", true, "Whether to use existing AWS resources (VPCs, subnets, etc.) or not.")
 	serverCmd.PersistentFlags().Bool("keep-database-data", true, "Whether to preserve database data after installation deletion or not.")
 	serverCmd.PersistentFlags().Bool("keep-filestore-data", true, "Whether to preserve filestore data after installation deletion or not.")
@@ -89,6 +123,13 @@ func init() {
 	serverCmd.PersistentFlags().String("mattermost-webhook", "", "Set to use a Mattermost webhook for spot instances termination notifications")
 	serverCmd.PersistentFlags().String("mattermost-channel", "", "Set a mattermost channel for spot instances termination notifications")
 	serverCmd.PersistentFlags().String("utilities-git-url", "", "The private git domain to use for utilities. For example https://gitlab.com")
+	serverCmd.PersistentFlags().String("utility-values-directory", "", "The local directory from which values files of utility versions provided in API requests may be read. Local values files are rejected when unset.")
+	serverCmd.PersistentFlags().String("utility-registry", "", "Path to a YAML or JSON file declaring additional Helm based cluster utilities.")
+	serverCmd.PersistentFlags().String("external-postgres-secret", "", "Path to a JSON secret file with the location and admin credentials of the PostgreSQL server used for external-postgres installation databases.")
+	serverCmd.PersistentFlags().String("s3-compatible-filestore-secret", "", "Path to a JSON secret file with the endpoint and root credentials of the object storage server used for s3-compatible installation filestores.")
+	serverCmd.PersistentFlags().Int("multitenant-database-auto-provisioning-threshold", 0, "The percent of the database weight capacity of the multitenant databases in a VPC above which a new multitenant RDS cluster is created. Set to 0 to disable.")
+	serverCmd.PersistentFlags().String("multitenant-database-instance-type", "db.r5.large", "The instance class of the instances of multitenant RDS clusters created by the provisioner.")
+	serverCmd.PersistentFlags().Int("multitenant-database-replicas", 1, "The number of reader instances of multitenant RDS clusters created by the provisioner.")
 	serverCmd.PersistentFlags().Int("max-proxy-db-connections-per-pool", 20, "The maximum number of proxy database connections per pool (logical database).")
 	serverCmd.PersistentFlags().Int("default-proxy-db-pool-size", 5, "The db proxy default pool size per user.")
 	serverCmd.PersistentFlags().Int("min-proxy-db-pool-size", 1, "The db proxy min pool size.")
@@ -178,6 +219,17 @@ var serverCmd = &cobra.Command{
 		}
 		model.SetUtilityDefaults(utilitiesGitURL)
 
+		utilityValuesDirectory, _ := command.Flags().GetString("utility-values-directory")
+		model.SetUtilityValuesDirectory(utilityValuesDirectory)
+
+		utilityRegistryPath, _ := command.Flags().GetString("utility-registry")
+		if utilityRegistryPath != "" {
+			err := loadUtilityRegistry(utilityRegistryPath)
+			if err != nil {
+				return errors.Wrap(err, "failed to load utility registry")
+			}
+		}
+
 		kubecostToken, _ := command.Flags().GetString("kubecost-token")
 		if kubecostToken != "" {
 			os.Setenv(model.KubecostToken, kubecostToken)
@@ -212,6 +264,53 @@ var serverCmd = &cobra.Command{
 			return errors.Errorf("cluster-resource-threshold-scale-value (%d) must be set between 0 and 10", clusterResourceThresholdScaleValue)
 		}
 
+		clusterAutoscaling, _ := command.Flags().GetBool("cluster-autoscaling")
+		clusterAutoscalingScaleUpThreshold, _ := command.Flags().GetInt("cluster-autoscaling-scale-up-threshold")
+		if clusterAutoscalingScaleUpThreshold < 10 || clusterAutoscalingScaleUpThreshold > 100 {
+			return errors.Errorf("cluster-autoscaling-scale-up-threshold (%d) must be set between 10 and 100", clusterAutoscalingScaleUpThreshold)
+		}
+		clusterAutoscalingScaleDownThreshold, _ := command.Flags().GetInt("cluster-autoscaling-scale-down-threshold")
+		if clusterAutoscalingScaleDownThreshold < 0 || clusterAutoscalingScaleDownThreshold >= clusterAutoscalingScaleUpThreshold {
+			return errors.Errorf("cluster-autoscaling-scale-down-threshold (%d) must be set between 0 and the scale up threshold (%d)", clusterAutoscalingScaleDownThreshold, clusterAutoscalingScaleUpThreshold)
+		}
+		clusterAutoscalingMinNodeCount, _ := command.Flags().GetInt64("cluster-autoscaling-min-node-count")
+		if clusterAutoscalingMinNodeCount < 1 {
+			return errors.Errorf("cluster-autoscaling-min-node-count (%d) must be at least 1", clusterAutoscalingMinNodeCount)
+		}
+		clusterAutoscalingCooldown, _ := command.Flags().GetInt("cluster-autoscaling-cooldown")
+
+		clusterRebalancingThreshold, _ := command.Flags().GetInt("cluster-rebalancing-threshold")
+		if clusterRebalancingThreshold < 10 || clusterRebalancingThreshold > 100 {
+			return errors.Errorf("cluster-rebalancing-threshold (%d) must be set between 10 and 100", clusterRebalancingThreshold)
+		}
+		clusterRebalancingMaxConcurrentMigrations, _ := command.Flags().GetInt("cluster-rebalancing-max-concurrent-migrations")
+		if clusterRebalancingMaxConcurrentMigrations < 1 {
+			return errors.Errorf("cluster-rebalancing-max-concurrent-migrations (%d) must be at least 1", clusterRebalancingMaxConcurrentMigrations)
+		}
+		clusterRebalancingExecute, _ := command.Flags().GetBool("cluster-rebalancing-execute")
+		clusterRebalancingInterval, _ := command.Flags().GetInt("cluster-rebalancing-interval")
+
+		databaseRebalancingTolerance, _ := command.Flags().GetInt("multitenant-database-rebalancing-tolerance")
+		if databaseRebalancingTolerance < 0 || databaseRebalancingTolerance > 100 {
+			return errors.Errorf("multitenant-database-rebalancing-tolerance (%d) must be set between 0 and 100", databaseRebalancingTolerance)
+		}
+		databaseRebalancingMaxConcurrentMigrations, _ := command.Flags().GetInt("multitenant-database-rebalancing-max-concurrent-migrations")
+		if databaseRebalancingMaxConcurrentMigrations < 1 {
+			return errors.Errorf("multitenant-database-rebalancing-max-concurrent-migrations (%d) must be at least 1", databaseRebalancingMaxConcurrentMigrations)
+		}
+		databaseRebalancingExecute, _ := command.Flags().GetBool("multitenant-database-rebalancing-execute")
+		databaseRebalancingInterval, _ := command.Flags().GetInt("multitenant-database-rebalancing-interval")
+		installationDBMetricsInterval, _ := command.Flags().GetInt("installation-db-metrics-interval")
+		dbCredentialRotationMaxAge, _ := command.Flags().GetInt("installation-db-credential-rotation-max-age")
+		if dbCredentialRotationMaxAge < 1 {
+			return errors.Errorf("installation-db-credential-rotation-max-age (%d) must be at least 1", dbCredentialRotationMaxAge)
+		}
+		dbCredentialRotationMaxConcurrent, _ := command.Flags().GetInt("installation-db-credential-rotation-max-concurrent")
+		if dbCredentialRotationMaxConcurrent < 1 {
+			return errors.Errorf("installation-db-credential-rotation-max-concurrent (%d) must be at least 1", dbCredentialRotationMaxConcurrent)
+		}
+		dbCredentialRotationInterval, _ := command.Flags().GetInt("installation-db-credential-rotation-interval")
+
 		clusterSupervisor, _ := command.Flags().GetBool("cluster-supervisor")
 		groupSupervisor, _ := command.Flags().GetBool("group-supervisor")
 		installationSupervisor, _ := command.Flags().GetBool("installation-supervisor")
@@ -220,6 +319,14 @@ var serverCmd = &cobra.Command{
 		importSupervisor, _ := command.Flags().GetBool("import-supervisor")
 		installationDBRestorationSupervisor, _ := command.Flags().GetBool("installation-db-restoration-supervisor")
 		installationDBMigrationSupervisor, _ := command.Flags().GetBool("installation-db-migration-supervisor")
+		clusterMigrationSupervisor, _ := command.Flags().GetBool("cluster-migration-supervisor")
+		clusterRebalancer, _ := command.Flags().GetBool("cluster-rebalancer")
+		multitenantDatabaseRebalancer, _ := command.Flags().GetBool("multitenant-database-rebalancer")
+		installationDBMetricsSupervisor, _ := command.Flags().GetBool("installation-db-metrics-supervisor")
+		installationDBCredentialRotationSupervisor, _ := command.Flags().GetBool("installation-db-credential-rotation-supervisor")
+		multitenantDatabaseUpgradeSupervisor, _ := command.Flags().GetBool("multitenant-database-upgrade-supervisor")
+		multitenantDatabaseCapacitySupervisor, _ := command.Flags().GetBool("multitenant-database-capacity-supervisor")
+		clusterPoolSupervisor, _ := command.Flags().GetBool("cluster-pool-supervisor")
 		supervisorsEnabled := []bool{
 			clusterSupervisor,
 			installationSupervisor,
@@ -229,11 +336,28 @@ var serverCmd = &cobra.Command{
 			importSupervisor,
 			installationDBRestorationSupervisor,
 			installationDBMigrationSupervisor,
+			clusterMigrationSupervisor,
+			clusterRebalancer,
+			multitenantDatabaseRebalancer,
+			installationDBMetricsSupervisor,
+			installationDBCredentialRotationSupervisor,
+			multitenantDatabaseUpgradeSupervisor,
+			multitenantDatabaseCapacitySupervisor,
+			clusterPoolSupervisor,
 		}
 		if !isAny(supervisorsEnabled) {
 			logger.Warn("Server will be running with no supervisors. Only API functionality will work.")
 		}
 
+		clusterSizesPath, _ := command.Flags().GetString("cluster-sizes")
+		if clusterSizesPath != "" {
+			clusterSizes, err := loadClusterSizes(clusterSizesPath)
+			if err != nil {
+				return errors.Wrap(err, "failed to load cluster sizes")
+			}
+			clusterdictionary.AddSizes(clusterSizes)
+		}
+
 		s3StateStore, _ := command.Flags().GetString("state-store")
 		keepDatabaseData, _ := command.Flags().GetBool("keep-database-data")
 		keepFilestoreData, _ := command.Flags().GetBool("keep-filestore-data")
@@ -241,6 +365,8 @@ var serverCmd = &cobra.Command{
 		balancedInstallationScheduling, _ := command.Flags().GetBool("balanced-installation-scheduling")
 		backupRestoreToolImage, _ := command.Flags().GetString("backup-restore-tool-image")
 		backupJobTTL, _ := command.Flags().GetInt32("backup-job-ttl-seconds")
+		dbEngineMigrationAssistImage, _ := command.Flags().GetString("db-engine-migration-assist-image")
+		dbEngineMigrationPgloaderImage, _ := command.Flags().GetString("db-engine-migration-pgloader-image")
 
 		deployMySQLOperator, _ := command.Flags().GetBool("deploy-mysql-operator")
 		deployMinioOperator, _ := command.Flags().GetBool("deploy-minio-operator")
@@ -262,34 +388,63 @@ var serverCmd = &cobra.Command{
 		}
 
 		logger.WithFields(logrus.Fields{
-			"build-hash":                             model.BuildHash,
-			"cluster-supervisor":                     clusterSupervisor,
-			"group-supervisor":                       groupSupervisor,
-			"installation-supervisor":                installationSupervisor,
-			"cluster-installation-supervisor":        clusterInstallationSupervisor,
-			"backup-supervisor":                      backupSupervisor,
-			"import-supervisor":                      importSupervisor,
-			"installation-db-restoration-supervisor": installationDBRestorationSupervisor,
-			"installation-db-migration-supervisor":   installationDBMigrationSupervisor,
-			"store-version":                          currentVersion,
-			"state-store":                            s3StateStore,
-			"working-directory":                      wd,
-			"balanced-installation-scheduling":       balancedInstallationScheduling,
-			"cluster-resource-threshold":             clusterResourceThreshold,
-			"cluster-resource-threshold-scale-value": clusterResourceThresholdScaleValue,
-			"use-existing-aws-resources":             useExistingResources,
-			"keep-database-data":                     keepDatabaseData,
-			"keep-filestore-data":                    keepFilestoreData,
-			"force-cr-upgrade":                       forceCRUpgrade,
-			"backup-restore-tool-image":              backupRestoreToolImage,
-			"backup-job-ttl-seconds":                 backupJobTTL,
-			"debug":                                  debugMode,
-			"dev-mode":                               devMode,
-			"deploy-mysql-operator":                  deployMySQLOperator,
-			"deploy-minio-operator":                  deployMinioOperator,
-			"maxDatabaseConnectionsPerPool":          maxDatabaseConnectionsPerPool,
-			"defaultPoolSize":                        defaultPoolSize,
-			"minPoolSize":                            minPoolSize,
+			"build-hash":                                                 model.BuildHash,
+			"cluster-supervisor":                                         clusterSupervisor,
+			"group-supervisor":                                           groupSupervisor,
+			"installation-supervisor":                                    installationSupervisor,
+			"cluster-installation-supervisor":                            clusterInstallationSupervisor,
+			"backup-supervisor":                                          backupSupervisor,
+			"import-supervisor":                                          importSupervisor,
+			"installation-db-restoration-supervisor":                     installationDBRestorationSupervisor,
+			"installation-db-migration-supervisor":                       installationDBMigrationSupervisor,
+			"cluster-migration-supervisor":                               clusterMigrationSupervisor,
+			"cluster-rebalancer":                                         clusterRebalancer,
+			"multitenant-database-rebalancer":                            multitenantDatabaseRebalancer,
+			"installation-db-metrics-supervisor":                         installationDBMetricsSupervisor,
+			"installation-db-credential-rotation-supervisor":             installationDBCredentialRotationSupervisor,
+			"multitenant-database-upgrade-supervisor":                    multitenantDatabaseUpgradeSupervisor,
+			"multitenant-database-capacity-supervisor":                   multitenantDatabaseCapacitySupervisor,
+			"cluster-pool-supervisor":                                    clusterPoolSupervisor,
+			"cluster-sizes":                                              clusterSizesPath,
+			"utility-values-directory":                                   utilityValuesDirectory,
+			"store-version":                                              currentVersion,
+			"state-store":                                                s3StateStore,
+			"working-directory":                                          wd,
+			"balanced-installation-scheduling":                           balancedInstallationScheduling,
+			"cluster-resource-threshold":                                 clusterResourceThreshold,
+			"cluster-resource-threshold-scale-value":                     clusterResourceThresholdScaleValue,
+			"cluster-autoscaling":                                        clusterAutoscaling,
+			"cluster-autoscaling-scale-up-threshold":                     clusterAutoscalingScaleUpThreshold,
+			"cluster-autoscaling-scale-down-threshold":                   clusterAutoscalingScaleDownThreshold,
+			"cluster-autoscaling-min-node-count":                         clusterAutoscalingMinNodeCount,
+			"cluster-autoscaling-cooldown":                               clusterAutoscalingCooldown,
+			"cluster-rebalancing-execute":                                clusterRebalancingExecute,
+			"cluster-rebalancing-threshold":                              clusterRebalancingThreshold,
+			"cluster-rebalancing-max-concurrent-migrations":              clusterRebalancingMaxConcurrentMigrations,
+			"cluster-rebalancing-interval":                               clusterRebalancingInterval,
+			"multitenant-database-rebalancing-execute":                   databaseRebalancingExecute,
+			"multitenant-database-rebalancing-tolerance":                 databaseRebalancingTolerance,
+			"multitenant-database-rebalancing-max-concurrent-migrations": databaseRebalancingMaxConcurrentMigrations,
+			"multitenant-database-rebalancing-interval":                  databaseRebalancingInterval,
+			"installation-db-metrics-interval":                           installationDBMetricsInterval,
+			"installation-db-credential-rotation-max-age":                dbCredentialRotationMaxAge,
+			"installation-db-credential-rotation-max-concurrent":         dbCredentialRotationMaxConcurrent,
+			"installation-db-credential-rotation-interval":               dbCredentialRotationInterval,
+			"use-existing-aws-resources":                                 useExistingResources,
+			"keep-database-data":                                         keepDatabaseData,
+			"keep-filestore-data":                                        keepFilestoreData,
+			"force-cr-upgrade":                                           forceCRUpgrade,
+			"backup-restore-tool-image":                                  backupRestoreToolImage,
+			"backup-job-ttl-seconds":                                     backupJobTTL,
+			"db-engine-migration-assist-image":                           dbEngineMigrationAssistImage,
+			"db-engine-migration-pgloader-image":                         dbEngineMigrationPgloaderImage,
+			"debug":                                                      debugMode,
+			"dev-mode":                                                   devMode,
+			"deploy-mysql-operator":                                      deployMySQLOperator,
+			"deploy-minio-operator":                                      deployMinioOperator,
+			"maxDatabaseConnectionsPerPool":                              maxDatabaseConnectionsPerPool,
+			"defaultPoolSize":                                            defaultPoolSize,
+			"minPoolSize":                                                minPoolSize,
 		}).Info("Starting Mattermost Provisioning Server")
 
 		deprecationWarnings(logger, command)
@@ -317,6 +472,22 @@ var serverCmd = &cobra.Command{
 			return errors.Wrap(err, "failed to build AWS client")
 		}
 
+		multitenantDatabaseThreshold, _ := command.Flags().GetInt("multitenant-database-auto-provisioning-threshold")
+		if multitenantDatabaseThreshold != 0 {
+			multitenantDatabaseInstanceType, _ := command.Flags().GetString("multitenant-database-instance-type")
+			multitenantDatabaseReplicas, _ := command.Flags().GetInt("multitenant-database-replicas")
+			err = awsClient.SetMultitenantDatabaseAutoProvisioning(toolsAWS.MultitenantDatabaseAutoProvisioning{
+				UtilizationThreshold: multitenantDatabaseThreshold,
+				InstanceType:         multitenantDatabaseInstanceType,
+				Replicas:             multitenantDatabaseReplicas,
+			})
+			if err != nil {
+				return err
+			}
+		} else if multitenantDatabaseCapacitySupervisor {
+			return errors.New("--multitenant-database-auto-provisioning-threshold flag must be provided when --multitenant-database-capacity-supervisor flag is provided")
+		}
+
 		err = checkRequirements(logger)
 		if err != nil {
 			return errors.Wrap(err, "failed health check")
@@ -324,6 +495,24 @@ var serverCmd = &cobra.Command{
 
 		resourceUtil := utils.NewResourceUtil(instanceID, awsClient)
 
+		externalPostgresSecret, _ := command.Flags().GetString("external-postgres-secret")
+		if externalPostgresSecret != "" {
+			externalPostgresServer, err := postgres.NewServerConfigFromFile(externalPostgresSecret)
+			if err != nil {
+				return errors.Wrap(err, "failed to load external postgres server secret")
+			}
+			resourceUtil.SetExternalPostgresServer(externalPostgresServer)
+		}
+
+		s3CompatibleFilestoreSecret, _ := command.Flags().GetString("s3-compatible-filestore-secret")
+		if s3CompatibleFilestoreSecret != "" {
+			s3CompatibleServer, err := s3compat.NewServerConfigFromFile(s3CompatibleFilestoreSecret)
+			if err != nil {
+				return errors.Wrap(err, "failed to load S3-compatible filestore server secret")
+			}
+			resourceUtil.SetS3CompatibleFilestoreServer(s3CompatibleServer)
+		}
+
 		provisioningParams := provisioner.ProvisioningParams{
 			S3StateStore:            s3StateStore,
 			AllowCIDRRangeList:      allowListCIDRRange,
@@ -341,9 +530,13 @@ var serverCmd = &cobra.Command{
 			logger,
 			sqlStore,
 			provisioner.NewBackupOperator(backupRestoreToolImage, awsRegion, backupJobTTL),
+			provisioner.NewDBEngineMigrationOperator(dbEngineMigrationAssistImage, dbEngineMigrationPgloaderImage, backupJobTTL),
 		)
 		defer kopsProvisioner.Teardown()
 
+		eksProvisioner := provisioner.NewEKSProvisioner(provisioningParams, awsClient, logger)
+		clusterProvisioner := provisioner.NewClusterProvisionerRouter(kopsProvisioner, eksProvisioner)
+
 		cloudMetrics := metrics.New()
 
 		delivererCfg := events.DelivererConfig{
@@ -359,7 +552,14 @@ var serverCmd = &cobra.Command{
 
 		var multiDoer supervisor.MultiDoer
 		if clusterSupervisor {
-			multiDoer = append(multiDoer, supervisor.NewClusterSupervisor(sqlStore, kopsProvisioner, awsClient, eventsProducer, instanceID, logger))
+			autoscalingOptions := supervisor.ClusterAutoscalingOptions{
+				Enabled:            clusterAutoscaling,
+				ScaleUpThreshold:   clusterAutoscalingScaleUpThreshold,
+				ScaleDownThreshold: clusterAutoscalingScaleDownThreshold,
+				MinNodeCount:       clusterAutoscalingMinNodeCount,
+				Cooldown:           time.Duration(clusterAutoscalingCooldown) * time.Second,
+			}
+			multiDoer = append(multiDoer, supervisor.NewClusterSupervisor(sqlStore, clusterProvisioner, awsClient, eventsProducer, autoscalingOptions, instanceID, logger))
 		}
 		if groupSupervisor {
 			multiDoer = append(multiDoer, supervisor.NewGroupSupervisor(sqlStore, eventsProducer, instanceID, logger))
@@ -387,6 +587,58 @@ var serverCmd = &cobra.Command{
 		if installationDBMigrationSupervisor {
 			multiDoer = append(multiDoer, supervisor.NewInstallationDBMigrationSupervisor(sqlStore, awsClient, resourceUtil, instanceID, kopsProvisioner, eventsProducer, logger))
 		}
+		if clusterMigrationSupervisor {
+			multiDoer = append(multiDoer, supervisor.NewClusterMigrationSupervisor(sqlStore, awsClient, instanceID, eventsProducer, logger))
+		}
+		if clusterRebalancer {
+			rebalancerOptions := supervisor.ClusterRebalancerOptions{
+				Execute:                 clusterRebalancingExecute,
+				UtilizationThreshold:    clusterRebalancingThreshold,
+				MaxConcurrentMigrations: clusterRebalancingMaxConcurrentMigrations,
+				Interval:                time.Duration(clusterRebalancingInterval) * time.Second,
+			}
+			multiDoer = append(multiDoer, supervisor.NewClusterRebalancer(sqlStore, clusterProvisioner, rebalancerOptions, instanceID, logger))
+		}
+		if multitenantDatabaseRebalancer {
+			databaseRebalancerOptions := supervisor.MultitenantDatabaseRebalancerOptions{
+				Execute:                 databaseRebalancingExecute,
+				Tolerance:               databaseRebalancingTolerance,
+				MaxConcurrentMigrations: databaseRebalancingMaxConcurrentMigrations,
+				Interval:                time.Duration(databaseRebalancingInterval) * time.Second,
+			}
+			multiDoer = append(multiDoer, supervisor.NewMultitenantDatabaseRebalancer(sqlStore, databaseRebalancerOptions, instanceID, logger))
+		}
+		if installationDBMetricsSupervisor {
+			multiDoer = append(multiDoer, supervisor.NewInstallationDatabaseMetricsSupervisor(sqlStore, awsClient, time.Duration(installationDBMetricsInterval)*time.Second, logger))
+		}
+		if installationDBCredentialRotationSupervisor {
+			credentialRotationOptions := supervisor.InstallationDatabaseCredentialRotationOptions{
+				MaxAge:                 time.Duration(dbCredentialRotationMaxAge) * 24 * time.Hour,
+				MaxConcurrentRotations: dbCredentialRotationMaxConcurrent,
+				Interval:               time.Duration(dbCredentialRotationInterval) * time.Second,
+			}
+			multiDoer = append(multiDoer, supervisor.NewInstallationDatabaseCredentialRotationSupervisor(sqlStore, credentialRotationOptions, instanceID, eventsProducer, logger))
+		}
+		if multitenantDatabaseUpgradeSupervisor {
+			multiDoer = append(multiDoer, supervisor.NewMultitenantDatabaseUpgradeSupervisor(sqlStore, awsClient, instanceID, logger))
+		}
+		if multitenantDatabaseCapacitySupervisor {
+			multiDoer = append(multiDoer, supervisor.NewMultitenantDatabaseCapacitySupervisor(sqlStore, awsClient, instanceID, logger))
+		}
+		if clusterPoolSupervisor {
+			clusterPoolTemplates, _ := command.Flags().GetStringSlice("cluster-pool-templates")
+			if len(clusterPoolTemplates) == 0 {
+				return errors.New("--cluster-pool-templates flag must be provided when --cluster-pool-supervisor flag is provided")
+			}
+			clusterPoolMaxClustersPerTemplate, _ := command.Flags().GetInt("cluster-pool-max-clusters-per-template")
+			clusterPoolStableClusterGracePeriod, _ := command.Flags().GetInt("cluster-pool-stable-cluster-grace-period")
+			clusterPoolOptions := supervisor.ClusterPoolOptions{
+				Templates:                clusterPoolTemplates,
+				MaxClustersPerTemplate:   clusterPoolMaxClustersPerTemplate,
+				StableClusterGracePeriod: time.Duration(clusterPoolStableClusterGracePeriod) * time.Second,
+			}
+			multiDoer = append(multiDoer, supervisor.NewClusterPoolSupervisor(sqlStore, clusterPoolOptions, eventsProducer, instanceID, logger))
+		}
 
 		// Setup the supervisor to effect any requested changes. It is wrapped in a
 		// scheduler to trigger it periodically in addition to being poked by the API
@@ -604,3 +856,29 @@ func isAny(conditions []bool) bool {
 	}
 	return false
 }
+
+// loadClusterSizes reads and validates cluster sizes from the given JSON file.
+func loadClusterSizes(path string) ([]*model.ClusterSize, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open cluster sizes file")
+	}
+	defer file.Close()
+
+	return model.NewClusterSizesFromReader(file)
+}
+
+func loadUtilityRegistry(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	config, err := model.NewUtilityRegistryConfigFromReader(file)
+	if err != nil {
+		return err
+	}
+
+	return model.SetUtilityRegistry(config.Utilities)
+}
@@ -13,6 +13,8 @@ func Register(rootRouter *mux.Router, context *Context) {
 	// api handler at /api
 	apiRouter := rootRouter.PathPrefix("/api").Subrouter()
 	initCluster(apiRouter, context)
+	initClusterTemplate(apiRouter, context)
+	initClusterSize(apiRouter, context)
 	initInstallation(apiRouter, context)
 	initClusterInstallation(apiRouter, context)
 	initGroup(apiRouter, context)
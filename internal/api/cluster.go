@@ -7,6 +7,7 @@ package api
 import (
 	"net/http"
 
+	"github.com/mattermost/mattermost-cloud/internal/common"
 	"github.com/mattermost/mattermost-cloud/internal/store"
 	"github.com/pkg/errors"
 
@@ -32,6 +33,8 @@ func initCluster(apiRouter *mux.Router, context *Context) {
 	clusterRouter.Handle("/kubernetes", addContext(handleUpgradeKubernetes)).Methods("PUT")
 	clusterRouter.Handle("/size", addContext(handleResizeCluster)).Methods("PUT")
 	clusterRouter.Handle("/utilities", addContext(handleGetAllUtilityMetadata)).Methods("GET")
+	clusterRouter.Handle("/utilities/{utility-name}", addContext(handleUpgradeClusterUtility)).Methods("PUT")
+	clusterRouter.Handle("/utilities/{utility-name}/values", addContext(handleGetClusterUtilityValues)).Methods("GET")
 	clusterRouter.Handle("/annotations", addContext(handleAddClusterAnnotations)).Methods("POST")
 	clusterRouter.Handle("/annotation/{annotation-name}", addContext(handleDeleteClusterAnnotation)).Methods("DELETE")
 	clusterRouter.Handle("", addContext(handleDeleteCluster)).Methods("DELETE")
@@ -98,6 +101,9 @@ func handleGetClusters(c *Context, w http.ResponseWriter, r *http.Request) {
 //		"zones": "",
 //		"allow-installations": true
 // }
+//
+// When a "template" name or ID is provided, values missing from the request
+// are taken from the cluster template.
 func handleCreateCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	createClusterRequest, err := model.NewCreateClusterRequestFromReader(r.Body)
 	if err != nil {
@@ -106,52 +112,13 @@ func handleCreateCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cluster := model.Cluster{
-		Provider: createClusterRequest.Provider,
-		ProviderMetadataAWS: &model.AWSMetadata{
-			Zones: createClusterRequest.Zones,
-		},
-		Provisioner: "kops",
-		ProvisionerMetadataKops: &model.KopsMetadata{
-			ChangeRequest: &model.KopsMetadataRequestedState{
-				Version:            createClusterRequest.Version,
-				AMI:                createClusterRequest.KopsAMI,
-				MasterInstanceType: createClusterRequest.MasterInstanceType,
-				MasterCount:        createClusterRequest.MasterCount,
-				NodeInstanceType:   createClusterRequest.NodeInstanceType,
-				NodeMinCount:       createClusterRequest.NodeMinCount,
-				NodeMaxCount:       createClusterRequest.NodeMaxCount,
-				Networking:         createClusterRequest.Networking,
-				VPC:                createClusterRequest.VPC,
-			},
-		},
-
-		AllowInstallations: createClusterRequest.AllowInstallations,
-		APISecurityLock:    createClusterRequest.APISecurityLock,
-		State:              model.ClusterStateCreationRequested,
-	}
-
-	cluster.SetUtilityDesiredVersions(createClusterRequest.DesiredUtilityVersions)
-
-	annotations, err := model.AnnotationsFromStringSlice(createClusterRequest.Annotations)
-	if err != nil {
-		c.Logger.WithError(err).Error("failed to validate extra annotations")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	err = c.Store.CreateCluster(&cluster, annotations)
+	cluster, annotations, err := common.CreateCluster(c.Store, createClusterRequest, c.EventProducer, c.Logger)
 	if err != nil {
 		c.Logger.WithError(err).Error("failed to create cluster")
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(common.ErrToStatus(err))
 		return
 	}
 
-	err = c.EventProducer.ProduceClusterStateChangeEvent(&cluster, model.NonApplicableState)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to create cluster state change event")
-	}
-
 	c.Supervisor.Do()
 
 	w.Header().Set("Content-Type", "application/json")
@@ -287,6 +254,12 @@ func handleUpdateClusterConfiguration(c *Context, w http.ResponseWriter, r *http
 		return
 	}
 
+	if updateClusterRequest.AllowInstallations && clusterDTO.IsEKS() {
+		c.Logger.Error("installations are not supported on EKS clusters yet")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	if clusterDTO.AllowInstallations != updateClusterRequest.AllowInstallations {
 		clusterDTO.AllowInstallations = updateClusterRequest.AllowInstallations
 		err := c.Store.UpdateCluster(clusterDTO.Cluster)
@@ -329,7 +302,19 @@ func handleUpgradeKubernetes(c *Context, w http.ResponseWriter, r *http.Request)
 
 	oldState := clusterDTO.State
 
-	if upgradeClusterRequest.Apply(clusterDTO.ProvisionerMetadataKops) {
+	var applied bool
+	if clusterDTO.IsEKS() {
+		if upgradeClusterRequest.KopsAMI != nil {
+			c.Logger.Error("kops AMI can't be set on EKS clusters")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		applied = upgradeClusterRequest.ApplyToEKS(clusterDTO.ProvisionerMetadataEKS)
+	} else {
+		applied = upgradeClusterRequest.Apply(clusterDTO.ProvisionerMetadataKops)
+	}
+
+	if applied {
 		clusterDTO.State = newState
 		err := c.Store.UpdateCluster(clusterDTO.Cluster)
 		if err != nil {
@@ -354,6 +339,118 @@ func handleUpgradeKubernetes(c *Context, w http.ResponseWriter, r *http.Request)
 	outputJSON(c, w, clusterDTO)
 }
 
+// handleUpgradeClusterUtility responds to PUT /api/cluster/{cluster}/utilities/{utility-name},
+// upgrading or previewing the upgrade of a single utility of the cluster.
+func handleUpgradeClusterUtility(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterID := vars["cluster"]
+	utilityName := vars["utility-name"]
+	c.Logger = c.Logger.WithField("cluster", clusterID).WithField("utility", utilityName)
+
+	if !model.IsValidUtilityName(utilityName) {
+		c.Logger.Errorf("unknown utility %s", utilityName)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	upgradeUtilityRequest, err := model.NewUpgradeClusterUtilityRequestFromReader(r.Body)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	newState := model.ClusterStateUtilityUpgradeRequested
+
+	clusterDTO, status, unlockOnce := getClusterForTransition(c, clusterID, newState)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	defer unlockOnce()
+
+	if clusterDTO.IsEKS() {
+		c.Logger.Error("utilities of EKS clusters can't be upgraded")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	oldState := clusterDTO.State
+
+	clusterDTO.RequestUtilityUpgrade(utilityName, upgradeUtilityRequest)
+	clusterDTO.State = newState
+	err = c.Store.UpdateCluster(clusterDTO.Cluster)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to update cluster")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if oldState != newState {
+		err = c.EventProducer.ProduceClusterStateChangeEvent(clusterDTO.Cluster, oldState)
+		if err != nil {
+			c.Logger.WithError(err).Error("Failed to create cluster state change event")
+		}
+	}
+
+	unlockOnce()
+	c.Supervisor.Do()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	outputJSON(c, w, clusterDTO)
+}
+
+// handleGetClusterUtilityValues responds to GET /api/cluster/{cluster}/utilities/{utility-name}/values,
+// returning the values of the utility rendered with the cluster context.
+func handleGetClusterUtilityValues(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterID := vars["cluster"]
+	utilityName := vars["utility-name"]
+	c.Logger = c.Logger.WithField("cluster", clusterID).WithField("utility", utilityName)
+
+	if !model.IsValidUtilityName(utilityName) {
+		c.Logger.Errorf("unknown utility %s", utilityName)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	cluster, err := c.Store.GetCluster(clusterID)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if cluster == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if cluster.IsEKS() {
+		c.Logger.Error("utility values of EKS clusters can't be rendered")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	valuesContext, err := c.AwsClient.GetUtilityValuesContext(cluster.ID, c.Logger)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to get the cluster context of the utility values")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	values, err := c.Provisioner.RenderClusterUtilityValues(cluster, utilityName, valuesContext)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to render utility values")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, values)
+}
+
 // handleResizeCluster responds to PUT /api/cluster/{cluster}/size,
 // resizing the cluster.
 func handleResizeCluster(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -368,6 +465,33 @@ func handleResizeCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(resizeClusterRequest.Size) != 0 {
+		clusterSize, err := common.GetClusterSize(c.Store, resizeClusterRequest.Size)
+		if err != nil {
+			c.Logger.WithError(err).Error("failed to query cluster size")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if clusterSize == nil {
+			c.Logger.Errorf("cluster size %s not found", resizeClusterRequest.Size)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if _, ok := clusterSize.AdditionalNodeGroups[resizeClusterRequest.NodeGroup]; len(resizeClusterRequest.NodeGroup) != 0 && !ok {
+			c.Logger.Errorf("cluster size %s has no node group %s", resizeClusterRequest.Size, resizeClusterRequest.NodeGroup)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		clusterSize.ApplyToPatchClusterSizeRequest(resizeClusterRequest)
+
+		err = resizeClusterRequest.Validate()
+		if err != nil {
+			c.Logger.WithError(err).Error("resize request based on cluster size failed validation")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
 	newState := model.ClusterStateResizeRequested
 
 	clusterDTO, status, unlockOnce := getClusterForTransition(c, clusterID, newState)
@@ -377,10 +501,25 @@ func handleResizeCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	}
 	defer unlockOnce()
 
-	// One more check that can't be done without both the request and the cluster.
+	if clusterDTO.IsEKS() {
+		resizeEKSCluster(c, w, clusterDTO, resizeClusterRequest, unlockOnce)
+		return
+	}
+
+	// A few more checks that can't be done without both the request and the cluster.
+	currentNodeMinCount := clusterDTO.ProvisionerMetadataKops.NodeMinCount
+	if len(resizeClusterRequest.NodeGroup) != 0 {
+		nodeGroup, ok := clusterDTO.ProvisionerMetadataKops.GetNodeGroup(resizeClusterRequest.NodeGroup)
+		if !ok {
+			c.Logger.Errorf("cluster has no node group %s", resizeClusterRequest.NodeGroup)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		currentNodeMinCount = nodeGroup.NodeMinCount
+	}
 	if resizeClusterRequest.NodeMinCount == nil &&
 		resizeClusterRequest.NodeMaxCount != nil &&
-		*resizeClusterRequest.NodeMaxCount < clusterDTO.ProvisionerMetadataKops.NodeMinCount {
+		*resizeClusterRequest.NodeMaxCount < currentNodeMinCount {
 		c.Logger.Error("resize patch would set max node count lower than min node count")
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -413,6 +552,50 @@ func handleResizeCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	outputJSON(c, w, clusterDTO)
 }
 
+// resizeEKSCluster applies a resize request to an EKS cluster. EKS clusters
+// have a single worker node group, so node groups can't be targeted.
+func resizeEKSCluster(c *Context, w http.ResponseWriter, clusterDTO *model.ClusterDTO, resizeClusterRequest *model.PatchClusterSizeRequest, unlockOnce func()) {
+	if len(resizeClusterRequest.NodeGroup) != 0 {
+		c.Logger.Error("node groups can't be resized on EKS clusters")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if resizeClusterRequest.NodeMinCount == nil &&
+		resizeClusterRequest.NodeMaxCount != nil &&
+		*resizeClusterRequest.NodeMaxCount < clusterDTO.ProvisionerMetadataEKS.NodeMinCount {
+		c.Logger.Error("resize patch would set max node count lower than min node count")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	newState := model.ClusterStateResizeRequested
+	oldState := clusterDTO.State
+
+	if resizeClusterRequest.ApplyToEKS(clusterDTO.ProvisionerMetadataEKS) {
+		clusterDTO.State = newState
+		err := c.Store.UpdateCluster(clusterDTO.Cluster)
+		if err != nil {
+			c.Logger.WithError(err).Error("failed to update cluster")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if oldState != newState {
+			err = c.EventProducer.ProduceClusterStateChangeEvent(clusterDTO.Cluster, oldState)
+			if err != nil {
+				c.Logger.WithError(err).Error("Failed to create cluster state change event")
+			}
+		}
+	}
+
+	unlockOnce()
+	c.Supervisor.Do()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	outputJSON(c, w, clusterDTO)
+}
+
 // handleDeleteCluster responds to DELETE /api/cluster/{cluster}, beginning the process of
 // deleting the cluster.
 func handleDeleteCluster(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -9,10 +9,7 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
-	"github.com/mattermost/mattermost-cloud/internal/common"
 	"github.com/mattermost/mattermost-cloud/model"
-	"github.com/pkg/errors"
-	log "github.com/sirupsen/logrus"
 )
 
 // initClusterInstallation registers cluster installation endpoints on the given router.
@@ -22,12 +19,11 @@ func initClusterInstallation(apiRouter *mux.Router, context *Context) {
 	}
 
 	clusterInstallationsRouter := apiRouter.PathPrefix("/cluster_installations").Subrouter()
+	initClusterMigration(clusterInstallationsRouter, context)
+
 	clusterInstallationsRouter.Handle("", addContext(handleGetClusterInstallations)).Methods("GET")
-	clusterInstallationsRouter.Handle("/migrate", addContext(handleMigrateClusterInstallations)).Methods("POST")
-	clusterInstallationsRouter.Handle("/migrate/dns", addContext(handleMigrateDNS)).Methods("POST")
 	clusterInstallationsRouter.Handle("/migrate/delete_inactive/{clusterID}", addContext(handleDeleteInActiveClusterInstallationsByCluster)).Methods("DELETE")
 	clusterInstallationsRouter.Handle("/migrate/delete_inactive/cluster_installation/{ClusterInstallationID}", addContext(handleDeleteInActiveClusterInstallationByID)).Methods("DELETE")
-	clusterInstallationsRouter.Handle("/migrate/switch_cluster_roles", addContext(handleSwitchClusterRoles)).Methods("POST")
 
 	clusterInstallationRouter := apiRouter.PathPrefix("/cluster_installation/{cluster_installation:[A-Za-z0-9]{26}}").Subrouter()
 	clusterInstallationRouter.Handle("", addContext(handleGetClusterInstallation)).Methods("GET")
@@ -370,160 +366,6 @@ func handleRunClusterInstallationMattermostCLI(c *Context, w http.ResponseWriter
 	w.Write(output)
 }
 
-// handleMigrateClusterInstallations responds to Post /api/cluster_installation/migrate.
-func handleMigrateClusterInstallations(c *Context, w http.ResponseWriter, r *http.Request) {
-	mcir, err := model.NewMigrateClusterInstallationRequestFromReader(r.Body)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to decode cluster migration request", mcir)
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	c.Logger = c.Logger.WithFields(log.Fields{
-		"source-cluster-id": mcir.SourceClusterID,
-		"target-cluster-id": mcir.TargetClusterID,
-	})
-
-	sourceCluster, _, err := getSourceAndTargetCluster(c, mcir)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to get source and target clusters")
-		w.WriteHeader(common.ErrToStatus(err))
-		return
-	}
-
-	// verify that the allows installation is false for the source cluster before migration starts
-	if sourceCluster.AllowInstallations {
-		c.Logger.Error("Allow installation must be set to false for the source cluster.")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	// Get the CIs for migration
-	clusterInstallations, status := GetClusterInstallationsForMigration(c, mcir)
-	if status != 0 {
-		c.Logger.WithError(err).Error("Failed to get CIs for migration")
-		w.WriteHeader(status)
-		return
-	}
-
-	// Migrate the cluster installations to the target cluster
-	c.Logger.Infof("Migrating installation(s) to clusterID: %s", mcir.TargetClusterID)
-	err = c.Store.MigrateClusterInstallations(clusterInstallations, mcir.TargetClusterID)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to migrate cluster installation(s)")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	migrateClusterInstallationResponse := getMigrateClusterInstallationResponse(mcir.SourceClusterID, mcir.TargetClusterID, model.OperationTypeMigration, len(clusterInstallations))
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	outputJSON(c, w, migrateClusterInstallationResponse)
-}
-
-// handleMigrateDns responds to Post /api/cluster_installation/migrate/dns.
-func handleMigrateDNS(c *Context, w http.ResponseWriter, r *http.Request) {
-	mcir, err := model.NewMigrateClusterInstallationRequestFromReader(r.Body)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to decode cluster migration request", mcir)
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	c.Logger = c.Logger.WithFields(log.Fields{
-		"source-cluster-id": mcir.SourceClusterID,
-		"target-cluster-id": mcir.TargetClusterID,
-	})
-
-	// Reset the DNS configuration status for respective installations to update the CNAME with the new LB.
-	IsActive := true
-	filter := &model.ClusterInstallationFilter{
-		ClusterID:      mcir.SourceClusterID,
-		InstallationID: mcir.InstallationID,
-		IsActive:       &IsActive,
-		Paging:         model.AllPagesNotDeleted(),
-	}
-	clusterInstallations, err := c.Store.GetClusterInstallations(filter)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to query cluster installations")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if len(clusterInstallations) == 0 {
-		c.Logger.Error("No matching cluster installations found")
-		w.WriteHeader(http.StatusNotFound)
-		return
-	}
-
-	IsActive = false
-	filter = &model.ClusterInstallationFilter{
-		ClusterID:      mcir.TargetClusterID,
-		InstallationID: mcir.InstallationID,
-		IsActive:       &IsActive,
-		Paging:         model.AllPagesNotDeleted(),
-	}
-	newClusterInstallations, err := c.Store.GetClusterInstallations(filter)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to query cluster installations")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	if len(newClusterInstallations) == 0 {
-		c.Logger.Error("No matching cluster installations found")
-		w.WriteHeader(http.StatusNotFound)
-		return
-	}
-
-	sourceCluster, _, err := getSourceAndTargetCluster(c, mcir)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to get source and target clusters")
-		w.WriteHeader(common.ErrToStatus(err))
-		return
-	}
-
-	// verify that the allows installation is false for the source cluster before migration starts
-	if sourceCluster.AllowInstallations {
-		c.Logger.Error("Allow installation must be set to false for the source cluster.")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-
-	// DNS Switch
-	clusterInstallationIDs := getClusterInstallationIDs(clusterInstallations)
-	newClusterInstallationIDs := getClusterInstallationIDs(newClusterInstallations)
-	var installationIDs, hibernatedInstallationIDs []string
-
-	for _, ci := range clusterInstallations {
-		installation, err := c.Store.GetInstallation(ci.InstallationID, false, false)
-		if err != nil {
-			c.Logger.WithError(err).Errorf("Failed to get refreshed installation")
-			return
-		}
-		if installation.State == model.InstallationStateHibernating {
-			hibernatedInstallationIDs = append(hibernatedInstallationIDs, ci.InstallationID)
-		} else {
-			installationIDs = append(installationIDs, ci.InstallationID)
-		}
-	}
-
-	totalInstallations := len(installationIDs) + len(hibernatedInstallationIDs)
-	c.Logger.Infof("Total DNS records to migrate: %d", totalInstallations)
-	if totalInstallations == 0 {
-		c.Logger.Error("No installation(s) found for DNS  migration")
-		w.WriteHeader(http.StatusNotFound)
-	}
-	status := dnsMigration(c, mcir, clusterInstallationIDs, newClusterInstallationIDs, installationIDs, hibernatedInstallationIDs)
-	if status != 0 {
-		c.Logger.Error("Failed to migrate DNS records")
-		w.WriteHeader(status)
-		return
-	}
-
-	migrateClusterInstallationResponse := getMigrateClusterInstallationResponse(mcir.SourceClusterID, mcir.TargetClusterID, model.OperationTypeDNS, len(clusterInstallations))
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	outputJSON(c, w, migrateClusterInstallationResponse)
-}
-
 // handleDeleteInActiveClusterInstallationsByCluster responds to Delete /api/cluster_installation/migrate/delete_inactive/clusterID.
 func handleDeleteInActiveClusterInstallationsByCluster(c *Context, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -583,144 +425,6 @@ func handleDeleteInActiveClusterInstallationByID(c *Context, w http.ResponseWrit
 	outputJSON(c, w, clusterInstallation)
 }
 
-func dnsMigration(c *Context, mcir model.MigrateClusterInstallationRequest, oldClusterInstallationIDs []string, newClusterInstallationIDs []string, installationIDs []string, hibernatingInstallationIDs []string) int {
-	if mcir.LockInstallation {
-		installationsToLock := append(installationIDs, hibernatingInstallationIDs...)
-		c.Logger.Infof("Locking %d installation(s) ", len(installationsToLock))
-		locked, err := c.Store.LockInstallations(installationsToLock, c.RequestID)
-		if err != nil {
-			c.Logger.WithError(err).Error("Failed to lock installation")
-			return http.StatusInternalServerError
-		} else if !locked {
-			c.Logger.Error("Failed to acquire lock for installation")
-			return http.StatusInternalServerError
-		}
-		defer func() {
-			unlocked, err := c.Store.UnlockInstallations(installationsToLock, c.RequestID, false)
-			if err != nil {
-				c.Logger.WithError(err).Errorf("Failed to unlock installation")
-			} else if !unlocked {
-				c.Logger.Warn("Failed to release lock for installation")
-			}
-		}()
-	}
-
-	err := c.Store.SwitchDNS(oldClusterInstallationIDs, newClusterInstallationIDs, installationIDs, hibernatingInstallationIDs)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to migrate DNS records")
-		return http.StatusInternalServerError
-	}
-
-	c.Logger.Infof("DNS Switch over has been processed for cluster %s: ", mcir.SourceClusterID)
-	return 0
-}
-
-func getClusterInstallationIDs(clusterInstallations []*model.ClusterInstallation) []string {
-	clusterInstallationIDs := make([]string, 0, len(clusterInstallations))
-	for _, clusterInstallation := range clusterInstallations {
-		clusterInstallationIDs = append(clusterInstallationIDs, clusterInstallation.ID)
-	}
-	return clusterInstallationIDs
-}
-
-// handleSwitchClusterRoles responds to Post /api/cluster_installations/migrate/switch_cluster_roles.
-func handleSwitchClusterRoles(c *Context, w http.ResponseWriter, r *http.Request) {
-	mcir, err := model.NewMigrateClusterInstallationRequestFromReader(r.Body)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to decode cluster migration request")
-		w.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	c.Logger = c.Logger.WithField("cluster_installation", mcir)
-
-	err = c.AwsClient.SwitchClusterTags(mcir.SourceClusterID, mcir.TargetClusterID, c.Logger)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to switch cluster tags")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-	migrateClusterInstallationResponse := getMigrateClusterInstallationResponse(mcir.SourceClusterID, mcir.TargetClusterID, model.OperationTypeSwitchClusterRoles, 0)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	outputJSON(c, w, migrateClusterInstallationResponse)
-}
-
-func getSourceAndTargetCluster(c *Context, request model.MigrateClusterInstallationRequest) (*model.Cluster, *model.Cluster, error) {
-	sourceCluster, err := c.Store.GetCluster(request.SourceClusterID)
-	if err != nil {
-		return nil, nil, common.ErrWrap(http.StatusInternalServerError, err, "failed to get source cluster")
-	}
-	if sourceCluster == nil {
-		return nil, nil, common.NewErr(http.StatusNotFound, errors.New("source cluster not found"))
-	}
-
-	targetCluster, err := c.Store.GetCluster(request.TargetClusterID)
-	if err != nil {
-		return nil, nil, common.ErrWrap(http.StatusInternalServerError, err, "failed to get target cluster")
-	}
-	if targetCluster == nil {
-		return nil, nil, common.NewErr(http.StatusNotFound, errors.New("target cluster not found"))
-	}
-	return sourceCluster, targetCluster, nil
-}
-
-// GetClusterInstallationsForMigration compare , filter already migrated installations & returns actual set of CIs for migration
-func GetClusterInstallationsForMigration(c *Context, request model.MigrateClusterInstallationRequest) ([]*model.ClusterInstallation, int) {
-	// Skip already migrated CIs if there is any
-	sourceActiveCIs := true
-	toMigrateFilter := &model.ClusterInstallationFilter{
-		ClusterID:      request.SourceClusterID,
-		InstallationID: request.InstallationID,
-		IsActive:       &sourceActiveCIs,
-		Paging:         model.AllPagesNotDeleted(),
-	}
-
-	// Get only those CIs for which migration is not completed yet.
-	targetActiveCIs := false
-	alredyMigratedFilter := &model.ClusterInstallationFilter{
-		ClusterID:      request.TargetClusterID,
-		InstallationID: request.InstallationID,
-		IsActive:       &targetActiveCIs,
-		Paging:         model.AllPagesNotDeleted(),
-	}
-
-	sourceClusterCIs, err := c.Store.GetClusterInstallations(toMigrateFilter)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to query cluster installations")
-		return nil, http.StatusInternalServerError
-	}
-	if len(sourceClusterCIs) == 0 {
-		c.Logger.WithError(err).Error("No matching cluster installations found")
-		return nil, http.StatusNotFound
-	}
-
-	migratedCIs, err := c.Store.GetClusterInstallations(alredyMigratedFilter)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to query cluster installations")
-		return nil, http.StatusInternalServerError
-	}
-
-	// Skip comparison if there is no matching CIs in the target cluster
-	if len(migratedCIs) == 0 {
-		return sourceClusterCIs, 0
-	}
-
-	toMigrate := []*model.ClusterInstallation{}
-	for _, ci := range sourceClusterCIs {
-		migrate := true
-		for _, migrated := range migratedCIs {
-			if ci.InstallationID == migrated.InstallationID {
-				migrate = false
-				break
-			}
-		}
-		if migrate {
-			toMigrate = append(toMigrate, ci)
-		}
-	}
-	return toMigrate, 0
-}
-
 func getMigrateClusterInstallationResponse(sourceClusterID string, tergetClusterID string, operationType string, noOfCIs int) model.MigrateClusterInstallationResponse {
 	return model.MigrateClusterInstallationResponse{
 		SourceClusterID:           sourceClusterID,
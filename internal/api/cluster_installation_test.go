@@ -558,608 +558,6 @@ func TestRunClusterInstallationMattermostCLI(t *testing.T) {
 	})
 }
 
-func TestMigrateClusterInstallations(t *testing.T) {
-	logger := testlib.MakeLogger(t)
-	sqlStore := store.MakeTestSQLStore(t, logger)
-
-	router := mux.NewRouter()
-	context := &api.Context{
-		Store:         sqlStore,
-		Supervisor:    &mockSupervisor{},
-		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
-		Logger:        logger,
-	}
-	api.Register(router, context)
-	ts := httptest.NewServer(router)
-	defer ts.Close()
-
-	model.SetDeployOperators(true, true)
-
-	t.Run("invalid payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate", ts.URL), "application/json", bytes.NewReader([]byte("invalid")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	t.Run("empty payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate", ts.URL), "application/json", bytes.NewReader([]byte("")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	client := model.NewClient(ts.URL)
-	t.Run("missing source cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "", TargetClusterID: "4567"})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("missing target cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: ""})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("no cluster installation found to migrate", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: "67899"})
-		require.EqualError(t, err, "failed with status code 404")
-	})
-
-	// Valid migration test
-	installation1, err := client.CreateInstallation(&model.CreateInstallationRequest{
-		OwnerID:  "owner1",
-		Version:  "version",
-		Image:    "custom-image",
-		DNS:      "dns1.example.com",
-		Affinity: model.InstallationAffinityIsolated,
-	})
-	require.NoError(t, err)
-
-	time.Sleep(1 * time.Millisecond)
-
-	installation2, err := client.CreateInstallation(&model.CreateInstallationRequest{
-		OwnerID:  "owner2",
-		Version:  "version",
-		Image:    "custom-image",
-		DNS:      "dns2.example.com",
-		Affinity: model.InstallationAffinityIsolated,
-	})
-	require.NoError(t, err)
-	sourceCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, sourceCluster.ID)
-
-	targetCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, targetCluster.ID)
-	clusterInstallation1 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation1.ID,
-		Namespace:      "namespace_10",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-
-	time.Sleep(1 * time.Millisecond)
-
-	clusterInstallation2 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation2.ID,
-		Namespace:      "namespace_11",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-
-	err = sqlStore.CreateClusterInstallation(clusterInstallation1)
-	require.NoError(t, err)
-
-	err = sqlStore.CreateClusterInstallation(clusterInstallation2)
-	require.NoError(t, err)
-
-	// New Installation to test already migrated CIs
-	installation3, err := client.CreateInstallation(&model.CreateInstallationRequest{
-		OwnerID:  "owner1",
-		Version:  "version",
-		Image:    "custom-image",
-		DNS:      "dns3.example.com",
-		Affinity: model.InstallationAffinityIsolated,
-	})
-	require.NoError(t, err)
-
-	time.Sleep(1 * time.Millisecond)
-
-	clusterInstallation3 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation3.ID,
-		Namespace:      "namespace_12",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-
-	t.Run("valid migration test", func(t *testing.T) {
-		mcir := &model.MigrateClusterInstallationRequest{SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, InstallationID: "", DNSSwitch: true, LockInstallation: true}
-		t.Log(mcir)
-		migrationResponse, err := client.MigrateClusterInstallation(mcir)
-		require.NoError(t, err)
-		newClusterInstallations, err := sqlStore.GetClusterInstallations(&model.ClusterInstallationFilter{ClusterID: targetCluster.ID, Paging: model.AllPagesNotDeleted()})
-		require.NoError(t, err)
-		assert.Equal(t, len(newClusterInstallations), migrationResponse.TotalClusterInstallations)
-		for _, ci := range newClusterInstallations {
-			assert.False(t, ci.IsActive)
-			assert.Equal(t, model.ClusterInstallationStateCreationRequested, ci.State)
-		}
-
-		// Should only return 1 valid installation for migration
-		err = sqlStore.CreateClusterInstallation(clusterInstallation3)
-		require.NoError(t, err)
-		cis, status := api.GetClusterInstallationsForMigration(context, *mcir)
-		assert.Equal(t, status, 0)
-		assert.Len(t, cis, 1)
-
-	})
-}
-
-func TestMigrateDNS(t *testing.T) {
-	logger := testlib.MakeLogger(t)
-	sqlStore := store.MakeTestSQLStore(t, logger)
-
-	router := mux.NewRouter()
-	api.Register(router, &api.Context{
-		Store:         sqlStore,
-		Supervisor:    &mockSupervisor{},
-		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
-		Logger:        logger,
-	})
-	ts := httptest.NewServer(router)
-	defer ts.Close()
-
-	model.SetDeployOperators(true, true)
-
-	t.Run("invalid payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate/dns", ts.URL), "application/json", bytes.NewReader([]byte("invalid")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	t.Run("empty payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate/dns", ts.URL), "application/json", bytes.NewReader([]byte("")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	client := model.NewClient(ts.URL)
-	t.Run("missing source cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "", TargetClusterID: "4567"})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("missing target cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: ""})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("No cluster installation found to migrate", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: "67899"})
-		require.EqualError(t, err, "failed with status code 404")
-	})
-
-	// Valid migration test
-	sourceCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, sourceCluster.ID)
-
-	installation1, err := client.CreateInstallation(&model.CreateInstallationRequest{
-		OwnerID:  "owner1",
-		Version:  "version",
-		Image:    "custom-image",
-		DNS:      "dns1.example.com",
-		Affinity: model.InstallationAffinityIsolated,
-	})
-	require.NoError(t, err)
-
-	time.Sleep(1 * time.Millisecond)
-
-	installation2, err := client.CreateInstallation(&model.CreateInstallationRequest{
-		OwnerID:  "owner2",
-		Version:  "version",
-		Image:    "custom-image",
-		DNS:      "dns2.example.com",
-		Affinity: model.InstallationAffinityIsolated,
-	})
-	require.NoError(t, err)
-
-	installation3 := &model.Installation{
-		OwnerID:   "owner3",
-		Version:   "version3",
-		Image:     "custom-image",
-		DNS:       "dns3.example.com",
-		Database:  model.InstallationDatabaseMysqlOperator,
-		Filestore: model.InstallationFilestoreMinioOperator,
-		Affinity:  model.InstallationAffinityIsolated,
-		State:     model.InstallationStateHibernating,
-	}
-
-	err = sqlStore.CreateInstallation(installation3, nil)
-	require.NoError(t, err)
-
-	time.Sleep(1 * time.Millisecond)
-	clusterInstallation1 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation1.ID,
-		Namespace:      "namespace_10",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-	err = sqlStore.CreateClusterInstallation(clusterInstallation1)
-	require.NoError(t, err)
-
-	clusterInstallation2 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation2.ID,
-		Namespace:      "namespace_11",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-	err = sqlStore.CreateClusterInstallation(clusterInstallation2)
-	require.NoError(t, err)
-
-	clusterInstallation3 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation3.ID,
-		Namespace:      "namespace_11",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-	err = sqlStore.CreateClusterInstallation(clusterInstallation3)
-	require.NoError(t, err)
-
-	targetCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, targetCluster)
-
-	t.Run("valid migration test", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: false, LockInstallation: false})
-		require.NoError(t, err)
-
-		_, err = client.MigrateDNS(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: true, LockInstallation: true})
-		require.NoError(t, err)
-
-		// varifying the outcomes
-		var isActiveClusterInstallations = false
-		filter := &model.ClusterInstallationFilter{
-			ClusterID:      sourceCluster.ID,
-			InstallationID: "",
-			Paging:         model.AllPagesNotDeleted(),
-			IsActive:       &isActiveClusterInstallations,
-		}
-		cis, err := sqlStore.GetClusterInstallations(filter)
-		require.NoError(t, err)
-		require.NotEmpty(t, cis)
-
-		isActiveClusterInstallations = true
-		filter = &model.ClusterInstallationFilter{
-			ClusterID:      targetCluster.ID,
-			InstallationID: "",
-			Paging:         model.AllPagesNotDeleted(),
-			IsActive:       &isActiveClusterInstallations,
-		}
-		cis, err = sqlStore.GetClusterInstallations(filter)
-		require.NoError(t, err)
-		require.NotEmpty(t, cis)
-		assert.Len(t, cis, 3)
-	})
-
-}
-func TestMigrateDNSForHibernatingInstallation(t *testing.T) {
-	logger := testlib.MakeLogger(t)
-	sqlStore := store.MakeTestSQLStore(t, logger)
-
-	router := mux.NewRouter()
-	api.Register(router, &api.Context{
-		Store:         sqlStore,
-		Supervisor:    &mockSupervisor{},
-		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
-		Logger:        logger,
-	})
-	ts := httptest.NewServer(router)
-	defer ts.Close()
-
-	model.SetDeployOperators(true, true)
-
-	t.Run("invalid payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate/dns", ts.URL), "application/json", bytes.NewReader([]byte("invalid")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	t.Run("empty payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate/dns", ts.URL), "application/json", bytes.NewReader([]byte("")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	client := model.NewClient(ts.URL)
-	t.Run("missing source cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "", TargetClusterID: "4567"})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("missing target cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: ""})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("No cluster installation found to migrate", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: "67899"})
-		require.EqualError(t, err, "failed with status code 404")
-	})
-
-	// Valid migration test
-	sourceCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, sourceCluster.ID)
-
-	installation1 := &model.Installation{
-		OwnerID:   "owner1",
-		Version:   "version1",
-		Image:     "custom-image",
-		DNS:       "dns1.example.com",
-		Database:  model.InstallationDatabaseMysqlOperator,
-		Filestore: model.InstallationFilestoreMinioOperator,
-		Affinity:  model.InstallationAffinityIsolated,
-		State:     model.InstallationStateHibernating,
-	}
-
-	err = sqlStore.CreateInstallation(installation1, nil)
-	require.NoError(t, err)
-
-	installation2 := &model.Installation{
-		OwnerID:   "owner2",
-		Version:   "version2",
-		Image:     "custom-image",
-		DNS:       "dns3.example.com",
-		Database:  model.InstallationDatabaseMysqlOperator,
-		Filestore: model.InstallationFilestoreMinioOperator,
-		Affinity:  model.InstallationAffinityIsolated,
-		State:     model.InstallationStateHibernating,
-	}
-
-	err = sqlStore.CreateInstallation(installation2, nil)
-	require.NoError(t, err)
-
-	time.Sleep(1 * time.Millisecond)
-	clusterInstallation1 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation1.ID,
-		Namespace:      "namespace_10",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-	err = sqlStore.CreateClusterInstallation(clusterInstallation1)
-	require.NoError(t, err)
-
-	clusterInstallation2 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation2.ID,
-		Namespace:      "namespace_11",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-	err = sqlStore.CreateClusterInstallation(clusterInstallation2)
-	require.NoError(t, err)
-
-	targetCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, targetCluster)
-
-	t.Run("valid migration test", func(t *testing.T) {
-		migrationResponse, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: false, LockInstallation: false})
-		require.NoError(t, err)
-
-		dnsSwitchResponse, err := client.MigrateDNS(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: true, LockInstallation: true})
-		require.NoError(t, err)
-		assert.Equal(t, dnsSwitchResponse.TotalClusterInstallations, migrationResponse.TotalClusterInstallations)
-		// varifying the outcomes
-		var isActiveClusterInstallations = false
-		filter := &model.ClusterInstallationFilter{
-			ClusterID:      sourceCluster.ID,
-			InstallationID: "",
-			Paging:         model.AllPagesNotDeleted(),
-			IsActive:       &isActiveClusterInstallations,
-		}
-		cis, err := sqlStore.GetClusterInstallations(filter)
-		require.NoError(t, err)
-		require.NotEmpty(t, cis)
-
-		isActiveClusterInstallations = true
-		filter = &model.ClusterInstallationFilter{
-			ClusterID:      targetCluster.ID,
-			InstallationID: "",
-			Paging:         model.AllPagesNotDeleted(),
-			IsActive:       &isActiveClusterInstallations,
-		}
-		cis, err = sqlStore.GetClusterInstallations(filter)
-		require.NoError(t, err)
-		require.NotEmpty(t, cis)
-		assert.Len(t, cis, 2)
-
-		// Verifying if migration has already processed
-		_, err = client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: false, LockInstallation: false})
-		require.EqualError(t, err, "failed with status code 404")
-
-	})
-
-}
-func TestMigrateDNSForNonHibernatingInstallation(t *testing.T) {
-	logger := testlib.MakeLogger(t)
-	sqlStore := store.MakeTestSQLStore(t, logger)
-
-	router := mux.NewRouter()
-	api.Register(router, &api.Context{
-		Store:         sqlStore,
-		Supervisor:    &mockSupervisor{},
-		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
-		Logger:        logger,
-	})
-	ts := httptest.NewServer(router)
-	defer ts.Close()
-
-	model.SetDeployOperators(true, true)
-
-	t.Run("invalid payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate/dns", ts.URL), "application/json", bytes.NewReader([]byte("invalid")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	t.Run("empty payload", func(t *testing.T) {
-		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_installations/migrate/dns", ts.URL), "application/json", bytes.NewReader([]byte("")))
-		require.NoError(t, err)
-		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
-	})
-
-	client := model.NewClient(ts.URL)
-	t.Run("missing source cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "", TargetClusterID: "4567"})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("missing target cluster", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: ""})
-		require.EqualError(t, err, "failed with status code 400")
-	})
-
-	t.Run("No cluster installation found to migrate", func(t *testing.T) {
-		_, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{SourceClusterID: "12345", TargetClusterID: "67899"})
-		require.EqualError(t, err, "failed with status code 404")
-	})
-
-	// Valid migration test
-	sourceCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, sourceCluster.ID)
-
-	installation1 := &model.Installation{
-		OwnerID:   "owner1",
-		Version:   "version1",
-		Image:     "custom-image",
-		DNS:       "dns1.example.com",
-		Database:  model.InstallationDatabaseMysqlOperator,
-		Filestore: model.InstallationFilestoreMinioOperator,
-		Affinity:  model.InstallationAffinityIsolated,
-		State:     model.InstallationStateCreationRequested,
-	}
-
-	err = sqlStore.CreateInstallation(installation1, nil)
-	require.NoError(t, err)
-
-	installation2 := &model.Installation{
-		OwnerID:   "owner2",
-		Version:   "version2",
-		Image:     "custom-image",
-		DNS:       "dns3.example.com",
-		Database:  model.InstallationDatabaseMysqlOperator,
-		Filestore: model.InstallationFilestoreMinioOperator,
-		Affinity:  model.InstallationAffinityIsolated,
-		State:     model.InstallationStateCreationRequested,
-	}
-
-	err = sqlStore.CreateInstallation(installation2, nil)
-	require.NoError(t, err)
-
-	time.Sleep(1 * time.Millisecond)
-	clusterInstallation1 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation1.ID,
-		Namespace:      "namespace_10",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-	err = sqlStore.CreateClusterInstallation(clusterInstallation1)
-	require.NoError(t, err)
-
-	clusterInstallation2 := &model.ClusterInstallation{
-		ClusterID:      sourceCluster.ID,
-		InstallationID: installation2.ID,
-		Namespace:      "namespace_11",
-		State:          model.ClusterInstallationStateCreationRequested,
-		IsActive:       true,
-	}
-	err = sqlStore.CreateClusterInstallation(clusterInstallation2)
-	require.NoError(t, err)
-
-	targetCluster, err := client.CreateCluster(&model.CreateClusterRequest{
-		Provider:    model.ProviderAWS,
-		Zones:       []string{"zone"},
-		Annotations: []string{"my-annotation"},
-	})
-	require.NoError(t, err)
-	require.NotNil(t, targetCluster)
-
-	t.Run("valid migration test", func(t *testing.T) {
-		migrationResponse, err := client.MigrateClusterInstallation(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: false, LockInstallation: false})
-		require.NoError(t, err)
-
-		dnsSwitchResponse, err := client.MigrateDNS(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: true, LockInstallation: true})
-		require.NoError(t, err)
-		assert.Equal(t, dnsSwitchResponse.TotalClusterInstallations, migrationResponse.TotalClusterInstallations)
-
-		// varifying the outcomes
-		var isActiveClusterInstallations = false
-		filter := &model.ClusterInstallationFilter{
-			ClusterID:      sourceCluster.ID,
-			InstallationID: "",
-			Paging:         model.AllPagesNotDeleted(),
-			IsActive:       &isActiveClusterInstallations,
-		}
-		cis, err := sqlStore.GetClusterInstallations(filter)
-		require.NoError(t, err)
-		require.NotEmpty(t, cis)
-
-		isActiveClusterInstallations = true
-		filter = &model.ClusterInstallationFilter{
-			ClusterID:      targetCluster.ID,
-			InstallationID: "",
-			Paging:         model.AllPagesNotDeleted(),
-			IsActive:       &isActiveClusterInstallations,
-		}
-		cis, err = sqlStore.GetClusterInstallations(filter)
-		require.NoError(t, err)
-		require.NotEmpty(t, cis)
-		assert.Len(t, cis, 2)
-
-		// Verifying if DNS Switch has already processed
-		_, err = client.MigrateDNS(&model.MigrateClusterInstallationRequest{InstallationID: "", SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID, DNSSwitch: true, LockInstallation: true})
-		require.EqualError(t, err, "failed with status code 404")
-	})
-
-}
 func TestDeleteInActiveClusterInstallationsByCluster(t *testing.T) {
 	logger := testlib.MakeLogger(t)
 	sqlStore := store.MakeTestSQLStore(t, logger)
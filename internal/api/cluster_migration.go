@@ -0,0 +1,220 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+// initClusterMigration registers cluster migration operation endpoints on the given router.
+func initClusterMigration(apiRouter *mux.Router, context *Context) {
+	addContext := func(handler contextHandlerFunc) *contextHandler {
+		return newContextHandler(context, handler)
+	}
+
+	migrationsRouter := apiRouter.PathPrefix("/migrations").Subrouter()
+	migrationsRouter.Handle("", addContext(handleCreateClusterMigration)).Methods("POST")
+	migrationsRouter.Handle("", addContext(handleGetClusterMigrationOperations)).Methods("GET")
+
+	migrationRouter := apiRouter.PathPrefix("/migration/{migration:[A-Za-z0-9]{26}}").Subrouter()
+	migrationRouter.Handle("", addContext(handleGetClusterMigrationOperation)).Methods("GET")
+	migrationRouter.Handle("/rollback", addContext(handleRollbackClusterMigration)).Methods("POST")
+}
+
+// handleCreateClusterMigration responds to POST /api/cluster_installations/migrations,
+// requests migration of cluster installations from one cluster to another.
+func handleCreateClusterMigration(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.Logger = c.Logger.WithField("action", "create-cluster-migration")
+
+	request, err := model.NewCreateClusterMigrationRequestFromReader(r.Body)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.Logger = c.Logger.WithField("source-cluster", request.SourceClusterID).
+		WithField("target-cluster", request.TargetClusterID)
+
+	sourceCluster, err := c.Store.GetCluster(request.SourceClusterID)
+	if err != nil {
+		c.Logger.WithError(err).Error("Failed to get source cluster")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if sourceCluster == nil {
+		c.Logger.Error("Source cluster not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	targetCluster, err := c.Store.GetCluster(request.TargetClusterID)
+	if err != nil {
+		c.Logger.WithError(err).Error("Failed to get target cluster")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if targetCluster == nil {
+		c.Logger.Error("Target cluster not found")
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// EKS clusters cannot host installations.
+	if sourceCluster.IsEKS() || targetCluster.IsEKS() {
+		c.Logger.Error("Installations cannot be migrated from or to EKS clusters")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Verify that the source cluster does not accept new installations before the migration starts.
+	if sourceCluster.AllowInstallations {
+		c.Logger.Error("Allow installation must be set to false for the source cluster.")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	for _, clusterID := range []string{sourceCluster.ID, targetCluster.ID} {
+		for _, filter := range []*model.ClusterMigrationFilter{
+			{Paging: model.AllPagesNotDeleted(), SourceClusterID: clusterID, States: model.AllClusterMigrationOperationsStatesPendingWork},
+			{Paging: model.AllPagesNotDeleted(), TargetClusterID: clusterID, States: model.AllClusterMigrationOperationsStatesPendingWork},
+		} {
+			pending, err := c.Store.GetClusterMigrationOperations(filter)
+			if err != nil {
+				c.Logger.WithError(err).Error("Failed to get pending cluster migrations")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if len(pending) > 0 {
+				c.Logger.Errorf("Cluster %s is already part of pending cluster migration %s", clusterID, pending[0].ID)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	operation := &model.ClusterMigrationOperation{
+		SourceClusterID:    request.SourceClusterID,
+		TargetClusterID:    request.TargetClusterID,
+		InstallationID:     request.InstallationID,
+		LockInstallations:  request.LockInstallations,
+		SwitchClusterRoles: request.SwitchClusterRoles,
+		State:              model.ClusterMigrationStateRequested,
+	}
+	err = c.Store.CreateClusterMigrationOperation(operation)
+	if err != nil {
+		c.Logger.WithError(err).Error("Failed to create cluster migration")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	c.Supervisor.Do()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	outputJSON(c, w, operation)
+}
+
+// handleGetClusterMigrationOperations responds to GET /api/cluster_installations/migrations,
+// returns list of cluster migration operations.
+func handleGetClusterMigrationOperations(c *Context, w http.ResponseWriter, r *http.Request) {
+	c.Logger = c.Logger.
+		WithField("action", "list-cluster-migrations")
+
+	paging, err := parsePaging(r.URL)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to parse paging parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	state := r.URL.Query().Get("state")
+	var states []model.ClusterMigrationOperationState
+	if state != "" {
+		states = append(states, model.ClusterMigrationOperationState(state))
+	}
+
+	operations, err := c.Store.GetClusterMigrationOperations(&model.ClusterMigrationFilter{
+		Paging:          paging,
+		InstallationID:  r.URL.Query().Get("installation"),
+		SourceClusterID: r.URL.Query().Get("source_cluster"),
+		TargetClusterID: r.URL.Query().Get("target_cluster"),
+		States:          states,
+	})
+	if err != nil {
+		c.Logger.WithError(err).Error("Failed to list cluster migrations")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, operations)
+}
+
+// handleGetClusterMigrationOperation responds to GET /api/cluster_installations/migration/{migration},
+// returns specified cluster migration operation.
+func handleGetClusterMigrationOperation(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	migrationID := vars["migration"]
+
+	c.Logger = c.Logger.
+		WithField("action", "get-cluster-migration").
+		WithField("migration-operation", migrationID)
+
+	operation, err := c.Store.GetClusterMigrationOperation(migrationID)
+	if err != nil {
+		c.Logger.WithError(err).Error("Failed to get cluster migration")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if operation == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, operation)
+}
+
+// handleRollbackClusterMigration responds to POST /api/cluster_installations/migration/{migration}/rollback,
+// requests rollback of the cluster migration.
+func handleRollbackClusterMigration(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	migrationID := vars["migration"]
+
+	c.Logger = c.Logger.
+		WithField("action", "rollback-cluster-migration").
+		WithField("migration-operation", migrationID)
+
+	operation, status, unlockOnce := lockClusterMigrationOperation(c, migrationID)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	defer unlockOnce()
+
+	if !operation.CanBeRolledBack() {
+		c.Logger.Warnf("Cannot rollback cluster migration in state %s", operation.State)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	operation.State = model.ClusterMigrationStateRollbackRequested
+	err := c.Store.UpdateClusterMigrationOperationState(operation)
+	if err != nil {
+		c.Logger.WithError(err).Error("Failed to request cluster migration rollback")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	unlockOnce()
+	c.Supervisor.Do()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	outputJSON(c, w, operation)
+}
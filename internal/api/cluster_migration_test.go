@@ -0,0 +1,123 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package api_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloud/internal/api"
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterMigrations(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:      sqlStore,
+		Supervisor: &mockSupervisor{},
+		Logger:     logger,
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+	client := model.NewClient(ts.URL)
+
+	sourceCluster := &model.Cluster{State: model.ClusterStateStable, AllowInstallations: true}
+	err := sqlStore.CreateCluster(sourceCluster, nil)
+	require.NoError(t, err)
+	targetCluster := &model.Cluster{State: model.ClusterStateStable, AllowInstallations: true}
+	err = sqlStore.CreateCluster(targetCluster, nil)
+	require.NoError(t, err)
+
+	t.Run("invalid request", func(t *testing.T) {
+		_, err = client.CreateClusterMigration(&model.CreateClusterMigrationRequest{SourceClusterID: sourceCluster.ID})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("unknown cluster", func(t *testing.T) {
+		_, err = client.CreateClusterMigration(&model.CreateClusterMigrationRequest{SourceClusterID: sourceCluster.ID, TargetClusterID: model.NewID()})
+		require.EqualError(t, err, "failed with status code 404")
+	})
+
+	t.Run("source cluster allows installations", func(t *testing.T) {
+		_, err = client.CreateClusterMigration(&model.CreateClusterMigrationRequest{SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	sourceCluster.AllowInstallations = false
+	err = sqlStore.UpdateCluster(sourceCluster)
+	require.NoError(t, err)
+
+	t.Run("EKS target cluster", func(t *testing.T) {
+		eksCluster := &model.Cluster{State: model.ClusterStateStable, Provisioner: model.ProvisionerEKS}
+		err = sqlStore.CreateCluster(eksCluster, nil)
+		require.NoError(t, err)
+
+		_, err = client.CreateClusterMigration(&model.CreateClusterMigrationRequest{SourceClusterID: sourceCluster.ID, TargetClusterID: eksCluster.ID})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	operation, err := client.CreateClusterMigration(&model.CreateClusterMigrationRequest{
+		SourceClusterID:   sourceCluster.ID,
+		TargetClusterID:   targetCluster.ID,
+		LockInstallations: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.ClusterMigrationStateRequested, operation.State)
+	assert.True(t, operation.LockInstallations)
+
+	t.Run("cluster already migrating", func(t *testing.T) {
+		_, err = client.CreateClusterMigration(&model.CreateClusterMigrationRequest{SourceClusterID: sourceCluster.ID, TargetClusterID: targetCluster.ID})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		fetched, err := client.GetClusterMigrationOperation(operation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, operation, fetched)
+
+		fetched, err = client.GetClusterMigrationOperation(model.NewID())
+		require.NoError(t, err)
+		assert.Nil(t, fetched)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		operations, err := client.GetClusterMigrationOperations(&model.GetClusterMigrationOperationsRequest{
+			Paging:          model.AllPagesNotDeleted(),
+			SourceClusterID: sourceCluster.ID,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []*model.ClusterMigrationOperation{operation}, operations)
+
+		operations, err = client.GetClusterMigrationOperations(&model.GetClusterMigrationOperationsRequest{
+			Paging: model.AllPagesNotDeleted(),
+			State:  string(model.ClusterMigrationStateSucceeded),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, operations)
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		rolledBack, err := client.RollbackClusterMigration(operation.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterMigrationStateRollbackRequested, rolledBack.State)
+
+		operation.State = model.ClusterMigrationStateCleanup
+		err = sqlStore.UpdateClusterMigrationOperationState(operation)
+		require.NoError(t, err)
+
+		_, err = client.RollbackClusterMigration(operation.ID)
+		require.EqualError(t, err, "failed with status code 400")
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloud/clusterdictionary"
+	"github.com/mattermost/mattermost-cloud/internal/common"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+// initClusterSize registers cluster size endpoints on the given router.
+func initClusterSize(apiRouter *mux.Router, context *Context) {
+	addContext := func(handler contextHandlerFunc) *contextHandler {
+		return newContextHandler(context, handler)
+	}
+
+	clusterSizesRouter := apiRouter.PathPrefix("/cluster_sizes").Subrouter()
+	clusterSizesRouter.Handle("", addContext(handleGetClusterSizes)).Methods("GET")
+	clusterSizesRouter.Handle("", addContext(handleCreateClusterSize)).Methods("POST")
+}
+
+// handleGetClusterSizes responds to GET /api/cluster_sizes, returning the
+// cluster sizes from the size dictionary followed by the cluster sizes stored
+// in the database.
+func handleGetClusterSizes(c *Context, w http.ResponseWriter, r *http.Request) {
+	storedSizes, err := c.Store.GetClusterSizes(&model.ClusterSizeFilter{
+		Paging: model.AllPagesNotDeleted(),
+	})
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster sizes")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	clusterSizes := append(clusterdictionary.Sizes(), storedSizes...)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, clusterSizes)
+}
+
+// handleCreateClusterSize responds to POST /api/cluster_sizes, creating a new
+// cluster size.
+func handleCreateClusterSize(c *Context, w http.ResponseWriter, r *http.Request) {
+	createClusterSizeRequest, err := model.NewCreateClusterSizeRequestFromReader(r.Body)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	existing, err := common.GetClusterSize(c.Store, createClusterSizeRequest.Name)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster sizes")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		c.Logger.Errorf("cluster size with name %s already exists", createClusterSizeRequest.Name)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	clusterSize := createClusterSizeRequest.ClusterSize()
+
+	err = c.Store.CreateClusterSize(clusterSize)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to create cluster size")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, clusterSize)
+}
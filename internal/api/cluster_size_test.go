@@ -0,0 +1,239 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package api_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloud/clusterdictionary"
+	"github.com/mattermost/mattermost-cloud/internal/api"
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/internal/testutil"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterSizes(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:      sqlStore,
+		Supervisor: &mockSupervisor{},
+		Logger:     logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	t.Run("built-in sizes", func(t *testing.T) {
+		sizes, err := client.GetClusterSizes()
+		require.NoError(t, err)
+		assert.Len(t, sizes, len(clusterdictionary.ValidSizes))
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_sizes", ts.URL), "application/json", bytes.NewReader([]byte("invalid")))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("invalid instance type", func(t *testing.T) {
+		_, err := client.CreateClusterSize(&model.CreateClusterSizeRequest{
+			Name:               "invalid",
+			MasterInstanceType: "t3.medium",
+			NodeInstanceType:   "large",
+			NodeMinCount:       2,
+		})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("built-in name", func(t *testing.T) {
+		_, err := client.CreateClusterSize(&model.CreateClusterSizeRequest{
+			Name:               clusterdictionary.SizeAlef500,
+			MasterInstanceType: "t3.medium",
+			NodeInstanceType:   "m5.large",
+			NodeMinCount:       2,
+		})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	clusterSize, err := client.CreateClusterSize(&model.CreateClusterSizeRequest{
+		Name:               "memory",
+		MasterInstanceType: "t3.large",
+		NodeInstanceType:   "m5.xlarge",
+		NodeMinCount:       3,
+		AdditionalNodeGroups: model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 2},
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, clusterSize.ID)
+	assert.Equal(t, int64(1), clusterSize.MasterCount)
+	assert.Equal(t, int64(3), clusterSize.NodeMaxCount)
+	assert.Equal(t, int64(2), clusterSize.AdditionalNodeGroups["memory"].NodeMaxCount)
+
+	t.Run("duplicate name", func(t *testing.T) {
+		_, err := client.CreateClusterSize(&model.CreateClusterSizeRequest{
+			Name:               "memory",
+			MasterInstanceType: "t3.medium",
+			NodeInstanceType:   "m5.large",
+			NodeMinCount:       2,
+		})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("list", func(t *testing.T) {
+		sizes, err := client.GetClusterSizes()
+		require.NoError(t, err)
+		require.Len(t, sizes, len(clusterdictionary.ValidSizes)+1)
+		assert.Equal(t, clusterSize, sizes[len(sizes)-1])
+	})
+}
+
+func TestCreateClusterWithSize(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	_, err := client.CreateClusterSize(&model.CreateClusterSizeRequest{
+		Name:               "memory",
+		MasterInstanceType: "t3.large",
+		NodeInstanceType:   "m5.xlarge",
+		NodeMinCount:       3,
+		AdditionalNodeGroups: model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 2},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("unknown size", func(t *testing.T) {
+		_, err = client.CreateCluster(&model.CreateClusterRequest{Size: "unknown"})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("built-in size", func(t *testing.T) {
+		cluster, err := client.CreateCluster(&model.CreateClusterRequest{Size: clusterdictionary.SizeAlef10000})
+		require.NoError(t, err)
+		assert.Equal(t, "t3.large", cluster.ProvisionerMetadataKops.ChangeRequest.MasterInstanceType)
+		assert.Equal(t, int64(3), cluster.ProvisionerMetadataKops.ChangeRequest.MasterCount)
+		assert.Equal(t, int64(10), cluster.ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+		assert.Equal(t, model.NetworkingCalico, cluster.ProvisionerMetadataKops.ChangeRequest.Networking)
+	})
+
+	t.Run("stored size with overrides", func(t *testing.T) {
+		cluster, err := client.CreateCluster(&model.CreateClusterRequest{
+			Size:         "memory",
+			NodeMinCount: 5,
+			NodeMaxCount: 5,
+		})
+		require.NoError(t, err)
+		changeRequest := cluster.ProvisionerMetadataKops.ChangeRequest
+		assert.Equal(t, "m5.xlarge", changeRequest.NodeInstanceType)
+		assert.Equal(t, int64(5), changeRequest.NodeMinCount)
+		require.Contains(t, changeRequest.AdditionalNodeGroups, "memory")
+		assert.Equal(t, "r5.xlarge", changeRequest.AdditionalNodeGroups["memory"].NodeInstanceType)
+
+		fetched, err := client.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, changeRequest.AdditionalNodeGroups, fetched.ProvisionerMetadataKops.ChangeRequest.AdditionalNodeGroups)
+	})
+
+	t.Run("template with unknown size", func(t *testing.T) {
+		_, err = client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{
+			Name:    "unknown-size",
+			Cluster: &model.CreateClusterRequest{Size: "unknown"},
+		})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("template with size", func(t *testing.T) {
+		_, err = client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{
+			Name:    "template",
+			Cluster: &model.CreateClusterRequest{Size: "memory", Version: "1.21.4"},
+		})
+		require.NoError(t, err)
+
+		cluster, err := client.CreateCluster(&model.CreateClusterRequest{Template: "template"})
+		require.NoError(t, err)
+		assert.Equal(t, "1.21.4", cluster.ProvisionerMetadataKops.ChangeRequest.Version)
+		assert.Equal(t, int64(3), cluster.ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+		assert.Contains(t, cluster.ProvisionerMetadataKops.ChangeRequest.AdditionalNodeGroups, "memory")
+
+		cluster, err = client.CreateCluster(&model.CreateClusterRequest{Template: "template", Size: clusterdictionary.SizeAlefDev})
+		require.NoError(t, err)
+		assert.Equal(t, "t3.medium", cluster.ProvisionerMetadataKops.ChangeRequest.NodeInstanceType)
+		assert.Empty(t, cluster.ProvisionerMetadataKops.ChangeRequest.AdditionalNodeGroups)
+	})
+
+	t.Run("resize with size", func(t *testing.T) {
+		cluster, err := client.CreateCluster(&model.CreateClusterRequest{})
+		require.NoError(t, err)
+		cluster.State = model.ClusterStateStable
+		err = sqlStore.UpdateCluster(cluster.Cluster)
+		require.NoError(t, err)
+
+		_, err = client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{Size: "unknown"})
+		require.EqualError(t, err, "failed with status code 400")
+
+		cluster, err = client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{Size: "memory"})
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, cluster.State)
+		assert.Equal(t, "m5.xlarge", cluster.ProvisionerMetadataKops.ChangeRequest.NodeInstanceType)
+		assert.Equal(t, int64(3), cluster.ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+	})
+
+	t.Run("resize node group", func(t *testing.T) {
+		cluster, err := client.CreateCluster(&model.CreateClusterRequest{Size: "memory"})
+		require.NoError(t, err)
+		cluster.State = model.ClusterStateStable
+		cluster.ProvisionerMetadataKops.CustomInstanceGroups = model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 2, NodeMaxCount: 2},
+		}
+		err = sqlStore.UpdateCluster(cluster.Cluster)
+		require.NoError(t, err)
+
+		_, err = client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{NodeGroup: "compute", NodeMinCount: iToP(3)})
+		require.EqualError(t, err, "failed with status code 400")
+
+		_, err = client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{Size: clusterdictionary.SizeAlefDev, NodeGroup: "memory"})
+		require.EqualError(t, err, "failed with status code 400")
+
+		_, err = client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{NodeGroup: "memory", NodeMaxCount: iToP(1)})
+		require.EqualError(t, err, "failed with status code 400")
+
+		cluster, err = client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{NodeGroup: "memory", NodeMinCount: iToP(4), NodeMaxCount: iToP(6)})
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, cluster.State)
+		changeRequest := cluster.ProvisionerMetadataKops.ChangeRequest
+		assert.Empty(t, changeRequest.NodeInstanceType)
+		assert.Zero(t, changeRequest.NodeMinCount)
+		assert.Equal(t, model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 4, NodeMaxCount: 6},
+		}, changeRequest.AdditionalNodeGroups)
+	})
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloud/internal/common"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+// initClusterTemplate registers cluster template endpoints on the given router.
+func initClusterTemplate(apiRouter *mux.Router, context *Context) {
+	addContext := func(handler contextHandlerFunc) *contextHandler {
+		return newContextHandler(context, handler)
+	}
+
+	clusterTemplatesRouter := apiRouter.PathPrefix("/cluster_templates").Subrouter()
+	clusterTemplatesRouter.Handle("", addContext(handleGetClusterTemplates)).Methods("GET")
+	clusterTemplatesRouter.Handle("", addContext(handleCreateClusterTemplate)).Methods("POST")
+
+	clusterTemplateRouter := apiRouter.PathPrefix("/cluster_template/{cluster_template:[A-Za-z0-9]{26}}").Subrouter()
+	clusterTemplateRouter.Handle("", addContext(handleGetClusterTemplate)).Methods("GET")
+	clusterTemplateRouter.Handle("", addContext(handleUpdateClusterTemplate)).Methods("PUT")
+	clusterTemplateRouter.Handle("", addContext(handleDeleteClusterTemplate)).Methods("DELETE")
+}
+
+// handleCreateClusterTemplate responds to POST /api/cluster_templates, creating
+// a new cluster template.
+func handleCreateClusterTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	createClusterTemplateRequest, err := model.NewCreateClusterTemplateRequestFromReader(r.Body)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	status := checkClusterTemplateSize(c, createClusterTemplateRequest.Cluster)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	existing, err := common.GetClusterTemplateByName(c.Store, createClusterTemplateRequest.Name)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster templates")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if existing != nil {
+		c.Logger.Errorf("cluster template with name %s already exists", createClusterTemplateRequest.Name)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	clusterTemplate := model.ClusterTemplate{
+		Name:        createClusterTemplateRequest.Name,
+		Description: createClusterTemplateRequest.Description,
+		Cluster:     createClusterTemplateRequest.Cluster,
+	}
+
+	err = c.Store.CreateClusterTemplate(&clusterTemplate)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to create cluster template")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, clusterTemplate)
+}
+
+// handleGetClusterTemplate responds to GET /api/cluster_template/{cluster_template},
+// returning the cluster template in question.
+func handleGetClusterTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterTemplateID := vars["cluster_template"]
+	c.Logger = c.Logger.WithField("cluster_template", clusterTemplateID)
+
+	clusterTemplate, err := c.Store.GetClusterTemplate(clusterTemplateID)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster template")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if clusterTemplate == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, clusterTemplate)
+}
+
+// handleGetClusterTemplates responds to GET /api/cluster_templates, returning
+// the specified page of cluster templates.
+func handleGetClusterTemplates(c *Context, w http.ResponseWriter, r *http.Request) {
+	paging, err := parsePaging(r.URL)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to parse paging parameters")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	filter := &model.ClusterTemplateFilter{
+		Paging: paging,
+		Name:   parseString(r.URL, "name", ""),
+	}
+
+	clusterTemplates, err := c.Store.GetClusterTemplates(filter)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster templates")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if clusterTemplates == nil {
+		clusterTemplates = []*model.ClusterTemplate{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, clusterTemplates)
+}
+
+// handleUpdateClusterTemplate responds to PUT /api/cluster_template/{cluster_template},
+// updating the cluster template. Clusters created from the template are not
+// modified.
+func handleUpdateClusterTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterTemplateID := vars["cluster_template"]
+	c.Logger = c.Logger.WithField("cluster_template", clusterTemplateID)
+
+	patchClusterTemplateRequest, err := model.NewPatchClusterTemplateRequestFromReader(r.Body)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	status := checkClusterTemplateSize(c, patchClusterTemplateRequest.Cluster)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+
+	clusterTemplate, err := c.Store.GetClusterTemplate(clusterTemplateID)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster template")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if clusterTemplate == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if clusterTemplate.IsDeleted() {
+		c.Logger.Warn("unable to update cluster template that is deleted")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if patchClusterTemplateRequest.Name != nil && *patchClusterTemplateRequest.Name != clusterTemplate.Name {
+		existing, err := common.GetClusterTemplateByName(c.Store, *patchClusterTemplateRequest.Name)
+		if err != nil {
+			c.Logger.WithError(err).Error("failed to query cluster templates")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			c.Logger.Errorf("cluster template with name %s already exists", *patchClusterTemplateRequest.Name)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	if patchClusterTemplateRequest.Apply(clusterTemplate) {
+		err = c.Store.UpdateClusterTemplate(clusterTemplate)
+		if err != nil {
+			c.Logger.WithError(err).Error("failed to update cluster template")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	outputJSON(c, w, clusterTemplate)
+}
+
+// handleDeleteClusterTemplate responds to DELETE /api/cluster_template/{cluster_template},
+// marking the cluster template as deleted. Clusters created from the template
+// keep referencing it.
+func handleDeleteClusterTemplate(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	clusterTemplateID := vars["cluster_template"]
+	c.Logger = c.Logger.WithField("cluster_template", clusterTemplateID)
+
+	clusterTemplate, err := c.Store.GetClusterTemplate(clusterTemplateID)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster template")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if clusterTemplate == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if clusterTemplate.IsDeleted() {
+		c.Logger.Warn("unable to delete cluster template that is already deleted")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err = c.Store.DeleteClusterTemplate(clusterTemplateID)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to mark cluster template as deleted")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// checkClusterTemplateSize ensures the cluster size referenced by the cluster
+// parameters of a template exists. A non-zero status is returned on failure.
+func checkClusterTemplateSize(c *Context, cluster *model.CreateClusterRequest) int {
+	if cluster == nil || len(cluster.Size) == 0 {
+		return 0
+	}
+
+	clusterSize, err := common.GetClusterSize(c.Store, cluster.Size)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster size")
+		return http.StatusInternalServerError
+	}
+	if clusterSize == nil {
+		c.Logger.Errorf("cluster size %s not found", cluster.Size)
+		return http.StatusBadRequest
+	}
+
+	return 0
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package api_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mattermost/mattermost-cloud/internal/api"
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/internal/testutil"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterTemplates(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:      sqlStore,
+		Supervisor: &mockSupervisor{},
+		Logger:     logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	t.Run("invalid payload", func(t *testing.T) {
+		resp, err := http.Post(fmt.Sprintf("%s/api/cluster_templates", ts.URL), "application/json", bytes.NewReader([]byte("invalid")))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("missing cluster parameters", func(t *testing.T) {
+		_, err := client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{Name: "template"})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("invalid cluster parameters", func(t *testing.T) {
+		_, err := client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{
+			Name:    "template",
+			Cluster: &model.CreateClusterRequest{Version: "invalid"},
+		})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	clusterTemplate, err := client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{
+		Name:    "template",
+		Cluster: &model.CreateClusterRequest{Version: "1.21.4", NodeInstanceType: "m5.xlarge"},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, clusterTemplate.ID)
+	assert.Equal(t, "1.21.4", clusterTemplate.Cluster.Version)
+
+	t.Run("duplicate name", func(t *testing.T) {
+		_, err = client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{
+			Name:    "template",
+			Cluster: &model.CreateClusterRequest{},
+		})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		fetched, err := client.GetClusterTemplate(clusterTemplate.ID)
+		require.NoError(t, err)
+		assert.Equal(t, clusterTemplate, fetched)
+
+		fetched, err = client.GetClusterTemplate(model.NewID())
+		require.NoError(t, err)
+		assert.Nil(t, fetched)
+
+		templates, err := client.GetClusterTemplates(&model.GetClusterTemplatesRequest{Paging: model.AllPagesNotDeleted(), Name: "template"})
+		require.NoError(t, err)
+		assert.Equal(t, []*model.ClusterTemplate{clusterTemplate}, templates)
+
+		templates, err = client.GetClusterTemplates(&model.GetClusterTemplatesRequest{Paging: model.AllPagesNotDeleted(), Name: "unknown"})
+		require.NoError(t, err)
+		assert.Empty(t, templates)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		description := "updated"
+		updated, err := client.UpdateClusterTemplate(clusterTemplate.ID, &model.PatchClusterTemplateRequest{
+			Description: &description,
+			Cluster:     &model.CreateClusterRequest{Version: "1.22.0"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "updated", updated.Description)
+		assert.Equal(t, "1.22.0", updated.Cluster.Version)
+		assert.Empty(t, updated.Cluster.NodeInstanceType)
+
+		_, err = client.UpdateClusterTemplate(model.NewID(), &model.PatchClusterTemplateRequest{Description: &description})
+		require.EqualError(t, err, "failed with status code 404")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		other, err := client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{
+			Name:    "other",
+			Cluster: &model.CreateClusterRequest{},
+		})
+		require.NoError(t, err)
+
+		err = client.DeleteClusterTemplate(other.ID)
+		require.NoError(t, err)
+
+		err = client.DeleteClusterTemplate(other.ID)
+		require.EqualError(t, err, "failed with status code 400")
+
+		templates, err := client.GetClusterTemplates(&model.GetClusterTemplatesRequest{Paging: model.AllPagesNotDeleted()})
+		require.NoError(t, err)
+		assert.Len(t, templates, 1)
+	})
+}
+
+func TestCreateClusterFromTemplate(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	clusterTemplate, err := client.CreateClusterTemplate(&model.CreateClusterTemplateRequest{
+		Name: "template",
+		Cluster: &model.CreateClusterRequest{
+			Zones:              []string{"us-east-1b"},
+			Version:            "1.21.4",
+			NodeInstanceType:   "m5.xlarge",
+			NodeMinCount:       4,
+			Networking:         model.NetworkingAmazon,
+			AllowInstallations: true,
+			Annotations:        []string{"multi-tenant"},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("unknown template", func(t *testing.T) {
+		_, err = client.CreateCluster(&model.CreateClusterRequest{Template: "unknown"})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("invalid overrides", func(t *testing.T) {
+		_, err = client.CreateCluster(&model.CreateClusterRequest{Template: "template", NodeMaxCount: 2})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("by name with overrides", func(t *testing.T) {
+		cluster, err := client.CreateCluster(&model.CreateClusterRequest{
+			Template:     "template",
+			NodeMinCount: 6,
+			NodeMaxCount: 6,
+			Annotations:  []string{"gpu"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, clusterTemplate.ID, cluster.ClusterTemplateID)
+		assert.True(t, cluster.AllowInstallations)
+		assert.Equal(t, []string{"us-east-1b"}, cluster.ProviderMetadataAWS.Zones)
+		assert.Equal(t, "1.21.4", cluster.ProvisionerMetadataKops.ChangeRequest.Version)
+		assert.Equal(t, "m5.xlarge", cluster.ProvisionerMetadataKops.ChangeRequest.NodeInstanceType)
+		assert.Equal(t, int64(6), cluster.ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+		assert.Equal(t, model.NetworkingAmazon, cluster.ProvisionerMetadataKops.ChangeRequest.Networking)
+		assert.Len(t, cluster.Annotations, 2)
+
+		fetched, err := client.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, clusterTemplate.ID, fetched.ClusterTemplateID)
+	})
+
+	t.Run("by id", func(t *testing.T) {
+		cluster, err := client.CreateCluster(&model.CreateClusterRequest{Template: clusterTemplate.ID})
+		require.NoError(t, err)
+		assert.Equal(t, clusterTemplate.ID, cluster.ClusterTemplateID)
+		assert.Equal(t, int64(4), cluster.ProvisionerMetadataKops.ChangeRequest.NodeMaxCount)
+	})
+}
@@ -1031,6 +1031,97 @@ func TestResizeCluster(t *testing.T) {
 	})
 }
 
+func TestEKSCluster(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	t.Run("missing role ARNs", func(t *testing.T) {
+		_, err := client.CreateCluster(&model.CreateClusterRequest{
+			Provider:    model.ProviderAWS,
+			Provisioner: model.ProvisionerEKS,
+		})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	cluster, err := client.CreateCluster(&model.CreateClusterRequest{
+		Provider:          model.ProviderAWS,
+		Provisioner:       model.ProvisionerEKS,
+		Version:           "1.21.2",
+		NodeInstanceType:  "m5.large",
+		NodeMinCount:      2,
+		NodeMaxCount:      2,
+		EKSClusterRoleARN: "arn:aws:iam::123456789012:role/eks-cluster",
+		EKSNodeRoleARN:    "arn:aws:iam::123456789012:role/eks-node",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.ProvisionerEKS, cluster.Provisioner)
+	assert.Nil(t, cluster.ProvisionerMetadataKops)
+	require.NotNil(t, cluster.ProvisionerMetadataEKS)
+	assert.Equal(t, "arn:aws:iam::123456789012:role/eks-cluster", cluster.ProvisionerMetadataEKS.ClusterRoleARN)
+	assert.Equal(t, "1.21", cluster.ProvisionerMetadataEKS.ChangeRequest.Version)
+	assert.EqualValues(t, 2, cluster.ProvisionerMetadataEKS.ChangeRequest.NodeMaxCount)
+
+	cluster.State = model.ClusterStateStable
+	cluster.ProvisionerMetadataEKS.ClearChangeRequest()
+	cluster.ProvisionerMetadataEKS.Version = "1.21"
+	cluster.ProvisionerMetadataEKS.NodeInstanceType = "m5.large"
+	cluster.ProvisionerMetadataEKS.NodeMinCount = 2
+	cluster.ProvisionerMetadataEKS.NodeMaxCount = 2
+	err = sqlStore.UpdateCluster(cluster.Cluster)
+	require.NoError(t, err)
+
+	t.Run("allow installations", func(t *testing.T) {
+		_, err := client.UpdateCluster(cluster.ID, &model.UpdateClusterRequest{AllowInstallations: true})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("upgrade with kops AMI", func(t *testing.T) {
+		_, err := client.UpgradeCluster(cluster.ID, &model.PatchUpgradeClusterRequest{Version: sToP("1.22.0"), KopsAMI: sToP("ami")})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("resize node group", func(t *testing.T) {
+		_, err := client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{NodeGroup: "spot", NodeMinCount: iToP(3)})
+		require.EqualError(t, err, "failed with status code 400")
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		clusterResp, err := client.UpgradeCluster(cluster.ID, &model.PatchUpgradeClusterRequest{Version: sToP("1.22.0")})
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateUpgradeRequested, clusterResp.State)
+		assert.Equal(t, "1.22", clusterResp.ProvisionerMetadataEKS.ChangeRequest.Version)
+	})
+
+	t.Run("resize", func(t *testing.T) {
+		cluster.State = model.ClusterStateStable
+		err = sqlStore.UpdateCluster(cluster.Cluster)
+		require.NoError(t, err)
+
+		clusterResp, err := client.ResizeCluster(cluster.ID, &model.PatchClusterSizeRequest{
+			NodeInstanceType: sToP("m5.xlarge"),
+			NodeMinCount:     iToP(3),
+			NodeMaxCount:     iToP(3),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, clusterResp.State)
+		assert.Equal(t, "m5.xlarge", clusterResp.ProvisionerMetadataEKS.ChangeRequest.NodeInstanceType)
+		assert.EqualValues(t, 3, clusterResp.ProvisionerMetadataEKS.ChangeRequest.NodeMaxCount)
+	})
+}
+
 func TestDeleteCluster(t *testing.T) {
 	logger := testlib.MakeLogger(t)
 	sqlStore := store.MakeTestSQLStore(t, logger)
@@ -1190,6 +1281,158 @@ func TestGetAllUtilityMetadata(t *testing.T) {
 	assert.Equal(t, model.DefaultUtilityVersions[model.FluentbitCanonicalName], utilityMetadata.DesiredVersions.Fluentbit)
 }
 
+func TestUpgradeClusterUtility(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	cluster1, err := client.CreateCluster(&model.CreateClusterRequest{
+		Provider: model.ProviderAWS,
+		Zones:    []string{"zone"},
+	})
+	require.NoError(t, err)
+
+	cluster1.State = model.ClusterStateStable
+	cluster1.UtilityMetadata.ActualVersions.Nginx = &model.HelmUtilityVersion{Chart: "4.0.18", ValuesPath: "nginx_values.yaml"}
+	err = sqlStore.UpdateCluster(cluster1.Cluster)
+	require.NoError(t, err)
+
+	t.Run("unknown cluster", func(t *testing.T) {
+		clusterResp, err := client.UpgradeClusterUtility(model.NewID(), model.NginxCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		require.EqualError(t, err, "failed with status code 404")
+		assert.Nil(t, clusterResp)
+	})
+
+	t.Run("unknown utility", func(t *testing.T) {
+		clusterResp, err := client.UpgradeClusterUtility(cluster1.ID, "unknown", &model.UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		require.EqualError(t, err, "failed with status code 404")
+		assert.Nil(t, clusterResp)
+	})
+
+	t.Run("missing chart", func(t *testing.T) {
+		clusterResp, err := client.UpgradeClusterUtility(cluster1.ID, model.NginxCanonicalName, &model.UpgradeClusterUtilityRequest{})
+		require.EqualError(t, err, "failed with status code 400")
+		assert.Nil(t, clusterResp)
+	})
+
+	t.Run("while api-security-locked", func(t *testing.T) {
+		err = sqlStore.LockClusterAPI(cluster1.ID)
+		require.NoError(t, err)
+
+		clusterResp, err := client.UpgradeClusterUtility(cluster1.ID, model.NginxCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		require.EqualError(t, err, "failed with status code 403")
+		assert.Nil(t, clusterResp)
+
+		err = sqlStore.UnlockClusterAPI(cluster1.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		clusterResp, err := client.UpgradeClusterUtility(cluster1.ID, model.NginxCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "4.0.19", Preview: true})
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateUtilityUpgradeRequested, clusterResp.State)
+
+		cluster, err := client.GetCluster(cluster1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateUtilityUpgradeRequested, cluster.State)
+		upgrade := cluster.GetUtilityUpgrade(model.NginxCanonicalName)
+		require.NotNil(t, upgrade)
+		assert.Equal(t, model.UtilityUpgradeStateRequested, upgrade.State)
+		assert.True(t, upgrade.Preview)
+		assert.Equal(t, &model.HelmUtilityVersion{Chart: "4.0.19", ValuesPath: "nginx_values.yaml"}, upgrade.ToVersion)
+	})
+
+	t.Run("while upgrading", func(t *testing.T) {
+		clusterResp, err := client.UpgradeClusterUtility(cluster1.ID, model.PromtailCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "3.11.0"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{model.NginxCanonicalName, model.PromtailCanonicalName}, clusterResp.PendingUtilityUpgrades())
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		cluster1.State = model.ClusterStateResizeRequested
+		err = sqlStore.UpdateCluster(cluster1.Cluster)
+		require.NoError(t, err)
+
+		clusterResp, err := client.UpgradeClusterUtility(cluster1.ID, model.NginxCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		require.EqualError(t, err, "failed with status code 400")
+		assert.Nil(t, clusterResp)
+	})
+
+	t.Run("eks cluster", func(t *testing.T) {
+		eksCluster := &model.Cluster{
+			Provider:               model.ProviderAWS,
+			Provisioner:            model.ProvisionerEKS,
+			ProvisionerMetadataEKS: &model.EKSMetadata{},
+			State:                  model.ClusterStateStable,
+		}
+		err = sqlStore.CreateCluster(eksCluster, nil)
+		require.NoError(t, err)
+
+		clusterResp, err := client.UpgradeClusterUtility(eksCluster.ID, model.NginxCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		require.EqualError(t, err, "failed with status code 400")
+		assert.Nil(t, clusterResp)
+	})
+}
+
+func TestGetClusterUtilityValues(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		Provisioner:   &mockProvisioner{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+		AwsClient:     mockAWSClient{},
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	cluster1, err := client.CreateCluster(&model.CreateClusterRequest{
+		Provider: model.ProviderAWS,
+		Zones:    []string{"zone"},
+	})
+	require.NoError(t, err)
+
+	t.Run("unknown cluster", func(t *testing.T) {
+		values, err := client.GetClusterUtilityValues(model.NewID(), model.NginxCanonicalName)
+		require.EqualError(t, err, "failed with status code 404")
+		assert.Nil(t, values)
+	})
+
+	t.Run("unknown utility", func(t *testing.T) {
+		values, err := client.GetClusterUtilityValues(cluster1.ID, "unknown")
+		require.EqualError(t, err, "failed with status code 404")
+		assert.Nil(t, values)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		values, err := client.GetClusterUtilityValues(cluster1.ID, model.NginxCanonicalName)
+		require.NoError(t, err)
+		assert.Equal(t, cluster1.ID, values.ClusterID)
+		assert.Equal(t, model.NginxCanonicalName, values.Utility)
+		assert.Equal(t, fmt.Sprintf("cluster: %s\ncidr: 10.0.0.0/16\n", cluster1.ID), values.Values)
+		assert.Equal(t, "10.0.0.0/16", values.Context.VPCCIDR)
+	})
+}
+
 func TestClusterAnnotations(t *testing.T) {
 	logger := testlib.MakeLogger(t)
 	sqlStore := store.MakeTestSQLStore(t, logger)
@@ -42,6 +42,25 @@ func (s *mockProvisioner) GetClusterResources(*model.Cluster, bool, log.FieldLog
 	return nil, nil
 }
 
+func (s *mockProvisioner) RenderClusterUtilityValues(cluster *model.Cluster, utilityName string, valuesContext *model.UtilityValuesContext) (*model.ClusterUtilityValues, error) {
+	if s.CommandError != nil {
+		return nil, s.CommandError
+	}
+
+	values, err := model.RenderUtilityValues("cluster: {{ .ClusterID }}\ncidr: {{ .VPCCIDR }}\n", valuesContext)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ClusterUtilityValues{
+		ClusterID: cluster.ID,
+		Utility:   utilityName,
+		Templated: true,
+		Values:    values,
+		Context:   valuesContext,
+	}, nil
+}
+
 func sToP(s string) *string {
 	return &s
 }
@@ -70,6 +70,15 @@ type Store interface {
 	GetWebhooks(filter *model.WebhookFilter) ([]*model.Webhook, error)
 	DeleteWebhook(webhookID string) error
 
+	CreateClusterTemplate(clusterTemplate *model.ClusterTemplate) error
+	GetClusterTemplate(clusterTemplateID string) (*model.ClusterTemplate, error)
+	GetClusterTemplates(filter *model.ClusterTemplateFilter) ([]*model.ClusterTemplate, error)
+	UpdateClusterTemplate(clusterTemplate *model.ClusterTemplate) error
+	DeleteClusterTemplate(clusterTemplateID string) error
+
+	CreateClusterSize(clusterSize *model.ClusterSize) error
+	GetClusterSizes(filter *model.ClusterSizeFilter) ([]*model.ClusterSize, error)
+
 	GetOrCreateAnnotations(annotations []*model.Annotation) ([]*model.Annotation, error)
 
 	CreateClusterAnnotations(clusterID string, annotations []*model.Annotation) ([]*model.Annotation, error)
@@ -93,12 +102,8 @@ type Store interface {
 	GetInstallationDBRestorationOperation(id string) (*model.InstallationDBRestorationOperation, error)
 	GetInstallationDBRestorationOperations(filter *model.InstallationDBRestorationFilter) ([]*model.InstallationDBRestorationOperation, error)
 
-	MigrateClusterInstallations(clusterInstallations []*model.ClusterInstallation, targetCluster string) error
-	SwitchDNS(oldCIsIDs, newCIsIDs, installationIDs []string, hibernatingInstallationIDs []string) error
 	DeleteClusterInstallation(id string) error
 	DeleteInActiveClusterInstallationByClusterID(clusterID string) (int64, error)
-	LockInstallations(installationIDs []string, lockerID string) (bool, error)
-	UnlockInstallations(installationIDs []string, lockerID string, force bool) (bool, error)
 	UpdateClusterInstallation(clusterInstallation *model.ClusterInstallation) error
 	TriggerInstallationDBMigration(dbMigrationOp *model.InstallationDBMigrationOperation, installation *model.Installation) (*model.InstallationDBMigrationOperation, error)
 	TriggerInstallationDBMigrationRollback(dbMigrationOp *model.InstallationDBMigrationOperation, installation *model.Installation) error
@@ -107,6 +112,12 @@ type Store interface {
 	UpdateInstallationDBMigrationOperationState(dbMigration *model.InstallationDBMigrationOperation) error
 	LockInstallationDBMigrationOperation(id, lockerID string) (bool, error)
 	UnlockInstallationDBMigrationOperation(id, lockerID string, force bool) (bool, error)
+	CreateClusterMigrationOperation(operation *model.ClusterMigrationOperation) error
+	GetClusterMigrationOperation(id string) (*model.ClusterMigrationOperation, error)
+	GetClusterMigrationOperations(filter *model.ClusterMigrationFilter) ([]*model.ClusterMigrationOperation, error)
+	UpdateClusterMigrationOperationState(operation *model.ClusterMigrationOperation) error
+	LockClusterMigrationOperations(ids []string, lockerID string) (bool, error)
+	UnlockClusterMigrationOperations(ids []string, lockerID string, force bool) (bool, error)
 
 	CreateSubscription(sub *model.Subscription) error
 	GetSubscriptions(filter *model.SubscriptionsFilter) ([]*model.Subscription, error)
@@ -121,12 +132,13 @@ type Provisioner interface {
 	ExecClusterInstallationCLI(cluster *model.Cluster, clusterInstallation *model.ClusterInstallation, args ...string) ([]byte, error)
 	ExecMattermostCLI(cluster *model.Cluster, clusterInstallation *model.ClusterInstallation, args ...string) ([]byte, error)
 	GetClusterResources(*model.Cluster, bool, log.FieldLogger) (*k8s.ClusterResources, error)
+	RenderClusterUtilityValues(cluster *model.Cluster, utilityName string, valuesContext *model.UtilityValuesContext) (*model.ClusterUtilityValues, error)
 }
 
 // AwsClient describes the interface required to communicate with the AWS
 type AwsClient interface {
-	SwitchClusterTags(clusterID string, targetClusterID string, logger logrus.FieldLogger) error
 	RDSDBCLusterExists(awsID string) (bool, error)
+	GetUtilityValuesContext(clusterID string, logger log.FieldLogger) (*model.UtilityValuesContext, error)
 }
 
 // DBProvider describes the interface required to get database for specific installation and specified type.
@@ -25,6 +25,7 @@ func initMultitenantDatabases(apiRouter *mux.Router, context *Context) {
 	MultitenantDatabaseRouter.Handle("", addContext(handleGetMultitenantDatabase)).Methods("GET")
 	MultitenantDatabaseRouter.Handle("", addContext(handleUpdateMultitenantDatabase)).Methods("PUT")
 	MultitenantDatabaseRouter.Handle("", addContext(handleDeleteMultitenantDatabase)).Methods("DELETE")
+	MultitenantDatabaseRouter.Handle("/upgrade", addContext(handleUpgradeMultitenantDatabase)).Methods("POST")
 }
 
 // handleGetMultitenantDatabases responds to GET /api/databases/multitenant_databases,
@@ -119,6 +120,57 @@ func handleUpdateMultitenantDatabase(c *Context, w http.ResponseWriter, r *http.
 	outputJSON(c, w, multitenantDatabase)
 }
 
+// handleUpgradeMultitenantDatabase responds to POST /api/databases/multitenant_database/{multitenant_database}/upgrade,
+// requesting an upgrade of the database engine version.
+func handleUpgradeMultitenantDatabase(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	multitenantDatabaseID := vars["multitenant_database"]
+	c.Logger = c.Logger.WithField("multitenant_database", multitenantDatabaseID)
+
+	upgradeDatabaseRequest, err := model.NewUpgradeMultitenantDatabaseRequestFromReader(r.Body)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to decode request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	multitenantDatabase, status, unlockOnce := lockDatabase(c, multitenantDatabaseID)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	defer unlockOnce()
+
+	if multitenantDatabase.DeleteAt > 0 {
+		c.Logger.Error("Cannot upgrade deleted multitenant database")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !multitenantDatabase.ValidUpgradeState() {
+		c.Logger.Errorf("Cannot upgrade multitenant database in state %s", multitenantDatabase.State)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	multitenantDatabase.State = model.DatabaseStateUpgradeRequested
+	multitenantDatabase.TargetEngineVersion = upgradeDatabaseRequest.EngineVersion
+	multitenantDatabase.UpgradeSnapshotID = ""
+
+	err = c.Store.UpdateMultitenantDatabase(multitenantDatabase)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to update multitenant database")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	unlockOnce()
+	c.Supervisor.Do()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	outputJSON(c, w, multitenantDatabase)
+}
+
 // handleDeleteMultitenantDatabase responds to DELETE /api/databases/multitenant_database/{multitenant_database},
 // marking the database as deleted.
 // WARNING: It does not delete actual database cluster.
@@ -265,6 +265,82 @@ func TestUpdateMultitenantDatabase(t *testing.T) {
 	})
 }
 
+func TestUpgradeMultitenantDatabase(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:      sqlStore,
+		Supervisor: &mockSupervisor{},
+		Logger:     logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	database1 := &model.MultitenantDatabase{
+		DatabaseType: model.DatabaseEngineTypePostgres,
+		State:        model.DatabaseStateStable,
+	}
+	err := sqlStore.CreateMultitenantDatabase(database1)
+	require.NoError(t, err)
+
+	t.Run("invalid payload", func(t *testing.T) {
+		httpRequest, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/databases/multitenant_database/%s/upgrade", ts.URL, database1.ID), bytes.NewReader([]byte("invalid")))
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(httpRequest)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("missing engine version", func(t *testing.T) {
+		database, err := client.UpgradeMultitenantDatabase(database1.ID, &model.UpgradeMultitenantDatabaseRequest{})
+		require.EqualError(t, err, "failed with status code 400")
+		require.Nil(t, database)
+	})
+
+	t.Run("unknown database", func(t *testing.T) {
+		database, err := client.UpgradeMultitenantDatabase(model.NewID(), &model.UpgradeMultitenantDatabaseRequest{EngineVersion: "13.7"})
+		require.EqualError(t, err, "failed with status code 404")
+		require.Nil(t, database)
+	})
+
+	t.Run("upgrade", func(t *testing.T) {
+		database, err := client.UpgradeMultitenantDatabase(database1.ID, &model.UpgradeMultitenantDatabaseRequest{EngineVersion: "13.7"})
+		require.NoError(t, err)
+		assert.Equal(t, model.DatabaseStateUpgradeRequested, database.State)
+		assert.Equal(t, "13.7", database.TargetEngineVersion)
+
+		database, err = sqlStore.GetMultitenantDatabase(database1.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.DatabaseStateUpgradeRequested, database.State)
+		assert.Equal(t, "13.7", database.TargetEngineVersion)
+		assert.Nil(t, database.LockAcquiredBy)
+	})
+
+	t.Run("upgrade already in progress", func(t *testing.T) {
+		database, err := client.UpgradeMultitenantDatabase(database1.ID, &model.UpgradeMultitenantDatabaseRequest{EngineVersion: "13.8"})
+		require.EqualError(t, err, "failed with status code 400")
+		require.Nil(t, database)
+	})
+
+	t.Run("retry failed upgrade", func(t *testing.T) {
+		database1.State = model.DatabaseStateUpgradeFailed
+		database1.UpgradeSnapshotID = "snapshot-id"
+		err = sqlStore.UpdateMultitenantDatabase(database1)
+		require.NoError(t, err)
+
+		database, err := client.UpgradeMultitenantDatabase(database1.ID, &model.UpgradeMultitenantDatabaseRequest{EngineVersion: "13.8"})
+		require.NoError(t, err)
+		assert.Equal(t, model.DatabaseStateUpgradeRequested, database.State)
+		assert.Equal(t, "13.8", database.TargetEngineVersion)
+		assert.Empty(t, database.UpgradeSnapshotID)
+	})
+}
+
 type mockAWSClient struct {
 	clusterExists bool
 	expectedRDSID string
@@ -281,6 +357,10 @@ func (m mockAWSClient) RDSDBCLusterExists(awsID string) (bool, error) {
 	return m.clusterExists, nil
 }
 
+func (m mockAWSClient) GetUtilityValuesContext(clusterID string, logger log.FieldLogger) (*model.UtilityValuesContext, error) {
+	return &model.UtilityValuesContext{ClusterID: clusterID, VPCCIDR: "10.0.0.0/16"}, nil
+}
+
 func TestDeleteMultitenantDatabase(t *testing.T) {
 	logger := testlib.MakeLogger(t)
 	sqlStore := store.MakeTestSQLStore(t, logger)
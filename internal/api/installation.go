@@ -39,6 +39,8 @@ func initInstallation(apiRouter *mux.Router, context *Context) {
 	installationRouter.Handle("/group", addContext(handleLeaveGroup)).Methods("DELETE")
 	installationRouter.Handle("/hibernate", addContext(handleHibernateInstallation)).Methods("POST")
 	installationRouter.Handle("/wakeup", addContext(handleWakeupInstallation)).Methods("POST")
+	installationRouter.Handle("/database/rotate_credentials", addContext(handleRotateInstallationDatabaseCredentials)).Methods("POST")
+	installationRouter.Handle("/filestore/rotate_access_key", addContext(handleRotateInstallationFilestoreAccessKey)).Methods("POST")
 	installationRouter.Handle("", addContext(handleDeleteInstallation)).Methods("DELETE")
 	installationRouter.Handle("/annotations", addContext(handleAddInstallationAnnotations)).Methods("POST")
 	installationRouter.Handle("/annotation/{annotation-name}", addContext(handleDeleteInstallationAnnotation)).Methods("DELETE")
@@ -196,6 +198,8 @@ func handleCreateInstallation(c *Context, w http.ResponseWriter, r *http.Request
 		MattermostEnv:              createInstallationRequest.MattermostEnv,
 		PriorityEnv:                createInstallationRequest.PriorityEnv,
 		SingleTenantDatabaseConfig: createInstallationRequest.SingleTenantDatabaseConfig.ToDBConfig(createInstallationRequest.Database),
+		Placement:                  createInstallationRequest.Placement,
+		DatabasePoolConfig:         createInstallationRequest.DatabasePoolConfig,
 		CRVersion:                  model.DefaultCRVersion,
 		State:                      model.InstallationStateCreationRequested,
 	}
@@ -288,6 +292,13 @@ func handleUpdateInstallation(c *Context, w http.ResponseWriter, r *http.Request
 	oldState := installationDTO.State
 
 	if patchInstallationRequest.Apply(installationDTO.Installation) {
+		err = validateDatabasePoolConfig(c, installationDTO.Installation)
+		if err != nil {
+			c.Logger.WithError(err).Error("invalid database pool config")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		installationDTO.State = newState
 
 		err = c.Store.UpdateInstallation(installationDTO.Installation)
@@ -311,6 +322,28 @@ func handleUpdateInstallation(c *Context, w http.ResponseWriter, r *http.Request
 	outputJSON(c, w, installationDTO)
 }
 
+// validateDatabasePoolConfig validates the database pool overrides of an
+// installation against the proxy database it is assigned to.
+func validateDatabasePoolConfig(c *Context, installation *model.Installation) error {
+	if installation.DatabasePoolConfig.IsEmpty() {
+		return nil
+	}
+	if installation.Database != model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer {
+		return errors.Errorf("database pool config is not supported with database %s", installation.Database)
+	}
+
+	maxInstallationsPerLogicalDatabase := model.GetDefaultProxyDatabaseMaxInstallationsPerLogicalDatabase()
+	databaseResources, err := c.Store.GetProxyDatabaseResourcesForInstallation(installation.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get proxy database resources")
+	}
+	if databaseResources != nil {
+		maxInstallationsPerLogicalDatabase = databaseResources.MultitenantDatabase.MaxInstallationsPerLogicalDatabase
+	}
+
+	return installation.DatabasePoolConfig.ValidateForLogicalDatabase(maxInstallationsPerLogicalDatabase)
+}
+
 // handleJoinGroup responds to PUT /api/installation/{installation}/group/{group}, joining the group.
 func handleJoinGroup(c *Context, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -455,6 +488,82 @@ func handleHibernateInstallation(c *Context, w http.ResponseWriter, r *http.Requ
 	outputJSON(c, w, installationDTO)
 }
 
+// handleRotateInstallationDatabaseCredentials responds to POST
+// /api/installation/{installation}/database/rotate_credentials, requesting a
+// rotation of the installation database credentials.
+func handleRotateInstallationDatabaseCredentials(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	installationID := vars["installation"]
+	c.Logger = c.Logger.WithField("installation", installationID)
+
+	newState := model.InstallationStateDBCredentialRotationRequested
+
+	installationDTO, status, unlockOnce := getInstallationForTransition(c, installationID, newState)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	defer unlockOnce()
+
+	if !model.SupportsCredentialRotation(installationDTO.Database) {
+		c.Logger.Warnf("database credential rotation is not supported for database type %q", installationDTO.Database)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := updateInstallationState(c, installationDTO, newState)
+	if err != nil {
+		c.Logger.WithError(err).Errorf("failed to update installation state to %q", newState)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	unlockOnce()
+	c.Supervisor.Do()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	outputJSON(c, w, installationDTO)
+}
+
+// handleRotateInstallationFilestoreAccessKey responds to POST
+// /api/installation/{installation}/filestore/rotate_access_key, requesting a
+// rotation of the installation filestore access key.
+func handleRotateInstallationFilestoreAccessKey(c *Context, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	installationID := vars["installation"]
+	c.Logger = c.Logger.WithField("installation", installationID)
+
+	newState := model.InstallationStateFilestoreAccessKeyRotationRequested
+
+	installationDTO, status, unlockOnce := getInstallationForTransition(c, installationID, newState)
+	if status != 0 {
+		w.WriteHeader(status)
+		return
+	}
+	defer unlockOnce()
+
+	if !model.SupportsAccessKeyRotation(installationDTO.Filestore) {
+		c.Logger.Warnf("filestore access key rotation is not supported for filestore type %q", installationDTO.Filestore)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := updateInstallationState(c, installationDTO, newState)
+	if err != nil {
+		c.Logger.WithError(err).Errorf("failed to update installation state to %q", newState)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	unlockOnce()
+	c.Supervisor.Do()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	outputJSON(c, w, installationDTO)
+}
+
 // handleWakeupInstallation responds to POST /api/installation/{installation}/wakeup,
 // moving the installation out of a hibernation state.
 func handleWakeupInstallation(c *Context, w http.ResponseWriter, r *http.Request) {
@@ -71,11 +71,14 @@ func handleTriggerInstallationDatabaseMigration(c *Context, w http.ResponseWrite
 		return
 	}
 
-	currentDB, err := c.Store.GetMultitenantDatabaseForInstallationID(installationDTO.ID)
-	if err != nil {
-		c.Logger.WithError(err).Error("Failed to get current multi-tenant database for installation")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+	var currentDB *model.MultitenantDatabase
+	if installationDTO.Database != model.InstallationDatabaseSingleTenantRDSMySQL {
+		currentDB, err = c.Store.GetMultitenantDatabaseForInstallationID(installationDTO.ID)
+		if err != nil {
+			c.Logger.WithError(err).Error("Failed to get current multi-tenant database for installation")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 	}
 
 	err = validateDBMigration(c, installationDTO.Installation, migrationRequest, currentDB)
@@ -89,9 +92,11 @@ func handleTriggerInstallationDatabaseMigration(c *Context, w http.ResponseWrite
 		InstallationID:         migrationRequest.InstallationID,
 		SourceDatabase:         installationDTO.Database,
 		DestinationDatabase:    migrationRequest.DestinationDatabase,
-		SourceMultiTenant:      &model.MultiTenantDBMigrationData{DatabaseID: currentDB.ID},
 		DestinationMultiTenant: migrationRequest.DestinationMultiTenant,
 	}
+	if currentDB != nil {
+		dbMigrationOperation.SourceMultiTenant = &model.MultiTenantDBMigrationData{DatabaseID: currentDB.ID}
+	}
 
 	oldInstallationState := installationDTO.State
 
@@ -282,10 +287,29 @@ func handleRollbackInstallationDatabaseMigration(c *Context, w http.ResponseWrit
 	outputJSON(c, w, dbMigrationOperation)
 }
 
+// validateDBMigration validates the requested migration. The currentDB is nil
+// when the installation is using a single tenant database.
 func validateDBMigration(c *Context, installation *model.Installation, migrationRequest *model.InstallationDBMigrationRequest, currentDB *model.MultitenantDatabase) error {
-	if migrationRequest.DestinationDatabase != model.InstallationDatabaseMultiTenantRDSPostgres ||
-		installation.Database != model.InstallationDatabaseMultiTenantRDSPostgres {
-		return errors.Errorf("db migration is supported only when both source and destination are %q database types", model.InstallationDatabaseMultiTenantRDSPostgres)
+	switch installation.Database {
+	case model.InstallationDatabaseMultiTenantRDSPostgres:
+	case model.InstallationDatabaseSingleTenantRDSMySQL, model.InstallationDatabaseMultiTenantRDSMySQL:
+		// MySQL installations can only be migrated to PostgreSQL with the database engine migration.
+		if migrationRequest.DestinationDatabase != model.InstallationDatabaseMultiTenantRDSPostgres {
+			return errors.Errorf("db migration from MySQL database is supported only when destination is %q database type", model.InstallationDatabaseMultiTenantRDSPostgres)
+		}
+	default:
+		return errors.Errorf("db migration is supported only when source is %q, %q or %q database type",
+			model.InstallationDatabaseMultiTenantRDSPostgres, model.InstallationDatabaseMultiTenantRDSMySQL, model.InstallationDatabaseSingleTenantRDSMySQL)
+	}
+
+	switch migrationRequest.DestinationDatabase {
+	case model.InstallationDatabaseMultiTenantRDSPostgres:
+	case model.InstallationDatabaseExternalPostgres:
+		// The external postgres server is configured on the provisioning
+		// server, so there is no destination database to validate.
+		return nil
+	default:
+		return errors.Errorf("db migration is supported only when destination is %q or %q database type", model.InstallationDatabaseMultiTenantRDSPostgres, model.InstallationDatabaseExternalPostgres)
 	}
 
 	if migrationRequest.DestinationMultiTenant == nil {
@@ -300,12 +324,14 @@ func validateDBMigration(c *Context, installation *model.Installation, migration
 		return errors.Errorf("destination database with id %q not found", migrationRequest.DestinationMultiTenant.DatabaseID)
 	}
 
-	if currentDB.ID == destinationDB.ID {
-		return errors.New("destination database is the same as current")
-	}
+	if currentDB != nil {
+		if currentDB.ID == destinationDB.ID {
+			return errors.New("destination database is the same as current")
+		}
 
-	if currentDB.VpcID != destinationDB.VpcID {
-		return errors.New("databases VPCs do not match, only migration inside the same VPC is supported")
+		if currentDB.VpcID != destinationDB.VpcID {
+			return errors.New("databases VPCs do not match, only migration inside the same VPC is supported")
+		}
 	}
 
 	err = common.ValidateDBMigrationDestination(c.Store, destinationDB, installation.ID, aws.DefaultRDSMultitenantDatabasePostgresCountLimit)
@@ -92,6 +92,26 @@ func TestTriggerInstallationDBMigration(t *testing.T) {
 	err = sqlStore.UpdateInstallation(installation1.Installation)
 	require.NoError(t, err)
 
+	t.Run("fail to trigger migration if destination database is being upgraded", func(t *testing.T) {
+		destinationDB := &model.MultitenantDatabase{
+			RdsClusterID: "cluster4",
+			VpcID:        "vpc1",
+			DatabaseType: model.DatabaseEngineTypePostgres,
+			State:        model.DatabaseStateUpgradeRequested,
+		}
+		err = sqlStore.CreateMultitenantDatabase(destinationDB)
+		require.NoError(t, err)
+
+		migrationRequest := &model.InstallationDBMigrationRequest{
+			InstallationID:         installation1.ID,
+			DestinationDatabase:    model.InstallationDatabaseMultiTenantRDSPostgres,
+			DestinationMultiTenant: &model.MultiTenantDBMigrationData{DatabaseID: destinationDB.ID},
+		}
+		migrationOperation, err = client.MigrateInstallationDatabase(migrationRequest)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "400")
+	})
+
 	t.Run("fail to trigger migration if other migration succeeded but not committed", func(t *testing.T) {
 		succeededMigration := &model.InstallationDBMigrationOperation{State: model.InstallationDBMigrationStateSucceeded, InstallationID: installation1.ID}
 		err = sqlStore.CreateInstallationDBMigrationOperation(succeededMigration)
@@ -158,6 +178,145 @@ func TestTriggerInstallationDBMigration(t *testing.T) {
 	})
 }
 
+func TestTriggerInstallationDBMigrationToExternalPostgres(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+
+	ts := httptest.NewServer(router)
+	client := model.NewClient(ts.URL)
+	installation1, err := client.CreateInstallation(
+		&model.CreateInstallationRequest{
+			OwnerID:   "owner",
+			DNS:       "dns1.example.com",
+			Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+			Filestore: model.InstallationFilestoreBifrost,
+		})
+	require.NoError(t, err)
+	installation1.State = model.InstallationStateHibernating
+	err = sqlStore.UpdateInstallation(installation1.Installation)
+	require.NoError(t, err)
+
+	currentDB := &model.MultitenantDatabase{
+		RdsClusterID:  "cluster1",
+		VpcID:         "vpc1",
+		DatabaseType:  model.DatabaseEngineTypePostgres,
+		Installations: model.MultitenantDatabaseInstallations{installation1.ID},
+	}
+	err = sqlStore.CreateMultitenantDatabase(currentDB)
+	require.NoError(t, err)
+
+	migrationOperation, err := client.MigrateInstallationDatabase(&model.InstallationDBMigrationRequest{
+		InstallationID:      installation1.ID,
+		DestinationDatabase: model.InstallationDatabaseExternalPostgres,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, model.InstallationDBMigrationStateRequested, migrationOperation.State)
+	assert.Equal(t, model.InstallationDatabaseExternalPostgres, migrationOperation.DestinationDatabase)
+	assert.Equal(t, currentDB.ID, migrationOperation.SourceMultiTenant.DatabaseID)
+	assert.Nil(t, migrationOperation.DestinationMultiTenant)
+}
+
+func TestTriggerInstallationDBMigrationFromMySQL(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+
+	ts := httptest.NewServer(router)
+	client := model.NewClient(ts.URL)
+
+	destinationDB := &model.MultitenantDatabase{
+		RdsClusterID: "cluster2",
+		VpcID:        "vpc1",
+		DatabaseType: model.DatabaseEngineTypePostgres,
+	}
+	err := sqlStore.CreateMultitenantDatabase(destinationDB)
+	require.NoError(t, err)
+
+	createHibernatingInstallation := func(t *testing.T, dns, database string) *model.InstallationDTO {
+		installation, err := client.CreateInstallation(
+			&model.CreateInstallationRequest{
+				OwnerID:   "owner",
+				DNS:       dns,
+				Database:  database,
+				Filestore: model.InstallationFilestoreBifrost,
+			})
+		require.NoError(t, err)
+		installation.State = model.InstallationStateHibernating
+		err = sqlStore.UpdateInstallation(installation.Installation)
+		require.NoError(t, err)
+
+		return installation
+	}
+
+	t.Run("single tenant mysql", func(t *testing.T) {
+		installation := createHibernatingInstallation(t, "dns1.example.com", model.InstallationDatabaseSingleTenantRDSMySQL)
+
+		t.Run("fail to trigger migration to external postgres", func(t *testing.T) {
+			_, err := client.MigrateInstallationDatabase(&model.InstallationDBMigrationRequest{
+				InstallationID:      installation.ID,
+				DestinationDatabase: model.InstallationDatabaseExternalPostgres,
+			})
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "400")
+		})
+
+		migrationOperation, err := client.MigrateInstallationDatabase(&model.InstallationDBMigrationRequest{
+			InstallationID:         installation.ID,
+			DestinationDatabase:    model.InstallationDatabaseMultiTenantRDSPostgres,
+			DestinationMultiTenant: &model.MultiTenantDBMigrationData{DatabaseID: destinationDB.ID},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, model.InstallationDBMigrationStateRequested, migrationOperation.State)
+		assert.Equal(t, model.InstallationDatabaseSingleTenantRDSMySQL, migrationOperation.SourceDatabase)
+		assert.Nil(t, migrationOperation.SourceMultiTenant)
+		assert.True(t, migrationOperation.IsEngineMigration())
+	})
+
+	t.Run("multitenant mysql", func(t *testing.T) {
+		installation := createHibernatingInstallation(t, "dns2.example.com", model.InstallationDatabaseMultiTenantRDSMySQL)
+
+		currentDB := &model.MultitenantDatabase{
+			RdsClusterID:  "cluster1",
+			VpcID:         "vpc1",
+			DatabaseType:  model.DatabaseEngineTypeMySQL,
+			Installations: model.MultitenantDatabaseInstallations{installation.ID},
+		}
+		err := sqlStore.CreateMultitenantDatabase(currentDB)
+		require.NoError(t, err)
+
+		migrationOperation, err := client.MigrateInstallationDatabase(&model.InstallationDBMigrationRequest{
+			InstallationID:         installation.ID,
+			DestinationDatabase:    model.InstallationDatabaseMultiTenantRDSPostgres,
+			DestinationMultiTenant: &model.MultiTenantDBMigrationData{DatabaseID: destinationDB.ID},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, model.InstallationDatabaseMultiTenantRDSMySQL, migrationOperation.SourceDatabase)
+		require.NotNil(t, migrationOperation.SourceMultiTenant)
+		assert.Equal(t, currentDB.ID, migrationOperation.SourceMultiTenant.DatabaseID)
+		assert.True(t, migrationOperation.IsEngineMigration())
+	})
+}
+
 func TestGetInstallationDBMigrationOperations(t *testing.T) {
 	logger := testlib.MakeLogger(t)
 	sqlStore := store.MakeTestSQLStore(t, logger)
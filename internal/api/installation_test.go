@@ -1225,6 +1225,172 @@ func TestWakeUpInstallation(t *testing.T) {
 	})
 }
 
+func TestRotateInstallationDatabaseCredentials(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	installation1, err := client.CreateInstallation(&model.CreateInstallationRequest{
+		OwnerID:   "owner",
+		Version:   "version",
+		DNS:       "dns1.example.com",
+		Affinity:  model.InstallationAffinityMultiTenant,
+		Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+		Filestore: model.InstallationFilestoreBifrost,
+	})
+	require.NoError(t, err)
+
+	t.Run("unknown installation", func(t *testing.T) {
+		installationResponse, err := client.RotateInstallationDatabaseCredentials(model.NewID())
+		require.EqualError(t, err, "failed with status code 404")
+		require.Nil(t, installationResponse)
+	})
+
+	t.Run("while creating", func(t *testing.T) {
+		installationResponse, err := client.RotateInstallationDatabaseCredentials(installation1.ID)
+		require.EqualError(t, err, "failed with status code 400")
+		require.Nil(t, installationResponse)
+	})
+
+	t.Run("while api-security-locked", func(t *testing.T) {
+		err = sqlStore.LockInstallationAPI(installation1.ID)
+		require.NoError(t, err)
+
+		installationResponse, err := client.RotateInstallationDatabaseCredentials(installation1.ID)
+		require.EqualError(t, err, "failed with status code 403")
+		require.Nil(t, installationResponse)
+
+		err = sqlStore.UnlockInstallationAPI(installation1.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("database not supported", func(t *testing.T) {
+		installation2, err := client.CreateInstallation(&model.CreateInstallationRequest{
+			OwnerID:   "owner",
+			Version:   "version",
+			DNS:       "dns2.example.com",
+			Affinity:  model.InstallationAffinityIsolated,
+			Database:  model.InstallationDatabaseSingleTenantRDSPostgres,
+			Filestore: model.InstallationFilestoreBifrost,
+		})
+		require.NoError(t, err)
+
+		installation2.State = model.InstallationStateStable
+		err = sqlStore.UpdateInstallation(installation2.Installation)
+		require.NoError(t, err)
+
+		installationResponse, err := client.RotateInstallationDatabaseCredentials(installation2.ID)
+		require.EqualError(t, err, "failed with status code 400")
+		require.Nil(t, installationResponse)
+	})
+
+	t.Run("while stable", func(t *testing.T) {
+		installation1.State = model.InstallationStateStable
+		err = sqlStore.UpdateInstallation(installation1.Installation)
+		require.NoError(t, err)
+
+		installationResponse, err := client.RotateInstallationDatabaseCredentials(installation1.ID)
+		require.NoError(t, err)
+		require.NotNil(t, installationResponse)
+		assert.Equal(t, model.InstallationStateDBCredentialRotationRequested, installationResponse.State)
+	})
+}
+
+func TestRotateInstallationFilestoreAccessKey(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	router := mux.NewRouter()
+	api.Register(router, &api.Context{
+		Store:         sqlStore,
+		Supervisor:    &mockSupervisor{},
+		EventProducer: testutil.SetupTestEventsProducer(sqlStore, logger),
+		Logger:        logger,
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := model.NewClient(ts.URL)
+
+	installation1, err := client.CreateInstallation(&model.CreateInstallationRequest{
+		OwnerID:   "owner",
+		Version:   "version",
+		DNS:       "dns1.example.com",
+		Affinity:  model.InstallationAffinityMultiTenant,
+		Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+		Filestore: model.InstallationFilestoreMultiTenantAwsS3,
+	})
+	require.NoError(t, err)
+
+	t.Run("unknown installation", func(t *testing.T) {
+		installationResponse, err := client.RotateInstallationFilestoreAccessKey(model.NewID())
+		require.EqualError(t, err, "failed with status code 404")
+		require.Nil(t, installationResponse)
+	})
+
+	t.Run("while creating", func(t *testing.T) {
+		installationResponse, err := client.RotateInstallationFilestoreAccessKey(installation1.ID)
+		require.EqualError(t, err, "failed with status code 400")
+		require.Nil(t, installationResponse)
+	})
+
+	t.Run("while api-security-locked", func(t *testing.T) {
+		err = sqlStore.LockInstallationAPI(installation1.ID)
+		require.NoError(t, err)
+
+		installationResponse, err := client.RotateInstallationFilestoreAccessKey(installation1.ID)
+		require.EqualError(t, err, "failed with status code 403")
+		require.Nil(t, installationResponse)
+
+		err = sqlStore.UnlockInstallationAPI(installation1.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("filestore not supported", func(t *testing.T) {
+		installation2, err := client.CreateInstallation(&model.CreateInstallationRequest{
+			OwnerID:   "owner",
+			Version:   "version",
+			DNS:       "dns2.example.com",
+			Affinity:  model.InstallationAffinityIsolated,
+			Database:  model.InstallationDatabaseSingleTenantRDSPostgres,
+			Filestore: model.InstallationFilestoreBifrost,
+		})
+		require.NoError(t, err)
+
+		installation2.State = model.InstallationStateStable
+		err = sqlStore.UpdateInstallation(installation2.Installation)
+		require.NoError(t, err)
+
+		installationResponse, err := client.RotateInstallationFilestoreAccessKey(installation2.ID)
+		require.EqualError(t, err, "failed with status code 400")
+		require.Nil(t, installationResponse)
+	})
+
+	t.Run("while stable", func(t *testing.T) {
+		installation1.State = model.InstallationStateStable
+		err = sqlStore.UpdateInstallation(installation1.Installation)
+		require.NoError(t, err)
+
+		installationResponse, err := client.RotateInstallationFilestoreAccessKey(installation1.ID)
+		require.NoError(t, err)
+		require.NotNil(t, installationResponse)
+		assert.Equal(t, model.InstallationStateFilestoreAccessKeyRotationRequested, installationResponse.State)
+	})
+}
+
 func TestLeaveGroup(t *testing.T) {
 	logger := testlib.MakeLogger(t)
 	sqlStore := store.MakeTestSQLStore(t, logger)
@@ -186,6 +186,41 @@ func lockInstallationDBMigrationOperation(c *Context, operationID string) (*mode
 	}
 }
 
+// lockClusterMigrationOperation synchronizes access to the given cluster
+// migration operation across potentially multiple provisioning servers.
+func lockClusterMigrationOperation(c *Context, operationID string) (*model.ClusterMigrationOperation, int, func()) {
+	operation, err := c.Store.GetClusterMigrationOperation(operationID)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to query cluster migration operation")
+		return nil, http.StatusInternalServerError, nil
+	}
+	if operation == nil {
+		return nil, http.StatusNotFound, nil
+	}
+
+	locked, err := c.Store.LockClusterMigrationOperations([]string{operationID}, c.RequestID)
+	if err != nil {
+		c.Logger.WithError(err).Error("failed to lock cluster migration operation")
+		return nil, http.StatusInternalServerError, nil
+	} else if !locked {
+		c.Logger.Error("failed to acquire lock for cluster migration operation")
+		return nil, http.StatusConflict, nil
+	}
+
+	unlockOnce := sync.Once{}
+
+	return operation, 0, func() {
+		unlockOnce.Do(func() {
+			unlocked, err := c.Store.UnlockClusterMigrationOperations([]string{operation.ID}, c.RequestID, false)
+			if err != nil {
+				c.Logger.WithError(err).Errorf("failed to unlock cluster migration operation")
+			} else if unlocked != true {
+				c.Logger.Warn("failed to release lock for cluster migration operation")
+			}
+		})
+	}
+}
+
 // lockDatabase synchronizes access to the given multitenant database across
 // potentially multiple provisioning servers.
 func lockDatabase(c *Context, databaseID string) (*model.MultitenantDatabase, int, func()) {
@@ -17,6 +17,7 @@ func TestNewCreateClusterRequestFromReader(t *testing.T) {
 	defaultCreateClusterRequest := func() *model.CreateClusterRequest {
 		return &model.CreateClusterRequest{
 			Provider:           "aws",
+			Provisioner:        model.ProvisionerKops,
 			Version:            "latest",
 			MasterInstanceType: "t3.medium",
 			MasterCount:        1,
@@ -77,11 +78,12 @@ func TestNewCreateClusterRequestFromReader(t *testing.T) {
 
 	t.Run("full request", func(t *testing.T) {
 		clusterRequest, err := model.NewCreateClusterRequestFromReader(bytes.NewReader([]byte(
-			`{"Provider": "aws", "Version": "1.12.4", "Size": "SizeAlef1000", "Zones": ["zone1", "zone2"]}`,
+			`{"Provider": "aws", "Version": "1.12.4", "Zones": ["zone1", "zone2"]}`,
 		)))
 		require.NoError(t, err)
 		require.Equal(t, &model.CreateClusterRequest{
 			Provider:           model.ProviderAWS,
+			Provisioner:        model.ProvisionerKops,
 			Version:            "1.12.4",
 			MasterInstanceType: "t3.medium",
 			MasterCount:        1,
@@ -104,6 +106,19 @@ func TestNewCreateClusterRequestFromReader(t *testing.T) {
 			},
 		}, clusterRequest)
 	})
+
+	t.Run("request with size", func(t *testing.T) {
+		clusterRequest, err := model.NewCreateClusterRequestFromReader(bytes.NewReader([]byte(
+			`{"Provider": "aws", "Version": "1.12.4", "Size": "SizeAlef1000", "Zones": ["zone1", "zone2"]}`,
+		)))
+		require.NoError(t, err)
+		require.Equal(t, &model.CreateClusterRequest{
+			Provider: model.ProviderAWS,
+			Version:  "1.12.4",
+			Size:     "SizeAlef1000",
+			Zones:    []string{"zone1", "zone2"},
+		}, clusterRequest)
+	})
 }
 
 func TestGetClustersRequestApplyToURL(t *testing.T) {
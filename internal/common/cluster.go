@@ -0,0 +1,169 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package common
+
+import (
+	"net/http"
+
+	"github.com/mattermost/mattermost-cloud/clusterdictionary"
+	"github.com/mattermost/mattermost-cloud/internal/events"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type clusterConfigStore interface {
+	GetClusterSizes(filter *model.ClusterSizeFilter) ([]*model.ClusterSize, error)
+	GetClusterTemplate(id string) (*model.ClusterTemplate, error)
+	GetClusterTemplates(filter *model.ClusterTemplateFilter) ([]*model.ClusterTemplate, error)
+}
+
+type clusterCreationStore interface {
+	clusterConfigStore
+	CreateCluster(cluster *model.Cluster, annotations []*model.Annotation) error
+}
+
+type clusterEventProducer interface {
+	ProduceClusterStateChangeEvent(cluster *model.Cluster, oldState string, extraDataFields ...events.DataField) error
+}
+
+// CreateCluster completes the create cluster request with the cluster size
+// and template it references and creates the new cluster. Values provided in
+// the request take precedence over the size, which takes precedence over the
+// template.
+func CreateCluster(store clusterCreationStore, createClusterRequest *model.CreateClusterRequest, eventsProducer clusterEventProducer, logger log.FieldLogger) (*model.Cluster, []*model.Annotation, error) {
+	clusterTemplateID, err := completeCreateClusterRequest(store, createClusterRequest)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	annotations, err := model.AnnotationsFromStringSlice(createClusterRequest.Annotations)
+	if err != nil {
+		return nil, nil, ErrWrap(http.StatusBadRequest, err, "invalid cluster annotations")
+	}
+
+	cluster := createClusterRequest.Cluster(clusterTemplateID)
+	err = store.CreateCluster(cluster, annotations)
+	if err != nil {
+		return nil, nil, ErrWrap(http.StatusInternalServerError, err, "failed to create cluster")
+	}
+
+	err = eventsProducer.ProduceClusterStateChangeEvent(cluster, model.NonApplicableState)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create cluster state change event")
+	}
+
+	return cluster, annotations, nil
+}
+
+// completeCreateClusterRequest completes a cluster create request referencing
+// a cluster size or template with their values, then sets the defaults and
+// validates the request. The ID of the applied cluster template is returned.
+func completeCreateClusterRequest(store clusterConfigStore, createClusterRequest *model.CreateClusterRequest) (string, error) {
+	if len(createClusterRequest.Size) != 0 {
+		clusterSize, err := GetClusterSize(store, createClusterRequest.Size)
+		if err != nil {
+			return "", ErrWrap(http.StatusInternalServerError, err, "failed to query cluster size")
+		}
+		if clusterSize == nil {
+			return "", NewErr(http.StatusBadRequest, errors.Errorf("cluster size %s not found", createClusterRequest.Size))
+		}
+		clusterSize.ApplyToCreateClusterRequest(createClusterRequest)
+	}
+
+	var clusterTemplateID string
+	if len(createClusterRequest.Template) != 0 {
+		clusterTemplate, err := GetClusterTemplateByNameOrID(store, createClusterRequest.Template)
+		if err != nil {
+			return "", ErrWrap(http.StatusInternalServerError, err, "failed to query cluster template")
+		}
+		if clusterTemplate == nil {
+			return "", NewErr(http.StatusBadRequest, errors.Errorf("cluster template %s not found", createClusterRequest.Template))
+		}
+		clusterTemplateID = clusterTemplate.ID
+
+		// The size of the template is only used when the request doesn't
+		// specify its own size.
+		if len(createClusterRequest.Size) == 0 && len(clusterTemplate.Cluster.Size) != 0 {
+			clusterSize, err := GetClusterSize(store, clusterTemplate.Cluster.Size)
+			if err != nil {
+				return "", ErrWrap(http.StatusInternalServerError, err, "failed to query cluster size")
+			}
+			if clusterSize == nil {
+				return "", NewErr(http.StatusBadRequest, errors.Errorf("cluster size %s of cluster template %s not found", clusterTemplate.Cluster.Size, clusterTemplate.Name))
+			}
+			clusterTemplate.Cluster = clusterTemplate.Cluster.Clone()
+			clusterSize.ApplyToCreateClusterRequest(clusterTemplate.Cluster)
+		}
+
+		createClusterRequest.ApplyClusterTemplate(clusterTemplate)
+	}
+
+	createClusterRequest.SetDefaults()
+	err := createClusterRequest.Validate()
+	if err != nil {
+		return "", ErrWrap(http.StatusBadRequest, err, "create cluster request failed validation")
+	}
+
+	return clusterTemplateID, nil
+}
+
+// GetClusterSize returns the built-in or stored cluster size with the given
+// name, or nil if there is none.
+func GetClusterSize(store clusterConfigStore, name string) (*model.ClusterSize, error) {
+	clusterSize := clusterdictionary.GetSize(name)
+	if clusterSize != nil {
+		return clusterSize, nil
+	}
+
+	clusterSizes, err := store.GetClusterSizes(&model.ClusterSizeFilter{
+		Paging: model.AllPagesNotDeleted(),
+		Name:   name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster sizes by name")
+	}
+	if len(clusterSizes) == 0 {
+		return nil, nil
+	}
+
+	return clusterSizes[0], nil
+}
+
+// GetClusterTemplateByName returns the cluster template with the given name
+// which is not deleted, or nil if there is none.
+func GetClusterTemplateByName(store clusterConfigStore, name string) (*model.ClusterTemplate, error) {
+	clusterTemplates, err := store.GetClusterTemplates(&model.ClusterTemplateFilter{
+		Paging: model.AllPagesNotDeleted(),
+		Name:   name,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster templates by name")
+	}
+	if len(clusterTemplates) == 0 {
+		return nil, nil
+	}
+
+	return clusterTemplates[0], nil
+}
+
+// GetClusterTemplateByNameOrID returns the cluster template which is not
+// deleted with the given name or ID, or nil if there is none.
+func GetClusterTemplateByNameOrID(store clusterConfigStore, nameOrID string) (*model.ClusterTemplate, error) {
+	clusterTemplate, err := GetClusterTemplateByName(store, nameOrID)
+	if err != nil || clusterTemplate != nil {
+		return clusterTemplate, err
+	}
+
+	clusterTemplate, err = store.GetClusterTemplate(nameOrID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster template by id")
+	}
+	if clusterTemplate == nil || clusterTemplate.IsDeleted() {
+		return nil, nil
+	}
+
+	return clusterTemplate, nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package common
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/clusterdictionary"
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/internal/testutil"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateCluster(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+	eventsProducer := testutil.SetupTestEventsProducer(sqlStore, logger)
+
+	template := &model.ClusterTemplate{
+		Name: "template",
+		Cluster: &model.CreateClusterRequest{
+			Size:               clusterdictionary.SizeAlef1000,
+			AllowInstallations: true,
+			Annotations:        []string{"multi-tenant"},
+		},
+	}
+	err := sqlStore.CreateClusterTemplate(template)
+	require.NoError(t, err)
+
+	t.Run("from template", func(t *testing.T) {
+		cluster, annotations, err := CreateCluster(sqlStore, &model.CreateClusterRequest{Template: template.Name}, eventsProducer, logger)
+		require.NoError(t, err)
+		assert.Equal(t, template.ID, cluster.ClusterTemplateID)
+		assert.Equal(t, model.ClusterStateCreationRequested, cluster.State)
+		assert.True(t, cluster.AllowInstallations)
+		assert.Equal(t, clusterdictionary.ValidSizes[clusterdictionary.SizeAlef1000].NodeMinCount, cluster.ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+		require.Len(t, annotations, 1)
+		assert.Equal(t, "multi-tenant", annotations[0].Name)
+
+		storedCluster, err := sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, template.ID, storedCluster.ClusterTemplateID)
+	})
+
+	t.Run("template not found", func(t *testing.T) {
+		_, _, err := CreateCluster(sqlStore, &model.CreateClusterRequest{Template: "unknown"}, eventsProducer, logger)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, ErrToStatus(err))
+	})
+
+	t.Run("size not found", func(t *testing.T) {
+		_, _, err := CreateCluster(sqlStore, &model.CreateClusterRequest{Size: "unknown"}, eventsProducer, logger)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusBadRequest, ErrToStatus(err))
+	})
+}
@@ -15,6 +15,9 @@ type dbMigrationValidationStore interface {
 
 // ValidateDBMigrationDestination validates if installation can be migrated to destinationDB.
 func ValidateDBMigrationDestination(store dbMigrationValidationStore, destinationDB *model.MultitenantDatabase, installationID string, maxWeight float64) error {
+	if destinationDB.IsUpgrading() {
+		return errors.Errorf("cannot migrate to database %q, it is being upgraded", destinationDB.ID)
+	}
 	if Contains(destinationDB.MigratedInstallations, installationID) {
 		return errors.Errorf("installation %q still exists in migrated installations for %q database, clean it up before migration", installationID, destinationDB.ID)
 	}
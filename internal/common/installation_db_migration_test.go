@@ -46,4 +46,11 @@ func TestValidateDBMigrationDestination(t *testing.T) {
 		err = ValidateDBMigrationDestination(sqlStore, database, "migrated", 10)
 		require.Error(t, err)
 	})
+
+	t.Run("destination database being upgraded", func(t *testing.T) {
+		upgradingDatabase := *database
+		upgradingDatabase.State = model.DatabaseStateUpgradeInProgress
+		err = ValidateDBMigrationDestination(sqlStore, &upgradingDatabase, "installation", 10)
+		require.Error(t, err)
+	})
 }
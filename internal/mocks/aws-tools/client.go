@@ -9,12 +9,14 @@ package mockawstools
 
 import (
 	acm "github.com/aws/aws-sdk-go/service/acm"
+	eks "github.com/aws/aws-sdk-go/service/eks"
 	gomock "github.com/golang/mock/gomock"
 	aws "github.com/mattermost/mattermost-cloud/internal/tools/aws"
 	model "github.com/mattermost/mattermost-cloud/model"
 	logrus "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
 	reflect "reflect"
+	time "time"
 )
 
 // MockAWS is a mock of AWS interface
@@ -99,6 +101,21 @@ func (mr *MockAWSMockRecorder) GetVpcResources(clusterID, logger interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVpcResources", reflect.TypeOf((*MockAWS)(nil).GetVpcResources), clusterID, logger)
 }
 
+// GetUtilityValuesContext mocks base method
+func (m *MockAWS) GetUtilityValuesContext(clusterID string, logger logrus.FieldLogger) (*model.UtilityValuesContext, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUtilityValuesContext", clusterID, logger)
+	ret0, _ := ret[0].(*model.UtilityValuesContext)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUtilityValuesContext indicates an expected call of GetUtilityValuesContext
+func (mr *MockAWSMockRecorder) GetUtilityValuesContext(clusterID, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUtilityValuesContext", reflect.TypeOf((*MockAWS)(nil).GetUtilityValuesContext), clusterID, logger)
+}
+
 // ReleaseVpc mocks base method
 func (m *MockAWS) ReleaseVpc(clusterID string, logger logrus.FieldLogger) error {
 	m.ctrl.T.Helper()
@@ -312,6 +329,36 @@ func (mr *MockAWSMockRecorder) IsValidAMI(AMIImage, logger interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsValidAMI", reflect.TypeOf((*MockAWS)(nil).IsValidAMI), AMIImage, logger)
 }
 
+// GetSpotInstancesMarkedForTermination mocks base method
+func (m *MockAWS) GetSpotInstancesMarkedForTermination(instanceIDs []string, logger logrus.FieldLogger) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSpotInstancesMarkedForTermination", instanceIDs, logger)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSpotInstancesMarkedForTermination indicates an expected call of GetSpotInstancesMarkedForTermination
+func (mr *MockAWSMockRecorder) GetSpotInstancesMarkedForTermination(instanceIDs, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSpotInstancesMarkedForTermination", reflect.TypeOf((*MockAWS)(nil).GetSpotInstancesMarkedForTermination), instanceIDs, logger)
+}
+
+// IsValidInstanceType mocks base method
+func (m *MockAWS) IsValidInstanceType(instanceType string, logger logrus.FieldLogger) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsValidInstanceType", instanceType, logger)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsValidInstanceType indicates an expected call of IsValidInstanceType
+func (mr *MockAWSMockRecorder) IsValidInstanceType(instanceType, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsValidInstanceType", reflect.TypeOf((*MockAWS)(nil).IsValidInstanceType), instanceType, logger)
+}
+
 // DynamoDBEnsureTableDeleted mocks base method
 func (m *MockAWS) DynamoDBEnsureTableDeleted(tableName string, logger logrus.FieldLogger) error {
 	m.ctrl.T.Helper()
@@ -470,3 +517,178 @@ func (mr *MockAWSMockRecorder) SwitchClusterTags(clusterID, targetClusterID, log
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SwitchClusterTags", reflect.TypeOf((*MockAWS)(nil).SwitchClusterTags), clusterID, targetClusterID, logger)
 }
+
+// EnsureEKSCluster mocks base method
+func (m *MockAWS) EnsureEKSCluster(config aws.EKSClusterConfig, logger logrus.FieldLogger) (*eks.Cluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureEKSCluster", config, logger)
+	ret0, _ := ret[0].(*eks.Cluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnsureEKSCluster indicates an expected call of EnsureEKSCluster
+func (mr *MockAWSMockRecorder) EnsureEKSCluster(config, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureEKSCluster", reflect.TypeOf((*MockAWS)(nil).EnsureEKSCluster), config, logger)
+}
+
+// GetEKSCluster mocks base method
+func (m *MockAWS) GetEKSCluster(name string) (*eks.Cluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEKSCluster", name)
+	ret0, _ := ret[0].(*eks.Cluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEKSCluster indicates an expected call of GetEKSCluster
+func (mr *MockAWSMockRecorder) GetEKSCluster(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEKSCluster", reflect.TypeOf((*MockAWS)(nil).GetEKSCluster), name)
+}
+
+// WaitForEKSClusterActive mocks base method
+func (m *MockAWS) WaitForEKSClusterActive(name string, timeout time.Duration) (*eks.Cluster, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForEKSClusterActive", name, timeout)
+	ret0, _ := ret[0].(*eks.Cluster)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitForEKSClusterActive indicates an expected call of WaitForEKSClusterActive
+func (mr *MockAWSMockRecorder) WaitForEKSClusterActive(name, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForEKSClusterActive", reflect.TypeOf((*MockAWS)(nil).WaitForEKSClusterActive), name, timeout)
+}
+
+// UpdateEKSClusterVersion mocks base method
+func (m *MockAWS) UpdateEKSClusterVersion(name, version string, logger logrus.FieldLogger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEKSClusterVersion", name, version, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEKSClusterVersion indicates an expected call of UpdateEKSClusterVersion
+func (mr *MockAWSMockRecorder) UpdateEKSClusterVersion(name, version, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEKSClusterVersion", reflect.TypeOf((*MockAWS)(nil).UpdateEKSClusterVersion), name, version, logger)
+}
+
+// EnsureEKSNodeGroup mocks base method
+func (m *MockAWS) EnsureEKSNodeGroup(clusterName string, config aws.EKSNodeGroupConfig, logger logrus.FieldLogger) (*eks.Nodegroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureEKSNodeGroup", clusterName, config, logger)
+	ret0, _ := ret[0].(*eks.Nodegroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnsureEKSNodeGroup indicates an expected call of EnsureEKSNodeGroup
+func (mr *MockAWSMockRecorder) EnsureEKSNodeGroup(clusterName, config, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureEKSNodeGroup", reflect.TypeOf((*MockAWS)(nil).EnsureEKSNodeGroup), clusterName, config, logger)
+}
+
+// GetEKSNodeGroup mocks base method
+func (m *MockAWS) GetEKSNodeGroup(clusterName, nodeGroupName string) (*eks.Nodegroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEKSNodeGroup", clusterName, nodeGroupName)
+	ret0, _ := ret[0].(*eks.Nodegroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEKSNodeGroup indicates an expected call of GetEKSNodeGroup
+func (mr *MockAWSMockRecorder) GetEKSNodeGroup(clusterName, nodeGroupName interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEKSNodeGroup", reflect.TypeOf((*MockAWS)(nil).GetEKSNodeGroup), clusterName, nodeGroupName)
+}
+
+// WaitForEKSNodeGroupActive mocks base method
+func (m *MockAWS) WaitForEKSNodeGroupActive(clusterName, nodeGroupName string, timeout time.Duration) (*eks.Nodegroup, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitForEKSNodeGroupActive", clusterName, nodeGroupName, timeout)
+	ret0, _ := ret[0].(*eks.Nodegroup)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// WaitForEKSNodeGroupActive indicates an expected call of WaitForEKSNodeGroupActive
+func (mr *MockAWSMockRecorder) WaitForEKSNodeGroupActive(clusterName, nodeGroupName, timeout interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitForEKSNodeGroupActive", reflect.TypeOf((*MockAWS)(nil).WaitForEKSNodeGroupActive), clusterName, nodeGroupName, timeout)
+}
+
+// UpdateEKSNodeGroupVersion mocks base method
+func (m *MockAWS) UpdateEKSNodeGroupVersion(clusterName, nodeGroupName, version string, logger logrus.FieldLogger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEKSNodeGroupVersion", clusterName, nodeGroupName, version, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEKSNodeGroupVersion indicates an expected call of UpdateEKSNodeGroupVersion
+func (mr *MockAWSMockRecorder) UpdateEKSNodeGroupVersion(clusterName, nodeGroupName, version, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEKSNodeGroupVersion", reflect.TypeOf((*MockAWS)(nil).UpdateEKSNodeGroupVersion), clusterName, nodeGroupName, version, logger)
+}
+
+// UpdateEKSNodeGroupScaling mocks base method
+func (m *MockAWS) UpdateEKSNodeGroupScaling(clusterName, nodeGroupName string, minCount, maxCount int64, logger logrus.FieldLogger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEKSNodeGroupScaling", clusterName, nodeGroupName, minCount, maxCount, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEKSNodeGroupScaling indicates an expected call of UpdateEKSNodeGroupScaling
+func (mr *MockAWSMockRecorder) UpdateEKSNodeGroupScaling(clusterName, nodeGroupName, minCount, maxCount, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEKSNodeGroupScaling", reflect.TypeOf((*MockAWS)(nil).UpdateEKSNodeGroupScaling), clusterName, nodeGroupName, minCount, maxCount, logger)
+}
+
+// EnsureEKSNodeGroupDeleted mocks base method
+func (m *MockAWS) EnsureEKSNodeGroupDeleted(clusterName, nodeGroupName string, timeout time.Duration, logger logrus.FieldLogger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureEKSNodeGroupDeleted", clusterName, nodeGroupName, timeout, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureEKSNodeGroupDeleted indicates an expected call of EnsureEKSNodeGroupDeleted
+func (mr *MockAWSMockRecorder) EnsureEKSNodeGroupDeleted(clusterName, nodeGroupName, timeout, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureEKSNodeGroupDeleted", reflect.TypeOf((*MockAWS)(nil).EnsureEKSNodeGroupDeleted), clusterName, nodeGroupName, timeout, logger)
+}
+
+// EnsureEKSClusterDeleted mocks base method
+func (m *MockAWS) EnsureEKSClusterDeleted(name string, timeout time.Duration, logger logrus.FieldLogger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsureEKSClusterDeleted", name, timeout, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsureEKSClusterDeleted indicates an expected call of EnsureEKSClusterDeleted
+func (mr *MockAWSMockRecorder) EnsureEKSClusterDeleted(name, timeout, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsureEKSClusterDeleted", reflect.TypeOf((*MockAWS)(nil).EnsureEKSClusterDeleted), name, timeout, logger)
+}
+
+// GetEKSClusterToken mocks base method
+func (m *MockAWS) GetEKSClusterToken(name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEKSClusterToken", name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEKSClusterToken indicates an expected call of GetEKSClusterToken
+func (mr *MockAWSMockRecorder) GetEKSClusterToken(name interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEKSClusterToken", reflect.TypeOf((*MockAWS)(nil).GetEKSClusterToken), name)
+}
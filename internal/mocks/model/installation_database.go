@@ -165,6 +165,34 @@ func (mr *MockDatabaseMockRecorder) RollbackMigration(store, dbMigration, logger
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackMigration", reflect.TypeOf((*MockDatabase)(nil).RollbackMigration), store, dbMigration, logger)
 }
 
+// RotateCredentials mocks base method
+func (m *MockDatabase) RotateCredentials(store model.InstallationDatabaseStoreInterface, logger logrus.FieldLogger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateCredentials", store, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RotateCredentials indicates an expected call of RotateCredentials
+func (mr *MockDatabaseMockRecorder) RotateCredentials(store, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateCredentials", reflect.TypeOf((*MockDatabase)(nil).RotateCredentials), store, logger)
+}
+
+// RevokePreviousCredentials mocks base method
+func (m *MockDatabase) RevokePreviousCredentials(store model.InstallationDatabaseStoreInterface, logger logrus.FieldLogger) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokePreviousCredentials", store, logger)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokePreviousCredentials indicates an expected call of RevokePreviousCredentials
+func (mr *MockDatabaseMockRecorder) RevokePreviousCredentials(store, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokePreviousCredentials", reflect.TypeOf((*MockDatabase)(nil).RevokePreviousCredentials), store, logger)
+}
+
 // MockInstallationDatabaseStoreInterface is a mock of InstallationDatabaseStoreInterface interface
 type MockInstallationDatabaseStoreInterface struct {
 	ctrl     *gomock.Controller
@@ -522,3 +550,33 @@ func (mr *MockClusterUtilityDatabaseStoreInterfaceMockRecorder) GetLogicalDataba
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLogicalDatabases", reflect.TypeOf((*MockClusterUtilityDatabaseStoreInterface)(nil).GetLogicalDatabases), filter)
 }
+
+// GetDatabaseSchemas mocks base method
+func (m *MockClusterUtilityDatabaseStoreInterface) GetDatabaseSchemas(filter *model.DatabaseSchemaFilter) ([]*model.DatabaseSchema, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDatabaseSchemas", filter)
+	ret0, _ := ret[0].([]*model.DatabaseSchema)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDatabaseSchemas indicates an expected call of GetDatabaseSchemas
+func (mr *MockClusterUtilityDatabaseStoreInterfaceMockRecorder) GetDatabaseSchemas(filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDatabaseSchemas", reflect.TypeOf((*MockClusterUtilityDatabaseStoreInterface)(nil).GetDatabaseSchemas), filter)
+}
+
+// GetInstallation mocks base method
+func (m *MockClusterUtilityDatabaseStoreInterface) GetInstallation(installationID string, includeGroupConfig, includeGroupConfigOverrides bool) (*model.Installation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetInstallation", installationID, includeGroupConfig, includeGroupConfigOverrides)
+	ret0, _ := ret[0].(*model.Installation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetInstallation indicates an expected call of GetInstallation
+func (mr *MockClusterUtilityDatabaseStoreInterfaceMockRecorder) GetInstallation(installationID, includeGroupConfig, includeGroupConfigOverrides interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetInstallation", reflect.TypeOf((*MockClusterUtilityDatabaseStoreInterface)(nil).GetInstallation), installationID, includeGroupConfig, includeGroupConfigOverrides)
+}
@@ -79,7 +79,8 @@ func (o BackupOperator) TriggerBackup(
 		envVars = bifrostEnvs()
 	}
 	if installation.Filestore == model.InstallationFilestoreBifrost ||
-		installation.Filestore == model.InstallationFilestoreMultiTenantAwsS3 {
+		installation.Filestore == model.InstallationFilestoreMultiTenantAwsS3 ||
+		installation.Filestore == model.InstallationFilestoreS3Compatible {
 		dataResidence.PathPrefix = installation.ID
 	}
 
@@ -88,7 +89,7 @@ func (o BackupOperator) TriggerBackup(
 	backupJobName := makeJobName(backupAction, backup.ID)
 	job := o.createBackupRestoreJob(backupJobName, installation.ID, backupAction, envVars, backupBackoffLimit)
 
-	err := o.startJob(jobsClient, job, logger)
+	err := startJob(jobsClient, job, logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to start backup job")
 	}
@@ -99,12 +100,12 @@ func (o BackupOperator) TriggerBackup(
 // CheckBackupStatus checks status of backup job,
 // returns job start time, when the job finished or -1 if it is still running.
 func (o BackupOperator) CheckBackupStatus(jobsClient v1.JobInterface, backup *model.InstallationBackup, logger log.FieldLogger) (int64, error) {
-	return o.checkJobStatus(jobsClient, makeJobName(backupAction, backup.ID), logger, extractStartTime)
+	return checkJobStatus(jobsClient, makeJobName(backupAction, backup.ID), logger, extractStartTime)
 }
 
 // CleanupBackupJob removes backup job from the cluster if it exists.
 func (o BackupOperator) CleanupBackupJob(jobsClient v1.JobInterface, backup *model.InstallationBackup, logger log.FieldLogger) error {
-	return o.cleanupJob(jobsClient, makeJobName(backupAction, backup.ID), logger)
+	return cleanupJob(jobsClient, makeJobName(backupAction, backup.ID), logger)
 }
 
 // TriggerRestore creates new restore job and waits for it to start.
@@ -133,7 +134,7 @@ func (o BackupOperator) TriggerRestore(
 	restoreJobName := makeJobName(restoreAction, backup.ID)
 	job := o.createBackupRestoreJob(restoreJobName, installation.ID, restoreAction, envVars, restoreBackoffLimit)
 
-	err := o.startJob(jobsClient, job, logger)
+	err := startJob(jobsClient, job, logger)
 	if err != nil {
 		return errors.Wrap(err, "Failed to start restore job")
 	}
@@ -143,23 +144,23 @@ func (o BackupOperator) TriggerRestore(
 // CheckRestoreStatus checks status of restore job,
 // returns job start time, when the job finished or -1 if it is still running.
 func (o BackupOperator) CheckRestoreStatus(jobsClient v1.JobInterface, backup *model.InstallationBackup, logger log.FieldLogger) (int64, error) {
-	return o.checkJobStatus(jobsClient, makeJobName(restoreAction, backup.ID), logger, extractCompletionTime)
+	return checkJobStatus(jobsClient, makeJobName(restoreAction, backup.ID), logger, extractCompletionTime)
 }
 
 // CleanupRestoreJob removes restore job from the cluster if it exists.
 func (o BackupOperator) CleanupRestoreJob(jobsClient v1.JobInterface, backup *model.InstallationBackup, logger log.FieldLogger) error {
-	return o.cleanupJob(jobsClient, makeJobName(restoreAction, backup.ID), logger)
+	return cleanupJob(jobsClient, makeJobName(restoreAction, backup.ID), logger)
 }
 
 // startJob creates job if does not exists and waits for it to start.
-func (o BackupOperator) startJob(jobsClient v1.JobInterface, job *batchv1.Job, logger log.FieldLogger) error {
+func startJob(jobsClient v1.JobInterface, job *batchv1.Job, logger log.FieldLogger) error {
 	jobName := job.Name
 
 	ctx := context.Background()
 	job, err := jobsClient.Create(ctx, job, metav1.CreateOptions{})
 	if err != nil {
 		if !k8sErrors.IsAlreadyExists(err) {
-			return errors.Wrap(err, "failed to create restore job")
+			return errors.Wrap(err, "failed to create job")
 		}
 		logger.Warnf("Job %q already exists", jobName)
 	}
@@ -182,7 +183,7 @@ func (o BackupOperator) startJob(jobsClient v1.JobInterface, job *batchv1.Job, l
 	return nil
 }
 
-func (o BackupOperator) checkJobStatus(
+func checkJobStatus(
 	jobsClient v1.JobInterface,
 	jobName string,
 	logger log.FieldLogger,
@@ -222,7 +223,7 @@ func (o BackupOperator) checkJobStatus(
 	return -1, nil
 }
 
-func (o BackupOperator) cleanupJob(jobsClient v1.JobInterface, jobName string, logger log.FieldLogger) error {
+func cleanupJob(jobsClient v1.JobInterface, jobName string, logger log.FieldLogger) error {
 	deletePropagation := metav1.DeletePropagationBackground
 	ctx := context.Background()
 	err := jobsClient.Delete(ctx, jobName, metav1.DeleteOptions{PropagationPolicy: &deletePropagation})
@@ -0,0 +1,165 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/k8s"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// clusterProvisioner is the set of cluster operations implemented by each
+// cluster provisioner.
+type clusterProvisioner interface {
+	PrepareCluster(cluster *model.Cluster) bool
+	CreateCluster(cluster *model.Cluster, awsClient aws.AWS) error
+	ProvisionCluster(cluster *model.Cluster, awsClient aws.AWS) error
+	UpgradeCluster(cluster *model.Cluster, awsClient aws.AWS) error
+	UpgradeClusterUtility(cluster *model.Cluster, utilityName string, awsClient aws.AWS) error
+	ResizeCluster(cluster *model.Cluster, awsClient aws.AWS) error
+	DeleteCluster(cluster *model.Cluster, awsClient aws.AWS) error
+	RefreshKopsMetadata(cluster *model.Cluster) error
+	GetClusterResources(cluster *model.Cluster, onlySchedulable bool, logger log.FieldLogger) (*k8s.ClusterResources, error)
+	GetSpotNodes(cluster *model.Cluster, logger log.FieldLogger) ([]model.ClusterNode, error)
+	DrainClusterNode(cluster *model.Cluster, nodeName string, logger log.FieldLogger) ([]string, error)
+}
+
+// ClusterProvisionerRouter dispatches cluster operations to the provisioner
+// matching the cluster.
+type ClusterProvisionerRouter struct {
+	kops clusterProvisioner
+	eks  clusterProvisioner
+}
+
+// NewClusterProvisionerRouter creates a new ClusterProvisionerRouter.
+func NewClusterProvisionerRouter(kopsProvisioner, eksProvisioner clusterProvisioner) *ClusterProvisionerRouter {
+	return &ClusterProvisionerRouter{
+		kops: kopsProvisioner,
+		eks:  eksProvisioner,
+	}
+}
+
+func (r *ClusterProvisionerRouter) provisioner(cluster *model.Cluster) (clusterProvisioner, error) {
+	switch cluster.Provisioner {
+	case model.ProvisionerKops, "":
+		return r.kops, nil
+	case model.ProvisionerEKS:
+		return r.eks, nil
+	}
+
+	return nil, errors.Errorf("unsupported cluster provisioner %q", cluster.Provisioner)
+}
+
+// PrepareCluster ensures a cluster object is ready for provisioning.
+func (r *ClusterProvisionerRouter) PrepareCluster(cluster *model.Cluster) bool {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return false
+	}
+
+	return provisioner.PrepareCluster(cluster)
+}
+
+// CreateCluster creates a cluster with the provisioner of the cluster.
+func (r *ClusterProvisionerRouter) CreateCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.CreateCluster(cluster, awsClient)
+}
+
+// ProvisionCluster provisions a cluster with the provisioner of the cluster.
+func (r *ClusterProvisionerRouter) ProvisionCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.ProvisionCluster(cluster, awsClient)
+}
+
+// UpgradeCluster upgrades a cluster with the provisioner of the cluster.
+func (r *ClusterProvisionerRouter) UpgradeCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.UpgradeCluster(cluster, awsClient)
+}
+
+// ResizeCluster resizes a cluster with the provisioner of the cluster.
+func (r *ClusterProvisionerRouter) ResizeCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.ResizeCluster(cluster, awsClient)
+}
+
+// DeleteCluster deletes a cluster with the provisioner of the cluster.
+func (r *ClusterProvisionerRouter) DeleteCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.DeleteCluster(cluster, awsClient)
+}
+
+// RefreshKopsMetadata refreshes the provisioner metadata of a cluster.
+func (r *ClusterProvisionerRouter) RefreshKopsMetadata(cluster *model.Cluster) error {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.RefreshKopsMetadata(cluster)
+}
+
+// GetClusterResources returns a snapshot of resources of a given cluster.
+func (r *ClusterProvisionerRouter) GetClusterResources(cluster *model.Cluster, onlySchedulable bool, logger log.FieldLogger) (*k8s.ClusterResources, error) {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return provisioner.GetClusterResources(cluster, onlySchedulable, logger)
+}
+
+// GetSpotNodes returns the worker nodes of a cluster running on spot instances.
+func (r *ClusterProvisionerRouter) GetSpotNodes(cluster *model.Cluster, logger log.FieldLogger) ([]model.ClusterNode, error) {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return provisioner.GetSpotNodes(cluster, logger)
+}
+
+// DrainClusterNode cordons and drains a node of a cluster.
+func (r *ClusterProvisionerRouter) DrainClusterNode(cluster *model.Cluster, nodeName string, logger log.FieldLogger) ([]string, error) {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	return provisioner.DrainClusterNode(cluster, nodeName, logger)
+}
+
+// UpgradeClusterUtility upgrades a single utility of a cluster.
+func (r *ClusterProvisionerRouter) UpgradeClusterUtility(cluster *model.Cluster, utilityName string, awsClient aws.AWS) error {
+	provisioner, err := r.provisioner(cluster)
+	if err != nil {
+		return err
+	}
+
+	return provisioner.UpgradeClusterUtility(cluster, utilityName, awsClient)
+}
@@ -0,0 +1,500 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/client-go/kubernetes/typed/batch/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	// Database drivers
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+)
+
+const (
+	// DBEngineMigrationSourceDatabase is a role of the source database secret.
+	DBEngineMigrationSourceDatabase = "source"
+	// DBEngineMigrationDestinationDatabase is a role of the destination database secret.
+	DBEngineMigrationDestinationDatabase = "destination"
+
+	// dbEngineMigrationDSNKey is the key of the database secrets holding the
+	// connection string in the format expected by migration-assist.
+	dbEngineMigrationDSNKey = "MIGRATION_DSN"
+	// dbEngineMigrationWorkDir is the directory shared by the containers of
+	// the migration job, holding the generated pgloader configuration.
+	dbEngineMigrationWorkDir = "/migration"
+	// dbEngineMigrationRowCountTimeout is the time allowed to count the rows
+	// of all migrated tables in both databases.
+	dbEngineMigrationRowCountTimeout = 10 * time.Minute
+
+	// Run migration job with only one attempt to avoid migrating on top of partially migrated data.
+	dbEngineMigrationBackoffLimit int32 = 0
+)
+
+// ErrDBEngineMigrationRowCountMismatch is returned when the row counts of the
+// migrated tables differ between the source and destination database.
+var ErrDBEngineMigrationRowCountMismatch = errors.New("row counts of migrated tables do not match")
+
+// dbEngineMigrationIgnoredTables are the tables which migration-assist leaves
+// out of the pgloader configuration. They are either recreated by the schema
+// migrations of the destination database or migrated by the plugins owning
+// them.
+var dbEngineMigrationIgnoredTables = []string{
+	"configurationfiles",
+	"configurations",
+	"db_config_migrations",
+	"db_lock",
+	"db_migrations",
+	"schema_migrations",
+}
+
+// dbEngineMigrationIgnoredTablePrefixes are the table name prefixes of plugin
+// tables which migration-assist leaves out of the pgloader configuration.
+var dbEngineMigrationIgnoredTablePrefixes = []string{"ir_", "focalboard_"}
+
+// DBEngineMigrationOperator provides methods to run, check and cleanup jobs
+// migrating installation data from MySQL to Postgres databases.
+//
+// The migration job runs the Mattermost migration-assist tool to check the
+// source database, create the schema of the destination database and
+// generate the pgloader configuration, which is then run with pgloader.
+type DBEngineMigrationOperator struct {
+	jobTTLSecondsAfterFinish *int32
+	migrationAssistImage     string
+	pgloaderImage            string
+}
+
+// NewDBEngineMigrationOperator creates new DBEngineMigrationOperator.
+func NewDBEngineMigrationOperator(migrationAssistImage, pgloaderImage string, jobTTLSeconds int32) *DBEngineMigrationOperator {
+	jobTTL := &jobTTLSeconds
+	if jobTTLSeconds < 0 {
+		jobTTL = nil
+	}
+
+	return &DBEngineMigrationOperator{
+		jobTTLSecondsAfterFinish: jobTTL,
+		migrationAssistImage:     migrationAssistImage,
+		pgloaderImage:            pgloaderImage,
+	}
+}
+
+// EnsureSecret creates or updates the database secret with specified role
+// used by migration jobs.
+func (o DBEngineMigrationOperator) EnsureSecret(
+	secretsClient typedcorev1.SecretInterface,
+	dbMigration *model.InstallationDBMigrationOperation,
+	role string,
+	databaseSecret *corev1.Secret,
+	logger log.FieldLogger) error {
+
+	err := o.validateImages()
+	if err != nil {
+		return err
+	}
+
+	connectionString := databaseSecret.StringData["DB_CONNECTION_STRING"]
+	if connectionString == "" {
+		return errors.Errorf("%s database secret has no connection string", role)
+	}
+
+	stringData := map[string]string{}
+	for key, value := range databaseSecret.StringData {
+		stringData[key] = value
+	}
+	stringData[dbEngineMigrationDSNKey] = makeDBEngineMigrationDSN(connectionString)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      makeDBEngineMigrationSecretName(dbMigration.ID, role),
+			Namespace: dbMigration.InstallationID,
+			Labels: map[string]string{
+				"app": "database-engine-migration",
+			},
+		},
+		StringData: stringData,
+	}
+
+	ctx := context.Background()
+	_, err = secretsClient.Create(ctx, secret, metav1.CreateOptions{})
+	if err == nil {
+		logger.Infof("Created %s database secret for engine migration", role)
+		return nil
+	}
+	if !k8sErrors.IsAlreadyExists(err) {
+		return errors.Wrapf(err, "failed to create %s database secret", role)
+	}
+
+	_, err = secretsClient.Update(ctx, secret, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update %s database secret", role)
+	}
+
+	logger.Infof("Updated %s database secret for engine migration", role)
+	return nil
+}
+
+// TriggerJob creates new job migrating the installation data to the
+// destination database and waits for it to start. The schema of the
+// destination database is created for the given Mattermost version.
+func (o DBEngineMigrationOperator) TriggerJob(
+	jobsClient v1.JobInterface,
+	dbMigration *model.InstallationDBMigrationOperation,
+	mattermostVersion string,
+	logger log.FieldLogger) error {
+
+	err := o.validateImages()
+	if err != nil {
+		return err
+	}
+
+	job := o.createMigrationJob(dbMigration, mattermostVersion)
+
+	err = startJob(jobsClient, job, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to start database engine migration job")
+	}
+
+	return nil
+}
+
+// CheckJobStatus checks status of the migration job, returns job completion
+// time, when the job finished or -1 if it is still running.
+func (o DBEngineMigrationOperator) CheckJobStatus(jobsClient v1.JobInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) (int64, error) {
+	return checkJobStatus(jobsClient, makeDBEngineMigrationJobName(dbMigration.ID), logger, extractCompletionTime)
+}
+
+// CleanupJob removes the migration job from the cluster if it exists.
+func (o DBEngineMigrationOperator) CleanupJob(jobsClient v1.JobInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	return cleanupJob(jobsClient, makeDBEngineMigrationJobName(dbMigration.ID), logger)
+}
+
+// VerifyRowCounts connects to the source and destination database with the
+// credentials of the migration secrets and compares the row counts of all
+// migrated tables. ErrDBEngineMigrationRowCountMismatch is returned if they
+// don't match.
+func (o DBEngineMigrationOperator) VerifyRowCounts(secretsClient typedcorev1.SecretInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbEngineMigrationRowCountTimeout)
+	defer cancel()
+
+	sourceDB, err := openDBEngineMigrationDatabase(ctx, secretsClient, dbMigration, DBEngineMigrationSourceDatabase, "mysql")
+	if err != nil {
+		return err
+	}
+	defer sourceDB.Close()
+
+	destinationDB, err := openDBEngineMigrationDatabase(ctx, secretsClient, dbMigration, DBEngineMigrationDestinationDatabase, "postgres")
+	if err != nil {
+		return err
+	}
+	defer destinationDB.Close()
+
+	return compareTableRowCounts(
+		ctx,
+		&sqlTableRowCounter{db: sourceDB, tablesQuery: mysqlTablesQuery, countQuery: "SELECT COUNT(*) FROM `%s`"},
+		&sqlTableRowCounter{db: destinationDB, tablesQuery: postgresTablesQuery, countQuery: `SELECT COUNT(*) FROM "%s"`},
+		logger,
+	)
+}
+
+// CleanupSecrets removes database secrets used by migration jobs from the
+// cluster if they exist.
+func (o DBEngineMigrationOperator) CleanupSecrets(secretsClient typedcorev1.SecretInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	ctx := context.Background()
+	for _, role := range []string{DBEngineMigrationSourceDatabase, DBEngineMigrationDestinationDatabase} {
+		secretName := makeDBEngineMigrationSecretName(dbMigration.ID, role)
+
+		err := secretsClient.Delete(ctx, secretName, metav1.DeleteOptions{})
+		if k8sErrors.IsNotFound(err) {
+			logger.Warnf("Secret %q does not exist, assuming already deleted", secretName)
+			continue
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete %s database secret", role)
+		}
+	}
+
+	logger.Info("Database engine migration secrets deleted")
+	return nil
+}
+
+func (o DBEngineMigrationOperator) validateImages() error {
+	if o.migrationAssistImage == "" || o.pgloaderImage == "" {
+		return errors.New("migration-assist and pgloader images must be configured to migrate between database engines")
+	}
+
+	return nil
+}
+
+// createMigrationJob creates the job migrating the installation data. The
+// migration-assist steps run as init containers, so that pgloader only runs
+// once the source database was checked, the destination schema created and
+// the pgloader configuration generated.
+func (o DBEngineMigrationOperator) createMigrationJob(dbMigration *model.InstallationDBMigrationOperation, mattermostVersion string) *batchv1.Job {
+	labels := map[string]string{
+		"app": "database-engine-migration",
+	}
+	backoffLimit := dbEngineMigrationBackoffLimit
+
+	env := []corev1.EnvVar{
+		{
+			Name:      "MIGRATION_SOURCE_DSN",
+			ValueFrom: envSourceFromSecret(makeDBEngineMigrationSecretName(dbMigration.ID, DBEngineMigrationSourceDatabase), dbEngineMigrationDSNKey),
+		},
+		{
+			Name:      "MIGRATION_DESTINATION_DSN",
+			ValueFrom: envSourceFromSecret(makeDBEngineMigrationSecretName(dbMigration.ID, DBEngineMigrationDestinationDatabase), dbEngineMigrationDSNKey),
+		},
+	}
+	volumeMounts := []corev1.VolumeMount{
+		{
+			Name:      "migration",
+			MountPath: dbEngineMigrationWorkDir,
+		},
+	}
+	pgloaderConfig := path.Join(dbEngineMigrationWorkDir, "migration.load")
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      makeDBEngineMigrationJobName(dbMigration.ID),
+			Namespace: dbMigration.InstallationID,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name:         "check-source",
+							Image:        o.migrationAssistImage,
+							Command:      []string{"sh", "-c", `migration-assist mysql "$MIGRATION_SOURCE_DSN"`},
+							Env:          env,
+							VolumeMounts: volumeMounts,
+						},
+						{
+							Name:         "create-destination-schema",
+							Image:        o.migrationAssistImage,
+							Command:      []string{"sh", "-c", fmt.Sprintf(`migration-assist postgres "$MIGRATION_DESTINATION_DSN" --run-migrations --mattermost-version=%q`, makeMigrationAssistVersion(mattermostVersion))},
+							Env:          env,
+							VolumeMounts: volumeMounts,
+						},
+						{
+							Name:         "generate-pgloader-config",
+							Image:        o.migrationAssistImage,
+							Command:      []string{"sh", "-c", fmt.Sprintf(`migration-assist pgloader --mysql="$MIGRATION_SOURCE_DSN" --postgres="$MIGRATION_DESTINATION_DSN" > %s`, pgloaderConfig)},
+							Env:          env,
+							VolumeMounts: volumeMounts,
+						},
+						{
+							Name:         "pgloader",
+							Image:        o.pgloaderImage,
+							Command:      []string{"pgloader", "--on-error-stop", pgloaderConfig},
+							VolumeMounts: volumeMounts,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:         "post-migrate",
+							Image:        o.migrationAssistImage,
+							Command:      []string{"sh", "-c", `migration-assist postgres post-migrate "$MIGRATION_DESTINATION_DSN"`},
+							Env:          env,
+							VolumeMounts: volumeMounts,
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name:         "migration",
+							VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+						},
+					},
+					RestartPolicy: corev1.RestartPolicyNever,
+				},
+			},
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: o.jobTTLSecondsAfterFinish,
+		},
+	}
+}
+
+// openDBEngineMigrationDatabase connects to the database of a migration
+// secret with the given driver.
+func openDBEngineMigrationDatabase(ctx context.Context, secretsClient typedcorev1.SecretInterface, dbMigration *model.InstallationDBMigrationOperation, role, driverName string) (*sql.DB, error) {
+	secretName := makeDBEngineMigrationSecretName(dbMigration.ID, role)
+	secret, err := secretsClient.Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get %s database secret", role)
+	}
+
+	dsn := string(secret.Data[dbEngineMigrationDSNKey])
+	if dsn == "" {
+		return nil, errors.Errorf("%s database secret %s has no connection string", role, secretName)
+	}
+
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s database", role)
+	}
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrapf(err, "failed to ping %s database", role)
+	}
+
+	return db, nil
+}
+
+const (
+	mysqlTablesQuery    = "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'"
+	postgresTablesQuery = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'"
+)
+
+// tableRowCounter lists the tables of a database and counts their rows.
+type tableRowCounter interface {
+	Tables(ctx context.Context) ([]string, error)
+	CountRows(ctx context.Context, table string) (int64, error)
+}
+
+type sqlTableRowCounter struct {
+	db          *sql.DB
+	tablesQuery string
+	countQuery  string
+}
+
+func (c *sqlTableRowCounter) Tables(ctx context.Context) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, c.tablesQuery)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query tables")
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan table name")
+		}
+		tables = append(tables, table)
+	}
+
+	return tables, rows.Err()
+}
+
+func (c *sqlTableRowCounter) CountRows(ctx context.Context, table string) (int64, error) {
+	var count int64
+	err := c.db.QueryRowContext(ctx, fmt.Sprintf(c.countQuery, table)).Scan(&count)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to count rows of table %s", table)
+	}
+
+	return count, nil
+}
+
+// compareTableRowCounts compares the row counts of all tables of the source
+// database migrated to the destination database. Postgres folds the table
+// names of the source database to lower case.
+func compareTableRowCounts(ctx context.Context, source, destination tableRowCounter, logger log.FieldLogger) error {
+	sourceTables, err := source.Tables(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list source database tables")
+	}
+	destinationTables, err := destination.Tables(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to list destination database tables")
+	}
+	destinationTableSet := map[string]bool{}
+	for _, table := range destinationTables {
+		destinationTableSet[table] = true
+	}
+
+	sort.Strings(sourceTables)
+
+	var mismatches int
+	for _, sourceTable := range sourceTables {
+		destinationTable := strings.ToLower(sourceTable)
+		if isDBEngineMigrationIgnoredTable(destinationTable) {
+			continue
+		}
+		if !destinationTableSet[destinationTable] {
+			logger.Errorf("Table %s is missing in the destination database", sourceTable)
+			mismatches++
+			continue
+		}
+
+		sourceCount, err := source.CountRows(ctx, sourceTable)
+		if err != nil {
+			return errors.Wrap(err, "failed to count source database rows")
+		}
+		destinationCount, err := destination.CountRows(ctx, destinationTable)
+		if err != nil {
+			return errors.Wrap(err, "failed to count destination database rows")
+		}
+		if sourceCount != destinationCount {
+			logger.Errorf("Table %s has %d rows in the source database and %d rows in the destination database", sourceTable, sourceCount, destinationCount)
+			mismatches++
+		}
+	}
+
+	if mismatches > 0 {
+		return ErrDBEngineMigrationRowCountMismatch
+	}
+
+	logger.Infof("Row counts of %d migrated tables match", len(sourceTables))
+
+	return nil
+}
+
+func isDBEngineMigrationIgnoredTable(table string) bool {
+	for _, ignored := range dbEngineMigrationIgnoredTables {
+		if table == ignored {
+			return true
+		}
+	}
+	for _, prefix := range dbEngineMigrationIgnoredTablePrefixes {
+		if strings.HasPrefix(table, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// makeDBEngineMigrationDSN converts a Mattermost database connection string
+// to the format expected by migration-assist and the Go SQL drivers. MySQL
+// connection strings drop the scheme, Postgres URLs are used as they are.
+func makeDBEngineMigrationDSN(connectionString string) string {
+	return strings.TrimPrefix(connectionString, "mysql://")
+}
+
+// makeMigrationAssistVersion formats a Mattermost version as migration-assist
+// expects it, with a leading v.
+func makeMigrationAssistVersion(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
+
+func makeDBEngineMigrationJobName(id string) string {
+	return makeJobName("engine-migrate", id)
+}
+
+func makeDBEngineMigrationSecretName(id, role string) string {
+	return fmt.Sprintf("database-engine-migration-%s-%s", id, role)
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDBEngineMigrationOperator_TriggerJob(t *testing.T) {
+	dbMigration := &model.InstallationDBMigrationOperation{
+		ID:             "migration-1",
+		InstallationID: "installation-1",
+	}
+
+	operator := NewDBEngineMigrationOperator("mattermost/migration-assist:test", "dimitri/pgloader:test", 100)
+
+	t.Run("create migration job", func(t *testing.T) {
+		k8sClient := fake.NewSimpleClientset()
+		jobClient := k8sClient.BatchV1().Jobs("installation-1")
+		go setJobActiveWhenExists(t, jobClient, "database-engine-migrate-migration-1")
+
+		err := operator.TriggerJob(jobClient, dbMigration, "9.5.0", logrus.New())
+		require.NoError(t, err)
+
+		createdJob, err := jobClient.Get(context.Background(), "database-engine-migrate-migration-1", metav1.GetOptions{})
+		require.NoError(t, err)
+
+		assert.Equal(t, "database-engine-migration", createdJob.Labels["app"])
+		assert.Equal(t, "installation-1", createdJob.Namespace)
+		assert.Equal(t, dbEngineMigrationBackoffLimit, *createdJob.Spec.BackoffLimit)
+		assert.Equal(t, int32(100), *createdJob.Spec.TTLSecondsAfterFinished)
+
+		podSpec := createdJob.Spec.Template.Spec
+		require.Len(t, podSpec.InitContainers, 4)
+		require.Len(t, podSpec.Containers, 1)
+
+		for _, container := range append(podSpec.InitContainers, podSpec.Containers...) {
+			if container.Name == "pgloader" {
+				assert.Equal(t, "dimitri/pgloader:test", container.Image)
+				assert.Equal(t, []string{"pgloader", "--on-error-stop", "/migration/migration.load"}, container.Command)
+				continue
+			}
+			assert.Equal(t, "mattermost/migration-assist:test", container.Image)
+			assertEnvVarFromSecret(t, "MIGRATION_SOURCE_DSN", "database-engine-migration-migration-1-source", "MIGRATION_DSN", container.Env)
+			assertEnvVarFromSecret(t, "MIGRATION_DESTINATION_DSN", "database-engine-migration-migration-1-destination", "MIGRATION_DSN", container.Env)
+		}
+		assert.Equal(t, "create-destination-schema", podSpec.InitContainers[1].Name)
+		assert.Contains(t, podSpec.InitContainers[1].Command[2], `--mattermost-version="v9.5.0"`)
+		assert.Equal(t, "post-migrate", podSpec.Containers[0].Name)
+	})
+
+	t.Run("succeed if job already exists", func(t *testing.T) {
+		existing := &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{Name: "database-engine-migrate-migration-1", Namespace: "installation-1"},
+			Status:     batchv1.JobStatus{Active: 1},
+		}
+		k8sClient := fake.NewSimpleClientset(existing)
+		jobClient := k8sClient.BatchV1().Jobs("installation-1")
+
+		err := operator.TriggerJob(jobClient, dbMigration, "9.5.0", logrus.New())
+		require.NoError(t, err)
+	})
+
+	t.Run("fail when images are not configured", func(t *testing.T) {
+		k8sClient := fake.NewSimpleClientset()
+		jobClient := k8sClient.BatchV1().Jobs("installation-1")
+
+		err := NewDBEngineMigrationOperator("", "dimitri/pgloader:test", 100).TriggerJob(jobClient, dbMigration, "9.5.0", logrus.New())
+		require.Error(t, err)
+
+		jobs, err := jobClient.List(context.Background(), metav1.ListOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, jobs.Items)
+	})
+}
+
+func TestDBEngineMigrationOperator_Secrets(t *testing.T) {
+	dbMigration := &model.InstallationDBMigrationOperation{
+		ID:             "migration-1",
+		InstallationID: "installation-1",
+	}
+
+	operator := NewDBEngineMigrationOperator("mattermost/migration-assist:test", "dimitri/pgloader:test", 100)
+
+	k8sClient := fake.NewSimpleClientset()
+	secretsClient := k8sClient.CoreV1().Secrets("installation-1")
+
+	t.Run("create secret", func(t *testing.T) {
+		err := operator.EnsureSecret(secretsClient, dbMigration, DBEngineMigrationSourceDatabase, &corev1.Secret{
+			StringData: map[string]string{"DB_CONNECTION_STRING": "mysql://source"},
+		}, logrus.New())
+		require.NoError(t, err)
+
+		secret, err := secretsClient.Get(context.Background(), "database-engine-migration-migration-1-source", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "mysql://source", secret.StringData["DB_CONNECTION_STRING"])
+		assert.Equal(t, "source", secret.StringData["MIGRATION_DSN"])
+	})
+
+	t.Run("update existing secret", func(t *testing.T) {
+		err := operator.EnsureSecret(secretsClient, dbMigration, DBEngineMigrationSourceDatabase, &corev1.Secret{
+			StringData: map[string]string{"DB_CONNECTION_STRING": "mysql://updated"},
+		}, logrus.New())
+		require.NoError(t, err)
+
+		secret, err := secretsClient.Get(context.Background(), "database-engine-migration-migration-1-source", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, "mysql://updated", secret.StringData["DB_CONNECTION_STRING"])
+		assert.Equal(t, "updated", secret.StringData["MIGRATION_DSN"])
+	})
+
+	t.Run("fail without connection string", func(t *testing.T) {
+		err := operator.EnsureSecret(secretsClient, dbMigration, DBEngineMigrationDestinationDatabase, &corev1.Secret{
+			StringData: map[string]string{},
+		}, logrus.New())
+		require.Error(t, err)
+	})
+
+	t.Run("cleanup secrets", func(t *testing.T) {
+		err := operator.CleanupSecrets(secretsClient, dbMigration, logrus.New())
+		require.NoError(t, err)
+
+		_, err = secretsClient.Get(context.Background(), "database-engine-migration-migration-1-source", metav1.GetOptions{})
+		require.Error(t, err)
+		assert.True(t, k8sErrors.IsNotFound(err))
+	})
+}
+
+type mockTableRowCounter struct {
+	rowCounts map[string]int64
+}
+
+func (c *mockTableRowCounter) Tables(ctx context.Context) ([]string, error) {
+	var tables []string
+	for table := range c.rowCounts {
+		tables = append(tables, table)
+	}
+
+	return tables, nil
+}
+
+func (c *mockTableRowCounter) CountRows(ctx context.Context, table string) (int64, error) {
+	count, ok := c.rowCounts[table]
+	if !ok {
+		return 0, errors.Errorf("table %s does not exist", table)
+	}
+
+	return count, nil
+}
+
+func TestCompareTableRowCounts(t *testing.T) {
+	for _, testCase := range []struct {
+		description       string
+		sourceRowCounts   map[string]int64
+		destinationCounts map[string]int64
+		expectedErr       error
+	}{
+		{
+			description:       "row counts match",
+			sourceRowCounts:   map[string]int64{"Users": 10, "Posts": 100},
+			destinationCounts: map[string]int64{"users": 10, "posts": 100},
+		},
+		{
+			description:       "row counts do not match",
+			sourceRowCounts:   map[string]int64{"Users": 10, "Posts": 100},
+			destinationCounts: map[string]int64{"users": 10, "posts": 99},
+			expectedErr:       ErrDBEngineMigrationRowCountMismatch,
+		},
+		{
+			description:       "table missing in destination",
+			sourceRowCounts:   map[string]int64{"Users": 10, "Posts": 100},
+			destinationCounts: map[string]int64{"users": 10},
+			expectedErr:       ErrDBEngineMigrationRowCountMismatch,
+		},
+		{
+			description:       "ignored tables",
+			sourceRowCounts:   map[string]int64{"Users": 10, "db_migrations": 200, "Configurations": 3, "IR_Incident": 5, "focalboard_blocks": 7},
+			destinationCounts: map[string]int64{"users": 10, "db_migrations": 150},
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			err := compareTableRowCounts(
+				context.Background(),
+				&mockTableRowCounter{rowCounts: testCase.sourceRowCounts},
+				&mockTableRowCounter{rowCounts: testCase.destinationCounts},
+				logrus.New(),
+			)
+			if testCase.expectedErr != nil {
+				assert.Equal(t, testCase.expectedErr, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/client-go/rest"
+
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/k8s"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+// eksWaitTimeout is how long to wait for EKS control plane and node group
+// operations to complete.
+const eksWaitTimeout = 40 * time.Minute
+
+// EKSProvisioner provisions clusters managed by AWS EKS.
+type EKSProvisioner struct {
+	params    ProvisioningParams
+	awsClient aws.AWS
+	logger    log.FieldLogger
+}
+
+// NewEKSProvisioner creates a new EKSProvisioner.
+func NewEKSProvisioner(provisioningParams ProvisioningParams, awsClient aws.AWS, logger log.FieldLogger) *EKSProvisioner {
+	logger = logger.WithField("provisioner", "eks")
+
+	return &EKSProvisioner{
+		params:    provisioningParams,
+		awsClient: awsClient,
+		logger:    logger,
+	}
+}
+
+// eksNodeGroupName returns the name of the managed node group of the worker
+// nodes with the given instance type. Managed node groups cannot change their
+// instance type, so a new node group is created when it changes.
+func eksNodeGroupName(instanceType string) string {
+	return fmt.Sprintf("nodes-%s", strings.ReplaceAll(instanceType, ".", "-"))
+}
+
+// PrepareCluster ensures a cluster object is ready for provisioning.
+func (provisioner *EKSProvisioner) PrepareCluster(cluster *model.Cluster) bool {
+	eksMetadata := cluster.ProvisionerMetadataEKS
+
+	// Don't regenerate the name if already set.
+	if eksMetadata.Name != "" {
+		return false
+	}
+
+	eksMetadata.Name = fmt.Sprintf("%s-eks", cluster.ID)
+	if eksMetadata.ChangeRequest != nil {
+		eksMetadata.NodeGroupName = eksNodeGroupName(eksMetadata.ChangeRequest.NodeInstanceType)
+	}
+
+	return true
+}
+
+// CreateCluster creates the EKS control plane and worker node group of the
+// cluster and waits for them to become active.
+func (provisioner *EKSProvisioner) CreateCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	logger := provisioner.logger.WithField("cluster", cluster.ID)
+
+	eksMetadata := cluster.ProvisionerMetadataEKS
+
+	err := eksMetadata.ValidateChangeRequest()
+	if err != nil {
+		return errors.Wrap(err, "EKSMetadata ChangeRequest failed validation")
+	}
+	if !provisioner.params.UseExistingAWSResources {
+		return errors.New("EKS clusters require existing AWS VPC resources")
+	}
+
+	isValid, err := awsClient.IsValidInstanceType(eksMetadata.ChangeRequest.NodeInstanceType, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to validate node instance type")
+	}
+	if !isValid {
+		return errors.Errorf("invalid node instance type %s", eksMetadata.ChangeRequest.NodeInstanceType)
+	}
+
+	var clusterResources aws.ClusterResources
+	if eksMetadata.ChangeRequest.VPC != "" {
+		clusterResources, err = awsClient.GetVpcResourcesByVpcID(eksMetadata.ChangeRequest.VPC, logger)
+	} else {
+		clusterResources, err = awsClient.GetAndClaimVpcResources(cluster.ID, provisioner.params.Owner, logger)
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.WithField("name", eksMetadata.Name).Info("Creating EKS cluster")
+
+	tags := map[string]string{
+		"CloudClusterID": cluster.ID,
+		"CloudOwner":     provisioner.params.Owner,
+	}
+	_, err = awsClient.EnsureEKSCluster(aws.EKSClusterConfig{
+		Name:             eksMetadata.Name,
+		Version:          eksMetadata.ChangeRequest.Version,
+		RoleARN:          eksMetadata.ClusterRoleARN,
+		SubnetIDs:        append(append([]string{}, clusterResources.PrivateSubnetIDs...), clusterResources.PublicSubnetsIDs...),
+		SecurityGroupIDs: clusterResources.MasterSecurityGroupIDs,
+		Tags:             tags,
+	}, logger)
+	if err != nil {
+		releaseErr := awsClient.ReleaseVpc(cluster.ID, logger)
+		if releaseErr != nil {
+			logger.WithError(releaseErr).Error("Unable to release VPC")
+		}
+
+		return errors.Wrap(err, "unable to create EKS cluster")
+	}
+
+	if eksMetadata.ChangeRequest.VPC != "" {
+		err = awsClient.TagResourcesByCluster(clusterResources, cluster.ID, provisioner.params.Owner, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("Waiting up to %s for EKS cluster to become active...", eksWaitTimeout)
+	eksCluster, err := awsClient.WaitForEKSClusterActive(eksMetadata.Name, eksWaitTimeout)
+	if err != nil {
+		return err
+	}
+
+	nodeMinCount := eksMetadata.ChangeRequest.NodeMinCount
+	nodeMaxCount := eksMetadata.ChangeRequest.NodeMaxCount
+	if nodeMaxCount < nodeMinCount {
+		nodeMaxCount = nodeMinCount
+	}
+	_, err = awsClient.EnsureEKSNodeGroup(eksMetadata.Name, aws.EKSNodeGroupConfig{
+		Name:         eksMetadata.NodeGroupName,
+		InstanceType: eksMetadata.ChangeRequest.NodeInstanceType,
+		MinCount:     nodeMinCount,
+		MaxCount:     nodeMaxCount,
+		RoleARN:      eksMetadata.NodeRoleARN,
+		SubnetIDs:    clusterResources.PrivateSubnetIDs,
+		Tags:         tags,
+	}, logger)
+	if err != nil {
+		return errors.Wrap(err, "unable to create EKS node group")
+	}
+
+	logger.Infof("Waiting up to %s for EKS node group to become active...", eksWaitTimeout)
+	_, err = awsClient.WaitForEKSNodeGroupActive(eksMetadata.Name, eksMetadata.NodeGroupName, eksWaitTimeout)
+	if err != nil {
+		return err
+	}
+
+	eksMetadata.ARN = awssdk.StringValue(eksCluster.Arn)
+	eksMetadata.Version = awssdk.StringValue(eksCluster.Version)
+	eksMetadata.NodeInstanceType = eksMetadata.ChangeRequest.NodeInstanceType
+	eksMetadata.NodeMinCount = nodeMinCount
+	eksMetadata.NodeMaxCount = nodeMaxCount
+	eksMetadata.VPC = clusterResources.VpcID
+
+	logger.WithField("name", eksMetadata.Name).Info("Successfully created EKS cluster")
+
+	return nil
+}
+
+// ProvisionCluster is called after the cluster creation and when the cluster
+// is reprovisioned. The utilities of kops clusters are not yet supported on
+// EKS clusters, so only the cluster connectivity is verified.
+func (provisioner *EKSProvisioner) ProvisionCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	logger := provisioner.logger.WithField("cluster", cluster.ID)
+
+	k8sClient, err := provisioner.k8sClient(cluster, logger)
+	if err != nil {
+		return err
+	}
+
+	versionInfo, err := k8sClient.Clientset.Discovery().ServerVersion()
+	if err != nil {
+		return errors.Wrap(err, "failed to get kubernetes version")
+	}
+	logger.Infof("EKS cluster is reachable and runs kubernetes %s, skipping utility provisioning", versionInfo.GitVersion)
+
+	return nil
+}
+
+// UpgradeCluster upgrades the EKS control plane and then the worker node group
+// to the requested k8s version.
+func (provisioner *EKSProvisioner) UpgradeCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	logger := provisioner.logger.WithField("cluster", cluster.ID)
+
+	eksMetadata := cluster.ProvisionerMetadataEKS
+
+	err := eksMetadata.ValidateChangeRequest()
+	if err != nil {
+		return errors.Wrap(err, "EKSMetadata ChangeRequest failed validation")
+	}
+	version := eksMetadata.ChangeRequest.Version
+	if len(version) == 0 || version == eksMetadata.Version {
+		logger.Info("EKS cluster is already on the requested version")
+		return nil
+	}
+
+	logger.Infof("Upgrading EKS cluster from %s to %s", eksMetadata.Version, version)
+
+	err = awsClient.UpdateEKSClusterVersion(eksMetadata.Name, version, logger)
+	if err != nil {
+		return err
+	}
+	_, err = awsClient.WaitForEKSClusterActive(eksMetadata.Name, eksWaitTimeout)
+	if err != nil {
+		return err
+	}
+	eksMetadata.Version = version
+
+	err = awsClient.UpdateEKSNodeGroupVersion(eksMetadata.Name, eksMetadata.NodeGroupName, version, logger)
+	if err != nil {
+		return err
+	}
+	_, err = awsClient.WaitForEKSNodeGroupActive(eksMetadata.Name, eksMetadata.NodeGroupName, eksWaitTimeout)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Successfully upgraded EKS cluster to %s", version)
+
+	return nil
+}
+
+// ResizeCluster resizes the worker node group of the cluster. As managed node
+// groups cannot change their instance type, a new node group is created and
+// the old one is deleted when the node instance type changes.
+func (provisioner *EKSProvisioner) ResizeCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	logger := provisioner.logger.WithField("cluster", cluster.ID)
+
+	eksMetadata := cluster.ProvisionerMetadataEKS
+
+	err := eksMetadata.ValidateChangeRequest()
+	if err != nil {
+		return errors.Wrap(err, "EKSMetadata ChangeRequest failed validation")
+	}
+
+	nodeMinCount := eksMetadata.NodeMinCount
+	if eksMetadata.ChangeRequest.NodeMinCount != 0 {
+		nodeMinCount = eksMetadata.ChangeRequest.NodeMinCount
+	}
+	nodeMaxCount := eksMetadata.NodeMaxCount
+	if eksMetadata.ChangeRequest.NodeMaxCount != 0 {
+		nodeMaxCount = eksMetadata.ChangeRequest.NodeMaxCount
+	}
+	if nodeMaxCount < nodeMinCount {
+		nodeMaxCount = nodeMinCount
+	}
+
+	instanceType := eksMetadata.ChangeRequest.NodeInstanceType
+	if len(instanceType) == 0 || instanceType == eksMetadata.NodeInstanceType {
+		logger.Infof("Resizing EKS node group %s to min %d and max %d nodes", eksMetadata.NodeGroupName, nodeMinCount, nodeMaxCount)
+		err = awsClient.UpdateEKSNodeGroupScaling(eksMetadata.Name, eksMetadata.NodeGroupName, nodeMinCount, nodeMaxCount, logger)
+		if err != nil {
+			return err
+		}
+		_, err = awsClient.WaitForEKSNodeGroupActive(eksMetadata.Name, eksMetadata.NodeGroupName, eksWaitTimeout)
+		if err != nil {
+			return err
+		}
+
+		eksMetadata.NodeMinCount = nodeMinCount
+		eksMetadata.NodeMaxCount = nodeMaxCount
+
+		return nil
+	}
+
+	isValid, err := awsClient.IsValidInstanceType(instanceType, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to validate node instance type")
+	}
+	if !isValid {
+		return errors.Errorf("invalid node instance type %s", instanceType)
+	}
+
+	clusterResources, err := awsClient.GetVpcResourcesByVpcID(eksMetadata.VPC, logger)
+	if err != nil {
+		return err
+	}
+
+	newNodeGroupName := eksNodeGroupName(instanceType)
+	logger.Infof("Replacing EKS node group %s with node group %s of %s instances", eksMetadata.NodeGroupName, newNodeGroupName, instanceType)
+
+	_, err = awsClient.EnsureEKSNodeGroup(eksMetadata.Name, aws.EKSNodeGroupConfig{
+		Name:         newNodeGroupName,
+		Version:      eksMetadata.Version,
+		InstanceType: instanceType,
+		MinCount:     nodeMinCount,
+		MaxCount:     nodeMaxCount,
+		RoleARN:      eksMetadata.NodeRoleARN,
+		SubnetIDs:    clusterResources.PrivateSubnetIDs,
+		Tags: map[string]string{
+			"CloudClusterID": cluster.ID,
+			"CloudOwner":     provisioner.params.Owner,
+		},
+	}, logger)
+	if err != nil {
+		return errors.Wrap(err, "unable to create replacement EKS node group")
+	}
+	_, err = awsClient.WaitForEKSNodeGroupActive(eksMetadata.Name, newNodeGroupName, eksWaitTimeout)
+	if err != nil {
+		return err
+	}
+
+	oldNodeGroupName := eksMetadata.NodeGroupName
+	eksMetadata.NodeGroupName = newNodeGroupName
+	eksMetadata.NodeInstanceType = instanceType
+	eksMetadata.NodeMinCount = nodeMinCount
+	eksMetadata.NodeMaxCount = nodeMaxCount
+
+	err = awsClient.EnsureEKSNodeGroupDeleted(eksMetadata.Name, oldNodeGroupName, eksWaitTimeout, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete replaced EKS node group")
+	}
+
+	return nil
+}
+
+// DeleteCluster deletes the EKS cluster and its node groups and releases the
+// cluster VPC.
+func (provisioner *EKSProvisioner) DeleteCluster(cluster *model.Cluster, awsClient aws.AWS) error {
+	logger := provisioner.logger.WithField("cluster", cluster.ID)
+
+	logger.Info("Deleting EKS cluster")
+
+	err := awsClient.EnsureEKSClusterDeleted(cluster.ProvisionerMetadataEKS.Name, eksWaitTimeout, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete EKS cluster")
+	}
+
+	err = awsClient.ReleaseVpc(cluster.ID, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to release cluster VPC")
+	}
+
+	logger.Info("Successfully deleted EKS cluster")
+
+	return nil
+}
+
+// RefreshKopsMetadata updates the EKS metadata of a cluster with the current
+// values of the EKS cluster and its worker node group.
+func (provisioner *EKSProvisioner) RefreshKopsMetadata(cluster *model.Cluster) error {
+	logger := provisioner.logger.WithField("cluster", cluster.ID)
+
+	logger.Info("Refreshing EKS metadata")
+
+	eksMetadata := cluster.ProvisionerMetadataEKS
+
+	eksCluster, err := provisioner.awsClient.GetEKSCluster(eksMetadata.Name)
+	if err != nil {
+		return err
+	}
+	if eksCluster == nil {
+		return errors.Errorf("EKS cluster %s not found", eksMetadata.Name)
+	}
+	eksMetadata.ARN = awssdk.StringValue(eksCluster.Arn)
+	eksMetadata.Version = awssdk.StringValue(eksCluster.Version)
+
+	nodeGroup, err := provisioner.awsClient.GetEKSNodeGroup(eksMetadata.Name, eksMetadata.NodeGroupName)
+	if err != nil {
+		return err
+	}
+	if nodeGroup == nil {
+		return errors.Errorf("EKS node group %s not found", eksMetadata.NodeGroupName)
+	}
+	updateEKSNodeGroupMetadata(eksMetadata, nodeGroup)
+
+	return nil
+}
+
+func updateEKSNodeGroupMetadata(eksMetadata *model.EKSMetadata, nodeGroup *eks.Nodegroup) {
+	if len(nodeGroup.InstanceTypes) != 0 {
+		eksMetadata.NodeInstanceType = awssdk.StringValue(nodeGroup.InstanceTypes[0])
+	}
+	if nodeGroup.ScalingConfig != nil {
+		eksMetadata.NodeMinCount = awssdk.Int64Value(nodeGroup.ScalingConfig.MinSize)
+		eksMetadata.NodeMaxCount = awssdk.Int64Value(nodeGroup.ScalingConfig.MaxSize)
+	}
+}
+
+// GetClusterResources returns a snapshot of resources of a given cluster.
+func (provisioner *EKSProvisioner) GetClusterResources(cluster *model.Cluster, onlySchedulable bool, logger log.FieldLogger) (*k8s.ClusterResources, error) {
+	logger = logger.WithField("cluster", cluster.ID)
+
+	k8sClient, err := provisioner.k8sClient(cluster, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return getClusterResources(k8sClient, onlySchedulable, logger)
+}
+
+// UpgradeClusterUtility upgrades a single utility of the cluster. EKS
+// clusters don't run the kops cluster utilities.
+func (provisioner *EKSProvisioner) UpgradeClusterUtility(cluster *model.Cluster, utilityName string, awsClient aws.AWS) error {
+	return errors.New("utility upgrades are not supported on EKS clusters")
+}
+
+// GetSpotNodes returns the worker nodes of the cluster which run on spot
+// instances. EKS clusters only have on-demand worker nodes.
+func (provisioner *EKSProvisioner) GetSpotNodes(cluster *model.Cluster, logger log.FieldLogger) ([]model.ClusterNode, error) {
+	return nil, nil
+}
+
+// DrainClusterNode cordons the node and evicts the Mattermost pods running on
+// it. The names of the evicted pods are returned.
+func (provisioner *EKSProvisioner) DrainClusterNode(cluster *model.Cluster, nodeName string, logger log.FieldLogger) ([]string, error) {
+	logger = logger.WithFields(log.Fields{
+		"cluster": cluster.ID,
+		"node":    nodeName,
+	})
+
+	k8sClient, err := provisioner.k8sClient(cluster, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return drainClusterNode(k8sClient, nodeName, logger)
+}
+
+// k8sClient returns a k8s client authenticated against the EKS cluster with
+// the AWS identity of the provisioner.
+func (provisioner *EKSProvisioner) k8sClient(cluster *model.Cluster, logger log.FieldLogger) (*k8s.KubeClient, error) {
+	name := cluster.ProvisionerMetadataEKS.Name
+
+	eksCluster, err := provisioner.awsClient.GetEKSCluster(name)
+	if err != nil {
+		return nil, err
+	}
+	if eksCluster == nil {
+		return nil, errors.Errorf("EKS cluster %s not found", name)
+	}
+	if eksCluster.CertificateAuthority == nil {
+		return nil, errors.Errorf("EKS cluster %s has no certificate authority yet", name)
+	}
+	caData, err := base64.StdEncoding.DecodeString(awssdk.StringValue(eksCluster.CertificateAuthority.Data))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode EKS cluster certificate authority")
+	}
+
+	token, err := provisioner.awsClient.GetEKSClusterToken(name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get EKS cluster token")
+	}
+
+	k8sClient, err := k8s.NewFromConfig(&rest.Config{
+		Host:        awssdk.StringValue(eksCluster.Endpoint),
+		BearerToken: token,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAData: caData,
+		},
+	}, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create k8s client")
+	}
+
+	return k8sClient, nil
+}
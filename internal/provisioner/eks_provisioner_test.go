@@ -0,0 +1,176 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"testing"
+
+	mocks "github.com/mattermost/mattermost-cloud/internal/mocks/aws-tools"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/model"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEKSProvisionerCreateCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := testlib.MakeLogger(t)
+	awsClient := mocks.NewMockAWS(ctrl)
+	provisioner := NewEKSProvisioner(ProvisioningParams{Owner: "owner", UseExistingAWSResources: true}, awsClient, logger)
+
+	cluster := &model.Cluster{
+		ID:          model.NewID(),
+		Provisioner: model.ProvisionerEKS,
+		ProvisionerMetadataEKS: &model.EKSMetadata{
+			ClusterRoleARN: "arn:aws:iam::123456789012:role/eks-cluster",
+			NodeRoleARN:    "arn:aws:iam::123456789012:role/eks-node",
+			ChangeRequest: &model.EKSMetadataRequestedState{
+				Version:          "1.21",
+				NodeInstanceType: "m5.large",
+				NodeMinCount:     2,
+				NodeMaxCount:     4,
+			},
+		},
+	}
+	require.True(t, provisioner.PrepareCluster(cluster))
+	require.False(t, provisioner.PrepareCluster(cluster))
+	assert.Equal(t, cluster.ID+"-eks", cluster.ProvisionerMetadataEKS.Name)
+	assert.Equal(t, "nodes-m5-large", cluster.ProvisionerMetadataEKS.NodeGroupName)
+
+	clusterResources := aws.ClusterResources{
+		VpcID:                  "vpc-1",
+		PrivateSubnetIDs:       []string{"private-1"},
+		PublicSubnetsIDs:       []string{"public-1"},
+		MasterSecurityGroupIDs: []string{"sg-1"},
+	}
+
+	gomock.InOrder(
+		awsClient.EXPECT().IsValidInstanceType("m5.large", gomock.Any()).Return(true, nil),
+		awsClient.EXPECT().GetAndClaimVpcResources(cluster.ID, "owner", gomock.Any()).Return(clusterResources, nil),
+		awsClient.EXPECT().EnsureEKSCluster(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(config aws.EKSClusterConfig, _ interface{}) (*eks.Cluster, error) {
+				assert.Equal(t, cluster.ID+"-eks", config.Name)
+				assert.Equal(t, []string{"private-1", "public-1"}, config.SubnetIDs)
+				assert.Equal(t, []string{"sg-1"}, config.SecurityGroupIDs)
+				return &eks.Cluster{}, nil
+			}),
+		awsClient.EXPECT().WaitForEKSClusterActive(cluster.ID+"-eks", gomock.Any()).
+			Return(&eks.Cluster{Arn: awssdk.String("arn"), Version: awssdk.String("1.21")}, nil),
+		awsClient.EXPECT().EnsureEKSNodeGroup(cluster.ID+"-eks", gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ string, config aws.EKSNodeGroupConfig, _ interface{}) (*eks.Nodegroup, error) {
+				assert.Equal(t, "nodes-m5-large", config.Name)
+				assert.Equal(t, []string{"private-1"}, config.SubnetIDs)
+				assert.EqualValues(t, 2, config.MinCount)
+				assert.EqualValues(t, 4, config.MaxCount)
+				return &eks.Nodegroup{}, nil
+			}),
+		awsClient.EXPECT().WaitForEKSNodeGroupActive(cluster.ID+"-eks", "nodes-m5-large", gomock.Any()).Return(&eks.Nodegroup{}, nil),
+	)
+
+	err := provisioner.CreateCluster(cluster, awsClient)
+	require.NoError(t, err)
+
+	eksMetadata := cluster.ProvisionerMetadataEKS
+	assert.Equal(t, "arn", eksMetadata.ARN)
+	assert.Equal(t, "1.21", eksMetadata.Version)
+	assert.Equal(t, "m5.large", eksMetadata.NodeInstanceType)
+	assert.EqualValues(t, 2, eksMetadata.NodeMinCount)
+	assert.EqualValues(t, 4, eksMetadata.NodeMaxCount)
+	assert.Equal(t, "vpc-1", eksMetadata.VPC)
+}
+
+func TestEKSProvisionerResizeCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := testlib.MakeLogger(t)
+	awsClient := mocks.NewMockAWS(ctrl)
+	provisioner := NewEKSProvisioner(ProvisioningParams{}, awsClient, logger)
+
+	newCluster := func(changeRequest *model.EKSMetadataRequestedState) *model.Cluster {
+		return &model.Cluster{
+			ID:          model.NewID(),
+			Provisioner: model.ProvisionerEKS,
+			ProvisionerMetadataEKS: &model.EKSMetadata{
+				Name:             "cluster-eks",
+				NodeGroupName:    "nodes-m5-large",
+				NodeInstanceType: "m5.large",
+				NodeMinCount:     2,
+				NodeMaxCount:     2,
+				VPC:              "vpc-1",
+				ChangeRequest:    changeRequest,
+			},
+		}
+	}
+
+	t.Run("scaling only", func(t *testing.T) {
+		cluster := newCluster(&model.EKSMetadataRequestedState{NodeMinCount: 3, NodeMaxCount: 5})
+
+		gomock.InOrder(
+			awsClient.EXPECT().UpdateEKSNodeGroupScaling("cluster-eks", "nodes-m5-large", int64(3), int64(5), gomock.Any()).Return(nil),
+			awsClient.EXPECT().WaitForEKSNodeGroupActive("cluster-eks", "nodes-m5-large", gomock.Any()).Return(&eks.Nodegroup{}, nil),
+		)
+
+		err := provisioner.ResizeCluster(cluster, awsClient)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, cluster.ProvisionerMetadataEKS.NodeMinCount)
+		assert.EqualValues(t, 5, cluster.ProvisionerMetadataEKS.NodeMaxCount)
+		assert.Equal(t, "nodes-m5-large", cluster.ProvisionerMetadataEKS.NodeGroupName)
+	})
+
+	t.Run("instance type change", func(t *testing.T) {
+		cluster := newCluster(&model.EKSMetadataRequestedState{NodeInstanceType: "m5.xlarge"})
+
+		gomock.InOrder(
+			awsClient.EXPECT().IsValidInstanceType("m5.xlarge", gomock.Any()).Return(true, nil),
+			awsClient.EXPECT().GetVpcResourcesByVpcID("vpc-1", gomock.Any()).Return(aws.ClusterResources{PrivateSubnetIDs: []string{"private-1"}}, nil),
+			awsClient.EXPECT().EnsureEKSNodeGroup("cluster-eks", gomock.Any(), gomock.Any()).Return(&eks.Nodegroup{}, nil),
+			awsClient.EXPECT().WaitForEKSNodeGroupActive("cluster-eks", "nodes-m5-xlarge", gomock.Any()).Return(&eks.Nodegroup{}, nil),
+			awsClient.EXPECT().EnsureEKSNodeGroupDeleted("cluster-eks", "nodes-m5-large", gomock.Any(), gomock.Any()).Return(nil),
+		)
+
+		err := provisioner.ResizeCluster(cluster, awsClient)
+		require.NoError(t, err)
+		assert.Equal(t, "nodes-m5-xlarge", cluster.ProvisionerMetadataEKS.NodeGroupName)
+		assert.Equal(t, "m5.xlarge", cluster.ProvisionerMetadataEKS.NodeInstanceType)
+	})
+}
+
+func TestClusterProvisionerRouter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger := testlib.MakeLogger(t)
+	awsClient := mocks.NewMockAWS(ctrl)
+	eksProvisioner := NewEKSProvisioner(ProvisioningParams{}, awsClient, logger)
+	router := NewClusterProvisionerRouter(nil, eksProvisioner)
+
+	t.Run("eks", func(t *testing.T) {
+		cluster := &model.Cluster{
+			Provisioner:            model.ProvisionerEKS,
+			ProvisionerMetadataEKS: &model.EKSMetadata{Name: "cluster-eks"},
+		}
+		awsClient.EXPECT().EnsureEKSClusterDeleted("cluster-eks", gomock.Any(), gomock.Any()).Return(nil)
+		awsClient.EXPECT().ReleaseVpc(cluster.ID, gomock.Any()).Return(nil)
+
+		err := router.DeleteCluster(cluster, awsClient)
+		require.NoError(t, err)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		cluster := &model.Cluster{Provisioner: "unknown"}
+
+		err := router.DeleteCluster(cluster, awsClient)
+		require.EqualError(t, err, `unsupported cluster provisioner "unknown"`)
+		assert.False(t, router.PrepareCluster(cluster))
+	})
+}
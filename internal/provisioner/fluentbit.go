@@ -96,6 +96,7 @@ func (f *fluentbit) NewHelmDeployment(logger log.FieldLogger) *helmDeployment {
 		namespace:           "fluent-bit",
 		kopsProvisioner:     f.provisioner,
 		kops:                f.kops,
+		awsClient:           f.awsClient,
 		logger:              f.logger,
 		desiredVersion:      f.desiredVersion,
 	}
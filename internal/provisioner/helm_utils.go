@@ -7,17 +7,14 @@ package provisioner
 import (
 	"bytes"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
-	"strings"
 	"time"
 
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
 	"github.com/mattermost/mattermost-cloud/internal/tools/helm"
 	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
 	"github.com/mattermost/mattermost-cloud/model"
@@ -31,14 +28,29 @@ type helmDeployment struct {
 	chartName           string
 	namespace           string
 	setArgument         string
-	desiredVersion      *model.HelmUtilityVersion
+	// valuesOverride is inline values YAML applied over the values file.
+	valuesOverride string
+	desiredVersion *model.HelmUtilityVersion
 
 	cluster         *model.Cluster
 	kopsProvisioner *KopsProvisioner
 	kops            *kops.Cmd
+	awsClient       aws.AWS
 	logger          log.FieldLogger
 }
 
+// valuesContext returns the function building the cluster context the values
+// templates of the deployment are rendered with.
+func (d *helmDeployment) valuesContext() valuesContextFunc {
+	if d.awsClient == nil || d.cluster == nil {
+		return cachedValuesContext(nil)
+	}
+
+	return cachedValuesContext(func() (*model.UtilityValuesContext, error) {
+		return d.awsClient.GetUtilityValuesContext(d.cluster.ID, d.logger)
+	})
+}
+
 func (d *helmDeployment) Update() error {
 	logger := d.logger.WithField("helm-update", d.chartName)
 
@@ -137,21 +149,11 @@ func upgradeHelmChart(chart helmDeployment, configPath string, logger log.FieldL
 		chart.desiredVersion = currentVersion
 	}
 
-	censoredPath := chart.desiredVersion.ValuesPath
-	defer func(chart *helmDeployment, censoredPath string) {
-		// so that we don't store the GitLab secret in the database
-		chart.desiredVersion.ValuesPath = censoredPath
-	}(&chart, censoredPath)
-
-	var err error
-	var cleanup func(string)
-	chart.desiredVersion.ValuesPath, cleanup, err = fetchFromGitlabIfNecessary(chart.desiredVersion.ValuesPath)
+	valuesFiles, cleanup, err := prepareHelmValuesFiles(chart.desiredVersion.Values(), chart.valuesOverride, chart.valuesContext(), logger)
 	if err != nil {
-		return errors.Wrap(err, "failed to get values file")
-	}
-	if cleanup != nil {
-		defer cleanup(chart.desiredVersion.ValuesPath)
+		return err
 	}
+	defer cleanup()
 
 	arguments := []string{
 		"--debug",
@@ -159,13 +161,15 @@ func upgradeHelmChart(chart helmDeployment, configPath string, logger log.FieldL
 		chart.chartDeploymentName,
 		chart.chartName,
 		"--kubeconfig", configPath,
-		"-f", chart.desiredVersion.Values(),
 		"--namespace", chart.namespace,
 		"--install",
 		"--create-namespace",
 		"--wait",
 		"--timeout", "20m",
 	}
+	for _, valuesFile := range valuesFiles {
+		arguments = append(arguments, "-f", valuesFile)
+	}
 	if chart.setArgument != "" {
 		arguments = append(arguments, "--set", chart.setArgument)
 	}
@@ -187,6 +191,30 @@ func upgradeHelmChart(chart helmDeployment, configPath string, logger log.FieldL
 	return nil
 }
 
+// writeTempValuesFile writes the given values to a temporary file, returning
+// its path and a cleanup function removing it.
+func writeTempValuesFile(values string) (string, func(), error) {
+	file, err := ioutil.TempFile("", "helm-values-*.yaml")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+
+	_, err = file.WriteString(values)
+	if err != nil {
+		file.Close()
+		cleanup()
+		return "", nil, err
+	}
+	err = file.Close()
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return file.Name(), cleanup, nil
+}
+
 // deleteHelmChart is used to delete Helm charts.
 func deleteHelmChart(chart helmDeployment, configPath string, logger log.FieldLogger) error {
 	arguments := []string{
@@ -243,14 +271,19 @@ func (l HelmListOutput) asListOutput() *HelmListOutput {
 }
 
 func (d *helmDeployment) List() (*HelmListOutput, error) {
+	return listHelmReleases(d.kops.GetKubeConfigPath(), d.logger)
+}
+
+// listHelmReleases lists the Helm releases of all namespaces of a cluster.
+func listHelmReleases(configPath string, logger log.FieldLogger) (*HelmListOutput, error) {
 	arguments := []string{
 		"list",
-		"--kubeconfig", d.kops.GetKubeConfigPath(),
+		"--kubeconfig", configPath,
 		"--output", "json",
 		"--all-namespaces",
 	}
 
-	logger := d.logger.WithFields(log.Fields{
+	logger = logger.WithFields(log.Fields{
 		"cmd": "helm3",
 	})
 
@@ -278,88 +311,91 @@ func (d *helmDeployment) List() (*HelmListOutput, error) {
 
 }
 
-func (d *helmDeployment) Version() (*model.HelmUtilityVersion, error) {
-	output, err := d.List()
-	if err != nil {
-		return nil, errors.Wrap(err, "while getting Helm Deployment version")
+// getHelmReleaseManifest returns the rendered manifest of a Helm release.
+func getHelmReleaseManifest(configPath, release, namespace string, logger log.FieldLogger) (string, error) {
+	arguments := []string{
+		"get",
+		"manifest",
+		release,
+		"--kubeconfig", configPath,
+		"--namespace", namespace,
 	}
 
-	for _, release := range output.asSlice() {
-		if release.Name == d.chartDeploymentName {
-			return &model.HelmUtilityVersion{Chart: release.Chart, ValuesPath: d.desiredVersion.Values()}, nil
-		}
+	helmClient, err := helm.New(logger.WithField("cmd", "helm3"))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create helm wrapper")
 	}
+	defer helmClient.Close()
 
-	return nil, errors.Errorf("unable to get version for chart %s", d.chartDeploymentName)
-}
+	rawOutput, err := helmClient.RunCommandRaw(arguments...)
+	if err != nil {
+		return "", errors.Wrapf(err, "while getting manifest of Helm release %s", release)
+	}
 
-type gitlabValuesFileResponse struct {
-	Content string `json:"content"`
+	return string(rawOutput), nil
 }
 
-// fetchFromGitlabIfNecessary returns the path of the values file. If
-// this is a local path or a non-Gitlab URL, the path is simply
-// returned unchanged. If a Gitlab URL is provided, the values file is
-// fetched and stored in the OS's temp dir and the filename of the
-// file is returned. If a temp file is created, a cleanup routine will
-// be returned as the second return value, otherwise that value will
-// be nil
-func fetchFromGitlabIfNecessary(path string) (string, func(string), error) {
-	gitlabKey := model.GetGitlabToken()
-	if gitlabKey == "" {
-		return path, nil, nil
-	}
-
-	valPathURL, err := url.Parse(path)
+// renderHelmUpgrade returns the manifest the upgrade of a Helm release to the
+// given chart version would apply, without applying it. The values of the
+// current release are reused so that arguments set at deployment time are
+// kept, with the values file of the given version and the values override
+// merged over them.
+func renderHelmUpgrade(configPath, release, chartName, namespace string, version *model.HelmUtilityVersion, valuesOverride string, getContext valuesContextFunc, logger log.FieldLogger) (string, error) {
+	valuesFiles, cleanup, err := prepareHelmValuesFiles(version.Values(), valuesOverride, getContext, logger)
 	if err != nil {
-		return "", nil, errors.Wrap(err, "failed to parse Helm values file path or URL")
+		return "", err
 	}
+	defer cleanup()
 
-	// silently allow other public non-Gitlab URLs
-	if !strings.HasPrefix(valPathURL.Host, "git") {
-		return path, nil, nil
-	}
-
-	// if Gitlab, fetch the file using the API
-	path = fmt.Sprintf("%s&private_token=%s", path, gitlabKey)
-
-	resp, err := http.Get(path)
-	if err != nil {
-		return "", nil, errors.Wrap(err, "failed to request the values file from Gitlab")
+	arguments := []string{
+		"upgrade",
+		release,
+		chartName,
+		"--kubeconfig", configPath,
+		"--namespace", namespace,
+		"--version", version.Version(),
+		"--reuse-values",
+		"--install",
+		"--dry-run",
+		"--output", "json",
 	}
-	if resp.StatusCode >= 400 {
-		return "", nil, errors.Errorf("request to Gitlab failed with status: %s", resp.Status)
+	for _, valuesFile := range valuesFiles {
+		arguments = append(arguments, "-f", valuesFile)
 	}
 
-	body, err := ioutil.ReadAll(resp.Body)
+	helmClient, err := helm.New(logger.WithField("cmd", "helm3"))
 	if err != nil {
-		return "", nil, errors.Wrap(err, "failed to read body from Gitlab response")
+		return "", errors.Wrap(err, "unable to create helm wrapper")
 	}
+	defer helmClient.Close()
 
-	valuesFileBytes := new(gitlabValuesFileResponse)
-	err = json.Unmarshal(body, valuesFileBytes)
+	rawOutput, err := helmClient.RunCommandRaw(arguments...)
 	if err != nil {
-		return "", nil, errors.Wrap(err, "failed to unmarshal JSON in Gitlab response")
+		return "", errors.Wrapf(err, "while rendering upgrade of Helm release %s", release)
 	}
 
-	temporaryValuesFile, err := ioutil.TempFile(os.TempDir(), "helm-values-file-")
-	if err != nil {
-		return "", nil, errors.Wrap(err, "failed to create temporary file for Helm values file")
+	var output struct {
+		Manifest string `json:"manifest"`
 	}
-
-	content, err := base64.StdEncoding.DecodeString(valuesFileBytes.Content)
+	err = json.Unmarshal(rawOutput, &output)
 	if err != nil {
-		return "", nil, errors.Wrap(err, "failed to decode base64-encoded YAML file")
+		return "", errors.Wrap(err, "unable to unmarshal JSON output from helm upgrade")
 	}
 
-	err = ioutil.WriteFile(temporaryValuesFile.Name(), []byte(content), 0600)
+	return output.Manifest, nil
+}
+
+func (d *helmDeployment) Version() (*model.HelmUtilityVersion, error) {
+	output, err := d.List()
 	if err != nil {
-		return "", nil, errors.Wrap(err, "failed to write values file to disk for Helm to read")
+		return nil, errors.Wrap(err, "while getting Helm Deployment version")
 	}
 
-	return temporaryValuesFile.Name(), func(path string) {
-		if strings.HasPrefix(path, os.TempDir()) {
-			os.Remove(path)
+	for _, release := range output.asSlice() {
+		if release.Name == d.chartDeploymentName {
+			return &model.HelmUtilityVersion{Chart: release.Chart, ValuesPath: d.desiredVersion.Values()}, nil
 		}
-	}, nil
+	}
+
+	return nil, errors.Errorf("unable to get version for chart %s", d.chartDeploymentName)
 }
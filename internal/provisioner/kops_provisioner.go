@@ -30,12 +30,13 @@ type ProvisioningParams struct {
 
 // KopsProvisioner provisions clusters using kops+terraform.
 type KopsProvisioner struct {
-	params         ProvisioningParams
-	resourceUtil   *utils.ResourceUtil
-	logger         log.FieldLogger
-	store          model.InstallationDatabaseStoreInterface
-	backupOperator *BackupOperator
-	kopsCache      map[string]*kops.Cmd
+	params                    ProvisioningParams
+	resourceUtil              *utils.ResourceUtil
+	logger                    log.FieldLogger
+	store                     model.InstallationDatabaseStoreInterface
+	backupOperator            *BackupOperator
+	dbEngineMigrationOperator *DBEngineMigrationOperator
+	kopsCache                 map[string]*kops.Cmd
 }
 
 // NewKopsProvisioner creates a new KopsProvisioner.
@@ -44,16 +45,18 @@ func NewKopsProvisioner(
 	resourceUtil *utils.ResourceUtil,
 	logger log.FieldLogger,
 	store model.InstallationDatabaseStoreInterface,
-	backupOperator *BackupOperator) *KopsProvisioner {
+	backupOperator *BackupOperator,
+	dbEngineMigrationOperator *DBEngineMigrationOperator) *KopsProvisioner {
 	logger = logger.WithField("provisioner", "kops")
 
 	return &KopsProvisioner{
-		params:         provisioningParams,
-		logger:         logger,
-		resourceUtil:   resourceUtil,
-		store:          store,
-		backupOperator: backupOperator,
-		kopsCache:      make(map[string]*kops.Cmd),
+		params:                    provisioningParams,
+		logger:                    logger,
+		resourceUtil:              resourceUtil,
+		store:                     store,
+		backupOperator:            backupOperator,
+		dbEngineMigrationOperator: dbEngineMigrationOperator,
+		kopsCache:                 make(map[string]*kops.Cmd),
 	}
 }
 
@@ -71,6 +71,11 @@ func (provisioner *KopsProvisioner) CreateCluster(cluster *model.Cluster, awsCli
 		}
 	}
 
+	err = validateKopsInstanceTypes(kopsMetadata.ChangeRequest, awsClient, logger)
+	if err != nil {
+		return err
+	}
+
 	cncVPCName := fmt.Sprintf("mattermost-cloud-%s-command-control", awsClient.GetCloudEnvironmentName())
 	cncVPCCIDR, err := awsClient.GetCIDRByVPCTag(cncVPCName, logger)
 	if err != nil {
@@ -119,6 +124,12 @@ func (provisioner *KopsProvisioner) CreateCluster(cluster *model.Cluster, awsCli
 
 		return errors.Wrap(err, "unable to create kops cluster")
 	}
+
+	err = createKopsAdditionalNodeGroups(kops, kopsMetadata, cluster.ProviderMetadataAWS.Zones, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create additional node groups")
+	}
+
 	// Tag Public subnets & respective VPC for the secondary cluster if there is no error.
 	if kopsMetadata.ChangeRequest.VPC != "" {
 		err = awsClient.TagResourcesByCluster(clusterResources, cluster.ID, provisioner.params.Owner, logger)
@@ -597,6 +608,8 @@ func (provisioner *KopsProvisioner) UpgradeCluster(cluster *model.Cluster, awsCl
 	}
 	defer kops.Close()
 
+	fromVersion := kopsMetadata.Version
+
 	switch kopsMetadata.ChangeRequest.Version {
 	case "":
 		logger.Info("Skipping kubernetes cluster version update")
@@ -615,7 +628,51 @@ func (provisioner *KopsProvisioner) UpgradeCluster(cluster *model.Cluster, awsCl
 		}
 	}
 
-	err = updateKopsInstanceGroupAMIs(kops, kopsMetadata, logger)
+	toVersion, err := getKopsKubernetesVersion(kops, kopsMetadata.Name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get target kubernetes version")
+	}
+
+	report := model.NewClusterUpgradeReport(fromVersion, toVersion)
+	cluster.UpgradeReport = report
+
+	err = provisioner.runUpgradePreflightChecks(cluster, report, logger)
+	if err == nil && !report.PreflightPassed() {
+		err = errors.New("cluster upgrade pre-flight checks failed")
+		report.Complete(model.ClusterUpgradeStatusPreflightFailed, err)
+	} else if err != nil {
+		err = errors.Wrap(err, "failed to run cluster upgrade pre-flight checks")
+		report.Complete(model.ClusterUpgradeStatusFailed, err)
+	}
+	if err != nil {
+		if toVersion != fromVersion && len(fromVersion) != 0 {
+			logger.Infof("Reverting kubernetes version of cluster spec to %s", fromVersion)
+			revertErr := kops.SetCluster(kopsMetadata.Name, fmt.Sprintf("spec.kubernetesVersion=%s", fromVersion))
+			if revertErr != nil {
+				logger.WithError(revertErr).Error("Failed to revert kubernetes version of cluster spec")
+			}
+		}
+		return err
+	}
+
+	err = provisioner.upgradeCluster(cluster, kops, report, awsClient, logger)
+	if err != nil {
+		report.Complete(model.ClusterUpgradeStatusFailed, err)
+		return err
+	}
+	report.Complete(model.ClusterUpgradeStatusSucceeded, nil)
+
+	logger.Info("Successfully upgraded cluster")
+
+	return nil
+}
+
+// upgradeCluster applies the upgraded cluster spec and rolls the instance
+// groups of a cluster which passed the upgrade pre-flight checks.
+func (provisioner *KopsProvisioner) upgradeCluster(cluster *model.Cluster, kops *kops.Cmd, report *model.ClusterUpgradeReport, awsClient aws.AWS, logger logrus.FieldLogger) error {
+	kopsMetadata := cluster.ProvisionerMetadataKops
+
+	err := updateKopsInstanceGroupAMIs(kops, kopsMetadata, logger)
 	if err != nil {
 		return errors.Wrap(err, "failed to update kops instance group AMIs")
 	}
@@ -676,32 +733,17 @@ func (provisioner *KopsProvisioner) UpgradeCluster(cluster *model.Cluster, awsCl
 		}
 	}
 
-	err = kops.RollingUpdateCluster(kopsMetadata.Name)
+	err = provisioner.stagedRollingUpdate(kops, cluster, report, logger)
 	if err != nil {
 		return err
 	}
 
-	// TODO: Rework this as we make the API calls asynchronous.
-	wait := 1000
-	if wait > 0 {
-		logger.Infof("Waiting up to %d seconds for k8s cluster to become ready...", wait)
-		err = kops.WaitForKubernetesReadiness(kopsMetadata.Name, wait)
-		if err != nil {
-			// Run non-silent validate one more time to log final cluster state
-			// and return original timeout error.
-			kops.ValidateCluster(kopsMetadata.Name, false)
-			return err
-		}
-	}
-
 	iamRole := fmt.Sprintf("nodes.%s", kopsMetadata.Name)
 	err = awsClient.AttachPolicyToRole(iamRole, aws.CustomNodePolicyName, logger)
 	if err != nil {
 		return errors.Wrap(err, "unable to attach custom node policy")
 	}
 
-	logger.Info("Successfully upgraded cluster")
-
 	return nil
 }
 
@@ -758,6 +800,11 @@ func (provisioner *KopsProvisioner) ResizeCluster(cluster *model.Cluster, awsCli
 		return errors.Wrap(err, "KopsMetadata ChangeRequest failed validation")
 	}
 
+	err = validateKopsInstanceTypes(kopsMetadata.ChangeRequest, awsClient, logger)
+	if err != nil {
+		return err
+	}
+
 	kops, err := kops.New(provisioner.params.S3StateStore, logger)
 	if err != nil {
 		return errors.Wrap(err, "failed to create kops wrapper")
@@ -787,8 +834,38 @@ func (provisioner *KopsProvisioner) ResizeCluster(cluster *model.Cluster, awsCli
 
 	logger.Info("Resizing cluster")
 
-	for igName, changeMetadata := range kopsMetadata.GetWorkerNodesResizeChanges() {
-		logger.Infof("Resizing instance group %s to %d nodes", igName, changeMetadata.NodeMinCount)
+	if kopsMetadata.HasWorkerNodesChanges() {
+		for igName, changeMetadata := range kopsMetadata.GetWorkerNodesResizeChanges() {
+			logger.Infof("Resizing instance group %s to %d nodes", igName, changeMetadata.NodeMinCount)
+
+			igManifest, err := kops.GetInstanceGroupYAML(kopsMetadata.Name, igName)
+			if err != nil {
+				return err
+			}
+
+			igManifest, err = grossKopsReplaceSize(
+				igManifest,
+				kopsMetadata.ChangeRequest.NodeInstanceType,
+				fmt.Sprintf("%d", changeMetadata.NodeMinCount),
+				fmt.Sprintf("%d", changeMetadata.NodeMaxCount),
+			)
+			if err != nil {
+				return errors.Wrap(err, "failed to update instance group yaml file")
+			}
+
+			err = ioutil.WriteFile(path.Join(kops.GetTempDir(), igFilename), []byte(igManifest), 0600)
+			if err != nil {
+				return errors.Wrap(err, "failed to write instance group yaml file")
+			}
+			_, err = kops.Replace(igFilename)
+			if err != nil {
+				return errors.Wrap(err, "failed to replace instance group resources")
+			}
+		}
+	}
+
+	for igName, nodeGroup := range kopsMetadata.ChangeRequest.AdditionalNodeGroups {
+		logger.Infof("Resizing instance group %s to %d %s nodes", igName, nodeGroup.NodeMinCount, nodeGroup.NodeInstanceType)
 
 		igManifest, err := kops.GetInstanceGroupYAML(kopsMetadata.Name, igName)
 		if err != nil {
@@ -797,21 +874,22 @@ func (provisioner *KopsProvisioner) ResizeCluster(cluster *model.Cluster, awsCli
 
 		igManifest, err = grossKopsReplaceSize(
 			igManifest,
-			kopsMetadata.ChangeRequest.NodeInstanceType,
-			fmt.Sprintf("%d", changeMetadata.NodeMinCount),
-			fmt.Sprintf("%d", changeMetadata.NodeMaxCount),
+			nodeGroup.NodeInstanceType,
+			fmt.Sprintf("%d", nodeGroup.NodeMinCount),
+			fmt.Sprintf("%d", nodeGroup.NodeMaxCount),
 		)
 		if err != nil {
 			return errors.Wrap(err, "failed to update instance group yaml file")
 		}
 
-		err = ioutil.WriteFile(path.Join(kops.GetTempDir(), igFilename), []byte(igManifest), 0600)
+		nodeGroupFilename := fmt.Sprintf("%s-ig.yaml", igName)
+		err = ioutil.WriteFile(path.Join(kops.GetTempDir(), nodeGroupFilename), []byte(igManifest), 0600)
 		if err != nil {
 			return errors.Wrap(err, "failed to write instance group yaml file")
 		}
-		_, err = kops.Replace(igFilename)
+		_, err = kops.Replace(nodeGroupFilename)
 		if err != nil {
-			return errors.Wrap(err, "failed to replace instance group resources")
+			return errors.Wrapf(err, "failed to replace instance group %s", igName)
 		}
 	}
 
@@ -973,6 +1051,12 @@ func (provisioner *KopsProvisioner) GetClusterResources(cluster *model.Cluster,
 		return nil, errors.Wrap(err, "failed to create k8s client from file")
 	}
 
+	return getClusterResources(k8sClient, onlySchedulable, logger)
+}
+
+// getClusterResources calculates the resources of the worker nodes of a
+// cluster and the resources requested by the pods running on them.
+func getClusterResources(k8sClient *k8s.KubeClient, onlySchedulable bool, logger logrus.FieldLogger) (*k8s.ClusterResources, error) {
 	ctx := context.TODO()
 	nodes, err := k8sClient.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
@@ -1105,3 +1189,79 @@ func (provisioner *KopsProvisioner) prepareSloth(cluster *model.Cluster, k8sClie
 
 	return nil
 }
+
+// GetSpotNodes returns the worker nodes of the cluster which run on spot
+// instances.
+func (provisioner *KopsProvisioner) GetSpotNodes(cluster *model.Cluster, logger logrus.FieldLogger) ([]model.ClusterNode, error) {
+	logger = logger.WithField("cluster", cluster.ID)
+
+	configLocation, err := provisioner.getCachedKopsClusterKubecfg(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kops config from cache")
+	}
+	defer provisioner.invalidateCachedKopsClientOnError(err, cluster.ProvisionerMetadataKops.Name, logger)
+
+	k8sClient, err := k8s.NewFromFile(configLocation, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create k8s client from file")
+	}
+
+	nodes, err := k8sClient.GetNodesWithLabel(model.SpotWorkerNodeLabel, "true")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get spot nodes")
+	}
+
+	var spotNodes []model.ClusterNode
+	for _, node := range nodes {
+		spotNodes = append(spotNodes, model.ClusterNode{
+			Name:          node.GetName(),
+			InstanceID:    model.InstanceIDFromProviderID(node.Spec.ProviderID),
+			NodeGroup:     node.GetLabels()[model.KopsInstanceGroupNodeLabel],
+			Unschedulable: node.Spec.Unschedulable,
+		})
+	}
+
+	return spotNodes, nil
+}
+
+// DrainClusterNode cordons the node and evicts the Mattermost pods running on
+// it, so that they are rescheduled on other nodes before the node goes away.
+// The names of the evicted pods are returned.
+func (provisioner *KopsProvisioner) DrainClusterNode(cluster *model.Cluster, nodeName string, logger logrus.FieldLogger) ([]string, error) {
+	logger = logger.WithFields(logrus.Fields{
+		"cluster": cluster.ID,
+		"node":    nodeName,
+	})
+
+	configLocation, err := provisioner.getCachedKopsClusterKubecfg(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get kops config from cache")
+	}
+	defer provisioner.invalidateCachedKopsClientOnError(err, cluster.ProvisionerMetadataKops.Name, logger)
+
+	k8sClient, err := k8s.NewFromFile(configLocation, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create k8s client from file")
+	}
+
+	return drainClusterNode(k8sClient, nodeName, logger)
+}
+
+// drainClusterNode cordons the node and evicts the Mattermost pods running on
+// it.
+func drainClusterNode(k8sClient *k8s.KubeClient, nodeName string, logger logrus.FieldLogger) ([]string, error) {
+	logger.Info("Cordoning node")
+	err := k8sClient.CordonNode(nodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Evicting Mattermost pods from node")
+	evicted, err := k8sClient.EvictPodsFromNode(nodeName, "installation-id")
+	if err != nil {
+		return evicted, err
+	}
+	logger.Infof("Evicted %d Mattermost pods from node", len(evicted))
+
+	return evicted, nil
+}
@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -107,6 +108,7 @@ func (provisioner *crProvisionerWrapper) CreateClusterInstallation(cluster *mode
 			},
 		},
 	}
+	setNodeGroupScheduling(&mattermost.Spec.Scheduling, cluster, installation)
 
 	if installation.State == model.InstallationStateHibernating {
 		logger.Info("creating hibernated cluster installation")
@@ -238,6 +240,7 @@ func (provisioner *crProvisionerWrapper) UpdateClusterInstallation(cluster *mode
 	mattermost.Spec.ResourceLabels = clusterInstallationBaseLabels(installation, clusterInstallation)
 
 	mattermost.Spec.Scheduling.Affinity = generateAffinityConfig(installation, clusterInstallation)
+	setNodeGroupScheduling(&mattermost.Spec.Scheduling, cluster, installation)
 
 	version := translateMattermostVersion(installation.Version)
 	if mattermost.Spec.Version == version {
@@ -341,6 +344,11 @@ func (provisioner *crProvisionerWrapper) RefreshSecrets(cluster *model.Cluster,
 		return errors.Wrap(err, "failed to ensure database and filestore")
 	}
 
+	// Pods don't pick up changed secret values until they are rolled, which
+	// is triggered by the credential rotation env vars.
+	mattermostEnv := getMattermostEnvWithOverrides(installation)
+	mattermost.Spec.MattermostEnv = mattermostEnv.ToEnvList()
+
 	_, err = mmClient.Update(ctx, mattermost, metav1.UpdateOptions{})
 	if err != nil {
 		return errors.Wrapf(err, "failed to update mattermost CR %s", mattermost.Name)
@@ -600,6 +608,42 @@ func generateAffinityConfig(installation *model.Installation, clusterInstallatio
 	}
 }
 
+// setNodeGroupScheduling constrains the installation pods to the nodes of the
+// node group set in the installation placement rules and tolerates the taints
+// of the node group. Installations without a node group are scheduled on any
+// untainted nodes.
+func setNodeGroupScheduling(scheduling *mmv1beta1.Scheduling, cluster *model.Cluster, installation *model.Installation) {
+	scheduling.NodeSelector = nil
+	scheduling.Tolerations = nil
+
+	nodeGroupName := installation.Placement.GetNodeGroup()
+	if len(nodeGroupName) == 0 || cluster.ProvisionerMetadataKops == nil {
+		return
+	}
+	nodeGroup, ok := cluster.ProvisionerMetadataKops.GetNodeGroup(nodeGroupName)
+	if !ok {
+		return
+	}
+
+	scheduling.NodeSelector = map[string]string{model.KopsInstanceGroupNodeLabel: nodeGroupName}
+	for _, taint := range nodeGroup.Taints {
+		nodeGroupTaint, err := model.ParseNodeGroupTaint(taint)
+		if err != nil {
+			continue
+		}
+		toleration := corev1.Toleration{
+			Key:      nodeGroupTaint.Key,
+			Operator: corev1.TolerationOpEqual,
+			Value:    nodeGroupTaint.Value,
+			Effect:   corev1.TaintEffect(nodeGroupTaint.Effect),
+		}
+		if len(nodeGroupTaint.Value) == 0 {
+			toleration.Operator = corev1.TolerationOpExists
+		}
+		scheduling.Tolerations = append(scheduling.Tolerations, toleration)
+	}
+}
+
 // getMattermostCustomResource gets the cluster installation resource from
 // the kubernetes API.
 func (provisioner *crProvisionerWrapper) getMattermostCustomResource(cluster *model.Cluster, clusterInstallation *model.ClusterInstallation, logger log.FieldLogger) (*mmv1beta1.Mattermost, error) {
@@ -823,7 +867,7 @@ func (provisioner *KopsProvisioner) PrepareClusterUtilities(cluster *model.Clust
 	// TODO: Yeah, so this is definitely a bit of a race condition. We would
 	// need to lock a bunch of stuff to do this completely properly, but that
 	// isn't really feasible right now.
-	ini, err := generatePGBouncerIni(cluster.ProvisionerMetadataKops.VPC, store)
+	ini, err := generatePGBouncerIni(cluster.ProvisionerMetadataKops.VPC, store, logger)
 	if err != nil {
 		return errors.Wrap(err, "failed to generate updated pgbouncer ini contents")
 	}
@@ -981,12 +1025,21 @@ func getMattermostEnvWithOverrides(installation *model.Installation) model.EnvVa
 	}
 	mattermostEnv["MM_SERVICESETTINGS_ENABLELOCALMODE"] = model.EnvVar{Value: "true"}
 
+	// Database overrides.
+	if installation.DatabaseCredentialsRotatedAt != 0 {
+		mattermostEnv["MM_CLOUD_DATABASE_CREDENTIALS_ROTATED_AT"] = model.EnvVar{Value: strconv.FormatInt(installation.DatabaseCredentialsRotatedAt, 10)}
+	}
+
 	// Filestore overrides.
+	if installation.FilestoreAccessKeyRotatedAt != 0 {
+		mattermostEnv["MM_CLOUD_FILESTORE_ACCESS_KEY_ROTATED_AT"] = model.EnvVar{Value: strconv.FormatInt(installation.FilestoreAccessKeyRotatedAt, 10)}
+	}
 	if !installation.InternalFilestore() {
 		mattermostEnv["MM_FILESETTINGS_AMAZONS3SSE"] = model.EnvVar{Value: "true"}
 	}
 	if installation.Filestore == model.InstallationFilestoreMultiTenantAwsS3 ||
-		installation.Filestore == model.InstallationFilestoreBifrost {
+		installation.Filestore == model.InstallationFilestoreBifrost ||
+		installation.Filestore == model.InstallationFilestoreS3Compatible {
 		mattermostEnv["MM_FILESETTINGS_AMAZONS3PATHPREFIX"] = model.EnvVar{Value: installation.ID}
 	}
 	if installation.Filestore == model.InstallationFilestoreBifrost {
@@ -996,6 +1049,11 @@ func getMattermostEnvWithOverrides(installation *model.Installation) model.EnvVa
 		mattermostEnv["MM_FILESETTINGS_AMAZONS3SSE"] = model.EnvVar{Value: "false"}
 		mattermostEnv["MM_FILESETTINGS_AMAZONS3SSL"] = model.EnvVar{Value: "false"}
 	}
+	if installation.Filestore == model.InstallationFilestoreS3Compatible {
+		// Server side encryption requires a KMS on most S3-compatible servers.
+		mattermostEnv["MM_FILESETTINGS_AMAZONS3SSE"] = model.EnvVar{Value: "false"}
+		mattermostEnv["MM_FILESETTINGS_AMAZONS3SIGNV2"] = model.EnvVar{Value: "false"}
+	}
 
 	return mattermostEnv
 }
@@ -0,0 +1,475 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+	v1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
+	"github.com/mattermost/mattermost-cloud/k8s"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+// instanceGroupHealthTimeout is how many seconds to wait for the cluster to
+// validate after the rolling update of an instance group.
+const instanceGroupHealthTimeout = 1000
+
+// removedAPI is an API version of a resource kind which is no longer served
+// starting with a k8s version. A kind of "*" matches every kind of the API
+// version.
+type removedAPI struct {
+	apiVersion  string
+	kind        string
+	removedIn   string
+	replacement string
+}
+
+// removedAPIs are the APIs removed by the k8s versions the clusters can be
+// upgraded to.
+var removedAPIs = []removedAPI{
+	{"extensions/v1beta1", "Deployment", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "DaemonSet", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "ReplicaSet", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "NetworkPolicy", "1.16", "networking.k8s.io/v1"},
+	{"extensions/v1beta1", "PodSecurityPolicy", "1.16", "policy/v1beta1"},
+	{"apps/v1beta1", "*", "1.16", "apps/v1"},
+	{"apps/v1beta2", "*", "1.16", "apps/v1"},
+	{"extensions/v1beta1", "Ingress", "1.22", "networking.k8s.io/v1"},
+	{"networking.k8s.io/v1beta1", "*", "1.22", "networking.k8s.io/v1"},
+	{"apiextensions.k8s.io/v1beta1", "*", "1.22", "apiextensions.k8s.io/v1"},
+	{"apiregistration.k8s.io/v1beta1", "*", "1.22", "apiregistration.k8s.io/v1"},
+	{"admissionregistration.k8s.io/v1beta1", "*", "1.22", "admissionregistration.k8s.io/v1"},
+	{"authentication.k8s.io/v1beta1", "*", "1.22", "authentication.k8s.io/v1"},
+	{"authorization.k8s.io/v1beta1", "*", "1.22", "authorization.k8s.io/v1"},
+	{"certificates.k8s.io/v1beta1", "*", "1.22", "certificates.k8s.io/v1"},
+	{"coordination.k8s.io/v1beta1", "*", "1.22", "coordination.k8s.io/v1"},
+	{"rbac.authorization.k8s.io/v1beta1", "*", "1.22", "rbac.authorization.k8s.io/v1"},
+	{"scheduling.k8s.io/v1beta1", "*", "1.22", "scheduling.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSIDriver", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "CSINode", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "StorageClass", "1.22", "storage.k8s.io/v1"},
+	{"storage.k8s.io/v1beta1", "VolumeAttachment", "1.22", "storage.k8s.io/v1"},
+	{"batch/v1beta1", "CronJob", "1.25", "batch/v1"},
+	{"discovery.k8s.io/v1beta1", "EndpointSlice", "1.25", "discovery.k8s.io/v1"},
+	{"events.k8s.io/v1beta1", "Event", "1.25", "events.k8s.io/v1"},
+	{"autoscaling/v2beta1", "HorizontalPodAutoscaler", "1.25", "autoscaling/v2"},
+	{"policy/v1beta1", "PodDisruptionBudget", "1.25", "policy/v1"},
+	{"policy/v1beta1", "PodSecurityPolicy", "1.25", ""},
+	{"node.k8s.io/v1beta1", "RuntimeClass", "1.25", "node.k8s.io/v1"},
+	{"autoscaling/v2beta2", "HorizontalPodAutoscaler", "1.26", "autoscaling/v2"},
+	{"flowcontrol.apiserver.k8s.io/v1beta1", "*", "1.26", "flowcontrol.apiserver.k8s.io/v1beta3"},
+	{"storage.k8s.io/v1beta1", "CSIStorageCapacity", "1.27", "storage.k8s.io/v1"},
+}
+
+// manifestResource is the part of a k8s resource manifest identifying it.
+type manifestResource struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+}
+
+// findRemovedAPIs returns a description of every resource of the manifest
+// using an API which is no longer served by the target k8s version.
+func findRemovedAPIs(manifest string, targetVersion semver.Version) []string {
+	var found []string
+	for _, document := range strings.Split(manifest, "\n---") {
+		var resource manifestResource
+		err := yaml.Unmarshal([]byte(document), &resource)
+		if err != nil || len(resource.APIVersion) == 0 {
+			continue
+		}
+
+		for _, api := range removedAPIs {
+			if api.apiVersion != resource.APIVersion || (api.kind != "*" && api.kind != resource.Kind) {
+				continue
+			}
+			removedIn := semver.MustParse(api.removedIn + ".0")
+			if targetVersion.Major < removedIn.Major ||
+				(targetVersion.Major == removedIn.Major && targetVersion.Minor < removedIn.Minor) {
+				continue
+			}
+
+			description := fmt.Sprintf("%s %s uses %s which is removed in %s", resource.Kind, resource.Metadata.Name, resource.APIVersion, api.removedIn)
+			if len(api.replacement) != 0 {
+				description += fmt.Sprintf(", use %s instead", api.replacement)
+			}
+			found = append(found, description)
+			break
+		}
+	}
+
+	return found
+}
+
+// podDisruptionBudgetStatus is the state of a PodDisruptionBudget relevant
+// to draining nodes.
+type podDisruptionBudgetStatus struct {
+	namespace          string
+	name               string
+	expectedPods       int32
+	disruptionsAllowed int32
+}
+
+// findBlockingPodDisruptionBudgets returns a description of every
+// PodDisruptionBudget which currently allows no disruption of its pods and
+// would therefore block the draining of nodes.
+func findBlockingPodDisruptionBudgets(budgets []podDisruptionBudgetStatus) []string {
+	var blocking []string
+	for _, budget := range budgets {
+		if budget.expectedPods > 0 && budget.disruptionsAllowed < 1 {
+			blocking = append(blocking, fmt.Sprintf("PodDisruptionBudget %s/%s allows no disruption of its %d pods", budget.namespace, budget.name, budget.expectedPods))
+		}
+	}
+
+	return blocking
+}
+
+// listPodDisruptionBudgets lists the PodDisruptionBudgets of all namespaces.
+// The v1beta1 API is used for clusters not serving the v1 API yet.
+func listPodDisruptionBudgets(k8sClient *k8s.KubeClient) ([]podDisruptionBudgetStatus, error) {
+	ctx := context.TODO()
+
+	var budgets []podDisruptionBudgetStatus
+	pdbs, err := k8sClient.Clientset.PolicyV1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err == nil {
+		for _, pdb := range pdbs.Items {
+			budgets = append(budgets, podDisruptionBudgetStatus{
+				namespace:          pdb.Namespace,
+				name:               pdb.Name,
+				expectedPods:       pdb.Status.ExpectedPods,
+				disruptionsAllowed: pdb.Status.DisruptionsAllowed,
+			})
+		}
+		return budgets, nil
+	}
+	if !k8sErrors.IsNotFound(err) {
+		return nil, errors.Wrap(err, "failed to list pod disruption budgets")
+	}
+
+	betaPDBs, err := k8sClient.Clientset.PolicyV1beta1().PodDisruptionBudgets("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pod disruption budgets")
+	}
+	for _, pdb := range betaPDBs.Items {
+		budgets = append(budgets, podDisruptionBudgetStatus{
+			namespace:          pdb.Namespace,
+			name:               pdb.Name,
+			expectedPods:       pdb.Status.ExpectedPods,
+			disruptionsAllowed: pdb.Status.DisruptionsAllowed,
+		})
+	}
+
+	return budgets, nil
+}
+
+// nodeCapacity is the allocatable and requested resources of a worker node.
+// Requests of pods which are not moved when the node is drained, such as
+// DaemonSet pods, are not included.
+type nodeCapacity struct {
+	name              string
+	allocatableCPU    int64
+	allocatableMemory int64
+	requestedCPU      int64
+	requestedMemory   int64
+}
+
+// checkSurgeCapacity returns a description of every worker node whose pods
+// can't be scheduled on the remaining nodes while it is replaced during the
+// rolling update.
+func checkSurgeCapacity(nodes []nodeCapacity) []string {
+	var freeCPU, freeMemory int64
+	for _, node := range nodes {
+		freeCPU += node.allocatableCPU - node.requestedCPU
+		freeMemory += node.allocatableMemory - node.requestedMemory
+	}
+
+	var problems []string
+	for _, node := range nodes {
+		otherFreeCPU := freeCPU - (node.allocatableCPU - node.requestedCPU)
+		otherFreeMemory := freeMemory - (node.allocatableMemory - node.requestedMemory)
+		if node.requestedCPU > otherFreeCPU {
+			problems = append(problems, fmt.Sprintf("node %s requests %dm CPU but only %dm is free on the other nodes", node.name, node.requestedCPU, otherFreeCPU))
+		}
+		if node.requestedMemory > otherFreeMemory {
+			problems = append(problems, fmt.Sprintf("node %s requests %dMi memory but only %dMi is free on the other nodes", node.name, node.requestedMemory/1000/1024/1024, otherFreeMemory/1000/1024/1024))
+		}
+	}
+
+	return problems
+}
+
+// isMasterNode returns true if the node runs the k8s control plane.
+func isMasterNode(node v1.Node) bool {
+	for _, label := range []string{"node-role.kubernetes.io/master", "node-role.kubernetes.io/control-plane"} {
+		if _, ok := node.Labels[label]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getWorkerNodeCapacities returns the capacity of the schedulable worker
+// nodes of a cluster.
+func getWorkerNodeCapacities(k8sClient *k8s.KubeClient) ([]nodeCapacity, error) {
+	ctx := context.TODO()
+	nodes, err := k8sClient.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	var capacities []nodeCapacity
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable || isMasterNode(node) {
+			continue
+		}
+
+		pods, err := k8sClient.Clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+			FieldSelector: fmt.Sprintf("spec.nodeName=%s", node.GetName()),
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to list pods for node %s", node.GetName())
+		}
+
+		var movedPods []v1.Pod
+		for _, pod := range pods.Items {
+			if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+				continue
+			}
+			if isDaemonSetPod(pod) {
+				continue
+			}
+			movedPods = append(movedPods, pod)
+		}
+		requestedCPU, requestedMemory := k8s.CalculateTotalPodMilliResourceRequests(movedPods)
+
+		capacities = append(capacities, nodeCapacity{
+			name:              node.GetName(),
+			allocatableCPU:    node.Status.Allocatable.Cpu().MilliValue(),
+			allocatableMemory: node.Status.Allocatable.Memory().MilliValue(),
+			requestedCPU:      requestedCPU,
+			requestedMemory:   requestedMemory,
+		})
+	}
+
+	return capacities, nil
+}
+
+func isDaemonSetPod(pod v1.Pod) bool {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// notReadyNodes returns the names of the nodes which are not ready.
+func notReadyNodes(nodes []v1.Node) []string {
+	var notReady []string
+	for _, node := range nodes {
+		ready := false
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeReady && condition.Status == v1.ConditionTrue {
+				ready = true
+				break
+			}
+		}
+		if !ready {
+			notReady = append(notReady, node.GetName())
+		}
+	}
+
+	return notReady
+}
+
+// orderInstanceGroupsForUpgrade returns the instance groups in the order
+// they are rolled during an upgrade: masters first, then worker node groups
+// and then any other instance group, each sorted by name.
+func orderInstanceGroupsForUpgrade(instanceGroups []kops.InstanceGroup) []kops.InstanceGroup {
+	rolePriority := func(role string) int {
+		switch role {
+		case "Master", "ControlPlane":
+			return 0
+		case "Node":
+			return 1
+		}
+		return 2
+	}
+
+	ordered := append([]kops.InstanceGroup{}, instanceGroups...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		pi, pj := rolePriority(ordered[i].Spec.Role), rolePriority(ordered[j].Spec.Role)
+		if pi != pj {
+			return pi < pj
+		}
+		return ordered[i].Metadata.Name < ordered[j].Metadata.Name
+	})
+
+	return ordered
+}
+
+// getKopsKubernetesVersion returns the k8s version of the kops cluster spec.
+func getKopsKubernetesVersion(kopsClient *kops.Cmd, name string) (string, error) {
+	versionJSON, err := kopsClient.GetClusterSpecInfoFromJSON(name, "kubernetesVersion")
+	if err != nil {
+		return "", err
+	}
+
+	var version string
+	err = json.Unmarshal([]byte(versionJSON), &version)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse kubernetes version of cluster spec")
+	}
+
+	return version, nil
+}
+
+// runUpgradePreflightChecks runs the checks ensuring that the cluster can be
+// upgraded to the target version and records their results in the report.
+func (provisioner *KopsProvisioner) runUpgradePreflightChecks(cluster *model.Cluster, report *model.ClusterUpgradeReport, logger log.FieldLogger) error {
+	kopsMetadata := cluster.ProvisionerMetadataKops
+
+	targetVersion, err := semver.ParseTolerant(report.ToVersion)
+	if err != nil {
+		return errors.Wrapf(err, "failed to parse target version %s", report.ToVersion)
+	}
+
+	configPath, err := provisioner.getCachedKopsClusterKubecfg(kopsMetadata.Name, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to get kops config from cache")
+	}
+	k8sClient, invalidateOnError, err := provisioner.k8sClient(kopsMetadata.Name, logger)
+	if err != nil {
+		return err
+	}
+	defer invalidateOnError(err)
+
+	logger.Info("Checking Helm releases for removed APIs")
+	releases, err := listHelmReleases(configPath, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to list Helm releases")
+	}
+	var removedAPIUsages []string
+	for _, release := range releases.asSlice() {
+		manifest, err := getHelmReleaseManifest(configPath, release.Name, release.Namespace, logger)
+		if err != nil {
+			return err
+		}
+		for _, usage := range findRemovedAPIs(manifest, targetVersion) {
+			removedAPIUsages = append(removedAPIUsages, fmt.Sprintf("release %s/%s: %s", release.Namespace, release.Name, usage))
+		}
+	}
+	report.AddPreflightCheck(model.ClusterUpgradeCheckDeprecatedAPIs, removedAPIUsages)
+
+	logger.Info("Checking pod disruption budgets")
+	budgets, err := listPodDisruptionBudgets(k8sClient)
+	if err != nil {
+		return err
+	}
+	report.AddPreflightCheck(model.ClusterUpgradeCheckPodDisruptionBudgets, findBlockingPodDisruptionBudgets(budgets))
+
+	logger.Info("Checking node capacity for the rolling update")
+	capacities, err := getWorkerNodeCapacities(k8sClient)
+	if err != nil {
+		return err
+	}
+	report.AddPreflightCheck(model.ClusterUpgradeCheckSurgeCapacity, checkSurgeCapacity(capacities))
+
+	for _, check := range report.PreflightChecks {
+		if !check.Passed {
+			logger.Warnf("Upgrade pre-flight check %s failed: %s", check.Name, strings.Join(check.Details, "; "))
+		}
+	}
+
+	return nil
+}
+
+// stagedRollingUpdate rolls the instance groups of the cluster one at a time
+// and validates the health of the cluster after each of them. The progress
+// is recorded in the report.
+func (provisioner *KopsProvisioner) stagedRollingUpdate(kopsClient *kops.Cmd, cluster *model.Cluster, report *model.ClusterUpgradeReport, logger log.FieldLogger) error {
+	name := cluster.ProvisionerMetadataKops.Name
+
+	instanceGroups, err := kopsClient.GetInstanceGroupsJSON(name)
+	if err != nil {
+		return errors.Wrap(err, "failed to get instance groups")
+	}
+	instanceGroups = orderInstanceGroupsForUpgrade(instanceGroups)
+
+	report.InstanceGroups = nil
+	for _, ig := range instanceGroups {
+		report.InstanceGroups = append(report.InstanceGroups, model.ClusterUpgradeInstanceGroup{
+			Name:   ig.Metadata.Name,
+			Role:   ig.Spec.Role,
+			Status: model.InstanceGroupUpgradeStatusPending,
+		})
+	}
+
+	for i := range report.InstanceGroups {
+		step := &report.InstanceGroups[i]
+		logger.Infof("Rolling instance group %s (%d/%d)", step.Name, i+1, len(report.InstanceGroups))
+		step.Status = model.InstanceGroupUpgradeStatusUpdating
+		step.StartedAt = model.GetMillis()
+
+		err = kopsClient.RollingUpdateInstanceGroup(name, step.Name)
+		if err == nil {
+			err = provisioner.validateClusterHealth(kopsClient, name, logger)
+		}
+		step.CompletedAt = model.GetMillis()
+		if err != nil {
+			step.Status = model.InstanceGroupUpgradeStatusFailed
+			step.Error = err.Error()
+			return errors.Wrapf(err, "failed to roll instance group %s", step.Name)
+		}
+		step.Status = model.InstanceGroupUpgradeStatusUpdated
+	}
+
+	return nil
+}
+
+// validateClusterHealth waits for the cluster to pass the kops validation and
+// ensures every node is ready.
+func (provisioner *KopsProvisioner) validateClusterHealth(kopsClient *kops.Cmd, name string, logger log.FieldLogger) error {
+	logger.Infof("Waiting up to %d seconds for k8s cluster to become ready...", instanceGroupHealthTimeout)
+	err := kopsClient.WaitForKubernetesReadiness(name, instanceGroupHealthTimeout)
+	if err != nil {
+		// Run non-silent validate one more time to log final cluster state
+		// and return original timeout error.
+		kopsClient.ValidateCluster(name, false)
+		return err
+	}
+
+	k8sClient, invalidateOnError, err := provisioner.k8sClient(name, logger)
+	if err != nil {
+		return err
+	}
+	defer invalidateOnError(err)
+
+	nodes, err := k8sClient.Clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to list nodes")
+	}
+	if notReady := notReadyNodes(nodes.Items); len(notReady) != 0 {
+		return errors.Errorf("nodes %s are not ready", strings.Join(notReady, ", "))
+	}
+
+	return nil
+}
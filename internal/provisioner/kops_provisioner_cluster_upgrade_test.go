@@ -0,0 +1,200 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
+	"github.com/mattermost/mattermost-cloud/k8s"
+)
+
+const testManifest = `---
+# Source: chart/templates/ingress.yaml
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: my-ingress
+---
+# Source: chart/templates/cronjob.yaml
+apiVersion: batch/v1beta1
+kind: CronJob
+metadata:
+  name: my-cronjob
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: my-deployment
+`
+
+func TestFindRemovedAPIs(t *testing.T) {
+	t.Run("before removal", func(t *testing.T) {
+		assert.Empty(t, findRemovedAPIs(testManifest, semver.MustParse("1.21.9")))
+	})
+
+	t.Run("ingress removed", func(t *testing.T) {
+		found := findRemovedAPIs(testManifest, semver.MustParse("1.22.0"))
+		require.Len(t, found, 1)
+		assert.Equal(t, "Ingress my-ingress uses networking.k8s.io/v1beta1 which is removed in 1.22, use networking.k8s.io/v1 instead", found[0])
+	})
+
+	t.Run("ingress and cronjob removed", func(t *testing.T) {
+		found := findRemovedAPIs(testManifest, semver.MustParse("1.25.3"))
+		require.Len(t, found, 2)
+		assert.Contains(t, found[1], "CronJob my-cronjob uses batch/v1beta1")
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		assert.Empty(t, findRemovedAPIs("{", semver.MustParse("1.25.3")))
+	})
+}
+
+func TestFindBlockingPodDisruptionBudgets(t *testing.T) {
+	blocking := findBlockingPodDisruptionBudgets([]podDisruptionBudgetStatus{
+		{namespace: "ns", name: "blocking", expectedPods: 2, disruptionsAllowed: 0},
+		{namespace: "ns", name: "allowing", expectedPods: 2, disruptionsAllowed: 1},
+		{namespace: "ns", name: "empty", expectedPods: 0, disruptionsAllowed: 0},
+	})
+	assert.Equal(t, []string{"PodDisruptionBudget ns/blocking allows no disruption of its 2 pods"}, blocking)
+}
+
+func TestListPodDisruptionBudgets(t *testing.T) {
+	k8sClient := &k8s.KubeClient{Clientset: fake.NewSimpleClientset(&policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "pdb", Namespace: "ns"},
+		Status:     policyv1.PodDisruptionBudgetStatus{ExpectedPods: 3, DisruptionsAllowed: 1},
+	})}
+
+	budgets, err := listPodDisruptionBudgets(k8sClient)
+	require.NoError(t, err)
+	assert.Equal(t, []podDisruptionBudgetStatus{
+		{namespace: "ns", name: "pdb", expectedPods: 3, disruptionsAllowed: 1},
+	}, budgets)
+}
+
+func TestCheckSurgeCapacity(t *testing.T) {
+	t.Run("enough capacity", func(t *testing.T) {
+		assert.Empty(t, checkSurgeCapacity([]nodeCapacity{
+			{name: "node1", allocatableCPU: 2000, allocatableMemory: 4000, requestedCPU: 1000, requestedMemory: 1000},
+			{name: "node2", allocatableCPU: 2000, allocatableMemory: 4000, requestedCPU: 500, requestedMemory: 1000},
+			{name: "node3", allocatableCPU: 2000, allocatableMemory: 4000, requestedCPU: 500, requestedMemory: 1000},
+		}))
+	})
+
+	t.Run("not enough cpu", func(t *testing.T) {
+		problems := checkSurgeCapacity([]nodeCapacity{
+			{name: "node1", allocatableCPU: 2000, allocatableMemory: 4000, requestedCPU: 1800, requestedMemory: 1000},
+			{name: "node2", allocatableCPU: 2000, allocatableMemory: 4000, requestedCPU: 1800, requestedMemory: 1000},
+		})
+		assert.Equal(t, []string{
+			"node node1 requests 1800m CPU but only 200m is free on the other nodes",
+			"node node2 requests 1800m CPU but only 200m is free on the other nodes",
+		}, problems)
+	})
+
+	t.Run("single node", func(t *testing.T) {
+		problems := checkSurgeCapacity([]nodeCapacity{
+			{name: "node1", allocatableCPU: 2000, allocatableMemory: 4000, requestedCPU: 100, requestedMemory: 100},
+		})
+		assert.Len(t, problems, 2)
+	})
+}
+
+func TestGetWorkerNodeCapacities(t *testing.T) {
+	newPod := func(name, node, cpu string, owner string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+			Spec: corev1.PodSpec{
+				NodeName: node,
+				Containers: []corev1.Container{{
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+					},
+				}},
+			},
+		}
+		if len(owner) != 0 {
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: "owner"}}
+		}
+		return pod
+	}
+
+	k8sClient := &k8s.KubeClient{Clientset: fake.NewSimpleClientset(
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "master", Labels: map[string]string{"node-role.kubernetes.io/master": ""}},
+		},
+		&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker"},
+			Status: corev1.NodeStatus{
+				Allocatable: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2")},
+			},
+		},
+		newPod("app", "worker", "500m", "ReplicaSet"),
+		newPod("agent", "worker", "100m", "DaemonSet"),
+	)}
+
+	// The fake clientset ignores field selectors, so every pod is returned
+	// for every node.
+	capacities, err := getWorkerNodeCapacities(k8sClient)
+	require.NoError(t, err)
+	require.Len(t, capacities, 1)
+	assert.Equal(t, "worker", capacities[0].name)
+	assert.EqualValues(t, 2000, capacities[0].allocatableCPU)
+	assert.EqualValues(t, 500, capacities[0].requestedCPU)
+}
+
+func TestNotReadyNodes(t *testing.T) {
+	nodes := []corev1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "ready"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "not-ready"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionFalse},
+			}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "unknown"},
+		},
+	}
+
+	assert.Equal(t, []string{"not-ready", "unknown"}, notReadyNodes(nodes))
+}
+
+func TestOrderInstanceGroupsForUpgrade(t *testing.T) {
+	newInstanceGroup := func(name, role string) kops.InstanceGroup {
+		return kops.InstanceGroup{
+			Metadata: kops.InstanceGroupMetadata{Name: name},
+			Spec:     kops.InstanceGroupSpec{Role: role},
+		}
+	}
+
+	ordered := orderInstanceGroupsForUpgrade([]kops.InstanceGroup{
+		newInstanceGroup("nodes-b", "Node"),
+		newInstanceGroup("bastions", "Bastion"),
+		newInstanceGroup("nodes-a", "Node"),
+		newInstanceGroup("master-us-east-1b", "Master"),
+		newInstanceGroup("master-us-east-1a", "Master"),
+	})
+
+	var names []string
+	for _, ig := range ordered {
+		names = append(names, ig.Metadata.Name)
+	}
+	assert.Equal(t, []string{"master-us-east-1a", "master-us-east-1b", "nodes-a", "nodes-b", "bastions"}, names)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EnsureDBEngineMigrationSecret creates or updates database secret with the
+// specified role used by database engine migration jobs of the installation.
+func (provisioner *KopsProvisioner) EnsureDBEngineMigrationSecret(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation, role string, secret *corev1.Secret) error {
+	logger := provisioner.dbEngineMigrationLogger(cluster, dbMigration)
+	logger.Infof("Ensuring %s database secret for database engine migration", role)
+
+	k8sClient, invalidateCache, err := provisioner.k8sClient(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+	defer invalidateCache(err)
+
+	secretsClient := k8sClient.Clientset.CoreV1().Secrets(dbMigration.InstallationID)
+
+	return provisioner.dbEngineMigrationOperator.EnsureSecret(secretsClient, dbMigration, role, secret, logger)
+}
+
+// TriggerDBEngineMigrationJob triggers database engine migration job for the
+// installation on the cluster.
+func (provisioner *KopsProvisioner) TriggerDBEngineMigrationJob(cluster *model.Cluster, installation *model.Installation, dbMigration *model.InstallationDBMigrationOperation) error {
+	logger := provisioner.dbEngineMigrationLogger(cluster, dbMigration)
+	logger.Info("Triggering database engine migration job for installation")
+
+	k8sClient, invalidateCache, err := provisioner.k8sClient(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+	defer invalidateCache(err)
+
+	jobsClient := k8sClient.Clientset.BatchV1().Jobs(dbMigration.InstallationID)
+
+	return provisioner.dbEngineMigrationOperator.TriggerJob(jobsClient, dbMigration, installation.Version, logger)
+}
+
+// CheckDBEngineMigrationJobStatus checks status of database engine migration
+// job, returns job completion time, when the job finished or -1 if it is still
+// running.
+func (provisioner *KopsProvisioner) CheckDBEngineMigrationJobStatus(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) (int64, error) {
+	logger := provisioner.dbEngineMigrationLogger(cluster, dbMigration)
+	logger.Info("Checking database engine migration job status for installation")
+
+	k8sClient, invalidateCache, err := provisioner.k8sClient(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return -1, errors.Wrap(err, "failed to create k8s client")
+	}
+	defer invalidateCache(err)
+
+	jobsClient := k8sClient.Clientset.BatchV1().Jobs(dbMigration.InstallationID)
+
+	return provisioner.dbEngineMigrationOperator.CheckJobStatus(jobsClient, dbMigration, logger)
+}
+
+// CleanupDBEngineMigrationJob deletes database engine migration job from the
+// cluster if it exists.
+func (provisioner *KopsProvisioner) CleanupDBEngineMigrationJob(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error {
+	logger := provisioner.dbEngineMigrationLogger(cluster, dbMigration)
+	logger.Info("Cleaning up database engine migration job for installation")
+
+	k8sClient, invalidateCache, err := provisioner.k8sClient(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+	defer invalidateCache(err)
+
+	jobsClient := k8sClient.Clientset.BatchV1().Jobs(dbMigration.InstallationID)
+
+	return provisioner.dbEngineMigrationOperator.CleanupJob(jobsClient, dbMigration, logger)
+}
+
+// VerifyDBEngineMigrationRowCounts compares the row counts of the tables
+// migrated between the source and destination database of the installation.
+// ErrDBEngineMigrationRowCountMismatch is returned if they don't match.
+func (provisioner *KopsProvisioner) VerifyDBEngineMigrationRowCounts(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error {
+	logger := provisioner.dbEngineMigrationLogger(cluster, dbMigration)
+	logger.Info("Verifying row counts of database engine migration for installation")
+
+	k8sClient, invalidateCache, err := provisioner.k8sClient(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+	defer invalidateCache(err)
+
+	secretsClient := k8sClient.Clientset.CoreV1().Secrets(dbMigration.InstallationID)
+
+	return provisioner.dbEngineMigrationOperator.VerifyRowCounts(secretsClient, dbMigration, logger)
+}
+
+// CleanupDBEngineMigrationSecrets deletes database secrets used by database
+// engine migration jobs from the cluster if they exist.
+func (provisioner *KopsProvisioner) CleanupDBEngineMigrationSecrets(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error {
+	logger := provisioner.dbEngineMigrationLogger(cluster, dbMigration)
+	logger.Info("Cleaning up database engine migration secrets for installation")
+
+	k8sClient, invalidateCache, err := provisioner.k8sClient(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+	defer invalidateCache(err)
+
+	secretsClient := k8sClient.Clientset.CoreV1().Secrets(dbMigration.InstallationID)
+
+	return provisioner.dbEngineMigrationOperator.CleanupSecrets(secretsClient, dbMigration, logger)
+}
+
+func (provisioner *KopsProvisioner) dbEngineMigrationLogger(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) log.FieldLogger {
+	return provisioner.logger.WithFields(log.Fields{
+		"cluster":      cluster.ID,
+		"installation": dbMigration.InstallationID,
+		"dbMigration":  dbMigration.ID,
+	})
+}
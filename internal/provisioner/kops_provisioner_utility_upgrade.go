@@ -0,0 +1,199 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+// utilityRelease is the Helm release deployed by a cluster utility.
+type utilityRelease struct {
+	name           string
+	chart          string
+	namespace      string
+	valuesOverride string
+}
+
+// utilityReleases are the Helm releases of the cluster utilities by canonical
+// name. They must be kept in line with the Helm deployments of the utility
+// handles.
+var utilityReleases = map[string]utilityRelease{
+	model.NginxCanonicalName:               {name: "nginx", chart: "ingress-nginx/ingress-nginx", namespace: "nginx"},
+	model.NginxInternalCanonicalName:       {name: "nginx-internal", chart: "ingress-nginx/ingress-nginx", namespace: "nginx-internal"},
+	model.PrometheusOperatorCanonicalName:  {name: "prometheus-operator", chart: "prometheus-community/kube-prometheus-stack", namespace: "prometheus"},
+	model.ThanosCanonicalName:              {name: "thanos", chart: "bitnami/thanos", namespace: "prometheus"},
+	model.FluentbitCanonicalName:           {name: "fluent-bit", chart: "fluent/fluent-bit", namespace: "fluent-bit"},
+	model.TeleportCanonicalName:            {name: "teleport-kube-agent", chart: "chartmuseum/teleport-kube-agent", namespace: "teleport"},
+	model.PgbouncerCanonicalName:           {name: "pgbouncer", chart: "chartmuseum/pgbouncer", namespace: "pgbouncer"},
+	model.PromtailCanonicalName:            {name: "promtail", chart: "grafana/promtail", namespace: "promtail"},
+	model.KubecostCanonicalName:            {name: "cost-analyzer", chart: "kubecost/cost-analyzer", namespace: "kubecost"},
+	model.NodeProblemDetectorCanonicalName: {name: "node-problem-detector", chart: "deliveryhero/node-problem-detector", namespace: "node-problem-detector"},
+}
+
+// getUtilityRelease returns the Helm release of a builtin or registered
+// utility.
+func getUtilityRelease(utilityName string) (utilityRelease, bool) {
+	if release, found := utilityReleases[utilityName]; found {
+		return release, true
+	}
+
+	definition := model.GetRegisteredUtility(utilityName)
+	if definition == nil {
+		return utilityRelease{}, false
+	}
+
+	return utilityRelease{
+		name:           definition.Release(),
+		chart:          definition.Chart,
+		namespace:      definition.Namespace,
+		valuesOverride: definition.ValuesTemplate,
+	}, true
+}
+
+// UpgradeClusterUtility upgrades a single utility of the cluster to the
+// version of its requested utility upgrade, or only computes the Helm diff of
+// the upgrade when a preview was requested. A failed upgrade is rolled back to
+// the actual version of the utility.
+func (provisioner *KopsProvisioner) UpgradeClusterUtility(cluster *model.Cluster, utilityName string, awsClient aws.AWS) error {
+	logger := provisioner.logger.WithFields(logrus.Fields{
+		"cluster": cluster.ID,
+		"utility": utilityName,
+	})
+
+	upgrade := cluster.GetUtilityUpgrade(utilityName)
+	if upgrade == nil || upgrade.State != model.UtilityUpgradeStateRequested {
+		return errors.Errorf("no upgrade requested for utility %s", utilityName)
+	}
+
+	logger.Infof("Upgrading utility to chart version %s", upgrade.ToVersion.Version())
+
+	state, err := provisioner.upgradeClusterUtility(cluster, utilityName, upgrade, awsClient, logger)
+	upgrade.Complete(state, err)
+
+	return err
+}
+
+func (provisioner *KopsProvisioner) upgradeClusterUtility(cluster *model.Cluster, utilityName string, upgrade *model.UtilityUpgrade, awsClient aws.AWS, logger logrus.FieldLogger) (string, error) {
+	release, found := getUtilityRelease(utilityName)
+	if !found {
+		return model.UtilityUpgradeStateFailed, errors.Errorf("unknown utility %s", utilityName)
+	}
+
+	kopsClient, err := provisioner.getCachedKopsClient(cluster.ProvisionerMetadataKops.Name, logger)
+	if err != nil {
+		return model.UtilityUpgradeStateFailed, errors.Wrap(err, "failed to get kops client from cache")
+	}
+
+	err = addHelmRepos(logger)
+	if err != nil {
+		return model.UtilityUpgradeStateFailed, err
+	}
+
+	upgrade.FromVersion = cluster.ActualUtilityVersion(utilityName)
+
+	getContext := cachedValuesContext(func() (*model.UtilityValuesContext, error) {
+		return awsClient.GetUtilityValuesContext(cluster.ID, logger)
+	})
+	upgrade.Diff, err = previewUtilityUpgrade(kopsClient.GetKubeConfigPath(), release, upgrade, getContext, logger)
+	if err != nil {
+		return model.UtilityUpgradeStateFailed, errors.Wrap(err, "failed to compute the diff of the utility upgrade")
+	}
+	if upgrade.Preview {
+		logger.Info("Computed utility upgrade preview")
+		return model.UtilityUpgradeStatePreviewed, nil
+	}
+
+	err = provisioner.deployUtilityVersion(cluster, kopsClient, utilityName, upgrade.ToVersion, awsClient, logger)
+	if err == nil {
+		logger.Info("Upgraded utility")
+		return model.UtilityUpgradeStateUpgraded, nil
+	}
+
+	if upgrade.FromVersion.IsEmpty() {
+		return model.UtilityUpgradeStateFailed, errors.Wrap(err, "failed to upgrade utility and no actual version to roll back to")
+	}
+
+	logger.WithError(err).Warnf("Failed to upgrade utility, rolling back to chart version %s", upgrade.FromVersion.Version())
+	rollbackErr := provisioner.deployUtilityVersion(cluster, kopsClient, utilityName, upgrade.FromVersion, awsClient, logger)
+	if rollbackErr != nil {
+		return model.UtilityUpgradeStateFailed, errors.Wrapf(err, "failed to upgrade utility and to roll it back (%s)", rollbackErr)
+	}
+
+	return model.UtilityUpgradeStateRolledBack, errors.Wrapf(err, "failed to upgrade utility, rolled back to chart version %s", upgrade.FromVersion.Version())
+}
+
+// deployUtilityVersion deploys the given version of a single utility and
+// records it as the actual version of the utility.
+func (provisioner *KopsProvisioner) deployUtilityVersion(cluster *model.Cluster, kopsClient *kops.Cmd, utilityName string, version *model.HelmUtilityVersion, awsClient aws.AWS, logger logrus.FieldLogger) error {
+	cluster.SetUtilityDesiredVersions(map[string]*model.HelmUtilityVersion{utilityName: version})
+
+	group, err := newUtilityGroupHandle(kopsClient, provisioner, cluster, awsClient, logger)
+	if err != nil {
+		return errors.Wrap(err, "couldn't create new utility group handle")
+	}
+
+	utility := group.getUtility(utilityName)
+	if utility == nil {
+		return errors.Errorf("unknown utility %s", utilityName)
+	}
+
+	err = utility.CreateOrUpgrade()
+	if err != nil {
+		// Don't leave the failed version behind for the next provisioning.
+		cluster.SetUtilityDesiredVersions(map[string]*model.HelmUtilityVersion{utilityName: nil})
+		return err
+	}
+
+	return cluster.SetUtilityActualVersion(utilityName, utility.ActualVersion())
+}
+
+// previewUtilityUpgrade returns the diff between the manifest of the deployed
+// utility release and the manifest of the upgraded release.
+func previewUtilityUpgrade(configPath string, release utilityRelease, upgrade *model.UtilityUpgrade, getContext valuesContextFunc, logger logrus.FieldLogger) (string, error) {
+	var currentManifest string
+	if !upgrade.FromVersion.IsEmpty() {
+		var err error
+		currentManifest, err = getHelmReleaseManifest(configPath, release.name, release.namespace, logger)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	upgradedManifest, err := renderHelmUpgrade(configPath, release.name, release.chart, release.namespace, upgrade.ToVersion, release.valuesOverride, getContext, logger)
+	if err != nil {
+		return "", err
+	}
+
+	fromName := fmt.Sprintf("%s (not installed)", release.name)
+	if !upgrade.FromVersion.IsEmpty() {
+		fromName = fmt.Sprintf("%s %s", release.name, upgrade.FromVersion.Version())
+	}
+
+	return diffManifests(currentManifest, upgradedManifest, fromName, fmt.Sprintf("%s %s", release.name, upgrade.ToVersion.Version()))
+}
+
+// diffManifests returns the unified diff of two rendered manifests.
+func diffManifests(current, upgraded, currentName, upgradedName string) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(current),
+		B:        difflib.SplitLines(upgraded),
+		FromFile: currentName,
+		ToFile:   upgradedName,
+		Context:  3,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to diff manifests")
+	}
+
+	return diff, nil
+}
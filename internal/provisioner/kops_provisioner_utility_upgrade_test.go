@@ -0,0 +1,51 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+func TestUtilityReleases(t *testing.T) {
+	for utilityName := range model.DefaultUtilityVersions {
+		_, found := utilityReleases[utilityName]
+		assert.True(t, found, "missing Helm release of utility %s", utilityName)
+	}
+}
+
+func TestDiffManifests(t *testing.T) {
+	t.Run("changes", func(t *testing.T) {
+		diff, err := diffManifests(
+			"kind: Deployment\nimage: nginx:1.0\nreplicas: 2\n",
+			"kind: Deployment\nimage: nginx:1.1\nreplicas: 2\n",
+			"nginx 4.0.18", "nginx 4.0.19",
+		)
+		require.NoError(t, err)
+		assert.Contains(t, diff, "--- nginx 4.0.18")
+		assert.Contains(t, diff, "+++ nginx 4.0.19")
+		assert.Contains(t, diff, "-image: nginx:1.0\n")
+		assert.Contains(t, diff, "+image: nginx:1.1\n")
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		diff, err := diffManifests("kind: Deployment\n", "kind: Deployment\n", "nginx 4.0.18", "nginx 4.0.18")
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+}
+
+func TestUpgradeClusterUtilityNotRequested(t *testing.T) {
+	provisioner := &KopsProvisioner{logger: testlib.MakeLogger(t)}
+	cluster := &model.Cluster{ID: model.NewID()}
+
+	err := provisioner.UpgradeClusterUtility(cluster, model.NginxCanonicalName, nil)
+	require.EqualError(t, err, "no upgrade requested for utility nginx")
+}
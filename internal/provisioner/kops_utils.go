@@ -10,14 +10,17 @@ import (
 	"io/ioutil"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
 	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
 	"github.com/mattermost/mattermost-cloud/internal/tools/terraform"
 	"github.com/mattermost/mattermost-cloud/k8s"
 	"github.com/mattermost/mattermost-cloud/model"
 	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
 	log "github.com/sirupsen/logrus"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -209,6 +212,83 @@ func updateKopsInstanceGroupAMIs(kops *kops.Cmd, kopsMetadata *model.KopsMetadat
 	return nil
 }
 
+// validateKopsInstanceTypes ensures the instance types of the change request
+// are offered by AWS.
+func validateKopsInstanceTypes(changeRequest *model.KopsMetadataRequestedState, awsClient aws.AWS, logger log.FieldLogger) error {
+	instanceTypes := []string{changeRequest.MasterInstanceType, changeRequest.NodeInstanceType}
+	for _, nodeGroup := range changeRequest.AdditionalNodeGroups {
+		instanceTypes = append(instanceTypes, nodeGroup.NodeInstanceType)
+		if nodeGroup.MixedInstancesPolicy != nil {
+			instanceTypes = append(instanceTypes, nodeGroup.MixedInstancesPolicy.Instances...)
+		}
+	}
+
+	for _, instanceType := range instanceTypes {
+		if len(instanceType) == 0 {
+			continue
+		}
+		valid, err := awsClient.IsValidInstanceType(instanceType, logger)
+		if err != nil {
+			return errors.Wrapf(err, "error checking the AWS instance type %s", instanceType)
+		}
+		if !valid {
+			return errors.Errorf("invalid AWS instance type %s", instanceType)
+		}
+	}
+
+	return nil
+}
+
+// createKopsAdditionalNodeGroups creates a kops instance group for each of the
+// additional node groups of the change request.
+func createKopsAdditionalNodeGroups(kops *kops.Cmd, kopsMetadata *model.KopsMetadata, zones []string, logger log.FieldLogger) error {
+	for igName, nodeGroup := range kopsMetadata.ChangeRequest.AdditionalNodeGroups {
+		logger.Infof("Creating instance group %s with %d %s nodes", igName, nodeGroup.NodeMinCount, nodeGroup.NodeInstanceType)
+
+		err := kops.CreateInstanceGroup(kopsMetadata.Name, igName, zones)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create instance group %s", igName)
+		}
+
+		igManifest, err := kops.GetInstanceGroupYAML(kopsMetadata.Name, igName)
+		if err != nil {
+			return errors.Wrap(err, "failed to get YAML output for instance group")
+		}
+		igManifest, err = grossKopsReplaceSize(
+			igManifest,
+			nodeGroup.NodeInstanceType,
+			fmt.Sprintf("%d", nodeGroup.NodeMinCount),
+			fmt.Sprintf("%d", nodeGroup.NodeMaxCount),
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to update instance group yaml file")
+		}
+		if len(kopsMetadata.ChangeRequest.AMI) != 0 && kopsMetadata.ChangeRequest.AMI != "latest" {
+			igManifest, err = grossKopsReplaceImage(igManifest, kopsMetadata.ChangeRequest.AMI)
+			if err != nil {
+				return errors.Wrap(err, "failed to replace image value in YAML")
+			}
+		}
+
+		igManifest, err = setKopsInstanceGroupScheduling(igManifest, nodeGroup)
+		if err != nil {
+			return errors.Wrap(err, "failed to set scheduling values in YAML")
+		}
+
+		igFilename := fmt.Sprintf("%s-ig.yaml", igName)
+		err = ioutil.WriteFile(path.Join(kops.GetTempDir(), igFilename), []byte(igManifest), 0600)
+		if err != nil {
+			return errors.Wrap(err, "failed to write instance group yaml file")
+		}
+		_, err = kops.Replace(igFilename)
+		if err != nil {
+			return errors.Wrapf(err, "failed to replace instance group %s", igName)
+		}
+	}
+
+	return nil
+}
+
 // grossKopsReplaceSize is a manual find-and-replace flow for updating a raw
 // kops instance group YAML manifest with new sizing values.
 // TODO: remove once new `kops set instancegroup` functionality is available.
@@ -272,3 +352,141 @@ func grossKopsReplaceImage(input, image string) (string, error) {
 
 	return input, nil
 }
+
+// setKopsInstanceGroupScheduling sets the node labels, taints, spot max price
+// and mixed instances policy of a worker node group in a raw kops instance
+// group YAML manifest. Node groups using spot instances are labeled as spot
+// workers. The manifest is returned unchanged if the node group sets none of
+// them.
+//
+// Example Manifest:
+//
+// apiVersion: kops.k8s.io/v1alpha2
+// kind: InstanceGroup
+// spec:
+//   maxPrice: "0.10"
+//   mixedInstancesPolicy:
+//     instances:
+//     - m5.large
+//     - m5a.large
+//     onDemandAboveBase: 0
+//   nodeLabels:
+//     node-role.kubernetes.io/spot-worker: "true"
+//     workload: search
+//   taints:
+//   - dedicated=search:NoSchedule
+func setKopsInstanceGroupScheduling(input string, nodeGroup model.KopsInstanceGroupMetadata) (string, error) {
+	nodeLabels := make(map[string]string, len(nodeGroup.Labels)+1)
+	for key, value := range nodeGroup.Labels {
+		nodeLabels[key] = value
+	}
+	if nodeGroup.IsSpot() {
+		nodeLabels[model.SpotWorkerNodeLabel] = "true"
+	}
+
+	if len(nodeLabels) == 0 && len(nodeGroup.Taints) == 0 &&
+		len(nodeGroup.MaxPrice) == 0 && nodeGroup.MixedInstancesPolicy == nil {
+		return input, nil
+	}
+
+	var manifest yaml.MapSlice
+	err := yaml.Unmarshal([]byte(input), &manifest)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to unmarshal instance group YAML")
+	}
+
+	specIndex := -1
+	for i, item := range manifest {
+		if item.Key == "spec" {
+			specIndex = i
+			break
+		}
+	}
+	if specIndex == -1 {
+		return "", errors.New("expected to find instance group spec, but found none")
+	}
+	spec, ok := manifest[specIndex].Value.(yaml.MapSlice)
+	if !ok {
+		return "", errors.New("instance group spec has unexpected format")
+	}
+
+	if len(nodeLabels) != 0 {
+		keys := make([]string, 0, len(nodeLabels))
+		for key := range nodeLabels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		// Labels set by kops, like the instance group label, are kept.
+		var labels yaml.MapSlice
+		for _, item := range spec {
+			if item.Key == "nodeLabels" {
+				labels, _ = item.Value.(yaml.MapSlice)
+			}
+		}
+		for _, key := range keys {
+			labels = setMapSliceValue(labels, key, nodeLabels[key])
+		}
+		spec = setMapSliceValue(spec, "nodeLabels", labels)
+	}
+	if len(nodeGroup.Taints) != 0 {
+		spec = setMapSliceValue(spec, "taints", nodeGroup.Taints)
+	}
+	if len(nodeGroup.MaxPrice) != 0 {
+		spec = setMapSliceValue(spec, "maxPrice", nodeGroup.MaxPrice)
+	}
+	if nodeGroup.MixedInstancesPolicy != nil {
+		spec = setMapSliceValue(spec, "mixedInstancesPolicy", kopsMixedInstancesPolicy(nodeGroup))
+	}
+	manifest[specIndex].Value = spec
+
+	output, err := yaml.Marshal(manifest)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal instance group YAML")
+	}
+
+	return string(output), nil
+}
+
+// kopsMixedInstancesPolicy returns the kops mixed instances policy of a node
+// group. The instance type of the node group is always part of the policy.
+func kopsMixedInstancesPolicy(nodeGroup model.KopsInstanceGroupMetadata) yaml.MapSlice {
+	policy := nodeGroup.MixedInstancesPolicy
+
+	var instances []string
+	seen := make(map[string]struct{})
+	for _, instanceType := range append([]string{nodeGroup.NodeInstanceType}, policy.Instances...) {
+		if _, ok := seen[instanceType]; ok || len(instanceType) == 0 {
+			continue
+		}
+		seen[instanceType] = struct{}{}
+		instances = append(instances, instanceType)
+	}
+
+	var mixedInstancesPolicy yaml.MapSlice
+	if len(instances) != 0 {
+		mixedInstancesPolicy = append(mixedInstancesPolicy, yaml.MapItem{Key: "instances", Value: instances})
+	}
+	if policy.OnDemandBase != nil {
+		mixedInstancesPolicy = append(mixedInstancesPolicy, yaml.MapItem{Key: "onDemandBase", Value: *policy.OnDemandBase})
+	}
+	if policy.OnDemandAboveBase != nil {
+		mixedInstancesPolicy = append(mixedInstancesPolicy, yaml.MapItem{Key: "onDemandAboveBase", Value: *policy.OnDemandAboveBase})
+	}
+	if len(policy.SpotAllocationStrategy) != 0 {
+		mixedInstancesPolicy = append(mixedInstancesPolicy, yaml.MapItem{Key: "spotAllocationStrategy", Value: policy.SpotAllocationStrategy})
+	}
+
+	return mixedInstancesPolicy
+}
+
+func setMapSliceValue(mapSlice yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i, item := range mapSlice {
+		if item.Key == key {
+			mapSlice[i].Value = value
+			return mapSlice
+		}
+	}
+
+	return append(mapSlice, yaml.MapItem{Key: key, Value: value})
+}
@@ -7,7 +7,9 @@ package provisioner
 import (
 	"testing"
 
+	"github.com/mattermost/mattermost-cloud/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func newDefaultTestManifest() string {
@@ -371,3 +373,95 @@ spec:
 		assert.Empty(t, replaced)
 	})
 }
+
+func TestSetKopsInstanceGroupScheduling(t *testing.T) {
+	testManifest := `apiVersion: kops.k8s.io/v1alpha2
+kind: InstanceGroup
+metadata:
+  name: memory
+spec:
+  image: kope.io/k8s-1.15-debian-stretch-amd64-hvm-ebs-2020-01-17
+  machineType: r5.xlarge
+  maxSize: 2
+  minSize: 2
+  nodeLabels:
+    kops.k8s.io/instancegroup: memory
+  role: Node
+`
+
+	t.Run("no scheduling values", func(t *testing.T) {
+		replaced, err := setKopsInstanceGroupScheduling(testManifest, model.KopsInstanceGroupMetadata{NodeInstanceType: "r5.xlarge"})
+		require.NoError(t, err)
+		assert.Equal(t, testManifest, replaced)
+	})
+
+	t.Run("labels, taints and max price", func(t *testing.T) {
+		expectedManifest := `apiVersion: kops.k8s.io/v1alpha2
+kind: InstanceGroup
+metadata:
+  name: memory
+spec:
+  image: kope.io/k8s-1.15-debian-stretch-amd64-hvm-ebs-2020-01-17
+  machineType: r5.xlarge
+  maxSize: 2
+  minSize: 2
+  nodeLabels:
+    kops.k8s.io/instancegroup: memory
+    lifecycle: spot
+    node-role.kubernetes.io/spot-worker: "true"
+    workload: memory
+  role: Node
+  taints:
+  - dedicated=memory:NoSchedule
+  maxPrice: "0.10"
+`
+		replaced, err := setKopsInstanceGroupScheduling(testManifest, model.KopsInstanceGroupMetadata{
+			Labels:   map[string]string{"workload": "memory", "lifecycle": "spot"},
+			Taints:   []string{"dedicated=memory:NoSchedule"},
+			MaxPrice: "0.10",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expectedManifest, replaced)
+	})
+
+	t.Run("mixed instances policy", func(t *testing.T) {
+		expectedManifest := `apiVersion: kops.k8s.io/v1alpha2
+kind: InstanceGroup
+metadata:
+  name: memory
+spec:
+  image: kope.io/k8s-1.15-debian-stretch-amd64-hvm-ebs-2020-01-17
+  machineType: r5.xlarge
+  maxSize: 2
+  minSize: 2
+  nodeLabels:
+    kops.k8s.io/instancegroup: memory
+    node-role.kubernetes.io/spot-worker: "true"
+  role: Node
+  mixedInstancesPolicy:
+    instances:
+    - r5.xlarge
+    - r5a.xlarge
+    onDemandBase: 1
+    onDemandAboveBase: 0
+    spotAllocationStrategy: capacity-optimized
+`
+		onDemandBase, onDemandAboveBase := int64(1), int64(0)
+		replaced, err := setKopsInstanceGroupScheduling(testManifest, model.KopsInstanceGroupMetadata{
+			NodeInstanceType: "r5.xlarge",
+			MixedInstancesPolicy: &model.KopsMixedInstancesPolicy{
+				Instances:              []string{"r5a.xlarge", "r5.xlarge"},
+				OnDemandBase:           &onDemandBase,
+				OnDemandAboveBase:      &onDemandAboveBase,
+				SpotAllocationStrategy: model.SpotAllocationStrategyCapacityOptimized,
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, expectedManifest, replaced)
+	})
+
+	t.Run("missing spec", func(t *testing.T) {
+		_, err := setKopsInstanceGroupScheduling("kind: InstanceGroup\n", model.KopsInstanceGroupMetadata{MaxPrice: "0.10"})
+		require.Error(t, err)
+	})
+}
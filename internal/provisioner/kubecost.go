@@ -186,6 +186,7 @@ func (k *kubecost) NewHelmDeployment(kubecostDNS string) *helmDeployment {
 		namespace:           "kubecost",
 		kopsProvisioner:     k.provisioner,
 		kops:                k.kops,
+		awsClient:           k.awsClient,
 		setArgument:         helmValueArguments,
 		logger:              k.logger,
 		desiredVersion:      k.desiredVersion,
@@ -134,6 +134,7 @@ func (n *nginx) NewHelmDeployment() (*helmDeployment, error) {
 		cluster:         n.cluster,
 		kopsProvisioner: n.provisioner,
 		kops:            n.kops,
+		awsClient:       n.awsClient,
 		logger:          n.logger,
 	}, nil
 }
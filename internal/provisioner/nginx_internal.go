@@ -129,6 +129,7 @@ func (n *nginxInternal) NewHelmDeployment() (*helmDeployment, error) {
 		cluster:         n.cluster,
 		kopsProvisioner: n.provisioner,
 		kops:            n.kops,
+		awsClient:       n.awsClient,
 		logger:          n.logger,
 	}, nil
 }
@@ -7,6 +7,7 @@ package provisioner
 import (
 	"strings"
 
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
 	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
 	"github.com/mattermost/mattermost-cloud/model"
 	"github.com/pkg/errors"
@@ -16,12 +17,14 @@ import (
 type nodeProblemDetector struct {
 	provisioner    *KopsProvisioner
 	kops           *kops.Cmd
+	awsClient      aws.AWS
+	cluster        *model.Cluster
 	logger         log.FieldLogger
 	desiredVersion *model.HelmUtilityVersion
 	actualVersion  *model.HelmUtilityVersion
 }
 
-func newNodeProblemDetectorHandle(desiredVersion *model.HelmUtilityVersion, cluster *model.Cluster, provisioner *KopsProvisioner, kops *kops.Cmd, logger log.FieldLogger) (*nodeProblemDetector, error) {
+func newNodeProblemDetectorHandle(desiredVersion *model.HelmUtilityVersion, cluster *model.Cluster, provisioner *KopsProvisioner, awsClient aws.AWS, kops *kops.Cmd, logger log.FieldLogger) (*nodeProblemDetector, error) {
 	if logger == nil {
 		return nil, errors.New("cannot instantiate NodeProblemDetector handle with nil logger")
 	}
@@ -37,6 +40,8 @@ func newNodeProblemDetectorHandle(desiredVersion *model.HelmUtilityVersion, clus
 	return &nodeProblemDetector{
 		provisioner:    provisioner,
 		kops:           kops,
+		awsClient:      awsClient,
+		cluster:        cluster,
 		logger:         logger.WithField("cluster-utility", model.NodeProblemDetectorCanonicalName),
 		desiredVersion: desiredVersion,
 		actualVersion:  cluster.UtilityMetadata.ActualVersions.NodeProblemDetector,
@@ -89,6 +94,7 @@ func (f *nodeProblemDetector) NewHelmDeployment(logger log.FieldLogger) *helmDep
 		namespace:           "node-problem-detector",
 		kopsProvisioner:     f.provisioner,
 		kops:                f.kops,
+		awsClient:           f.awsClient,
 		logger:              logger,
 		desiredVersion:      f.desiredVersion,
 	}
@@ -7,6 +7,7 @@ package provisioner
 import (
 	"testing"
 
+	mocks "github.com/mattermost/mattermost-cloud/internal/mocks/aws-tools"
 	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
 	"github.com/mattermost/mattermost-cloud/model"
 
@@ -21,12 +22,13 @@ func TestNewHelmDeploymentWithDefaultConfigurationNodeProblemDetector(t *testing
 
 	provisioner := &KopsProvisioner{}
 	logger := log.New()
+	awsClient := mocks.NewMockAWS(ctrl)
 	kops := &kops.Cmd{}
 	nodeProblemDetector, err := newNodeProblemDetectorHandle(&model.HelmUtilityVersion{Chart: "2.0.5"}, &model.Cluster{
 		UtilityMetadata: &model.UtilityMetadata{
 			ActualVersions: model.UtilityGroupVersions{},
 		},
-	}, provisioner, kops, logger)
+	}, provisioner, awsClient, kops, logger)
 	require.NoError(t, err, "should not error when creating new node-problem-detector handler")
 	require.NotNil(t, nodeProblemDetector, "node-problem-detector should not be nil")
 
@@ -121,6 +121,7 @@ func (p *pgbouncer) NewHelmDeployment() *helmDeployment {
 		namespace:           "pgbouncer",
 		kopsProvisioner:     p.provisioner,
 		kops:                p.kops,
+		awsClient:           p.awsClient,
 		logger:              p.logger,
 		desiredVersion:      p.desiredVersion,
 	}
@@ -210,8 +211,9 @@ server_reset_query_always = 1
 [databases]
 `
 
-func generatePGBouncerIni(vpcID string, store model.ClusterUtilityDatabaseStoreInterface) (string, error) {
+func generatePGBouncerIni(vpcID string, store model.ClusterUtilityDatabaseStoreInterface, logger log.FieldLogger) (string, error) {
 	ini := generatePGBouncerBaseIni()
+	var users string
 
 	multitenantDatabases, err := store.GetMultitenantDatabases(&model.MultitenantDatabaseFilter{
 		DatabaseType:          model.DatabaseEngineTypePostgresProxy,
@@ -248,12 +250,65 @@ func generatePGBouncerIni(vpcID string, store model.ClusterUtilityDatabaseStoreI
 				multitenantDatabase.ReaderEndpoint,
 				aws.DefaultPGBouncerAuthUsername,
 			)
+
+			userEntries, err := generatePGBouncerUserEntries(logicalDatabase, multitenantDatabase, store, logger)
+			if err != nil {
+				return "", errors.Wrapf(err, "failed to generate user entries for logical database %s", logicalDatabase.Name)
+			}
+			users += userEntries
 		}
 	}
 
+	if len(users) != 0 {
+		ini = fmt.Sprintf("%s\n[users]\n%s", ini, users)
+	}
+
 	return ini, nil
 }
 
+// generatePGBouncerUserEntries returns the user entries overriding the pool
+// settings of the installations on a logical database. Overrides which do not
+// fit the logical database are skipped so that the default pool settings are
+// used instead.
+func generatePGBouncerUserEntries(logicalDatabase *model.LogicalDatabase, multitenantDatabase *model.MultitenantDatabase, store model.ClusterUtilityDatabaseStoreInterface, logger log.FieldLogger) (string, error) {
+	databaseSchemas, err := store.GetDatabaseSchemas(&model.DatabaseSchemaFilter{
+		LogicalDatabaseID: logicalDatabase.ID,
+		Paging:            model.AllPagesNotDeleted(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get database schemas")
+	}
+
+	var users string
+	for _, databaseSchema := range databaseSchemas {
+		installation, err := store.GetInstallation(databaseSchema.InstallationID, false, false)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get installation %s", databaseSchema.InstallationID)
+		}
+		if installation == nil || installation.DatabasePoolConfig.IsEmpty() {
+			continue
+		}
+
+		poolConfig := installation.DatabasePoolConfig
+		err = poolConfig.ValidateForLogicalDatabase(multitenantDatabase.MaxInstallationsPerLogicalDatabase)
+		if err != nil {
+			logger.WithError(err).Warnf("Ignoring database pool config of installation %s", installation.ID)
+			continue
+		}
+
+		var settings []string
+		if poolConfig.PoolSize != 0 {
+			settings = append(settings, fmt.Sprintf("pool_size=%d", poolConfig.PoolSize))
+		}
+		if poolConfig.MaxConnections != 0 {
+			settings = append(settings, fmt.Sprintf("max_user_connections=%d", poolConfig.MaxConnections))
+		}
+		users = fmt.Sprintf("%s%s = %s\n", users, aws.MattermostPGBouncerDatabaseUsername(installation.ID), strings.Join(settings, " "))
+	}
+
+	return users, nil
+}
+
 func generatePGBouncerBaseIni() string {
 	return fmt.Sprintf(baseIni, model.GetMinPoolSize(), model.GetDefaultPoolSize(), model.GetMaxDatabaseConnectionsPerPool())
 }
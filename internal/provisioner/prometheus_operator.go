@@ -192,6 +192,7 @@ func (p *prometheusOperator) NewHelmDeployment(prometheusDNS string) *helmDeploy
 		chartDeploymentName: "prometheus-operator",
 		chartName:           "prometheus-community/kube-prometheus-stack",
 		kops:                p.kops,
+		awsClient:           p.awsClient,
 		kopsProvisioner:     p.provisioner,
 		logger:              p.logger,
 		namespace:           "prometheus",
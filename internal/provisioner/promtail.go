@@ -19,6 +19,7 @@ type promtail struct {
 	environment    string
 	provisioner    *KopsProvisioner
 	kops           *kops.Cmd
+	awsClient      aws.AWS
 	cluster        *model.Cluster
 	logger         log.FieldLogger
 	desiredVersion *model.HelmUtilityVersion
@@ -42,6 +43,7 @@ func newPromtailHandle(cluster *model.Cluster, desiredVersion *model.HelmUtility
 		environment:    awsClient.GetCloudEnvironmentName(),
 		provisioner:    provisioner,
 		kops:           kops,
+		awsClient:      awsClient,
 		cluster:        cluster,
 		logger:         logger.WithField("cluster-utility", model.PromtailCanonicalName),
 		desiredVersion: desiredVersion,
@@ -108,6 +110,7 @@ func (p *promtail) NewHelmDeployment() *helmDeployment {
 		namespace:           "promtail",
 		kopsProvisioner:     p.provisioner,
 		kops:                p.kops,
+		awsClient:           p.awsClient,
 		logger:              p.logger,
 		setArgument:         fmt.Sprintf("extraArgs={-client.external-labels=cluster=%s}", p.cluster.ID),
 		desiredVersion:      p.desiredVersion,
@@ -0,0 +1,222 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/internal/tools/kops"
+	"github.com/mattermost/mattermost-cloud/k8s"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// registeredUtility is a utility of the utility registry, deployed from its
+// declarative definition.
+type registeredUtility struct {
+	definition     *model.UtilityDefinition
+	provisioner    *KopsProvisioner
+	kops           *kops.Cmd
+	awsClient      aws.AWS
+	logger         log.FieldLogger
+	cluster        *model.Cluster
+	actualVersion  *model.HelmUtilityVersion
+	desiredVersion *model.HelmUtilityVersion
+}
+
+func newRegisteredUtilityHandle(definition *model.UtilityDefinition, version *model.HelmUtilityVersion, cluster *model.Cluster, provisioner *KopsProvisioner, awsClient aws.AWS, kops *kops.Cmd, logger log.FieldLogger) (*registeredUtility, error) {
+	if logger == nil {
+		return nil, errors.New("cannot instantiate registered utility handle with nil logger")
+	}
+
+	if definition == nil {
+		return nil, errors.New("cannot create a connection to a registered utility if the definition provided is nil")
+	}
+
+	if cluster == nil {
+		return nil, errors.Errorf("cannot create a connection to %s if the cluster provided is nil", definition.Name)
+	}
+
+	if provisioner == nil {
+		return nil, errors.Errorf("cannot create a connection to %s if the provisioner provided is nil", definition.Name)
+	}
+
+	if kops == nil {
+		return nil, errors.Errorf("cannot create a connection to %s if the Kops command provided is nil", definition.Name)
+	}
+
+	return &registeredUtility{
+		definition:     definition,
+		provisioner:    provisioner,
+		kops:           kops,
+		awsClient:      awsClient,
+		cluster:        cluster,
+		logger:         logger.WithField("cluster-utility", definition.Name),
+		desiredVersion: version,
+		actualVersion:  cluster.ActualUtilityVersion(definition.Name),
+	}, nil
+}
+
+func (u *registeredUtility) CreateOrUpgrade() error {
+	h := u.NewHelmDeployment()
+
+	err := h.Update()
+	if err != nil {
+		return err
+	}
+
+	if u.definition.Readiness != nil {
+		err = u.waitForReadiness()
+		if err != nil {
+			return errors.Wrapf(err, "%s failed its readiness check", u.definition.Name)
+		}
+	}
+
+	actualVersion, err := h.Version()
+	if err != nil {
+		return err
+	}
+
+	u.actualVersion = actualVersion
+	return nil
+}
+
+func (u *registeredUtility) waitForReadiness() error {
+	k8sClient, err := k8s.NewFromFile(u.kops.GetKubeConfigPath(), u.logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to set up the k8s client")
+	}
+
+	check := u.definition.Readiness
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(check.Timeout())*time.Second)
+	defer cancel()
+
+	return waitForUtilityReadiness(ctx, k8sClient.Clientset, u.definition.Namespace, check, 5*time.Second)
+}
+
+func (u *registeredUtility) DesiredVersion() *model.HelmUtilityVersion {
+	return u.desiredVersion
+}
+
+func (u *registeredUtility) ActualVersion() *model.HelmUtilityVersion {
+	if u.actualVersion == nil {
+		return nil
+	}
+
+	return &model.HelmUtilityVersion{
+		Chart:      strings.TrimPrefix(u.actualVersion.Version(), path.Base(u.definition.Chart)+"-"),
+		ValuesPath: u.actualVersion.Values(),
+	}
+}
+
+func (u *registeredUtility) Destroy() error {
+	return nil
+}
+
+func (u *registeredUtility) ValuesPath() string {
+	if u.desiredVersion == nil {
+		return ""
+	}
+	return u.desiredVersion.Values()
+}
+
+func (u *registeredUtility) Migrate() error {
+	return nil
+}
+
+func (u *registeredUtility) NewHelmDeployment() *helmDeployment {
+	return &helmDeployment{
+		chartDeploymentName: u.definition.Release(),
+		chartName:           u.definition.Chart,
+		namespace:           u.definition.Namespace,
+		valuesOverride:      u.definition.ValuesTemplate,
+		desiredVersion:      u.desiredVersion,
+
+		cluster:         u.cluster,
+		kopsProvisioner: u.provisioner,
+		kops:            u.kops,
+		awsClient:       u.awsClient,
+		logger:          u.logger,
+	}
+}
+
+func (u *registeredUtility) Name() string {
+	return u.definition.Name
+}
+
+// waitForUtilityReadiness waits until the workload of the readiness check is
+// ready or the context is done.
+func waitForUtilityReadiness(ctx context.Context, clientset kubernetes.Interface, namespace string, check *model.UtilityReadinessCheck, interval time.Duration) error {
+	for {
+		ready, err := utilityWorkloadReady(ctx, clientset, namespace, check)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errors.Errorf("timed out waiting for %s %s/%s to be ready", check.Kind, namespace, check.Name)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// utilityWorkloadReady returns true if all pods of the workload of the
+// readiness check are ready. A workload which doesn't exist yet is not ready.
+func utilityWorkloadReady(ctx context.Context, clientset kubernetes.Interface, namespace string, check *model.UtilityReadinessCheck) (bool, error) {
+	var err error
+	switch check.Kind {
+	case model.UtilityReadinessKindDeployment:
+		deployment, getErr := clientset.AppsV1().Deployments(namespace).Get(ctx, check.Name, metav1.GetOptions{})
+		if getErr == nil {
+			desired := int32(1)
+			if deployment.Spec.Replicas != nil {
+				desired = *deployment.Spec.Replicas
+			}
+			return deployment.Status.ObservedGeneration >= deployment.Generation &&
+				deployment.Status.UpdatedReplicas >= desired &&
+				deployment.Status.ReadyReplicas >= desired, nil
+		}
+		err = getErr
+	case model.UtilityReadinessKindDaemonSet:
+		daemonSet, getErr := clientset.AppsV1().DaemonSets(namespace).Get(ctx, check.Name, metav1.GetOptions{})
+		if getErr == nil {
+			return daemonSet.Status.ObservedGeneration >= daemonSet.Generation &&
+				daemonSet.Status.UpdatedNumberScheduled >= daemonSet.Status.DesiredNumberScheduled &&
+				daemonSet.Status.NumberReady >= daemonSet.Status.DesiredNumberScheduled, nil
+		}
+		err = getErr
+	case model.UtilityReadinessKindStatefulSet:
+		statefulSet, getErr := clientset.AppsV1().StatefulSets(namespace).Get(ctx, check.Name, metav1.GetOptions{})
+		if getErr == nil {
+			desired := int32(1)
+			if statefulSet.Spec.Replicas != nil {
+				desired = *statefulSet.Spec.Replicas
+			}
+			return statefulSet.Status.ObservedGeneration >= statefulSet.Generation &&
+				statefulSet.Status.ReadyReplicas >= desired, nil
+		}
+		err = getErr
+	default:
+		return false, errors.Errorf("unsupported readiness check kind %s", check.Kind)
+	}
+
+	if k8sErrors.IsNotFound(err) {
+		return false, nil
+	}
+
+	return false, errors.Wrapf(err, "failed to get %s %s/%s", check.Kind, namespace, check.Name)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestUtilityWorkloadReady(t *testing.T) {
+	replicas := int32(2)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "cert-manager", Namespace: "cert-manager", Generation: 2},
+		Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, UpdatedReplicas: 2, ReadyReplicas: 1},
+	}
+	daemonSet := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "cert-manager", Generation: 1},
+		Status:     appsv1.DaemonSetStatus{ObservedGeneration: 1, DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberReady: 3},
+	}
+	clientset := fake.NewSimpleClientset(deployment, daemonSet)
+	ctx := context.Background()
+
+	t.Run("deployment not ready", func(t *testing.T) {
+		ready, err := utilityWorkloadReady(ctx, clientset, "cert-manager", &model.UtilityReadinessCheck{Kind: model.UtilityReadinessKindDeployment, Name: "cert-manager"})
+		require.NoError(t, err)
+		assert.False(t, ready)
+	})
+
+	t.Run("deployment ready", func(t *testing.T) {
+		deployment.Status.ReadyReplicas = 2
+		_, err := clientset.AppsV1().Deployments("cert-manager").UpdateStatus(ctx, deployment, metav1.UpdateOptions{})
+		require.NoError(t, err)
+
+		ready, err := utilityWorkloadReady(ctx, clientset, "cert-manager", &model.UtilityReadinessCheck{Kind: model.UtilityReadinessKindDeployment, Name: "cert-manager"})
+		require.NoError(t, err)
+		assert.True(t, ready)
+	})
+
+	t.Run("daemonset ready", func(t *testing.T) {
+		ready, err := utilityWorkloadReady(ctx, clientset, "cert-manager", &model.UtilityReadinessCheck{Kind: model.UtilityReadinessKindDaemonSet, Name: "agent"})
+		require.NoError(t, err)
+		assert.True(t, ready)
+	})
+
+	t.Run("missing workload", func(t *testing.T) {
+		ready, err := utilityWorkloadReady(ctx, clientset, "cert-manager", &model.UtilityReadinessCheck{Kind: model.UtilityReadinessKindStatefulSet, Name: "missing"})
+		require.NoError(t, err)
+		assert.False(t, ready)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		err := waitForUtilityReadiness(ctx, clientset, "cert-manager", &model.UtilityReadinessCheck{Kind: model.UtilityReadinessKindStatefulSet, Name: "missing"}, 10*time.Millisecond)
+		assert.Error(t, err)
+	})
+}
+
+func TestRegisteredUtility(t *testing.T) {
+	definition := &model.UtilityDefinition{
+		Name:           "cert-manager",
+		Chart:          "jetstack/cert-manager",
+		Namespace:      "cert-manager",
+		ValuesTemplate: "installCRDs: true\n",
+	}
+	desiredVersion := &model.HelmUtilityVersion{Chart: "1.8.0"}
+	utility := &registeredUtility{
+		definition:     definition,
+		desiredVersion: desiredVersion,
+		actualVersion:  &model.HelmUtilityVersion{Chart: "cert-manager-1.7.0", ValuesPath: "values.yaml"},
+	}
+
+	assert.Equal(t, "cert-manager", utility.Name())
+	assert.Equal(t, &model.HelmUtilityVersion{Chart: "1.7.0", ValuesPath: "values.yaml"}, utility.ActualVersion())
+
+	deployment := utility.NewHelmDeployment()
+	assert.Equal(t, "cert-manager", deployment.chartDeploymentName)
+	assert.Equal(t, "jetstack/cert-manager", deployment.chartName)
+	assert.Equal(t, "cert-manager", deployment.namespace)
+	assert.Equal(t, "installCRDs: true\n", deployment.valuesOverride)
+	assert.Equal(t, desiredVersion, deployment.desiredVersion)
+}
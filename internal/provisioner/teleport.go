@@ -123,6 +123,7 @@ func (n *teleport) NewHelmDeployment() *helmDeployment {
 		setArgument:         fmt.Sprintf("kubeClusterName=%s", teleportClusterName),
 		kopsProvisioner:     n.provisioner,
 		kops:                n.kops,
+		awsClient:           n.awsClient,
 		logger:              n.logger,
 		desiredVersion:      n.desiredVersion,
 	}
@@ -176,6 +176,7 @@ func (t *thanos) NewHelmDeployment(thanosDNS, thanosDNSGRPC string) *helmDeploym
 		chartDeploymentName: "thanos",
 		chartName:           "bitnami/thanos",
 		kops:                t.kops,
+		awsClient:           t.awsClient,
 		kopsProvisioner:     t.provisioner,
 		logger:              t.logger,
 		namespace:           "prometheus",
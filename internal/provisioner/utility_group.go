@@ -134,15 +134,29 @@ func newUtilityGroupHandle(kops *kops.Cmd, provisioner *KopsProvisioner, cluster
 	}
 	nodeProblemDetector, err := newNodeProblemDetectorHandle(
 		cluster.DesiredUtilityVersion(model.NodeProblemDetectorCanonicalName),
-		cluster, provisioner, kops, logger)
+		cluster, provisioner, awsClient, kops, logger)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get handle for Node Problem Detector")
 	}
 
 	// the order of utilities here matters; the utilities are deployed
 	// in order to resolve dependencies between them
+	utilities := []Utility{nginx, nginxInternal, prometheusOperator, thanos, fluentbit, teleport, pgbouncer, promtail, kubecost, nodeProblemDetector}
+
+	// registered utilities are ordered by their dependencies and may
+	// depend on any of the builtin utilities
+	for _, definition := range model.RegisteredUtilities() {
+		registered, err := newRegisteredUtilityHandle(
+			definition, cluster.DesiredUtilityVersion(definition.Name),
+			cluster, provisioner, awsClient, kops, logger)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get handle for %s", definition.Name)
+		}
+		utilities = append(utilities, registered)
+	}
+
 	return &utilityGroup{
-		utilities:   []Utility{nginx, nginxInternal, prometheusOperator, thanos, fluentbit, teleport, pgbouncer, promtail, kubecost, nodeProblemDetector},
+		utilities:   utilities,
 		kops:        kops,
 		provisioner: provisioner,
 		cluster:     cluster,
@@ -174,10 +188,8 @@ func (group utilityGroup) DestroyUtilityGroup() error {
 	return nil
 }
 
-// ProvisionUtilityGroup reapplies the chart for the UtilityGroup. This will cause services to upgrade to a new version, if one is available.
-func (group utilityGroup) ProvisionUtilityGroup() error {
-	logger := group.provisioner.logger.WithField("utility-group", "UpgradeManifests")
-
+// addHelmRepos adds the Helm repos of all builtin and registered utilities.
+func addHelmRepos(logger log.FieldLogger) error {
 	logger.Info("Adding new Helm repos.")
 	for repoName, repoURL := range helmRepos {
 		err := helmRepoAdd(repoName, repoURL, logger)
@@ -185,6 +197,39 @@ func (group utilityGroup) ProvisionUtilityGroup() error {
 			return errors.Wrap(err, "unable to add helm repos")
 		}
 	}
+	for _, definition := range model.RegisteredUtilities() {
+		if _, builtin := helmRepos[definition.Repository.Name]; builtin {
+			continue
+		}
+		err := helmRepoAdd(definition.Repository.Name, definition.Repository.URL, logger)
+		if err != nil {
+			return errors.Wrapf(err, "unable to add helm repo of %s", definition.Name)
+		}
+	}
+
+	return nil
+}
+
+// getUtility returns the utility with the given canonical name, or nil if
+// the group has no such utility.
+func (group utilityGroup) getUtility(name string) Utility {
+	for _, utility := range group.utilities {
+		if utility.Name() == name {
+			return utility
+		}
+	}
+
+	return nil
+}
+
+// ProvisionUtilityGroup reapplies the chart for the UtilityGroup. This will cause services to upgrade to a new version, if one is available.
+func (group utilityGroup) ProvisionUtilityGroup() error {
+	logger := group.provisioner.logger.WithField("utility-group", "UpgradeManifests")
+
+	err := addHelmRepos(logger)
+	if err != nil {
+		return err
+	}
 
 	for _, utility := range group.utilities {
 		if utility.DesiredVersion().IsEmpty() {
@@ -0,0 +1,172 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// valuesContextFunc returns the cluster context utility values templates are
+// rendered with. It is only called when a template has to be rendered, since
+// building the context requires AWS calls.
+type valuesContextFunc func() (*model.UtilityValuesContext, error)
+
+// cachedValuesContext returns a valuesContextFunc which builds the context
+// at most once.
+func cachedValuesContext(build valuesContextFunc) valuesContextFunc {
+	var valuesContext *model.UtilityValuesContext
+	return func() (*model.UtilityValuesContext, error) {
+		if valuesContext != nil {
+			return valuesContext, nil
+		}
+		if build == nil {
+			return nil, errors.New("no cluster context available to render utility values templates")
+		}
+
+		var err error
+		valuesContext, err = build()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the cluster context of the utility values")
+		}
+
+		return valuesContext, nil
+	}
+}
+
+// prepareHelmValuesFiles returns the local values files to pass to Helm for
+// the given values path and values override. The values file is fetched from
+// its values source and rendered with the cluster context if it is a values
+// template. The values override is always rendered. The returned cleanup
+// function removes the temporary files.
+func prepareHelmValuesFiles(valuesPath, valuesOverride string, getContext valuesContextFunc, logger log.FieldLogger) ([]string, func(), error) {
+	var files []string
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+
+	// Utilities of the utility registry may only have inline values.
+	if valuesPath != "" || valuesOverride == "" {
+		if model.IsUtilityValuesTemplate(valuesPath) {
+			values, err := readValuesFile(valuesPath, logger)
+			if err != nil {
+				return nil, nil, err
+			}
+			renderedPath, err := writeRenderedValues(values, getContext, &cleanups)
+			if err != nil {
+				cleanup()
+				return nil, nil, errors.Wrapf(err, "failed to render values template %s", valuesPath)
+			}
+			files = append(files, renderedPath)
+		} else {
+			fetchedPath, fetchCleanup, err := fetchValuesFile(valuesPath, logger)
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "failed to get values file")
+			}
+			cleanups = append(cleanups, fetchCleanup)
+			files = append(files, fetchedPath)
+		}
+	}
+
+	if valuesOverride != "" {
+		overridePath, err := writeRenderedValues(valuesOverride, getContext, &cleanups)
+		if err != nil {
+			cleanup()
+			return nil, nil, errors.Wrap(err, "failed to render values override")
+		}
+		files = append(files, overridePath)
+	}
+
+	return files, cleanup, nil
+}
+
+// writeRenderedValues renders the values template to a temporary file and
+// registers its cleanup.
+func writeRenderedValues(values string, getContext valuesContextFunc, cleanups *[]func()) (string, error) {
+	rendered, err := renderValuesTemplate(values, getContext)
+	if err != nil {
+		return "", err
+	}
+
+	renderedPath, cleanup, err := writeTempValuesFile(rendered)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to write rendered values file")
+	}
+	*cleanups = append(*cleanups, cleanup)
+
+	return renderedPath, nil
+}
+
+// renderValuesTemplate renders the values template with the cluster context,
+// only building the context if the template references it.
+func renderValuesTemplate(values string, getContext valuesContextFunc) (string, error) {
+	if !model.HasUtilityValuesTemplateActions(values) {
+		return values, nil
+	}
+
+	valuesContext, err := getContext()
+	if err != nil {
+		return "", err
+	}
+
+	return model.RenderUtilityValues(values, valuesContext)
+}
+
+// RenderClusterUtilityValues returns the values the utility is deployed with
+// on the cluster, rendered with the given cluster context. The desired
+// version of the utility is used, falling back to its actual version. Local
+// values files outside of the utility values directory are not read, so that
+// no other file of the provisioning server is returned to API callers.
+func (provisioner *KopsProvisioner) RenderClusterUtilityValues(cluster *model.Cluster, utilityName string, valuesContext *model.UtilityValuesContext) (*model.ClusterUtilityValues, error) {
+	version := cluster.DesiredUtilityVersion(utilityName)
+	if version == nil {
+		version = cluster.ActualUtilityVersion(utilityName)
+	}
+
+	var valuesOverride string
+	if definition := model.GetRegisteredUtility(utilityName); definition != nil {
+		valuesOverride = definition.ValuesTemplate
+	}
+
+	getContext := func() (*model.UtilityValuesContext, error) { return valuesContext, nil }
+	values := &model.ClusterUtilityValues{
+		ClusterID: cluster.ID,
+		Utility:   utilityName,
+		Context:   valuesContext,
+	}
+
+	if version != nil && version.Values() != "" {
+		values.ValuesPath = version.Values()
+		values.Templated = model.IsUtilityValuesTemplate(values.ValuesPath)
+	}
+
+	if values.ValuesPath != "" && model.IsUtilityValuesPathAllowed(values.ValuesPath) {
+		var err error
+		values.Values, err = readValuesFile(values.ValuesPath, provisioner.logger)
+		if err != nil {
+			return nil, err
+		}
+		if values.Templated {
+			values.Values, err = renderValuesTemplate(values.Values, getContext)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to render values template %s", values.ValuesPath)
+			}
+		}
+	}
+
+	if valuesOverride != "" {
+		var err error
+		values.ValuesOverride, err = renderValuesTemplate(valuesOverride, getContext)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render values override")
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// valuesSource reads utility values files from the locations of a values
+// path scheme.
+type valuesSource interface {
+	Read(location *url.URL, logger log.FieldLogger) ([]byte, error)
+}
+
+// valuesSources are the values sources by values path scheme.
+var valuesSources = map[string]valuesSource{
+	"":                                localValuesSource{},
+	model.UtilityValuesSchemeFile:     localValuesSource{},
+	model.UtilityValuesSchemeHTTP:     httpValuesSource{},
+	model.UtilityValuesSchemeHTTPS:    httpValuesSource{},
+	model.UtilityValuesSchemeGitHTTPS: gitValuesSource{},
+	model.UtilityValuesSchemeGitSSH:   gitValuesSource{},
+	model.UtilityValuesSchemeS3:       &s3ValuesSource{newClient: newS3ValuesClient},
+}
+
+// fetchValuesFile returns the path of a local copy of the values file at the
+// given values path. Local values files without checksum are used in place,
+// others are fetched to a temporary file which is removed by the returned
+// cleanup function.
+func fetchValuesFile(valuesPath string, logger log.FieldLogger) (string, func(), error) {
+	noCleanup := func() {}
+	if len(valuesPath) == 0 {
+		return "", noCleanup, nil
+	}
+
+	location, checksum, err := model.SplitUtilityValuesChecksum(valuesPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, local := valuesSources[location.Scheme].(localValuesSource); local && len(checksum) == 0 {
+		return location.Path, noCleanup, nil
+	}
+
+	values, err := readValuesFile(valuesPath, logger)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fetchedPath, cleanup, err := writeTempValuesFile(values)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to write values file to disk for Helm to read")
+	}
+
+	return fetchedPath, cleanup, nil
+}
+
+// readValuesFile reads the values file at the given values path from its
+// values source, verifying its checksum if the path has one.
+func readValuesFile(valuesPath string, logger log.FieldLogger) (string, error) {
+	location, checksum, err := model.SplitUtilityValuesChecksum(valuesPath)
+	if err != nil {
+		return "", err
+	}
+
+	source, found := valuesSources[location.Scheme]
+	if !found {
+		return "", errors.Errorf("unsupported values path scheme %q", location.Scheme)
+	}
+
+	data, err := source.Read(location, logger)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read values file %s", location.Redacted())
+	}
+
+	if len(checksum) != 0 {
+		err = model.VerifyUtilityValuesChecksum(checksum, data)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to verify values file %s", location.Redacted())
+		}
+	}
+
+	return string(data), nil
+}
+
+// localValuesSource reads values files from the local filesystem.
+type localValuesSource struct{}
+
+func (localValuesSource) Read(location *url.URL, logger log.FieldLogger) ([]byte, error) {
+	return ioutil.ReadFile(location.Path)
+}
+
+type gitlabValuesFileResponse struct {
+	Content string `json:"content"`
+}
+
+// httpValuesSource reads values files served over HTTP. Files of a Gitlab
+// instance are fetched using its API when a Gitlab token is configured.
+type httpValuesSource struct{}
+
+func (httpValuesSource) Read(location *url.URL, logger log.FieldLogger) ([]byte, error) {
+	gitlabKey := model.GetGitlabToken()
+	if gitlabKey == "" || !strings.HasPrefix(location.Host, "git") {
+		return httpGet(location.String())
+	}
+
+	gitlabLocation := *location
+	query := gitlabLocation.Query()
+	query.Set("private_token", gitlabKey)
+	gitlabLocation.RawQuery = query.Encode()
+
+	body, err := httpGet(gitlabLocation.String())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to request the values file from Gitlab")
+	}
+
+	valuesFile := new(gitlabValuesFileResponse)
+	err = json.Unmarshal(body, valuesFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal JSON in Gitlab response")
+	}
+
+	content, err := base64.StdEncoding.DecodeString(valuesFile.Content)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode base64-encoded YAML file")
+	}
+
+	return content, nil
+}
+
+func httpGet(rawURL string) ([]byte, error) {
+	resp, err := http.Get(rawURL)
+	if err != nil {
+		// The URL may hold a token, don't leak it in the error.
+		if urlErr, ok := err.(*url.Error); ok {
+			return nil, errors.Wrap(urlErr.Err, "failed to request values file")
+		}
+		return nil, errors.Wrap(err, "failed to request values file")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return nil, errors.Errorf("request failed with status: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	return body, nil
+}
+
+// gitValuesSource reads values files from a shallow clone of a Git
+// repository. The ref query parameter selects a branch or tag.
+type gitValuesSource struct{}
+
+func (gitValuesSource) Read(location *url.URL, logger log.FieldLogger) ([]byte, error) {
+	repository, filePath, err := splitGitValuesLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "helm-values-git-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temporary directory for Git clone")
+	}
+	defer os.RemoveAll(dir)
+
+	arguments := []string{"clone", "--quiet", "--depth", "1"}
+	if ref := location.Query().Get("ref"); len(ref) != 0 {
+		arguments = append(arguments, "--branch", ref)
+	}
+	arguments = append(arguments, repository.String(), dir)
+
+	// Run without the exec helper so that credentials in the repository URL
+	// aren't logged.
+	cmd := exec.Command("git", arguments...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		logger.WithError(err).Debugf("Git output was:\n%s\n", string(output))
+		return nil, errors.Wrapf(err, "failed to clone repository %s", repository.Redacted())
+	}
+
+	return ioutil.ReadFile(filepath.Join(dir, filePath))
+}
+
+// splitGitValuesLocation returns the URL of the repository and the path of
+// the values file in the repository.
+func splitGitValuesLocation(location *url.URL) (*url.URL, string, error) {
+	parts := strings.SplitN(location.Path, model.UtilityValuesGitPathSeparator, 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, "", errors.Errorf("git values path must separate the repository and the file path with %q", model.UtilityValuesGitPathSeparator)
+	}
+
+	repository := &url.URL{
+		Scheme: strings.TrimPrefix(location.Scheme, "git+"),
+		User:   location.User,
+		Host:   location.Host,
+		Path:   parts[0],
+	}
+
+	// Cleaning the rooted path keeps it inside of the repository.
+	filePath := filepath.Clean("/" + parts[1])
+
+	return repository, filePath, nil
+}
+
+// s3ValuesSource reads values files stored as S3 objects. The region query
+// parameter selects the region of the bucket.
+type s3ValuesSource struct {
+	newClient func(region string) (s3iface.S3API, error)
+}
+
+func newS3ValuesClient(region string) (s3iface.S3API, error) {
+	awsSession, err := session.NewSession(&awssdk.Config{Region: awssdk.String(region)})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AWS session")
+	}
+
+	return s3.New(awsSession), nil
+}
+
+func (source *s3ValuesSource) Read(location *url.URL, logger log.FieldLogger) ([]byte, error) {
+	region := location.Query().Get("region")
+	if len(region) == 0 {
+		region = aws.DefaultAWSRegion
+	}
+
+	client, err := source.newClient(region)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: awssdk.String(location.Host),
+		Key:    awssdk.String(strings.TrimPrefix(location.Path, "/")),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get S3 object")
+	}
+	defer output.Body.Close()
+
+	return ioutil.ReadAll(output.Body)
+}
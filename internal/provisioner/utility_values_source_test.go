@@ -0,0 +1,158 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/golang/mock/gomock"
+	mockawssdk "github.com/mattermost/mattermost-cloud/internal/mocks/aws-sdk"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchValuesFile(t *testing.T) {
+	logger := log.New()
+	content := []byte("controller:\n  replicaCount: 2\n")
+	sum := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	localPath := filepath.Join(t.TempDir(), "nginx_values.yaml")
+	require.NoError(t, ioutil.WriteFile(localPath, content, 0600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nginx_values.yaml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		assert.Empty(t, r.URL.Query().Get("checksum"))
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	t.Run("no values path", func(t *testing.T) {
+		path, cleanup, err := fetchValuesFile("", logger)
+		require.NoError(t, err)
+		defer cleanup()
+		assert.Empty(t, path)
+	})
+
+	t.Run("local path is used in place", func(t *testing.T) {
+		path, cleanup, err := fetchValuesFile(localPath, logger)
+		require.NoError(t, err)
+		defer cleanup()
+		assert.Equal(t, localPath, path)
+
+		path, cleanup, err = fetchValuesFile("file://"+localPath, logger)
+		require.NoError(t, err)
+		defer cleanup()
+		assert.Equal(t, localPath, path)
+	})
+
+	t.Run("local path with checksum", func(t *testing.T) {
+		path, cleanup, err := fetchValuesFile(fmt.Sprintf("file://%s?checksum=%s", localPath, checksum), logger)
+		require.NoError(t, err)
+		defer cleanup()
+		assert.NotEqual(t, localPath, path)
+
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("http with checksum", func(t *testing.T) {
+		path, cleanup, err := fetchValuesFile(fmt.Sprintf("%s/nginx_values.yaml?checksum=%s", server.URL, checksum), logger)
+		require.NoError(t, err)
+		defer cleanup()
+
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("http checksum mismatch", func(t *testing.T) {
+		otherSum := sha256.Sum256([]byte("other"))
+		_, _, err := fetchValuesFile(fmt.Sprintf("%s/nginx_values.yaml?checksum=sha256:%s", server.URL, hex.EncodeToString(otherSum[:])), logger)
+		assert.Error(t, err)
+	})
+
+	t.Run("http not found", func(t *testing.T) {
+		_, _, err := fetchValuesFile(server.URL+"/missing.yaml", logger)
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		_, _, err := fetchValuesFile("ftp://example.com/nginx_values.yaml", logger)
+		assert.Error(t, err)
+	})
+}
+
+func TestSplitGitValuesLocation(t *testing.T) {
+	for _, tc := range []struct {
+		valuesPath         string
+		expectedRepository string
+		expectedFilePath   string
+		expectError        bool
+	}{
+		{"git+https://github.com/org/values.git//dev/nginx_values.yaml?ref=main", "https://github.com/org/values.git", "/dev/nginx_values.yaml", false},
+		{"git+ssh://git@github.com/org/values.git//nginx_values.yaml", "ssh://git@github.com/org/values.git", "/nginx_values.yaml", false},
+		{"git+https://github.com/org/values.git//../../etc/passwd", "https://github.com/org/values.git", "/etc/passwd", false},
+		{"git+https://github.com/org/values.git/nginx_values.yaml", "", "", true},
+	} {
+		t.Run(tc.valuesPath, func(t *testing.T) {
+			location, err := url.Parse(tc.valuesPath)
+			require.NoError(t, err)
+
+			repository, filePath, err := splitGitValuesLocation(location)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedRepository, repository.String())
+			assert.Equal(t, tc.expectedFilePath, filePath)
+		})
+	}
+}
+
+func TestS3ValuesSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s3Client := mockawssdk.NewMockS3API(ctrl)
+	var clientRegion string
+	source := &s3ValuesSource{newClient: func(region string) (s3iface.S3API, error) {
+		clientRegion = region
+		return s3Client, nil
+	}}
+
+	s3Client.EXPECT().
+		GetObject(gomock.Any()).
+		Do(func(input *s3.GetObjectInput) {
+			assert.Equal(t, "values-bucket", *input.Bucket)
+			assert.Equal(t, "dev/nginx_values.yaml", *input.Key)
+		}).
+		Return(&s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader([]byte("replicaCount: 2\n")))}, nil)
+
+	location, err := url.Parse("s3://values-bucket/dev/nginx_values.yaml?region=eu-west-1")
+	require.NoError(t, err)
+
+	data, err := source.Read(location, log.New())
+	require.NoError(t, err)
+	assert.Equal(t, "replicaCount: 2\n", string(data))
+	assert.Equal(t, "eu-west-1", clientRegion)
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package provisioner
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareHelmValuesFiles(t *testing.T) {
+	logger := log.New()
+	dir := t.TempDir()
+	staticPath := filepath.Join(dir, "nginx_values.yaml")
+	templatePath := filepath.Join(dir, "nginx_values.yaml.tmpl")
+	require.NoError(t, ioutil.WriteFile(staticPath, []byte("annotation: '{{ $labels.instance }}'\n"), 0600))
+	require.NoError(t, ioutil.WriteFile(templatePath, []byte("cidr: {{ .VPCCIDR }}\n"), 0600))
+
+	var contextCalls int
+	getContext := cachedValuesContext(func() (*model.UtilityValuesContext, error) {
+		contextCalls++
+		return &model.UtilityValuesContext{ClusterID: "cluster1", VPCCIDR: "10.0.0.0/16"}, nil
+	})
+
+	readFile := func(t *testing.T, path string) string {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("static values file", func(t *testing.T) {
+		files, cleanup, err := prepareHelmValuesFiles(staticPath, "", getContext, logger)
+		require.NoError(t, err)
+		defer cleanup()
+
+		assert.Equal(t, []string{staticPath}, files)
+		assert.Equal(t, 0, contextCalls)
+	})
+
+	t.Run("values template", func(t *testing.T) {
+		files, cleanup, err := prepareHelmValuesFiles(templatePath, "", getContext, logger)
+		require.NoError(t, err)
+
+		require.Len(t, files, 1)
+		assert.NotEqual(t, templatePath, files[0])
+		assert.Equal(t, "cidr: 10.0.0.0/16\n", readFile(t, files[0]))
+
+		cleanup()
+		_, err = os.Stat(files[0])
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("values override", func(t *testing.T) {
+		files, cleanup, err := prepareHelmValuesFiles("", "cluster: {{ .ClusterID }}\n", getContext, logger)
+		require.NoError(t, err)
+		defer cleanup()
+
+		require.Len(t, files, 1)
+		assert.Equal(t, "cluster: cluster1\n", readFile(t, files[0]))
+		assert.Equal(t, 1, contextCalls)
+	})
+
+	t.Run("context error", func(t *testing.T) {
+		failingContext := cachedValuesContext(func() (*model.UtilityValuesContext, error) {
+			return nil, errors.New("no VPC")
+		})
+		_, _, err := prepareHelmValuesFiles(templatePath, "", failingContext, logger)
+		assert.Error(t, err)
+	})
+
+	t.Run("no context", func(t *testing.T) {
+		_, _, err := prepareHelmValuesFiles(templatePath, "", cachedValuesContext(nil), logger)
+		assert.Error(t, err)
+	})
+}
+
+func TestRenderClusterUtilityValues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("cluster: {{ .ClusterID }}\n"))
+	}))
+	defer server.Close()
+
+	valuesDirectory := t.TempDir()
+	model.SetUtilityValuesDirectory(valuesDirectory)
+	defer model.SetUtilityValuesDirectory("")
+
+	allowedTemplatePath := filepath.Join(valuesDirectory, "promtail_values.yaml.tmpl")
+	require.NoError(t, ioutil.WriteFile(allowedTemplatePath, []byte("cluster: {{ .ClusterID }}\n"), 0600))
+
+	localTemplatePath := filepath.Join(t.TempDir(), "promtail_values.yaml.tmpl")
+	require.NoError(t, ioutil.WriteFile(localTemplatePath, []byte("cluster: {{ .ClusterID }}\n"), 0600))
+
+	valuesContext := &model.UtilityValuesContext{ClusterID: "cluster1"}
+	provisioner := &KopsProvisioner{logger: log.New()}
+
+	for _, testCase := range []struct {
+		description    string
+		valuesPath     string
+		expectedValues string
+	}{
+		{
+			description:    "remote values template",
+			valuesPath:     server.URL + "/promtail_values.yaml.tmpl",
+			expectedValues: "cluster: cluster1\n",
+		},
+		{
+			description:    "local values template under the values directory",
+			valuesPath:     allowedTemplatePath,
+			expectedValues: "cluster: cluster1\n",
+		},
+		{
+			description: "local values template",
+			valuesPath:  localTemplatePath,
+		},
+		{
+			description: "local values file url",
+			valuesPath:  "file://" + localTemplatePath,
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			cluster := &model.Cluster{
+				ID: "cluster1",
+				UtilityMetadata: &model.UtilityMetadata{
+					ActualVersions: model.UtilityGroupVersions{
+						Promtail: &model.HelmUtilityVersion{Chart: "2.0.0", ValuesPath: testCase.valuesPath},
+					},
+				},
+			}
+
+			values, err := provisioner.RenderClusterUtilityValues(cluster, model.PromtailCanonicalName, valuesContext)
+			require.NoError(t, err)
+			assert.Equal(t, &model.ClusterUtilityValues{
+				ClusterID:  "cluster1",
+				Utility:    model.PromtailCanonicalName,
+				ValuesPath: testCase.valuesPath,
+				Templated:  true,
+				Values:     testCase.expectedValues,
+				Context:    valuesContext,
+			}, values)
+		})
+	}
+}
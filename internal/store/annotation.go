@@ -25,7 +25,7 @@ var annotationColumns = []string{
 
 var (
 	// ErrClusterAnnotationUsedByInstallation is an error returned when user attempts to delete cluster annotation
-	// present on the installation scheduled on that cluster.
+	// present on the installation scheduled on that cluster or required by its placement rules.
 	ErrClusterAnnotationUsedByInstallation = errors.New("cannot delete cluster annotation, " +
 		"it is used by one or more installations scheduled on the cluster")
 	// ErrInstallationAnnotationDoNotMatchClusters is an error returned when user attempts to add annotation to the
@@ -229,6 +229,19 @@ func (sqlStore *SQLStore) DeleteClusterAnnotation(clusterID string, annotationNa
 		if model.ContainsAnnotation(annotations, annotation) {
 			return ErrClusterAnnotationUsedByInstallation
 		}
+
+		var rawInstallation rawInstallation
+		err = sqlStore.getBuilder(tx, &rawInstallation, installationSelect.Where("ID = ?", ci.InstallationID))
+		if err != nil {
+			return errors.Wrapf(err, "failed to get '%s' installation", ci.InstallationID)
+		}
+		installation, err := rawInstallation.toInstallation()
+		if err != nil {
+			return errors.Wrapf(err, "failed to convert '%s' installation", ci.InstallationID)
+		}
+		if installation.Placement.RequiresClusterAnnotation(annotation.Name) {
+			return ErrClusterAnnotationUsedByInstallation
+		}
 	}
 
 	builder := sq.Delete(clusterAnnotationTable).
@@ -20,7 +20,8 @@ func init() {
 	clusterSelect = sq.
 		Select("Cluster.ID", "Provider", "Provisioner", "ProviderMetadataRaw", "ProvisionerMetadataRaw",
 			"UtilityMetadataRaw", "State", "AllowInstallations", "CreateAt", "DeleteAt",
-			"APISecurityLock", "LockAcquiredBy", "LockAcquiredAt").
+			"APISecurityLock", "LockAcquiredBy", "LockAcquiredAt", "ClusterTemplateID",
+			"UpgradeReportRaw").
 		From("Cluster")
 }
 
@@ -29,6 +30,7 @@ type RawClusterMetadata struct {
 	ProviderMetadataRaw    []byte
 	ProvisionerMetadataRaw []byte
 	UtilityMetadataRaw     []byte
+	UpgradeReportRaw       []byte
 }
 
 type rawCluster struct {
@@ -43,19 +45,30 @@ func buildRawMetadata(cluster *model.Cluster) (*RawClusterMetadata, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to marshal ProviderMetadataAWS")
 	}
-	provisionerMetadataJSON, err := json.Marshal(cluster.ProvisionerMetadataKops)
+	// The provisioner metadata column holds the metadata of the provisioner
+	// of the cluster.
+	var provisionerMetadata interface{} = cluster.ProvisionerMetadataKops
+	if cluster.IsEKS() {
+		provisionerMetadata = cluster.ProvisionerMetadataEKS
+	}
+	provisionerMetadataJSON, err := json.Marshal(provisionerMetadata)
 	if err != nil {
-		return nil, errors.Wrap(err, "unable to marshal ProvisionerMetadataKops")
+		return nil, errors.Wrap(err, "unable to marshal provisioner metadata")
 	}
 	utilityMetadataJSON, err := json.Marshal(cluster.UtilityMetadata)
 	if err != nil {
 		return nil, errors.Wrap(err, "unable to marshal UtilityMetadata")
 	}
+	upgradeReportJSON, err := json.Marshal(cluster.UpgradeReport)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal UpgradeReport")
+	}
 
 	return &RawClusterMetadata{
 		ProviderMetadataRaw:    providerMetadataJSON,
 		ProvisionerMetadataRaw: provisionerMetadataJSON,
 		UtilityMetadataRaw:     utilityMetadataJSON,
+		UpgradeReportRaw:       upgradeReportJSON,
 	}, nil
 }
 
@@ -65,7 +78,11 @@ func (r *rawCluster) toCluster() (*model.Cluster, error) {
 	if err != nil {
 		return nil, err
 	}
-	r.Cluster.ProvisionerMetadataKops, err = model.NewKopsMetadata(r.ProvisionerMetadataRaw)
+	if r.Cluster.IsEKS() {
+		r.Cluster.ProvisionerMetadataEKS, err = model.NewEKSMetadata(r.ProvisionerMetadataRaw)
+	} else {
+		r.Cluster.ProvisionerMetadataKops, err = model.NewKopsMetadata(r.ProvisionerMetadataRaw)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -73,6 +90,10 @@ func (r *rawCluster) toCluster() (*model.Cluster, error) {
 	if err != nil {
 		return nil, err
 	}
+	r.Cluster.UpgradeReport, err = model.NewClusterUpgradeReportFromRaw(r.UpgradeReportRaw)
+	if err != nil {
+		return nil, err
+	}
 	if r.Cluster.ProvisionerMetadataKops != nil && r.Cluster.ProvisionerMetadataKops.Networking != "" {
 		r.Cluster.Networking = r.Cluster.ProvisionerMetadataKops.Networking
 	}
@@ -211,6 +232,8 @@ func (sqlStore *SQLStore) createCluster(execer execer, cluster *model.Cluster) e
 			"APISecurityLock":        cluster.APISecurityLock,
 			"LockAcquiredBy":         nil,
 			"LockAcquiredAt":         0,
+			"ClusterTemplateID":      cluster.ClusterTemplateID,
+			"UpgradeReportRaw":       rawMetadata.UpgradeReportRaw,
 		}),
 	)
 	if err != nil {
@@ -235,6 +258,7 @@ func (sqlStore *SQLStore) UpdateCluster(cluster *model.Cluster) error {
 			"Provisioner":            cluster.Provisioner,
 			"ProvisionerMetadataRaw": rawMetadata.ProvisionerMetadataRaw,
 			"UtilityMetadataRaw":     rawMetadata.UtilityMetadataRaw,
+			"UpgradeReportRaw":       rawMetadata.UpgradeReportRaw,
 			"AllowInstallations":     cluster.AllowInstallations,
 		}).
 		Where("ID = ?", cluster.ID),
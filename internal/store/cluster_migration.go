@@ -0,0 +1,246 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	clusterMigrationTable = "ClusterMigrationOperation"
+)
+
+var clusterMigrationSelect sq.SelectBuilder
+
+func init() {
+	clusterMigrationSelect = sq.
+		Select("ID",
+			"SourceClusterID",
+			"TargetClusterID",
+			"InstallationID",
+			"LockInstallations",
+			"SwitchClusterRoles",
+			"DNSSwitched",
+			"ClusterInstallationsRaw",
+			"RequestAt",
+			"State",
+			"CompleteAt",
+			"DeleteAt",
+			"LockAcquiredBy",
+			"LockAcquiredAt",
+		).
+		From(clusterMigrationTable)
+}
+
+type rawClusterMigrationOperation struct {
+	*model.ClusterMigrationOperation
+	ClusterInstallationsRaw []byte
+}
+
+type rawClusterMigrationOperations []*rawClusterMigrationOperation
+
+func (r *rawClusterMigrationOperation) toClusterMigrationOperation() (*model.ClusterMigrationOperation, error) {
+	// We only need to set values that are converted from a raw database format.
+	if r.ClusterInstallationsRaw != nil {
+		var clusterInstallations []model.ClusterMigrationClusterInstallation
+		err := json.Unmarshal(r.ClusterInstallationsRaw, &clusterInstallations)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal cluster installations")
+		}
+		r.ClusterMigrationOperation.ClusterInstallations = clusterInstallations
+	}
+
+	return r.ClusterMigrationOperation, nil
+}
+
+func (rs *rawClusterMigrationOperations) toClusterMigrationOperations() ([]*model.ClusterMigrationOperation, error) {
+	var operations []*model.ClusterMigrationOperation
+	for _, raw := range *rs {
+		operation, err := raw.toClusterMigrationOperation()
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
+
+// CreateClusterMigrationOperation records cluster migration to the database, assigning it a unique ID.
+func (sqlStore *SQLStore) CreateClusterMigrationOperation(operation *model.ClusterMigrationOperation) error {
+	operation.ID = model.NewID()
+	operation.RequestAt = model.GetMillis()
+
+	insertsMap := map[string]interface{}{
+		"ID":                 operation.ID,
+		"SourceClusterID":    operation.SourceClusterID,
+		"TargetClusterID":    operation.TargetClusterID,
+		"InstallationID":     operation.InstallationID,
+		"LockInstallations":  operation.LockInstallations,
+		"SwitchClusterRoles": operation.SwitchClusterRoles,
+		"DNSSwitched":        operation.DNSSwitched,
+		"RequestAt":          operation.RequestAt,
+		"State":              operation.State,
+		"CompleteAt":         operation.CompleteAt,
+		"DeleteAt":           0,
+		"LockAcquiredBy":     operation.LockAcquiredBy,
+		"LockAcquiredAt":     operation.LockAcquiredAt,
+	}
+
+	if operation.ClusterInstallations != nil {
+		clusterInstallationsJSON, err := json.Marshal(operation.ClusterInstallations)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal cluster installations")
+		}
+		insertsMap["ClusterInstallationsRaw"] = clusterInstallationsJSON
+	}
+
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Insert(clusterMigrationTable).
+		SetMap(insertsMap),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster migration operation")
+	}
+
+	return nil
+}
+
+// GetClusterMigrationOperation fetches the given cluster migration.
+func (sqlStore *SQLStore) GetClusterMigrationOperation(id string) (*model.ClusterMigrationOperation, error) {
+	builder := clusterMigrationSelect.
+		Where("ID = ?", id)
+
+	var raw rawClusterMigrationOperation
+	err := sqlStore.getBuilder(sqlStore.db, &raw, builder)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to query for cluster migration")
+	}
+
+	return raw.toClusterMigrationOperation()
+}
+
+// GetClusterMigrationOperations fetches the given page of created cluster migrations. The first page is 0.
+func (sqlStore *SQLStore) GetClusterMigrationOperations(filter *model.ClusterMigrationFilter) ([]*model.ClusterMigrationOperation, error) {
+	builder := clusterMigrationSelect.
+		OrderBy("RequestAt DESC")
+	builder = sqlStore.applyClusterMigrationFilter(builder, filter)
+
+	return sqlStore.getClusterMigrationOperations(builder)
+}
+
+// GetUnlockedClusterMigrationOperationsPendingWork returns unlocked cluster migrations in a pending state.
+func (sqlStore *SQLStore) GetUnlockedClusterMigrationOperationsPendingWork() ([]*model.ClusterMigrationOperation, error) {
+	builder := clusterMigrationSelect.
+		Where(sq.Eq{
+			"State": model.AllClusterMigrationOperationsStatesPendingWork,
+		}).
+		Where("LockAcquiredAt = 0").
+		OrderBy("RequestAt ASC")
+
+	return sqlStore.getClusterMigrationOperations(builder)
+}
+
+func (sqlStore *SQLStore) getClusterMigrationOperations(builder builder) ([]*model.ClusterMigrationOperation, error) {
+	var raw rawClusterMigrationOperations
+	err := sqlStore.selectBuilder(sqlStore.db, &raw, builder)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query for cluster migrations")
+	}
+
+	return raw.toClusterMigrationOperations()
+}
+
+// UpdateClusterMigrationOperationState updates the given cluster migration state.
+func (sqlStore *SQLStore) UpdateClusterMigrationOperationState(operation *model.ClusterMigrationOperation) error {
+	return sqlStore.updateClusterMigrationFields(
+		operation.ID, map[string]interface{}{
+			"State": operation.State,
+		})
+}
+
+// UpdateClusterMigrationOperation updates the given cluster migration.
+func (sqlStore *SQLStore) UpdateClusterMigrationOperation(operation *model.ClusterMigrationOperation) error {
+	clusterInstallationsJSON, err := json.Marshal(operation.ClusterInstallations)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cluster installations")
+	}
+
+	return sqlStore.updateClusterMigrationFields(
+		operation.ID, map[string]interface{}{
+			"State":                   operation.State,
+			"DNSSwitched":             operation.DNSSwitched,
+			"ClusterInstallationsRaw": clusterInstallationsJSON,
+			"CompleteAt":              operation.CompleteAt,
+		})
+}
+
+func (sqlStore *SQLStore) updateClusterMigrationFields(id string, fields map[string]interface{}) error {
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Update(clusterMigrationTable).
+		SetMap(fields).
+		Where("ID = ?", id))
+	if err != nil {
+		return errors.Wrapf(err, "failed to update cluster migration fields: %s", getMapKeys(fields))
+	}
+
+	return nil
+}
+
+// DeleteClusterMigrationOperation marks the given cluster migration operation as deleted,
+// but does not remove the record from the database.
+func (sqlStore *SQLStore) DeleteClusterMigrationOperation(id string) error {
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Update(clusterMigrationTable).
+		Set("DeleteAt", model.GetMillis()).
+		Where("ID = ?", id).
+		Where("DeleteAt = ?", 0))
+	if err != nil {
+		return errors.Wrap(err, "failed to to mark cluster migration as deleted")
+	}
+
+	return nil
+}
+
+// LockClusterMigrationOperations marks ClusterMigrationOperations as locked for exclusive use by the caller.
+func (sqlStore *SQLStore) LockClusterMigrationOperations(ids []string, lockerID string) (bool, error) {
+	return sqlStore.lockRows(clusterMigrationTable, ids, lockerID)
+}
+
+// UnlockClusterMigrationOperations releases locks previously acquired against a caller.
+func (sqlStore *SQLStore) UnlockClusterMigrationOperations(ids []string, lockerID string, force bool) (bool, error) {
+	return sqlStore.unlockRows(clusterMigrationTable, ids, lockerID, force)
+}
+
+func (sqlStore *SQLStore) applyClusterMigrationFilter(builder sq.SelectBuilder, filter *model.ClusterMigrationFilter) sq.SelectBuilder {
+	builder = applyPagingFilter(builder, filter.Paging)
+
+	if len(filter.IDs) > 0 {
+		builder = builder.Where(sq.Eq{"ID": filter.IDs})
+	}
+	if filter.InstallationID != "" {
+		builder = builder.Where("InstallationID = ?", filter.InstallationID)
+	}
+	if filter.SourceClusterID != "" {
+		builder = builder.Where("SourceClusterID = ?", filter.SourceClusterID)
+	}
+	if filter.TargetClusterID != "" {
+		builder = builder.Where("TargetClusterID = ?", filter.TargetClusterID)
+	}
+	if len(filter.States) > 0 {
+		builder = builder.Where(sq.Eq{
+			"State": filter.States,
+		})
+	}
+
+	return builder
+}
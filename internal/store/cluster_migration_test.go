@@ -0,0 +1,143 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterMigrationOperation(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	operation := &model.ClusterMigrationOperation{
+		SourceClusterID:   model.NewID(),
+		TargetClusterID:   model.NewID(),
+		LockInstallations: true,
+		State:             model.ClusterMigrationStateRequested,
+	}
+
+	err := sqlStore.CreateClusterMigrationOperation(operation)
+	require.NoError(t, err)
+	assert.NotEmpty(t, operation.ID)
+
+	fetched, err := sqlStore.GetClusterMigrationOperation(operation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, operation, fetched)
+
+	operation.ClusterInstallations = []model.ClusterMigrationClusterInstallation{
+		{
+			InstallationID:              model.NewID(),
+			SourceClusterInstallationID: model.NewID(),
+			TargetClusterInstallationID: model.NewID(),
+		},
+	}
+	operation.DNSSwitched = true
+	operation.State = model.ClusterMigrationStateWaitingForInstallations
+	err = sqlStore.UpdateClusterMigrationOperation(operation)
+	require.NoError(t, err)
+
+	fetched, err = sqlStore.GetClusterMigrationOperation(operation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, operation, fetched)
+
+	t.Run("unknown operation", func(t *testing.T) {
+		fetched, err = sqlStore.GetClusterMigrationOperation("unknown")
+		require.NoError(t, err)
+		assert.Nil(t, fetched)
+	})
+}
+
+func TestGetClusterMigrations(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	clusterID1 := model.NewID()
+	clusterID2 := model.NewID()
+	installationID := model.NewID()
+
+	operations := []*model.ClusterMigrationOperation{
+		{SourceClusterID: clusterID1, TargetClusterID: clusterID2, InstallationID: installationID, State: model.ClusterMigrationStateRequested},
+		{SourceClusterID: clusterID1, TargetClusterID: clusterID2, State: model.ClusterMigrationStateFailed},
+		{SourceClusterID: clusterID2, TargetClusterID: clusterID1, State: model.ClusterMigrationStateRollbackRequested},
+		{SourceClusterID: clusterID2, TargetClusterID: clusterID1, State: model.ClusterMigrationStateSucceeded},
+	}
+
+	for i := range operations {
+		err := sqlStore.CreateClusterMigrationOperation(operations[i])
+		require.NoError(t, err)
+		time.Sleep(1 * time.Millisecond) // Ensure RequestAt is different for all operations.
+	}
+
+	for _, testCase := range []struct {
+		description string
+		filter      *model.ClusterMigrationFilter
+		fetchedIds  []string
+	}{
+		{
+			description: "fetch all",
+			filter:      &model.ClusterMigrationFilter{Paging: model.AllPagesNotDeleted()},
+			fetchedIds:  []string{operations[3].ID, operations[2].ID, operations[1].ID, operations[0].ID},
+		},
+		{
+			description: "fetch by source cluster",
+			filter:      &model.ClusterMigrationFilter{SourceClusterID: clusterID1, Paging: model.AllPagesNotDeleted()},
+			fetchedIds:  []string{operations[1].ID, operations[0].ID},
+		},
+		{
+			description: "fetch by target cluster",
+			filter:      &model.ClusterMigrationFilter{TargetClusterID: clusterID1, Paging: model.AllPagesNotDeleted()},
+			fetchedIds:  []string{operations[3].ID, operations[2].ID},
+		},
+		{
+			description: "fetch by installation",
+			filter:      &model.ClusterMigrationFilter{InstallationID: installationID, Paging: model.AllPagesNotDeleted()},
+			fetchedIds:  []string{operations[0].ID},
+		},
+		{
+			description: "fetch pending operations",
+			filter:      &model.ClusterMigrationFilter{States: model.AllClusterMigrationOperationsStatesPendingWork, Paging: model.AllPagesNotDeleted()},
+			fetchedIds:  []string{operations[2].ID, operations[0].ID},
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			fetched, err := sqlStore.GetClusterMigrationOperations(testCase.filter)
+			require.NoError(t, err)
+			assert.Equal(t, len(testCase.fetchedIds), len(fetched))
+
+			for i, o := range fetched {
+				assert.Equal(t, testCase.fetchedIds[i], o.ID)
+			}
+		})
+	}
+
+	t.Run("pending work", func(t *testing.T) {
+		pending, err := sqlStore.GetUnlockedClusterMigrationOperationsPendingWork()
+		require.NoError(t, err)
+		require.Equal(t, 2, len(pending))
+		assert.Equal(t, operations[0].ID, pending[0].ID)
+
+		locked, err := sqlStore.LockClusterMigrationOperations([]string{operations[0].ID}, "abc")
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		pending, err = sqlStore.GetUnlockedClusterMigrationOperationsPendingWork()
+		require.NoError(t, err)
+		require.Equal(t, 1, len(pending))
+		assert.Equal(t, operations[2].ID, pending[0].ID)
+
+		unlocked, err := sqlStore.UnlockClusterMigrationOperations([]string{operations[0].ID}, "abc", false)
+		require.NoError(t, err)
+		assert.True(t, unlocked)
+	})
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"encoding/json"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+)
+
+var clusterSizeSelect sq.SelectBuilder
+
+func init() {
+	clusterSizeSelect = sq.
+		Select("ID", "Name", "MasterInstanceType", "MasterCount", "NodeInstanceType",
+			"NodeMinCount", "NodeMaxCount", "AdditionalNodeGroupsRaw", "CreateAt", "DeleteAt").
+		From("ClusterSize")
+}
+
+type rawClusterSize struct {
+	*model.ClusterSize
+	AdditionalNodeGroupsRaw []byte
+}
+
+type rawClusterSizes []*rawClusterSize
+
+func (r *rawClusterSize) toClusterSize() (*model.ClusterSize, error) {
+	// We only need to set values that are converted from a raw database format.
+	if r.AdditionalNodeGroupsRaw != nil {
+		additionalNodeGroups := model.KopsInstanceGroupsMetadata{}
+		err := json.Unmarshal(r.AdditionalNodeGroupsRaw, &additionalNodeGroups)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal additional node groups")
+		}
+		r.ClusterSize.AdditionalNodeGroups = additionalNodeGroups
+	}
+
+	return r.ClusterSize, nil
+}
+
+func (rs *rawClusterSizes) toClusterSizes() ([]*model.ClusterSize, error) {
+	var clusterSizes []*model.ClusterSize
+	for _, rawClusterSize := range *rs {
+		clusterSize, err := rawClusterSize.toClusterSize()
+		if err != nil {
+			return nil, err
+		}
+		clusterSizes = append(clusterSizes, clusterSize)
+	}
+
+	return clusterSizes, nil
+}
+
+// GetClusterSizes fetches the given page of created cluster sizes. The first
+// page is 0.
+func (sqlStore *SQLStore) GetClusterSizes(filter *model.ClusterSizeFilter) ([]*model.ClusterSize, error) {
+	builder := clusterSizeSelect.
+		OrderBy("CreateAt ASC")
+
+	builder = applyPagingFilter(builder, filter.Paging)
+
+	if filter.Name != "" {
+		builder = builder.Where("Name = ?", filter.Name)
+	}
+
+	var rawClusterSizes rawClusterSizes
+	err := sqlStore.selectBuilder(sqlStore.db, &rawClusterSizes, builder)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query for cluster sizes")
+	}
+
+	return rawClusterSizes.toClusterSizes()
+}
+
+// CreateClusterSize records the given cluster size to the database, assigning
+// it a unique ID.
+func (sqlStore *SQLStore) CreateClusterSize(clusterSize *model.ClusterSize) error {
+	clusterSize.ID = model.NewID()
+	clusterSize.CreateAt = model.GetMillis()
+
+	var additionalNodeGroupsRaw []byte
+	if clusterSize.AdditionalNodeGroups != nil {
+		var err error
+		additionalNodeGroupsRaw, err = json.Marshal(clusterSize.AdditionalNodeGroups)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal additional node groups")
+		}
+	}
+
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Insert("ClusterSize").
+		SetMap(map[string]interface{}{
+			"ID":                      clusterSize.ID,
+			"Name":                    clusterSize.Name,
+			"MasterInstanceType":      clusterSize.MasterInstanceType,
+			"MasterCount":             clusterSize.MasterCount,
+			"NodeInstanceType":        clusterSize.NodeInstanceType,
+			"NodeMinCount":            clusterSize.NodeMinCount,
+			"NodeMaxCount":            clusterSize.NodeMaxCount,
+			"AdditionalNodeGroupsRaw": additionalNodeGroupsRaw,
+			"CreateAt":                clusterSize.CreateAt,
+			"DeleteAt":                0,
+		}),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster size")
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterSizes(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	size1 := &model.ClusterSize{
+		Name:               "size1",
+		MasterInstanceType: "t3.medium",
+		MasterCount:        1,
+		NodeInstanceType:   "m5.large",
+		NodeMinCount:       2,
+		NodeMaxCount:       2,
+	}
+	size2 := &model.ClusterSize{
+		Name:               "size2",
+		MasterInstanceType: "t3.large",
+		MasterCount:        3,
+		NodeInstanceType:   "m5.xlarge",
+		NodeMinCount:       4,
+		NodeMaxCount:       6,
+		AdditionalNodeGroups: model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 2, NodeMaxCount: 2},
+		},
+	}
+
+	err := sqlStore.CreateClusterSize(size1)
+	require.NoError(t, err)
+	require.NotEmpty(t, size1.ID)
+
+	time.Sleep(1 * time.Millisecond)
+
+	err = sqlStore.CreateClusterSize(size2)
+	require.NoError(t, err)
+
+	sizes, err := sqlStore.GetClusterSizes(&model.ClusterSizeFilter{Paging: model.AllPagesNotDeleted()})
+	require.NoError(t, err)
+	require.Equal(t, []*model.ClusterSize{size1, size2}, sizes)
+
+	sizes, err = sqlStore.GetClusterSizes(&model.ClusterSizeFilter{Paging: model.AllPagesNotDeleted(), Name: "size2"})
+	require.NoError(t, err)
+	require.Equal(t, []*model.ClusterSize{size2}, sizes)
+
+	sizes, err = sqlStore.GetClusterSizes(&model.ClusterSizeFilter{Paging: model.AllPagesNotDeleted(), Name: "unknown"})
+	require.NoError(t, err)
+	require.Empty(t, sizes)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+)
+
+var clusterTemplateSelect sq.SelectBuilder
+
+func init() {
+	clusterTemplateSelect = sq.
+		Select("ID", "Name", "Description", "ClusterRaw", "CreateAt", "DeleteAt").
+		From("ClusterTemplate")
+}
+
+type rawClusterTemplate struct {
+	*model.ClusterTemplate
+	ClusterRaw []byte
+}
+
+type rawClusterTemplates []*rawClusterTemplate
+
+func (r *rawClusterTemplate) toClusterTemplate() (*model.ClusterTemplate, error) {
+	// We only need to set values that are converted from a raw database format.
+	if r.ClusterRaw != nil {
+		cluster := &model.CreateClusterRequest{}
+		err := json.Unmarshal(r.ClusterRaw, cluster)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to unmarshal cluster template parameters")
+		}
+		r.ClusterTemplate.Cluster = cluster
+	}
+
+	return r.ClusterTemplate, nil
+}
+
+func (rs *rawClusterTemplates) toClusterTemplates() ([]*model.ClusterTemplate, error) {
+	var clusterTemplates []*model.ClusterTemplate
+	for _, rawClusterTemplate := range *rs {
+		clusterTemplate, err := rawClusterTemplate.toClusterTemplate()
+		if err != nil {
+			return nil, err
+		}
+		clusterTemplates = append(clusterTemplates, clusterTemplate)
+	}
+
+	return clusterTemplates, nil
+}
+
+// GetClusterTemplate fetches the given cluster template by id.
+func (sqlStore *SQLStore) GetClusterTemplate(id string) (*model.ClusterTemplate, error) {
+	var rawClusterTemplate rawClusterTemplate
+	err := sqlStore.getBuilder(sqlStore.db, &rawClusterTemplate,
+		clusterTemplateSelect.Where("ID = ?", id),
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster template by id")
+	}
+
+	return rawClusterTemplate.toClusterTemplate()
+}
+
+// GetClusterTemplates fetches the given page of created cluster templates. The
+// first page is 0.
+func (sqlStore *SQLStore) GetClusterTemplates(filter *model.ClusterTemplateFilter) ([]*model.ClusterTemplate, error) {
+	builder := clusterTemplateSelect.
+		OrderBy("CreateAt ASC")
+
+	builder = applyPagingFilter(builder, filter.Paging)
+
+	if filter.Name != "" {
+		builder = builder.Where("Name = ?", filter.Name)
+	}
+
+	var rawClusterTemplates rawClusterTemplates
+	err := sqlStore.selectBuilder(sqlStore.db, &rawClusterTemplates, builder)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query for cluster templates")
+	}
+
+	return rawClusterTemplates.toClusterTemplates()
+}
+
+// CreateClusterTemplate records the given cluster template to the database,
+// assigning it a unique ID.
+func (sqlStore *SQLStore) CreateClusterTemplate(clusterTemplate *model.ClusterTemplate) error {
+	clusterTemplate.ID = model.NewID()
+	clusterTemplate.CreateAt = model.GetMillis()
+
+	clusterRaw, err := json.Marshal(clusterTemplate.Cluster)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cluster template parameters")
+	}
+
+	_, err = sqlStore.execBuilder(sqlStore.db, sq.
+		Insert("ClusterTemplate").
+		SetMap(map[string]interface{}{
+			"ID":          clusterTemplate.ID,
+			"Name":        clusterTemplate.Name,
+			"Description": clusterTemplate.Description,
+			"ClusterRaw":  clusterRaw,
+			"CreateAt":    clusterTemplate.CreateAt,
+			"DeleteAt":    0,
+		}),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster template")
+	}
+
+	return nil
+}
+
+// UpdateClusterTemplate updates the given cluster template in the database.
+func (sqlStore *SQLStore) UpdateClusterTemplate(clusterTemplate *model.ClusterTemplate) error {
+	clusterRaw, err := json.Marshal(clusterTemplate.Cluster)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal cluster template parameters")
+	}
+
+	_, err = sqlStore.execBuilder(sqlStore.db, sq.
+		Update("ClusterTemplate").
+		SetMap(map[string]interface{}{
+			"Name":        clusterTemplate.Name,
+			"Description": clusterTemplate.Description,
+			"ClusterRaw":  clusterRaw,
+		}).
+		Where("ID = ?", clusterTemplate.ID),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update cluster template")
+	}
+
+	return nil
+}
+
+// DeleteClusterTemplate marks the given cluster template as deleted, but does
+// not remove the record from the database.
+func (sqlStore *SQLStore) DeleteClusterTemplate(id string) error {
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Update("ClusterTemplate").
+		Set("DeleteAt", model.GetMillis()).
+		Where("ID = ?", id).
+		Where("DeleteAt = 0"),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark cluster template as deleted")
+	}
+
+	return nil
+}
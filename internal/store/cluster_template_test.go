@@ -0,0 +1,108 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterTemplates(t *testing.T) {
+	t.Run("get unknown cluster template", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := MakeTestSQLStore(t, logger)
+		defer CloseConnection(t, sqlStore)
+
+		clusterTemplate, err := sqlStore.GetClusterTemplate("unknown")
+		require.NoError(t, err)
+		require.Nil(t, clusterTemplate)
+	})
+
+	t.Run("create, get, update and delete cluster templates", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := MakeTestSQLStore(t, logger)
+		defer CloseConnection(t, sqlStore)
+
+		template1 := &model.ClusterTemplate{
+			Name:        "template1",
+			Description: "first template",
+			Cluster: &model.CreateClusterRequest{
+				Zones:            []string{"us-east-1a", "us-east-1b"},
+				Version:          "1.21.4",
+				NodeInstanceType: "m5.xlarge",
+				DesiredUtilityVersions: map[string]*model.HelmUtilityVersion{
+					model.NginxCanonicalName: {Chart: "4.0.0", ValuesPath: "values.yaml"},
+				},
+			},
+		}
+		template2 := &model.ClusterTemplate{
+			Name:    "template2",
+			Cluster: &model.CreateClusterRequest{Networking: model.NetworkingAmazon},
+		}
+
+		err := sqlStore.CreateClusterTemplate(template1)
+		require.NoError(t, err)
+		require.NotEmpty(t, template1.ID)
+
+		time.Sleep(1 * time.Millisecond)
+
+		err = sqlStore.CreateClusterTemplate(template2)
+		require.NoError(t, err)
+
+		actualTemplate1, err := sqlStore.GetClusterTemplate(template1.ID)
+		require.NoError(t, err)
+		require.Equal(t, template1, actualTemplate1)
+
+		actualTemplates, err := sqlStore.GetClusterTemplates(&model.ClusterTemplateFilter{Paging: model.AllPagesNotDeleted()})
+		require.NoError(t, err)
+		require.Equal(t, []*model.ClusterTemplate{template1, template2}, actualTemplates)
+
+		actualTemplates, err = sqlStore.GetClusterTemplates(&model.ClusterTemplateFilter{Paging: model.AllPagesNotDeleted(), Name: "template2"})
+		require.NoError(t, err)
+		require.Equal(t, []*model.ClusterTemplate{template2}, actualTemplates)
+
+		template1.Description = "updated"
+		template1.Cluster.Version = "1.22.0"
+		err = sqlStore.UpdateClusterTemplate(template1)
+		require.NoError(t, err)
+
+		actualTemplate1, err = sqlStore.GetClusterTemplate(template1.ID)
+		require.NoError(t, err)
+		require.Equal(t, template1, actualTemplate1)
+
+		err = sqlStore.DeleteClusterTemplate(template1.ID)
+		require.NoError(t, err)
+
+		actualTemplate1, err = sqlStore.GetClusterTemplate(template1.ID)
+		require.NoError(t, err)
+		require.True(t, actualTemplate1.IsDeleted())
+
+		actualTemplates, err = sqlStore.GetClusterTemplates(&model.ClusterTemplateFilter{Paging: model.AllPagesNotDeleted()})
+		require.NoError(t, err)
+		require.Equal(t, []*model.ClusterTemplate{template2}, actualTemplates)
+
+		actualTemplates, err = sqlStore.GetClusterTemplates(&model.ClusterTemplateFilter{Paging: model.AllPagesWithDeleted()})
+		require.NoError(t, err)
+		require.Len(t, actualTemplates, 2)
+	})
+
+	t.Run("cluster template ID is stored on the cluster", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := MakeTestSQLStore(t, logger)
+		defer CloseConnection(t, sqlStore)
+
+		cluster := &model.Cluster{ClusterTemplateID: model.NewID()}
+		err := sqlStore.CreateCluster(cluster, nil)
+		require.NoError(t, err)
+
+		actualCluster, err := sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		require.Equal(t, cluster.ClusterTemplateID, actualCluster.ClusterTemplateID)
+	})
+}
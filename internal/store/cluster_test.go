@@ -10,6 +10,7 @@ import (
 
 	"github.com/mattermost/mattermost-cloud/internal/testlib"
 	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -142,6 +143,68 @@ func TestClusters(t *testing.T) {
 		require.Equal(t, cluster2, actualCluster2)
 	})
 
+	t.Run("eks cluster metadata", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := MakeTestSQLStore(t, logger)
+		defer CloseConnection(t, sqlStore)
+
+		cluster := &model.Cluster{
+			Provider:            "aws",
+			Provisioner:         model.ProvisionerEKS,
+			ProviderMetadataAWS: &model.AWSMetadata{Zones: []string{"zone1"}},
+			ProvisionerMetadataEKS: &model.EKSMetadata{
+				Name:             "cluster-eks",
+				Version:          "1.21",
+				NodeInstanceType: "m5.large",
+				NodeMinCount:     2,
+				NodeMaxCount:     2,
+				ChangeRequest:    &model.EKSMetadataRequestedState{Version: "1.22"},
+			},
+			UtilityMetadata: &model.UtilityMetadata{},
+			State:           model.ClusterStateCreationRequested,
+		}
+
+		err := sqlStore.CreateCluster(cluster, nil)
+		require.NoError(t, err)
+
+		actualCluster, err := sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		require.Equal(t, cluster, actualCluster)
+		require.Nil(t, actualCluster.ProvisionerMetadataKops)
+	})
+
+	t.Run("upgrade report", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := MakeTestSQLStore(t, logger)
+		defer CloseConnection(t, sqlStore)
+
+		cluster := &model.Cluster{
+			Provider:                "aws",
+			Provisioner:             "kops",
+			ProviderMetadataAWS:     &model.AWSMetadata{Zones: []string{"zone1"}},
+			ProvisionerMetadataKops: &model.KopsMetadata{Version: "1.21.5"},
+			UtilityMetadata:         &model.UtilityMetadata{},
+			State:                   model.ClusterStateUpgradeRequested,
+		}
+
+		err := sqlStore.CreateCluster(cluster, nil)
+		require.NoError(t, err)
+
+		actualCluster, err := sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		require.Nil(t, actualCluster.UpgradeReport)
+
+		cluster.UpgradeReport = model.NewClusterUpgradeReport("1.21.5", "1.22.4")
+		cluster.UpgradeReport.AddPreflightCheck(model.ClusterUpgradeCheckPodDisruptionBudgets, []string{"blocking"})
+		cluster.UpgradeReport.Complete(model.ClusterUpgradeStatusPreflightFailed, errors.New("pre-flight checks failed"))
+		err = sqlStore.UpdateCluster(cluster)
+		require.NoError(t, err)
+
+		actualCluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		require.Equal(t, cluster.UpgradeReport, actualCluster.UpgradeReport)
+	})
+
 	t.Run("delete cluster", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
 		sqlStore := MakeTestSQLStore(t, logger)
@@ -35,6 +35,9 @@ func (sqlStore *SQLStore) GetOrCreateProxyDatabaseResourcesForInstallation(insta
 	if multitenantDatabase.DeleteAt > 0 {
 		return nil, errors.Errorf("multitenant database %s has been deleted", multitenantDatabaseID)
 	}
+	if multitenantDatabase.IsUpgrading() {
+		return nil, errors.Errorf("multitenant database %s is being upgraded", multitenantDatabaseID)
+	}
 
 	logicalDatabases, err := sqlStore.GetLogicalDatabases(&model.LogicalDatabaseFilter{
 		MultitenantDatabaseID: multitenantDatabaseID,
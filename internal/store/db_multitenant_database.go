@@ -29,6 +29,8 @@ func init() {
 			"InstallationsRaw",
 			"MigratedInstallationsRaw",
 			"MaxInstallationsPerLogicalDatabase",
+			"TargetEngineVersion",
+			"UpgradeSnapshotID",
 			"WriterEndpoint",
 			"ReaderEndpoint",
 			"CreateAt",
@@ -115,6 +117,9 @@ func (sqlStore *SQLStore) GetMultitenantDatabases(filter *model.MultitenantDatab
 	if len(filter.DatabaseType) > 0 {
 		builder = builder.Where(sq.Eq{"DatabaseType": filter.DatabaseType})
 	}
+	if len(filter.States) > 0 {
+		builder = builder.Where(sq.Eq{"State": filter.States})
+	}
 
 	var rawDatabases rawMultitenantDatabases
 
@@ -189,6 +194,8 @@ func (sqlStore *SQLStore) CreateMultitenantDatabase(multitenantDatabase *model.M
 			"InstallationsRaw":                   installationsJSON,
 			"MigratedInstallationsRaw":           migratedInstallationsJSON,
 			"MaxInstallationsPerLogicalDatabase": multitenantDatabase.MaxInstallationsPerLogicalDatabase,
+			"TargetEngineVersion":                multitenantDatabase.TargetEngineVersion,
+			"UpgradeSnapshotID":                  multitenantDatabase.UpgradeSnapshotID,
 			"WriterEndpoint":                     multitenantDatabase.WriterEndpoint,
 			"ReaderEndpoint":                     multitenantDatabase.ReaderEndpoint,
 			"LockAcquiredBy":                     nil,
@@ -224,8 +231,12 @@ func (sqlStore *SQLStore) updateMultitenantDatabase(db execer, multitenantDataba
 		SetMap(map[string]interface{}{
 			"State":                              multitenantDatabase.State,
 			"MaxInstallationsPerLogicalDatabase": multitenantDatabase.MaxInstallationsPerLogicalDatabase,
+			"TargetEngineVersion":                multitenantDatabase.TargetEngineVersion,
+			"UpgradeSnapshotID":                  multitenantDatabase.UpgradeSnapshotID,
 			"InstallationsRaw":                   []byte(installationsJSON),
 			"MigratedInstallationsRaw":           []byte(migratedInstallationsJSON),
+			"WriterEndpoint":                     multitenantDatabase.WriterEndpoint,
+			"ReaderEndpoint":                     multitenantDatabase.ReaderEndpoint,
 		}).
 		Where(sq.Eq{"ID": multitenantDatabase.ID}),
 	)
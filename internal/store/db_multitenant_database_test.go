@@ -216,6 +216,8 @@ func (s *TestMultitenantDatabaseSuite) TestUpdate() {
 	s.Assert().True(locked)
 
 	s.database1.Installations = model.MultitenantDatabaseInstallations{model.NewID()}
+	s.database1.WriterEndpoint = "writer.example.com"
+	s.database1.ReaderEndpoint = "reader.example.com"
 	s.database1.LockAcquiredBy = &s.lockerID
 
 	err = s.sqlStore.UpdateMultitenantDatabase(s.database1)
@@ -225,6 +227,33 @@ func (s *TestMultitenantDatabaseSuite) TestUpdate() {
 	s.Assert().NoError(err)
 	s.Assert().NotNil(database)
 	s.Assert().Equal(s.database1.Installations, database.Installations)
+	s.Assert().Equal("writer.example.com", database.WriterEndpoint)
+	s.Assert().Equal("reader.example.com", database.ReaderEndpoint)
+}
+
+func (s *TestMultitenantDatabaseSuite) TestUpgradeFields() {
+	s.database1.State = model.DatabaseStateUpgradeSnapshotInProgress
+	s.database1.TargetEngineVersion = "13.7"
+	s.database1.UpgradeSnapshotID = "database-id0-upgrade-1"
+
+	err := s.sqlStore.UpdateMultitenantDatabase(s.database1)
+	s.Assert().NoError(err)
+
+	database, err := s.sqlStore.GetMultitenantDatabase(s.database1.ID)
+	s.Assert().NoError(err)
+	s.Assert().NotNil(database)
+	s.Assert().Equal(model.DatabaseStateUpgradeSnapshotInProgress, database.State)
+	s.Assert().Equal("13.7", database.TargetEngineVersion)
+	s.Assert().Equal("database-id0-upgrade-1", database.UpgradeSnapshotID)
+
+	databases, err := s.sqlStore.GetMultitenantDatabases(&model.MultitenantDatabaseFilter{
+		States:                model.AllMultitenantDatabaseUpgradeStatesPendingWork,
+		MaxInstallationsLimit: model.NoInstallationsLimit,
+		Paging:                model.AllPagesNotDeleted(),
+	})
+	s.Assert().NoError(err)
+	s.Require().Len(databases, 1)
+	s.Assert().Equal(s.database1.ID, databases[0].ID)
 }
 
 func (s *TestMultitenantDatabaseSuite) TestGetMultitenantDatabaseForInstallationID() {
@@ -21,8 +21,8 @@ func init() {
 		Select(
 			"Installation.ID", "OwnerID", "Version", "Image", "DNS", "Database", "Filestore", "Size",
 			"Affinity", "GroupID", "GroupSequence", "State", "License",
-			"MattermostEnvRaw", "PriorityEnvRaw", "SingleTenantDatabaseConfigRaw", "CreateAt", "DeleteAt",
-			"APISecurityLock", "LockAcquiredBy", "LockAcquiredAt", "CRVersion",
+			"MattermostEnvRaw", "PriorityEnvRaw", "SingleTenantDatabaseConfigRaw", "PlacementRaw", "DatabaseMetricsRaw", "DatabasePoolConfigRaw", "CreateAt", "DeleteAt",
+			"DatabaseCredentialsRotatedAt", "FilestoreAccessKeyRotatedAt", "APISecurityLock", "LockAcquiredBy", "LockAcquiredAt", "CRVersion",
 		).
 		From("Installation")
 }
@@ -32,6 +32,9 @@ type rawInstallation struct {
 	MattermostEnvRaw              []byte
 	PriorityEnvRaw                []byte
 	SingleTenantDatabaseConfigRaw []byte
+	PlacementRaw                  []byte
+	DatabaseMetricsRaw            []byte
+	DatabasePoolConfigRaw         []byte
 }
 
 type rawInstallations []*rawInstallation
@@ -66,6 +69,33 @@ func (r *rawInstallation) toInstallation() (*model.Installation, error) {
 		r.Installation.SingleTenantDatabaseConfig = singleTenantDBConfig
 	}
 
+	if r.PlacementRaw != nil {
+		placement := &model.InstallationPlacement{}
+		err = json.Unmarshal(r.PlacementRaw, placement)
+		if err != nil {
+			return nil, err
+		}
+		r.Installation.Placement = placement
+	}
+
+	if r.DatabaseMetricsRaw != nil {
+		databaseMetrics := &model.InstallationDatabaseMetrics{}
+		err = json.Unmarshal(r.DatabaseMetricsRaw, databaseMetrics)
+		if err != nil {
+			return nil, err
+		}
+		r.Installation.DatabaseMetrics = databaseMetrics
+	}
+
+	if r.DatabasePoolConfigRaw != nil {
+		databasePoolConfig := &model.InstallationDatabasePoolConfig{}
+		err = json.Unmarshal(r.DatabasePoolConfigRaw, databasePoolConfig)
+		if err != nil {
+			return nil, err
+		}
+		r.Installation.DatabasePoolConfig = databasePoolConfig
+	}
+
 	return r.Installation, nil
 }
 
@@ -381,6 +411,22 @@ func (sqlStore *SQLStore) createInstallation(db execer, installation *model.Inst
 		insertsMap["SingleTenantDatabaseConfigRaw"] = singleTenantDBConfJSON
 	}
 
+	placementJSON, err := installation.Placement.ToJSON()
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal Placement")
+	}
+	if placementJSON != nil {
+		insertsMap["PlacementRaw"] = placementJSON
+	}
+
+	databasePoolConfigJSON, err := installation.DatabasePoolConfig.ToJSON()
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal DatabasePoolConfig")
+	}
+	if databasePoolConfigJSON != nil {
+		insertsMap["DatabasePoolConfigRaw"] = databasePoolConfigJSON
+	}
+
 	_, err = sqlStore.execBuilder(db, sq.
 		Insert("Installation").
 		SetMap(insertsMap),
@@ -411,24 +457,37 @@ func (sqlStore *SQLStore) updateInstallation(db execer, installation *model.Inst
 		return errors.Wrap(err, "unable to marshal PriorityEnv")
 	}
 
+	// For Postgres we cannot set typed nil as it is not mapped to NULL value.
+	var databasePoolConfigRaw interface{}
+	if installation.DatabasePoolConfig != nil {
+		databasePoolConfigJSON, err := installation.DatabasePoolConfig.ToJSON()
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal DatabasePoolConfig")
+		}
+		databasePoolConfigRaw = databasePoolConfigJSON
+	}
+
 	_, err = sqlStore.execBuilder(db, sq.
 		Update("Installation").
 		SetMap(map[string]interface{}{
-			"OwnerID":          installation.OwnerID,
-			"GroupID":          installation.GroupID,
-			"GroupSequence":    installation.GroupSequence,
-			"Version":          installation.Version,
-			"Image":            installation.Image,
-			"DNS":              installation.DNS,
-			"Database":         installation.Database,
-			"Filestore":        installation.Filestore,
-			"Size":             installation.Size,
-			"Affinity":         installation.Affinity,
-			"License":          installation.License,
-			"MattermostEnvRaw": []byte(envJSON),
-			"PriorityEnvRaw":   []byte(priorityEnvJSON),
-			"State":            installation.State,
-			"CRVersion":        installation.CRVersion,
+			"OwnerID":                      installation.OwnerID,
+			"GroupID":                      installation.GroupID,
+			"GroupSequence":                installation.GroupSequence,
+			"Version":                      installation.Version,
+			"Image":                        installation.Image,
+			"DNS":                          installation.DNS,
+			"Database":                     installation.Database,
+			"Filestore":                    installation.Filestore,
+			"Size":                         installation.Size,
+			"Affinity":                     installation.Affinity,
+			"License":                      installation.License,
+			"MattermostEnvRaw":             []byte(envJSON),
+			"PriorityEnvRaw":               []byte(priorityEnvJSON),
+			"DatabasePoolConfigRaw":        databasePoolConfigRaw,
+			"State":                        installation.State,
+			"CRVersion":                    installation.CRVersion,
+			"DatabaseCredentialsRotatedAt": installation.DatabaseCredentialsRotatedAt,
+			"FilestoreAccessKeyRotatedAt":  installation.FilestoreAccessKeyRotatedAt,
 		}).
 		Where("ID = ?", installation.ID),
 	)
@@ -493,6 +552,67 @@ func (sqlStore *SQLStore) UpdateInstallationCRVersion(installationID, crVersion
 	return nil
 }
 
+// UpdateInstallationDatabaseCredentialsRotatedAt updates the time the
+// database credentials of the given installation were last rotated.
+func (sqlStore *SQLStore) UpdateInstallationDatabaseCredentialsRotatedAt(installationID string, rotatedAt int64) error {
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Update("Installation").
+		SetMap(map[string]interface{}{
+			"DatabaseCredentialsRotatedAt": rotatedAt,
+		}).
+		Where("ID = ?", installationID),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update installation database credentials rotation time")
+	}
+
+	return nil
+}
+
+// UpdateInstallationFilestoreAccessKeyRotatedAt updates the time the
+// filestore access key of the given installation was last rotated.
+func (sqlStore *SQLStore) UpdateInstallationFilestoreAccessKeyRotatedAt(installationID string, rotatedAt int64) error {
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Update("Installation").
+		SetMap(map[string]interface{}{
+			"FilestoreAccessKeyRotatedAt": rotatedAt,
+		}).
+		Where("ID = ?", installationID),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update installation filestore access key rotation time")
+	}
+
+	return nil
+}
+
+// UpdateInstallationDatabaseMetrics updates the observed database metrics of
+// the given installation.
+func (sqlStore *SQLStore) UpdateInstallationDatabaseMetrics(installationID string, metrics *model.InstallationDatabaseMetrics) error {
+	// For Postgres we cannot set typed nil as it is not mapped to NULL value.
+	var metricsRaw interface{}
+	if metrics != nil {
+		metricsJSON, err := metrics.ToJSON()
+		if err != nil {
+			return errors.Wrap(err, "unable to marshal DatabaseMetrics")
+		}
+		metricsRaw = metricsJSON
+	}
+
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Update("Installation").
+		SetMap(map[string]interface{}{
+			"DatabaseMetricsRaw": metricsRaw,
+		}).
+		Where("ID = ?", installationID),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update installation database metrics")
+	}
+
+	return nil
+}
+
 // GetInstallationsTotalDatabaseWeight returns the total weight value of the
 // provided installations.
 func (sqlStore *SQLStore) GetInstallationsTotalDatabaseWeight(installationIDs []string) (float64, error) {
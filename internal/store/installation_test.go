@@ -131,6 +131,10 @@ func TestInstallations(t *testing.T) {
 		GroupID:                    &groupID2,
 		State:                      model.InstallationStateCreationRequested,
 		SingleTenantDatabaseConfig: &dbConfig,
+		Placement: &model.InstallationPlacement{
+			RequiredClusterAnnotations: []string{"compliance-hipaa"},
+			OwnerAntiAffinity:          true,
+		},
 	}
 
 	err = sqlStore.CreateInstallation(installation4, nil)
@@ -938,6 +942,56 @@ func TestUpdateInstallationCRVersion(t *testing.T) {
 	assert.Equal(t, storedInstallation.CRVersion, model.V1betaCRVersion)
 }
 
+func TestUpdateInstallationDatabaseMetrics(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	installation1 := &model.Installation{
+		OwnerID:   model.NewID(),
+		Version:   "version",
+		DNS:       "dns1.example.com",
+		Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+		Filestore: model.InstallationFilestoreBifrost,
+		Size:      mmv1alpha1.Size100String,
+		Affinity:  model.InstallationAffinityMultiTenant,
+		State:     model.InstallationStateStable,
+		CRVersion: model.V1betaCRVersion,
+	}
+
+	err := sqlStore.CreateInstallation(installation1, nil)
+	require.NoError(t, err)
+
+	storedInstallation, err := sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, storedInstallation.DatabaseMetrics)
+
+	metrics := &model.InstallationDatabaseMetrics{
+		SizeBytes:        2 * model.DatabaseWeightSizeBytes,
+		Connections:      5,
+		QueriesPerSecond: 1.5,
+		TransactionCount: 1000,
+		CollectAt:        model.GetMillis(),
+	}
+	err = sqlStore.UpdateInstallationDatabaseMetrics(installation1.ID, metrics)
+	require.NoError(t, err)
+
+	storedInstallation, err = sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, metrics, storedInstallation.DatabaseMetrics)
+
+	totalWeight, err := sqlStore.GetInstallationsTotalDatabaseWeight([]string{installation1.ID})
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), totalWeight)
+
+	err = sqlStore.UpdateInstallationDatabaseMetrics(installation1.ID, nil)
+	require.NoError(t, err)
+
+	storedInstallation, err = sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, storedInstallation.DatabaseMetrics)
+}
+
 func TestGetInstallationsTotalDatabaseWeight(t *testing.T) {
 	logger := testlib.MakeLogger(t)
 	sqlStore := MakeTestSQLStore(t, logger)
@@ -1109,3 +1163,103 @@ func createAndCheckInstallation(t *testing.T, store *SQLStore, installation *mod
 	require.NoError(t, err)
 	require.NotEmpty(t, installation.ID)
 }
+
+func TestInstallationDatabasePoolConfig(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	installation1 := &model.Installation{
+		OwnerID:            model.NewID(),
+		Version:            "version",
+		DNS:                "dns1.example.com",
+		Database:           model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer,
+		DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5, MaxConnections: 10},
+		Filestore:          model.InstallationFilestoreBifrost,
+		Size:               mmv1alpha1.Size100String,
+		Affinity:           model.InstallationAffinityMultiTenant,
+		State:              model.InstallationStateStable,
+		CRVersion:          model.V1betaCRVersion,
+	}
+
+	err := sqlStore.CreateInstallation(installation1, nil)
+	require.NoError(t, err)
+
+	storedInstallation, err := sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, installation1.DatabasePoolConfig, storedInstallation.DatabasePoolConfig)
+
+	installation1.DatabasePoolConfig = nil
+	err = sqlStore.UpdateInstallation(installation1)
+	require.NoError(t, err)
+
+	storedInstallation, err = sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, storedInstallation.DatabasePoolConfig)
+}
+
+func TestInstallationDatabaseCredentialsRotatedAt(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	installation1 := &model.Installation{
+		OwnerID:   model.NewID(),
+		Version:   "version",
+		DNS:       "dns1.example.com",
+		Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+		Filestore: model.InstallationFilestoreBifrost,
+		Size:      mmv1alpha1.Size100String,
+		Affinity:  model.InstallationAffinityMultiTenant,
+		State:     model.InstallationStateStable,
+		CRVersion: model.V1betaCRVersion,
+	}
+
+	err := sqlStore.CreateInstallation(installation1, nil)
+	require.NoError(t, err)
+
+	storedInstallation, err := sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), storedInstallation.DatabaseCredentialsRotatedAt)
+
+	rotatedAt := model.GetMillis()
+	err = sqlStore.UpdateInstallationDatabaseCredentialsRotatedAt(installation1.ID, rotatedAt)
+	require.NoError(t, err)
+
+	storedInstallation, err = sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, rotatedAt, storedInstallation.DatabaseCredentialsRotatedAt)
+}
+
+func TestInstallationFilestoreAccessKeyRotatedAt(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	installation1 := &model.Installation{
+		OwnerID:   model.NewID(),
+		Version:   "version",
+		DNS:       "dns1.example.com",
+		Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+		Filestore: model.InstallationFilestoreMultiTenantAwsS3,
+		Size:      mmv1alpha1.Size100String,
+		Affinity:  model.InstallationAffinityMultiTenant,
+		State:     model.InstallationStateStable,
+		CRVersion: model.V1betaCRVersion,
+	}
+
+	err := sqlStore.CreateInstallation(installation1, nil)
+	require.NoError(t, err)
+
+	storedInstallation, err := sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), storedInstallation.FilestoreAccessKeyRotatedAt)
+
+	rotatedAt := model.GetMillis()
+	err = sqlStore.UpdateInstallationFilestoreAccessKeyRotatedAt(installation1.ID, rotatedAt)
+	require.NoError(t, err)
+
+	storedInstallation, err = sqlStore.GetInstallation(installation1.ID, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, rotatedAt, storedInstallation.FilestoreAccessKeyRotatedAt)
+}
@@ -1740,6 +1740,188 @@ var migrations = []migration{
 			return err
 		}
 
+		return nil
+	}},
+	{semver.MustParse("0.34.0"), semver.MustParse("0.35.0"), func(e execer) error {
+		// Add PlacementRaw column to Installation
+		_, err := e.Exec(`
+				ALTER TABLE Installation
+				ADD COLUMN PlacementRaw BYTEA NULL;
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.35.0"), semver.MustParse("0.36.0"), func(e execer) error {
+		// Add SupervisorTask table.
+		_, err := e.Exec(`
+			CREATE TABLE SupervisorTask (
+				ID TEXT PRIMARY KEY,
+				LastRunAt BIGINT NOT NULL,
+				LockAcquiredBy TEXT NULL,
+				LockAcquiredAt BIGINT NOT NULL
+			);
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.36.0"), semver.MustParse("0.37.0"), func(e execer) error {
+		// Add ClusterMigrationOperation table.
+		_, err := e.Exec(`
+			CREATE TABLE ClusterMigrationOperation (
+				ID TEXT PRIMARY KEY,
+				SourceClusterID TEXT NOT NULL,
+				TargetClusterID TEXT NOT NULL,
+				InstallationID TEXT NOT NULL,
+				LockInstallations BOOLEAN NOT NULL,
+				SwitchClusterRoles BOOLEAN NOT NULL,
+				DNSSwitched BOOLEAN NOT NULL,
+				ClusterInstallationsRaw BYTEA NULL,
+				RequestAt BIGINT NOT NULL,
+				State TEXT NOT NULL,
+				CompleteAt BIGINT NOT NULL,
+				DeleteAt BIGINT NOT NULL,
+				LockAcquiredBy TEXT NULL,
+				LockAcquiredAt BIGINT NOT NULL
+			);
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.37.0"), semver.MustParse("0.38.0"), func(e execer) error {
+		// Add ClusterTemplate table.
+		_, err := e.Exec(`
+			CREATE TABLE ClusterTemplate (
+				ID TEXT PRIMARY KEY,
+				Name TEXT NOT NULL,
+				Description TEXT NOT NULL,
+				ClusterRaw BYTEA NOT NULL,
+				CreateAt BIGINT NOT NULL,
+				DeleteAt BIGINT NOT NULL
+			);
+		`)
+		if err != nil {
+			return err
+		}
+
+		_, err = e.Exec(`
+				ALTER TABLE Cluster
+				ADD COLUMN ClusterTemplateID TEXT NOT NULL DEFAULT '';
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.38.0"), semver.MustParse("0.39.0"), func(e execer) error {
+		// Add ClusterSize table.
+		_, err := e.Exec(`
+			CREATE TABLE ClusterSize (
+				ID TEXT PRIMARY KEY,
+				Name TEXT NOT NULL,
+				MasterInstanceType TEXT NOT NULL,
+				MasterCount BIGINT NOT NULL,
+				NodeInstanceType TEXT NOT NULL,
+				NodeMinCount BIGINT NOT NULL,
+				NodeMaxCount BIGINT NOT NULL,
+				AdditionalNodeGroupsRaw BYTEA NULL,
+				CreateAt BIGINT NOT NULL,
+				DeleteAt BIGINT NOT NULL
+			);
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.39.0"), semver.MustParse("0.40.0"), func(e execer) error {
+		// Add UpgradeReportRaw column to Cluster table.
+		_, err := e.Exec(`
+				ALTER TABLE Cluster
+				ADD COLUMN UpgradeReportRaw BYTEA NULL;
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.40.0"), semver.MustParse("0.41.0"), func(e execer) error {
+		// Add DatabaseMetricsRaw column to Installation table.
+		_, err := e.Exec(`
+				ALTER TABLE Installation
+				ADD COLUMN DatabaseMetricsRaw BYTEA NULL;
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.41.0"), semver.MustParse("0.42.0"), func(e execer) error {
+		// Add DatabasePoolConfigRaw column to Installation table.
+		_, err := e.Exec(`
+				ALTER TABLE Installation
+				ADD COLUMN DatabasePoolConfigRaw BYTEA NULL;
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.42.0"), semver.MustParse("0.43.0"), func(e execer) error {
+		// Add DatabaseCredentialsRotatedAt column to Installation table.
+		_, err := e.Exec(`
+				ALTER TABLE Installation
+				ADD COLUMN DatabaseCredentialsRotatedAt BIGINT NOT NULL DEFAULT '0';
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.43.0"), semver.MustParse("0.44.0"), func(e execer) error {
+		// Add FilestoreAccessKeyRotatedAt column to Installation table.
+		_, err := e.Exec(`
+				ALTER TABLE Installation
+				ADD COLUMN FilestoreAccessKeyRotatedAt BIGINT NOT NULL DEFAULT '0';
+		`)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}},
+	{semver.MustParse("0.44.0"), semver.MustParse("0.45.0"), func(e execer) error {
+		// Add engine version upgrade columns to MultitenantDatabase table.
+		_, err := e.Exec(`
+				ALTER TABLE MultitenantDatabase
+				ADD COLUMN TargetEngineVersion TEXT NOT NULL DEFAULT '';
+		`)
+		if err != nil {
+			return err
+		}
+
+		_, err = e.Exec(`
+				ALTER TABLE MultitenantDatabase
+				ADD COLUMN UpgradeSnapshotID TEXT NOT NULL DEFAULT '';
+		`)
+		if err != nil {
+			return err
+		}
+
 		return nil
 	}},
 }
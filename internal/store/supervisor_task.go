@@ -0,0 +1,77 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+)
+
+const supervisorTaskTable = "SupervisorTask"
+
+var supervisorTaskSelect sq.SelectBuilder
+
+func init() {
+	supervisorTaskSelect = sq.
+		Select("ID", "LastRunAt", "LockAcquiredBy", "LockAcquiredAt").
+		From(supervisorTaskTable)
+}
+
+// GetSupervisorTask fetches the given supervisor task. Nil is returned if
+// the task was never locked.
+func (sqlStore *SQLStore) GetSupervisorTask(id string) (*model.SupervisorTask, error) {
+	var task model.SupervisorTask
+	err := sqlStore.getBuilder(sqlStore.db, &task, supervisorTaskSelect.Where("ID = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get supervisor task")
+	}
+
+	return &task, nil
+}
+
+// UpdateSupervisorTaskLastRunAt sets the time the given supervisor task was
+// last started.
+func (sqlStore *SQLStore) UpdateSupervisorTaskLastRunAt(id string, lastRunAt int64) error {
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Update(supervisorTaskTable).
+		Set("LastRunAt", lastRunAt).
+		Where("ID = ?", id),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update supervisor task last run")
+	}
+
+	return nil
+}
+
+// LockSupervisorTask marks the supervisor task as locked for exclusive use by
+// the caller. The task is recorded first if it does not exist yet.
+func (sqlStore *SQLStore) LockSupervisorTask(id, lockerID string) (bool, error) {
+	_, err := sqlStore.execBuilder(sqlStore.db, sq.
+		Insert(supervisorTaskTable).
+		SetMap(map[string]interface{}{
+			"ID":             id,
+			"LastRunAt":      0,
+			"LockAcquiredBy": nil,
+			"LockAcquiredAt": 0,
+		}).
+		Suffix("ON CONFLICT DO NOTHING"),
+	)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to create supervisor task")
+	}
+
+	return sqlStore.lockRows(supervisorTaskTable, []string{id}, lockerID)
+}
+
+// UnlockSupervisorTask releases a lock previously acquired against a caller.
+func (sqlStore *SQLStore) UnlockSupervisorTask(id, lockerID string, force bool) (bool, error) {
+	return sqlStore.unlockRows(supervisorTaskTable, []string{id}, lockerID, force)
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package store
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisorTask(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := MakeTestSQLStore(t, logger)
+	defer CloseConnection(t, sqlStore)
+
+	task, err := sqlStore.GetSupervisorTask("task")
+	require.NoError(t, err)
+	assert.Nil(t, task)
+
+	locked, err := sqlStore.LockSupervisorTask("task", "locker1")
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	locked, err = sqlStore.LockSupervisorTask("task", "locker2")
+	require.NoError(t, err)
+	assert.False(t, locked)
+
+	err = sqlStore.UpdateSupervisorTaskLastRunAt("task", 100)
+	require.NoError(t, err)
+
+	task, err = sqlStore.GetSupervisorTask("task")
+	require.NoError(t, err)
+	require.NotNil(t, task)
+	assert.Equal(t, int64(100), task.LastRunAt)
+	require.NotNil(t, task.LockAcquiredBy)
+	assert.Equal(t, "locker1", *task.LockAcquiredBy)
+
+	unlocked, err := sqlStore.UnlockSupervisorTask("task", "locker1", false)
+	require.NoError(t, err)
+	assert.True(t, unlocked)
+
+	locked, err = sqlStore.LockSupervisorTask("task", "locker2")
+	require.NoError(t, err)
+	assert.True(t, locked)
+
+	task, err = sqlStore.GetSupervisorTask("task")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), task.LastRunAt)
+}
@@ -6,6 +6,7 @@ package supervisor
 
 import (
 	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/k8s"
 	"github.com/mattermost/mattermost-cloud/model"
 	log "github.com/sirupsen/logrus"
 )
@@ -20,6 +21,9 @@ type clusterStore interface {
 	UnlockCluster(clusterID string, lockerID string, force bool) (bool, error)
 	DeleteCluster(clusterID string) error
 
+	GetClusterInstallations(filter *model.ClusterInstallationFilter) ([]*model.ClusterInstallation, error)
+	GetInstallations(installationFilter *model.InstallationFilter, includeGroupConfig, includeGroupConfigOverrides bool) ([]*model.Installation, error)
+
 	GetWebhooks(filter *model.WebhookFilter) ([]*model.Webhook, error)
 }
 
@@ -29,9 +33,13 @@ type clusterProvisioner interface {
 	CreateCluster(cluster *model.Cluster, aws aws.AWS) error
 	ProvisionCluster(cluster *model.Cluster, aws aws.AWS) error
 	UpgradeCluster(cluster *model.Cluster, aws aws.AWS) error
+	UpgradeClusterUtility(cluster *model.Cluster, utilityName string, aws aws.AWS) error
 	ResizeCluster(cluster *model.Cluster, aws aws.AWS) error
 	DeleteCluster(cluster *model.Cluster, aws aws.AWS) error
 	RefreshKopsMetadata(cluster *model.Cluster) error
+	GetClusterResources(cluster *model.Cluster, onlySchedulable bool, logger log.FieldLogger) (*k8s.ClusterResources, error)
+	GetSpotNodes(cluster *model.Cluster, logger log.FieldLogger) ([]model.ClusterNode, error)
+	DrainClusterNode(cluster *model.Cluster, nodeName string, logger log.FieldLogger) ([]string, error)
 }
 
 // ClusterSupervisor finds clusters pending work and effects the required changes.
@@ -43,19 +51,24 @@ type ClusterSupervisor struct {
 	provisioner    clusterProvisioner
 	aws            aws.AWS
 	eventsProducer eventProducer
-	instanceID     string
-	logger         log.FieldLogger
+	autoscaling    ClusterAutoscalingOptions
+	// drainedSpotNodes are the names of the drained spot nodes by cluster ID.
+	drainedSpotNodes map[string]map[string]struct{}
+	instanceID       string
+	logger           log.FieldLogger
 }
 
 // NewClusterSupervisor creates a new ClusterSupervisor.
-func NewClusterSupervisor(store clusterStore, clusterProvisioner clusterProvisioner, aws aws.AWS, eventProducer eventProducer, instanceID string, logger log.FieldLogger) *ClusterSupervisor {
+func NewClusterSupervisor(store clusterStore, clusterProvisioner clusterProvisioner, aws aws.AWS, eventProducer eventProducer, autoscaling ClusterAutoscalingOptions, instanceID string, logger log.FieldLogger) *ClusterSupervisor {
 	return &ClusterSupervisor{
-		store:          store,
-		provisioner:    clusterProvisioner,
-		aws:            aws,
-		eventsProducer: eventProducer,
-		instanceID:     instanceID,
-		logger:         logger,
+		store:            store,
+		provisioner:      clusterProvisioner,
+		aws:              aws,
+		eventsProducer:   eventProducer,
+		autoscaling:      autoscaling,
+		drainedSpotNodes: make(map[string]map[string]struct{}),
+		instanceID:       instanceID,
+		logger:           logger,
 	}
 }
 
@@ -76,6 +89,11 @@ func (s *ClusterSupervisor) Do() error {
 		s.Supervise(cluster)
 	}
 
+	if s.autoscaling.Enabled {
+		s.autoscaleClusters()
+	}
+	s.drainTerminatingSpotNodes()
+
 	return nil
 }
 
@@ -145,6 +163,8 @@ func (s *ClusterSupervisor) transitionCluster(cluster *model.Cluster, logger log
 		return s.provisionCluster(cluster, logger)
 	case model.ClusterStateUpgradeRequested:
 		return s.upgradeCluster(cluster, logger)
+	case model.ClusterStateUtilityUpgradeRequested:
+		return s.upgradeClusterUtilities(cluster, logger)
 	case model.ClusterStateResizeRequested:
 		return s.resizeCluster(cluster, logger)
 	case model.ClusterStateRefreshMetadata:
@@ -206,6 +226,31 @@ func (s *ClusterSupervisor) upgradeCluster(cluster *model.Cluster, logger log.Fi
 	return s.refreshClusterMetadata(cluster, logger)
 }
 
+func (s *ClusterSupervisor) upgradeClusterUtilities(cluster *model.Cluster, logger log.FieldLogger) string {
+	for _, utilityName := range cluster.PendingUtilityUpgrades() {
+		utilityLogger := logger.WithField("utility", utilityName)
+
+		err := s.provisioner.UpgradeClusterUtility(cluster, utilityName, s.aws)
+		if err != nil {
+			utilityLogger.WithError(err).Error("Failed to upgrade cluster utility")
+			upgrade := cluster.GetUtilityUpgrade(utilityName)
+			if upgrade.State == model.UtilityUpgradeStateRequested {
+				upgrade.Complete(model.UtilityUpgradeStateFailed, err)
+			}
+			err = s.store.UpdateCluster(cluster)
+			if err != nil {
+				utilityLogger.WithError(err).Error("Failed to save updated cluster metadata")
+				return model.ClusterStateRefreshMetadata
+			}
+			return model.ClusterStateUtilityUpgradeFailed
+		}
+
+		utilityLogger.Info("Finished upgrading cluster utility")
+	}
+
+	return s.refreshClusterMetadata(cluster, logger)
+}
+
 func (s *ClusterSupervisor) resizeCluster(cluster *model.Cluster, logger log.FieldLogger) string {
 	err := s.provisioner.ResizeCluster(cluster, s.aws)
 	if err != nil {
@@ -214,6 +259,10 @@ func (s *ClusterSupervisor) resizeCluster(cluster *model.Cluster, logger log.Fie
 	}
 
 	logger.Info("Finished resizing cluster")
+	if cluster.ProvisionerMetadataKops != nil {
+		cluster.ProvisionerMetadataKops.ApplyWorkerNodeCountChangeRequest()
+	}
+
 	return s.refreshClusterMetadata(cluster, logger)
 }
 
@@ -223,6 +272,9 @@ func (s *ClusterSupervisor) refreshClusterMetadata(cluster *model.Cluster, logge
 		cluster.ProvisionerMetadataKops.ClearRotatorRequest()
 		cluster.ProvisionerMetadataKops.ClearWarnings()
 	}
+	if cluster.ProvisionerMetadataEKS != nil {
+		cluster.ProvisionerMetadataEKS.ClearChangeRequest()
+	}
 
 	err := s.provisioner.RefreshKopsMetadata(cluster)
 	if err != nil {
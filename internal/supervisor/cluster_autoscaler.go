@@ -0,0 +1,293 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/events"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	autoscalingDecisionScaleUp   = "scale-up"
+	autoscalingDecisionScaleDown = "scale-down"
+)
+
+// ClusterAutoscalingOptions are the various options that control how the
+// cluster supervisor scales cluster worker nodes.
+type ClusterAutoscalingOptions struct {
+	// Enabled controls if the cluster supervisor scales stable clusters.
+	Enabled bool
+	// ScaleUpThreshold is the CPU or memory percentage of requested resources
+	// of scheduled installations above which worker nodes are added. It is
+	// also the target utilization when calculating the new node count.
+	ScaleUpThreshold int
+	// ScaleDownThreshold is the CPU and memory percentage of requested
+	// resources of scheduled installations below which worker nodes are
+	// removed.
+	ScaleDownThreshold int
+	// MinNodeCount is the lowest worker node count a cluster will be scaled
+	// down to.
+	MinNodeCount int64
+	// Cooldown is the minimum time between two scaling decisions of the same
+	// cluster.
+	Cooldown time.Duration
+}
+
+// autoscalingDecision is a worker node count change of a single cluster.
+type autoscalingDecision struct {
+	direction     string
+	currentCount  int64
+	newCount      int64
+	cpuPercent    int
+	memoryPercent int
+}
+
+func (d *autoscalingDecision) reason() string {
+	return fmt.Sprintf("requested installation resources at CPU=%d%%, Memory=%d%% of %d worker nodes", d.cpuPercent, d.memoryPercent, d.currentCount)
+}
+
+// autoscaleClusters checks all stable clusters and requests worker node
+// resizing for the ones where the requested resources of scheduled
+// installations crossed the autoscaling thresholds.
+func (s *ClusterSupervisor) autoscaleClusters() {
+	clusters, err := s.store.GetClusters(&model.ClusterFilter{Paging: model.AllPagesNotDeleted()})
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to query clusters for autoscaling")
+		return
+	}
+
+	for _, cluster := range clusters {
+		if cluster.State != model.ClusterStateStable || cluster.ProvisionerMetadataKops == nil {
+			continue
+		}
+		if s.isAutoscalingCoolingDown(cluster) {
+			continue
+		}
+
+		s.autoscaleCluster(cluster)
+	}
+}
+
+func (s *ClusterSupervisor) autoscaleCluster(cluster *model.Cluster) {
+	logger := s.logger.WithFields(log.Fields{
+		"cluster": cluster.ID,
+		"action":  "autoscale",
+	})
+
+	lock := newClusterLock(cluster.ID, s.instanceID, s.store, logger)
+	if !lock.TryLock() {
+		return
+	}
+	defer lock.Unlock()
+
+	cluster, err := s.store.GetCluster(cluster.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get refreshed cluster")
+		return
+	}
+	if cluster == nil || cluster.State != model.ClusterStateStable || s.isAutoscalingCoolingDown(cluster) {
+		return
+	}
+
+	decision, err := s.getAutoscalingDecision(cluster, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to calculate cluster autoscaling decision")
+		return
+	}
+	if decision == nil {
+		return
+	}
+
+	cluster.State = model.ClusterStateResizeRequested
+	cluster.ProvisionerMetadataKops.ChangeRequest = &model.KopsMetadataRequestedState{
+		NodeDesiredCount: decision.newCount,
+	}
+	cluster.ProvisionerMetadataKops.AutoscaledAt = model.GetMillis()
+
+	logger.Infof("Autoscaling cluster worker nodes from %d to %d (min=%d, max=%d): %s",
+		decision.currentCount,
+		decision.newCount,
+		s.autoscalingMinNodeCount(cluster),
+		cluster.ProvisionerMetadataKops.NodeMaxCount,
+		decision.reason(),
+	)
+
+	err = s.store.UpdateCluster(cluster)
+	if err != nil {
+		logger.WithError(err).Error("Failed to update cluster")
+		return
+	}
+
+	err = s.eventsProducer.ProduceClusterStateChangeEvent(cluster, model.ClusterStateStable,
+		events.DataField{Key: "AutoscalingDecision", Value: decision.direction},
+		events.DataField{Key: "AutoscalingNodeCount", Value: fmt.Sprintf("%d", decision.currentCount)},
+		events.DataField{Key: "AutoscalingNewNodeCount", Value: fmt.Sprintf("%d", decision.newCount)},
+		events.DataField{Key: "AutoscalingReason", Value: decision.reason()},
+	)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create cluster state change event")
+	}
+}
+
+// isAutoscalingCoolingDown returns true if the cluster autoscaler changed the
+// worker node count of the cluster within the cooldown period.
+func (s *ClusterSupervisor) isAutoscalingCoolingDown(cluster *model.Cluster) bool {
+	autoscaledAt := cluster.ProvisionerMetadataKops.AutoscaledAt
+	if autoscaledAt == 0 {
+		return false
+	}
+
+	return time.Since(model.TimeFromMillis(autoscaledAt)) < s.autoscaling.Cooldown
+}
+
+// getAutoscalingDecision calculates the worker node count needed to keep the
+// requested resources of installations scheduled on the cluster below the
+// scale up threshold. A nil decision is returned if no scaling is needed.
+func (s *ClusterSupervisor) getAutoscalingDecision(cluster *model.Cluster, logger log.FieldLogger) (*autoscalingDecision, error) {
+	currentCount := cluster.ProvisionerMetadataKops.GetWorkerNodeCount()
+	if currentCount < 1 {
+		return nil, nil
+	}
+
+	requestedCPU, requestedMemory, err := s.getRequestedInstallationResources(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := s.provisioner.GetClusterResources(cluster, true, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster resources")
+	}
+	if resources.MilliTotalCPU == 0 || resources.MilliTotalMemory == 0 {
+		return nil, errors.New("cluster has no schedulable resources")
+	}
+
+	decision := &autoscalingDecision{
+		currentCount:  currentCount,
+		cpuPercent:    int(requestedCPU * 100 / resources.MilliTotalCPU),
+		memoryPercent: int(requestedMemory * 100 / resources.MilliTotalMemory),
+	}
+
+	// The node count needed for the requested resources to be at the scale up
+	// threshold, assuming all worker nodes provide the same resources.
+	targetCount := requiredNodeCount(requestedCPU, resources.MilliTotalCPU, currentCount, s.autoscaling.ScaleUpThreshold)
+	if count := requiredNodeCount(requestedMemory, resources.MilliTotalMemory, currentCount, s.autoscaling.ScaleUpThreshold); count > targetCount {
+		targetCount = count
+	}
+
+	logger.Debugf("Cluster autoscaling check: CPU=%d%%, Memory=%d%%, workers=%d, target=%d",
+		decision.cpuPercent, decision.memoryPercent, currentCount, targetCount)
+
+	switch {
+	case decision.cpuPercent > s.autoscaling.ScaleUpThreshold || decision.memoryPercent > s.autoscaling.ScaleUpThreshold:
+		maxCount := cluster.ProvisionerMetadataKops.NodeMaxCount
+		if targetCount > maxCount {
+			targetCount = maxCount
+		}
+		if targetCount <= currentCount {
+			logger.Debugf("Cluster is above the autoscaling threshold, but already at the max worker node count (%d)", maxCount)
+			return nil, nil
+		}
+		decision.direction = autoscalingDecisionScaleUp
+	case decision.cpuPercent < s.autoscaling.ScaleDownThreshold && decision.memoryPercent < s.autoscaling.ScaleDownThreshold:
+		minCount := s.autoscalingMinNodeCount(cluster)
+		if targetCount < minCount {
+			targetCount = minCount
+		}
+		if targetCount >= currentCount {
+			return nil, nil
+		}
+		decision.direction = autoscalingDecisionScaleDown
+	default:
+		return nil, nil
+	}
+
+	decision.newCount = targetCount
+
+	return decision, nil
+}
+
+// getRequestedInstallationResources returns the CPU and memory requirements in
+// milli units of all installations scheduled on the cluster. Hibernating
+// installations are not running any pods and are not included.
+func (s *ClusterSupervisor) getRequestedInstallationResources(cluster *model.Cluster) (int64, int64, error) {
+	clusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging:    model.AllPagesNotDeleted(),
+		ClusterID: cluster.ID,
+	})
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to get cluster installations")
+	}
+	if len(clusterInstallations) == 0 {
+		return 0, 0, nil
+	}
+
+	installationIDs := make([]string, 0, len(clusterInstallations))
+	for _, clusterInstallation := range clusterInstallations {
+		installationIDs = append(installationIDs, clusterInstallation.InstallationID)
+	}
+
+	installations, err := s.store.GetInstallations(&model.InstallationFilter{
+		Paging:          model.AllPagesNotDeleted(),
+		InstallationIDs: installationIDs,
+	}, false, false)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to get installations")
+	}
+
+	var totalCPU, totalMemory int64
+	for _, installation := range installations {
+		if installation.State == model.InstallationStateHibernating {
+			continue
+		}
+		cpu, memory, err := installationResourceRequirements(installation)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "failed to calculate resource requirements of installation %s", installation.ID)
+		}
+		totalCPU += cpu
+		totalMemory += memory
+	}
+
+	return totalCPU, totalMemory, nil
+}
+
+// autoscalingMinNodeCount returns the lowest worker node count the cluster can
+// be scaled down to. The cluster node min count is never crossed, and every
+// node instance group keeps at least one node.
+func (s *ClusterSupervisor) autoscalingMinNodeCount(cluster *model.Cluster) int64 {
+	minCount := s.autoscaling.MinNodeCount
+	if cluster.ProvisionerMetadataKops.NodeMinCount > minCount {
+		minCount = cluster.ProvisionerMetadataKops.NodeMinCount
+	}
+	if igCount := int64(len(cluster.ProvisionerMetadataKops.NodeInstanceGroups)); igCount > minCount {
+		minCount = igCount
+	}
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	return minCount
+}
+
+// requiredNodeCount returns the number of nodes needed for the requested
+// resources to stay at or below the threshold percent of the capacity.
+func requiredNodeCount(requested, total, currentCount int64, threshold int) int64 {
+	perNodeCapacity := total * int64(threshold) / 100 / currentCount
+	if perNodeCapacity == 0 {
+		return currentCount
+	}
+
+	count := requested / perNodeCapacity
+	if requested%perNodeCapacity != 0 {
+		count++
+	}
+
+	return count
+}
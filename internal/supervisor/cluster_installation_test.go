@@ -70,6 +70,10 @@ func (s *mockClusterInstallationStore) GetLogicalDatabases(filter *model.Logical
 	return nil, nil
 }
 
+func (s *mockClusterInstallationStore) GetDatabaseSchemas(filter *model.DatabaseSchemaFilter) ([]*model.DatabaseSchema, error) {
+	return nil, nil
+}
+
 func (s *mockClusterInstallationStore) GetWebhooks(filter *model.WebhookFilter) ([]*model.Webhook, error) {
 	return nil, nil
 }
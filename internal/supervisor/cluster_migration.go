@@ -0,0 +1,723 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/internal/webhook"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// clusterMigrationStore abstracts the database operations required by the supervisor.
+type clusterMigrationStore interface {
+	GetUnlockedClusterMigrationOperationsPendingWork() ([]*model.ClusterMigrationOperation, error)
+	GetClusterMigrationOperation(id string) (*model.ClusterMigrationOperation, error)
+	UpdateClusterMigrationOperationState(operation *model.ClusterMigrationOperation) error
+	UpdateClusterMigrationOperation(operation *model.ClusterMigrationOperation) error
+	clusterMigrationOperationLockStore
+
+	GetCluster(id string) (*model.Cluster, error)
+	clusterLockStore
+
+	LockInstallations(installationIDs []string, lockerID string) (bool, error)
+	UnlockInstallations(installationIDs []string, lockerID string, force bool) (bool, error)
+
+	installationSchedulingStore
+	MigrateClusterInstallations(clusterInstallations []*model.ClusterInstallation, targetCluster string) error
+	SwitchDNS(oldCIsIDs, newCIsIDs, installationIDs []string, hibernatingInstallationIDs []string) error
+	clusterInstallationDeletionStore
+
+	GetWebhooks(filter *model.WebhookFilter) ([]*model.Webhook, error)
+}
+
+// ClusterMigrationSupervisor finds pending cluster migrations and effects the
+// required changes.
+//
+// The migration of all installations between two clusters is performed in the
+// following steps, each persisted, so that the migration resumes where it left
+// off after a restart:
+// 1. Inactive cluster installations are created on the target cluster.
+// 2. Wait for target cluster installations to become stable.
+// 3. DNS records are switched to the target cluster.
+// 4. Wait for installations to become stable (or hibernated) again.
+// 5. Inactive source cluster installations are deleted.
+//
+// Until source cluster installations are deleted the migration can be rolled
+// back, which switches DNS back to the source cluster if needed and deletes
+// the target cluster installations.
+type ClusterMigrationSupervisor struct {
+	store          clusterMigrationStore
+	aws            aws.AWS
+	instanceID     string
+	eventsProducer eventProducer
+	logger         log.FieldLogger
+}
+
+// NewClusterMigrationSupervisor creates a new ClusterMigrationSupervisor.
+func NewClusterMigrationSupervisor(
+	store clusterMigrationStore,
+	aws aws.AWS,
+	instanceID string,
+	eventsProducer eventProducer,
+	logger log.FieldLogger) *ClusterMigrationSupervisor {
+	return &ClusterMigrationSupervisor{
+		store:          store,
+		aws:            aws,
+		instanceID:     instanceID,
+		eventsProducer: eventsProducer,
+		logger:         logger,
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the supervisor.
+func (s *ClusterMigrationSupervisor) Shutdown() {
+	s.logger.Debug("Shutting down cluster migration supervisor")
+}
+
+// Do looks for work to be done on any pending cluster migrations and attempts to schedule the required work.
+func (s *ClusterMigrationSupervisor) Do() error {
+	operations, err := s.store.GetUnlockedClusterMigrationOperationsPendingWork()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to query for pending work")
+		return nil
+	}
+
+	for _, operation := range operations {
+		s.Supervise(operation)
+	}
+
+	return nil
+}
+
+// Supervise schedules the required work on the given cluster migration.
+func (s *ClusterMigrationSupervisor) Supervise(operation *model.ClusterMigrationOperation) {
+	logger := s.logger.WithFields(log.Fields{
+		"clusterMigration": operation.ID,
+		"sourceCluster":    operation.SourceClusterID,
+		"targetCluster":    operation.TargetClusterID,
+	})
+
+	lock := newClusterMigrationOperationLock(operation.ID, s.instanceID, s.store, logger)
+	if !lock.TryLock() {
+		return
+	}
+	defer lock.Unlock()
+
+	// Before working on the migration operation, it is crucial that we ensure that it
+	// was not updated to a new state by another provisioning server.
+	originalState := operation.State
+	operation, err := s.store.GetClusterMigrationOperation(operation.ID)
+	if err != nil {
+		logger.WithError(err).Errorf("Failed to get refreshed cluster migration")
+		return
+	}
+	if operation.State != originalState {
+		logger.WithField("oldMigrationState", originalState).
+			WithField("newMigrationState", operation.State).
+			Warn("Another provisioner has worked on this cluster migration; skipping...")
+		return
+	}
+
+	logger.Debugf("Supervising cluster migration in state %s", operation.State)
+
+	newState := s.transitionMigration(operation, s.instanceID, logger)
+
+	operation, err = s.store.GetClusterMigrationOperation(operation.ID)
+	if err != nil {
+		logger.WithError(err).Errorf("Failed to get cluster migration and thus persist state %s", newState)
+		return
+	}
+
+	if operation.State == newState {
+		return
+	}
+
+	oldState := operation.State
+	operation.State = newState
+
+	err = s.store.UpdateClusterMigrationOperationState(operation)
+	if err != nil {
+		logger.WithError(err).Errorf("Failed to set cluster migration state to %s", newState)
+		return
+	}
+
+	webhookPayload := &model.WebhookPayload{
+		Type:      model.TypeClusterMigration,
+		ID:        operation.ID,
+		NewState:  string(operation.State),
+		OldState:  string(oldState),
+		Timestamp: time.Now().UnixNano(),
+		ExtraData: map[string]string{"Environment": s.aws.GetCloudEnvironmentName()},
+	}
+	err = webhook.SendToAllWebhooks(s.store, webhookPayload, logger.WithField("webhookEvent", webhookPayload.NewState))
+	if err != nil {
+		logger.WithError(err).Error("Unable to process and send webhooks")
+	}
+
+	logger.Debugf("Transitioned cluster migration from %s to %s", oldState, operation.State)
+}
+
+// transitionMigration works with the given cluster migration to transition it to a final state.
+func (s *ClusterMigrationSupervisor) transitionMigration(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) model.ClusterMigrationOperationState {
+	switch operation.State {
+	case model.ClusterMigrationStateRequested:
+		return s.createTargetClusterInstallations(operation, instanceID, logger)
+	case model.ClusterMigrationStateWaitingForClusterInstallations:
+		return s.waitForTargetClusterInstallations(operation, instanceID, logger)
+	case model.ClusterMigrationStateSwitchingDNS:
+		return s.switchDNS(operation, instanceID, logger)
+	case model.ClusterMigrationStateWaitingForInstallations:
+		return s.waitForInstallations(operation, instanceID, logger)
+	case model.ClusterMigrationStateCleanup:
+		return s.cleanupSourceClusterInstallations(operation, instanceID, logger)
+	case model.ClusterMigrationStateRollbackRequested:
+		return s.revertMigration(operation, model.ClusterMigrationStateRollbackFinished, instanceID, logger)
+	case model.ClusterMigrationStateFailing:
+		return s.revertMigration(operation, model.ClusterMigrationStateFailed, instanceID, logger)
+	default:
+		logger.Warnf("Found cluster migration pending work in unexpected state %s", operation.State)
+		return operation.State
+	}
+}
+
+func (s *ClusterMigrationSupervisor) createTargetClusterInstallations(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) model.ClusterMigrationOperationState {
+	targetCluster, err := s.store.GetCluster(operation.TargetClusterID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get target cluster")
+		return operation.State
+	}
+	if targetCluster == nil {
+		logger.Error("Target cluster not found")
+		return model.ClusterMigrationStateFailing
+	}
+	if targetCluster.IsEKS() {
+		logger.Errorf("Target cluster %s is an EKS cluster which cannot host installations", targetCluster.ID)
+		return model.ClusterMigrationStateFailing
+	}
+
+	clusterLock := newClusterLock(targetCluster.ID, instanceID, s.store, logger)
+	if !clusterLock.TryLock() {
+		logger.Debugf("Failed to lock target cluster %s", targetCluster.ID)
+		return operation.State
+	}
+	defer clusterLock.Unlock()
+
+	isActive := true
+	sourceClusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging:         model.AllPagesNotDeleted(),
+		ClusterID:      operation.SourceClusterID,
+		InstallationID: operation.InstallationID,
+		IsActive:       &isActive,
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to get source cluster installations")
+		return operation.State
+	}
+	if len(sourceClusterInstallations) == 0 {
+		logger.Error("No cluster installations found to migrate")
+		return model.ClusterMigrationStateFailing
+	}
+
+	// Target cluster installations stored by a previous attempt are kept, so
+	// that they are not lost if this attempt stops early.
+	storedPairs := make(map[string]model.ClusterMigrationClusterInstallation, len(operation.ClusterInstallations))
+	for _, pair := range operation.ClusterInstallations {
+		if pair.TargetClusterInstallationID != "" {
+			storedPairs[pair.SourceClusterInstallationID] = pair
+		}
+	}
+
+	var pairs []model.ClusterMigrationClusterInstallation
+	var created int
+	// Target cluster installations created before returning early are stored
+	// with the operation, so that they are removed when the migration is
+	// reverted.
+	stop := func(state model.ClusterMigrationOperationState) model.ClusterMigrationOperationState {
+		if len(pairs) == 0 {
+			return state
+		}
+		operation.ClusterInstallations = pairs
+		err = s.store.UpdateClusterMigrationOperation(operation)
+		if err != nil {
+			logger.WithError(err).Error("Failed to store cluster installations of cluster migration")
+			return operation.State
+		}
+		return state
+	}
+
+	for _, sourceClusterInstallation := range sourceClusterInstallations {
+		if pair, ok := storedPairs[sourceClusterInstallation.ID]; ok {
+			pairs = append(pairs, pair)
+			continue
+		}
+
+		pair := model.ClusterMigrationClusterInstallation{
+			InstallationID:              sourceClusterInstallation.InstallationID,
+			SourceClusterInstallationID: sourceClusterInstallation.ID,
+		}
+
+		existing, err := s.getTargetClusterInstallation(operation, sourceClusterInstallation)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to get target cluster installation of installation %s", sourceClusterInstallation.InstallationID)
+			return stop(operation.State)
+		}
+		if existing != nil {
+			pair.TargetClusterInstallationID = existing.ID
+			pairs = append(pairs, pair)
+			continue
+		}
+
+		installation, err := s.store.GetInstallation(sourceClusterInstallation.InstallationID, true, false)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to get installation %s", sourceClusterInstallation.InstallationID)
+			return stop(operation.State)
+		}
+		if installation == nil || installationIsDeleting(installation) {
+			logger.Errorf("Installation %s is being deleted; cancelling cluster migration", sourceClusterInstallation.InstallationID)
+			return stop(model.ClusterMigrationStateFailing)
+		}
+		if !installationCanBeScheduledOnCluster(s.store, targetCluster, installation, logger) {
+			logger.Errorf("Installation %s cannot be scheduled on target cluster %s", installation.ID, targetCluster.ID)
+			return stop(model.ClusterMigrationStateFailing)
+		}
+
+		// Cluster installations are created one by one, so that scheduling
+		// checks of the following installations take them into account.
+		targetClusterInstallation := &model.ClusterInstallation{
+			InstallationID: installation.ID,
+			Namespace:      sourceClusterInstallation.Namespace,
+		}
+		err = s.store.MigrateClusterInstallations([]*model.ClusterInstallation{targetClusterInstallation}, targetCluster.ID)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to create target cluster installation of installation %s", installation.ID)
+			return stop(operation.State)
+		}
+		created++
+
+		err = s.eventsProducer.ProduceClusterInstallationStateChangeEvent(targetClusterInstallation, model.NonApplicableState)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create cluster installation state change event")
+		}
+
+		pair.TargetClusterInstallationID = targetClusterInstallation.ID
+		pairs = append(pairs, pair)
+	}
+
+	operation.ClusterInstallations = pairs
+	err = s.store.UpdateClusterMigrationOperation(operation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to store cluster installations of cluster migration")
+		return operation.State
+	}
+
+	logger.Infof("Requested creation of %d cluster installation(s) on target cluster", created)
+
+	return model.ClusterMigrationStateWaitingForClusterInstallations
+}
+
+// getTargetClusterInstallation returns the cluster installation created on the
+// target cluster by the given operation for the installation of the source
+// cluster installation, so that the creation step can be safely repeated
+// after a restart. Nil is returned if there is no such cluster installation.
+func (s *ClusterMigrationSupervisor) getTargetClusterInstallation(operation *model.ClusterMigrationOperation, sourceClusterInstallation *model.ClusterInstallation) (*model.ClusterInstallation, error) {
+	isActive := false
+	clusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging:         model.AllPagesNotDeleted(),
+		ClusterID:      operation.TargetClusterID,
+		InstallationID: sourceClusterInstallation.InstallationID,
+		IsActive:       &isActive,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ci := range clusterInstallations {
+		if ci.InstallationID != sourceClusterInstallation.InstallationID ||
+			ci.Namespace != sourceClusterInstallation.Namespace ||
+			ci.CreateAt < operation.RequestAt ||
+			ci.State == model.ClusterInstallationStateDeletionRequested ||
+			ci.State == model.ClusterInstallationStateDeleted {
+			continue
+		}
+		return ci, nil
+	}
+
+	return nil, nil
+}
+
+func (s *ClusterMigrationSupervisor) waitForTargetClusterInstallations(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) model.ClusterMigrationOperationState {
+	if len(operation.ClusterInstallations) == 0 {
+		logger.Error("Cluster migration has no cluster installations")
+		return model.ClusterMigrationStateFailing
+	}
+
+	clusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging: model.AllPagesWithDeleted(),
+		IDs:    operation.TargetClusterInstallationIDs(),
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to get target cluster installations")
+		return operation.State
+	}
+	if len(clusterInstallations) != len(operation.ClusterInstallations) {
+		logger.Error("Some target cluster installations no longer exist")
+		return model.ClusterMigrationStateFailing
+	}
+
+	var pending int
+	for _, ci := range clusterInstallations {
+		switch ci.State {
+		case model.ClusterInstallationStateStable:
+		case model.ClusterInstallationStateCreationFailed,
+			model.ClusterInstallationStateDeletionRequested,
+			model.ClusterInstallationStateDeletionFailed,
+			model.ClusterInstallationStateDeleted:
+			logger.Errorf("Target cluster installation %s is in unrecoverable state %s", ci.ID, ci.State)
+			return model.ClusterMigrationStateFailing
+		default:
+			pending++
+		}
+	}
+	if pending > 0 {
+		logger.Debugf("Waiting for %d target cluster installation(s) to become stable", pending)
+		return operation.State
+	}
+
+	logger.Info("All target cluster installations are stable")
+
+	return model.ClusterMigrationStateSwitchingDNS
+}
+
+func (s *ClusterMigrationSupervisor) switchDNS(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) model.ClusterMigrationOperationState {
+	installations, err := s.getInstallations(operation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get installations")
+		return operation.State
+	}
+
+	for _, installation := range installations {
+		if installationIsDeleting(installation) {
+			logger.Warnf("Installation %s is being deleted (state %s); cancelling cluster migration", installation.ID, installation.State)
+			return model.ClusterMigrationStateFailing
+		}
+		if !installationCanBeMigrated(installation) {
+			logger.Debugf("Waiting for installation %s to become stable before switching DNS (currently %s)", installation.ID, installation.State)
+			return operation.State
+		}
+	}
+
+	unlock, err := s.lockInstallations(operation, instanceID, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to lock installations")
+		return operation.State
+	}
+	defer unlock()
+
+	ciLock := newClusterInstallationLocks(
+		append(operation.SourceClusterInstallationIDs(), operation.TargetClusterInstallationIDs()...),
+		instanceID, s.store, logger,
+	)
+	if !ciLock.TryLock() {
+		logger.Debug("Failed to lock cluster installations")
+		return operation.State
+	}
+	defer ciLock.Unlock()
+
+	installationIDs, hibernatingInstallationIDs := splitHibernatingInstallations(installations)
+	err = s.store.SwitchDNS(operation.SourceClusterInstallationIDs(), operation.TargetClusterInstallationIDs(), installationIDs, hibernatingInstallationIDs)
+	if err != nil {
+		logger.WithError(err).Error("Failed to switch DNS to target cluster")
+		return operation.State
+	}
+
+	operation.DNSSwitched = true
+	err = s.store.UpdateClusterMigrationOperation(operation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to mark DNS as switched for cluster migration")
+		return operation.State
+	}
+
+	s.produceInstallationStateChangeEvents(installations, logger)
+
+	logger.Infof("Switched DNS of %d installation(s) (%d hibernating) to target cluster", len(installations), len(hibernatingInstallationIDs))
+
+	return model.ClusterMigrationStateWaitingForInstallations
+}
+
+func (s *ClusterMigrationSupervisor) waitForInstallations(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) model.ClusterMigrationOperationState {
+	installations, err := s.getInstallations(operation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get installations")
+		return operation.State
+	}
+
+	var pending int
+	for _, installation := range installations {
+		if !installationCanBeMigrated(installation) {
+			pending++
+		}
+	}
+	if pending > 0 {
+		logger.Debugf("Waiting for %d installation(s) to become stable after DNS switch", pending)
+		return operation.State
+	}
+
+	logger.Info("All installations are stable on target cluster")
+
+	return model.ClusterMigrationStateCleanup
+}
+
+func (s *ClusterMigrationSupervisor) cleanupSourceClusterInstallations(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) model.ClusterMigrationOperationState {
+	for _, id := range operation.SourceClusterInstallationIDs() {
+		err := requestInactiveClusterInstallationDeletion(s.store, s.eventsProducer, id, instanceID, logger)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to request deletion of source cluster installation %s", id)
+			return operation.State
+		}
+	}
+
+	if operation.SwitchClusterRoles {
+		err := s.aws.SwitchClusterTags(operation.SourceClusterID, operation.TargetClusterID, logger)
+		if err != nil {
+			logger.WithError(err).Error("Failed to switch cluster roles")
+			return operation.State
+		}
+	}
+
+	operation.CompleteAt = model.GetMillis()
+	err := s.store.UpdateClusterMigrationOperation(operation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to set complete at for cluster migration")
+		return operation.State
+	}
+
+	logger.Infof("Migrated %d installation(s) to target cluster", len(operation.ClusterInstallations))
+
+	return model.ClusterMigrationStateSucceeded
+}
+
+// revertMigration switches DNS back to the source cluster if it was already
+// switched and deletes target cluster installations.
+func (s *ClusterMigrationSupervisor) revertMigration(operation *model.ClusterMigrationOperation, finalState model.ClusterMigrationOperationState, instanceID string, logger log.FieldLogger) model.ClusterMigrationOperationState {
+	if operation.DNSSwitched {
+		err := s.switchDNSBack(operation, instanceID, logger)
+		if err != nil {
+			logger.WithError(err).Error("Failed to switch DNS back to source cluster")
+			return operation.State
+		}
+		logger.Info("Switched DNS back to source cluster")
+	}
+
+	for _, id := range operation.TargetClusterInstallationIDs() {
+		if id == "" {
+			continue
+		}
+		err := requestInactiveClusterInstallationDeletion(s.store, s.eventsProducer, id, instanceID, logger)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to request deletion of target cluster installation %s", id)
+			return operation.State
+		}
+	}
+
+	operation.CompleteAt = model.GetMillis()
+	err := s.store.UpdateClusterMigrationOperation(operation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to set complete at for cluster migration")
+		return operation.State
+	}
+
+	logger.Info("Cluster migration reverted")
+
+	return finalState
+}
+
+func (s *ClusterMigrationSupervisor) switchDNSBack(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) error {
+	installations, err := s.getInstallations(operation)
+	if err != nil {
+		return errors.Wrap(err, "failed to get installations")
+	}
+
+	unlock, err := s.lockInstallations(operation, instanceID, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock installations")
+	}
+	defer unlock()
+
+	ciLock := newClusterInstallationLocks(
+		append(operation.SourceClusterInstallationIDs(), operation.TargetClusterInstallationIDs()...),
+		instanceID, s.store, logger,
+	)
+	if !ciLock.TryLock() {
+		return errors.New("failed to lock cluster installations")
+	}
+	defer ciLock.Unlock()
+
+	installationIDs, hibernatingInstallationIDs := splitHibernatingInstallations(installations)
+	err = s.store.SwitchDNS(operation.TargetClusterInstallationIDs(), operation.SourceClusterInstallationIDs(), installationIDs, hibernatingInstallationIDs)
+	if err != nil {
+		return errors.Wrap(err, "failed to switch DNS")
+	}
+
+	operation.DNSSwitched = false
+	err = s.store.UpdateClusterMigrationOperation(operation)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark DNS as switched back for cluster migration")
+	}
+
+	s.produceInstallationStateChangeEvents(installations, logger)
+
+	return nil
+}
+
+func (s *ClusterMigrationSupervisor) getInstallations(operation *model.ClusterMigrationOperation) ([]*model.Installation, error) {
+	var installations []*model.Installation
+	for _, ci := range operation.ClusterInstallations {
+		installation, err := s.store.GetInstallation(ci.InstallationID, false, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get installation %s", ci.InstallationID)
+		}
+		if installation == nil {
+			return nil, errors.Errorf("installation %s not found", ci.InstallationID)
+		}
+		installations = append(installations, installation)
+	}
+
+	return installations, nil
+}
+
+// lockInstallations locks all migrated installations if requested by the
+// operation and returns a function releasing the locks.
+func (s *ClusterMigrationSupervisor) lockInstallations(operation *model.ClusterMigrationOperation, instanceID string, logger log.FieldLogger) (func(), error) {
+	if !operation.LockInstallations {
+		return func() {}, nil
+	}
+
+	installationIDs := make([]string, 0, len(operation.ClusterInstallations))
+	for _, ci := range operation.ClusterInstallations {
+		installationIDs = append(installationIDs, ci.InstallationID)
+	}
+
+	locked, err := s.store.LockInstallations(installationIDs, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, errors.New("failed to acquire lock for installations")
+	}
+
+	return func() {
+		unlocked, err := s.store.UnlockInstallations(installationIDs, instanceID, false)
+		if err != nil {
+			logger.WithError(err).Error("Failed to unlock installations")
+		} else if !unlocked {
+			logger.Error("Failed to release lock for installations")
+		}
+	}, nil
+}
+
+func (s *ClusterMigrationSupervisor) produceInstallationStateChangeEvents(oldInstallations []*model.Installation, logger log.FieldLogger) {
+	for _, oldInstallation := range oldInstallations {
+		installation, err := s.store.GetInstallation(oldInstallation.ID, false, false)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to get installation %s after DNS switch", oldInstallation.ID)
+			continue
+		}
+		err = s.eventsProducer.ProduceInstallationStateChangeEvent(installation, oldInstallation.State)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create installation state change event")
+		}
+	}
+}
+
+// splitHibernatingInstallations returns IDs of installations which are not
+// hibernated and of those which are.
+func splitHibernatingInstallations(installations []*model.Installation) ([]string, []string) {
+	var installationIDs, hibernatingInstallationIDs []string
+	for _, installation := range installations {
+		if installation.State == model.InstallationStateHibernating ||
+			installation.State == model.InstallationStateDNSMigrationHibernating {
+			hibernatingInstallationIDs = append(hibernatingInstallationIDs, installation.ID)
+		} else {
+			installationIDs = append(installationIDs, installation.ID)
+		}
+	}
+
+	return installationIDs, hibernatingInstallationIDs
+}
+
+// clusterInstallationDeletionStore abstracts the database operations required
+// to request deletion of inactive cluster installations.
+type clusterInstallationDeletionStore interface {
+	GetClusterInstallation(clusterInstallationID string) (*model.ClusterInstallation, error)
+	UpdateClusterInstallation(clusterInstallation *model.ClusterInstallation) error
+	clusterInstallationLockStore
+}
+
+// requestInactiveClusterInstallationDeletion moves the given cluster
+// installation to deletion requested state. Active, deleted or already
+// deleting cluster installations are left untouched.
+func requestInactiveClusterInstallationDeletion(store clusterInstallationDeletionStore, eventsProducer eventProducer, clusterInstallationID, instanceID string, logger log.FieldLogger) error {
+	ciLock := newClusterInstallationLock(clusterInstallationID, instanceID, store, logger)
+	if !ciLock.TryLock() {
+		return errors.Errorf("failed to lock cluster installation %s", clusterInstallationID)
+	}
+	defer ciLock.Unlock()
+
+	clusterInstallation, err := store.GetClusterInstallation(clusterInstallationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster installation")
+	}
+	if clusterInstallation == nil || clusterInstallation.IsDeleted() {
+		return nil
+	}
+	if clusterInstallation.IsActive {
+		logger.Warnf("Cluster installation %s is active; skipping deletion", clusterInstallation.ID)
+		return nil
+	}
+	if clusterInstallation.State == model.ClusterInstallationStateDeletionRequested ||
+		clusterInstallation.State == model.ClusterInstallationStateDeleted {
+		return nil
+	}
+
+	oldState := clusterInstallation.State
+	clusterInstallation.State = model.ClusterInstallationStateDeletionRequested
+	err = store.UpdateClusterInstallation(clusterInstallation)
+	if err != nil {
+		return errors.Wrap(err, "failed to update cluster installation")
+	}
+
+	err = eventsProducer.ProduceClusterInstallationStateChangeEvent(clusterInstallation, oldState)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create cluster installation state change event")
+	}
+
+	return nil
+}
+
+// installationCanBeMigrated returns true if the installation is in a state
+// which allows moving it between clusters.
+func installationCanBeMigrated(installation *model.Installation) bool {
+	return installation.State == model.InstallationStateStable ||
+		installation.State == model.InstallationStateHibernating
+}
+
+// installationIsDeleting returns true if the installation is being or has
+// been deleted.
+func installationIsDeleting(installation *model.Installation) bool {
+	switch installation.State {
+	case model.InstallationStateDeletionRequested,
+		model.InstallationStateDeletionInProgress,
+		model.InstallationStateDeletionFinalCleanup,
+		model.InstallationStateDeletionFailed,
+		model.InstallationStateDeleted:
+		return true
+	}
+
+	return installation.IsDeleted()
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import log "github.com/sirupsen/logrus"
+
+type clusterMigrationOperationLockStore interface {
+	LockClusterMigrationOperations(id []string, lockerID string) (bool, error)
+	UnlockClusterMigrationOperations(id []string, lockerID string, force bool) (bool, error)
+}
+
+type clusterMigrationOperationLock struct {
+	ids      []string
+	lockerID string
+	store    clusterMigrationOperationLockStore
+	logger   log.FieldLogger
+}
+
+func newClusterMigrationOperationLock(id, lockerID string, store clusterMigrationOperationLockStore, logger log.FieldLogger) *clusterMigrationOperationLock {
+	return &clusterMigrationOperationLock{
+		ids:      []string{id},
+		lockerID: lockerID,
+		store:    store,
+		logger:   logger,
+	}
+}
+
+func (l *clusterMigrationOperationLock) TryLock() bool {
+	locked, err := l.store.LockClusterMigrationOperations(l.ids, l.lockerID)
+	if err != nil {
+		l.logger.WithError(err).Error("failed to lock clusterMigrationOperations")
+		return false
+	}
+
+	return locked
+}
+
+func (l *clusterMigrationOperationLock) Unlock() {
+	unlocked, err := l.store.UnlockClusterMigrationOperations(l.ids, l.lockerID, false)
+	if err != nil {
+		l.logger.WithError(err).Error("failed to unlock clusterMigrationOperations")
+	} else if unlocked != true {
+		l.logger.Error("failed to release lock for clusterMigrationOperations")
+	}
+}
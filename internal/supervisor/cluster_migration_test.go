@@ -0,0 +1,375 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterMigrationSupervisor_Supervise(t *testing.T) {
+	setup := func(t *testing.T, sqlStore *store.SQLStore) ([]*model.Installation, *model.ClusterMigrationOperation) {
+		sourceCluster := &model.Cluster{State: model.ClusterStateStable, AllowInstallations: false}
+		err := sqlStore.CreateCluster(sourceCluster, nil)
+		require.NoError(t, err)
+		targetCluster := &model.Cluster{State: model.ClusterStateStable, AllowInstallations: true}
+		err = sqlStore.CreateCluster(targetCluster, nil)
+		require.NoError(t, err)
+
+		var installations []*model.Installation
+		for _, state := range []string{model.InstallationStateStable, model.InstallationStateHibernating} {
+			installation := &model.Installation{
+				OwnerID:  model.NewID(),
+				DNS:      model.NewID() + ".example.com",
+				Size:     mmv1alpha1.Size100String,
+				Affinity: model.InstallationAffinityMultiTenant,
+				State:    state,
+			}
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+			installations = append(installations, installation)
+
+			err = sqlStore.CreateClusterInstallation(&model.ClusterInstallation{
+				ClusterID:      sourceCluster.ID,
+				InstallationID: installation.ID,
+				Namespace:      installation.ID,
+				State:          model.ClusterInstallationStateStable,
+				IsActive:       true,
+			})
+			require.NoError(t, err)
+		}
+
+		operation := &model.ClusterMigrationOperation{
+			SourceClusterID:   sourceCluster.ID,
+			TargetClusterID:   targetCluster.ID,
+			LockInstallations: true,
+			State:             model.ClusterMigrationStateRequested,
+		}
+		err = sqlStore.CreateClusterMigrationOperation(operation)
+		require.NoError(t, err)
+
+		return installations, operation
+	}
+
+	supervise := func(t *testing.T, s *supervisor.ClusterMigrationSupervisor, sqlStore *store.SQLStore, operation *model.ClusterMigrationOperation, expectedState model.ClusterMigrationOperationState) *model.ClusterMigrationOperation {
+		s.Supervise(operation)
+		operation, err := sqlStore.GetClusterMigrationOperation(operation.ID)
+		require.NoError(t, err)
+		require.Equal(t, expectedState, operation.State)
+		return operation
+	}
+
+	setClusterInstallationsState := func(t *testing.T, sqlStore *store.SQLStore, ids []string, state string) {
+		for _, id := range ids {
+			clusterInstallation, err := sqlStore.GetClusterInstallation(id)
+			require.NoError(t, err)
+			clusterInstallation.State = state
+			err = sqlStore.UpdateClusterInstallation(clusterInstallation)
+			require.NoError(t, err)
+		}
+	}
+
+	assertClusterInstallations := func(t *testing.T, sqlStore *store.SQLStore, ids []string, isActive bool, state string) {
+		for _, id := range ids {
+			clusterInstallation, err := sqlStore.GetClusterInstallation(id)
+			require.NoError(t, err)
+			assert.Equal(t, isActive, clusterInstallation.IsActive)
+			assert.Equal(t, state, clusterInstallation.State)
+		}
+	}
+
+	assertInstallationStates := func(t *testing.T, sqlStore *store.SQLStore, installations []*model.Installation, states ...string) {
+		for i, installation := range installations {
+			fetched, err := sqlStore.GetInstallation(installation.ID, false, false)
+			require.NoError(t, err)
+			assert.Equal(t, states[i], fetched.State)
+		}
+	}
+
+	setInstallationStates := func(t *testing.T, sqlStore *store.SQLStore, installations []*model.Installation, states ...string) {
+		for i, installation := range installations {
+			fetched, err := sqlStore.GetInstallation(installation.ID, false, false)
+			require.NoError(t, err)
+			fetched.State = states[i]
+			err = sqlStore.UpdateInstallation(fetched)
+			require.NoError(t, err)
+		}
+	}
+
+	t.Run("full migration", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installations, operation := setup(t, sqlStore)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		require.Len(t, operation.ClusterInstallations, 2)
+		assertClusterInstallations(t, sqlStore, operation.TargetClusterInstallationIDs(), false, model.ClusterInstallationStateCreationRequested)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		setClusterInstallationsState(t, sqlStore, operation.TargetClusterInstallationIDs(), model.ClusterInstallationStateStable)
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateSwitchingDNS)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForInstallations)
+		assert.True(t, operation.DNSSwitched)
+		assertClusterInstallations(t, sqlStore, operation.TargetClusterInstallationIDs(), true, model.ClusterInstallationStateStable)
+		assertClusterInstallations(t, sqlStore, operation.SourceClusterInstallationIDs(), false, model.ClusterInstallationStateStable)
+		assertInstallationStates(t, sqlStore, installations, model.InstallationStateCreationDNS, model.InstallationStateDNSMigrationHibernating)
+
+		for _, installation := range installations {
+			installation, err := sqlStore.GetInstallation(installation.ID, false, false)
+			require.NoError(t, err)
+			assert.Nil(t, installation.LockAcquiredBy)
+		}
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForInstallations)
+		setInstallationStates(t, sqlStore, installations, model.InstallationStateStable, model.InstallationStateHibernating)
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateCleanup)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateSucceeded)
+		assert.NotZero(t, operation.CompleteAt)
+		assertClusterInstallations(t, sqlStore, operation.SourceClusterInstallationIDs(), false, model.ClusterInstallationStateDeletionRequested)
+	})
+
+	t.Run("resume after restart reuses target cluster installations", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, operation := setup(t, sqlStore)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		targetIDs := operation.TargetClusterInstallationIDs()
+
+		// Simulate provisioner restarting before the state was persisted.
+		operation.State = model.ClusterMigrationStateRequested
+		err := sqlStore.UpdateClusterMigrationOperationState(operation)
+		require.NoError(t, err)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		assert.ElementsMatch(t, targetIDs, operation.TargetClusterInstallationIDs())
+	})
+
+	t.Run("target cluster installations created before an error are stored", func(t *testing.T) {
+		setupFailingMigration := func(t *testing.T, sqlStore *store.SQLStore, logger log.FieldLogger) (*supervisor.ClusterMigrationSupervisor, *failingInstallationStore, *model.ClusterMigrationOperation) {
+			installations, operation := setup(t, sqlStore)
+			migrationStore := &failingInstallationStore{SQLStore: sqlStore, installationID: installations[1].ID}
+			s := supervisor.NewClusterMigrationSupervisor(migrationStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+			operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateRequested)
+			require.Len(t, operation.ClusterInstallations, 1)
+			return s, migrationStore, operation
+		}
+
+		t.Run("retry keeps the stored target cluster installations", func(t *testing.T) {
+			logger := testlib.MakeLogger(t)
+			sqlStore := store.MakeTestSQLStore(t, logger)
+			defer store.CloseConnection(t, sqlStore)
+
+			s, migrationStore, operation := setupFailingMigration(t, sqlStore, logger)
+			targetIDs := operation.TargetClusterInstallationIDs()
+			migrationStore.installationID = ""
+
+			operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+			require.Len(t, operation.ClusterInstallations, 2)
+			assert.Equal(t, targetIDs[0], operation.ClusterInstallations[0].TargetClusterInstallationID)
+		})
+
+		t.Run("rollback deletes the stored target cluster installations", func(t *testing.T) {
+			logger := testlib.MakeLogger(t)
+			sqlStore := store.MakeTestSQLStore(t, logger)
+			defer store.CloseConnection(t, sqlStore)
+
+			s, _, operation := setupFailingMigration(t, sqlStore, logger)
+			operation.State = model.ClusterMigrationStateRollbackRequested
+			err := sqlStore.UpdateClusterMigrationOperationState(operation)
+			require.NoError(t, err)
+
+			operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateRollbackFinished)
+			assertClusterInstallations(t, sqlStore, operation.TargetClusterInstallationIDs(), false, model.ClusterInstallationStateDeletionRequested)
+		})
+	})
+
+	t.Run("single installation", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installations, operation := setup(t, sqlStore)
+		operation.InstallationID = installations[0].ID
+		err := sqlStore.CreateClusterMigrationOperation(operation)
+		require.NoError(t, err)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		require.Len(t, operation.ClusterInstallations, 1)
+		assert.Equal(t, installations[0].ID, operation.ClusterInstallations[0].InstallationID)
+	})
+
+	t.Run("inactive cluster installations not created by the migration are not reused", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installations, operation := setup(t, sqlStore)
+		staleClusterInstallation := &model.ClusterInstallation{
+			ClusterID:      operation.TargetClusterID,
+			InstallationID: installations[0].ID,
+			Namespace:      installations[0].ID,
+			State:          model.ClusterInstallationStateStable,
+			IsActive:       false,
+		}
+		err := sqlStore.CreateClusterInstallation(staleClusterInstallation)
+		require.NoError(t, err)
+		time.Sleep(1 * time.Millisecond) // Ensure the operation is requested after the stale cluster installation is created.
+
+		operation.InstallationID = installations[0].ID
+		err = sqlStore.CreateClusterMigrationOperation(operation)
+		require.NoError(t, err)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		require.Len(t, operation.ClusterInstallations, 1)
+		assert.NotEqual(t, staleClusterInstallation.ID, operation.ClusterInstallations[0].TargetClusterInstallationID)
+	})
+
+	t.Run("installation cannot be scheduled on target cluster", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, operation := setup(t, sqlStore)
+		isolatedInstallation := &model.Installation{
+			OwnerID:  model.NewID(),
+			DNS:      model.NewID() + ".example.com",
+			Size:     mmv1alpha1.Size100String,
+			Affinity: model.InstallationAffinityIsolated,
+			State:    model.InstallationStateStable,
+		}
+		err := sqlStore.CreateInstallation(isolatedInstallation, nil)
+		require.NoError(t, err)
+		err = sqlStore.CreateClusterInstallation(&model.ClusterInstallation{
+			ClusterID:      operation.TargetClusterID,
+			InstallationID: isolatedInstallation.ID,
+			Namespace:      isolatedInstallation.ID,
+			State:          model.ClusterInstallationStateStable,
+			IsActive:       true,
+		})
+		require.NoError(t, err)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateFailing)
+		assert.Empty(t, operation.ClusterInstallations)
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateFailed)
+
+		clusterInstallations, err := sqlStore.GetClusterInstallations(&model.ClusterInstallationFilter{
+			Paging:    model.AllPagesNotDeleted(),
+			ClusterID: operation.TargetClusterID,
+		})
+		require.NoError(t, err)
+		assert.Len(t, clusterInstallations, 1)
+	})
+
+	t.Run("target cluster not allowing installations", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, operation := setup(t, sqlStore)
+		targetCluster, err := sqlStore.GetCluster(operation.TargetClusterID)
+		require.NoError(t, err)
+		targetCluster.AllowInstallations = false
+		err = sqlStore.UpdateCluster(targetCluster)
+		require.NoError(t, err)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		supervise(t, s, sqlStore, operation, model.ClusterMigrationStateFailing)
+	})
+
+	t.Run("EKS target cluster", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, operation := setup(t, sqlStore)
+		targetCluster, err := sqlStore.GetCluster(operation.TargetClusterID)
+		require.NoError(t, err)
+		targetCluster.Provisioner = model.ProvisionerEKS
+		err = sqlStore.UpdateCluster(targetCluster)
+		require.NoError(t, err)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		supervise(t, s, sqlStore, operation, model.ClusterMigrationStateFailing)
+	})
+
+	t.Run("rollback after DNS switch", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installations, operation := setup(t, sqlStore)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		setClusterInstallationsState(t, sqlStore, operation.TargetClusterInstallationIDs(), model.ClusterInstallationStateStable)
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateSwitchingDNS)
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForInstallations)
+
+		operation.State = model.ClusterMigrationStateRollbackRequested
+		err := sqlStore.UpdateClusterMigrationOperationState(operation)
+		require.NoError(t, err)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateRollbackFinished)
+		assert.False(t, operation.DNSSwitched)
+		assertClusterInstallations(t, sqlStore, operation.SourceClusterInstallationIDs(), true, model.ClusterInstallationStateStable)
+		assertClusterInstallations(t, sqlStore, operation.TargetClusterInstallationIDs(), false, model.ClusterInstallationStateDeletionRequested)
+		assertInstallationStates(t, sqlStore, installations, model.InstallationStateCreationDNS, model.InstallationStateDNSMigrationHibernating)
+	})
+
+	t.Run("target cluster installation failed", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installations, operation := setup(t, sqlStore)
+		s := supervisor.NewClusterMigrationSupervisor(sqlStore, &mockAWS{}, "instanceID", &mockEventProducer{}, logger)
+
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateWaitingForClusterInstallations)
+		setClusterInstallationsState(t, sqlStore, operation.TargetClusterInstallationIDs()[:1], model.ClusterInstallationStateCreationFailed)
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateFailing)
+		operation = supervise(t, s, sqlStore, operation, model.ClusterMigrationStateFailed)
+
+		assertClusterInstallations(t, sqlStore, operation.TargetClusterInstallationIDs(), false, model.ClusterInstallationStateDeletionRequested)
+		assertClusterInstallations(t, sqlStore, operation.SourceClusterInstallationIDs(), true, model.ClusterInstallationStateStable)
+		assertInstallationStates(t, sqlStore, installations, model.InstallationStateStable, model.InstallationStateHibernating)
+	})
+}
+
+// failingInstallationStore fails to get the installation with the given ID.
+type failingInstallationStore struct {
+	*store.SQLStore
+	installationID string
+}
+
+func (s *failingInstallationStore) GetInstallation(id string, includeGroupConfig, includeGroupConfigOverrides bool) (*model.Installation, error) {
+	if id == s.installationID {
+		return nil, errors.New("failed to get installation")
+	}
+
+	return s.SQLStore.GetInstallation(id, includeGroupConfig, includeGroupConfigOverrides)
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/common"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// clusterPoolStore abstracts the database operations required by the cluster
+// pool supervisor.
+type clusterPoolStore interface {
+	GetInstallations(installationFilter *model.InstallationFilter, includeGroupConfig, includeGroupConfigOverrides bool) ([]*model.Installation, error)
+	GetAnnotationsForInstallation(installationID string) ([]*model.Annotation, error)
+	GetClusters(clusterFilter *model.ClusterFilter) ([]*model.Cluster, error)
+	GetClusterSizes(filter *model.ClusterSizeFilter) ([]*model.ClusterSize, error)
+	GetClusterTemplate(id string) (*model.ClusterTemplate, error)
+	GetClusterTemplates(filter *model.ClusterTemplateFilter) ([]*model.ClusterTemplate, error)
+	CreateCluster(cluster *model.Cluster, annotations []*model.Annotation) error
+	supervisorTaskLockStore
+}
+
+// ClusterPoolOptions configures the cluster pool supervisor.
+type ClusterPoolOptions struct {
+	// Templates are the names of the cluster templates new clusters are
+	// created from. Templates listed first are preferred.
+	Templates []string
+	// MaxClustersPerTemplate is the maximum number of clusters allowing
+	// installations created from a template. Set to 0 for no limit.
+	MaxClustersPerTemplate int
+	// StableClusterGracePeriod is the time after its creation during which a
+	// stable cluster created from a template is expected to receive the
+	// installations waiting for it, so no other cluster is created from the
+	// template.
+	StableClusterGracePeriod time.Duration
+}
+
+// ClusterPoolSupervisor creates new clusters from cluster templates when
+// installations cannot be scheduled on any existing cluster. Waiting
+// installations are scheduled by the installation supervisor once the new
+// cluster is stable.
+//
+// Only one provisioning server creates clusters from a given template at a
+// time, so that installations waiting for a template get a single cluster.
+type ClusterPoolSupervisor struct {
+	store          clusterPoolStore
+	options        ClusterPoolOptions
+	eventsProducer eventProducer
+	instanceID     string
+	logger         log.FieldLogger
+}
+
+// NewClusterPoolSupervisor creates a new ClusterPoolSupervisor creating
+// clusters from the configured cluster templates.
+func NewClusterPoolSupervisor(store clusterPoolStore, options ClusterPoolOptions, eventsProducer eventProducer, instanceID string, logger log.FieldLogger) *ClusterPoolSupervisor {
+	return &ClusterPoolSupervisor{
+		store:          store,
+		options:        options,
+		eventsProducer: eventsProducer,
+		instanceID:     instanceID,
+		logger:         logger.WithField("supervisor", "cluster-pool"),
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the cluster pool supervisor.
+func (s *ClusterPoolSupervisor) Shutdown() {
+	s.logger.Debug("Shutting down cluster pool supervisor")
+}
+
+// Do looks for installations without compatible clusters and creates new
+// clusters from the matching cluster templates.
+func (s *ClusterPoolSupervisor) Do() error {
+	installations, err := s.store.GetInstallations(&model.InstallationFilter{
+		Paging: model.AllPagesNotDeleted(),
+		State:  model.InstallationStateCreationNoCompatibleClusters,
+	}, false, false)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to query for installations without compatible clusters")
+		return nil
+	}
+	if len(installations) == 0 {
+		return nil
+	}
+
+	templates, err := s.getTemplates()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get cluster pool templates")
+		return nil
+	}
+
+	waiting := map[string][]string{}
+	for _, installation := range installations {
+		template, err := s.matchingTemplate(installation, templates)
+		if err != nil {
+			s.logger.WithError(err).Errorf("Failed to find cluster pool template for installation %s", installation.ID)
+			continue
+		}
+		if template == nil {
+			s.logger.Warnf("No cluster pool template provides the annotations required by installation %s", installation.ID)
+			continue
+		}
+		waiting[template.ID] = append(waiting[template.ID], installation.ID)
+	}
+
+	for _, template := range templates {
+		installationIDs, ok := waiting[template.ID]
+		if !ok {
+			continue
+		}
+		logger := s.logger.WithField("cluster-template", template.Name)
+
+		err = s.ensurePoolCluster(template, installationIDs, logger)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create cluster from cluster pool template")
+		}
+	}
+
+	return nil
+}
+
+// getTemplates returns the configured cluster templates in the configured
+// order. Missing templates are skipped.
+func (s *ClusterPoolSupervisor) getTemplates() ([]*model.ClusterTemplate, error) {
+	var templates []*model.ClusterTemplate
+	for _, name := range s.options.Templates {
+		template, err := common.GetClusterTemplateByName(s.store, name)
+		if err != nil {
+			return nil, err
+		}
+		if template == nil || template.Cluster == nil {
+			s.logger.Warnf("Cluster pool template %s not found", name)
+			continue
+		}
+		if template.Cluster.Provisioner == model.ProvisionerEKS {
+			s.logger.Warnf("Cluster pool template %s creates EKS clusters which cannot host installations", name)
+			continue
+		}
+		templates = append(templates, template)
+	}
+
+	return templates, nil
+}
+
+// matchingTemplate returns the template providing all annotations required by
+// the installation with the fewest additional annotations. Templates listed
+// first win ties.
+func (s *ClusterPoolSupervisor) matchingTemplate(installation *model.Installation, templates []*model.ClusterTemplate) (*model.ClusterTemplate, error) {
+	annotations, err := s.store.GetAnnotationsForInstallation(installation.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get annotations for installation")
+	}
+
+	required := getAnnotationsNames(annotations)
+	if installation.Placement != nil {
+		required = append(required, installation.Placement.RequiredClusterAnnotations...)
+	}
+
+	var match *model.ClusterTemplate
+	for _, template := range templates {
+		if !templateProvidesAnnotations(template, required) {
+			continue
+		}
+		providesNodeGroup, err := s.templateProvidesNodeGroup(template, installation.Placement.GetNodeGroup())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to check node groups of cluster template %s", template.Name)
+		}
+		if !providesNodeGroup {
+			continue
+		}
+		if match == nil || len(template.Cluster.Annotations) < len(match.Cluster.Annotations) {
+			match = template
+		}
+	}
+
+	return match, nil
+}
+
+// templateProvidesAnnotations returns true if clusters created from the
+// template have all the given annotations.
+func templateProvidesAnnotations(template *model.ClusterTemplate, names []string) bool {
+	templateAnnotations := make(map[string]struct{}, len(template.Cluster.Annotations))
+	for _, annotation := range template.Cluster.Annotations {
+		templateAnnotations[annotation] = struct{}{}
+	}
+	for _, name := range names {
+		if _, ok := templateAnnotations[name]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// templateProvidesNodeGroup returns true if clusters created from the template
+// have the given additional node group.
+func (s *ClusterPoolSupervisor) templateProvidesNodeGroup(template *model.ClusterTemplate, nodeGroup string) (bool, error) {
+	if len(nodeGroup) == 0 {
+		return true, nil
+	}
+
+	nodeGroups := template.Cluster.AdditionalNodeGroups
+	if nodeGroups == nil && len(template.Cluster.Size) != 0 {
+		size, err := common.GetClusterSize(s.store, template.Cluster.Size)
+		if err != nil {
+			return false, err
+		}
+		if size == nil {
+			return false, nil
+		}
+		nodeGroups = size.AdditionalNodeGroups
+	}
+	_, ok := nodeGroups[nodeGroup]
+
+	return ok, nil
+}
+
+// ensurePoolCluster creates a new cluster from the template while holding the
+// lock of the template, unless a cluster created from it is already on its way
+// to become stable, was created recently or the template reached its cluster
+// limit.
+func (s *ClusterPoolSupervisor) ensurePoolCluster(template *model.ClusterTemplate, installationIDs []string, logger log.FieldLogger) error {
+	lock := newSupervisorTaskLock(clusterPoolTask(template.ID), s.instanceID, s.store, logger)
+	if !lock.TryLock() {
+		return nil
+	}
+	defer lock.Unlock()
+
+	clusters, err := s.store.GetClusters(&model.ClusterFilter{Paging: model.AllPagesNotDeleted()})
+	if err != nil {
+		return errors.Wrap(err, "failed to get clusters")
+	}
+
+	var templateClusters int
+	for _, cluster := range clusters {
+		if cluster.ClusterTemplateID != template.ID || !cluster.AllowInstallations {
+			continue
+		}
+		templateClusters++
+
+		switch cluster.State {
+		case model.ClusterStateCreationRequested, model.ClusterStateProvisioningRequested:
+			logger.Debugf("Waiting for cluster %s to be created for %d installations", cluster.ID, len(installationIDs))
+			return nil
+		case model.ClusterStateCreationFailed, model.ClusterStateProvisioningFailed:
+			logger.Warnf("Cluster %s created from the cluster pool template is in state %s; no more clusters will be created until it is deleted", cluster.ID, cluster.State)
+			return nil
+		case model.ClusterStateStable:
+			if model.GetMillis()-cluster.CreateAt < s.options.StableClusterGracePeriod.Milliseconds() {
+				logger.Debugf("Waiting for installations to be scheduled on the recently created cluster %s", cluster.ID)
+				return nil
+			}
+		}
+	}
+
+	if s.options.MaxClustersPerTemplate > 0 && templateClusters >= s.options.MaxClustersPerTemplate {
+		logger.Warnf("Cluster pool template has reached its limit of %d clusters; %d installations have no compatible clusters", s.options.MaxClustersPerTemplate, len(installationIDs))
+		return nil
+	}
+
+	cluster, _, err := common.CreateCluster(s.store, &model.CreateClusterRequest{
+		Template:           template.ID,
+		AllowInstallations: true,
+	}, s.eventsProducer, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to create cluster from cluster template")
+	}
+
+	logger.WithField("cluster", cluster.ID).Infof("Requested creation of a new cluster for %d installations without compatible clusters", len(installationIDs))
+
+	return nil
+}
+
+// clusterPoolTask returns the supervisor task guarding the creation of
+// clusters from a cluster template.
+func clusterPoolTask(templateID string) string {
+	return fmt.Sprintf("cluster-pool-%s", templateID)
+}
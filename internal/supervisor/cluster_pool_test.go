@@ -0,0 +1,232 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/clusterdictionary"
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterPoolSupervisorDo(t *testing.T) {
+	options := supervisor.ClusterPoolOptions{
+		Templates:                []string{"gpu", "default"},
+		MaxClustersPerTemplate:   2,
+		StableClusterGracePeriod: time.Hour,
+	}
+
+	createTemplates := func(t *testing.T, sqlStore *store.SQLStore) {
+		for _, template := range []*model.ClusterTemplate{
+			{Name: "gpu", Cluster: &model.CreateClusterRequest{Size: clusterdictionary.SizeAlef1000, Annotations: []string{"multi-tenant", "gpu"}}},
+			{Name: "default", Cluster: &model.CreateClusterRequest{Size: clusterdictionary.SizeAlef500, Annotations: []string{"multi-tenant"}}},
+			{Name: "unused", Cluster: &model.CreateClusterRequest{Size: clusterdictionary.SizeAlef500, Annotations: []string{"multi-tenant", "dedicated"}}},
+		} {
+			err := sqlStore.CreateClusterTemplate(template)
+			require.NoError(t, err)
+		}
+	}
+
+	createInstallation := func(t *testing.T, sqlStore *store.SQLStore, annotations []string, placement *model.InstallationPlacement) {
+		installation := &model.Installation{
+			OwnerID:   model.NewID(),
+			DNS:       model.NewID() + ".example.com",
+			Size:      mmv1alpha1.Size100String,
+			Affinity:  model.InstallationAffinityMultiTenant,
+			Placement: placement,
+			State:     model.InstallationStateCreationNoCompatibleClusters,
+		}
+		installationAnnotations, err := model.AnnotationsFromStringSlice(annotations)
+		require.NoError(t, err)
+		err = sqlStore.CreateInstallation(installation, installationAnnotations)
+		require.NoError(t, err)
+	}
+
+	getClusters := func(t *testing.T, sqlStore *store.SQLStore) []*model.Cluster {
+		clusters, err := sqlStore.GetClusters(&model.ClusterFilter{Paging: model.AllPagesNotDeleted()})
+		require.NoError(t, err)
+		return clusters
+	}
+
+	getTemplateID := func(t *testing.T, sqlStore *store.SQLStore, name string) string {
+		templates, err := sqlStore.GetClusterTemplates(&model.ClusterTemplateFilter{Paging: model.AllPagesNotDeleted(), Name: name})
+		require.NoError(t, err)
+		require.Len(t, templates, 1)
+		return templates[0].ID
+	}
+
+	t.Run("creates one cluster from the matching template", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		createTemplates(t, sqlStore)
+
+		createInstallation(t, sqlStore, []string{"multi-tenant"}, nil)
+		createInstallation(t, sqlStore, []string{"multi-tenant"}, nil)
+
+		poolSupervisor := supervisor.NewClusterPoolSupervisor(sqlStore, options, &mockEventProducer{}, "instanceID", logger)
+		err := poolSupervisor.Do()
+		require.NoError(t, err)
+
+		clusters := getClusters(t, sqlStore)
+		require.Len(t, clusters, 1)
+		assert.Equal(t, model.ClusterStateCreationRequested, clusters[0].State)
+		assert.True(t, clusters[0].AllowInstallations)
+		assert.Equal(t, model.ProvisionerKops, clusters[0].Provisioner)
+		assert.Equal(t, clusterdictionary.ValidSizes[clusterdictionary.SizeAlef500].NodeMinCount, clusters[0].ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+
+		template, err := sqlStore.GetClusterTemplates(&model.ClusterTemplateFilter{Paging: model.AllPagesNotDeleted(), Name: "default"})
+		require.NoError(t, err)
+		require.Len(t, template, 1)
+		assert.Equal(t, template[0].ID, clusters[0].ClusterTemplateID)
+
+		annotations, err := sqlStore.GetAnnotationsForCluster(clusters[0].ID)
+		require.NoError(t, err)
+		require.Len(t, annotations, 1)
+		assert.Equal(t, "multi-tenant", annotations[0].Name)
+
+		t.Run("waits for the cluster being created", func(t *testing.T) {
+			err = poolSupervisor.Do()
+			require.NoError(t, err)
+			assert.Len(t, getClusters(t, sqlStore), 1)
+		})
+
+		t.Run("does not retry after cluster creation failed", func(t *testing.T) {
+			clusters[0].State = model.ClusterStateCreationFailed
+			err = sqlStore.UpdateCluster(clusters[0])
+			require.NoError(t, err)
+
+			err = poolSupervisor.Do()
+			require.NoError(t, err)
+			assert.Len(t, getClusters(t, sqlStore), 1)
+		})
+	})
+
+	t.Run("waits for installations to be scheduled on a recently created cluster", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		createTemplates(t, sqlStore)
+
+		createInstallation(t, sqlStore, []string{"multi-tenant"}, nil)
+
+		err := sqlStore.CreateCluster(&model.Cluster{
+			Provisioner:        model.ProvisionerKops,
+			ClusterTemplateID:  getTemplateID(t, sqlStore, "default"),
+			AllowInstallations: true,
+			State:              model.ClusterStateStable,
+		}, nil)
+		require.NoError(t, err)
+
+		poolSupervisor := supervisor.NewClusterPoolSupervisor(sqlStore, options, &mockEventProducer{}, "instanceID", logger)
+		err = poolSupervisor.Do()
+		require.NoError(t, err)
+		assert.Len(t, getClusters(t, sqlStore), 1)
+
+		t.Run("creates a cluster after the grace period", func(t *testing.T) {
+			noGracePeriodOptions := options
+			noGracePeriodOptions.StableClusterGracePeriod = 0
+			poolSupervisor = supervisor.NewClusterPoolSupervisor(sqlStore, noGracePeriodOptions, &mockEventProducer{}, "instanceID", logger)
+
+			err = poolSupervisor.Do()
+			require.NoError(t, err)
+			clusters := getClusters(t, sqlStore)
+			require.Len(t, clusters, 2)
+
+			t.Run("stops at the cluster limit of the template", func(t *testing.T) {
+				for _, cluster := range clusters {
+					cluster.State = model.ClusterStateStable
+					err = sqlStore.UpdateCluster(cluster)
+					require.NoError(t, err)
+				}
+
+				err = poolSupervisor.Do()
+				require.NoError(t, err)
+				assert.Len(t, getClusters(t, sqlStore), 2)
+			})
+		})
+	})
+
+	t.Run("skips templates locked by another instance", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		createTemplates(t, sqlStore)
+
+		createInstallation(t, sqlStore, []string{"multi-tenant"}, nil)
+
+		task := "cluster-pool-" + getTemplateID(t, sqlStore, "default")
+		locked, err := sqlStore.LockSupervisorTask(task, "otherInstance")
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		poolSupervisor := supervisor.NewClusterPoolSupervisor(sqlStore, options, &mockEventProducer{}, "instanceID", logger)
+		err = poolSupervisor.Do()
+		require.NoError(t, err)
+		assert.Empty(t, getClusters(t, sqlStore))
+
+		unlocked, err := sqlStore.UnlockSupervisorTask(task, "otherInstance", false)
+		require.NoError(t, err)
+		require.True(t, unlocked)
+
+		err = poolSupervisor.Do()
+		require.NoError(t, err)
+		assert.Len(t, getClusters(t, sqlStore), 1)
+	})
+
+	t.Run("uses placement rules to find the template", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		createTemplates(t, sqlStore)
+
+		createInstallation(t, sqlStore, []string{"multi-tenant"}, &model.InstallationPlacement{RequiredClusterAnnotations: []string{"gpu"}})
+
+		poolSupervisor := supervisor.NewClusterPoolSupervisor(sqlStore, options, &mockEventProducer{}, "instanceID", logger)
+		err := poolSupervisor.Do()
+		require.NoError(t, err)
+
+		clusters := getClusters(t, sqlStore)
+		require.Len(t, clusters, 1)
+		annotations, err := sqlStore.GetAnnotationsForCluster(clusters[0].ID)
+		require.NoError(t, err)
+		assert.Len(t, annotations, 2)
+	})
+
+	t.Run("no template with the required node group", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		createTemplates(t, sqlStore)
+
+		createInstallation(t, sqlStore, []string{"multi-tenant"}, &model.InstallationPlacement{NodeGroup: "memory"})
+
+		poolSupervisor := supervisor.NewClusterPoolSupervisor(sqlStore, options, &mockEventProducer{}, "instanceID", logger)
+		err := poolSupervisor.Do()
+		require.NoError(t, err)
+		assert.Empty(t, getClusters(t, sqlStore))
+	})
+
+	t.Run("no matching template", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		createTemplates(t, sqlStore)
+
+		createInstallation(t, sqlStore, []string{"dedicated"}, nil)
+
+		poolSupervisor := supervisor.NewClusterPoolSupervisor(sqlStore, options, &mockEventProducer{}, "instanceID", logger)
+		err := poolSupervisor.Do()
+		require.NoError(t, err)
+		assert.Empty(t, getClusters(t, sqlStore))
+	})
+}
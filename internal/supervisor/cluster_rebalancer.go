@@ -0,0 +1,366 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/k8s"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// clusterRebalancerStore abstracts the database operations required by the
+// cluster rebalancer.
+type clusterRebalancerStore interface {
+	GetClusters(clusterFilter *model.ClusterFilter) ([]*model.Cluster, error)
+	GetClusterMigrationOperations(filter *model.ClusterMigrationFilter) ([]*model.ClusterMigrationOperation, error)
+	CreateClusterMigrationOperation(operation *model.ClusterMigrationOperation) error
+	installationSchedulingStore
+
+	GetSupervisorTask(id string) (*model.SupervisorTask, error)
+	UpdateSupervisorTaskLastRunAt(id string, lastRunAt int64) error
+	supervisorTaskLockStore
+}
+
+// clusterRebalancerTask is the supervisor task guarding rebalancing runs, so
+// that only one provisioning server plans moves at a time.
+const clusterRebalancerTask = "cluster-rebalancer"
+
+// clusterRebalancerProvisioner abstracts the provisioning operations required
+// by the cluster rebalancer.
+type clusterRebalancerProvisioner interface {
+	GetClusterResources(cluster *model.Cluster, onlySchedulable bool, logger log.FieldLogger) (*k8s.ClusterResources, error)
+}
+
+// ClusterRebalancerOptions are the various options that control how clusters
+// are rebalanced.
+type ClusterRebalancerOptions struct {
+	// Execute controls if proposed moves are executed as cluster migration
+	// operations or only logged.
+	Execute bool
+	// UtilizationThreshold is the CPU or memory percentage above which a
+	// cluster is considered overloaded. Installations are only moved to
+	// clusters which stay below the threshold after the move.
+	UtilizationThreshold int
+	// MaxConcurrentMigrations is the maximum number of cluster migration
+	// operations in progress at the same time.
+	MaxConcurrentMigrations int
+	// Interval is the minimum time between rebalancing runs.
+	Interval time.Duration
+}
+
+// ClusterRebalancer periodically checks cluster utilization and moves
+// installations away from overloaded clusters.
+type ClusterRebalancer struct {
+	store       clusterRebalancerStore
+	provisioner clusterRebalancerProvisioner
+	options     ClusterRebalancerOptions
+	instanceID  string
+	logger      log.FieldLogger
+}
+
+// NewClusterRebalancer creates a new ClusterRebalancer.
+func NewClusterRebalancer(store clusterRebalancerStore, provisioner clusterRebalancerProvisioner, options ClusterRebalancerOptions, instanceID string, logger log.FieldLogger) *ClusterRebalancer {
+	return &ClusterRebalancer{
+		store:       store,
+		provisioner: provisioner,
+		options:     options,
+		instanceID:  instanceID,
+		logger:      logger.WithField("supervisor", "cluster-rebalancer"),
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the cluster rebalancer.
+func (r *ClusterRebalancer) Shutdown() {
+	r.logger.Debug("Shutting down cluster rebalancer")
+}
+
+// Do checks cluster utilization and, if needed, proposes or executes
+// installation moves between clusters.
+func (r *ClusterRebalancer) Do() error {
+	lock := newSupervisorTaskLock(clusterRebalancerTask, r.instanceID, r.store, r.logger)
+	if !lock.TryLock() {
+		return nil
+	}
+	defer lock.Unlock()
+
+	task, err := r.store.GetSupervisorTask(clusterRebalancerTask)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get last cluster rebalancing run")
+		return nil
+	}
+	if task != nil && time.Since(model.TimeFromMillis(task.LastRunAt)) < r.options.Interval {
+		return nil
+	}
+	err = r.store.UpdateSupervisorTaskLastRunAt(clusterRebalancerTask, model.GetMillis())
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to record cluster rebalancing run")
+		return nil
+	}
+
+	pendingOperations, err := r.store.GetClusterMigrationOperations(&model.ClusterMigrationFilter{
+		Paging: model.AllPagesNotDeleted(),
+		States: model.AllClusterMigrationOperationsStatesPendingWork,
+	})
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get pending cluster migration operations")
+		return nil
+	}
+
+	moves, err := r.planRebalance(pendingOperations, r.logger)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to plan cluster rebalancing")
+		return nil
+	}
+	if len(moves) == 0 {
+		r.logger.Debug("No cluster rebalancing required")
+		return nil
+	}
+
+	for _, move := range moves {
+		r.logger.WithFields(log.Fields{
+			"installation":   move.installation.ID,
+			"source-cluster": move.sourceClusterInstallation.ClusterID,
+			"target-cluster": move.targetCluster.ID,
+		}).Info("Proposed installation move to rebalance clusters")
+	}
+
+	if !r.options.Execute {
+		return nil
+	}
+
+	available := r.options.MaxConcurrentMigrations - len(pendingOperations)
+	if available <= 0 {
+		r.logger.Debugf("Maximum number of concurrent cluster migrations (%d) reached", r.options.MaxConcurrentMigrations)
+		return nil
+	}
+	if len(moves) > available {
+		moves = moves[:available]
+	}
+
+	for _, move := range moves {
+		operation := &model.ClusterMigrationOperation{
+			InstallationID:    move.installation.ID,
+			SourceClusterID:   move.sourceClusterInstallation.ClusterID,
+			TargetClusterID:   move.targetCluster.ID,
+			LockInstallations: true,
+			State:             model.ClusterMigrationStateRequested,
+		}
+		err = r.store.CreateClusterMigrationOperation(operation)
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to create cluster migration operation")
+			return nil
+		}
+		r.logger.WithField("clusterMigrationOperation", operation.ID).
+			Infof("Requested migration of installation %s from cluster %s to cluster %s", operation.InstallationID, operation.SourceClusterID, operation.TargetClusterID)
+	}
+
+	return nil
+}
+
+// rebalanceMove is a proposed move of a single installation to another cluster.
+type rebalanceMove struct {
+	installation              *model.Installation
+	sourceClusterInstallation *model.ClusterInstallation
+	targetCluster             *model.Cluster
+}
+
+// clusterUtilization holds the projected resource usage of a cluster while
+// planning moves.
+type clusterUtilization struct {
+	cluster   *model.Cluster
+	resources k8s.ClusterResources
+}
+
+func (u *clusterUtilization) overloaded(threshold int) bool {
+	return u.resources.CalculateCPUPercentUsed(0) > threshold ||
+		u.resources.CalculateMemoryPercentUsed(0) > threshold
+}
+
+func (u *clusterUtilization) combinedPercent(cpu, memory int64) int {
+	return (u.resources.CalculateCPUPercentUsed(cpu) + u.resources.CalculateMemoryPercentUsed(memory)) / 2
+}
+
+func (u *clusterUtilization) fits(cpu, memory int64, threshold int) bool {
+	return u.resources.CalculateCPUPercentUsed(cpu) <= threshold &&
+		u.resources.CalculateMemoryPercentUsed(memory) <= threshold
+}
+
+// planRebalance returns the installation moves needed to bring overloaded
+// clusters below the utilization threshold. Clusters and installations already
+// taking part in pending cluster migrations are not considered.
+func (r *ClusterRebalancer) planRebalance(pendingOperations []*model.ClusterMigrationOperation, logger log.FieldLogger) ([]*rebalanceMove, error) {
+	busyClusters := map[string]bool{}
+	busyInstallations := map[string]bool{}
+	for _, operation := range pendingOperations {
+		busyClusters[operation.SourceClusterID] = true
+		busyClusters[operation.TargetClusterID] = true
+		if operation.InstallationID != "" {
+			busyInstallations[operation.InstallationID] = true
+		}
+	}
+
+	// Moves planned during this run are not yet visible to the scheduling
+	// checks, so installations planned for each target are tracked here.
+	// Clusters losing installations are not used as targets in the same run.
+	plannedInstallations := map[string][]*model.Installation{}
+	sourceClusters := map[string]bool{}
+
+	clusters, err := r.store.GetClusters(&model.ClusterFilter{Paging: model.AllPagesNotDeleted()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get clusters")
+	}
+
+	var utilizations []*clusterUtilization
+	for _, cluster := range clusters {
+		if cluster.State != model.ClusterStateStable || cluster.IsEKS() || busyClusters[cluster.ID] {
+			continue
+		}
+		resources, err := r.provisioner.GetClusterResources(cluster, true, logger)
+		if err != nil {
+			logger.WithError(err).Warnf("Failed to get resources of cluster %s", cluster.ID)
+			continue
+		}
+		if resources.MilliTotalCPU == 0 || resources.MilliTotalMemory == 0 {
+			continue
+		}
+		utilizations = append(utilizations, &clusterUtilization{cluster: cluster, resources: *resources})
+	}
+
+	// Handle the most loaded clusters first.
+	sort.SliceStable(utilizations, func(i, j int) bool {
+		return utilizations[i].combinedPercent(0, 0) > utilizations[j].combinedPercent(0, 0)
+	})
+
+	var moves []*rebalanceMove
+	for _, source := range utilizations {
+		if !source.overloaded(r.options.UtilizationThreshold) {
+			continue
+		}
+		logger.Infof("Cluster %s is over the utilization threshold (%d%%): CPU=%d%%, Memory=%d%%",
+			source.cluster.ID,
+			r.options.UtilizationThreshold,
+			source.resources.CalculateCPUPercentUsed(0),
+			source.resources.CalculateMemoryPercentUsed(0),
+		)
+
+		candidates, err := r.getMovableInstallations(source.cluster, busyInstallations)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to get movable installations of cluster %s", source.cluster.ID)
+			continue
+		}
+		// Move the largest installations first to keep the number of moves low.
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return source.combinedPercent(candidates[i].cpu, candidates[i].memory) > source.combinedPercent(candidates[j].cpu, candidates[j].memory)
+		})
+
+		for _, candidate := range candidates {
+			if !source.overloaded(r.options.UtilizationThreshold) {
+				break
+			}
+
+			var target *clusterUtilization
+			for _, potentialTarget := range utilizations {
+				if potentialTarget == source ||
+					sourceClusters[potentialTarget.cluster.ID] ||
+					!potentialTarget.fits(candidate.cpu, candidate.memory, r.options.UtilizationThreshold) {
+					continue
+				}
+				if target != nil &&
+					target.combinedPercent(candidate.cpu, candidate.memory) <= potentialTarget.combinedPercent(candidate.cpu, candidate.memory) {
+					continue
+				}
+				if !installationCanBeScheduledOnCluster(r.store, potentialTarget.cluster, candidate.installation, logger) {
+					continue
+				}
+				// Only multitenant installations are moved, so planned moves
+				// cannot break isolation and only owner anti-affinity has to
+				// be checked against them.
+				if conflictingInstallationID := findOwnerAntiAffinityConflictWithInstallations(candidate.installation, plannedInstallations[potentialTarget.cluster.ID]); conflictingInstallationID != "" {
+					logger.Debugf("Installation %s planned to move to cluster %s conflicts with owner anti-affinity rules", conflictingInstallationID, potentialTarget.cluster.ID)
+					continue
+				}
+				target = potentialTarget
+			}
+			if target == nil {
+				logger.Debugf("No cluster can accept installation %s", candidate.installation.ID)
+				continue
+			}
+
+			source.resources.MilliUsedCPU -= candidate.cpu
+			source.resources.MilliUsedMemory -= candidate.memory
+			target.resources.MilliUsedCPU += candidate.cpu
+			target.resources.MilliUsedMemory += candidate.memory
+			busyInstallations[candidate.installation.ID] = true
+			plannedInstallations[target.cluster.ID] = append(plannedInstallations[target.cluster.ID], candidate.installation)
+			sourceClusters[source.cluster.ID] = true
+
+			moves = append(moves, &rebalanceMove{
+				installation:              candidate.installation,
+				sourceClusterInstallation: candidate.clusterInstallation,
+				targetCluster:             target.cluster,
+			})
+		}
+
+		if source.overloaded(r.options.UtilizationThreshold) {
+			logger.Warnf("Cluster %s will remain over the utilization threshold after rebalancing", source.cluster.ID)
+		}
+	}
+
+	return moves, nil
+}
+
+type movableInstallation struct {
+	installation        *model.Installation
+	clusterInstallation *model.ClusterInstallation
+	cpu                 int64
+	memory              int64
+}
+
+// getMovableInstallations returns stable multitenant installations of the
+// given cluster.
+func (r *ClusterRebalancer) getMovableInstallations(cluster *model.Cluster, busyInstallations map[string]bool) ([]*movableInstallation, error) {
+	isActive := true
+	clusterInstallations, err := r.store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging:    model.AllPagesNotDeleted(),
+		ClusterID: cluster.ID,
+		IsActive:  &isActive,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster installations")
+	}
+
+	var movable []*movableInstallation
+	for _, clusterInstallation := range clusterInstallations {
+		if clusterInstallation.State != model.ClusterInstallationStateStable ||
+			busyInstallations[clusterInstallation.InstallationID] {
+			continue
+		}
+		installation, err := r.store.GetInstallation(clusterInstallation.InstallationID, true, false)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get installation")
+		}
+		if installation == nil ||
+			installation.State != model.InstallationStateStable ||
+			installation.Affinity != model.InstallationAffinityMultiTenant {
+			continue
+		}
+		cpu, memory, err := installationResourceRequirements(installation)
+		if err != nil {
+			return nil, err
+		}
+		movable = append(movable, &movableInstallation{
+			installation:        installation,
+			clusterInstallation: clusterInstallation,
+			cpu:                 cpu,
+			memory:              memory,
+		})
+	}
+
+	return movable, nil
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/k8s"
+	"github.com/mattermost/mattermost-cloud/model"
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClusterRebalancerProvisioner struct {
+	resources map[string]k8s.ClusterResources
+}
+
+func (p *mockClusterRebalancerProvisioner) GetClusterResources(cluster *model.Cluster, onlySchedulable bool, logger log.FieldLogger) (*k8s.ClusterResources, error) {
+	resources := p.resources[cluster.ID]
+	return &resources, nil
+}
+
+func TestClusterRebalancer(t *testing.T) {
+	size, err := mmv1alpha1.GetClusterSize(mmv1alpha1.Size100String)
+	require.NoError(t, err)
+	installationCPU := size.CalculateCPUMilliRequirement(false, false)
+
+	setup := func(t *testing.T, sqlStore *store.SQLStore) (*model.Cluster, *model.Cluster, []*model.ClusterInstallation, *mockClusterRebalancerProvisioner) {
+		overloadedCluster := &model.Cluster{State: model.ClusterStateStable, AllowInstallations: true}
+		err := sqlStore.CreateCluster(overloadedCluster, nil)
+		require.NoError(t, err)
+		emptyCluster := &model.Cluster{State: model.ClusterStateStable, AllowInstallations: true}
+		err = sqlStore.CreateCluster(emptyCluster, nil)
+		require.NoError(t, err)
+
+		var clusterInstallations []*model.ClusterInstallation
+		for i := 0; i < 2; i++ {
+			installation := &model.Installation{
+				OwnerID:   model.NewID(),
+				DNS:       model.NewID() + ".example.com",
+				Size:      mmv1alpha1.Size100String,
+				Affinity:  model.InstallationAffinityMultiTenant,
+				Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+				Filestore: model.InstallationFilestoreBifrost,
+				State:     model.InstallationStateStable,
+			}
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+
+			clusterInstallation := &model.ClusterInstallation{
+				ClusterID:      overloadedCluster.ID,
+				InstallationID: installation.ID,
+				Namespace:      installation.ID,
+				State:          model.ClusterInstallationStateStable,
+				IsActive:       true,
+			}
+			err = sqlStore.CreateClusterInstallation(clusterInstallation)
+			require.NoError(t, err)
+			clusterInstallations = append(clusterInstallations, clusterInstallation)
+		}
+
+		// The overloaded cluster goes below the threshold after moving one
+		// of its installations.
+		provisioner := &mockClusterRebalancerProvisioner{
+			resources: map[string]k8s.ClusterResources{
+				overloadedCluster.ID: {
+					MilliTotalCPU:    10000,
+					MilliUsedCPU:     8000 + installationCPU,
+					MilliTotalMemory: 100000000000000,
+					MilliUsedMemory:  100,
+				},
+				emptyCluster.ID: {
+					MilliTotalCPU:    10000,
+					MilliUsedCPU:     1000,
+					MilliTotalMemory: 100000000000000,
+					MilliUsedMemory:  100,
+				},
+			},
+		}
+
+		return overloadedCluster, emptyCluster, clusterInstallations, provisioner
+	}
+
+	getOperations := func(t *testing.T, sqlStore *store.SQLStore) []*model.ClusterMigrationOperation {
+		operations, err := sqlStore.GetClusterMigrationOperations(&model.ClusterMigrationFilter{
+			Paging: model.AllPagesNotDeleted(),
+		})
+		require.NoError(t, err)
+		return operations
+	}
+
+	t.Run("only propose moves", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, _, _, provisioner := setup(t, sqlStore)
+
+		rebalancer := supervisor.NewClusterRebalancer(sqlStore, provisioner, supervisor.ClusterRebalancerOptions{
+			UtilizationThreshold:    80,
+			MaxConcurrentMigrations: 5,
+		}, "instanceID", logger)
+		err := rebalancer.Do()
+		require.NoError(t, err)
+
+		assert.Empty(t, getOperations(t, sqlStore))
+	})
+
+	t.Run("execute moves", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		overloadedCluster, emptyCluster, clusterInstallations, provisioner := setup(t, sqlStore)
+
+		rebalancer := supervisor.NewClusterRebalancer(sqlStore, provisioner, supervisor.ClusterRebalancerOptions{
+			Execute:                 true,
+			UtilizationThreshold:    80,
+			MaxConcurrentMigrations: 5,
+		}, "instanceID", logger)
+		err := rebalancer.Do()
+		require.NoError(t, err)
+
+		operations := getOperations(t, sqlStore)
+		require.Len(t, operations, 1)
+		assert.Equal(t, model.ClusterMigrationStateRequested, operations[0].State)
+		assert.Equal(t, overloadedCluster.ID, operations[0].SourceClusterID)
+		assert.Equal(t, emptyCluster.ID, operations[0].TargetClusterID)
+		assert.True(t, operations[0].LockInstallations)
+
+		var installationIDs []string
+		for _, ci := range clusterInstallations {
+			installationIDs = append(installationIDs, ci.InstallationID)
+		}
+		assert.Contains(t, installationIDs, operations[0].InstallationID)
+
+		t.Run("clusters with pending migrations are skipped", func(t *testing.T) {
+			err = rebalancer.Do()
+			require.NoError(t, err)
+			assert.Len(t, getOperations(t, sqlStore), 1)
+		})
+	})
+
+	t.Run("target cluster not allowing installations", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, emptyCluster, _, provisioner := setup(t, sqlStore)
+		emptyCluster.AllowInstallations = false
+		err := sqlStore.UpdateCluster(emptyCluster)
+		require.NoError(t, err)
+
+		rebalancer := supervisor.NewClusterRebalancer(sqlStore, provisioner, supervisor.ClusterRebalancerOptions{
+			Execute:                 true,
+			UtilizationThreshold:    80,
+			MaxConcurrentMigrations: 5,
+		}, "instanceID", logger)
+		err = rebalancer.Do()
+		require.NoError(t, err)
+
+		assert.Empty(t, getOperations(t, sqlStore))
+	})
+
+	t.Run("max concurrent migrations reached", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, _, _, provisioner := setup(t, sqlStore)
+
+		rebalancer := supervisor.NewClusterRebalancer(sqlStore, provisioner, supervisor.ClusterRebalancerOptions{
+			Execute:                 true,
+			UtilizationThreshold:    80,
+			MaxConcurrentMigrations: 0,
+		}, "instanceID", logger)
+		err := rebalancer.Do()
+		require.NoError(t, err)
+
+		assert.Empty(t, getOperations(t, sqlStore))
+	})
+	t.Run("moves planned in the same run are checked for owner anti-affinity", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		overloadedCluster, emptyCluster, clusterInstallations, provisioner := setup(t, sqlStore)
+		err := sqlStore.DeleteClusterInstallation(clusterInstallations[0].ID)
+		require.NoError(t, err)
+		installation := &model.Installation{
+			OwnerID:   model.NewID(),
+			DNS:       model.NewID() + ".example.com",
+			Size:      mmv1alpha1.Size100String,
+			Affinity:  model.InstallationAffinityMultiTenant,
+			Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+			Filestore: model.InstallationFilestoreBifrost,
+			Placement: &model.InstallationPlacement{OwnerAntiAffinity: true},
+			State:     model.InstallationStateStable,
+		}
+		err = sqlStore.CreateInstallation(installation, nil)
+		require.NoError(t, err)
+		err = sqlStore.CreateClusterInstallation(&model.ClusterInstallation{
+			ClusterID:      overloadedCluster.ID,
+			InstallationID: installation.ID,
+			Namespace:      installation.ID,
+			State:          model.ClusterInstallationStateStable,
+			IsActive:       true,
+		})
+		require.NoError(t, err)
+
+		// Both installations have to be moved and fit on the empty cluster,
+		// but they cannot share it.
+		provisioner.resources[overloadedCluster.ID] = k8s.ClusterResources{
+			MilliTotalCPU:    10000,
+			MilliUsedCPU:     8000 + 2*installationCPU,
+			MilliTotalMemory: 100000000000000,
+			MilliUsedMemory:  100,
+		}
+		provisioner.resources[emptyCluster.ID] = k8s.ClusterResources{
+			MilliTotalCPU:    1000000,
+			MilliUsedCPU:     1000,
+			MilliTotalMemory: 100000000000000,
+			MilliUsedMemory:  100,
+		}
+
+		rebalancer := supervisor.NewClusterRebalancer(sqlStore, provisioner, supervisor.ClusterRebalancerOptions{
+			Execute:                 true,
+			UtilizationThreshold:    80,
+			MaxConcurrentMigrations: 5,
+		}, "instanceID", logger)
+		err = rebalancer.Do()
+		require.NoError(t, err)
+
+		assert.Len(t, getOperations(t, sqlStore), 1)
+	})
+
+	t.Run("run only once per interval across provisioners", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		_, _, _, provisioner := setup(t, sqlStore)
+		options := supervisor.ClusterRebalancerOptions{
+			Execute:                 true,
+			UtilizationThreshold:    80,
+			MaxConcurrentMigrations: 5,
+			Interval:                time.Hour,
+		}
+
+		locked, err := sqlStore.LockSupervisorTask("cluster-rebalancer", "otherInstance")
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		rebalancer := supervisor.NewClusterRebalancer(sqlStore, provisioner, options, "instanceID", logger)
+		err = rebalancer.Do()
+		require.NoError(t, err)
+		assert.Empty(t, getOperations(t, sqlStore))
+
+		unlocked, err := sqlStore.UnlockSupervisorTask("cluster-rebalancer", "otherInstance", false)
+		require.NoError(t, err)
+		require.True(t, unlocked)
+
+		err = rebalancer.Do()
+		require.NoError(t, err)
+		assert.Len(t, getOperations(t, sqlStore), 1)
+
+		// Cancel the requested migration so that the clusters are no longer
+		// busy and only the interval prevents another run.
+		operation := getOperations(t, sqlStore)[0]
+		operation.State = model.ClusterMigrationStateFailed
+		err = sqlStore.UpdateClusterMigrationOperationState(operation)
+		require.NoError(t, err)
+
+		otherRebalancer := supervisor.NewClusterRebalancer(sqlStore, provisioner, options, "otherInstance", logger)
+		err = otherRebalancer.Do()
+		require.NoError(t, err)
+		assert.Len(t, getOperations(t, sqlStore), 1)
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost-cloud/internal/events"
+	"github.com/mattermost/mattermost-cloud/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	spotDrainStatusNoticeReceived = "termination-notice-received"
+	spotDrainStatusDrained        = "drained"
+	spotDrainStatusFailed         = "drain-failed"
+)
+
+// drainTerminatingSpotNodes checks the spot nodes of all stable clusters with
+// spot node groups and drains the ones which received a termination notice.
+func (s *ClusterSupervisor) drainTerminatingSpotNodes() {
+	clusters, err := s.store.GetClusters(&model.ClusterFilter{Paging: model.AllPagesNotDeleted()})
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to query clusters for spot node termination")
+		return
+	}
+
+	for _, cluster := range clusters {
+		if cluster.State != model.ClusterStateStable || cluster.ProvisionerMetadataKops == nil {
+			continue
+		}
+		if !cluster.ProvisionerMetadataKops.CustomInstanceGroups.HasSpotNodeGroups() {
+			continue
+		}
+
+		s.drainTerminatingSpotNodesOfCluster(cluster)
+	}
+}
+
+func (s *ClusterSupervisor) drainTerminatingSpotNodesOfCluster(cluster *model.Cluster) {
+	logger := s.logger.WithFields(log.Fields{
+		"cluster": cluster.ID,
+		"action":  "spot-drain",
+	})
+
+	lock := newClusterLock(cluster.ID, s.instanceID, s.store, logger)
+	if !lock.TryLock() {
+		return
+	}
+	defer lock.Unlock()
+
+	cluster, err := s.store.GetCluster(cluster.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get refreshed cluster")
+		return
+	}
+	if cluster == nil || cluster.State != model.ClusterStateStable {
+		return
+	}
+
+	nodes, err := s.provisioner.GetSpotNodes(cluster, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get spot nodes")
+		return
+	}
+
+	// Forget drained nodes which are gone, node names are not reused while
+	// the node exists.
+	drained := make(map[string]struct{})
+	nodesByInstanceID := make(map[string]model.ClusterNode, len(nodes))
+	var instanceIDs []string
+	for _, node := range nodes {
+		if _, ok := s.drainedSpotNodes[cluster.ID][node.Name]; ok {
+			drained[node.Name] = struct{}{}
+			continue
+		}
+		if len(node.InstanceID) == 0 {
+			continue
+		}
+		nodesByInstanceID[node.InstanceID] = node
+		instanceIDs = append(instanceIDs, node.InstanceID)
+	}
+	s.drainedSpotNodes[cluster.ID] = drained
+
+	terminatingInstanceIDs, err := s.aws.GetSpotInstancesMarkedForTermination(instanceIDs, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to check spot instances for termination notices")
+		return
+	}
+
+	for _, instanceID := range terminatingInstanceIDs {
+		node, ok := nodesByInstanceID[instanceID]
+		if !ok {
+			continue
+		}
+		nodeLogger := logger.WithField("node", node.Name)
+		nodeLogger.Infof("Spot instance %s of node group %s received a termination notice", instanceID, node.NodeGroup)
+		s.produceSpotDrainEvent(cluster, node, spotDrainStatusNoticeReceived, nodeLogger)
+
+		evicted, err := s.provisioner.DrainClusterNode(cluster, node.Name, nodeLogger)
+		if err != nil {
+			nodeLogger.WithError(err).Error("Failed to drain spot node")
+			s.produceSpotDrainEvent(cluster, node, spotDrainStatusFailed, nodeLogger,
+				events.DataField{Key: "SpotDrainError", Value: err.Error()},
+			)
+			continue
+		}
+
+		drained[node.Name] = struct{}{}
+		s.produceSpotDrainEvent(cluster, node, spotDrainStatusDrained, nodeLogger,
+			events.DataField{Key: "SpotDrainEvictedPodCount", Value: fmt.Sprintf("%d", len(evicted))},
+			events.DataField{Key: "SpotDrainEvictedPods", Value: strings.Join(evicted, ",")},
+		)
+	}
+}
+
+// produceSpotDrainEvent reports the drain progress of a spot node as a cluster
+// event. The cluster state is not changed by draining.
+func (s *ClusterSupervisor) produceSpotDrainEvent(cluster *model.Cluster, node model.ClusterNode, status string, logger log.FieldLogger, extraDataFields ...events.DataField) {
+	dataFields := append([]events.DataField{
+		{Key: "SpotDrainNode", Value: node.Name},
+		{Key: "SpotDrainInstanceID", Value: node.InstanceID},
+		{Key: "SpotDrainNodeGroup", Value: node.NodeGroup},
+		{Key: "SpotDrainStatus", Value: status},
+	}, extraDataFields...)
+
+	err := s.eventsProducer.ProduceClusterStateChangeEvent(cluster, cluster.State, dataFields...)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create cluster state change event")
+	}
+}
@@ -5,7 +5,9 @@
 package supervisor_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/mattermost/mattermost-cloud/internal/testutil"
 
@@ -13,7 +15,11 @@ import (
 	"github.com/mattermost/mattermost-cloud/internal/supervisor"
 	"github.com/mattermost/mattermost-cloud/internal/testlib"
 	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/k8s"
 	"github.com/mattermost/mattermost-cloud/model"
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -58,11 +64,25 @@ func (s *mockClusterStore) DeleteCluster(clusterID string) error {
 	return nil
 }
 
+func (s *mockClusterStore) GetClusterInstallations(filter *model.ClusterInstallationFilter) ([]*model.ClusterInstallation, error) {
+	return nil, nil
+}
+
+func (s *mockClusterStore) GetInstallations(installationFilter *model.InstallationFilter, includeGroupConfig, includeGroupConfigOverrides bool) ([]*model.Installation, error) {
+	return nil, nil
+}
+
 func (s *mockClusterStore) GetWebhooks(filter *model.WebhookFilter) ([]*model.Webhook, error) {
 	return nil, nil
 }
 
-type mockClusterProvisioner struct{}
+type mockClusterProvisioner struct {
+	ClusterResources  *k8s.ClusterResources
+	SpotNodes         []model.ClusterNode
+	DrainedNodes      []string
+	UpgradedUtilities []string
+	UtilityUpgradeErr error
+}
 
 func (p *mockClusterProvisioner) PrepareCluster(cluster *model.Cluster) bool {
 	return true
@@ -80,6 +100,16 @@ func (p *mockClusterProvisioner) UpgradeCluster(cluster *model.Cluster, aws aws.
 	return nil
 }
 
+func (p *mockClusterProvisioner) UpgradeClusterUtility(cluster *model.Cluster, utilityName string, aws aws.AWS) error {
+	p.UpgradedUtilities = append(p.UpgradedUtilities, utilityName)
+	if p.UtilityUpgradeErr != nil {
+		cluster.GetUtilityUpgrade(utilityName).Complete(model.UtilityUpgradeStateRolledBack, p.UtilityUpgradeErr)
+		return p.UtilityUpgradeErr
+	}
+	cluster.GetUtilityUpgrade(utilityName).Complete(model.UtilityUpgradeStateUpgraded, nil)
+	return nil
+}
+
 func (p *mockClusterProvisioner) ResizeCluster(cluster *model.Cluster, aws aws.AWS) error {
 	return nil
 }
@@ -92,6 +122,28 @@ func (p *mockClusterProvisioner) RefreshKopsMetadata(cluster *model.Cluster) err
 	return nil
 }
 
+func (p *mockClusterProvisioner) GetClusterResources(cluster *model.Cluster, onlySchedulable bool, logger log.FieldLogger) (*k8s.ClusterResources, error) {
+	return p.ClusterResources, nil
+}
+
+func (p *mockClusterProvisioner) GetSpotNodes(cluster *model.Cluster, logger log.FieldLogger) ([]model.ClusterNode, error) {
+	return p.SpotNodes, nil
+}
+
+func (p *mockClusterProvisioner) DrainClusterNode(cluster *model.Cluster, nodeName string, logger log.FieldLogger) ([]string, error) {
+	p.DrainedNodes = append(p.DrainedNodes, nodeName)
+	return []string{"installation/mattermost-0"}, nil
+}
+
+type mockSpotTerminationAWS struct {
+	mockAWS
+	TerminatingInstanceIDs []string
+}
+
+func (a *mockSpotTerminationAWS) GetSpotInstancesMarkedForTermination(instanceIDs []string, logger log.FieldLogger) ([]string, error) {
+	return a.TerminatingInstanceIDs, nil
+}
+
 func TestClusterSupervisorDo(t *testing.T) {
 	t.Run("no clusters pending work", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
@@ -102,6 +154,7 @@ func TestClusterSupervisorDo(t *testing.T) {
 			&mockClusterProvisioner{},
 			&mockAWS{},
 			&mockEventProducer{},
+			supervisor.ClusterAutoscalingOptions{},
 			"instanceID",
 			logger,
 		)
@@ -127,6 +180,7 @@ func TestClusterSupervisorDo(t *testing.T) {
 			&mockClusterProvisioner{},
 			&mockAWS{},
 			&mockEventProducer{},
+			supervisor.ClusterAutoscalingOptions{},
 			"instanceID",
 			logger,
 		)
@@ -148,6 +202,7 @@ func TestClusterSupervisorSupervise(t *testing.T) {
 		{"creation requested", model.ClusterStateCreationRequested, model.ClusterStateStable},
 		{"provision requested", model.ClusterStateProvisioningRequested, model.ClusterStateStable},
 		{"upgrade requested", model.ClusterStateUpgradeRequested, model.ClusterStateStable},
+		{"utility upgrade requested", model.ClusterStateUtilityUpgradeRequested, model.ClusterStateStable},
 		{"resize requested", model.ClusterStateResizeRequested, model.ClusterStateStable},
 		{"deletion requested", model.ClusterStateDeletionRequested, model.ClusterStateDeleted},
 		{"refresh metadata", model.ClusterStateRefreshMetadata, model.ClusterStateStable},
@@ -163,6 +218,7 @@ func TestClusterSupervisorSupervise(t *testing.T) {
 				&mockClusterProvisioner{},
 				&mockAWS{},
 				testutil.SetupTestEventsProducer(sqlStore, logger),
+				supervisor.ClusterAutoscalingOptions{},
 				"instanceID",
 				logger,
 			)
@@ -192,6 +248,7 @@ func TestClusterSupervisorSupervise(t *testing.T) {
 			&mockClusterProvisioner{},
 			&mockAWS{},
 			testutil.SetupTestEventsProducer(sqlStore, logger),
+			supervisor.ClusterAutoscalingOptions{},
 			"instanceID",
 			logger,
 		)
@@ -215,3 +272,356 @@ func TestClusterSupervisorSupervise(t *testing.T) {
 		require.Equal(t, model.ClusterStateDeletionRequested, cluster.State)
 	})
 }
+
+func TestClusterSupervisorUtilityUpgrade(t *testing.T) {
+	setup := func(t *testing.T, sqlStore *store.SQLStore) *model.Cluster {
+		cluster := &model.Cluster{
+			Provider:                model.ProviderAWS,
+			ProvisionerMetadataKops: &model.KopsMetadata{},
+			State:                   model.ClusterStateUtilityUpgradeRequested,
+		}
+		cluster.RequestUtilityUpgrade(model.PromtailCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "3.11.0"})
+		cluster.RequestUtilityUpgrade(model.NginxCanonicalName, &model.UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		err := sqlStore.CreateCluster(cluster, nil)
+		require.NoError(t, err)
+
+		return cluster
+	}
+
+	t.Run("success", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		provisioner := &mockClusterProvisioner{}
+		supervisor := supervisor.NewClusterSupervisor(
+			sqlStore,
+			provisioner,
+			&mockAWS{},
+			testutil.SetupTestEventsProducer(sqlStore, logger),
+			supervisor.ClusterAutoscalingOptions{},
+			"instanceID",
+			logger,
+		)
+
+		cluster := setup(t, sqlStore)
+		supervisor.Supervise(cluster)
+
+		assert.Equal(t, []string{model.NginxCanonicalName, model.PromtailCanonicalName}, provisioner.UpgradedUtilities)
+
+		cluster, err := sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateStable, cluster.State)
+		assert.Equal(t, model.UtilityUpgradeStateUpgraded, cluster.GetUtilityUpgrade(model.NginxCanonicalName).State)
+		assert.Equal(t, model.UtilityUpgradeStateUpgraded, cluster.GetUtilityUpgrade(model.PromtailCanonicalName).State)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		provisioner := &mockClusterProvisioner{UtilityUpgradeErr: errors.New("timed out")}
+		supervisor := supervisor.NewClusterSupervisor(
+			sqlStore,
+			provisioner,
+			&mockAWS{},
+			testutil.SetupTestEventsProducer(sqlStore, logger),
+			supervisor.ClusterAutoscalingOptions{},
+			"instanceID",
+			logger,
+		)
+
+		cluster := setup(t, sqlStore)
+		supervisor.Supervise(cluster)
+
+		assert.Equal(t, []string{model.NginxCanonicalName}, provisioner.UpgradedUtilities)
+
+		cluster, err := sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateUtilityUpgradeFailed, cluster.State)
+		nginxUpgrade := cluster.GetUtilityUpgrade(model.NginxCanonicalName)
+		assert.Equal(t, model.UtilityUpgradeStateRolledBack, nginxUpgrade.State)
+		assert.Equal(t, "timed out", nginxUpgrade.Error)
+		assert.Equal(t, []string{model.PromtailCanonicalName}, cluster.PendingUtilityUpgrades())
+	})
+}
+
+func TestClusterSupervisorAutoscaling(t *testing.T) {
+	size, err := mmv1alpha1.GetClusterSize(mmv1alpha1.Size1000String)
+	require.NoError(t, err)
+	installationCPU := size.CalculateCPUMilliRequirement(false, false)
+	installationMemory := size.CalculateMemoryMilliRequirement(false, false)
+
+	autoscalingOptions := supervisor.ClusterAutoscalingOptions{
+		Enabled:            true,
+		ScaleUpThreshold:   80,
+		ScaleDownThreshold: 40,
+		MinNodeCount:       2,
+		Cooldown:           time.Hour,
+	}
+
+	setup := func(t *testing.T, sqlStore *store.SQLStore, installationCount int) *model.Cluster {
+		cluster := &model.Cluster{
+			Provider: model.ProviderAWS,
+			ProvisionerMetadataKops: &model.KopsMetadata{
+				NodeMinCount:     2,
+				NodeMaxCount:     8,
+				NodeDesiredCount: 4,
+				NodeInstanceGroups: model.KopsInstanceGroupsMetadata{
+					"nodes-a": {NodeMinCount: 2, NodeMaxCount: 4},
+					"nodes-b": {NodeMinCount: 2, NodeMaxCount: 4},
+				},
+			},
+			State:              model.ClusterStateStable,
+			AllowInstallations: true,
+		}
+		err := sqlStore.CreateCluster(cluster, nil)
+		require.NoError(t, err)
+
+		for i := 0; i < installationCount; i++ {
+			installation := &model.Installation{
+				OwnerID:   model.NewID(),
+				DNS:       model.NewID() + ".example.com",
+				Size:      mmv1alpha1.Size1000String,
+				Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+				Filestore: model.InstallationFilestoreMultiTenantAwsS3,
+				Affinity:  model.InstallationAffinityMultiTenant,
+				State:     model.InstallationStateStable,
+			}
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+
+			err = sqlStore.CreateClusterInstallation(&model.ClusterInstallation{
+				ClusterID:      cluster.ID,
+				InstallationID: installation.ID,
+				Namespace:      installation.ID,
+				State:          model.ClusterInstallationStateStable,
+				IsActive:       true,
+			})
+			require.NoError(t, err)
+		}
+
+		return cluster
+	}
+
+	// Each of the 4 worker nodes fits exactly 2 installations.
+	provisioner := &mockClusterProvisioner{
+		ClusterResources: &k8s.ClusterResources{
+			MilliTotalCPU:    8 * installationCPU,
+			MilliTotalMemory: 8 * installationMemory,
+		},
+	}
+
+	t.Run("scale up", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		cluster := setup(t, sqlStore, 8)
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockAWS{}, &mockEventProducer{}, autoscalingOptions, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, cluster.State)
+		require.NotNil(t, cluster.ProvisionerMetadataKops.ChangeRequest)
+		assert.Equal(t, int64(5), cluster.ProvisionerMetadataKops.ChangeRequest.NodeDesiredCount)
+		assert.Zero(t, cluster.ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+		assert.Zero(t, cluster.ProvisionerMetadataKops.ChangeRequest.NodeMaxCount)
+		assert.Equal(t, int64(2), cluster.ProvisionerMetadataKops.NodeMinCount)
+		assert.Equal(t, int64(8), cluster.ProvisionerMetadataKops.NodeMaxCount)
+		assert.NotZero(t, cluster.ProvisionerMetadataKops.AutoscaledAt)
+	})
+
+	t.Run("scale up is capped at max node count", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		cluster := setup(t, sqlStore, 20)
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockAWS{}, &mockEventProducer{}, autoscalingOptions, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, cluster.State)
+		assert.Equal(t, int64(8), cluster.ProvisionerMetadataKops.ChangeRequest.NodeDesiredCount)
+	})
+
+	t.Run("scale down", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		cluster := setup(t, sqlStore, 2)
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockAWS{}, &mockEventProducer{}, autoscalingOptions, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, cluster.State)
+		assert.Equal(t, int64(2), cluster.ProvisionerMetadataKops.ChangeRequest.NodeDesiredCount)
+		assert.Zero(t, cluster.ProvisionerMetadataKops.ChangeRequest.NodeMinCount)
+		assert.Equal(t, int64(2), cluster.ProvisionerMetadataKops.NodeMinCount)
+	})
+
+	t.Run("scale down keeps cluster node min count", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		cluster := setup(t, sqlStore, 2)
+		cluster.ProvisionerMetadataKops.NodeMinCount = 3
+		err := sqlStore.UpdateCluster(cluster)
+		require.NoError(t, err)
+
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockAWS{}, &mockEventProducer{}, autoscalingOptions, "instanceID", logger)
+
+		err = clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, cluster.State)
+		assert.Equal(t, int64(3), cluster.ProvisionerMetadataKops.ChangeRequest.NodeDesiredCount)
+		assert.Equal(t, int64(3), cluster.ProvisionerMetadataKops.NodeMinCount)
+	})
+
+	t.Run("no scaling within thresholds", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		cluster := setup(t, sqlStore, 5)
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockAWS{}, &mockEventProducer{}, autoscalingOptions, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateStable, cluster.State)
+		assert.Nil(t, cluster.ProvisionerMetadataKops.ChangeRequest)
+	})
+
+	t.Run("cooldown", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		cluster := setup(t, sqlStore, 8)
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockAWS{}, &mockEventProducer{}, autoscalingOptions, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		// Resizing finished, but the cluster is still in the cooldown period,
+		// also for the supervisors of other provisioners.
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		cluster.State = model.ClusterStateStable
+		cluster.ProvisionerMetadataKops.ClearChangeRequest()
+		err = sqlStore.UpdateCluster(cluster)
+		require.NoError(t, err)
+
+		clusterSupervisor = supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockAWS{}, &mockEventProducer{}, autoscalingOptions, "instanceID2", logger)
+		err = clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateStable, cluster.State)
+
+		// The cooldown period is over.
+		cluster.ProvisionerMetadataKops.AutoscaledAt = model.GetMillis() - (2 * time.Hour).Milliseconds()
+		err = sqlStore.UpdateCluster(cluster)
+		require.NoError(t, err)
+
+		err = clusterSupervisor.Do()
+		require.NoError(t, err)
+
+		cluster, err = sqlStore.GetCluster(cluster.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.ClusterStateResizeRequested, cluster.State)
+	})
+}
+
+func TestClusterSupervisorSpotDrain(t *testing.T) {
+	setup := func(t *testing.T, sqlStore *store.SQLStore, maxPrice string) *model.Cluster {
+		cluster := &model.Cluster{
+			Provider: model.ProviderAWS,
+			ProvisionerMetadataKops: &model.KopsMetadata{
+				NodeMinCount: 2,
+				NodeMaxCount: 2,
+				CustomInstanceGroups: model.KopsInstanceGroupsMetadata{
+					"spot": {NodeMinCount: 2, NodeMaxCount: 4, MaxPrice: maxPrice},
+				},
+			},
+			State:              model.ClusterStateStable,
+			AllowInstallations: true,
+		}
+		err := sqlStore.CreateCluster(cluster, nil)
+		require.NoError(t, err)
+
+		return cluster
+	}
+
+	spotNodes := []model.ClusterNode{
+		{Name: "node-1", InstanceID: "i-1", NodeGroup: "spot"},
+		{Name: "node-2", InstanceID: "i-2", NodeGroup: "spot"},
+	}
+
+	t.Run("drain terminating spot node", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		setup(t, sqlStore, "0.05")
+		provisioner := &mockClusterProvisioner{SpotNodes: spotNodes}
+		awsClient := &mockSpotTerminationAWS{TerminatingInstanceIDs: []string{"i-2"}}
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, awsClient, &mockEventProducer{}, supervisor.ClusterAutoscalingOptions{}, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"node-2"}, provisioner.DrainedNodes)
+
+		// Already drained nodes are not drained again.
+		err = clusterSupervisor.Do()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"node-2"}, provisioner.DrainedNodes)
+	})
+
+	t.Run("no termination notice", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		setup(t, sqlStore, "0.05")
+		provisioner := &mockClusterProvisioner{SpotNodes: spotNodes}
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, &mockSpotTerminationAWS{}, &mockEventProducer{}, supervisor.ClusterAutoscalingOptions{}, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+		assert.Empty(t, provisioner.DrainedNodes)
+	})
+
+	t.Run("no spot node groups", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		setup(t, sqlStore, "")
+		provisioner := &mockClusterProvisioner{SpotNodes: spotNodes}
+		awsClient := &mockSpotTerminationAWS{TerminatingInstanceIDs: []string{"i-1", "i-2"}}
+		clusterSupervisor := supervisor.NewClusterSupervisor(sqlStore, provisioner, awsClient, &mockEventProducer{}, supervisor.ClusterAutoscalingOptions{}, "instanceID", logger)
+
+		err := clusterSupervisor.Do()
+		require.NoError(t, err)
+		assert.Empty(t, provisioner.DrainedNodes)
+	})
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/mattermost/mattermost-cloud/internal/webhook"
 	"github.com/mattermost/mattermost-cloud/model"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // installationDBMigrationStore abstracts the database operations required by the supervisor.
@@ -57,6 +58,12 @@ type installationDBMigrationStore interface {
 type dbMigrationCIProvisioner interface {
 	ClusterInstallationProvisioner(version string) provisioner.ClusterInstallationProvisioner
 	ExecClusterInstallationJob(cluster *model.Cluster, clusterInstallation *model.ClusterInstallation, args ...string) error
+	EnsureDBEngineMigrationSecret(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation, role string, secret *corev1.Secret) error
+	TriggerDBEngineMigrationJob(cluster *model.Cluster, installation *model.Installation, dbMigration *model.InstallationDBMigrationOperation) error
+	CheckDBEngineMigrationJobStatus(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) (int64, error)
+	CleanupDBEngineMigrationJob(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error
+	VerifyDBEngineMigrationRowCounts(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error
+	CleanupDBEngineMigrationSecrets(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error
 }
 
 type databaseProvider interface {
@@ -189,9 +196,18 @@ func (s *DBMigrationSupervisor) Supervise(migration *model.InstallationDBMigrati
 func (s *DBMigrationSupervisor) transitionMigration(dbMigration *model.InstallationDBMigrationOperation, instanceID string, logger log.FieldLogger) model.InstallationDBMigrationOperationState {
 	switch dbMigration.State {
 	case model.InstallationDBMigrationStateRequested:
+		if dbMigration.IsEngineMigration() {
+			return s.prepareEngineMigration(dbMigration, instanceID, logger)
+		}
 		return s.triggerInstallationBackup(dbMigration, instanceID, logger)
 	case model.InstallationDBMigrationStateBackupInProgress:
 		return s.waitForInstallationBackup(dbMigration, instanceID, logger)
+	case model.InstallationDBMigrationStateEngineMigrationSetup:
+		return s.setupEngineMigration(dbMigration, instanceID, logger)
+	case model.InstallationDBMigrationStateEngineMigrationInProgress:
+		return s.waitForEngineMigration(dbMigration, instanceID, logger)
+	case model.InstallationDBMigrationStateVerifyingRowCounts:
+		return s.verifyRowCounts(dbMigration, instanceID, logger)
 	case model.InstallationDBMigrationStateDatabaseSwitch:
 		return s.switchDatabase(dbMigration, instanceID, logger)
 	case model.InstallationDBMigrationStateRefreshSecrets:
@@ -264,7 +280,11 @@ func (s *DBMigrationSupervisor) waitForInstallationBackup(dbMigration *model.Ins
 	}
 }
 
-func (s *DBMigrationSupervisor) switchDatabase(dbMigration *model.InstallationDBMigrationOperation, instanceID string, logger log.FieldLogger) model.InstallationDBMigrationOperationState {
+// prepareEngineMigration stores the connection details of the source database
+// for the engine migration jobs while the installation is still registered in it.
+// The source database is left intact during the engine migration, therefore no
+// backup is taken.
+func (s *DBMigrationSupervisor) prepareEngineMigration(dbMigration *model.InstallationDBMigrationOperation, instanceID string, logger log.FieldLogger) model.InstallationDBMigrationOperationState {
 	installation, lock, err := getAndLockInstallation(s.store, dbMigration.InstallationID, instanceID, logger)
 	if err != nil {
 		logger.WithError(err).Error("Failed to get and lock installation")
@@ -272,8 +292,43 @@ func (s *DBMigrationSupervisor) switchDatabase(dbMigration *model.InstallationDB
 	}
 	defer lock.Unlock()
 
+	cluster, err := s.getInstallationCluster(installation.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get cluster for installation")
+		return dbMigration.State
+	}
+
 	sourceDB := s.dbProvider.GetDatabase(installation.ID, dbMigration.SourceDatabase)
+	sourceSecret, err := sourceDB.GenerateDatabaseSecret(s.store, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to generate source database secret")
+		return dbMigration.State
+	}
+
+	err = s.dbMigrationCIProvisioner.EnsureDBEngineMigrationSecret(cluster, dbMigration, provisioner.DBEngineMigrationSourceDatabase, sourceSecret)
+	if err != nil {
+		logger.WithError(err).Error("Failed to ensure source database secret for engine migration")
+		return dbMigration.State
+	}
+
+	return model.InstallationDBMigrationStateEngineMigrationSetup
+}
+
+func (s *DBMigrationSupervisor) setupEngineMigration(dbMigration *model.InstallationDBMigrationOperation, instanceID string, logger log.FieldLogger) model.InstallationDBMigrationOperationState {
+	installation, lock, err := getAndLockInstallation(s.store, dbMigration.InstallationID, instanceID, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get and lock installation")
+		return dbMigration.State
+	}
+	defer lock.Unlock()
 
+	cluster, err := s.getInstallationCluster(installation.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get cluster for installation")
+		return dbMigration.State
+	}
+
+	sourceDB := s.dbProvider.GetDatabase(installation.ID, dbMigration.SourceDatabase)
 	err = sourceDB.MigrateOut(s.store, dbMigration, logger)
 	if err != nil {
 		logger.WithError(err).Errorf("Failed to migrate installation out of database")
@@ -287,6 +342,117 @@ func (s *DBMigrationSupervisor) switchDatabase(dbMigration *model.InstallationDB
 		return dbMigration.State
 	}
 
+	destinationSecret, err := destinationDB.GenerateDatabaseSecret(s.store, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to generate destination database secret")
+		return dbMigration.State
+	}
+
+	err = s.dbMigrationCIProvisioner.EnsureDBEngineMigrationSecret(cluster, dbMigration, provisioner.DBEngineMigrationDestinationDatabase, destinationSecret)
+	if err != nil {
+		logger.WithError(err).Error("Failed to ensure destination database secret for engine migration")
+		return dbMigration.State
+	}
+
+	err = s.dbMigrationCIProvisioner.TriggerDBEngineMigrationJob(cluster, installation, dbMigration)
+	if err != nil {
+		logger.WithError(err).Error("Failed to trigger database engine migration job")
+		return dbMigration.State
+	}
+
+	return model.InstallationDBMigrationStateEngineMigrationInProgress
+}
+
+func (s *DBMigrationSupervisor) waitForEngineMigration(dbMigration *model.InstallationDBMigrationOperation, instanceID string, logger log.FieldLogger) model.InstallationDBMigrationOperationState {
+	cluster, err := s.getInstallationCluster(dbMigration.InstallationID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get cluster for installation")
+		return dbMigration.State
+	}
+
+	completeAt, err := s.dbMigrationCIProvisioner.CheckDBEngineMigrationJobStatus(cluster, dbMigration)
+	if err != nil {
+		if err == provisioner.ErrJobBackoffLimitReached {
+			logger.WithError(err).Error("Database engine migration job backoff limit reached, migration failed")
+			return model.InstallationDBMigrationStateFailing
+		}
+		logger.WithError(err).Error("Failed to check database engine migration job status")
+		return dbMigration.State
+	}
+	if completeAt <= 0 {
+		logger.Debug("Database engine migration in progress")
+		return dbMigration.State
+	}
+
+	logger.Info("Database engine migration finished successfully")
+
+	err = s.dbMigrationCIProvisioner.CleanupDBEngineMigrationJob(cluster, dbMigration)
+	if err != nil {
+		logger.WithError(err).Error("Failed to cleanup database engine migration job")
+		return dbMigration.State
+	}
+
+	return model.InstallationDBMigrationStateVerifyingRowCounts
+}
+
+// verifyRowCounts compares the row counts of the migrated tables in the
+// source and destination database before the installation is switched to the
+// destination database. Failures to reach the databases are retried.
+func (s *DBMigrationSupervisor) verifyRowCounts(dbMigration *model.InstallationDBMigrationOperation, instanceID string, logger log.FieldLogger) model.InstallationDBMigrationOperationState {
+	cluster, err := s.getInstallationCluster(dbMigration.InstallationID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get cluster for installation")
+		return dbMigration.State
+	}
+
+	err = s.dbMigrationCIProvisioner.VerifyDBEngineMigrationRowCounts(cluster, dbMigration)
+	if err != nil {
+		if err == provisioner.ErrDBEngineMigrationRowCountMismatch {
+			logger.WithError(err).Error("Row counts of migrated tables do not match, migration failed")
+			return model.InstallationDBMigrationStateFailing
+		}
+		logger.WithError(err).Error("Failed to verify row counts of migrated tables")
+		return dbMigration.State
+	}
+
+	logger.Info("Row counts of migrated tables verified successfully")
+
+	err = s.dbMigrationCIProvisioner.CleanupDBEngineMigrationSecrets(cluster, dbMigration)
+	if err != nil {
+		logger.WithError(err).Error("Failed to cleanup database engine migration secrets")
+		return dbMigration.State
+	}
+
+	return model.InstallationDBMigrationStateDatabaseSwitch
+}
+
+func (s *DBMigrationSupervisor) switchDatabase(dbMigration *model.InstallationDBMigrationOperation, instanceID string, logger log.FieldLogger) model.InstallationDBMigrationOperationState {
+	installation, lock, err := getAndLockInstallation(s.store, dbMigration.InstallationID, instanceID, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get and lock installation")
+		return dbMigration.State
+	}
+	defer lock.Unlock()
+
+	// The installation was already migrated out of the source database and to
+	// the destination database before the engine migration job was triggered.
+	if !dbMigration.IsEngineMigration() {
+		sourceDB := s.dbProvider.GetDatabase(installation.ID, dbMigration.SourceDatabase)
+
+		err = sourceDB.MigrateOut(s.store, dbMigration, logger)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to migrate installation out of database")
+			return dbMigration.State
+		}
+
+		destinationDB := s.dbProvider.GetDatabase(installation.ID, dbMigration.DestinationDatabase)
+		err = destinationDB.MigrateTo(s.store, dbMigration, logger)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to migrate installation to database")
+			return dbMigration.State
+		}
+	}
+
 	installation.Database = dbMigration.DestinationDatabase
 	err = s.store.UpdateInstallation(installation)
 	if err != nil {
@@ -311,6 +477,11 @@ func (s *DBMigrationSupervisor) refreshCredentials(dbMigration *model.Installati
 		return dbMigration.State
 	}
 
+	// Data of engine migration is already present in the destination database.
+	if dbMigration.IsEngineMigration() {
+		return model.InstallationDBMigrationStateUpdatingInstallationConfig
+	}
+
 	return model.InstallationDBMigrationStateTriggerRestoration
 }
 
@@ -396,9 +567,18 @@ func (s *DBMigrationSupervisor) updateInstallationConfig(dbMigration *model.Inst
 		return dbMigration.State
 	}
 
+	configChanges := []string{"SqlSettings.DataSource $MM_CONFIG"}
+	serverEnvs := "MM_CLUSTERSETTINGS_ENABLE=false"
+	if dbMigration.IsEngineMigration() {
+		// Configuration migrated from the source database still points to the source
+		// database engine, so the server needs to be started with overridden SQL settings.
+		configChanges = append([]string{"SqlSettings.DriverName " + model.DatabaseEngineTypePostgres}, configChanges...)
+		serverEnvs += " MM_SQLSETTINGS_DRIVERNAME=" + model.DatabaseEngineTypePostgres + " MM_SQLSETTINGS_DATASOURCE=$MM_CONFIG"
+	}
+
 	var command []string
 	if strings.HasPrefix(installation.Version, "5.") {
-		command = []string{"/bin/sh", "-c", "mattermost config set SqlSettings.DataSource $MM_CONFIG"}
+		command = []string{"/bin/sh", "-c", configSetCommands("mattermost config set", configChanges)}
 	} else {
 		// As `mattermost config` command was removed in v6 we need to work around performing config change without any other running server.
 		// This command does the following:
@@ -407,7 +587,7 @@ func (s *DBMigrationSupervisor) updateInstallationConfig(dbMigration *model.Inst
 		// - Executes config change with mmctl.
 		// - Attempts to terminate Mattermost server (we want it to should down gracefully if possible).
 		// WARNING: this should not be done if other MM pods are online as disabled clustering may lead to some issues.
-		command = []string{"/bin/sh", "-c", "MM_CLUSTERSETTINGS_ENABLE=false mattermost & pid=$!; until $(curl --output /dev/null --silent --fail localhost:8065/api/v4/system/ping); do sleep 2; done; mmctl --local config set SqlSettings.DataSource $MM_CONFIG && kill $pid"}
+		command = []string{"/bin/sh", "-c", serverEnvs + " mattermost & pid=$!; until $(curl --output /dev/null --silent --fail localhost:8065/api/v4/system/ping); do sleep 2; done; " + configSetCommands("mmctl --local config set", configChanges) + " && kill $pid"}
 	}
 
 	err = s.dbMigrationCIProvisioner.ExecClusterInstallationJob(cluster, clusterInstallation, command...)
@@ -522,6 +702,34 @@ func (s *DBMigrationSupervisor) rollbackMigration(dbMigration *model.Installatio
 	return model.InstallationDBMigrationStateRollbackFinished
 }
 
+func configSetCommands(configSet string, changes []string) string {
+	commands := make([]string, 0, len(changes))
+	for _, change := range changes {
+		commands = append(commands, configSet+" "+change)
+	}
+	return strings.Join(commands, " && ")
+}
+
+func (s *DBMigrationSupervisor) getInstallationCluster(installationID string) (*model.Cluster, error) {
+	clusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{InstallationID: installationID, Paging: model.AllPagesNotDeleted()})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster installations")
+	}
+	if len(clusterInstallations) == 0 {
+		return nil, errors.New("expected at least one cluster installation for the installation but found none")
+	}
+
+	cluster, err := s.store.GetCluster(clusterInstallations[0].ClusterID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get cluster")
+	}
+	if cluster == nil {
+		return nil, errors.Errorf("cluster %s not found", clusterInstallations[0].ClusterID)
+	}
+
+	return cluster, nil
+}
+
 func (s *DBMigrationSupervisor) refreshSecrets(installation *model.Installation) error {
 	cis, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{InstallationID: installation.ID, Paging: model.AllPagesNotDeleted()})
 	if err != nil {
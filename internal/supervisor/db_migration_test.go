@@ -15,6 +15,7 @@ import (
 	"github.com/mattermost/mattermost-cloud/internal/tools/utils"
 	"github.com/mattermost/mattermost-cloud/model"
 	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -145,7 +146,10 @@ func (m *mockDBMigrationStore) GetWebhooks(filter *model.WebhookFilter) ([]*mode
 	return nil, nil
 }
 
-type mockDatabase struct{}
+type mockDatabase struct {
+	migratedOut int
+	migratedTo  int
+}
 
 func (m *mockDatabase) TeardownMigrated(store model.InstallationDatabaseStoreInterface, migrationOp *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
 	return nil
@@ -155,6 +159,14 @@ func (m *mockDatabase) RollbackMigration(store model.InstallationDatabaseStoreIn
 	return nil
 }
 
+func (m *mockDatabase) RotateCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	panic("implement me")
+}
+
+func (m *mockDatabase) RevokePreviousCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	panic("implement me")
+}
+
 func (m *mockDatabase) Provision(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
 	panic("implement me")
 }
@@ -168,7 +180,7 @@ func (m *mockDatabase) Snapshot(store model.InstallationDatabaseStoreInterface,
 }
 
 func (m *mockDatabase) GenerateDatabaseSecret(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*corev1.Secret, error) {
-	panic("implement me")
+	return &corev1.Secret{}, nil
 }
 
 func (m *mockDatabase) RefreshResourceMetadata(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
@@ -176,21 +188,38 @@ func (m *mockDatabase) RefreshResourceMetadata(store model.InstallationDatabaseS
 }
 
 func (m *mockDatabase) MigrateOut(store model.InstallationDatabaseStoreInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	m.migratedOut++
 	return nil
 }
 
 func (m *mockDatabase) MigrateTo(store model.InstallationDatabaseStoreInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	m.migratedTo++
 	return nil
 }
 
-type mockResourceUtil struct{}
+type mockResourceUtil struct {
+	database *mockDatabase
+}
 
 func (m *mockResourceUtil) GetDatabase(installationID, dbType string) model.Database {
+	if m.database != nil {
+		return m.database
+	}
 	return &mockDatabase{}
 }
 
 type mockMigrationProvisioner struct {
 	expectedCommand []string
+
+	executedCommand  []string
+	ensuredSecrets   []string
+	triggeredJobs    []string
+	cleanedUpJobs    int
+	jobCompleteAt    int64
+	jobErr           error
+	rowCountErr      error
+	rowCountChecks   int
+	cleanedUpSecrets int
 }
 
 func (m *mockMigrationProvisioner) ClusterInstallationProvisioner(version string) provisioner.ClusterInstallationProvisioner {
@@ -198,6 +227,36 @@ func (m *mockMigrationProvisioner) ClusterInstallationProvisioner(version string
 }
 
 func (m *mockMigrationProvisioner) ExecClusterInstallationJob(cluster *model.Cluster, clusterInstallation *model.ClusterInstallation, args ...string) error {
+	m.executedCommand = args
+	return nil
+}
+
+func (m *mockMigrationProvisioner) EnsureDBEngineMigrationSecret(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation, role string, secret *corev1.Secret) error {
+	m.ensuredSecrets = append(m.ensuredSecrets, role)
+	return nil
+}
+
+func (m *mockMigrationProvisioner) TriggerDBEngineMigrationJob(cluster *model.Cluster, installation *model.Installation, dbMigration *model.InstallationDBMigrationOperation) error {
+	m.triggeredJobs = append(m.triggeredJobs, installation.Version)
+	return nil
+}
+
+func (m *mockMigrationProvisioner) CheckDBEngineMigrationJobStatus(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) (int64, error) {
+	return m.jobCompleteAt, m.jobErr
+}
+
+func (m *mockMigrationProvisioner) CleanupDBEngineMigrationJob(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error {
+	m.cleanedUpJobs++
+	return nil
+}
+
+func (m *mockMigrationProvisioner) VerifyDBEngineMigrationRowCounts(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error {
+	m.rowCountChecks++
+	return m.rowCountErr
+}
+
+func (m *mockMigrationProvisioner) CleanupDBEngineMigrationSecrets(cluster *model.Cluster, dbMigration *model.InstallationDBMigrationOperation) error {
+	m.cleanedUpSecrets++
 	return nil
 }
 
@@ -377,10 +436,11 @@ func TestDBMigrationSupervisor_Supervise(t *testing.T) {
 		err := sqlStore.CreateInstallationDBMigrationOperation(migrationOp)
 		require.NoError(t, err)
 
+		database := &mockDatabase{}
 		dbMigrationSupervisor := supervisor.NewInstallationDBMigrationSupervisor(
 			sqlStore,
 			&mockAWS{},
-			&mockResourceUtil{},
+			&mockResourceUtil{database: database},
 			"instanceID",
 			nil,
 			&mockEventProducer{},
@@ -392,6 +452,8 @@ func TestDBMigrationSupervisor_Supervise(t *testing.T) {
 		migrationOp, err = sqlStore.GetInstallationDBMigrationOperation(migrationOp.ID)
 		require.NoError(t, err)
 		assert.Equal(t, model.InstallationDBMigrationStateRefreshSecrets, migrationOp.State)
+		assert.Equal(t, 1, database.migratedOut)
+		assert.Equal(t, 1, database.migratedTo)
 
 		installation, err = sqlStore.GetInstallation(installation.ID, false, false)
 		require.NoError(t, err)
@@ -783,3 +845,212 @@ func setupMigrationRequiredResources(t *testing.T, sqlStore *store.SQLStore) (*m
 
 	return installation, clusterInstallation
 }
+
+func TestDBMigrationSupervisor_EngineMigration(t *testing.T) {
+	newEngineMigration := func(t *testing.T, sqlStore *store.SQLStore, installation *model.Installation, state model.InstallationDBMigrationOperationState) *model.InstallationDBMigrationOperation {
+		installation.Database = model.InstallationDatabaseMultiTenantRDSMySQL
+		err := sqlStore.UpdateInstallation(installation)
+		require.NoError(t, err)
+
+		migrationOp := &model.InstallationDBMigrationOperation{
+			InstallationID:         installation.ID,
+			State:                  state,
+			SourceDatabase:         model.InstallationDatabaseMultiTenantRDSMySQL,
+			DestinationDatabase:    model.InstallationDatabaseMultiTenantRDSPostgres,
+			SourceMultiTenant:      &model.MultiTenantDBMigrationData{DatabaseID: "source-id"},
+			DestinationMultiTenant: &model.MultiTenantDBMigrationData{DatabaseID: "destination-id"},
+		}
+		err = sqlStore.CreateInstallationDBMigrationOperation(migrationOp)
+		require.NoError(t, err)
+
+		return migrationOp
+	}
+
+	t.Run("prepare engine migration", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installation, _ := setupMigrationRequiredResources(t, sqlStore)
+		migrationOp := newEngineMigration(t, sqlStore, installation, model.InstallationDBMigrationStateRequested)
+
+		migrationProvisioner := &mockMigrationProvisioner{}
+		dbMigrationSupervisor := supervisor.NewInstallationDBMigrationSupervisor(sqlStore, &mockAWS{}, &mockResourceUtil{}, "instanceID", migrationProvisioner, &mockEventProducer{}, logger)
+		dbMigrationSupervisor.Supervise(migrationOp)
+
+		// Assert
+		migrationOp, err := sqlStore.GetInstallationDBMigrationOperation(migrationOp.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.InstallationDBMigrationStateEngineMigrationSetup, migrationOp.State)
+		assert.Empty(t, migrationOp.BackupID)
+		assert.Equal(t, []string{provisioner.DBEngineMigrationSourceDatabase}, migrationProvisioner.ensuredSecrets)
+	})
+
+	t.Run("setup engine migration", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installation, _ := setupMigrationRequiredResources(t, sqlStore)
+		migrationOp := newEngineMigration(t, sqlStore, installation, model.InstallationDBMigrationStateEngineMigrationSetup)
+
+		database := &mockDatabase{}
+		migrationProvisioner := &mockMigrationProvisioner{}
+		dbMigrationSupervisor := supervisor.NewInstallationDBMigrationSupervisor(sqlStore, &mockAWS{}, &mockResourceUtil{database: database}, "instanceID", migrationProvisioner, &mockEventProducer{}, logger)
+		dbMigrationSupervisor.Supervise(migrationOp)
+
+		// Assert
+		migrationOp, err := sqlStore.GetInstallationDBMigrationOperation(migrationOp.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.InstallationDBMigrationStateEngineMigrationInProgress, migrationOp.State)
+		assert.Equal(t, 1, database.migratedOut)
+		assert.Equal(t, 1, database.migratedTo)
+		assert.Equal(t, []string{provisioner.DBEngineMigrationDestinationDatabase}, migrationProvisioner.ensuredSecrets)
+		assert.Equal(t, []string{installation.Version}, migrationProvisioner.triggeredJobs)
+
+		installation, err = sqlStore.GetInstallation(installation.ID, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, model.InstallationDatabaseMultiTenantRDSMySQL, installation.Database, "installation database should not be switched before verification")
+	})
+
+	for _, testCase := range []struct {
+		description              string
+		state                    model.InstallationDBMigrationOperationState
+		jobCompleteAt            int64
+		jobErr                   error
+		rowCountErr              error
+		expectedState            model.InstallationDBMigrationOperationState
+		expectedCleanedUpJobs    int
+		expectedRowCountChecks   int
+		expectedCleanedUpSecrets int
+	}{
+		{
+			description:   "engine migration in progress",
+			state:         model.InstallationDBMigrationStateEngineMigrationInProgress,
+			jobCompleteAt: -1,
+			expectedState: model.InstallationDBMigrationStateEngineMigrationInProgress,
+		},
+		{
+			description:           "engine migration finished",
+			state:                 model.InstallationDBMigrationStateEngineMigrationInProgress,
+			jobCompleteAt:         100,
+			expectedState:         model.InstallationDBMigrationStateVerifyingRowCounts,
+			expectedCleanedUpJobs: 1,
+		},
+		{
+			description:   "engine migration failed",
+			state:         model.InstallationDBMigrationStateEngineMigrationInProgress,
+			jobErr:        provisioner.ErrJobBackoffLimitReached,
+			expectedState: model.InstallationDBMigrationStateFailing,
+		},
+		{
+			description:              "row counts match",
+			state:                    model.InstallationDBMigrationStateVerifyingRowCounts,
+			expectedState:            model.InstallationDBMigrationStateDatabaseSwitch,
+			expectedRowCountChecks:   1,
+			expectedCleanedUpSecrets: 1,
+		},
+		{
+			description:            "row counts do not match",
+			state:                  model.InstallationDBMigrationStateVerifyingRowCounts,
+			rowCountErr:            provisioner.ErrDBEngineMigrationRowCountMismatch,
+			expectedState:          model.InstallationDBMigrationStateFailing,
+			expectedRowCountChecks: 1,
+		},
+		{
+			description:            "row counts can't be verified",
+			state:                  model.InstallationDBMigrationStateVerifyingRowCounts,
+			rowCountErr:            errors.New("connection refused"),
+			expectedState:          model.InstallationDBMigrationStateVerifyingRowCounts,
+			expectedRowCountChecks: 1,
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			logger := testlib.MakeLogger(t)
+			sqlStore := store.MakeTestSQLStore(t, logger)
+			defer store.CloseConnection(t, sqlStore)
+
+			installation, _ := setupMigrationRequiredResources(t, sqlStore)
+			migrationOp := newEngineMigration(t, sqlStore, installation, testCase.state)
+
+			migrationProvisioner := &mockMigrationProvisioner{
+				jobCompleteAt: testCase.jobCompleteAt,
+				jobErr:        testCase.jobErr,
+				rowCountErr:   testCase.rowCountErr,
+			}
+			dbMigrationSupervisor := supervisor.NewInstallationDBMigrationSupervisor(sqlStore, &mockAWS{}, &mockResourceUtil{}, "instanceID", migrationProvisioner, &mockEventProducer{}, logger)
+			dbMigrationSupervisor.Supervise(migrationOp)
+
+			// Assert
+			migrationOp, err := sqlStore.GetInstallationDBMigrationOperation(migrationOp.ID)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expectedState, migrationOp.State)
+			assert.Empty(t, migrationProvisioner.triggeredJobs)
+			assert.Equal(t, testCase.expectedCleanedUpJobs, migrationProvisioner.cleanedUpJobs)
+			assert.Equal(t, testCase.expectedRowCountChecks, migrationProvisioner.rowCountChecks)
+			assert.Equal(t, testCase.expectedCleanedUpSecrets, migrationProvisioner.cleanedUpSecrets)
+		})
+	}
+
+	t.Run("switch database after engine migration", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installation, _ := setupMigrationRequiredResources(t, sqlStore)
+		migrationOp := newEngineMigration(t, sqlStore, installation, model.InstallationDBMigrationStateDatabaseSwitch)
+
+		database := &mockDatabase{}
+		dbMigrationSupervisor := supervisor.NewInstallationDBMigrationSupervisor(sqlStore, &mockAWS{}, &mockResourceUtil{database: database}, "instanceID", &mockMigrationProvisioner{}, &mockEventProducer{}, logger)
+		dbMigrationSupervisor.Supervise(migrationOp)
+
+		// Assert
+		migrationOp, err := sqlStore.GetInstallationDBMigrationOperation(migrationOp.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.InstallationDBMigrationStateRefreshSecrets, migrationOp.State)
+		assert.Zero(t, database.migratedOut, "installation was migrated out of the source database during setup")
+		assert.Zero(t, database.migratedTo, "installation was migrated to the destination database during setup")
+
+		installation, err = sqlStore.GetInstallation(installation.ID, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, model.InstallationDatabaseMultiTenantRDSPostgres, installation.Database)
+	})
+
+	t.Run("refresh secrets skips restoration", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installation, _ := setupMigrationRequiredResources(t, sqlStore)
+		migrationOp := newEngineMigration(t, sqlStore, installation, model.InstallationDBMigrationStateRefreshSecrets)
+
+		dbMigrationSupervisor := supervisor.NewInstallationDBMigrationSupervisor(sqlStore, &mockAWS{}, &mockResourceUtil{}, "instanceID", &mockMigrationProvisioner{}, &mockEventProducer{}, logger)
+		dbMigrationSupervisor.Supervise(migrationOp)
+
+		// Assert
+		migrationOp, err := sqlStore.GetInstallationDBMigrationOperation(migrationOp.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.InstallationDBMigrationStateUpdatingInstallationConfig, migrationOp.State)
+	})
+
+	t.Run("update installation config switches driver", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		installation, _ := setupMigrationRequiredResources(t, sqlStore)
+		migrationOp := newEngineMigration(t, sqlStore, installation, model.InstallationDBMigrationStateUpdatingInstallationConfig)
+
+		migrationProvisioner := &mockMigrationProvisioner{}
+		dbMigrationSupervisor := supervisor.NewInstallationDBMigrationSupervisor(sqlStore, &mockAWS{}, &mockResourceUtil{}, "instanceID", migrationProvisioner, &mockEventProducer{}, logger)
+		dbMigrationSupervisor.Supervise(migrationOp)
+
+		// Assert
+		migrationOp, err := sqlStore.GetInstallationDBMigrationOperation(migrationOp.ID)
+		require.NoError(t, err)
+		assert.Equal(t, model.InstallationDBMigrationStateFinalizing, migrationOp.State)
+		require.Len(t, migrationProvisioner.executedCommand, 3)
+		assert.Contains(t, migrationProvisioner.executedCommand[2], "MM_SQLSETTINGS_DRIVERNAME=postgres")
+		assert.Contains(t, migrationProvisioner.executedCommand[2], "mmctl --local config set SqlSettings.DriverName postgres && mmctl --local config set SqlSettings.DataSource $MM_CONFIG")
+	})
+}
@@ -5,6 +5,7 @@
 package supervisor
 
 import (
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -18,7 +19,6 @@ import (
 
 	"github.com/mattermost/mattermost-cloud/internal/metrics"
 	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
-	"github.com/mattermost/mattermost-cloud/internal/tools/utils"
 	"github.com/mattermost/mattermost-cloud/k8s"
 	"github.com/mattermost/mattermost-cloud/model"
 	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
@@ -36,16 +36,21 @@ type installationStore interface {
 	clusterLockStore
 
 	GetInstallation(installationID string, includeGroupConfig, includeGroupConfigOverrides bool) (*model.Installation, error)
+	GetInstallations(installationFilter *model.InstallationFilter, includeGroupConfig, includeGroupConfigOverrides bool) ([]*model.Installation, error)
 	GetUnlockedInstallationsPendingWork() ([]*model.Installation, error)
 	UpdateInstallation(installation *model.Installation) error
 	UpdateInstallationGroupSequence(installation *model.Installation) error
 	UpdateInstallationState(*model.Installation) error
 	UpdateInstallationCRVersion(installationID, crVersion string) error
+	UpdateInstallationDatabaseCredentialsRotatedAt(installationID string, rotatedAt int64) error
+	UpdateInstallationFilestoreAccessKeyRotatedAt(installationID string, rotatedAt int64) error
 	DeleteInstallation(installationID string) error
 	installationLockStore
 
 	GetSingleTenantDatabaseConfigForInstallation(installationID string) (*model.SingleTenantDatabaseConfig, error)
 	GetAnnotationsForInstallation(installationID string) ([]*model.Annotation, error)
+	GetAnnotationsForCluster(clusterID string) ([]*model.Annotation, error)
+	GetAnnotationsForClusters(filter *model.ClusterFilter) (map[string][]*model.Annotation, error)
 
 	CreateClusterInstallation(clusterInstallation *model.ClusterInstallation) error
 	GetClusterInstallation(clusterInstallationID string) (*model.ClusterInstallation, error)
@@ -89,6 +94,13 @@ type installationProvisioner interface {
 	GetPublicLoadBalancerEndpoint(cluster *model.Cluster, namespace string) (string, error)
 }
 
+// installationResourceProvider provides the database and filestore of
+// installations.
+type installationResourceProvider interface {
+	GetDatabaseForInstallation(installation *model.Installation) model.Database
+	GetFilestore(installation *model.Installation) model.Filestore
+}
+
 // InstallationSupervisor finds installations pending work and effects the required changes.
 //
 // The degree of parallelism is controlled by a weighted semaphore, intended to be shared with
@@ -101,7 +113,7 @@ type InstallationSupervisor struct {
 	keepDatabaseData  bool
 	keepFilestoreData bool
 	scheduling        InstallationSupervisorSchedulingOptions
-	resourceUtil      *utils.ResourceUtil
+	resourceUtil      installationResourceProvider
 	logger            log.FieldLogger
 	metrics           *metrics.CloudMetrics
 	eventsProducer    eventProducer
@@ -136,7 +148,7 @@ func NewInstallationSupervisor(
 	keepDatabaseData,
 	keepFilestoreData bool,
 	scheduling InstallationSupervisorSchedulingOptions,
-	resourceUtil *utils.ResourceUtil,
+	resourceUtil installationResourceProvider,
 	logger log.FieldLogger,
 	metrics *metrics.CloudMetrics,
 	eventsProducer eventProducer,
@@ -312,6 +324,21 @@ func (s *InstallationSupervisor) transitionInstallation(installation *model.Inst
 	case model.InstallationStateWakeUpRequested:
 		return s.wakeUpInstallation(installation, instanceID, logger)
 
+	case model.InstallationStateDBCredentialRotationRequested:
+		return s.rotateDatabaseCredentials(installation, instanceID, logger)
+
+	case model.InstallationStateDBCredentialRotationInProgress:
+		return s.waitForDatabaseCredentialRotationStable(installation, instanceID, logger)
+
+	case model.InstallationStateFilestoreAccessKeyRotationRequested:
+		return s.rotateFilestoreAccessKey(installation, instanceID, logger)
+
+	case model.InstallationStateFilestoreAccessKeyRotationRefreshingSecrets:
+		return s.refreshFilestoreAccessKeySecrets(installation, instanceID, logger)
+
+	case model.InstallationStateFilestoreAccessKeyRotationInProgress:
+		return s.waitForFilestoreAccessKeyRotationStable(installation, instanceID, logger)
+
 	case model.InstallationStateDeletionRequested,
 		model.InstallationStateDeletionInProgress:
 		return s.deleteInstallation(installation, instanceID, logger)
@@ -387,6 +414,11 @@ func (s *InstallationSupervisor) createInstallation(installation *model.Installa
 		clusters = s.prioritizeLowerUtilizedClusters(clusters, installation, instanceID, logger)
 	}
 
+	if installation.Placement != nil && len(installation.Placement.PreferredClusterAnnotations) > 0 {
+		logger.Info("Attempting to schedule installation on clusters with preferred annotations first")
+		clusters = s.prioritizePreferredClusters(clusters, clusterFilter, installation, logger)
+	}
+
 	for _, cluster := range clusters {
 		clusterInstallation := s.createClusterInstallation(cluster, installation, instanceID, logger)
 		if clusterInstallation != nil {
@@ -394,7 +426,8 @@ func (s *InstallationSupervisor) createInstallation(installation *model.Installa
 		}
 	}
 
-	// TODO: Support creating a cluster on demand if no existing cluster meets the criteria.
+	// The cluster pool supervisor, if enabled, creates a new cluster for
+	// installations in this state.
 	logger.Warn("No compatible clusters available for installation scheduling")
 
 	return model.InstallationStateCreationNoCompatibleClusters
@@ -418,7 +451,7 @@ func (s *InstallationSupervisor) prioritizeLowerUtilizedClusters(clusters []*mod
 	var filteredPrioritizedClusters []*model.Cluster
 
 	for _, cluster := range clusters {
-		if !s.installationCanBeScheduledOnCluster(cluster, installation, logger) {
+		if !installationCanBeScheduledOnCluster(s.store, cluster, installation, logger) {
 			continue
 		}
 
@@ -458,6 +491,27 @@ func (s *InstallationSupervisor) prioritizeLowerUtilizedClusters(clusters []*mod
 	return filteredPrioritizedClusters
 }
 
+// prioritizePreferredClusters orders the given cluster list so that clusters
+// matching the most preferred annotations of the installation placement rules
+// come first. The relative order of clusters with equal score is preserved.
+func (s *InstallationSupervisor) prioritizePreferredClusters(clusters []*model.Cluster, clusterFilter *model.ClusterFilter, installation *model.Installation, logger log.FieldLogger) []*model.Cluster {
+	clusterAnnotations, err := s.store.GetAnnotationsForClusters(clusterFilter)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get cluster annotations; skipping preferred cluster ordering")
+		return clusters
+	}
+
+	scores := make(map[string]int, len(clusters))
+	for _, cluster := range clusters {
+		scores[cluster.ID] = installation.Placement.PreferredClusterAnnotationsScore(clusterAnnotations[cluster.ID])
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return scores[clusters[i].ID] > scores[clusters[j].ID]
+	})
+
+	return clusters
+}
+
 // getClusterResources returns cluster resources from cache or will obtain them
 // directly if they don't exist.
 func (s *InstallationSupervisor) getClusterResources(cluster *model.Cluster, logger log.FieldLogger) (*k8s.ClusterResources, error) {
@@ -484,7 +538,7 @@ func (s *InstallationSupervisor) createClusterInstallation(cluster *model.Cluste
 	}
 	defer clusterLock.Unlock()
 
-	if !s.installationCanBeScheduledOnCluster(cluster, installation, logger) {
+	if !installationCanBeScheduledOnCluster(s.store, cluster, installation, logger) {
 		return nil
 	}
 
@@ -538,6 +592,7 @@ func (s *InstallationSupervisor) createClusterInstallation(cluster *model.Cluste
 		cluster.State = model.ClusterStateResizeRequested
 		cluster.ProvisionerMetadataKops.ChangeRequest = &model.KopsMetadataRequestedState{
 			NodeMinCount: newWorkerCount,
+			NodeMaxCount: cluster.ProvisionerMetadataKops.NodeMaxCount,
 		}
 
 		logger.WithField("cluster", cluster.ID).Infof("Scaling cluster worker nodes from %d to %d (max=%d)",
@@ -582,60 +637,6 @@ func (s *InstallationSupervisor) createClusterInstallation(cluster *model.Cluste
 	return clusterInstallation
 }
 
-// installationCanBeScheduledOnCluster checks if the given installation can be
-// scheduled on the given cluster in regards to configuration and state. This
-// does not include resource checks.
-func (s *InstallationSupervisor) installationCanBeScheduledOnCluster(cluster *model.Cluster, installation *model.Installation, logger log.FieldLogger) bool {
-	if cluster.State != model.ClusterStateStable {
-		logger.Debugf("Cluster %s is not stable (currently %s)", cluster.ID, cluster.State)
-		return false
-	}
-	if !cluster.AllowInstallations {
-		logger.Debugf("Cluster %s is set to not allow for new installation scheduling", cluster.ID)
-		return false
-	}
-
-	existingClusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
-		Paging:    model.AllPagesNotDeleted(),
-		ClusterID: cluster.ID,
-	})
-	if err != nil {
-		logger.WithError(err).Error("Failed to get existing cluster installations")
-		return false
-	}
-
-	////////////////////////////////////////////////////////////////////////////
-	//                              MULTI-TENANCY                             //
-	////////////////////////////////////////////////////////////////////////////
-	// Current model:                                                         //
-	// - isolation=true  | 1 cluster installations                            //
-	// - isolation=false | X cluster installations, where "X" is as many as   //
-	//                     will fit with the given CPU and Memory threshold.  //
-	////////////////////////////////////////////////////////////////////////////
-	if installation.Affinity == model.InstallationAffinityIsolated {
-		if len(existingClusterInstallations) > 0 {
-			logger.Debugf("Cluster %s already has %d installations", cluster.ID, len(existingClusterInstallations))
-			return false
-		}
-	} else {
-		if len(existingClusterInstallations) == 1 {
-			// This should be the only scenario where we need to check if the
-			// cluster installation running requires isolation or not.
-			installation, err := s.store.GetInstallation(existingClusterInstallations[0].InstallationID, true, false)
-			if err != nil {
-				logger.WithError(err).Error("Failed to get existing installation")
-				return false
-			}
-			if installation.Affinity == model.InstallationAffinityIsolated {
-				logger.Debugf("Cluster %s already has an isolated installation %s", cluster.ID, installation.ID)
-				return false
-			}
-		}
-	}
-
-	return true
-}
-
 func (s *InstallationSupervisor) preProvisionInstallation(installation *model.Installation, instanceID string, logger log.FieldLogger) string {
 	err := s.resourceUtil.GetDatabaseForInstallation(installation).Provision(s.store, logger)
 	if err != nil {
@@ -804,6 +805,15 @@ func (s *InstallationSupervisor) updateInstallation(installation *model.Installa
 			return installation.State
 		}
 
+		// Cluster utilities are prepared again so that changes to the
+		// installation database pool config reach the database proxy.
+		err = s.provisioner.ClusterInstallationProvisioner(installation.CRVersion).
+			PrepareClusterUtilities(cluster, installation, s.store, s.aws)
+		if err != nil {
+			logger.WithError(err).Error("Failed to prepare cluster utilities")
+			return installation.State
+		}
+
 		err = s.provisioner.ClusterInstallationProvisioner(installation.CRVersion).
 			UpdateClusterInstallation(cluster, installation, clusterInstallation)
 		if err != nil {
@@ -1012,6 +1022,176 @@ func (s *InstallationSupervisor) wakeUpInstallation(installation *model.Installa
 	return s.updateInstallation(installation, instanceID, logger)
 }
 
+// rotateDatabaseCredentials rotates the database credentials of the
+// installation and rolls its pods so that they use them. It is retried until
+// all cluster installations were refreshed, which is safe as databases resume
+// a rotation whose previous credentials were not revoked yet instead of
+// rotating them again.
+func (s *InstallationSupervisor) rotateDatabaseCredentials(installation *model.Installation, instanceID string, logger log.FieldLogger) string {
+	err := s.resourceUtil.GetDatabaseForInstallation(installation).RotateCredentials(s.store, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to rotate database credentials")
+		return installation.State
+	}
+
+	installation.DatabaseCredentialsRotatedAt = model.GetMillis()
+	err = s.store.UpdateInstallationDatabaseCredentialsRotatedAt(installation.ID, installation.DatabaseCredentialsRotatedAt)
+	if err != nil {
+		logger.WithError(err).Error("Failed to store database credentials rotation time")
+		return installation.State
+	}
+
+	if !s.refreshClusterInstallationSecrets(installation, instanceID, logger) {
+		return installation.State
+	}
+
+	return s.waitForDatabaseCredentialRotationStable(installation, instanceID, logger)
+}
+
+func (s *InstallationSupervisor) waitForDatabaseCredentialRotationStable(installation *model.Installation, instanceID string, logger log.FieldLogger) string {
+	stable, err := s.checkIfClusterInstallationsAreStable(installation, logger)
+	if err != nil {
+		logger.WithError(err).Error("Installation database credential rotation failed")
+		return model.InstallationStateDBCredentialRotationFailed
+	}
+	if !stable {
+		return model.InstallationStateDBCredentialRotationInProgress
+	}
+
+	// All pods are running with the new credentials, so the database user
+	// used before can be revoked.
+	err = s.resourceUtil.GetDatabaseForInstallation(installation).RevokePreviousCredentials(s.store, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to revoke previous database credentials")
+		return model.InstallationStateDBCredentialRotationInProgress
+	}
+
+	logger.Info("Finished rotating installation database credentials")
+
+	return model.InstallationStateStable
+}
+
+// rotateFilestoreAccessKey rotates the filestore access key of the
+// installation. The installation moves on as soon as the new access key is
+// stored, so that a failure while rolling the pods never rotates the key
+// again.
+func (s *InstallationSupervisor) rotateFilestoreAccessKey(installation *model.Installation, instanceID string, logger log.FieldLogger) string {
+	err := s.resourceUtil.GetFilestore(installation).RotateAccessKey(s.store, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to rotate filestore access key")
+		return installation.State
+	}
+
+	installation.FilestoreAccessKeyRotatedAt = model.GetMillis()
+	err = s.store.UpdateInstallationFilestoreAccessKeyRotatedAt(installation.ID, installation.FilestoreAccessKeyRotatedAt)
+	if err != nil {
+		logger.WithError(err).Error("Failed to store filestore access key rotation time")
+	}
+
+	return s.refreshFilestoreAccessKeySecrets(installation, instanceID, logger)
+}
+
+func (s *InstallationSupervisor) refreshFilestoreAccessKeySecrets(installation *model.Installation, instanceID string, logger log.FieldLogger) string {
+	if !s.refreshClusterInstallationSecrets(installation, instanceID, logger) {
+		return model.InstallationStateFilestoreAccessKeyRotationRefreshingSecrets
+	}
+
+	return s.waitForFilestoreAccessKeyRotationStable(installation, instanceID, logger)
+}
+
+func (s *InstallationSupervisor) waitForFilestoreAccessKeyRotationStable(installation *model.Installation, instanceID string, logger log.FieldLogger) string {
+	stable, err := s.checkIfClusterInstallationsAreStable(installation, logger)
+	if err != nil {
+		logger.WithError(err).Error("Installation filestore access key rotation failed")
+		return model.InstallationStateFilestoreAccessKeyRotationFailed
+	}
+	if !stable {
+		return model.InstallationStateFilestoreAccessKeyRotationInProgress
+	}
+
+	// All pods are running with the new access key, so the old one can be
+	// deleted.
+	err = s.resourceUtil.GetFilestore(installation).RevokePreviousAccessKey(s.store, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to revoke previous filestore access key")
+		return model.InstallationStateFilestoreAccessKeyRotationInProgress
+	}
+
+	logger.Info("Finished rotating installation filestore access key")
+
+	return model.InstallationStateStable
+}
+
+// refreshClusterInstallationSecrets updates the database and filestore
+// secrets of all cluster installations of an installation and rolls their
+// pods so that they use the current credentials. It returns false if not all
+// cluster installations were refreshed.
+func (s *InstallationSupervisor) refreshClusterInstallationSecrets(installation *model.Installation, instanceID string, logger log.FieldLogger) bool {
+	clusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging:         model.AllPagesNotDeleted(),
+		InstallationID: installation.ID,
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to find cluster installations")
+		return false
+	}
+
+	clusterInstallationIDs := getClusterInstallationIDs(clusterInstallations)
+
+	clusterInstallationLocks := newClusterInstallationLocks(clusterInstallationIDs, instanceID, s.store, logger)
+	if !clusterInstallationLocks.TryLock() {
+		logger.Debugf("Failed to lock %d cluster installations", len(clusterInstallations))
+		return false
+	}
+	defer clusterInstallationLocks.Unlock()
+
+	// Fetch the same cluster installations again, now that we have the locks.
+	clusterInstallations, err = s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging: model.AllPagesNotDeleted(),
+		IDs:    clusterInstallationIDs,
+	})
+	if err != nil {
+		logger.WithError(err).Warnf("Failed to fetch %d cluster installations by ids", len(clusterInstallations))
+		return false
+	}
+
+	for _, clusterInstallation := range clusterInstallations {
+		cluster, err := s.store.GetCluster(clusterInstallation.ClusterID)
+		if err != nil {
+			logger.WithError(err).Warnf("Failed to query cluster %s", clusterInstallation.ClusterID)
+			return false
+		}
+		if cluster == nil {
+			logger.Errorf("Failed to find cluster %s", clusterInstallation.ClusterID)
+			return false
+		}
+
+		err = s.provisioner.ClusterInstallationProvisioner(installation.CRVersion).
+			RefreshSecrets(cluster, installation, clusterInstallation)
+		if err != nil {
+			logger.WithError(err).Error("Failed to refresh cluster installation secrets")
+			return false
+		}
+
+		oldState := clusterInstallation.State
+		clusterInstallation.State = model.ClusterInstallationStateReconciling
+		err = s.store.UpdateClusterInstallation(clusterInstallation)
+		if err != nil {
+			logger.WithError(err).Errorf("Failed to change cluster installation state to %s", model.ClusterInstallationStateReconciling)
+			return false
+		}
+
+		err = s.eventsProducer.ProduceClusterInstallationStateChangeEvent(clusterInstallation, oldState)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create cluster installation state change event")
+		}
+	}
+
+	logger.Info("Finished refreshing cluster installation secrets")
+
+	return true
+}
+
 func (s *InstallationSupervisor) deleteInstallation(installation *model.Installation, instanceID string, logger log.FieldLogger) string {
 	clusterInstallations, err := s.store.GetClusterInstallations(&model.ClusterInstallationFilter{
 		Paging:         model.AllPagesWithDeleted(),
@@ -1616,6 +1796,10 @@ func (s *InstallationSupervisor) processInstallationMetrics(installation *model.
 	case model.InstallationStateWakeUpRequested:
 		s.metrics.InstallationWakeUpDurationHist.WithLabelValues(groupID).Observe(elapsedSeconds)
 		logger.Debugf("Installation was woken up in %d seconds", int(elapsedSeconds))
+	case model.InstallationStateDBCredentialRotationRequested:
+		logger.Debugf("Installation database credentials were rotated in %d seconds", int(elapsedSeconds))
+	case model.InstallationStateFilestoreAccessKeyRotationRequested:
+		logger.Debugf("Installation filestore access key was rotated in %d seconds", int(elapsedSeconds))
 	case model.InstallationStateDeletionRequested:
 		s.metrics.InstallationDeletionDurationHist.WithLabelValues(groupID).Observe(elapsedSeconds)
 		logger.Debugf("Installation was deleted in %d seconds", int(elapsedSeconds))
@@ -0,0 +1,171 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// installationDatabaseCredentialRotationStore abstracts the database
+// operations required by the installation database credential rotation
+// supervisor.
+type installationDatabaseCredentialRotationStore interface {
+	GetInstallation(installationID string, includeGroupConfig, includeGroupConfigOverrides bool) (*model.Installation, error)
+	GetInstallations(filter *model.InstallationFilter, includeGroupConfig, includeGroupConfigOverrides bool) ([]*model.Installation, error)
+	UpdateInstallationState(installation *model.Installation) error
+	installationLockStore
+}
+
+// InstallationDatabaseCredentialRotationOptions are the various options that
+// control scheduled installation database credential rotation.
+type InstallationDatabaseCredentialRotationOptions struct {
+	// MaxAge is the age of database credentials after which they are rotated.
+	MaxAge time.Duration
+	// MaxConcurrentRotations is the maximum number of installations rotating
+	// their database credentials at the same time.
+	MaxConcurrentRotations int
+	// Interval is the minimum time between checks for credentials to rotate.
+	Interval time.Duration
+}
+
+// InstallationDatabaseCredentialRotationSupervisor periodically requests
+// database credential rotation of stable installations whose credentials are
+// older than the configured max age.
+type InstallationDatabaseCredentialRotationSupervisor struct {
+	store          installationDatabaseCredentialRotationStore
+	options        InstallationDatabaseCredentialRotationOptions
+	instanceID     string
+	eventsProducer eventProducer
+	lastRun        time.Time
+	logger         log.FieldLogger
+}
+
+// NewInstallationDatabaseCredentialRotationSupervisor creates a new
+// InstallationDatabaseCredentialRotationSupervisor.
+func NewInstallationDatabaseCredentialRotationSupervisor(store installationDatabaseCredentialRotationStore, options InstallationDatabaseCredentialRotationOptions, instanceID string, eventsProducer eventProducer, logger log.FieldLogger) *InstallationDatabaseCredentialRotationSupervisor {
+	return &InstallationDatabaseCredentialRotationSupervisor{
+		store:          store,
+		options:        options,
+		instanceID:     instanceID,
+		eventsProducer: eventsProducer,
+		logger:         logger.WithField("supervisor", "installation-database-credential-rotation"),
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the installation database
+// credential rotation supervisor.
+func (s *InstallationDatabaseCredentialRotationSupervisor) Shutdown() {
+	s.logger.Debug("Shutting down installation database credential rotation supervisor")
+}
+
+// Do requests database credential rotation of installations with expired
+// credentials, keeping at most the configured number of rotations in
+// progress.
+func (s *InstallationDatabaseCredentialRotationSupervisor) Do() error {
+	if time.Since(s.lastRun) < s.options.Interval {
+		return nil
+	}
+	s.lastRun = time.Now()
+
+	installations, err := s.store.GetInstallations(&model.InstallationFilter{
+		Paging: model.AllPagesNotDeleted(),
+	}, false, false)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get installations")
+		return nil
+	}
+
+	var inProgress int
+	var expired []*model.Installation
+	for _, installation := range installations {
+		switch installation.State {
+		case model.InstallationStateDBCredentialRotationRequested,
+			model.InstallationStateDBCredentialRotationInProgress:
+			inProgress++
+		case model.InstallationStateStable:
+			if s.credentialsExpired(installation) {
+				expired = append(expired, installation)
+			}
+		}
+	}
+
+	available := s.options.MaxConcurrentRotations - inProgress
+	if available <= 0 {
+		s.logger.Debugf("Found %d installations rotating database credentials; skipping new rotations", inProgress)
+		return nil
+	}
+
+	var requested int
+	for _, installation := range expired {
+		if requested >= available {
+			break
+		}
+		if s.requestRotation(installation.ID) {
+			requested++
+		}
+	}
+
+	s.logger.Debugf("Requested database credential rotation of %d out of %d installations with expired credentials", requested, len(expired))
+
+	return nil
+}
+
+// credentialsExpired returns true if the installation database supports
+// credential rotation and its credentials are older than the max age.
+// Installations that never rotated their credentials are aged from creation.
+func (s *InstallationDatabaseCredentialRotationSupervisor) credentialsExpired(installation *model.Installation) bool {
+	if !model.SupportsCredentialRotation(installation.Database) {
+		return false
+	}
+
+	rotatedAt := installation.DatabaseCredentialsRotatedAt
+	if rotatedAt == 0 {
+		rotatedAt = installation.CreateAt
+	}
+
+	return time.Duration(model.GetMillis()-rotatedAt)*time.Millisecond >= s.options.MaxAge
+}
+
+func (s *InstallationDatabaseCredentialRotationSupervisor) requestRotation(installationID string) bool {
+	logger := s.logger.WithField("installation", installationID)
+
+	lock := newInstallationLock(installationID, s.instanceID, s.store, logger)
+	if !lock.TryLock() {
+		logger.Debug("Failed to lock installation")
+		return false
+	}
+	defer lock.Unlock()
+
+	// Fetch the installation again, now that we have the lock.
+	installation, err := s.store.GetInstallation(installationID, false, false)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get installation")
+		return false
+	}
+	if installation == nil || installation.State != model.InstallationStateStable {
+		logger.Debug("Installation is no longer stable; skipping database credential rotation")
+		return false
+	}
+
+	oldState := installation.State
+	installation.State = model.InstallationStateDBCredentialRotationRequested
+	err = s.store.UpdateInstallationState(installation)
+	if err != nil {
+		logger.WithError(err).Error("Failed to request database credential rotation")
+		return false
+	}
+
+	err = s.eventsProducer.ProduceInstallationStateChangeEvent(installation, oldState)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create installation state change event")
+	}
+
+	logger.Info("Requested scheduled database credential rotation")
+
+	return true
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/internal/testutil"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallationDatabaseCredentialRotationSupervisor(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	createInstallation := func(database, state string, rotatedAt int64) *model.Installation {
+		installation := &model.Installation{
+			OwnerID:   model.NewID(),
+			DNS:       model.NewID() + ".example.com",
+			Affinity:  model.InstallationAffinityMultiTenant,
+			Database:  database,
+			Filestore: model.InstallationFilestoreBifrost,
+			State:     state,
+		}
+		err := sqlStore.CreateInstallation(installation, nil)
+		require.NoError(t, err)
+		err = sqlStore.UpdateInstallationDatabaseCredentialsRotatedAt(installation.ID, rotatedAt)
+		require.NoError(t, err)
+		return installation
+	}
+
+	expiredAt := model.GetMillis() - (2 * time.Hour).Milliseconds()
+
+	expired1 := createInstallation(model.InstallationDatabaseMultiTenantRDSPostgres, model.InstallationStateStable, expiredAt)
+	expired2 := createInstallation(model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer, model.InstallationStateStable, expiredAt)
+	recent := createInstallation(model.InstallationDatabaseMultiTenantRDSPostgres, model.InstallationStateStable, model.GetMillis())
+	neverRotated := createInstallation(model.InstallationDatabaseMultiTenantRDSPostgres, model.InstallationStateStable, 0)
+	unsupported := createInstallation(model.InstallationDatabaseMysqlOperator, model.InstallationStateStable, expiredAt)
+	hibernating := createInstallation(model.InstallationDatabaseMultiTenantRDSPostgres, model.InstallationStateHibernating, expiredAt)
+
+	expectState := func(t *testing.T, installation *model.Installation, state string) {
+		t.Helper()
+		installation, err := sqlStore.GetInstallation(installation.ID, false, false)
+		require.NoError(t, err)
+		assert.Equal(t, state, installation.State)
+	}
+
+	rotationSupervisor := supervisor.NewInstallationDatabaseCredentialRotationSupervisor(
+		sqlStore,
+		supervisor.InstallationDatabaseCredentialRotationOptions{
+			MaxAge:                 time.Hour,
+			MaxConcurrentRotations: 1,
+		},
+		"instanceID",
+		testutil.SetupTestEventsProducer(sqlStore, logger),
+		logger,
+	)
+
+	err := rotationSupervisor.Do()
+	require.NoError(t, err)
+
+	installations, err := sqlStore.GetInstallations(&model.InstallationFilter{
+		Paging: model.AllPagesNotDeleted(),
+		State:  model.InstallationStateDBCredentialRotationRequested,
+	}, false, false)
+	require.NoError(t, err)
+	require.Len(t, installations, 1)
+	assert.Contains(t, []string{expired1.ID, expired2.ID}, installations[0].ID)
+
+	t.Run("max concurrent rotations reached", func(t *testing.T) {
+		err = rotationSupervisor.Do()
+		require.NoError(t, err)
+
+		installations, err = sqlStore.GetInstallations(&model.InstallationFilter{
+			Paging: model.AllPagesNotDeleted(),
+			State:  model.InstallationStateDBCredentialRotationRequested,
+		}, false, false)
+		require.NoError(t, err)
+		assert.Len(t, installations, 1)
+	})
+
+	t.Run("rotation finished", func(t *testing.T) {
+		installations[0].State = model.InstallationStateStable
+		installations[0].DatabaseCredentialsRotatedAt = model.GetMillis()
+		err = sqlStore.UpdateInstallation(installations[0])
+		require.NoError(t, err)
+
+		err = rotationSupervisor.Do()
+		require.NoError(t, err)
+
+		if installations[0].ID == expired1.ID {
+			expectState(t, expired1, model.InstallationStateStable)
+			expectState(t, expired2, model.InstallationStateDBCredentialRotationRequested)
+		} else {
+			expectState(t, expired1, model.InstallationStateDBCredentialRotationRequested)
+			expectState(t, expired2, model.InstallationStateStable)
+		}
+	})
+
+	expectState(t, recent, model.InstallationStateStable)
+	expectState(t, neverRotated, model.InstallationStateStable)
+	expectState(t, unsupported, model.InstallationStateStable)
+	expectState(t, hibernating, model.InstallationStateHibernating)
+}
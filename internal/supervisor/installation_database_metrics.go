@@ -0,0 +1,105 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// installationDatabaseMetricsStore abstracts the database operations required
+// by the installation database metrics supervisor.
+type installationDatabaseMetricsStore interface {
+	GetInstallations(filter *model.InstallationFilter, includeGroupConfig, includeGroupConfigOverrides bool) ([]*model.Installation, error)
+	UpdateInstallationDatabaseMetrics(installationID string, metrics *model.InstallationDatabaseMetrics) error
+	model.InstallationDatabaseStoreInterface
+}
+
+// installationDatabaseMetricsSource gathers the observed database usage of
+// installations. Nil metrics are returned for installations whose database
+// type is not supported by the source.
+type installationDatabaseMetricsSource interface {
+	GetInstallationDatabaseMetrics(installation *model.Installation, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*model.InstallationDatabaseMetrics, error)
+}
+
+// InstallationDatabaseMetricsSupervisor periodically collects database usage
+// metrics of installations. The metrics are part of the database weight of
+// installations used when placing installations on multitenant databases.
+type InstallationDatabaseMetricsSupervisor struct {
+	store    installationDatabaseMetricsStore
+	source   installationDatabaseMetricsSource
+	interval time.Duration
+	lastRun  time.Time
+	logger   log.FieldLogger
+}
+
+// NewInstallationDatabaseMetricsSupervisor creates a new
+// InstallationDatabaseMetricsSupervisor.
+func NewInstallationDatabaseMetricsSupervisor(store installationDatabaseMetricsStore, source installationDatabaseMetricsSource, interval time.Duration, logger log.FieldLogger) *InstallationDatabaseMetricsSupervisor {
+	return &InstallationDatabaseMetricsSupervisor{
+		store:    store,
+		source:   source,
+		interval: interval,
+		logger:   logger.WithField("supervisor", "installation-database-metrics"),
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the installation database
+// metrics supervisor.
+func (s *InstallationDatabaseMetricsSupervisor) Shutdown() {
+	s.logger.Debug("Shutting down installation database metrics supervisor")
+}
+
+// Do collects and stores database metrics of stable and hibernating
+// installations.
+func (s *InstallationDatabaseMetricsSupervisor) Do() error {
+	if time.Since(s.lastRun) < s.interval {
+		return nil
+	}
+	s.lastRun = time.Now()
+
+	installations, err := s.store.GetInstallations(&model.InstallationFilter{
+		Paging: model.AllPagesNotDeleted(),
+	}, false, false)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to get installations")
+		return nil
+	}
+
+	var collected int
+	for _, installation := range installations {
+		if installation.State != model.InstallationStateStable &&
+			installation.State != model.InstallationStateHibernating {
+			continue
+		}
+
+		logger := s.logger.WithField("installation", installation.ID)
+
+		metrics, err := s.source.GetInstallationDatabaseMetrics(installation, s.store, logger)
+		if err != nil {
+			logger.WithError(err).Error("Failed to get installation database metrics")
+			continue
+		}
+		if metrics == nil {
+			continue
+		}
+
+		err = s.store.UpdateInstallationDatabaseMetrics(installation.ID, metrics)
+		if err != nil {
+			logger.WithError(err).Error("Failed to store installation database metrics")
+			continue
+		}
+		collected++
+
+		logger.Debugf("Collected installation database metrics: size=%d bytes, connections=%d, queries per second=%.2f",
+			metrics.SizeBytes, metrics.Connections, metrics.QueriesPerSecond)
+	}
+
+	s.logger.Debugf("Collected database metrics of %d installations", collected)
+
+	return nil
+}
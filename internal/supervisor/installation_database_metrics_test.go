@@ -0,0 +1,85 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockInstallationDatabaseMetricsSource struct {
+	metrics map[string]*model.InstallationDatabaseMetrics
+	calls   int
+}
+
+func (s *mockInstallationDatabaseMetricsSource) GetInstallationDatabaseMetrics(installation *model.Installation, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*model.InstallationDatabaseMetrics, error) {
+	s.calls++
+	metrics, ok := s.metrics[installation.ID]
+	if !ok {
+		return nil, errors.New("no metrics")
+	}
+	return metrics, nil
+}
+
+func TestInstallationDatabaseMetricsSupervisor(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+	sqlStore := store.MakeTestSQLStore(t, logger)
+	defer store.CloseConnection(t, sqlStore)
+
+	createInstallation := func(state string) *model.Installation {
+		installation := &model.Installation{
+			OwnerID:   model.NewID(),
+			DNS:       model.NewID() + ".example.com",
+			Affinity:  model.InstallationAffinityMultiTenant,
+			Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+			Filestore: model.InstallationFilestoreBifrost,
+			State:     state,
+		}
+		err := sqlStore.CreateInstallation(installation, nil)
+		require.NoError(t, err)
+		return installation
+	}
+
+	stableInstallation := createInstallation(model.InstallationStateStable)
+	failingInstallation := createInstallation(model.InstallationStateHibernating)
+	pendingInstallation := createInstallation(model.InstallationStateCreationRequested)
+
+	source := &mockInstallationDatabaseMetricsSource{
+		metrics: map[string]*model.InstallationDatabaseMetrics{
+			stableInstallation.ID: {
+				SizeBytes:   3 * model.DatabaseWeightSizeBytes,
+				Connections: 10,
+				CollectAt:   model.GetMillis(),
+			},
+			pendingInstallation.ID: {SizeBytes: 1},
+		},
+	}
+
+	metricsSupervisor := supervisor.NewInstallationDatabaseMetricsSupervisor(sqlStore, source, 0, logger)
+	err := metricsSupervisor.Do()
+	require.NoError(t, err)
+	assert.Equal(t, 2, source.calls)
+
+	installation, err := sqlStore.GetInstallation(stableInstallation.ID, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, source.metrics[stableInstallation.ID], installation.DatabaseMetrics)
+	assert.Equal(t, float64(3), installation.GetDatabaseWeight())
+
+	installation, err = sqlStore.GetInstallation(failingInstallation.ID, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, installation.DatabaseMetrics)
+
+	installation, err = sqlStore.GetInstallation(pendingInstallation.ID, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, installation.DatabaseMetrics)
+}
@@ -7,6 +7,7 @@ package supervisor_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/mattermost/mattermost-cloud/internal/events"
 	"github.com/mattermost/mattermost-cloud/internal/testutil"
@@ -14,6 +15,7 @@ import (
 	"github.com/mattermost/mattermost-cloud/internal/provisioner"
 
 	"github.com/aws/aws-sdk-go/service/acm"
+	"github.com/aws/aws-sdk-go/service/eks"
 	"github.com/mattermost/mattermost-cloud/internal/metrics"
 	"github.com/mattermost/mattermost-cloud/internal/store"
 	"github.com/mattermost/mattermost-cloud/internal/supervisor"
@@ -23,6 +25,7 @@ import (
 	"github.com/mattermost/mattermost-cloud/k8s"
 	"github.com/mattermost/mattermost-cloud/model"
 	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -101,6 +104,14 @@ func (s *mockInstallationStore) UpdateInstallationCRVersion(installationID, crVe
 	return nil
 }
 
+func (s *mockInstallationStore) UpdateInstallationDatabaseCredentialsRotatedAt(installationID string, rotatedAt int64) error {
+	return nil
+}
+
+func (s *mockInstallationStore) UpdateInstallationFilestoreAccessKeyRotatedAt(installationID string, rotatedAt int64) error {
+	return nil
+}
+
 func (s *mockInstallationStore) LockInstallation(installationID, lockerID string) (bool, error) {
 	return true, nil
 }
@@ -173,6 +184,14 @@ func (s *mockInstallationStore) GetAnnotationsForInstallation(installationID str
 	return nil, nil
 }
 
+func (s *mockInstallationStore) GetAnnotationsForCluster(clusterID string) ([]*model.Annotation, error) {
+	return nil, nil
+}
+
+func (s *mockInstallationStore) GetAnnotationsForClusters(filter *model.ClusterFilter) (map[string][]*model.Annotation, error) {
+	return nil, nil
+}
+
 func (s *mockInstallationStore) GetInstallationBackups(filter *model.InstallationBackupFilter) ([]*model.InstallationBackup, error) {
 	return nil, nil
 }
@@ -301,6 +320,7 @@ func (m *mockMultitenantDBStore) GetLogicalDatabase(logicalDatabaseID string) (*
 type mockInstallationProvisioner struct {
 	UseCustomClusterResources bool
 	CustomClusterResources    *k8s.ClusterResources
+	RefreshSecretsErr         error
 }
 
 func (p *mockInstallationProvisioner) ClusterInstallationProvisioner(version string) provisioner.ClusterInstallationProvisioner {
@@ -358,7 +378,7 @@ func (p *mockInstallationProvisioner) GetPublicLoadBalancerEndpoint(cluster *mod
 }
 
 func (p *mockInstallationProvisioner) RefreshSecrets(cluster *model.Cluster, installation *model.Installation, clusterInstallation *model.ClusterInstallation) error {
-	return nil
+	return p.RefreshSecretsErr
 }
 
 func (p *mockInstallationProvisioner) PrepareClusterUtilities(cluster *model.Cluster, installation *model.Installation, store model.ClusterUtilityDatabaseStoreInterface, awsClient aws.AWS) error {
@@ -397,6 +417,10 @@ func (a *mockAWS) GetVpcResources(clusterID string, logger log.FieldLogger) (aws
 	return aws.ClusterResources{}, nil
 }
 
+func (a *mockAWS) GetUtilityValuesContext(clusterID string, logger log.FieldLogger) (*model.UtilityValuesContext, error) {
+	return &model.UtilityValuesContext{ClusterID: clusterID}, nil
+}
+
 func (a *mockAWS) ReleaseVpc(clusterID string, logger log.FieldLogger) error {
 	return nil
 }
@@ -459,6 +483,14 @@ func (a *mockAWS) IsValidAMI(AMIID string, logger log.FieldLogger) (bool, error)
 	return true, nil
 }
 
+func (a *mockAWS) IsValidInstanceType(instanceType string, logger log.FieldLogger) (bool, error) {
+	return true, nil
+}
+
+func (a *mockAWS) GetSpotInstancesMarkedForTermination(instanceIDs []string, logger log.FieldLogger) ([]string, error) {
+	return nil, nil
+}
+
 func (a *mockAWS) S3FilestoreProvision(installationID string, logger log.FieldLogger) error {
 	return nil
 }
@@ -495,6 +527,54 @@ func (a *mockAWS) SwitchClusterTags(clusterID string, targetClusterID string, lo
 	return nil
 }
 
+func (a *mockAWS) EnsureEKSCluster(config aws.EKSClusterConfig, logger log.FieldLogger) (*eks.Cluster, error) {
+	return &eks.Cluster{}, nil
+}
+
+func (a *mockAWS) GetEKSCluster(name string) (*eks.Cluster, error) {
+	return nil, nil
+}
+
+func (a *mockAWS) WaitForEKSClusterActive(name string, timeout time.Duration) (*eks.Cluster, error) {
+	return &eks.Cluster{}, nil
+}
+
+func (a *mockAWS) UpdateEKSClusterVersion(name, version string, logger log.FieldLogger) error {
+	return nil
+}
+
+func (a *mockAWS) EnsureEKSNodeGroup(clusterName string, config aws.EKSNodeGroupConfig, logger log.FieldLogger) (*eks.Nodegroup, error) {
+	return &eks.Nodegroup{}, nil
+}
+
+func (a *mockAWS) GetEKSNodeGroup(clusterName, nodeGroupName string) (*eks.Nodegroup, error) {
+	return nil, nil
+}
+
+func (a *mockAWS) WaitForEKSNodeGroupActive(clusterName, nodeGroupName string, timeout time.Duration) (*eks.Nodegroup, error) {
+	return &eks.Nodegroup{}, nil
+}
+
+func (a *mockAWS) UpdateEKSNodeGroupVersion(clusterName, nodeGroupName, version string, logger log.FieldLogger) error {
+	return nil
+}
+
+func (a *mockAWS) UpdateEKSNodeGroupScaling(clusterName, nodeGroupName string, minCount, maxCount int64, logger log.FieldLogger) error {
+	return nil
+}
+
+func (a *mockAWS) EnsureEKSNodeGroupDeleted(clusterName, nodeGroupName string, timeout time.Duration, logger log.FieldLogger) error {
+	return nil
+}
+
+func (a *mockAWS) EnsureEKSClusterDeleted(name string, timeout time.Duration, logger log.FieldLogger) error {
+	return nil
+}
+
+func (a *mockAWS) GetEKSClusterToken(name string) (string, error) {
+	return "", nil
+}
+
 func (a *mockAWS) SecretsManagerGetPGBouncerAuthUserPassword(vpcID string) (string, error) {
 	return "password", nil
 }
@@ -511,6 +591,64 @@ func (m *mockEventProducer) ProduceClusterInstallationStateChangeEvent(clusterIn
 	return nil
 }
 
+// mockCredentials records credential rotations of installation resources.
+type mockCredentials struct {
+	rotateErr   error
+	revokeErr   error
+	rotations   int
+	revocations int
+}
+
+type mockCredentialsDatabase struct {
+	mockDatabase
+	credentials *mockCredentials
+}
+
+func (m *mockCredentialsDatabase) RotateCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	m.credentials.rotations++
+	return m.credentials.rotateErr
+}
+
+func (m *mockCredentialsDatabase) RevokePreviousCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	m.credentials.revocations++
+	return m.credentials.revokeErr
+}
+
+type mockCredentialsFilestore struct {
+	model.Filestore
+	credentials *mockCredentials
+}
+
+func (m *mockCredentialsFilestore) RotateAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	m.credentials.rotations++
+	return m.credentials.rotateErr
+}
+
+func (m *mockCredentialsFilestore) RevokePreviousAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	m.credentials.revocations++
+	return m.credentials.revokeErr
+}
+
+// mockInstallationResources provides installation resources recording their
+// credential rotations, or the default resources if no credentials are set.
+type mockInstallationResources struct {
+	credentials *mockCredentials
+}
+
+func (m *mockInstallationResources) GetDatabaseForInstallation(installation *model.Installation) model.Database {
+	if m.credentials == nil {
+		return (&utils.ResourceUtil{}).GetDatabaseForInstallation(installation)
+	}
+	return &mockCredentialsDatabase{credentials: m.credentials}
+}
+
+func (m *mockInstallationResources) GetFilestore(installation *model.Installation) model.Filestore {
+	if m.credentials == nil {
+		return (&utils.ResourceUtil{}).GetFilestore(installation)
+	}
+	return &mockCredentialsFilestore{credentials: m.credentials}
+}
+
 func TestInstallationSupervisorDo(t *testing.T) {
 	standardSchedulingOptions := supervisor.NewInstallationSupervisorSchedulingOptions(false, 80, 0)
 
@@ -1581,6 +1719,47 @@ func TestInstallationSupervisor(t *testing.T) {
 		expectClusterInstallations(t, sqlStore, installation, 0, "")
 	})
 
+	t.Run("no compatible clusters, cluster installations not yet created, EKS cluster", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+		supervisor := supervisor.NewInstallationSupervisor(
+			sqlStore,
+			&mockInstallationProvisioner{},
+			&mockAWS{},
+			"instanceID",
+			false,
+			false,
+			standardSchedulingOptions,
+			&utils.ResourceUtil{},
+			logger,
+			cloudMetrics,
+			testutil.SetupTestEventsProducer(sqlStore, logger),
+			false,
+		)
+
+		cluster := standardStableTestCluster()
+		cluster.Provisioner = model.ProvisionerEKS
+		err := sqlStore.CreateCluster(cluster, nil)
+		require.NoError(t, err)
+
+		installation := &model.Installation{
+			OwnerID:  model.NewID(),
+			Version:  "version",
+			DNS:      "dns.example.com",
+			Size:     mmv1alpha1.Size100String,
+			Affinity: model.InstallationAffinityIsolated,
+			State:    model.InstallationStateCreationNoCompatibleClusters,
+		}
+
+		err = sqlStore.CreateInstallation(installation, nil)
+		require.NoError(t, err)
+
+		supervisor.Supervise(installation)
+		expectInstallationState(t, sqlStore, installation, model.InstallationStateCreationNoCompatibleClusters)
+		expectClusterInstallations(t, sqlStore, installation, 0, "")
+	})
+
 	t.Run("no compatible clusters, cluster installations not yet created, available cluster", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
 		sqlStore := store.MakeTestSQLStore(t, logger)
@@ -2193,6 +2372,321 @@ func TestInstallationSupervisor(t *testing.T) {
 		expectClusterInstallations(t, sqlStore, installation, 1, model.ClusterInstallationStateReconciling)
 	})
 
+	t.Run("credential rotation", func(t *testing.T) {
+		setup := func(t *testing.T, state, clusterInstallationState string, provisioner *mockInstallationProvisioner, credentials *mockCredentials) (*store.SQLStore, *supervisor.InstallationSupervisor, *model.Installation) {
+			logger := testlib.MakeLogger(t)
+			sqlStore := store.MakeTestSQLStore(t, logger)
+			installationSupervisor := supervisor.NewInstallationSupervisor(
+				sqlStore,
+				provisioner,
+				&mockAWS{},
+				"instanceID",
+				false,
+				false,
+				standardSchedulingOptions,
+				&mockInstallationResources{credentials: credentials},
+				logger,
+				cloudMetrics,
+				testutil.SetupTestEventsProducer(sqlStore, logger),
+				false,
+			)
+
+			cluster := standardStableTestCluster()
+			err := sqlStore.CreateCluster(cluster, nil)
+			require.NoError(t, err)
+
+			groupID := model.NewID()
+			installation := &model.Installation{
+				OwnerID:  model.NewID(),
+				Version:  "version",
+				DNS:      "dns.example.com",
+				Size:     mmv1alpha1.Size100String,
+				Affinity: model.InstallationAffinityIsolated,
+				GroupID:  &groupID,
+				State:    state,
+			}
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+
+			clusterInstallation := &model.ClusterInstallation{
+				ClusterID:      cluster.ID,
+				InstallationID: installation.ID,
+				Namespace:      "namespace",
+				State:          clusterInstallationState,
+			}
+			err = sqlStore.CreateClusterInstallation(clusterInstallation)
+			require.NoError(t, err)
+
+			return sqlStore, installationSupervisor, installation
+		}
+
+		supervise := func(t *testing.T, sqlStore *store.SQLStore, installationSupervisor *supervisor.InstallationSupervisor, installation *model.Installation) {
+			t.Helper()
+			installation, err := sqlStore.GetInstallation(installation.ID, false, false)
+			require.NoError(t, err)
+			installationSupervisor.Supervise(installation)
+		}
+
+		for _, testCase := range []struct {
+			description                      string
+			state                            string
+			clusterInstallationState         string
+			credentials                      *mockCredentials
+			refreshSecretsErr                error
+			expectedState                    string
+			expectedClusterInstallationState string
+			expectedRotations                int
+			expectedRevocations              int
+		}{
+			{
+				description:                      "database credential rotation requested, database not supported",
+				state:                            model.InstallationStateDBCredentialRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				expectedState:                    model.InstallationStateDBCredentialRotationRequested,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+			},
+			{
+				description:                      "database credential rotation requested, rotation fails",
+				state:                            model.InstallationStateDBCredentialRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{rotateErr: errors.New("rotation failed")},
+				expectedState:                    model.InstallationStateDBCredentialRotationRequested,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRotations:                1,
+			},
+			{
+				description:                      "database credential rotation requested, refreshing secrets fails",
+				state:                            model.InstallationStateDBCredentialRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				refreshSecretsErr:                errors.New("refresh failed"),
+				expectedState:                    model.InstallationStateDBCredentialRotationRequested,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRotations:                1,
+			},
+			{
+				description:                      "database credential rotation requested",
+				state:                            model.InstallationStateDBCredentialRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateDBCredentialRotationInProgress,
+				expectedClusterInstallationState: model.ClusterInstallationStateReconciling,
+				expectedRotations:                1,
+			},
+			{
+				description:                      "database credential rotation in progress, cluster installations reconciling",
+				state:                            model.InstallationStateDBCredentialRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateReconciling,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateDBCredentialRotationInProgress,
+				expectedClusterInstallationState: model.ClusterInstallationStateReconciling,
+			},
+			{
+				description:                      "database credential rotation in progress, cluster installations failed",
+				state:                            model.InstallationStateDBCredentialRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateCreationFailed,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateDBCredentialRotationFailed,
+				expectedClusterInstallationState: model.ClusterInstallationStateCreationFailed,
+			},
+			{
+				description:                      "database credential rotation in progress, revoking fails",
+				state:                            model.InstallationStateDBCredentialRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{revokeErr: errors.New("revoke failed")},
+				expectedState:                    model.InstallationStateDBCredentialRotationInProgress,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRevocations:              1,
+			},
+			{
+				description:                      "database credential rotation in progress, cluster installations stable",
+				state:                            model.InstallationStateDBCredentialRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateStable,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRevocations:              1,
+			},
+			{
+				description:                      "filestore access key rotation requested, filestore not supported",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationRequested,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+			},
+			{
+				description:                      "filestore access key rotation requested, rotation fails",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{rotateErr: errors.New("rotation failed")},
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationRequested,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRotations:                1,
+			},
+			{
+				description:                      "filestore access key rotation requested, refreshing secrets fails",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				refreshSecretsErr:                errors.New("refresh failed"),
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationRefreshingSecrets,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRotations:                1,
+			},
+			{
+				description:                      "filestore access key rotation requested",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationRequested,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				expectedClusterInstallationState: model.ClusterInstallationStateReconciling,
+				expectedRotations:                1,
+			},
+			{
+				description:                      "filestore access key rotation refreshing secrets, refreshing secrets fails",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationRefreshingSecrets,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				refreshSecretsErr:                errors.New("refresh failed"),
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationRefreshingSecrets,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+			},
+			{
+				description:                      "filestore access key rotation refreshing secrets",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationRefreshingSecrets,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				expectedClusterInstallationState: model.ClusterInstallationStateReconciling,
+			},
+			{
+				description:                      "filestore access key rotation in progress, cluster installations reconciling",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateReconciling,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				expectedClusterInstallationState: model.ClusterInstallationStateReconciling,
+			},
+			{
+				description:                      "filestore access key rotation in progress, cluster installations failed",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateCreationFailed,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationFailed,
+				expectedClusterInstallationState: model.ClusterInstallationStateCreationFailed,
+			},
+			{
+				description:                      "filestore access key rotation in progress, revoking fails",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{revokeErr: errors.New("revoke failed")},
+				expectedState:                    model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRevocations:              1,
+			},
+			{
+				description:                      "filestore access key rotation in progress, cluster installations stable",
+				state:                            model.InstallationStateFilestoreAccessKeyRotationInProgress,
+				clusterInstallationState:         model.ClusterInstallationStateStable,
+				credentials:                      &mockCredentials{},
+				expectedState:                    model.InstallationStateStable,
+				expectedClusterInstallationState: model.ClusterInstallationStateStable,
+				expectedRevocations:              1,
+			},
+		} {
+			t.Run(testCase.description, func(t *testing.T) {
+				provisioner := &mockInstallationProvisioner{RefreshSecretsErr: testCase.refreshSecretsErr}
+				sqlStore, installationSupervisor, installation := setup(t, testCase.state, testCase.clusterInstallationState, provisioner, testCase.credentials)
+				defer store.CloseConnection(t, sqlStore)
+
+				supervise(t, sqlStore, installationSupervisor, installation)
+				expectInstallationState(t, sqlStore, installation, testCase.expectedState)
+				expectClusterInstallations(t, sqlStore, installation, 1, testCase.expectedClusterInstallationState)
+				if testCase.credentials != nil {
+					assert.Equal(t, testCase.expectedRotations, testCase.credentials.rotations)
+					assert.Equal(t, testCase.expectedRevocations, testCase.credentials.revocations)
+				}
+			})
+		}
+
+		t.Run("database credential rotation retried after refreshing secrets failed", func(t *testing.T) {
+			credentials := &mockCredentials{}
+			provisioner := &mockInstallationProvisioner{RefreshSecretsErr: errors.New("refresh failed")}
+			sqlStore, installationSupervisor, installation := setup(t, model.InstallationStateDBCredentialRotationRequested, model.ClusterInstallationStateStable, provisioner, credentials)
+			defer store.CloseConnection(t, sqlStore)
+
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateDBCredentialRotationRequested)
+
+			// The database resumes the rotation it already completed instead
+			// of rotating the credentials again.
+			provisioner.RefreshSecretsErr = nil
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateDBCredentialRotationInProgress)
+			expectClusterInstallations(t, sqlStore, installation, 1, model.ClusterInstallationStateReconciling)
+			assert.Equal(t, 2, credentials.rotations)
+			assert.Equal(t, 0, credentials.revocations)
+
+			installation, err := sqlStore.GetInstallation(installation.ID, false, false)
+			require.NoError(t, err)
+			assert.NotZero(t, installation.DatabaseCredentialsRotatedAt)
+		})
+
+		t.Run("database credential revocation retried", func(t *testing.T) {
+			credentials := &mockCredentials{revokeErr: errors.New("revoke failed")}
+			sqlStore, installationSupervisor, installation := setup(t, model.InstallationStateDBCredentialRotationInProgress, model.ClusterInstallationStateStable, &mockInstallationProvisioner{}, credentials)
+			defer store.CloseConnection(t, sqlStore)
+
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateDBCredentialRotationInProgress)
+
+			credentials.revokeErr = nil
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateStable)
+			assert.Equal(t, 0, credentials.rotations)
+			assert.Equal(t, 2, credentials.revocations)
+		})
+
+		t.Run("filestore access key rotation retried after refreshing secrets failed", func(t *testing.T) {
+			credentials := &mockCredentials{}
+			provisioner := &mockInstallationProvisioner{RefreshSecretsErr: errors.New("refresh failed")}
+			sqlStore, installationSupervisor, installation := setup(t, model.InstallationStateFilestoreAccessKeyRotationRequested, model.ClusterInstallationStateStable, provisioner, credentials)
+			defer store.CloseConnection(t, sqlStore)
+
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateFilestoreAccessKeyRotationRefreshingSecrets)
+			expectClusterInstallations(t, sqlStore, installation, 1, model.ClusterInstallationStateStable)
+
+			// The stored access key is not rotated again, only the secrets
+			// are refreshed.
+			provisioner.RefreshSecretsErr = nil
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateFilestoreAccessKeyRotationInProgress)
+			expectClusterInstallations(t, sqlStore, installation, 1, model.ClusterInstallationStateReconciling)
+			assert.Equal(t, 1, credentials.rotations)
+			assert.Equal(t, 0, credentials.revocations)
+
+			installation, err := sqlStore.GetInstallation(installation.ID, false, false)
+			require.NoError(t, err)
+			assert.NotZero(t, installation.FilestoreAccessKeyRotatedAt)
+		})
+
+		t.Run("filestore access key revocation retried", func(t *testing.T) {
+			credentials := &mockCredentials{revokeErr: errors.New("revoke failed")}
+			sqlStore, installationSupervisor, installation := setup(t, model.InstallationStateFilestoreAccessKeyRotationInProgress, model.ClusterInstallationStateStable, &mockInstallationProvisioner{}, credentials)
+			defer store.CloseConnection(t, sqlStore)
+
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateFilestoreAccessKeyRotationInProgress)
+
+			credentials.revokeErr = nil
+			supervise(t, sqlStore, installationSupervisor, installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateStable)
+			assert.Equal(t, 0, credentials.rotations)
+			assert.Equal(t, 2, credentials.revocations)
+		})
+	})
+
 	t.Run("deletion requested, cluster installations stable", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
 		sqlStore := store.MakeTestSQLStore(t, logger)
@@ -3026,6 +3520,172 @@ func TestInstallationSupervisor(t *testing.T) {
 		})
 	})
 
+	t.Run("placement rules", func(t *testing.T) {
+		setupSupervisor := func(t *testing.T) (*supervisor.InstallationSupervisor, *store.SQLStore) {
+			logger := testlib.MakeLogger(t)
+			sqlStore := store.MakeTestSQLStore(t, logger)
+			supervisor := supervisor.NewInstallationSupervisor(
+				sqlStore,
+				&mockInstallationProvisioner{},
+				&mockAWS{},
+				"instanceID",
+				false,
+				false,
+				standardSchedulingOptions,
+				&utils.ResourceUtil{},
+				logger,
+				cloudMetrics,
+				testutil.SetupTestEventsProducer(sqlStore, logger),
+				false,
+			)
+
+			return supervisor, sqlStore
+		}
+
+		installationWithPlacement := func(ownerID, dns string, placement *model.InstallationPlacement) *model.Installation {
+			return &model.Installation{
+				OwnerID:   ownerID,
+				Version:   "version",
+				DNS:       dns,
+				Size:      mmv1alpha1.Size100String,
+				Affinity:  model.InstallationAffinityMultiTenant,
+				State:     model.InstallationStateCreationRequested,
+				Placement: placement,
+			}
+		}
+
+		t.Run("required cluster annotations missing", func(t *testing.T) {
+			supervisor, sqlStore := setupSupervisor(t)
+			defer store.CloseConnection(t, sqlStore)
+
+			cluster := standardStableTestCluster()
+			err := sqlStore.CreateCluster(cluster, []*model.Annotation{{Name: "general"}})
+			require.NoError(t, err)
+
+			installation := installationWithPlacement(model.NewID(), "dns.example.com", &model.InstallationPlacement{
+				RequiredClusterAnnotations: []string{"compliance-hipaa"},
+			})
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+
+			supervisor.Supervise(installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateCreationNoCompatibleClusters)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster, 0)
+		})
+
+		t.Run("required cluster annotations present", func(t *testing.T) {
+			supervisor, sqlStore := setupSupervisor(t)
+			defer store.CloseConnection(t, sqlStore)
+
+			cluster1 := standardStableTestCluster()
+			err := sqlStore.CreateCluster(cluster1, []*model.Annotation{{Name: "general"}})
+			require.NoError(t, err)
+			cluster2 := standardStableTestCluster()
+			err = sqlStore.CreateCluster(cluster2, []*model.Annotation{{Name: "general"}, {Name: "compliance-hipaa"}})
+			require.NoError(t, err)
+
+			installation := installationWithPlacement(model.NewID(), "dns.example.com", &model.InstallationPlacement{
+				RequiredClusterAnnotations: []string{"compliance-hipaa"},
+			})
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+
+			supervisor.Supervise(installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateCreationInProgress)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster1, 0)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster2, 1)
+		})
+
+		t.Run("required node group", func(t *testing.T) {
+			supervisor, sqlStore := setupSupervisor(t)
+			defer store.CloseConnection(t, sqlStore)
+
+			cluster1 := standardStableTestCluster()
+			err := sqlStore.CreateCluster(cluster1, nil)
+			require.NoError(t, err)
+			cluster2 := standardStableTestCluster()
+			cluster2.ProvisionerMetadataKops.CustomInstanceGroups = model.KopsInstanceGroupsMetadata{
+				"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 2, NodeMaxCount: 2},
+			}
+			err = sqlStore.CreateCluster(cluster2, nil)
+			require.NoError(t, err)
+
+			installation := installationWithPlacement(model.NewID(), "dns.example.com", &model.InstallationPlacement{
+				NodeGroup: "memory",
+			})
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+
+			supervisor.Supervise(installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateCreationInProgress)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster1, 0)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster2, 1)
+		})
+
+		t.Run("preferred cluster annotations", func(t *testing.T) {
+			supervisor, sqlStore := setupSupervisor(t)
+			defer store.CloseConnection(t, sqlStore)
+
+			cluster1 := standardStableTestCluster()
+			err := sqlStore.CreateCluster(cluster1, nil)
+			require.NoError(t, err)
+			cluster2 := standardStableTestCluster()
+			err = sqlStore.CreateCluster(cluster2, []*model.Annotation{{Name: "region-eu"}})
+			require.NoError(t, err)
+
+			installation := installationWithPlacement(model.NewID(), "dns.example.com", &model.InstallationPlacement{
+				PreferredClusterAnnotations: []string{"region-eu"},
+			})
+			err = sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+
+			supervisor.Supervise(installation)
+			expectInstallationState(t, sqlStore, installation, model.InstallationStateCreationInProgress)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster1, 0)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster2, 1)
+		})
+
+		t.Run("owner anti-affinity", func(t *testing.T) {
+			supervisor, sqlStore := setupSupervisor(t)
+			defer store.CloseConnection(t, sqlStore)
+
+			cluster := standardStableTestCluster()
+			err := sqlStore.CreateCluster(cluster, nil)
+			require.NoError(t, err)
+
+			owner := model.NewID()
+			installation1 := installationWithPlacement(owner, "dns1.example.com", &model.InstallationPlacement{
+				OwnerAntiAffinity: true,
+			})
+			err = sqlStore.CreateInstallation(installation1, nil)
+			require.NoError(t, err)
+
+			supervisor.Supervise(installation1)
+			expectInstallationState(t, sqlStore, installation1, model.InstallationStateCreationInProgress)
+			expectClusterInstallationsOnCluster(t, sqlStore, cluster, 1)
+
+			t.Run("same owner", func(t *testing.T) {
+				installation2 := installationWithPlacement(owner, "dns2.example.com", nil)
+				err = sqlStore.CreateInstallation(installation2, nil)
+				require.NoError(t, err)
+
+				supervisor.Supervise(installation2)
+				expectInstallationState(t, sqlStore, installation2, model.InstallationStateCreationInProgress)
+				expectClusterInstallationsOnCluster(t, sqlStore, cluster, 2)
+			})
+
+			t.Run("different owner", func(t *testing.T) {
+				installation3 := installationWithPlacement(model.NewID(), "dns3.example.com", nil)
+				err = sqlStore.CreateInstallation(installation3, nil)
+				require.NoError(t, err)
+
+				supervisor.Supervise(installation3)
+				expectInstallationState(t, sqlStore, installation3, model.InstallationStateCreationNoCompatibleClusters)
+				expectClusterInstallationsOnCluster(t, sqlStore, cluster, 2)
+			})
+		})
+	})
+
 	t.Run("force CR upgrade to v1Beta", func(t *testing.T) {
 		logger := testlib.MakeLogger(t)
 		sqlStore := store.MakeTestSQLStore(t, logger)
@@ -0,0 +1,131 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// multitenantDatabaseCapacityStore abstracts the database operations required
+// by the multitenant database capacity supervisor.
+type multitenantDatabaseCapacityStore interface {
+	model.InstallationDatabaseStoreInterface
+	supervisorTaskLockStore
+}
+
+// multitenantDatabaseCapacityProvisioner creates new multitenant databases
+// when the existing ones of a VPC fill up.
+type multitenantDatabaseCapacityProvisioner interface {
+	EnsureMultitenantDatabaseCapacity(vpcID, databaseType, instanceID string, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error
+}
+
+// MultitenantDatabaseCapacitySupervisor makes sure that the multitenant
+// databases of each VPC have room for new installations.
+//
+// Only one provisioning server works on the databases of a given VPC and
+// database type at a time, so that a single new RDS cluster is created when
+// the existing ones cross the utilization threshold.
+type MultitenantDatabaseCapacitySupervisor struct {
+	store       multitenantDatabaseCapacityStore
+	provisioner multitenantDatabaseCapacityProvisioner
+	instanceID  string
+	logger      log.FieldLogger
+}
+
+// NewMultitenantDatabaseCapacitySupervisor creates a new
+// MultitenantDatabaseCapacitySupervisor.
+func NewMultitenantDatabaseCapacitySupervisor(store multitenantDatabaseCapacityStore, provisioner multitenantDatabaseCapacityProvisioner, instanceID string, logger log.FieldLogger) *MultitenantDatabaseCapacitySupervisor {
+	return &MultitenantDatabaseCapacitySupervisor{
+		store:       store,
+		provisioner: provisioner,
+		instanceID:  instanceID,
+		logger:      logger.WithField("supervisor", "multitenant-database-capacity"),
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the multitenant database
+// capacity supervisor.
+func (s *MultitenantDatabaseCapacitySupervisor) Shutdown() {
+	s.logger.Debug("Shutting down multitenant database capacity supervisor")
+}
+
+// multitenantDatabaseGroup identifies the multitenant databases of a type in
+// a VPC.
+type multitenantDatabaseGroup struct {
+	vpcID        string
+	databaseType string
+}
+
+// Do ensures the capacity of the multitenant databases of every VPC and
+// database type.
+func (s *MultitenantDatabaseCapacitySupervisor) Do() error {
+	databases, err := s.store.GetMultitenantDatabases(&model.MultitenantDatabaseFilter{
+		MaxInstallationsLimit: model.NoInstallationsLimit,
+		Paging:                model.AllPagesNotDeleted(),
+	})
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to query for multitenant databases")
+		return nil
+	}
+
+	groups := map[multitenantDatabaseGroup]bool{}
+	for _, database := range databases {
+		// Only multitenant databases on RDS clusters are created by the
+		// provisioner.
+		switch database.DatabaseType {
+		case model.DatabaseEngineTypeMySQL, model.DatabaseEngineTypePostgres, model.DatabaseEngineTypePostgresProxy:
+		default:
+			continue
+		}
+		groups[multitenantDatabaseGroup{vpcID: database.VpcID, databaseType: database.DatabaseType}] = true
+	}
+
+	sortedGroups := make([]multitenantDatabaseGroup, 0, len(groups))
+	for group := range groups {
+		sortedGroups = append(sortedGroups, group)
+	}
+	sort.Slice(sortedGroups, func(i, j int) bool {
+		if sortedGroups[i].vpcID != sortedGroups[j].vpcID {
+			return sortedGroups[i].vpcID < sortedGroups[j].vpcID
+		}
+		return sortedGroups[i].databaseType < sortedGroups[j].databaseType
+	})
+
+	for _, group := range sortedGroups {
+		s.Supervise(group.vpcID, group.databaseType)
+	}
+
+	return nil
+}
+
+// Supervise ensures the capacity of the multitenant databases of the given
+// type in the VPC while holding the capacity lock of the VPC.
+func (s *MultitenantDatabaseCapacitySupervisor) Supervise(vpcID, databaseType string) {
+	logger := s.logger.WithFields(log.Fields{
+		"vpc":           vpcID,
+		"database-type": databaseType,
+	})
+
+	lock := newSupervisorTaskLock(multitenantDatabaseCapacityTask(vpcID, databaseType), s.instanceID, s.store, logger)
+	if !lock.TryLock() {
+		return
+	}
+	defer lock.Unlock()
+
+	err := s.provisioner.EnsureMultitenantDatabaseCapacity(vpcID, databaseType, s.instanceID, s.store, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to ensure multitenant database capacity")
+	}
+}
+
+// multitenantDatabaseCapacityTask returns the supervisor task guarding the
+// capacity of the multitenant databases of a type in a VPC.
+func multitenantDatabaseCapacityTask(vpcID, databaseType string) string {
+	return fmt.Sprintf("multitenant-database-capacity-%s-%s", databaseType, vpcID)
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockMultitenantDatabaseCapacityProvisioner struct {
+	t        *testing.T
+	sqlStore *store.SQLStore
+	calls    []string
+}
+
+func (p *mockMultitenantDatabaseCapacityProvisioner) EnsureMultitenantDatabaseCapacity(vpcID, databaseType, instanceID string, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	task, err := p.sqlStore.GetSupervisorTask("multitenant-database-capacity-" + databaseType + "-" + vpcID)
+	require.NoError(p.t, err)
+	require.NotNil(p.t, task)
+	require.NotNil(p.t, task.LockAcquiredBy)
+	assert.Equal(p.t, instanceID, *task.LockAcquiredBy)
+
+	p.calls = append(p.calls, vpcID+"/"+databaseType)
+	return nil
+}
+
+func TestMultitenantDatabaseCapacitySupervisor(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+
+	setup := func(t *testing.T) *store.SQLStore {
+		sqlStore := store.MakeTestSQLStore(t, logger)
+
+		for _, database := range []*model.MultitenantDatabase{
+			{RdsClusterID: "rds-cluster-multitenant-1", VpcID: "vpc1", DatabaseType: model.DatabaseEngineTypePostgres, State: model.DatabaseStateStable},
+			{RdsClusterID: "rds-cluster-multitenant-2", VpcID: "vpc1", DatabaseType: model.DatabaseEngineTypePostgres, State: model.DatabaseStateCreationRequested},
+			{RdsClusterID: "rds-cluster-multitenant-3", VpcID: "vpc1", DatabaseType: model.DatabaseEngineTypeMySQL, State: model.DatabaseStateStable},
+			{RdsClusterID: "rds-cluster-multitenant-4", VpcID: "vpc2", DatabaseType: model.DatabaseEngineTypePostgres, State: model.DatabaseStateStable},
+			{RdsClusterID: "rds-cluster-multitenant-5", VpcID: "vpc2", DatabaseType: model.DatabaseEngineTypePostgresProxy, State: model.DatabaseStateStable},
+		} {
+			err := sqlStore.CreateMultitenantDatabase(database)
+			require.NoError(t, err)
+		}
+
+		return sqlStore
+	}
+
+	t.Run("ensures capacity of each vpc and database type", func(t *testing.T) {
+		sqlStore := setup(t)
+		defer store.CloseConnection(t, sqlStore)
+
+		provisioner := &mockMultitenantDatabaseCapacityProvisioner{t: t, sqlStore: sqlStore}
+		capacitySupervisor := supervisor.NewMultitenantDatabaseCapacitySupervisor(sqlStore, provisioner, "instanceID", logger)
+
+		err := capacitySupervisor.Do()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vpc1/mysql", "vpc1/postgres", "vpc2/postgres", "vpc2/postgres-proxy"}, provisioner.calls)
+
+		task, err := sqlStore.GetSupervisorTask("multitenant-database-capacity-postgres-vpc1")
+		require.NoError(t, err)
+		assert.Nil(t, task.LockAcquiredBy)
+	})
+
+	t.Run("skips vpcs locked by another provisioner", func(t *testing.T) {
+		sqlStore := setup(t)
+		defer store.CloseConnection(t, sqlStore)
+
+		locked, err := sqlStore.LockSupervisorTask("multitenant-database-capacity-postgres-vpc1", "otherInstanceID")
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		provisioner := &mockMultitenantDatabaseCapacityProvisioner{t: t, sqlStore: sqlStore}
+		capacitySupervisor := supervisor.NewMultitenantDatabaseCapacitySupervisor(sqlStore, provisioner, "instanceID", logger)
+
+		err = capacitySupervisor.Do()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"vpc1/mysql", "vpc2/postgres", "vpc2/postgres-proxy"}, provisioner.calls)
+
+		task, err := sqlStore.GetSupervisorTask("multitenant-database-capacity-postgres-vpc1")
+		require.NoError(t, err)
+		require.NotNil(t, task.LockAcquiredBy)
+		assert.Equal(t, "otherInstanceID", *task.LockAcquiredBy)
+	})
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import log "github.com/sirupsen/logrus"
+
+type multitenantDatabaseLockStore interface {
+	LockMultitenantDatabases(ids []string, lockerID string) (bool, error)
+	UnlockMultitenantDatabases(ids []string, lockerID string, force bool) (bool, error)
+}
+
+type multitenantDatabaseLock struct {
+	ids      []string
+	lockerID string
+	store    multitenantDatabaseLockStore
+	logger   log.FieldLogger
+}
+
+func newMultitenantDatabaseLock(id, lockerID string, store multitenantDatabaseLockStore, logger log.FieldLogger) *multitenantDatabaseLock {
+	return &multitenantDatabaseLock{
+		ids:      []string{id},
+		lockerID: lockerID,
+		store:    store,
+		logger:   logger,
+	}
+}
+
+func (l *multitenantDatabaseLock) TryLock() bool {
+	locked, err := l.store.LockMultitenantDatabases(l.ids, l.lockerID)
+	if err != nil {
+		l.logger.WithError(err).Error("failed to lock multitenant databases")
+		return false
+	}
+
+	return locked
+}
+
+func (l *multitenantDatabaseLock) Unlock() {
+	unlocked, err := l.store.UnlockMultitenantDatabases(l.ids, l.lockerID, false)
+	if err != nil {
+		l.logger.WithError(err).Error("failed to unlock multitenant databases")
+	} else if unlocked != true {
+		l.logger.Error("failed to release lock for multitenant databases")
+	}
+}
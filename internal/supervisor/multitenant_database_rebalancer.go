@@ -0,0 +1,368 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// multitenantDatabaseRebalancerStore abstracts the database operations
+// required by the multitenant database rebalancer.
+type multitenantDatabaseRebalancerStore interface {
+	GetMultitenantDatabases(filter *model.MultitenantDatabaseFilter) ([]*model.MultitenantDatabase, error)
+	GetInstallationDBMigrationOperations(filter *model.InstallationDBMigrationFilter) ([]*model.InstallationDBMigrationOperation, error)
+	TriggerInstallationDBMigration(dbMigrationOp *model.InstallationDBMigrationOperation, installation *model.Installation) (*model.InstallationDBMigrationOperation, error)
+	getAndLockInstallationStore
+}
+
+// MultitenantDatabaseRebalancerOptions are the various options that control
+// how multitenant databases are rebalanced.
+type MultitenantDatabaseRebalancerOptions struct {
+	// Execute controls if proposed moves are executed as installation DB
+	// migration operations or only logged.
+	Execute bool
+	// Tolerance is the percent of the capacity of a multitenant database by
+	// which its weight may exceed the average weight of the databases in its
+	// VPC before installations are moved away from it.
+	Tolerance int
+	// MaxConcurrentMigrations is the maximum number of DB migration operations
+	// in progress at the same time.
+	MaxConcurrentMigrations int
+	// Interval is the minimum time between rebalancing runs.
+	Interval time.Duration
+}
+
+// MultitenantDatabaseRebalancer periodically compares the weight of the
+// installations on the multitenant databases of each VPC and moves
+// installations from the most loaded databases to the least loaded ones.
+type MultitenantDatabaseRebalancer struct {
+	store      multitenantDatabaseRebalancerStore
+	options    MultitenantDatabaseRebalancerOptions
+	instanceID string
+	lastRun    time.Time
+	logger     log.FieldLogger
+}
+
+// NewMultitenantDatabaseRebalancer creates a new MultitenantDatabaseRebalancer.
+func NewMultitenantDatabaseRebalancer(store multitenantDatabaseRebalancerStore, options MultitenantDatabaseRebalancerOptions, instanceID string, logger log.FieldLogger) *MultitenantDatabaseRebalancer {
+	return &MultitenantDatabaseRebalancer{
+		store:      store,
+		options:    options,
+		instanceID: instanceID,
+		logger:     logger.WithField("supervisor", "multitenant-database-rebalancer"),
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the multitenant database
+// rebalancer.
+func (r *MultitenantDatabaseRebalancer) Shutdown() {
+	r.logger.Debug("Shutting down multitenant database rebalancer")
+}
+
+// Do checks the load of multitenant databases and, if needed, proposes or
+// executes installation database migrations between them.
+func (r *MultitenantDatabaseRebalancer) Do() error {
+	if time.Since(r.lastRun) < r.options.Interval {
+		return nil
+	}
+	r.lastRun = time.Now()
+
+	// Succeeded migrations still need to be committed or rolled back, so
+	// their installations and databases are left alone as well.
+	pendingOperations, err := r.store.GetInstallationDBMigrationOperations(&model.InstallationDBMigrationFilter{
+		Paging: model.AllPagesNotDeleted(),
+		States: append([]model.InstallationDBMigrationOperationState{model.InstallationDBMigrationStateSucceeded}, model.AllInstallationDBMigrationOperationsStatesPendingWork...),
+	})
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get pending DB migration operations")
+		return nil
+	}
+
+	plan, err := r.planRebalance(pendingOperations, r.logger)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to plan multitenant database rebalancing")
+		return nil
+	}
+	if len(plan.moves) == 0 {
+		r.logger.Debug("No multitenant database rebalancing required")
+		return nil
+	}
+
+	for _, move := range plan.moves {
+		r.logger.WithFields(log.Fields{
+			"installation":         move.installation.ID,
+			"source-database":      move.source.database.ID,
+			"destination-database": move.destination.database.ID,
+			"weight":               move.installation.GetDatabaseWeight(),
+		}).Info("Proposed installation move to rebalance multitenant databases")
+	}
+
+	report := &databaseRebalanceReport{proposed: len(plan.moves)}
+	defer func() { report.log(plan, r.options.Execute, r.logger) }()
+
+	if !r.options.Execute {
+		return nil
+	}
+
+	inProgress := 0
+	for _, operation := range pendingOperations {
+		if operation.State != model.InstallationDBMigrationStateSucceeded {
+			inProgress++
+		}
+	}
+	available := r.options.MaxConcurrentMigrations - inProgress
+	if available < 0 {
+		available = 0
+	}
+
+	for i, move := range plan.moves {
+		if i >= available {
+			report.deferred = len(plan.moves) - i
+			r.logger.Debugf("Maximum number of concurrent DB migrations (%d) reached", r.options.MaxConcurrentMigrations)
+			break
+		}
+
+		operation, err := r.requestMove(move, r.logger)
+		if err != nil {
+			report.failed++
+			r.logger.WithError(err).Errorf("Failed to request DB migration of installation %s", move.installation.ID)
+			continue
+		}
+		report.requested++
+		r.logger.WithField("dbMigrationOperation", operation.ID).
+			Infof("Requested DB migration of installation %s from multitenant database %s to %s", operation.InstallationID, move.source.database.ID, move.destination.database.ID)
+	}
+
+	return nil
+}
+
+// requestMove triggers the DB migration of a planned move after making sure
+// that the installation is still ready for it.
+func (r *MultitenantDatabaseRebalancer) requestMove(move *databaseRebalanceMove, logger log.FieldLogger) (*model.InstallationDBMigrationOperation, error) {
+	lock := newInstallationLock(move.installation.ID, r.instanceID, r.store, logger)
+	if !lock.TryLock() {
+		return nil, errors.New("failed to lock installation")
+	}
+	defer lock.Unlock()
+
+	installation, err := r.store.GetInstallation(move.installation.ID, false, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get installation")
+	}
+	if installation == nil || !installationCanMoveDatabase(installation) {
+		return nil, errors.New("installation is no longer ready for a DB migration")
+	}
+
+	return r.store.TriggerInstallationDBMigration(&model.InstallationDBMigrationOperation{
+		InstallationID:         installation.ID,
+		SourceDatabase:         installation.Database,
+		DestinationDatabase:    model.InstallationDatabaseMultiTenantRDSPostgres,
+		SourceMultiTenant:      &model.MultiTenantDBMigrationData{DatabaseID: move.source.database.ID},
+		DestinationMultiTenant: &model.MultiTenantDBMigrationData{DatabaseID: move.destination.database.ID},
+	}, installation)
+}
+
+// installationCanMoveDatabase returns true if DB migrations can be requested
+// for the installation.
+func installationCanMoveDatabase(installation *model.Installation) bool {
+	return installation.State == model.InstallationStateStable &&
+		installation.Database == model.InstallationDatabaseMultiTenantRDSPostgres &&
+		!installation.APISecurityLock
+}
+
+// databaseLoad holds the projected weight of a multitenant database while
+// planning moves.
+type databaseLoad struct {
+	database      *model.MultitenantDatabase
+	installations []*model.Installation
+	weight        float64
+	plannedWeight float64
+}
+
+// databaseRebalanceMove is a proposed move of a single installation to another
+// multitenant database.
+type databaseRebalanceMove struct {
+	installation *model.Installation
+	source       *databaseLoad
+	destination  *databaseLoad
+}
+
+// databaseRebalancePlan is the result of planning a rebalancing run.
+type databaseRebalancePlan struct {
+	loads []*databaseLoad
+	moves []*databaseRebalanceMove
+}
+
+// databaseRebalanceReport summarizes what happened to the moves of a plan.
+type databaseRebalanceReport struct {
+	proposed  int
+	requested int
+	deferred  int
+	failed    int
+}
+
+func (report *databaseRebalanceReport) log(plan *databaseRebalancePlan, executed bool, logger log.FieldLogger) {
+	for _, load := range plan.loads {
+		if load.weight == load.plannedWeight {
+			continue
+		}
+		logger.Infof("Multitenant database %s in VPC %s: weight %.2f -> %.2f",
+			load.database.ID, load.database.VpcID, load.weight, load.plannedWeight)
+	}
+
+	if !executed {
+		logger.Infof("Multitenant database rebalancing summary: %d moves proposed; execution is disabled", report.proposed)
+		return
+	}
+	logger.Infof("Multitenant database rebalancing summary: %d moves proposed, %d requested, %d deferred, %d failed",
+		report.proposed, report.requested, report.deferred, report.failed)
+}
+
+// planRebalance returns the installation moves needed to bring the weight of
+// each multitenant database close to the average weight of the databases in
+// its VPC. Databases and installations already taking part in DB migrations
+// are not considered.
+func (r *MultitenantDatabaseRebalancer) planRebalance(pendingOperations []*model.InstallationDBMigrationOperation, logger log.FieldLogger) (*databaseRebalancePlan, error) {
+	busyDatabases := map[string]bool{}
+	busyInstallations := map[string]bool{}
+	for _, operation := range pendingOperations {
+		if operation.SourceMultiTenant != nil {
+			busyDatabases[operation.SourceMultiTenant.DatabaseID] = true
+		}
+		if operation.DestinationMultiTenant != nil {
+			busyDatabases[operation.DestinationMultiTenant.DatabaseID] = true
+		}
+		busyInstallations[operation.InstallationID] = true
+	}
+
+	databases, err := r.store.GetMultitenantDatabases(&model.MultitenantDatabaseFilter{
+		DatabaseType:          model.DatabaseEngineTypePostgres,
+		MaxInstallationsLimit: model.NoInstallationsLimit,
+		Paging:                model.AllPagesNotDeleted(),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get multitenant databases")
+	}
+
+	vpcLoads := map[string][]*databaseLoad{}
+	var vpcIDs []string
+	for _, database := range databases {
+		if database.State != model.DatabaseStateStable || busyDatabases[database.ID] {
+			continue
+		}
+		load := &databaseLoad{database: database}
+		for _, installationID := range database.Installations {
+			installation, err := r.store.GetInstallation(installationID, false, false)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to get installation %s", installationID)
+			}
+			if installation == nil {
+				continue
+			}
+			load.weight += installation.GetDatabaseWeight()
+			load.installations = append(load.installations, installation)
+		}
+		load.plannedWeight = load.weight
+
+		if _, ok := vpcLoads[database.VpcID]; !ok {
+			vpcIDs = append(vpcIDs, database.VpcID)
+		}
+		vpcLoads[database.VpcID] = append(vpcLoads[database.VpcID], load)
+	}
+
+	plan := &databaseRebalancePlan{}
+	capacity := float64(aws.DefaultRDSMultitenantDatabasePostgresCountLimit)
+	tolerance := capacity * float64(r.options.Tolerance) / 100
+
+	for _, vpcID := range vpcIDs {
+		loads := vpcLoads[vpcID]
+		plan.loads = append(plan.loads, loads...)
+		if len(loads) < 2 {
+			continue
+		}
+
+		var totalWeight float64
+		for _, load := range loads {
+			totalWeight += load.weight
+		}
+		average := totalWeight / float64(len(loads))
+		upperBound := average + tolerance
+
+		// Handle the most loaded databases first.
+		sort.SliceStable(loads, func(i, j int) bool {
+			return loads[i].weight > loads[j].weight
+		})
+
+		for _, source := range loads {
+			if source.plannedWeight <= upperBound {
+				continue
+			}
+			logger.Infof("Multitenant database %s is over the average weight of VPC %s (%.2f) by more than %d%% of its capacity: weight=%.2f",
+				source.database.ID, vpcID, average, r.options.Tolerance, source.weight)
+
+			// Move the largest installations first to keep the number of
+			// moves low.
+			candidates := make([]*model.Installation, len(source.installations))
+			copy(candidates, source.installations)
+			sort.SliceStable(candidates, func(i, j int) bool {
+				return candidates[i].GetDatabaseWeight() > candidates[j].GetDatabaseWeight()
+			})
+
+			for _, candidate := range candidates {
+				if source.plannedWeight <= upperBound {
+					break
+				}
+				if busyInstallations[candidate.ID] || !installationCanMoveDatabase(candidate) {
+					continue
+				}
+				weight := candidate.GetDatabaseWeight()
+
+				var destination *databaseLoad
+				for _, potentialDestination := range loads {
+					if potentialDestination == source ||
+						potentialDestination.database.MigratedInstallations.Contains(candidate.ID) {
+						continue
+					}
+					// A move must not overload the destination or make it
+					// more loaded than the source was.
+					projected := potentialDestination.plannedWeight + weight
+					if projected > upperBound || projected >= capacity || projected >= source.plannedWeight {
+						continue
+					}
+					if destination != nil && destination.plannedWeight <= potentialDestination.plannedWeight {
+						continue
+					}
+					destination = potentialDestination
+				}
+				if destination == nil {
+					logger.Debugf("No multitenant database can accept installation %s", candidate.ID)
+					continue
+				}
+
+				source.plannedWeight -= weight
+				destination.plannedWeight += weight
+				busyInstallations[candidate.ID] = true
+
+				plan.moves = append(plan.moves, &databaseRebalanceMove{
+					installation: candidate,
+					source:       source,
+					destination:  destination,
+				})
+			}
+
+			if source.plannedWeight > upperBound {
+				logger.Warnf("Multitenant database %s will remain over the average weight of VPC %s after rebalancing", source.database.ID, vpcID)
+			}
+		}
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultitenantDatabaseRebalancer(t *testing.T) {
+	setup := func(t *testing.T, sqlStore *store.SQLStore) (*model.MultitenantDatabase, *model.MultitenantDatabase) {
+		var installationIDs []string
+		for i := 0; i < 4; i++ {
+			installation := &model.Installation{
+				OwnerID:   model.NewID(),
+				DNS:       model.NewID() + ".example.com",
+				Affinity:  model.InstallationAffinityMultiTenant,
+				Database:  model.InstallationDatabaseMultiTenantRDSPostgres,
+				Filestore: model.InstallationFilestoreBifrost,
+				State:     model.InstallationStateStable,
+			}
+			err := sqlStore.CreateInstallation(installation, nil)
+			require.NoError(t, err)
+			installationIDs = append(installationIDs, installation.ID)
+		}
+
+		overloadedDatabase := &model.MultitenantDatabase{
+			RdsClusterID:  "rds-cluster-multitenant-1",
+			VpcID:         "vpc-1",
+			DatabaseType:  model.DatabaseEngineTypePostgres,
+			State:         model.DatabaseStateStable,
+			Installations: installationIDs,
+		}
+		err := sqlStore.CreateMultitenantDatabase(overloadedDatabase)
+		require.NoError(t, err)
+
+		emptyDatabase := &model.MultitenantDatabase{
+			RdsClusterID: "rds-cluster-multitenant-2",
+			VpcID:        "vpc-1",
+			DatabaseType: model.DatabaseEngineTypePostgres,
+			State:        model.DatabaseStateStable,
+		}
+		err = sqlStore.CreateMultitenantDatabase(emptyDatabase)
+		require.NoError(t, err)
+
+		// Databases in other VPCs are never used as destinations.
+		otherVPCDatabase := &model.MultitenantDatabase{
+			RdsClusterID: "rds-cluster-multitenant-3",
+			VpcID:        "vpc-2",
+			DatabaseType: model.DatabaseEngineTypePostgres,
+			State:        model.DatabaseStateStable,
+		}
+		err = sqlStore.CreateMultitenantDatabase(otherVPCDatabase)
+		require.NoError(t, err)
+
+		return overloadedDatabase, emptyDatabase
+	}
+
+	getOperations := func(t *testing.T, sqlStore *store.SQLStore) []*model.InstallationDBMigrationOperation {
+		operations, err := sqlStore.GetInstallationDBMigrationOperations(&model.InstallationDBMigrationFilter{
+			Paging: model.AllPagesNotDeleted(),
+		})
+		require.NoError(t, err)
+		return operations
+	}
+
+	t.Run("only propose moves", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		setup(t, sqlStore)
+
+		rebalancer := supervisor.NewMultitenantDatabaseRebalancer(sqlStore, supervisor.MultitenantDatabaseRebalancerOptions{
+			MaxConcurrentMigrations: 5,
+		}, "instance-id", logger)
+		err := rebalancer.Do()
+		require.NoError(t, err)
+
+		assert.Empty(t, getOperations(t, sqlStore))
+	})
+
+	t.Run("execute moves", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		overloadedDatabase, emptyDatabase := setup(t, sqlStore)
+
+		rebalancer := supervisor.NewMultitenantDatabaseRebalancer(sqlStore, supervisor.MultitenantDatabaseRebalancerOptions{
+			Execute:                 true,
+			MaxConcurrentMigrations: 5,
+		}, "instance-id", logger)
+		err := rebalancer.Do()
+		require.NoError(t, err)
+
+		operations := getOperations(t, sqlStore)
+		require.Len(t, operations, 2)
+		for _, operation := range operations {
+			assert.Equal(t, model.InstallationDBMigrationStateRequested, operation.State)
+			assert.Equal(t, model.InstallationDatabaseMultiTenantRDSPostgres, operation.SourceDatabase)
+			assert.Equal(t, model.InstallationDatabaseMultiTenantRDSPostgres, operation.DestinationDatabase)
+			assert.Equal(t, overloadedDatabase.ID, operation.SourceMultiTenant.DatabaseID)
+			assert.Equal(t, emptyDatabase.ID, operation.DestinationMultiTenant.DatabaseID)
+
+			installation, err := sqlStore.GetInstallation(operation.InstallationID, false, false)
+			require.NoError(t, err)
+			assert.Equal(t, model.InstallationStateDBMigrationInProgress, installation.State)
+		}
+
+		t.Run("databases with pending migrations are skipped", func(t *testing.T) {
+			err = rebalancer.Do()
+			require.NoError(t, err)
+			assert.Len(t, getOperations(t, sqlStore), 2)
+		})
+	})
+
+	t.Run("tolerance not exceeded", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		setup(t, sqlStore)
+
+		rebalancer := supervisor.NewMultitenantDatabaseRebalancer(sqlStore, supervisor.MultitenantDatabaseRebalancerOptions{
+			Execute:                 true,
+			Tolerance:               1,
+			MaxConcurrentMigrations: 5,
+		}, "instance-id", logger)
+		err := rebalancer.Do()
+		require.NoError(t, err)
+
+		assert.Empty(t, getOperations(t, sqlStore))
+	})
+
+	t.Run("max concurrent migrations", func(t *testing.T) {
+		logger := testlib.MakeLogger(t)
+		sqlStore := store.MakeTestSQLStore(t, logger)
+		defer store.CloseConnection(t, sqlStore)
+
+		setup(t, sqlStore)
+
+		rebalancer := supervisor.NewMultitenantDatabaseRebalancer(sqlStore, supervisor.MultitenantDatabaseRebalancerOptions{
+			Execute:                 true,
+			MaxConcurrentMigrations: 1,
+		}, "instance-id", logger)
+		err := rebalancer.Do()
+		require.NoError(t, err)
+
+		assert.Len(t, getOperations(t, sqlStore), 1)
+	})
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/model"
+	log "github.com/sirupsen/logrus"
+)
+
+// multitenantDatabaseUpgradeStore abstracts the database operations required
+// by the multitenant database upgrade supervisor.
+type multitenantDatabaseUpgradeStore interface {
+	GetMultitenantDatabases(filter *model.MultitenantDatabaseFilter) ([]*model.MultitenantDatabase, error)
+	GetMultitenantDatabase(multitenantDatabaseID string) (*model.MultitenantDatabase, error)
+	UpdateMultitenantDatabase(multitenantDatabase *model.MultitenantDatabase) error
+	multitenantDatabaseLockStore
+}
+
+// multitenantDatabaseUpgrader performs the engine version upgrade of the RDS
+// cluster of a multitenant database.
+type multitenantDatabaseUpgrader interface {
+	SnapshotMultitenantDatabase(database *model.MultitenantDatabase, snapshotID string, logger log.FieldLogger) error
+	IsMultitenantDatabaseSnapshotAvailable(snapshotID string) (bool, error)
+	UpgradeMultitenantDatabaseEngine(database *model.MultitenantDatabase, logger log.FieldLogger) error
+	IsMultitenantDatabaseEngineUpgraded(database *model.MultitenantDatabase) (bool, error)
+}
+
+// MultitenantDatabaseUpgradeSupervisor finds multitenant databases with a
+// requested engine version upgrade and effects the required changes.
+//
+// The RDS cluster is snapshotted before it is upgraded in place. New
+// installations are not assigned to a database while it is being upgraded.
+type MultitenantDatabaseUpgradeSupervisor struct {
+	store      multitenantDatabaseUpgradeStore
+	upgrader   multitenantDatabaseUpgrader
+	instanceID string
+	logger     log.FieldLogger
+}
+
+// NewMultitenantDatabaseUpgradeSupervisor creates a new
+// MultitenantDatabaseUpgradeSupervisor.
+func NewMultitenantDatabaseUpgradeSupervisor(store multitenantDatabaseUpgradeStore, upgrader multitenantDatabaseUpgrader, instanceID string, logger log.FieldLogger) *MultitenantDatabaseUpgradeSupervisor {
+	return &MultitenantDatabaseUpgradeSupervisor{
+		store:      store,
+		upgrader:   upgrader,
+		instanceID: instanceID,
+		logger:     logger.WithField("supervisor", "multitenant-database-upgrade"),
+	}
+}
+
+// Shutdown performs graceful shutdown tasks for the multitenant database
+// upgrade supervisor.
+func (s *MultitenantDatabaseUpgradeSupervisor) Shutdown() {
+	s.logger.Debug("Shutting down multitenant database upgrade supervisor")
+}
+
+// Do looks for work to be done on any pending multitenant database upgrades
+// and attempts to schedule the required work.
+func (s *MultitenantDatabaseUpgradeSupervisor) Do() error {
+	databases, err := s.store.GetMultitenantDatabases(&model.MultitenantDatabaseFilter{
+		States:                model.AllMultitenantDatabaseUpgradeStatesPendingWork,
+		MaxInstallationsLimit: model.NoInstallationsLimit,
+		Paging:                model.AllPagesNotDeleted(),
+	})
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to query for multitenant database upgrades pending work")
+		return nil
+	}
+
+	for _, database := range databases {
+		s.Supervise(database)
+	}
+
+	return nil
+}
+
+// Supervise schedules the required work on the given multitenant database.
+func (s *MultitenantDatabaseUpgradeSupervisor) Supervise(database *model.MultitenantDatabase) {
+	logger := s.logger.WithFields(log.Fields{
+		"multitenant-database": database.ID,
+		"rds-cluster-id":       database.RdsClusterID,
+	})
+
+	lock := newMultitenantDatabaseLock(database.ID, s.instanceID, s.store, logger)
+	if !lock.TryLock() {
+		return
+	}
+	defer lock.Unlock()
+
+	// Before working on the database, it is crucial that we ensure that it
+	// was not updated to a new state by another provisioning server.
+	originalState := database.State
+	database, err := s.store.GetMultitenantDatabase(database.ID)
+	if err != nil {
+		logger.WithError(err).Error("Failed to get refreshed multitenant database")
+		return
+	}
+	if database.State != originalState {
+		logger.WithField("oldDatabaseState", originalState).
+			WithField("newDatabaseState", database.State).
+			Warn("Another provisioner has worked on this multitenant database; skipping...")
+		return
+	}
+
+	logger.Debugf("Supervising multitenant database in state %s", database.State)
+
+	newState := s.transitionMultitenantDatabase(database, logger)
+	if database.State == newState {
+		return
+	}
+
+	oldState := database.State
+	database.State = newState
+
+	err = s.store.UpdateMultitenantDatabase(database)
+	if err != nil {
+		logger.WithError(err).Errorf("Failed to set multitenant database state to %s", newState)
+		return
+	}
+
+	logger.Debugf("Transitioned multitenant database from %s to %s", oldState, database.State)
+}
+
+// transitionMultitenantDatabase works with the given multitenant database to
+// transition it to a final state.
+func (s *MultitenantDatabaseUpgradeSupervisor) transitionMultitenantDatabase(database *model.MultitenantDatabase, logger log.FieldLogger) string {
+	switch database.State {
+	case model.DatabaseStateUpgradeRequested:
+		return s.snapshotDatabase(database, logger)
+	case model.DatabaseStateUpgradeSnapshotInProgress:
+		return s.upgradeDatabase(database, logger)
+	case model.DatabaseStateUpgradeInProgress:
+		return s.waitForDatabaseUpgrade(database, logger)
+	default:
+		logger.Warnf("Found multitenant database pending work in unexpected state %s", database.State)
+		return database.State
+	}
+}
+
+func (s *MultitenantDatabaseUpgradeSupervisor) snapshotDatabase(database *model.MultitenantDatabase, logger log.FieldLogger) string {
+	// The snapshot ID is stored before the snapshot is created so that a
+	// retry doesn't create a second snapshot.
+	if len(database.UpgradeSnapshotID) == 0 {
+		database.UpgradeSnapshotID = aws.RDSMultitenantUpgradeSnapshotID(database.RdsClusterID, model.GetMillis())
+		err := s.store.UpdateMultitenantDatabase(database)
+		if err != nil {
+			logger.WithError(err).Error("Failed to store upgrade snapshot ID")
+			return database.State
+		}
+	}
+
+	err := s.upgrader.SnapshotMultitenantDatabase(database, database.UpgradeSnapshotID, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to snapshot multitenant database")
+		return database.State
+	}
+
+	return model.DatabaseStateUpgradeSnapshotInProgress
+}
+
+func (s *MultitenantDatabaseUpgradeSupervisor) upgradeDatabase(database *model.MultitenantDatabase, logger log.FieldLogger) string {
+	available, err := s.upgrader.IsMultitenantDatabaseSnapshotAvailable(database.UpgradeSnapshotID)
+	if err != nil {
+		logger.WithError(err).Error("Multitenant database upgrade snapshot failed")
+		return model.DatabaseStateUpgradeFailed
+	}
+	if !available {
+		logger.Debug("Multitenant database upgrade snapshot is not available yet")
+		return database.State
+	}
+
+	err = s.upgrader.UpgradeMultitenantDatabaseEngine(database, logger)
+	if err != nil {
+		logger.WithError(err).Error("Failed to upgrade multitenant database engine")
+		return model.DatabaseStateUpgradeFailed
+	}
+
+	return model.DatabaseStateUpgradeInProgress
+}
+
+func (s *MultitenantDatabaseUpgradeSupervisor) waitForDatabaseUpgrade(database *model.MultitenantDatabase, logger log.FieldLogger) string {
+	upgraded, err := s.upgrader.IsMultitenantDatabaseEngineUpgraded(database)
+	if err != nil {
+		logger.WithError(err).Error("Failed to check multitenant database engine upgrade")
+		return database.State
+	}
+	if !upgraded {
+		logger.Debug("Multitenant database engine upgrade is still in progress")
+		return database.State
+	}
+
+	logger.WithField("engine-version", database.TargetEngineVersion).Info("Multitenant database engine upgraded")
+
+	return model.DatabaseStateStable
+}
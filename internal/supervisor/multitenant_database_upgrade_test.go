@@ -0,0 +1,157 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/internal/store"
+	"github.com/mattermost/mattermost-cloud/internal/supervisor"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockMultitenantDatabaseUpgrader struct {
+	snapshots         []string
+	snapshotAvailable bool
+	snapshotErr       error
+	upgrades          []string
+	upgradeErr        error
+	upgraded          bool
+}
+
+func (u *mockMultitenantDatabaseUpgrader) SnapshotMultitenantDatabase(database *model.MultitenantDatabase, snapshotID string, logger log.FieldLogger) error {
+	u.snapshots = append(u.snapshots, snapshotID)
+	return nil
+}
+
+func (u *mockMultitenantDatabaseUpgrader) IsMultitenantDatabaseSnapshotAvailable(snapshotID string) (bool, error) {
+	return u.snapshotAvailable, u.snapshotErr
+}
+
+func (u *mockMultitenantDatabaseUpgrader) UpgradeMultitenantDatabaseEngine(database *model.MultitenantDatabase, logger log.FieldLogger) error {
+	u.upgrades = append(u.upgrades, database.TargetEngineVersion)
+	return u.upgradeErr
+}
+
+func (u *mockMultitenantDatabaseUpgrader) IsMultitenantDatabaseEngineUpgraded(database *model.MultitenantDatabase) (bool, error) {
+	return u.upgraded, nil
+}
+
+func TestMultitenantDatabaseUpgradeSupervisor(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+
+	setup := func(t *testing.T) (*store.SQLStore, *model.MultitenantDatabase, *model.MultitenantDatabase) {
+		sqlStore := store.MakeTestSQLStore(t, logger)
+
+		database := &model.MultitenantDatabase{
+			RdsClusterID:        "rds-cluster-multitenant-1",
+			DatabaseType:        model.DatabaseEngineTypePostgres,
+			State:               model.DatabaseStateUpgradeRequested,
+			TargetEngineVersion: "13.7",
+		}
+		err := sqlStore.CreateMultitenantDatabase(database)
+		require.NoError(t, err)
+
+		stableDatabase := &model.MultitenantDatabase{
+			RdsClusterID: "rds-cluster-multitenant-2",
+			DatabaseType: model.DatabaseEngineTypePostgres,
+			State:        model.DatabaseStateStable,
+		}
+		err = sqlStore.CreateMultitenantDatabase(stableDatabase)
+		require.NoError(t, err)
+
+		return sqlStore, database, stableDatabase
+	}
+
+	expectDatabase := func(t *testing.T, sqlStore *store.SQLStore, database *model.MultitenantDatabase, state string) *model.MultitenantDatabase {
+		t.Helper()
+		database, err := sqlStore.GetMultitenantDatabase(database.ID)
+		require.NoError(t, err)
+		assert.Equal(t, state, database.State)
+		assert.Nil(t, database.LockAcquiredBy)
+		return database
+	}
+
+	t.Run("upgrade succeeds", func(t *testing.T) {
+		sqlStore, database, stableDatabase := setup(t)
+		defer store.CloseConnection(t, sqlStore)
+
+		upgrader := &mockMultitenantDatabaseUpgrader{}
+		upgradeSupervisor := supervisor.NewMultitenantDatabaseUpgradeSupervisor(sqlStore, upgrader, "instanceID", logger)
+
+		err := upgradeSupervisor.Do()
+		require.NoError(t, err)
+		database = expectDatabase(t, sqlStore, database, model.DatabaseStateUpgradeSnapshotInProgress)
+		assert.NotEmpty(t, database.UpgradeSnapshotID)
+		assert.Equal(t, []string{database.UpgradeSnapshotID}, upgrader.snapshots)
+
+		err = upgradeSupervisor.Do()
+		require.NoError(t, err)
+		expectDatabase(t, sqlStore, database, model.DatabaseStateUpgradeSnapshotInProgress)
+		assert.Empty(t, upgrader.upgrades)
+
+		upgrader.snapshotAvailable = true
+		err = upgradeSupervisor.Do()
+		require.NoError(t, err)
+		expectDatabase(t, sqlStore, database, model.DatabaseStateUpgradeInProgress)
+		assert.Equal(t, []string{"13.7"}, upgrader.upgrades)
+
+		err = upgradeSupervisor.Do()
+		require.NoError(t, err)
+		expectDatabase(t, sqlStore, database, model.DatabaseStateUpgradeInProgress)
+
+		upgrader.upgraded = true
+		err = upgradeSupervisor.Do()
+		require.NoError(t, err)
+		database = expectDatabase(t, sqlStore, database, model.DatabaseStateStable)
+		assert.Equal(t, "13.7", database.TargetEngineVersion)
+
+		expectDatabase(t, sqlStore, stableDatabase, model.DatabaseStateStable)
+		assert.Len(t, upgrader.snapshots, 1)
+	})
+
+	t.Run("snapshot fails", func(t *testing.T) {
+		sqlStore, database, _ := setup(t)
+		defer store.CloseConnection(t, sqlStore)
+
+		upgrader := &mockMultitenantDatabaseUpgrader{snapshotErr: errors.New("snapshot failed")}
+		upgradeSupervisor := supervisor.NewMultitenantDatabaseUpgradeSupervisor(sqlStore, upgrader, "instanceID", logger)
+
+		err := upgradeSupervisor.Do()
+		require.NoError(t, err)
+		err = upgradeSupervisor.Do()
+		require.NoError(t, err)
+		expectDatabase(t, sqlStore, database, model.DatabaseStateUpgradeFailed)
+		assert.Empty(t, upgrader.upgrades)
+	})
+
+	t.Run("upgrade fails", func(t *testing.T) {
+		sqlStore, database, _ := setup(t)
+		defer store.CloseConnection(t, sqlStore)
+
+		upgrader := &mockMultitenantDatabaseUpgrader{
+			snapshotAvailable: true,
+			upgradeErr:        errors.New("invalid upgrade target"),
+		}
+		upgradeSupervisor := supervisor.NewMultitenantDatabaseUpgradeSupervisor(sqlStore, upgrader, "instanceID", logger)
+
+		err := upgradeSupervisor.Do()
+		require.NoError(t, err)
+		err = upgradeSupervisor.Do()
+		require.NoError(t, err)
+		expectDatabase(t, sqlStore, database, model.DatabaseStateUpgradeFailed)
+
+		// Failed upgrades are not retried until requested again.
+		err = upgradeSupervisor.Do()
+		require.NoError(t, err)
+		expectDatabase(t, sqlStore, database, model.DatabaseStateUpgradeFailed)
+		assert.Len(t, upgrader.upgrades, 1)
+	})
+}
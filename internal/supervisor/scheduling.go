@@ -0,0 +1,171 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	"strings"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// installationSchedulingStore abstracts the database operations required to
+// check if an installation can be scheduled on a cluster.
+type installationSchedulingStore interface {
+	GetInstallation(installationID string, includeGroupConfig, includeGroupConfigOverrides bool) (*model.Installation, error)
+	GetInstallations(installationFilter *model.InstallationFilter, includeGroupConfig, includeGroupConfigOverrides bool) ([]*model.Installation, error)
+	GetClusterInstallations(*model.ClusterInstallationFilter) ([]*model.ClusterInstallation, error)
+	GetAnnotationsForCluster(clusterID string) ([]*model.Annotation, error)
+}
+
+// installationCanBeScheduledOnCluster checks if the given installation can be
+// scheduled on the given cluster in regards to configuration and state. This
+// does not include resource checks.
+func installationCanBeScheduledOnCluster(store installationSchedulingStore, cluster *model.Cluster, installation *model.Installation, logger log.FieldLogger) bool {
+	if cluster.State != model.ClusterStateStable {
+		logger.Debugf("Cluster %s is not stable (currently %s)", cluster.ID, cluster.State)
+		return false
+	}
+	if !cluster.AllowInstallations {
+		logger.Debugf("Cluster %s is set to not allow for new installation scheduling", cluster.ID)
+		return false
+	}
+	// EKS clusters are only provisioned with a connection check and miss the
+	// utilities needed to run installations.
+	if cluster.IsEKS() {
+		logger.Debugf("Cluster %s is an EKS cluster which cannot host installations", cluster.ID)
+		return false
+	}
+
+	if !installation.Placement.ClusterHasNodeGroup(cluster) {
+		logger.Debugf("Cluster %s is missing node group %s required by installation placement rules", cluster.ID, installation.Placement.GetNodeGroup())
+		return false
+	}
+
+	if installation.Placement != nil && len(installation.Placement.RequiredClusterAnnotations) > 0 {
+		clusterAnnotations, err := store.GetAnnotationsForCluster(cluster.ID)
+		if err != nil {
+			logger.WithError(err).Error("Failed to get cluster annotations")
+			return false
+		}
+		if !installation.Placement.HasRequiredClusterAnnotations(clusterAnnotations) {
+			logger.Debugf("Cluster %s is missing annotations required by installation placement rules: [%s]", cluster.ID, strings.Join(installation.Placement.RequiredClusterAnnotations, ", "))
+			return false
+		}
+	}
+
+	existingClusterInstallations, err := store.GetClusterInstallations(&model.ClusterInstallationFilter{
+		Paging:    model.AllPagesNotDeleted(),
+		ClusterID: cluster.ID,
+	})
+	if err != nil {
+		logger.WithError(err).Error("Failed to get existing cluster installations")
+		return false
+	}
+
+	////////////////////////////////////////////////////////////////////////////
+	//                              MULTI-TENANCY                             //
+	////////////////////////////////////////////////////////////////////////////
+	// Current model:                                                         //
+	// - isolation=true  | 1 cluster installations                            //
+	// - isolation=false | X cluster installations, where "X" is as many as   //
+	//                     will fit with the given CPU and Memory threshold.  //
+	////////////////////////////////////////////////////////////////////////////
+	if installation.Affinity == model.InstallationAffinityIsolated {
+		if len(existingClusterInstallations) > 0 {
+			logger.Debugf("Cluster %s already has %d installations", cluster.ID, len(existingClusterInstallations))
+			return false
+		}
+	} else {
+		if len(existingClusterInstallations) == 1 {
+			// This should be the only scenario where we need to check if the
+			// cluster installation running requires isolation or not.
+			installation, err := store.GetInstallation(existingClusterInstallations[0].InstallationID, true, false)
+			if err != nil {
+				logger.WithError(err).Error("Failed to get existing installation")
+				return false
+			}
+			if installation.Affinity == model.InstallationAffinityIsolated {
+				logger.Debugf("Cluster %s already has an isolated installation %s", cluster.ID, installation.ID)
+				return false
+			}
+		}
+	}
+
+	if len(existingClusterInstallations) > 0 {
+		conflictingInstallationID, err := findOwnerAntiAffinityConflict(store, installation, existingClusterInstallations)
+		if err != nil {
+			logger.WithError(err).Error("Failed to check owner anti-affinity")
+			return false
+		}
+		if conflictingInstallationID != "" {
+			logger.Debugf("Cluster %s has installation %s which conflicts with owner anti-affinity rules", cluster.ID, conflictingInstallationID)
+			return false
+		}
+	}
+
+	return true
+}
+
+// findOwnerAntiAffinityConflict returns the ID of the first installation of the
+// given cluster installations that cannot share a cluster with the provided
+// installation because of owner anti-affinity placement rules of either of
+// them. An empty string is returned if there are no conflicts.
+func findOwnerAntiAffinityConflict(store installationSchedulingStore, installation *model.Installation, clusterInstallations []*model.ClusterInstallation) (string, error) {
+	installationIDs := make([]string, 0, len(clusterInstallations))
+	for _, clusterInstallation := range clusterInstallations {
+		installationIDs = append(installationIDs, clusterInstallation.InstallationID)
+	}
+
+	existingInstallations, err := store.GetInstallations(&model.InstallationFilter{
+		InstallationIDs: installationIDs,
+		Paging:          model.AllPagesNotDeleted(),
+	}, false, false)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get existing installations")
+	}
+
+	return findOwnerAntiAffinityConflictWithInstallations(installation, existingInstallations), nil
+}
+
+// findOwnerAntiAffinityConflictWithInstallations returns the ID of the first
+// of the given installations that cannot share a cluster with the provided
+// installation because of owner anti-affinity placement rules of either of
+// them. An empty string is returned if there are no conflicts.
+func findOwnerAntiAffinityConflictWithInstallations(installation *model.Installation, installations []*model.Installation) string {
+	for _, existing := range installations {
+		if existing.ID == installation.ID {
+			continue
+		}
+		if installation.Placement.ConflictsWithOwner(installation.OwnerID, existing.OwnerID) ||
+			existing.Placement.ConflictsWithOwner(existing.OwnerID, installation.OwnerID) {
+			return existing.ID
+		}
+	}
+
+	return ""
+}
+
+// installationResourceRequirements returns the CPU and memory requirements of
+// the given installation in milli units.
+func installationResourceRequirements(installation *model.Installation) (int64, int64, error) {
+	size, err := mmv1alpha1.GetClusterSize(installation.Size)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "invalid cluster installation size")
+	}
+
+	cpu := size.CalculateCPUMilliRequirement(
+		installation.InternalDatabase(),
+		installation.InternalFilestore(),
+	)
+	memory := size.CalculateMemoryMilliRequirement(
+		installation.InternalDatabase(),
+		installation.InternalFilestore(),
+	)
+
+	return cpu, memory, nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package supervisor
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+type supervisorTaskLockStore interface {
+	LockSupervisorTask(id, lockerID string) (bool, error)
+	UnlockSupervisorTask(id, lockerID string, force bool) (bool, error)
+}
+
+type supervisorTaskLock struct {
+	id       string
+	lockerID string
+	store    supervisorTaskLockStore
+	logger   log.FieldLogger
+}
+
+func newSupervisorTaskLock(id, lockerID string, store supervisorTaskLockStore, logger log.FieldLogger) *supervisorTaskLock {
+	return &supervisorTaskLock{
+		id:       id,
+		lockerID: lockerID,
+		store:    store,
+		logger:   logger,
+	}
+}
+
+func (l *supervisorTaskLock) TryLock() bool {
+	locked, err := l.store.LockSupervisorTask(l.id, l.lockerID)
+	if err != nil {
+		l.logger.WithError(err).Error("failed to lock supervisor task")
+		return false
+	}
+
+	return locked
+}
+
+func (l *supervisorTaskLock) Unlock() {
+	unlocked, err := l.store.UnlockSupervisorTask(l.id, l.lockerID, false)
+	if err != nil {
+		l.logger.WithError(err).Error("failed to unlock supervisor task")
+	} else if unlocked != true {
+		l.logger.Error("failed to release lock for supervisor task")
+	}
+}
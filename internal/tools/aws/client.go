@@ -7,6 +7,7 @@ package aws
 import (
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/applicationautoscaling"
 	"github.com/aws/aws-sdk-go/service/applicationautoscaling/applicationautoscalingiface"
@@ -19,6 +20,8 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/eks/eksiface"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/iam/iamiface"
 	"github.com/aws/aws-sdk-go/service/kms"
@@ -49,6 +52,7 @@ type AWS interface {
 
 	GetAndClaimVpcResources(clusterID, owner string, logger log.FieldLogger) (ClusterResources, error)
 	GetVpcResources(clusterID string, logger log.FieldLogger) (ClusterResources, error)
+	GetUtilityValuesContext(clusterID string, logger log.FieldLogger) (*model.UtilityValuesContext, error)
 	ReleaseVpc(clusterID string, logger log.FieldLogger) error
 	AttachPolicyToRole(roleName, policyName string, logger log.FieldLogger) error
 	DetachPolicyFromRole(roleName, policyName string, logger log.FieldLogger) error
@@ -67,6 +71,8 @@ type AWS interface {
 	TagResource(resourceID, key, value string, logger log.FieldLogger) error
 	UntagResource(resourceID, key, value string, logger log.FieldLogger) error
 	IsValidAMI(AMIImage string, logger log.FieldLogger) (bool, error)
+	IsValidInstanceType(instanceType string, logger log.FieldLogger) (bool, error)
+	GetSpotInstancesMarkedForTermination(instanceIDs []string, logger log.FieldLogger) ([]string, error)
 
 	DynamoDBEnsureTableDeleted(tableName string, logger log.FieldLogger) error
 	S3EnsureBucketDeleted(bucketName string, logger log.FieldLogger) error
@@ -82,6 +88,19 @@ type AWS interface {
 
 	SecretsManagerGetPGBouncerAuthUserPassword(vpcID string) (string, error)
 	SwitchClusterTags(clusterID string, targetClusterID string, logger log.FieldLogger) error
+
+	EnsureEKSCluster(config EKSClusterConfig, logger log.FieldLogger) (*eks.Cluster, error)
+	GetEKSCluster(name string) (*eks.Cluster, error)
+	WaitForEKSClusterActive(name string, timeout time.Duration) (*eks.Cluster, error)
+	UpdateEKSClusterVersion(name, version string, logger log.FieldLogger) error
+	EnsureEKSNodeGroup(clusterName string, config EKSNodeGroupConfig, logger log.FieldLogger) (*eks.Nodegroup, error)
+	GetEKSNodeGroup(clusterName, nodeGroupName string) (*eks.Nodegroup, error)
+	WaitForEKSNodeGroupActive(clusterName, nodeGroupName string, timeout time.Duration) (*eks.Nodegroup, error)
+	UpdateEKSNodeGroupVersion(clusterName, nodeGroupName, version string, logger log.FieldLogger) error
+	UpdateEKSNodeGroupScaling(clusterName, nodeGroupName string, minCount, maxCount int64, logger log.FieldLogger) error
+	EnsureEKSNodeGroupDeleted(clusterName, nodeGroupName string, timeout time.Duration, logger log.FieldLogger) error
+	EnsureEKSClusterDeleted(name string, timeout time.Duration, logger log.FieldLogger) error
+	GetEKSClusterToken(name string) (string, error)
 }
 
 // Client is a client for interacting with AWS resources in a single AWS account.
@@ -92,6 +111,8 @@ type Client struct {
 	service *Service
 	config  *aws.Config
 	mux     *sync.Mutex
+
+	multitenantDatabaseAutoProvisioning *MultitenantDatabaseAutoProvisioning
 }
 
 // NewAWSClientWithConfig returns a new instance of Client with a custom configuration.
@@ -133,6 +154,7 @@ type Service struct {
 	dynamodb              dynamodbiface.DynamoDBAPI
 	sts                   stsiface.STSAPI
 	appAutoscaling        applicationautoscalingiface.ApplicationAutoScalingAPI
+	eks                   eksiface.EKSAPI
 }
 
 // NewService creates a new instance of Service.
@@ -150,6 +172,7 @@ func NewService(sess *session.Session) *Service {
 		dynamodb:              dynamodb.New(sess),
 		sts:                   sts.New(sess),
 		appAutoscaling:        applicationautoscaling.New(sess),
+		eks:                   eks.New(sess),
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/mattermost/mattermost-cloud/model"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -214,6 +215,39 @@ func (a *Client) GetVpcResources(clusterID string, logger log.FieldLogger) (Clus
 	return a.getClusterResourcesForVPC(*vpc.VpcId, *vpc.CidrBlock, logger)
 }
 
+// GetUtilityValuesContext returns the cluster context the utility values
+// templates of a cluster are rendered with.
+func (a *Client) GetUtilityValuesContext(clusterID string, logger log.FieldLogger) (*model.UtilityValuesContext, error) {
+	clusterResources, err := a.GetVpcResources(clusterID, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the VPC information")
+	}
+
+	privateDomain, err := a.GetPrivateZoneDomainName(logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the private domain")
+	}
+
+	certificate, err := a.GetCertificateSummaryByTag(DefaultInstallCertificatesTagKey, DefaultInstallCertificatesTagValue, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the installation certificate")
+	}
+
+	privateCertificate, err := a.GetCertificateSummaryByTag(DefaultInstallPrivateCertificatesTagKey, DefaultInstallPrivateCertificatesTagValue, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the private certificate")
+	}
+
+	return &model.UtilityValuesContext{
+		ClusterID:             clusterID,
+		VPCCIDR:               clusterResources.VpcCIDR,
+		PrivateDomain:         privateDomain,
+		Environment:           a.GetCloudEnvironmentName(),
+		CertificateARN:        aws.StringValue(certificate.CertificateArn),
+		PrivateCertificateARN: aws.StringValue(privateCertificate.CertificateArn),
+	}, nil
+}
+
 // ReleaseVpc changes the tags on a VPC to mark it as "available" again.
 func (a *Client) ReleaseVpc(clusterID string, logger log.FieldLogger) error {
 	return a.releaseVpc(clusterID, logger)
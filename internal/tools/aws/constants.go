@@ -42,6 +42,15 @@ const (
 	// there is no cluster running in the VPC.
 	VpcClusterIDTagValueNone = "none"
 
+	// SpotStatusMarkedForTermination is the spot instance request status code
+	// of spot instances which received an interruption notice and will be
+	// terminated.
+	SpotStatusMarkedForTermination = "marked-for-termination"
+
+	// SpotStatusMarkedForStop is the spot instance request status code of spot
+	// instances which received an interruption notice and will be stopped.
+	SpotStatusMarkedForStop = "marked-for-stop"
+
 	// DefaultDatabaseMySQLVersion is the default version of MySQL used when
 	// creating databases.
 	DefaultDatabaseMySQLVersion = "5.7"
@@ -206,9 +206,17 @@ func (d *RDSDatabase) GenerateDatabaseSecret(store model.InstallationDatabaseSto
 	return databaseSecret, nil
 }
 
-// MigrateOut migration is not supported for single tenant RDS.
+// MigrateOut keeps the single tenant RDS database in place when the installation
+// is migrated to a different database engine, so that the migration can be
+// rolled back. Other migrations are not supported for single tenant RDS.
 func (d *RDSDatabase) MigrateOut(store model.InstallationDatabaseStoreInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
-	return errors.New("database migration is not supported for single tenant RDS")
+	if !dbMigration.IsEngineMigration() {
+		return errors.New("database migration is not supported for single tenant RDS")
+	}
+
+	logger.Infof("Installation %s migrated out of single tenant RDS database", d.installationID)
+
+	return nil
 }
 
 // MigrateTo migration is not supported for single tenant RDS.
@@ -216,9 +224,16 @@ func (d *RDSDatabase) MigrateTo(store model.InstallationDatabaseStoreInterface,
 	return errors.New("database migration is not supported for single tenant RDS")
 }
 
-// TeardownMigrated tearing down migrated databases is not supported for single tenant RDS.
+// TeardownMigrated removes the single tenant RDS database the installation was
+// migrated out of to a different database engine.
 func (d *RDSDatabase) TeardownMigrated(store model.InstallationDatabaseStoreInterface, migrationOp *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
-	return errors.New("tearing down migrated installations is not supported for single tenant RDS")
+	if !migrationOp.IsEngineMigration() {
+		return errors.New("tearing down migrated installations is not supported for single tenant RDS")
+	}
+
+	logger.Info("Tearing down migrated single tenant RDS database")
+
+	return d.Teardown(store, false, logger)
 }
 
 // RollbackMigration rolling back migration is not supported for single tenant RDS.
@@ -226,6 +241,16 @@ func (d *RDSDatabase) RollbackMigration(store model.InstallationDatabaseStoreInt
 	return errors.New("rolling back db migration is not supported for single tenant RDS")
 }
 
+// RotateCredentials rotating credentials is not supported for single tenant RDS.
+func (d *RDSDatabase) RotateCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("rotating credentials is not supported for single tenant RDS")
+}
+
+// RevokePreviousCredentials revoking credentials is not supported for single tenant RDS.
+func (d *RDSDatabase) RevokePreviousCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("revoking credentials is not supported for single tenant RDS")
+}
+
 func (d *RDSDatabase) rdsDatabaseProvision(installationID string, logger log.FieldLogger) error {
 	awsID := CloudID(installationID)
 
@@ -325,7 +350,7 @@ func (d *RDSDatabase) rdsDatabaseProvision(installationID string, logger log.Fie
 		return errors.Wrapf(err, "failed to convert database type to database engine")
 	}
 
-	err = d.client.rdsEnsureDBClusterCreated(awsID, *vpcs[0].VpcId, rdsSecret.MasterUsername, rdsSecret.MasterPassword, *keyMetadata.KeyId, d.databaseType, logger)
+	err = d.client.rdsEnsureDBClusterCreated(awsID, *vpcs[0].VpcId, rdsSecret.MasterUsername, rdsSecret.MasterPassword, *keyMetadata.KeyId, d.databaseType, nil, logger)
 	if err != nil {
 		return errors.Wrap(err, "failed to ensure DB cluster was created")
 	}
@@ -0,0 +1,247 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// secretVersionStageCurrent is the Secrets Manager staging label of the
+	// secret version in use.
+	secretVersionStageCurrent = "AWSCURRENT"
+	// secretVersionStagePending is the Secrets Manager staging label of a
+	// secret version that is being rotated in.
+	secretVersionStagePending = "AWSPENDING"
+	// secretVersionStagePrevious is the Secrets Manager staging label of the
+	// secret version that was in use before the last rotation.
+	secretVersionStagePrevious = "AWSPREVIOUS"
+)
+
+// rotateDatabaseUserSecret rotates the credentials of a database user secret
+// following the Secrets Manager rotation steps. The secret alternates between
+// two database users, so that the user in use before the rotation keeps
+// working until it is revoked:
+//  1. A new password for the user not in use is stored as the pending version
+//     of the secret.
+//  2. The pending user is activated with the pending password.
+//  3. The pending version is promoted to the current version, which makes
+//     the old user the previous version until it is revoked.
+//
+// A pending version left behind by an interrupted rotation is reused, and no
+// new rotation is started while the previous version was not revoked yet, so
+// the rotation can safely be retried.
+func rotateDatabaseUserSecret(secretName, username, alternateUsername string, activateUser func(secret *RDSSecret) error, client *Client, logger log.FieldLogger) error {
+	logger = logger.WithField("secret-name", secretName)
+
+	secret, err := client.Service().secretsManager.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe secret %s", secretName)
+	}
+
+	pendingVersionID := findSecretVersionWithStage(secret.VersionIdsToStages, secretVersionStagePending)
+	if pendingVersionID == "" && findSecretVersionWithStage(secret.VersionIdsToStages, secretVersionStagePrevious) != "" {
+		logger.Info("Previous database user secret was not revoked yet; resuming the last rotation")
+		return nil
+	}
+
+	pendingVersionID, pendingSecret, err := ensurePendingSecretVersion(secretName, pendingVersionID, username, alternateUsername, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure pending secret version")
+	}
+
+	err = activateUser(pendingSecret)
+	if err != nil {
+		return errors.Wrap(err, "failed to activate pending database user")
+	}
+
+	err = promotePendingSecretVersion(secretName, pendingVersionID, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to promote pending secret version")
+	}
+
+	logger.WithField("database-user", pendingSecret.MasterUsername).Info("Database user secret rotated")
+
+	return nil
+}
+
+// revokePreviousDatabaseUserSecret deactivates the database user of the
+// previous version of a database user secret and then removes the previous
+// version stage. Secrets Manager deprecates the old version once no staging
+// label is attached to it.
+func revokePreviousDatabaseUserSecret(secretName string, deactivateUser func(username string) error, client *Client, logger log.FieldLogger) error {
+	logger = logger.WithField("secret-name", secretName)
+
+	secret, err := client.Service().secretsManager.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe secret %s", secretName)
+	}
+
+	previousVersionID := findSecretVersionWithStage(secret.VersionIdsToStages, secretVersionStagePrevious)
+	if previousVersionID == "" {
+		logger.Debug("Database user secret has no previous version; assuming already revoked")
+		return nil
+	}
+
+	previousSecret, err := getSecretVersion(secretName, &secretsmanager.GetSecretValueInput{
+		SecretId:  aws.String(secretName),
+		VersionId: aws.String(previousVersionID),
+	}, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to get previous secret version")
+	}
+	currentSecret, err := getSecretVersion(secretName, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretName),
+	}, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to get current secret version")
+	}
+
+	// The user of the previous version is only deactivated if the current
+	// version doesn't use it as well.
+	if previousSecret.MasterUsername != currentSecret.MasterUsername {
+		err = deactivateUser(previousSecret.MasterUsername)
+		if err != nil {
+			return errors.Wrap(err, "failed to deactivate previous database user")
+		}
+	}
+
+	_, err = client.Service().secretsManager.UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            aws.String(secretName),
+		VersionStage:        aws.String(secretVersionStagePrevious),
+		RemoveFromVersionId: aws.String(previousVersionID),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove previous version stage from secret %s", secretName)
+	}
+
+	logger.WithField("database-user", previousSecret.MasterUsername).Info("Previous database user secret revoked")
+
+	return nil
+}
+
+// ensurePendingSecretVersion returns the pending version of a database user
+// secret. If there is none yet, it is created with a new password for the
+// database user which is not used by the current version.
+func ensurePendingSecretVersion(secretName, pendingVersionID, username, alternateUsername string, client *Client) (string, *RDSSecret, error) {
+	if pendingVersionID != "" {
+		pendingSecret, err := getSecretVersion(secretName, &secretsmanager.GetSecretValueInput{
+			SecretId:  aws.String(secretName),
+			VersionId: aws.String(pendingVersionID),
+		}, client)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "failed to get pending secret version")
+		}
+
+		return pendingVersionID, pendingSecret, nil
+	}
+
+	currentSecret, err := getSecretVersion(secretName, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretName),
+	}, client)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get current secret version")
+	}
+
+	pendingSecret := &RDSSecret{
+		MasterUsername: alternateUsername,
+		MasterPassword: newRandomPassword(40),
+	}
+	if currentSecret.MasterUsername == alternateUsername {
+		pendingSecret.MasterUsername = username
+	}
+	err = pendingSecret.Validate()
+	if err != nil {
+		return "", nil, errors.Wrap(err, "RDS secret failed validation")
+	}
+
+	b, err := json.Marshal(pendingSecret)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to marshal secrets manager payload")
+	}
+
+	result, err := client.Service().secretsManager.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:      aws.String(secretName),
+		SecretString:  aws.String(string(b)),
+		VersionStages: aws.StringSlice([]string{secretVersionStagePending}),
+	})
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to put pending version of secret %s", secretName)
+	}
+
+	return *result.VersionId, pendingSecret, nil
+}
+
+// getSecretVersion returns the database user secret payload of a secret
+// version.
+func getSecretVersion(secretName string, input *secretsmanager.GetSecretValueInput, client *Client) (*RDSSecret, error) {
+	result, err := client.Service().secretsManager.GetSecretValue(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get secret %s", secretName)
+	}
+
+	secret, err := unmarshalSecretPayload(*result.SecretString)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal secret payload")
+	}
+
+	return secret, nil
+}
+
+// promotePendingSecretVersion makes the pending version the current version
+// of a secret.
+func promotePendingSecretVersion(secretName, pendingVersionID string, client *Client) error {
+	secret, err := client.Service().secretsManager.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe secret %s", secretName)
+	}
+
+	currentVersionID := findSecretVersionWithStage(secret.VersionIdsToStages, secretVersionStageCurrent)
+	if currentVersionID != pendingVersionID {
+		_, err = client.Service().secretsManager.UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+			SecretId:            aws.String(secretName),
+			VersionStage:        aws.String(secretVersionStageCurrent),
+			MoveToVersionId:     aws.String(pendingVersionID),
+			RemoveFromVersionId: aws.String(currentVersionID),
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to move current version stage of secret %s", secretName)
+		}
+	}
+
+	_, err = client.Service().secretsManager.UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            aws.String(secretName),
+		VersionStage:        aws.String(secretVersionStagePending),
+		RemoveFromVersionId: aws.String(pendingVersionID),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove pending version stage from secret %s", secretName)
+	}
+
+	return nil
+}
+
+func findSecretVersionWithStage(versionIDsToStages map[string][]*string, stage string) string {
+	for versionID, stages := range versionIDsToStages {
+		for _, versionStage := range stages {
+			if aws.StringValue(versionStage) == stage {
+				return versionID
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/pkg/errors"
+)
+
+func (a *AWSTestSuite) TestRotateDatabaseUserSecret() {
+	secretName := RDSMultitenantSecretName(a.InstallationA.ID)
+
+	for _, testCase := range []struct {
+		description         string
+		currentSecretString string
+		expectedUsername    string
+	}{
+		{
+			description:         "original user in use",
+			currentSecretString: `{"MasterUsername":"user1","MasterPassword":"oX5rWueZt6ynsijE9PHpUO0VUWSwWSxqXCaZw1dC"}`,
+			expectedUsername:    "user2",
+		},
+		{
+			description:         "alternate user in use",
+			currentSecretString: `{"MasterUsername":"user2","MasterPassword":"oX5rWueZt6ynsijE9PHpUO0VUWSwWSxqXCaZw1dC"}`,
+			expectedUsername:    "user1",
+		},
+	} {
+		a.T().Run(testCase.description, func(t *testing.T) {
+			a.SetupTest()
+
+			gomock.InOrder(
+				a.Mocks.API.SecretsManager.EXPECT().
+					DescribeSecret(gomock.Any()).
+					Return(&secretsmanager.DescribeSecretOutput{
+						VersionIdsToStages: map[string][]*string{
+							"current": aws.StringSlice([]string{secretVersionStageCurrent}),
+						},
+					}, nil),
+				a.Mocks.API.SecretsManager.EXPECT().
+					GetSecretValue(&secretsmanager.GetSecretValueInput{
+						SecretId: aws.String(secretName),
+					}).
+					Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String(testCase.currentSecretString)}, nil),
+				a.Mocks.API.SecretsManager.EXPECT().
+					PutSecretValue(gomock.Any()).
+					Do(func(input *secretsmanager.PutSecretValueInput) {
+						a.Assert().Equal(secretName, *input.SecretId)
+						a.Assert().Equal([]string{secretVersionStagePending}, aws.StringValueSlice(input.VersionStages))
+					}).
+					Return(&secretsmanager.PutSecretValueOutput{VersionId: aws.String("pending")}, nil),
+				a.Mocks.API.SecretsManager.EXPECT().
+					DescribeSecret(gomock.Any()).
+					Return(&secretsmanager.DescribeSecretOutput{
+						VersionIdsToStages: map[string][]*string{
+							"current": aws.StringSlice([]string{secretVersionStageCurrent}),
+							"pending": aws.StringSlice([]string{secretVersionStagePending}),
+						},
+					}, nil),
+				a.Mocks.API.SecretsManager.EXPECT().
+					UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+						SecretId:            aws.String(secretName),
+						VersionStage:        aws.String(secretVersionStageCurrent),
+						MoveToVersionId:     aws.String("pending"),
+						RemoveFromVersionId: aws.String("current"),
+					}).
+					Return(&secretsmanager.UpdateSecretVersionStageOutput{}, nil),
+				a.Mocks.API.SecretsManager.EXPECT().
+					UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+						SecretId:            aws.String(secretName),
+						VersionStage:        aws.String(secretVersionStagePending),
+						RemoveFromVersionId: aws.String("pending"),
+					}).
+					Return(&secretsmanager.UpdateSecretVersionStageOutput{}, nil),
+			)
+
+			var pendingSecret *RDSSecret
+			err := rotateDatabaseUserSecret(secretName, "user1", "user2", func(secret *RDSSecret) error {
+				pendingSecret = secret
+				return nil
+			}, a.Mocks.AWS, testlib.MakeLogger(t))
+			a.Assert().NoError(err)
+			a.Require().NotNil(pendingSecret)
+			a.Assert().Equal(testCase.expectedUsername, pendingSecret.MasterUsername)
+			a.Assert().Len(pendingSecret.MasterPassword, 40)
+			a.Assert().NotEqual("oX5rWueZt6ynsijE9PHpUO0VUWSwWSxqXCaZw1dC", pendingSecret.MasterPassword)
+		})
+	}
+}
+
+func (a *AWSTestSuite) TestRotateDatabaseUserSecretActivateUserError() {
+	secretName := RDSMultitenantSecretName(a.InstallationA.ID)
+	pendingSecretString := `{"MasterUsername":"user2","MasterPassword":"pAE9xNKXGTJhBUcNnRgCtBbbs8BPsCh7uR2WzqBu"}`
+
+	// A pending version left behind by an earlier attempt is reused and the
+	// secret is not promoted when the user can't be activated.
+	gomock.InOrder(
+		a.Mocks.API.SecretsManager.EXPECT().
+			DescribeSecret(gomock.Any()).
+			Return(&secretsmanager.DescribeSecretOutput{
+				VersionIdsToStages: map[string][]*string{
+					"current": aws.StringSlice([]string{secretVersionStageCurrent}),
+					"pending": aws.StringSlice([]string{secretVersionStagePending}),
+				},
+			}, nil),
+		a.Mocks.API.SecretsManager.EXPECT().
+			GetSecretValue(&secretsmanager.GetSecretValueInput{
+				SecretId:  aws.String(secretName),
+				VersionId: aws.String("pending"),
+			}).
+			Return(&secretsmanager.GetSecretValueOutput{SecretString: &pendingSecretString}, nil),
+	)
+
+	err := rotateDatabaseUserSecret(secretName, "user1", "user2", func(secret *RDSSecret) error {
+		a.Assert().Equal("user2", secret.MasterUsername)
+		a.Assert().Equal("pAE9xNKXGTJhBUcNnRgCtBbbs8BPsCh7uR2WzqBu", secret.MasterPassword)
+		return errors.New("database unavailable")
+	}, a.Mocks.AWS, testlib.MakeLogger(a.T()))
+	a.Assert().Error(err)
+	a.Assert().Equal("failed to activate pending database user: database unavailable", err.Error())
+}
+
+func (a *AWSTestSuite) TestRotateDatabaseUserSecretPreviousNotRevoked() {
+	secretName := RDSMultitenantSecretName(a.InstallationA.ID)
+
+	// A rotation which was promoted but not revoked yet is not repeated, so
+	// the previous user the pods may still use keeps working.
+	a.Mocks.API.SecretsManager.EXPECT().
+		DescribeSecret(gomock.Any()).
+		Return(&secretsmanager.DescribeSecretOutput{
+			VersionIdsToStages: map[string][]*string{
+				"current":  aws.StringSlice([]string{secretVersionStageCurrent}),
+				"previous": aws.StringSlice([]string{secretVersionStagePrevious}),
+			},
+		}, nil).
+		Times(1)
+
+	err := rotateDatabaseUserSecret(secretName, "user1", "user2", func(secret *RDSSecret) error {
+		a.Fail("no user must be activated")
+		return nil
+	}, a.Mocks.AWS, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestRevokePreviousDatabaseUserSecret() {
+	secretName := RDSMultitenantSecretName(a.InstallationA.ID)
+
+	for _, testCase := range []struct {
+		description          string
+		previousSecretString string
+		deactivateErr        error
+		expectedDeactivated  []string
+		expectRevoked        bool
+		expectErr            bool
+	}{
+		{
+			description:          "previous user is deactivated",
+			previousSecretString: `{"MasterUsername":"user1","MasterPassword":"pAE9xNKXGTJhBUcNnRgCtBbbs8BPsCh7uR2WzqBu"}`,
+			expectedDeactivated:  []string{"user1"},
+			expectRevoked:        true,
+		},
+		{
+			description:          "previous user is the current user",
+			previousSecretString: `{"MasterUsername":"user2","MasterPassword":"pAE9xNKXGTJhBUcNnRgCtBbbs8BPsCh7uR2WzqBu"}`,
+			expectRevoked:        true,
+		},
+		{
+			description:          "deactivating previous user fails",
+			previousSecretString: `{"MasterUsername":"user1","MasterPassword":"pAE9xNKXGTJhBUcNnRgCtBbbs8BPsCh7uR2WzqBu"}`,
+			deactivateErr:        errors.New("database unavailable"),
+			expectedDeactivated:  []string{"user1"},
+			expectErr:            true,
+		},
+	} {
+		a.T().Run(testCase.description, func(t *testing.T) {
+			a.SetupTest()
+
+			calls := []*gomock.Call{
+				a.Mocks.API.SecretsManager.EXPECT().
+					DescribeSecret(gomock.Any()).
+					Return(&secretsmanager.DescribeSecretOutput{
+						VersionIdsToStages: map[string][]*string{
+							"current":  aws.StringSlice([]string{secretVersionStageCurrent}),
+							"previous": aws.StringSlice([]string{secretVersionStagePrevious}),
+						},
+					}, nil),
+				a.Mocks.API.SecretsManager.EXPECT().
+					GetSecretValue(&secretsmanager.GetSecretValueInput{
+						SecretId:  aws.String(secretName),
+						VersionId: aws.String("previous"),
+					}).
+					Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String(testCase.previousSecretString)}, nil),
+				a.Mocks.API.SecretsManager.EXPECT().
+					GetSecretValue(&secretsmanager.GetSecretValueInput{
+						SecretId: aws.String(secretName),
+					}).
+					Return(&secretsmanager.GetSecretValueOutput{
+						SecretString: aws.String(`{"MasterUsername":"user2","MasterPassword":"oX5rWueZt6ynsijE9PHpUO0VUWSwWSxqXCaZw1dC"}`),
+					}, nil),
+			}
+			if testCase.expectRevoked {
+				calls = append(calls, a.Mocks.API.SecretsManager.EXPECT().
+					UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+						SecretId:            aws.String(secretName),
+						VersionStage:        aws.String(secretVersionStagePrevious),
+						RemoveFromVersionId: aws.String("previous"),
+					}).
+					Return(&secretsmanager.UpdateSecretVersionStageOutput{}, nil))
+			}
+			gomock.InOrder(calls...)
+
+			var deactivated []string
+			err := revokePreviousDatabaseUserSecret(secretName, func(username string) error {
+				deactivated = append(deactivated, username)
+				return testCase.deactivateErr
+			}, a.Mocks.AWS, testlib.MakeLogger(t))
+			if testCase.expectErr {
+				a.Assert().Error(err)
+			} else {
+				a.Assert().NoError(err)
+			}
+			a.Assert().Equal(testCase.expectedDeactivated, deactivated)
+		})
+	}
+}
+
+func (a *AWSTestSuite) TestRevokePreviousDatabaseUserSecretAlreadyRevoked() {
+	secretName := RDSMultitenantSecretName(a.InstallationA.ID)
+
+	a.Mocks.API.SecretsManager.EXPECT().
+		DescribeSecret(gomock.Any()).
+		Return(&secretsmanager.DescribeSecretOutput{
+			VersionIdsToStages: map[string][]*string{
+				"current": aws.StringSlice([]string{secretVersionStageCurrent}),
+			},
+		}, nil).
+		Times(1)
+
+	err := revokePreviousDatabaseUserSecret(secretName, func(username string) error {
+		a.Fail("no user must be deactivated")
+		return nil
+	}, a.Mocks.AWS, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
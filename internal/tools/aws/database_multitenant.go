@@ -425,6 +425,9 @@ func (d *RDSMultitenantDatabase) MigrateTo(store model.InstallationDatabaseStore
 	if err != nil {
 		return errors.Wrap(err, "failed to query for the multitenant database")
 	}
+	if database.IsUpgrading() {
+		return errors.Errorf("multitenant database %s is being upgraded (state: %s)", database.ID, database.State)
+	}
 
 	err = d.migrateInstallationToDB(store, database)
 	if err != nil {
@@ -482,10 +485,10 @@ func (d *RDSMultitenantDatabase) migrateInstallationToDB(store model.Installatio
 	return nil
 }
 
-// TODO: for now rollback will be supported only for multi-tenant postgres to multi-tenant postgres migration
-// To support more DB types we will have to split this method to two.
-
 // RollbackMigration rollbacks Installation to the source database.
+// Rollback is supported for migrations to multitenant postgres database from
+// multitenant postgres database or from MySQL databases migrated with engine
+// migration.
 func (d *RDSMultitenantDatabase) RollbackMigration(store model.InstallationDatabaseStoreInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
 	installationDatabaseName := MattermostRDSDatabaseName(d.installationID)
 
@@ -494,10 +497,18 @@ func (d *RDSMultitenantDatabase) RollbackMigration(store model.InstallationDatab
 		"database-type":            d.databaseType,
 	})
 
-	if dbMigration.SourceDatabase != model.InstallationDatabaseMultiTenantRDSPostgres ||
-		dbMigration.DestinationDatabase != model.InstallationDatabaseMultiTenantRDSPostgres {
+	if dbMigration.DestinationDatabase != model.InstallationDatabaseMultiTenantRDSPostgres {
 		return errors.New("db migration rollback is supported only for multitenant postgres database")
 	}
+	switch dbMigration.SourceDatabase {
+	case model.InstallationDatabaseMultiTenantRDSPostgres, model.InstallationDatabaseMultiTenantRDSMySQL:
+		if dbMigration.SourceMultiTenant == nil {
+			return errors.New("source multitenant database data not provided")
+		}
+	case model.InstallationDatabaseSingleTenantRDSMySQL:
+	default:
+		return errors.Errorf("db migration rollback is not supported for %q source database", dbMigration.SourceDatabase)
+	}
 
 	unlockDest, err := lockMultitenantDatabase(dbMigration.DestinationMultiTenant.DatabaseID, d.instanceID, store, logger)
 	if err != nil {
@@ -509,27 +520,30 @@ func (d *RDSMultitenantDatabase) RollbackMigration(store model.InstallationDatab
 		return errors.Wrap(err, "failed to query for the multitenant database")
 	}
 
-	unlockSource, err := lockMultitenantDatabase(dbMigration.SourceMultiTenant.DatabaseID, d.instanceID, store, logger)
-	if err != nil {
-		return errors.Wrap(err, "failed to lock multitenant database")
-	}
-	defer unlockSource()
-	sourceDatabase, err := store.GetMultitenantDatabase(dbMigration.SourceMultiTenant.DatabaseID)
-	if err != nil {
-		return errors.Wrap(err, "failed to query for the multitenant database")
-	}
+	var sourceDatabase *model.MultitenantDatabase
+	if dbMigration.SourceMultiTenant != nil {
+		unlockSource, err := lockMultitenantDatabase(dbMigration.SourceMultiTenant.DatabaseID, d.instanceID, store, logger)
+		if err != nil {
+			return errors.Wrap(err, "failed to lock multitenant database")
+		}
+		defer unlockSource()
+		sourceDatabase, err = store.GetMultitenantDatabase(dbMigration.SourceMultiTenant.DatabaseID)
+		if err != nil {
+			return errors.Wrap(err, "failed to query for the multitenant database")
+		}
 
-	sourceDatabase.MigratedInstallations.Remove(d.installationID)
-	destinationDatabase.Installations.Remove(d.installationID)
+		sourceDatabase.MigratedInstallations.Remove(d.installationID)
+		if !common.Contains(sourceDatabase.Installations, d.installationID) {
+			sourceDatabase.Installations.Add(d.installationID)
+		}
 
-	if !common.Contains(sourceDatabase.Installations, d.installationID) {
-		sourceDatabase.Installations.Add(d.installationID)
+		err = store.UpdateMultitenantDatabase(sourceDatabase)
+		if err != nil {
+			return errors.Wrap(err, "failed to update source multitenant database")
+		}
 	}
 
-	err = store.UpdateMultitenantDatabase(sourceDatabase)
-	if err != nil {
-		return errors.Wrap(err, "failed to update source multitenant database")
-	}
+	destinationDatabase.Installations.Remove(d.installationID)
 	err = store.UpdateMultitenantDatabase(destinationDatabase)
 	if err != nil {
 		return errors.Wrap(err, "failed to update destination multitenant database")
@@ -555,9 +569,15 @@ func (d *RDSMultitenantDatabase) RollbackMigration(store model.InstallationDatab
 	if err != nil {
 		return errors.Wrap(err, "failed to update counter tag with current weight")
 	}
-	err = updateCounterTagWithCurrentWeight(sourceDatabase, rdsCluster, store, d.client, logger)
-	if err != nil {
-		return errors.Wrap(err, "failed to update counter tag with current weight")
+	if sourceDatabase != nil {
+		sourceRDSCluster, err := describeRDSCluster(sourceDatabase.RdsClusterID, d.client)
+		if err != nil {
+			return errors.Wrapf(err, "failed to describe the multitenant RDS cluster ID %s", sourceDatabase.ID)
+		}
+		err = updateCounterTagWithCurrentWeight(sourceDatabase, sourceRDSCluster, store, d.client, logger)
+		if err != nil {
+			return errors.Wrap(err, "failed to update counter tag with current weight")
+		}
 	}
 
 	logger.Infof("Installation %s migrated to multitenant database %s", d.installationID, destinationDatabase.ID)
@@ -565,6 +585,104 @@ func (d *RDSMultitenantDatabase) RollbackMigration(store model.InstallationDatab
 	return nil
 }
 
+// RotateCredentials switches the installation to the other one of its two
+// database users with a new password and stores it as the current version of
+// the installation secret. The user in use before stays valid until the
+// previous credentials are revoked.
+func (d *RDSMultitenantDatabase) RotateCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	logger = logger.WithFields(log.Fields{
+		"multitenant-rds-database": MattermostRDSDatabaseName(d.installationID),
+		"database-type":            d.databaseType,
+	})
+	logger.Info("Rotating multitenant RDS database credentials")
+
+	err := d.runCredentialsSQLCommands(store, logger, func(ctx context.Context) error {
+		return rotateDatabaseUserSecret(
+			RDSMultitenantSecretName(d.installationID),
+			MattermostMultitenantDatabaseUsername(d.installationID),
+			MattermostMultitenantDatabaseAlternateUsername(d.installationID),
+			func(secret *RDSSecret) error {
+				return d.activateDatabaseUser(ctx, secret.MasterUsername, secret.MasterPassword)
+			},
+			d.client, logger)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate installation database secret")
+	}
+
+	logger.Info("Multitenant RDS database credentials rotated")
+
+	return nil
+}
+
+// RevokePreviousCredentials deactivates the database user that was used
+// before the last credential rotation and removes it from the installation
+// secret.
+func (d *RDSMultitenantDatabase) RevokePreviousCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	logger = logger.WithFields(log.Fields{
+		"multitenant-rds-database": MattermostRDSDatabaseName(d.installationID),
+		"database-type":            d.databaseType,
+	})
+
+	err := d.runCredentialsSQLCommands(store, logger, func(ctx context.Context) error {
+		return revokePreviousDatabaseUserSecret(RDSMultitenantSecretName(d.installationID), func(username string) error {
+			return d.deactivateDatabaseUser(ctx, username)
+		}, d.client, logger)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke previous installation database secret")
+	}
+
+	return nil
+}
+
+// runCredentialsSQLCommands locks the multitenant database of the
+// installation and runs the given credential changes while connected to its
+// RDS cluster as the master user.
+func (d *RDSMultitenantDatabase) runCredentialsSQLCommands(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger, run func(ctx context.Context) error) error {
+	err := d.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "multitenant database configuration is invalid")
+	}
+
+	multitenantDatabase, err := store.GetMultitenantDatabaseForInstallationID(d.installationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to query for the multitenant database")
+	}
+
+	unlock, err := lockMultitenantDatabase(multitenantDatabase.ID, d.instanceID, store, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock multitenant database")
+	}
+	defer unlock()
+
+	rdsCluster, err := describeRDSCluster(multitenantDatabase.RdsClusterID, d.client)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe RDS cluster")
+	}
+
+	rdsID := *rdsCluster.DBClusterIdentifier
+	logger = logger.WithField("rds-cluster-id", rdsID)
+
+	masterSecretValue, err := d.client.Service().secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: rdsCluster.DBClusterIdentifier,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the master secret for the multitenant RDS cluster %s", rdsID)
+	}
+
+	close, err := d.connectRDSCluster(*rdsCluster.Endpoint, DefaultMattermostDatabaseUsername, *masterSecretValue.SecretString)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to the multitenant RDS cluster %s", rdsID)
+	}
+	defer close(logger)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(DefaultMySQLContextTimeSeconds*time.Second))
+	defer cancel()
+
+	return run(ctx)
+}
+
 // Helpers
 
 // getAssignedMultitenantDatabaseResources returns the assigned multitenant
@@ -608,6 +726,9 @@ func (d *RDSMultitenantDatabase) getAndLockAssignedMultitenantDatabase(store mod
 //	1. fetch a multitenant database by installation ID.
 //	2. fetch all multitenant databases in the store which are under the max number of installations limit.
 //	3. fetch all multitenant databases in the RDS cluster that are under the max number of installations limit.
+//
+// Multitenant databases requested by auto-provisioning are skipped until the
+// capacity supervisor has brought them to a stable state.
 func (d *RDSMultitenantDatabase) assignInstallationToMultitenantDatabaseAndLock(vpcID string, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*model.MultitenantDatabase, func(), error) {
 	multitenantDatabases, err := store.GetMultitenantDatabases(&model.MultitenantDatabaseFilter{
 		DatabaseType:          d.databaseType,
@@ -618,6 +739,7 @@ func (d *RDSMultitenantDatabase) assignInstallationToMultitenantDatabaseAndLock(
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to get available multitenant databases")
 	}
+	multitenantDatabases = filterCreatedMultitenantDatabases(multitenantDatabases)
 
 	if len(multitenantDatabases) == 0 {
 		logger.Infof("No %s multitenant databases with less than %d installations found in the datastore; fetching all available resources from AWS", d.databaseType, d.MaxSupportedDatabases())
@@ -637,12 +759,19 @@ func (d *RDSMultitenantDatabase) assignInstallationToMultitenantDatabaseAndLock(
 	// as close to maximim efficiency as possible.
 	// TODO: we haven't aquired a lock yet on any of these databases so this
 	// could open up small race conditions.
-	selectedDatabase := &model.MultitenantDatabase{}
+	var selectedDatabase *model.MultitenantDatabase
 	for _, multitenantDatabase := range multitenantDatabases {
-		if multitenantDatabase.Installations.Count() >= selectedDatabase.Installations.Count() {
+		// Databases being upgraded don't accept new installations.
+		if multitenantDatabase.IsUpgrading() {
+			continue
+		}
+		if selectedDatabase == nil || multitenantDatabase.Installations.Count() >= selectedDatabase.Installations.Count() {
 			selectedDatabase = multitenantDatabase
 		}
 	}
+	if selectedDatabase == nil {
+		return nil, nil, errors.New("no multitenant databases are currently available for new installations; all of them are being upgraded")
+	}
 
 	unlockFn, err := lockMultitenantDatabase(selectedDatabase.ID, d.instanceID, store, logger)
 	if err != nil {
@@ -655,6 +784,10 @@ func (d *RDSMultitenantDatabase) assignInstallationToMultitenantDatabaseAndLock(
 		unlockFn()
 		return nil, nil, errors.Wrap(err, "failed to refresh multitenant database after lock")
 	}
+	if selectedDatabase.IsUpgrading() {
+		unlockFn()
+		return nil, nil, errors.Errorf("selected multitenant database %s is being upgraded", selectedDatabase.ID)
+	}
 
 	// Finish assigning the installation.
 	selectedDatabase.Installations.Add(d.installationID)
@@ -667,6 +800,21 @@ func (d *RDSMultitenantDatabase) assignInstallationToMultitenantDatabaseAndLock(
 	return selectedDatabase, unlockFn, nil
 }
 
+// filterCreatedMultitenantDatabases returns the multitenant databases whose
+// RDS cluster was created, leaving out the ones requested by auto-provisioning
+// which are still being created by the capacity supervisor.
+func filterCreatedMultitenantDatabases(multitenantDatabases []*model.MultitenantDatabase) []*model.MultitenantDatabase {
+	var created []*model.MultitenantDatabase
+	for _, multitenantDatabase := range multitenantDatabases {
+		if multitenantDatabase.State == model.DatabaseStateCreationRequested {
+			continue
+		}
+		created = append(created, multitenantDatabase)
+	}
+
+	return created
+}
+
 func (d *RDSMultitenantDatabase) getMultitenantDatabasesFromResourceTags(vpcID string, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) ([]*model.MultitenantDatabase, error) {
 	databaseType := d.DatabaseTypeTagValue()
 
@@ -985,9 +1133,14 @@ func (d *RDSMultitenantDatabase) cleanupDatabase(rdsClusterID, rdsClusterendpoin
 		return errors.Wrapf(err, "failed to drop multitenant RDS database name %s", databaseName)
 	}
 
-	err = dropUserIfExists(ctx, d.db, MattermostMultitenantDatabaseUsername(d.installationID))
-	if err != nil {
-		return errors.Wrap(err, "failed to delete installation database user")
+	for _, username := range []string{
+		MattermostMultitenantDatabaseAlternateUsername(d.installationID),
+		MattermostMultitenantDatabaseUsername(d.installationID),
+	} {
+		err = dropUserIfExists(ctx, d.db, username)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete installation database user")
+		}
 	}
 
 	return nil
@@ -1183,6 +1336,62 @@ func (d *RDSMultitenantDatabase) ensureDatabaseUserIsCreated(ctx context.Context
 	return nil
 }
 
+// activateDatabaseUser makes sure that one of the two database users of the
+// installation exists, can log in with the given password and has access to
+// the installation database. The alternate user acts as the original user,
+// which owns the database objects.
+func (d *RDSMultitenantDatabase) activateDatabaseUser(ctx context.Context, username, password string) error {
+	err := d.ensureDatabaseUserIsCreated(ctx, username, password)
+	if err != nil {
+		return errors.Wrap(err, "failed to create database user")
+	}
+
+	if d.databaseType == model.DatabaseEngineTypeMySQL {
+		_, err = d.db.QueryContext(ctx, "ALTER USER ?@? IDENTIFIED BY ? ACCOUNT UNLOCK", username, "%", password)
+		if err != nil {
+			return errors.New("failed to run alter user SQL command: error suppressed")
+		}
+	} else {
+		// Parameters can't be used here either, see ensureDatabaseUserIsCreated.
+		query := fmt.Sprintf("ALTER USER %s WITH LOGIN PASSWORD '%s'", username, password)
+		_, err = d.db.QueryContext(ctx, query)
+		if err != nil {
+			return errors.New("failed to run alter user SQL command: error suppressed")
+		}
+
+		owner := MattermostMultitenantDatabaseUsername(d.installationID)
+		if username != owner {
+			err = ensureDatabaseUserActsAsOwner(ctx, d.db, username, owner)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = d.ensureDatabaseUserHasFullPermissions(ctx, MattermostRDSDatabaseName(d.installationID), username)
+	if err != nil {
+		return errors.Wrap(err, "failed to grant permissions to database user")
+	}
+
+	return nil
+}
+
+// deactivateDatabaseUser prevents a database user of the installation from
+// logging in. The user is kept so that it can be activated again by the next
+// credential rotation.
+func (d *RDSMultitenantDatabase) deactivateDatabaseUser(ctx context.Context, username string) error {
+	if d.databaseType == model.DatabaseEngineTypeMySQL {
+		_, err := d.db.QueryContext(ctx, "ALTER USER ?@? ACCOUNT LOCK", username, "%")
+		if err != nil {
+			return errors.Wrap(err, "failed to run lock user SQL command")
+		}
+
+		return nil
+	}
+
+	return deactivatePostgresUser(ctx, d.db, username)
+}
+
 func (d *RDSMultitenantDatabase) ensureDatabaseUserHasFullPermissions(ctx context.Context, databaseName, username string) error {
 	if d.databaseType == model.DatabaseEngineTypeMySQL {
 		// Query placeholders don't seem to work with argument database.
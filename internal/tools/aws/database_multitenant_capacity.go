@@ -0,0 +1,331 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// MultitenantDatabaseAutoProvisioning configures the creation of new
+// multitenant RDS clusters when the existing ones of a VPC fill up.
+type MultitenantDatabaseAutoProvisioning struct {
+	// UtilizationThreshold is the percent of the total database weight
+	// capacity of the multitenant databases of a type in a VPC above which a
+	// new RDS cluster is created.
+	UtilizationThreshold int
+	// InstanceType is the instance class of the RDS cluster instances.
+	InstanceType string
+	// Replicas is the number of reader instances of each RDS cluster.
+	Replicas int
+}
+
+// Validate returns an error if the auto-provisioning configuration is invalid.
+func (p *MultitenantDatabaseAutoProvisioning) Validate() error {
+	if p.UtilizationThreshold < 1 || p.UtilizationThreshold > 100 {
+		return errors.Errorf("utilization threshold must be between 1 and 100 (was %d)", p.UtilizationThreshold)
+	}
+	if len(p.InstanceType) == 0 {
+		return errors.New("instance type must be specified")
+	}
+	if p.Replicas < 0 {
+		return errors.Errorf("replicas must be 0 or higher (was %d)", p.Replicas)
+	}
+
+	return nil
+}
+
+// SetMultitenantDatabaseAutoProvisioning enables the creation of new
+// multitenant RDS clusters when the utilization of the existing ones crosses
+// the configured threshold.
+func (a *Client) SetMultitenantDatabaseAutoProvisioning(config MultitenantDatabaseAutoProvisioning) error {
+	err := config.Validate()
+	if err != nil {
+		return errors.Wrap(err, "invalid multitenant database auto-provisioning config")
+	}
+	a.multitenantDatabaseAutoProvisioning = &config
+
+	return nil
+}
+
+// EnsureMultitenantDatabaseCapacity makes sure that there is room for new
+// installations on the multitenant databases of the given type in the VPC.
+// It does nothing unless auto-provisioning is enabled.
+//
+// Callers must hold a lock preventing other provisioners from ensuring the
+// capacity of the same VPC and database type at the same time.
+func (a *Client) EnsureMultitenantDatabaseCapacity(vpcID, databaseType, instanceID string, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	database := NewRDSMultitenantDatabase(databaseType, instanceID, "", a)
+
+	return database.ensureMultitenantDatabaseCapacity(vpcID, store, logger)
+}
+
+// ensureMultitenantDatabaseCapacity makes sure that there is room for new
+// installations on the multitenant databases of the VPC. Multitenant databases
+// which are still being created are brought to a stable state once their RDS
+// cluster is ready, and a new RDS cluster is created when the weight of the
+// installations on the stable ones crosses the utilization threshold.
+//
+// The multitenant databases are read after the caller took the capacity lock,
+// so a database requested by another provisioner is always seen here and no
+// second RDS cluster is created for the same demand.
+func (d *RDSMultitenantDatabase) ensureMultitenantDatabaseCapacity(vpcID string, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	config := d.client.multitenantDatabaseAutoProvisioning
+	if config == nil {
+		return nil
+	}
+
+	multitenantDatabases, err := store.GetMultitenantDatabases(&model.MultitenantDatabaseFilter{
+		DatabaseType:          d.databaseType,
+		MaxInstallationsLimit: model.NoInstallationsLimit,
+		VpcID:                 vpcID,
+		Paging:                model.AllPagesNotDeleted(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to get multitenant databases")
+	}
+
+	var totalWeight float64
+	var capacity int
+	var creating bool
+	for _, multitenantDatabase := range multitenantDatabases {
+		if multitenantDatabase.State == model.DatabaseStateCreationRequested {
+			err = d.ensureMultitenantDatabaseClusterCreated(multitenantDatabase, store, logger)
+			if err != nil {
+				logger.WithError(err).Errorf("Failed to create RDS cluster %s", multitenantDatabase.RdsClusterID)
+			}
+			if multitenantDatabase.State == model.DatabaseStateCreationRequested {
+				creating = true
+				continue
+			}
+		}
+
+		weight, err := store.GetInstallationsTotalDatabaseWeight(multitenantDatabase.Installations)
+		if err != nil {
+			return errors.Wrapf(err, "failed to calculate total weight of multitenant database %s", multitenantDatabase.ID)
+		}
+		totalWeight += weight
+		capacity += d.capacityMaxSupportedDatabases()
+	}
+
+	if creating {
+		logger.Debugf("A new %s multitenant database is already being created in VPC %s", d.databaseType, vpcID)
+		return nil
+	}
+
+	utilization := 100
+	if capacity != 0 {
+		utilization = int(math.Ceil(totalWeight * 100 / float64(capacity)))
+	}
+	if utilization < config.UtilizationThreshold {
+		return nil
+	}
+
+	logger.Infof("Utilization of %s multitenant databases in VPC %s is %d%%; creating a new RDS cluster", d.databaseType, vpcID, utilization)
+
+	multitenantDatabase := &model.MultitenantDatabase{
+		RdsClusterID: RDSMultitenantClusterID(vpcID, model.NewID()[:8]),
+		VpcID:        vpcID,
+		DatabaseType: d.databaseType,
+		State:        model.DatabaseStateCreationRequested,
+	}
+	if d.databaseType == model.DatabaseEngineTypePostgresProxy {
+		multitenantDatabase.MaxInstallationsPerLogicalDatabase = model.GetDefaultProxyDatabaseMaxInstallationsPerLogicalDatabase()
+	}
+	err = store.CreateMultitenantDatabase(multitenantDatabase)
+	if err != nil {
+		return errors.Wrap(err, "failed to register new multitenant database")
+	}
+
+	return d.ensureMultitenantDatabaseClusterCreated(multitenantDatabase, store, logger)
+}
+
+// ensureMultitenantDatabaseClusterCreated creates the RDS cluster of a
+// multitenant database requested by the provisioner and marks the database as
+// stable once the cluster is available. Proxy databases are marked for
+// provisioning instead, so that the PgBouncer auth user is set up when the
+// first installation is assigned to them. It is safe to call repeatedly while
+// the cluster is being created.
+func (d *RDSMultitenantDatabase) ensureMultitenantDatabaseClusterCreated(multitenantDatabase *model.MultitenantDatabase, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	config := d.client.multitenantDatabaseAutoProvisioning
+	rdsClusterID := multitenantDatabase.RdsClusterID
+
+	logger = logger.WithField("db-cluster-name", rdsClusterID)
+
+	unlockFn, err := lockMultitenantDatabase(multitenantDatabase.ID, d.instanceID, store, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock multitenant database")
+	}
+	defer unlockFn()
+
+	masterPassword, err := d.ensureMultitenantDatabaseMasterSecretCreated(multitenantDatabase)
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure master secret was created")
+	}
+
+	tags := []*rds.Tag{
+		{
+			Key:   aws.String(trimTagPrefix(RDSMultitenantPurposeTagKey)),
+			Value: aws.String(RDSMultitenantPurposeTagValueProvisioning),
+		},
+		{
+			Key:   aws.String(trimTagPrefix(RDSMultitenantOwnerTagKey)),
+			Value: aws.String(RDSMultitenantOwnerTagValueCloudTeam),
+		},
+		{
+			Key:   aws.String(trimTagPrefix(DefaultRDSMultitenantDatabaseTypeTagKey)),
+			Value: aws.String(d.capacityDatabaseTypeTagValue()),
+		},
+		{
+			Key:   aws.String(trimTagPrefix(VpcIDTagKey)),
+			Value: aws.String(multitenantDatabase.VpcID),
+		},
+		{
+			Key:   aws.String(trimTagPrefix(CloudInstallationDatabaseTagKey)),
+			Value: aws.String(d.DatabaseTypeTagValue()),
+		},
+		{
+			Key:   aws.String(trimTagPrefix(RDSMultitenantInstallationCounterTagKey)),
+			Value: aws.String("0"),
+		},
+		{
+			Key:   aws.String(trimTagPrefix(DefaultRDSMultitenantDatabaseIDTagKey)),
+			Value: aws.String(rdsClusterID),
+		},
+		// The Terraform tag is left out on purpose so that the cluster is not
+		// registered a second time when looking up clusters by resource tags.
+	}
+
+	engineType := d.capacityEngineType()
+	err = d.client.rdsEnsureDBClusterCreated(rdsClusterID, multitenantDatabase.VpcID, DefaultMattermostDatabaseUsername, masterPassword, "", engineType, tags, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure DB cluster was created")
+	}
+
+	dbEngine, err := dbEngineFromType(engineType)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert database type to database engine")
+	}
+
+	err = d.client.rdsEnsureDBClusterInstanceCreated(rdsClusterID, fmt.Sprintf("%s-master", rdsClusterID), dbEngine, config.InstanceType, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure DB primary instance was created")
+	}
+	for i := 0; i < config.Replicas; i++ {
+		err = d.client.rdsEnsureDBClusterInstanceCreated(rdsClusterID, fmt.Sprintf("%s-replica-%d", rdsClusterID, i), dbEngine, config.InstanceType, logger)
+		if err != nil {
+			return errors.Wrap(err, "failed to ensure DB replica instance was created")
+		}
+	}
+
+	rdsCluster, err := describeRDSCluster(rdsClusterID, d.client)
+	if err != nil {
+		return err
+	}
+	if *rdsCluster.Status != DefaultRDSStatusAvailable {
+		logger.Infof("RDS cluster is %s; waiting for it to become available", *rdsCluster.Status)
+		return nil
+	}
+	ready, err := isRDSClusterEndpointsReady(rdsClusterID, d.client)
+	if err != nil {
+		return err
+	}
+	if !ready {
+		logger.Info("RDS cluster endpoints are not ready yet")
+		return nil
+	}
+
+	multitenantDatabase.WriterEndpoint = *rdsCluster.Endpoint
+	multitenantDatabase.ReaderEndpoint = *rdsCluster.ReaderEndpoint
+	multitenantDatabase.State = model.DatabaseStateStable
+	if d.databaseType == model.DatabaseEngineTypePostgresProxy {
+		multitenantDatabase.State = model.DatabaseStateProvisioningRequested
+	}
+	err = store.UpdateMultitenantDatabase(multitenantDatabase)
+	if err != nil {
+		multitenantDatabase.State = model.DatabaseStateCreationRequested
+		return errors.Wrap(err, "failed to store created multitenant database")
+	}
+
+	logger.Infof("Multitenant database %s is ready for new installations", multitenantDatabase.ID)
+
+	return nil
+}
+
+// ensureMultitenantDatabaseMasterSecretCreated returns the master password of
+// a multitenant RDS cluster, creating it first if needed. The secret is named
+// after the cluster and holds only the password, as expected when connecting
+// to multitenant databases.
+func (d *RDSMultitenantDatabase) ensureMultitenantDatabaseMasterSecretCreated(multitenantDatabase *model.MultitenantDatabase) (string, error) {
+	secretValue, err := d.client.Service().secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(multitenantDatabase.RdsClusterID),
+	})
+	if err == nil {
+		return *secretValue.SecretString, nil
+	}
+	if !IsErrorCode(err, secretsmanager.ErrCodeResourceNotFoundException) {
+		return "", errors.Wrapf(err, "failed to get master secret %s", multitenantDatabase.RdsClusterID)
+	}
+
+	password := newRandomPassword(40)
+	_, err = d.client.Service().secretsManager.CreateSecret(&secretsmanager.CreateSecretInput{
+		Name:         aws.String(multitenantDatabase.RdsClusterID),
+		Description:  aws.String(fmt.Sprintf("Master password of the multitenant RDS cluster %s", multitenantDatabase.RdsClusterID)),
+		SecretString: aws.String(password),
+		Tags: []*secretsmanager.Tag{
+			{
+				Key:   aws.String(trimTagPrefix(DefaultRDSMultitenantDatabaseIDTagKey)),
+				Value: aws.String(multitenantDatabase.RdsClusterID),
+			},
+			{
+				Key:   aws.String(trimTagPrefix(VpcIDTagKey)),
+				Value: aws.String(multitenantDatabase.VpcID),
+			},
+		},
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create master secret %s", multitenantDatabase.RdsClusterID)
+	}
+
+	return password, nil
+}
+
+// capacityEngineType returns the database engine type of the RDS clusters
+// created for the multitenant databases. Proxy databases run on PostgreSQL
+// clusters.
+func (d *RDSMultitenantDatabase) capacityEngineType() string {
+	if d.databaseType == model.DatabaseEngineTypePostgresProxy {
+		return model.DatabaseEngineTypePostgres
+	}
+
+	return d.databaseType
+}
+
+// capacityDatabaseTypeTagValue returns the multitenant database type tag value
+// of the RDS clusters created for the multitenant databases.
+func (d *RDSMultitenantDatabase) capacityDatabaseTypeTagValue() string {
+	if d.databaseType == model.DatabaseEngineTypePostgresProxy {
+		return DefaultRDSMultitenantDatabaseDBProxyTypeTagValue
+	}
+
+	return DefaultRDSMultitenantDatabaseTypeTagValue
+}
+
+// capacityMaxSupportedDatabases returns the maximum weight of installations
+// supported on one RDS cluster of the multitenant databases.
+func (d *RDSMultitenantDatabase) capacityMaxSupportedDatabases() int {
+	if d.databaseType == model.DatabaseEngineTypePostgresProxy {
+		return DefaultRDSMultitenantPGBouncerDatabasePostgresCountLimit
+	}
+
+	return d.MaxSupportedDatabases()
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/rds"
+	gt "github.com/aws/aws-sdk-go/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+)
+
+func (a *AWSTestSuite) newAutoProvisioningMultitenantDatabase() *RDSMultitenantDatabase {
+	a.Require().NoError(a.Mocks.AWS.SetMultitenantDatabaseAutoProvisioning(MultitenantDatabaseAutoProvisioning{
+		UtilizationThreshold: 80,
+		InstanceType:         "db.r5.large",
+		Replicas:             1,
+	}))
+
+	return &RDSMultitenantDatabase{
+		databaseType:   model.DatabaseEngineTypePostgres,
+		installationID: a.InstallationA.ID,
+		instanceID:     a.InstanceID,
+		client:         a.Mocks.AWS,
+	}
+}
+
+func (a *AWSTestSuite) TestMultitenantDatabaseAutoProvisioningValidate() {
+	for _, config := range []MultitenantDatabaseAutoProvisioning{
+		{UtilizationThreshold: 0, InstanceType: "db.r5.large"},
+		{UtilizationThreshold: 101, InstanceType: "db.r5.large"},
+		{UtilizationThreshold: 80},
+		{UtilizationThreshold: 80, InstanceType: "db.r5.large", Replicas: -1},
+	} {
+		a.Assert().Error(config.Validate())
+	}
+	a.Assert().NoError((&MultitenantDatabaseAutoProvisioning{UtilizationThreshold: 80, InstanceType: "db.r5.large"}).Validate())
+}
+
+func (a *AWSTestSuite) TestEnsureMultitenantDatabaseCapacityDisabled() {
+	err := a.Mocks.AWS.EnsureMultitenantDatabaseCapacity(a.VPCa, model.DatabaseEngineTypePostgres, a.InstanceID, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestEnsureMultitenantDatabaseCapacityBelowThreshold() {
+	database := a.newAutoProvisioningMultitenantDatabase()
+
+	gomock.InOrder(
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetMultitenantDatabases(gomock.Any()).
+			Do(func(input *model.MultitenantDatabaseFilter) {
+				a.Assert().Equal(a.VPCa, input.VpcID)
+				a.Assert().Equal(model.DatabaseEngineTypePostgres, input.DatabaseType)
+				a.Assert().Equal(model.NoInstallationsLimit, input.MaxInstallationsLimit)
+			}).
+			Return([]*model.MultitenantDatabase{{ID: "database1", State: model.DatabaseStateStable}}, nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetInstallationsTotalDatabaseWeight(gomock.Any()).
+			Return(float64(DefaultRDSMultitenantDatabasePostgresCountLimit/2), nil),
+	)
+
+	err := database.ensureMultitenantDatabaseCapacity(a.VPCa, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestEnsureMultitenantDatabaseCapacityCreatesCluster() {
+	database := a.newAutoProvisioningMultitenantDatabase()
+
+	var created *model.MultitenantDatabase
+	gomock.InOrder(
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetMultitenantDatabases(gomock.Any()).
+			Return([]*model.MultitenantDatabase{{ID: "database1", State: model.DatabaseStateStable}}, nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetInstallationsTotalDatabaseWeight(gomock.Any()).
+			Return(float64(DefaultRDSMultitenantDatabasePostgresCountLimit-10), nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			CreateMultitenantDatabase(gomock.Any()).
+			Do(func(input *model.MultitenantDatabase) {
+				a.Assert().True(strings.HasPrefix(input.RdsClusterID, RDSMultitenantDBClusterResourceNamePrefix))
+				a.Assert().Equal(a.VPCa, input.VpcID)
+				a.Assert().Equal(model.DatabaseEngineTypePostgres, input.DatabaseType)
+				a.Assert().Equal(model.DatabaseStateCreationRequested, input.State)
+				input.ID = "database2"
+				created = input
+			}).
+			Return(nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			LockMultitenantDatabase("database2", a.InstanceID).
+			Return(true, nil),
+		a.Mocks.API.SecretsManager.EXPECT().
+			GetSecretValue(gomock.Any()).
+			Return(nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)),
+		a.Mocks.API.SecretsManager.EXPECT().
+			CreateSecret(gomock.Any()).
+			Do(func(input *secretsmanager.CreateSecretInput) {
+				a.Assert().Equal(created.RdsClusterID, *input.Name)
+				a.Assert().Len(*input.SecretString, 40)
+			}).
+			Return(&secretsmanager.CreateSecretOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(nil, awserr.New(rds.ErrCodeDBClusterNotFoundFault, "not found", nil)),
+		a.Mocks.API.EC2.EXPECT().
+			DescribeSecurityGroups(gomock.Any()).
+			Return(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []*ec2.SecurityGroup{{GroupId: &a.GroupID}}}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBSubnetGroups(gomock.Any()).
+			Return(&rds.DescribeDBSubnetGroupsOutput{DBSubnetGroups: []*rds.DBSubnetGroup{{DBSubnetGroupName: aws.String(DBSubnetGroupName(a.VPCa))}}}, nil),
+		a.Mocks.API.EC2.EXPECT().
+			DescribeAvailabilityZones(gomock.Any()).
+			Return(&ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{{ZoneName: aws.String("us-honk-1a")}, {ZoneName: aws.String("us-honk-1b")}}}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			CreateDBCluster(gomock.Any()).
+			Do(func(input *rds.CreateDBClusterInput) {
+				a.Assert().Equal(created.RdsClusterID, *input.DBClusterIdentifier)
+				a.Assert().Equal("aurora-postgresql", *input.Engine)
+				a.Assert().Equal(DefaultMattermostDatabaseUsername, *input.MasterUsername)
+				a.Assert().Nil(input.KmsKeyId)
+
+				tags := map[string]string{}
+				for _, tag := range input.Tags {
+					tags[*tag.Key] = *tag.Value
+				}
+				a.Assert().Equal(map[string]string{
+					"Purpose":                             "provisioning",
+					"Owner":                               "cloud-team",
+					"DatabaseType":                        "multitenant-rds",
+					"VpcID":                               a.VPCa,
+					"MattermostCloudInstallationDatabase": DatabaseTypePostgresSQLAurora,
+					"Counter":                             "0",
+					"MultitenantDatabaseID":               created.RdsClusterID,
+				}, tags)
+			}).
+			Return(&rds.CreateDBClusterOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBInstances(gomock.Any()).
+			Return(nil, errors.New("not found")),
+		a.Mocks.API.RDS.EXPECT().
+			CreateDBInstance(gomock.Any()).
+			Do(func(input *rds.CreateDBInstanceInput) {
+				a.Assert().Equal(created.RdsClusterID+"-master", *input.DBInstanceIdentifier)
+				a.Assert().Equal("db.r5.large", *input.DBInstanceClass)
+			}).
+			Return(&rds.CreateDBInstanceOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBInstances(gomock.Any()).
+			Return(nil, errors.New("not found")),
+		a.Mocks.API.RDS.EXPECT().
+			CreateDBInstance(gomock.Any()).
+			Do(func(input *rds.CreateDBInstanceInput) {
+				a.Assert().Equal(created.RdsClusterID+"-replica-0", *input.DBInstanceIdentifier)
+			}).
+			Return(&rds.CreateDBInstanceOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{DBClusters: []*rds.DBCluster{{Status: aws.String("creating")}}}, nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			UnlockMultitenantDatabase("database2", a.InstanceID, true).
+			Return(true, nil),
+	)
+
+	err := database.ensureMultitenantDatabaseCapacity(a.VPCa, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+	a.Assert().Equal(model.DatabaseStateCreationRequested, created.State)
+}
+
+func (a *AWSTestSuite) TestEnsureMultitenantDatabaseCapacityCompletesCreation() {
+	database := a.newAutoProvisioningMultitenantDatabase()
+
+	creating := &model.MultitenantDatabase{
+		ID:           "database2",
+		RdsClusterID: a.RDSClusterID,
+		VpcID:        a.VPCa,
+		DatabaseType: model.DatabaseEngineTypePostgres,
+		State:        model.DatabaseStateCreationRequested,
+	}
+
+	gomock.InOrder(
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetMultitenantDatabases(gomock.Any()).
+			Return([]*model.MultitenantDatabase{creating}, nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			LockMultitenantDatabase("database2", a.InstanceID).
+			Return(true, nil),
+		a.Mocks.API.SecretsManager.EXPECT().
+			GetSecretValue(gomock.Any()).
+			Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String("password")}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBInstances(gomock.Any()).
+			Return(&rds.DescribeDBInstancesOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBInstances(gomock.Any()).
+			Return(&rds.DescribeDBInstancesOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{DBClusters: []*rds.DBCluster{{
+				Status:         aws.String(DefaultRDSStatusAvailable),
+				Endpoint:       aws.String("writer.example.com"),
+				ReaderEndpoint: aws.String("reader.example.com"),
+			}}}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusterEndpoints(gomock.Any()).
+			Return(&rds.DescribeDBClusterEndpointsOutput{DBClusterEndpoints: []*rds.DBClusterEndpoint{{Status: aws.String(DefaultRDSStatusAvailable)}}}, nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			UpdateMultitenantDatabase(gomock.Any()).
+			Do(func(input *model.MultitenantDatabase) {
+				a.Assert().Equal(model.DatabaseStateStable, input.State)
+				a.Assert().Equal("writer.example.com", input.WriterEndpoint)
+				a.Assert().Equal("reader.example.com", input.ReaderEndpoint)
+			}).
+			Return(nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			UnlockMultitenantDatabase("database2", a.InstanceID, true).
+			Return(true, nil),
+		// The new database is empty, so no other cluster is created.
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetInstallationsTotalDatabaseWeight(gomock.Any()).
+			Return(float64(0), nil),
+	)
+
+	err := database.ensureMultitenantDatabaseCapacity(a.VPCa, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+	a.Assert().Equal(model.DatabaseStateStable, creating.State)
+}
+
+func (a *AWSTestSuite) TestEnsureMultitenantDatabaseCapacityProxyBelowThreshold() {
+	database := a.newAutoProvisioningMultitenantDatabase()
+	database.databaseType = model.DatabaseEngineTypePostgresProxy
+
+	gomock.InOrder(
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetMultitenantDatabases(gomock.Any()).
+			Do(func(input *model.MultitenantDatabaseFilter) {
+				a.Assert().Equal(model.DatabaseEngineTypePostgresProxy, input.DatabaseType)
+			}).
+			Return([]*model.MultitenantDatabase{{ID: "database1", State: model.DatabaseStateStable}}, nil),
+		// Proxy databases hold more installations than the other PostgreSQL
+		// multitenant databases.
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetInstallationsTotalDatabaseWeight(gomock.Any()).
+			Return(float64(DefaultRDSMultitenantDatabasePostgresCountLimit), nil),
+	)
+
+	err := database.ensureMultitenantDatabaseCapacity(a.VPCa, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestEnsureMultitenantDatabaseCapacityCompletesProxyCreation() {
+	database := a.newAutoProvisioningMultitenantDatabase()
+	database.databaseType = model.DatabaseEngineTypePostgresProxy
+
+	creating := &model.MultitenantDatabase{
+		ID:                                 "database2",
+		RdsClusterID:                       a.RDSClusterID,
+		VpcID:                              a.VPCa,
+		DatabaseType:                       model.DatabaseEngineTypePostgresProxy,
+		State:                              model.DatabaseStateCreationRequested,
+		MaxInstallationsPerLogicalDatabase: model.GetDefaultProxyDatabaseMaxInstallationsPerLogicalDatabase(),
+	}
+
+	gomock.InOrder(
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetMultitenantDatabases(gomock.Any()).
+			Return([]*model.MultitenantDatabase{creating}, nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			LockMultitenantDatabase("database2", a.InstanceID).
+			Return(true, nil),
+		a.Mocks.API.SecretsManager.EXPECT().
+			GetSecretValue(gomock.Any()).
+			Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String("password")}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBInstances(gomock.Any()).
+			Return(&rds.DescribeDBInstancesOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBInstances(gomock.Any()).
+			Return(&rds.DescribeDBInstancesOutput{}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{DBClusters: []*rds.DBCluster{{
+				Status:         aws.String(DefaultRDSStatusAvailable),
+				Endpoint:       aws.String("writer.example.com"),
+				ReaderEndpoint: aws.String("reader.example.com"),
+			}}}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusterEndpoints(gomock.Any()).
+			Return(&rds.DescribeDBClusterEndpointsOutput{DBClusterEndpoints: []*rds.DBClusterEndpoint{{Status: aws.String(DefaultRDSStatusAvailable)}}}, nil),
+		// The PgBouncer auth user is set up when the first installation is
+		// assigned to the database.
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			UpdateMultitenantDatabase(gomock.Any()).
+			Do(func(input *model.MultitenantDatabase) {
+				a.Assert().Equal(model.DatabaseStateProvisioningRequested, input.State)
+			}).
+			Return(nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			UnlockMultitenantDatabase("database2", a.InstanceID, true).
+			Return(true, nil),
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetInstallationsTotalDatabaseWeight(gomock.Any()).
+			Return(float64(0), nil),
+	)
+
+	err := database.ensureMultitenantDatabaseCapacity(a.VPCa, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+	a.Assert().Equal(model.DatabaseStateProvisioningRequested, creating.State)
+}
+
+func (a *AWSTestSuite) TestAssignInstallationToMultitenantDatabaseSkipsDatabasesBeingCreated() {
+	database := a.newAutoProvisioningMultitenantDatabase()
+
+	gomock.InOrder(
+		a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+			GetMultitenantDatabases(gomock.Any()).
+			Return([]*model.MultitenantDatabase{{ID: "database2", State: model.DatabaseStateCreationRequested}}, nil),
+		// RDS clusters tagged for multitenant use are still looked up.
+		a.Mocks.API.ResourceGroupsTagging.EXPECT().
+			GetResources(gomock.Any()).
+			Return(&gt.GetResourcesOutput{}, nil),
+	)
+
+	_, _, err := database.assignInstallationToMultitenantDatabaseAndLock(a.VPCa, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().EqualError(err, "no multitenant databases are currently available for new installations")
+}
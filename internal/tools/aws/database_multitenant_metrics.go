@@ -0,0 +1,174 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// GetInstallationDatabaseMetrics returns the observed database usage of an
+// installation on a multitenant RDS database, including PGBouncer ones. Nil
+// metrics are returned for other database types.
+func (a *Client) GetInstallationDatabaseMetrics(installation *model.Installation, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*model.InstallationDatabaseMetrics, error) {
+	var databaseType string
+	switch installation.Database {
+	case model.InstallationDatabaseMultiTenantRDSPostgres:
+		databaseType = model.DatabaseEngineTypePostgres
+	case model.InstallationDatabaseMultiTenantRDSMySQL:
+		databaseType = model.DatabaseEngineTypeMySQL
+	case model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer:
+		database := NewRDSMultitenantPGBouncerDatabase(model.DatabaseEngineTypePostgresProxy, "", installation.ID, a)
+
+		return database.getDatabaseMetrics(store, logger)
+	default:
+		return nil, nil
+	}
+
+	database := NewRDSMultitenantDatabase(databaseType, "", installation.ID, a)
+
+	return database.getDatabaseMetrics(installation.DatabaseMetrics, store, logger)
+}
+
+// getDatabaseMetrics queries the multitenant RDS cluster for the size,
+// connections and transactions of the installation database. The previous
+// metrics are used to calculate the query rate.
+func (d *RDSMultitenantDatabase) getDatabaseMetrics(previous *model.InstallationDatabaseMetrics, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*model.InstallationDatabaseMetrics, error) {
+	multitenantDatabase, err := store.GetMultitenantDatabaseForInstallationID(d.installationID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get multitenant database for installation")
+	}
+
+	rdsCluster, err := describeRDSCluster(multitenantDatabase.RdsClusterID, d.client)
+	if err != nil {
+		return nil, err
+	}
+
+	masterSecretValue, err := d.client.Service().secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: rdsCluster.DBClusterIdentifier,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get master secret by ID %s", *rdsCluster.DBClusterIdentifier)
+	}
+
+	close, err := d.connectRDSCluster(*rdsCluster.Endpoint, DefaultMattermostDatabaseUsername, *masterSecretValue.SecretString)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to multitenant RDS cluster ID %s", *rdsCluster.DBClusterIdentifier)
+	}
+	defer close(logger)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(DefaultMySQLContextTimeSeconds*time.Second))
+	defer cancel()
+
+	databaseName := MattermostRDSDatabaseName(d.installationID)
+	metrics := &model.InstallationDatabaseMetrics{CollectAt: model.GetMillis()}
+
+	if d.databaseType == model.DatabaseEngineTypeMySQL {
+		err = queryInt64(ctx, d.db, &metrics.SizeBytes, "SELECT COALESCE(SUM(data_length + index_length), 0) FROM information_schema.TABLES WHERE table_schema = ?", databaseName)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get database size")
+		}
+		err = queryInt64(ctx, d.db, &metrics.Connections, "SELECT COUNT(*) FROM information_schema.PROCESSLIST WHERE DB = ?", databaseName)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get database connections")
+		}
+		// MySQL doesn't report transactions per schema, so the query rate is
+		// left empty.
+		return metrics, nil
+	}
+
+	err = queryInt64(ctx, d.db, &metrics.SizeBytes, "SELECT pg_database_size($1)", databaseName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get database size")
+	}
+	err = queryInt64(ctx, d.db, &metrics.Connections, "SELECT numbackends FROM pg_stat_database WHERE datname = $1", databaseName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get database connections")
+	}
+	err = queryInt64(ctx, d.db, &metrics.TransactionCount, "SELECT xact_commit + xact_rollback FROM pg_stat_database WHERE datname = $1", databaseName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get database transactions")
+	}
+
+	// The transaction counter is reset when statistics are reset or the
+	// cluster fails over, in which case the rate is calculated on the next
+	// collection.
+	if previous != nil && previous.CollectAt < metrics.CollectAt && previous.TransactionCount <= metrics.TransactionCount {
+		seconds := float64(metrics.CollectAt-previous.CollectAt) / 1000
+		metrics.QueriesPerSecond = float64(metrics.TransactionCount-previous.TransactionCount) / seconds
+	}
+
+	return metrics, nil
+}
+
+// getDatabaseMetrics queries the multitenant RDS cluster for the size and
+// connections of the installation schema. Installations share the logical
+// databases of PGBouncer databases, so the size is the one of the schema
+// owned by the installation and the connections are the ones of its database
+// users. PostgreSQL doesn't report transactions per schema, so the query rate
+// is left empty.
+func (d *RDSMultitenantPGBouncerDatabase) getDatabaseMetrics(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*model.InstallationDatabaseMetrics, error) {
+	dbResources, err := store.GetProxyDatabaseResourcesForInstallation(d.installationID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get proxy database resources for installation")
+	}
+	if dbResources == nil {
+		return nil, errors.New("no proxy database resources found for installation")
+	}
+
+	rdsCluster, err := describeRDSCluster(dbResources.MultitenantDatabase.RdsClusterID, d.client)
+	if err != nil {
+		return nil, err
+	}
+
+	masterSecretValue, err := d.client.Service().secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: rdsCluster.DBClusterIdentifier,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get master secret by ID %s", *rdsCluster.DBClusterIdentifier)
+	}
+
+	close, err := d.connectRDSCluster(dbResources.LogicalDatabase.Name, *rdsCluster.Endpoint, DefaultMattermostDatabaseUsername, *masterSecretValue.SecretString)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to multitenant RDS cluster ID %s", *rdsCluster.DBClusterIdentifier)
+	}
+	defer close(logger)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(DefaultMySQLContextTimeSeconds*time.Second))
+	defer cancel()
+
+	schemaName := MattermostPGBouncerDatabaseUsername(d.installationID)
+	metrics := &model.InstallationDatabaseMetrics{CollectAt: model.GetMillis()}
+
+	err = queryInt64(ctx, d.db, &metrics.SizeBytes, "SELECT COALESCE(SUM(pg_total_relation_size(c.oid)), 0) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relkind IN ('r', 'm')", schemaName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get database schema size")
+	}
+	err = queryInt64(ctx, d.db, &metrics.Connections, "SELECT COUNT(*) FROM pg_stat_activity WHERE datname = $1 AND usename IN ($2, $3)", dbResources.LogicalDatabase.Name, schemaName, MattermostPGBouncerDatabaseAlternateUsername(d.installationID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get database connections")
+	}
+
+	return metrics, nil
+}
+
+func queryInt64(ctx context.Context, db SQLDatabaseManager, value *int64, query string, args ...interface{}) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return errors.Wrap(err, "failed to run SQL query")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return errors.New("query returned no rows")
+	}
+
+	return rows.Scan(value)
+}
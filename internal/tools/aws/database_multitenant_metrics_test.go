@@ -0,0 +1,30 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+func (a *AWSTestSuite) TestGetInstallationDatabaseMetricsOtherDatabase() {
+	installation := &model.Installation{ID: a.InstallationA.ID, Database: model.InstallationDatabaseSingleTenantRDSPostgres}
+
+	metrics, err := a.Mocks.AWS.GetInstallationDatabaseMetrics(installation, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+	a.Assert().Nil(metrics)
+}
+
+func (a *AWSTestSuite) TestGetInstallationDatabaseMetricsPGBouncerWithoutSchema() {
+	installation := &model.Installation{ID: a.InstallationA.ID, Database: model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer}
+
+	a.Mocks.Model.DatabaseInstallationStore.EXPECT().
+		GetProxyDatabaseResourcesForInstallation(a.InstallationA.ID).
+		Return(nil, nil)
+
+	metrics, err := a.Mocks.AWS.GetInstallationDatabaseMetrics(installation, a.Mocks.Model.DatabaseInstallationStore, testlib.MakeLogger(a.T()))
+	a.Assert().EqualError(err, "no proxy database resources found for installation")
+	a.Assert().Nil(metrics)
+}
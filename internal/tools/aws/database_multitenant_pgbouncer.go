@@ -173,6 +173,7 @@ func (d *RDSMultitenantPGBouncerDatabase) assignInstallationToProxiedDatabaseAnd
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to get available multitenant databases")
 	}
+	multitenantDatabases = filterCreatedMultitenantDatabases(multitenantDatabases)
 
 	if len(multitenantDatabases) == 0 {
 		logger.Infof("No %s multitenant databases with less than %d installations found in the datastore; fetching all available resources from AWS", d.databaseType, d.MaxSupportedDatabases())
@@ -190,12 +191,19 @@ func (d *RDSMultitenantPGBouncerDatabase) assignInstallationToProxiedDatabaseAnd
 	// We want to be smart about how we assign the installation to a database.
 	// Find the database with the most installations on it to keep utilization
 	// as close to maximim efficiency as possible.
-	selectedDatabase := &model.MultitenantDatabase{}
+	var selectedDatabase *model.MultitenantDatabase
 	for _, multitenantDatabase := range multitenantDatabases {
-		if multitenantDatabase.Installations.Count() >= selectedDatabase.Installations.Count() {
+		// Databases being upgraded don't accept new installations.
+		if multitenantDatabase.IsUpgrading() {
+			continue
+		}
+		if selectedDatabase == nil || multitenantDatabase.Installations.Count() >= selectedDatabase.Installations.Count() {
 			selectedDatabase = multitenantDatabase
 		}
 	}
+	if selectedDatabase == nil {
+		return nil, nil, errors.New("no multitenant proxy databases are currently available for new installations; all of them are being upgraded")
+	}
 
 	unlockFn, err := lockMultitenantDatabase(selectedDatabase.ID, d.instanceID, store, logger)
 	if err != nil {
@@ -803,8 +811,9 @@ func (d *RDSMultitenantPGBouncerDatabase) removeInstallationPGBouncerResources(d
 	}
 
 	username := MattermostPGBouncerDatabaseUsername(d.installationID)
+	alternateUsername := MattermostPGBouncerDatabaseAlternateUsername(d.installationID)
 
-	err = d.cleanupDatabase(*rdsCluster.DBClusterIdentifier, *rdsCluster.Endpoint, dbResources.LogicalDatabase.Name, username, logger)
+	err = d.cleanupDatabase(*rdsCluster.DBClusterIdentifier, *rdsCluster.Endpoint, dbResources.LogicalDatabase.Name, username, alternateUsername, logger)
 	if err != nil {
 		return errors.Wrap(err, "failed to cleanup pgbouncer database")
 	}
@@ -823,7 +832,7 @@ func (d *RDSMultitenantPGBouncerDatabase) removeInstallationPGBouncerResources(d
 	return nil
 }
 
-func (d *RDSMultitenantPGBouncerDatabase) cleanupDatabase(rdsClusterID, rdsClusterendpoint, databaseName, installationUsername string, logger log.FieldLogger) error {
+func (d *RDSMultitenantPGBouncerDatabase) cleanupDatabase(rdsClusterID, rdsClusterendpoint, databaseName, installationUsername, alternateUsername string, logger log.FieldLogger) error {
 	masterSecretValue, err := d.client.Service().secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
 		SecretId: aws.String(rdsClusterID),
 	})
@@ -845,14 +854,16 @@ func (d *RDSMultitenantPGBouncerDatabase) cleanupDatabase(rdsClusterID, rdsClust
 		return errors.Wrap(err, "failed to delete installation schema")
 	}
 
-	err = dropUserIfExists(ctx, d.db, installationUsername)
-	if err != nil {
-		return errors.Wrap(err, "failed to delete installation database user")
-	}
+	for _, username := range []string{alternateUsername, installationUsername} {
+		err = dropUserIfExists(ctx, d.db, username)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete installation database user")
+		}
 
-	err = d.deleteInstallationUsernameEntry(ctx, installationUsername)
-	if err != nil {
-		return errors.Wrap(err, "failed to remove installation database user from pgbouncer table")
+		err = d.deleteInstallationUsernameEntry(ctx, username)
+		if err != nil {
+			return errors.Wrap(err, "failed to remove installation database user from pgbouncer table")
+		}
 	}
 
 	return nil
@@ -895,6 +906,153 @@ func (d *RDSMultitenantPGBouncerDatabase) RollbackMigration(store model.Installa
 	return errors.New("rolling back db migration is not supported for PGBouncer database")
 }
 
+// RotateCredentials switches the installation to the other one of its two
+// database users with a new password, updates the PGBouncer user entries and
+// stores the password as the current version of the installation secret. The
+// user in use before stays valid until the previous credentials are revoked.
+func (d *RDSMultitenantPGBouncerDatabase) RotateCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	err := d.runCredentialsSQLCommands(store, logger, func(ctx context.Context, logger log.FieldLogger) error {
+		logger.Info("Rotating multitenant RDS PGBouncer database credentials")
+
+		err := rotateDatabaseUserSecret(
+			RDSMultitenantPGBouncerSecretName(d.installationID),
+			MattermostPGBouncerDatabaseUsername(d.installationID),
+			MattermostPGBouncerDatabaseAlternateUsername(d.installationID),
+			func(secret *RDSSecret) error {
+				return d.activateDatabaseUser(ctx, secret.MasterUsername, secret.MasterPassword)
+			},
+			d.client, logger)
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Multitenant RDS PGBouncer database credentials rotated")
+
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate installation database secret")
+	}
+
+	return nil
+}
+
+// RevokePreviousCredentials deactivates the database user that was used
+// before the last credential rotation, removes its PGBouncer user entry and
+// removes it from the installation secret.
+func (d *RDSMultitenantPGBouncerDatabase) RevokePreviousCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	err := d.runCredentialsSQLCommands(store, logger, func(ctx context.Context, logger log.FieldLogger) error {
+		return revokePreviousDatabaseUserSecret(RDSMultitenantPGBouncerSecretName(d.installationID), func(username string) error {
+			return d.deactivateDatabaseUser(ctx, username)
+		}, d.client, logger)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke previous installation database secret")
+	}
+
+	return nil
+}
+
+// runCredentialsSQLCommands locks the multitenant database of the
+// installation and runs the given credential changes while connected to its
+// logical database as the master user.
+func (d *RDSMultitenantPGBouncerDatabase) runCredentialsSQLCommands(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger, run func(ctx context.Context, logger log.FieldLogger) error) error {
+	err := d.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "pgbouncer database configuration is invalid")
+	}
+
+	dbResources, err := store.GetProxyDatabaseResourcesForInstallation(d.installationID)
+	if err != nil {
+		return errors.Wrap(err, "failed to query for database resources")
+	}
+	if dbResources == nil {
+		return errors.New("no database resources found for this installation; it potentially has not been assigned yet")
+	}
+
+	unlock, err := lockMultitenantDatabase(dbResources.MultitenantDatabase.ID, d.instanceID, store, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock multitenant database")
+	}
+	defer unlock()
+
+	logger = logger.WithFields(log.Fields{
+		"multitenant-database":  dbResources.MultitenantDatabase.ID,
+		"rds-cluster-id":        dbResources.MultitenantDatabase.RdsClusterID,
+		"logical-database-name": dbResources.LogicalDatabase.Name,
+	})
+
+	rdsCluster, err := describeRDSCluster(dbResources.MultitenantDatabase.RdsClusterID, d.client)
+	if err != nil {
+		return errors.Wrap(err, "failed to describe rds cluster")
+	}
+
+	rdsID := *rdsCluster.DBClusterIdentifier
+
+	masterSecretValue, err := d.client.Service().secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: rdsCluster.DBClusterIdentifier,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to find the master secret for the multitenant proxy cluster %s", rdsID)
+	}
+
+	close, err := d.connectRDSCluster(dbResources.LogicalDatabase.Name, *rdsCluster.Endpoint, DefaultMattermostDatabaseUsername, *masterSecretValue.SecretString)
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to the multitenant proxy cluster %s", rdsID)
+	}
+	defer close(logger)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(DefaultMySQLContextTimeSeconds*time.Second))
+	defer cancel()
+
+	return run(ctx, logger)
+}
+
+// activateDatabaseUser makes sure that one of the two database users of the
+// installation exists, can log in with the given password and has a PGBouncer
+// user entry, which PGBouncer uses to authenticate clients. The alternate
+// user acts as the original user, which owns the installation schema.
+func (d *RDSMultitenantPGBouncerDatabase) activateDatabaseUser(ctx context.Context, username, password string) error {
+	err := d.ensureDatabaseUserIsCreated(ctx, username, password)
+	if err != nil {
+		return errors.Wrap(err, "failed to create database user")
+	}
+
+	query := fmt.Sprintf("ALTER USER %s WITH LOGIN PASSWORD '%s'", username, password)
+	_, err = d.db.QueryContext(ctx, query)
+	if err != nil {
+		return errors.New("failed to run alter user SQL command: error suppressed")
+	}
+
+	owner := MattermostPGBouncerDatabaseUsername(d.installationID)
+	if username != owner {
+		err = ensureDatabaseUserActsAsOwner(ctx, d.db, username, owner)
+		if err != nil {
+			return err
+		}
+	}
+
+	query = fmt.Sprintf(`INSERT INTO pgbouncer.pgbouncer_users (usename, passwd) VALUES ('%s', 'md5%x') ON CONFLICT (usename) DO UPDATE SET passwd = EXCLUDED.passwd`, username, md5.Sum([]byte(password+username)))
+	_, err = d.db.QueryContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "failed to run upsert pgbouncer installation user SQL command")
+	}
+
+	return nil
+}
+
+// deactivateDatabaseUser prevents a database user of the installation from
+// logging in and removes its PGBouncer user entry. The user is kept so that it
+// can be activated again by the next credential rotation.
+func (d *RDSMultitenantPGBouncerDatabase) deactivateDatabaseUser(ctx context.Context, username string) error {
+	err := d.deleteInstallationUsernameEntry(ctx, username)
+	if err != nil {
+		return errors.Wrap(err, "failed to remove database user from pgbouncer table")
+	}
+
+	return deactivatePostgresUser(ctx, d.db, username)
+}
+
 // RefreshResourceMetadata ensures various operator database resource's metadata
 // are correct.
 func (d *RDSMultitenantPGBouncerDatabase) RefreshResourceMetadata(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
@@ -0,0 +1,166 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// rdsSnapshotStatusFailed is the status of a DB cluster snapshot that
+	// could not be created.
+	rdsSnapshotStatusFailed = "failed"
+	// rdsSnapshotStatusDeleting is the status of a DB cluster snapshot that
+	// is being deleted.
+	rdsSnapshotStatusDeleting = "deleting"
+)
+
+// SnapshotMultitenantDatabase creates a snapshot with the given ID of the RDS
+// cluster of a multitenant database. A snapshot that already exists is left
+// untouched.
+func (a *Client) SnapshotMultitenantDatabase(database *model.MultitenantDatabase, snapshotID string, logger log.FieldLogger) error {
+	logger = logger.WithFields(log.Fields{
+		"rds-cluster-id":  database.RdsClusterID,
+		"rds-snapshot-id": snapshotID,
+		"database-type":   database.DatabaseType,
+	})
+
+	_, err := a.Service().rds.CreateDBClusterSnapshot(&rds.CreateDBClusterSnapshotInput{
+		DBClusterIdentifier:         aws.String(database.RdsClusterID),
+		DBClusterSnapshotIdentifier: aws.String(snapshotID),
+	})
+	if err != nil {
+		if IsErrorCode(err, rds.ErrCodeDBClusterSnapshotAlreadyExistsFault) {
+			logger.Debug("RDS cluster upgrade snapshot already exists")
+			return nil
+		}
+		return errors.Wrap(err, "failed to create a DB cluster snapshot")
+	}
+
+	logger.Info("RDS cluster upgrade snapshot in progress")
+
+	return nil
+}
+
+// IsMultitenantDatabaseSnapshotAvailable returns true once the DB cluster
+// snapshot with the given ID is available. An error is returned if the
+// snapshot failed or is being deleted.
+func (a *Client) IsMultitenantDatabaseSnapshotAvailable(snapshotID string) (bool, error) {
+	output, err := a.Service().rds.DescribeDBClusterSnapshots(&rds.DescribeDBClusterSnapshotsInput{
+		DBClusterSnapshotIdentifier: aws.String(snapshotID),
+	})
+	if err != nil {
+		return false, errors.Wrapf(err, "failed to describe DB cluster snapshot %s", snapshotID)
+	}
+	if len(output.DBClusterSnapshots) != 1 {
+		return false, errors.Errorf("expected exactly one DB cluster snapshot %s (found %d)", snapshotID, len(output.DBClusterSnapshots))
+	}
+
+	status := aws.StringValue(output.DBClusterSnapshots[0].Status)
+	switch status {
+	case DefaultRDSStatusAvailable:
+		return true, nil
+	case rdsSnapshotStatusFailed, rdsSnapshotStatusDeleting:
+		return false, errors.Errorf("DB cluster snapshot %s is in unexpected status %s", snapshotID, status)
+	}
+
+	return false, nil
+}
+
+// UpgradeMultitenantDatabaseEngine starts an in-place upgrade of the RDS
+// cluster of a multitenant database to its target engine version. The target
+// version must be a valid upgrade target of the current engine version.
+func (a *Client) UpgradeMultitenantDatabaseEngine(database *model.MultitenantDatabase, logger log.FieldLogger) error {
+	logger = logger.WithFields(log.Fields{
+		"rds-cluster-id":        database.RdsClusterID,
+		"target-engine-version": database.TargetEngineVersion,
+	})
+
+	rdsCluster, err := describeRDSCluster(database.RdsClusterID, a)
+	if err != nil {
+		return err
+	}
+	if aws.StringValue(rdsCluster.EngineVersion) == database.TargetEngineVersion {
+		logger.Info("RDS cluster already runs the target engine version")
+		return nil
+	}
+	if aws.StringValue(rdsCluster.Status) != DefaultRDSStatusAvailable {
+		return errors.Errorf("RDS cluster %s is not available (status: %s)", database.RdsClusterID, aws.StringValue(rdsCluster.Status))
+	}
+
+	engineVersions, err := a.Service().rds.DescribeDBEngineVersions(&rds.DescribeDBEngineVersionsInput{
+		Engine:        rdsCluster.Engine,
+		EngineVersion: rdsCluster.EngineVersion,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to describe current engine version")
+	}
+	if len(engineVersions.DBEngineVersions) != 1 {
+		return errors.Errorf("expected exactly one engine version %s %s (found %d)", aws.StringValue(rdsCluster.Engine), aws.StringValue(rdsCluster.EngineVersion), len(engineVersions.DBEngineVersions))
+	}
+
+	var target *rds.UpgradeTarget
+	for _, upgradeTarget := range engineVersions.DBEngineVersions[0].ValidUpgradeTarget {
+		if aws.StringValue(upgradeTarget.EngineVersion) == database.TargetEngineVersion {
+			target = upgradeTarget
+			break
+		}
+	}
+	if target == nil {
+		return errors.Errorf("engine version %s is not a valid upgrade target of %s %s", database.TargetEngineVersion, aws.StringValue(rdsCluster.Engine), aws.StringValue(rdsCluster.EngineVersion))
+	}
+
+	_, err = a.Service().rds.ModifyDBCluster(&rds.ModifyDBClusterInput{
+		DBClusterIdentifier:      aws.String(database.RdsClusterID),
+		EngineVersion:            aws.String(database.TargetEngineVersion),
+		AllowMajorVersionUpgrade: aws.Bool(aws.BoolValue(target.IsMajorVersionUpgrade)),
+		ApplyImmediately:         aws.Bool(true),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to modify DB cluster engine version")
+	}
+
+	logger.WithField("current-engine-version", aws.StringValue(rdsCluster.EngineVersion)).Info("RDS cluster engine upgrade started")
+
+	return nil
+}
+
+// IsMultitenantDatabaseEngineUpgraded returns true once the RDS cluster of a
+// multitenant database and all of its instances are available and run the
+// target engine version.
+func (a *Client) IsMultitenantDatabaseEngineUpgraded(database *model.MultitenantDatabase) (bool, error) {
+	rdsCluster, err := describeRDSCluster(database.RdsClusterID, a)
+	if err != nil {
+		return false, err
+	}
+	if aws.StringValue(rdsCluster.Status) != DefaultRDSStatusAvailable ||
+		aws.StringValue(rdsCluster.EngineVersion) != database.TargetEngineVersion {
+		return false, nil
+	}
+
+	output, err := a.Service().rds.DescribeDBInstances(&rds.DescribeDBInstancesInput{
+		Filters: []*rds.Filter{
+			{
+				Name:   aws.String("db-cluster-id"),
+				Values: []*string{aws.String(database.RdsClusterID)},
+			},
+		},
+	})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to describe DB cluster instances")
+	}
+	for _, instance := range output.DBInstances {
+		if aws.StringValue(instance.DBInstanceStatus) != DefaultRDSStatusAvailable ||
+			aws.StringValue(instance.EngineVersion) != database.TargetEngineVersion {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
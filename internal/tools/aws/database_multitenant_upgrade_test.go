@@ -0,0 +1,194 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+)
+
+func (a *AWSTestSuite) TestSnapshotMultitenantDatabase() {
+	database := &model.MultitenantDatabase{RdsClusterID: a.RDSClusterID}
+
+	a.Mocks.API.RDS.EXPECT().
+		CreateDBClusterSnapshot(&rds.CreateDBClusterSnapshotInput{
+			DBClusterIdentifier:         aws.String(a.RDSClusterID),
+			DBClusterSnapshotIdentifier: aws.String("snapshot-id"),
+		}).
+		Return(&rds.CreateDBClusterSnapshotOutput{}, nil).
+		Times(1)
+
+	err := a.Mocks.AWS.SnapshotMultitenantDatabase(database, "snapshot-id", testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestSnapshotMultitenantDatabaseAlreadyExists() {
+	database := &model.MultitenantDatabase{RdsClusterID: a.RDSClusterID}
+
+	a.Mocks.API.RDS.EXPECT().
+		CreateDBClusterSnapshot(gomock.Any()).
+		Return(nil, awserr.New(rds.ErrCodeDBClusterSnapshotAlreadyExistsFault, "already exists", nil)).
+		Times(1)
+
+	err := a.Mocks.AWS.SnapshotMultitenantDatabase(database, "snapshot-id", testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestIsMultitenantDatabaseSnapshotAvailable() {
+	expectSnapshotStatus := func(status string) {
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusterSnapshots(&rds.DescribeDBClusterSnapshotsInput{
+				DBClusterSnapshotIdentifier: aws.String("snapshot-id"),
+			}).
+			Return(&rds.DescribeDBClusterSnapshotsOutput{
+				DBClusterSnapshots: []*rds.DBClusterSnapshot{{Status: aws.String(status)}},
+			}, nil).
+			Times(1)
+	}
+
+	expectSnapshotStatus("creating")
+	available, err := a.Mocks.AWS.IsMultitenantDatabaseSnapshotAvailable("snapshot-id")
+	a.Assert().NoError(err)
+	a.Assert().False(available)
+
+	expectSnapshotStatus("available")
+	available, err = a.Mocks.AWS.IsMultitenantDatabaseSnapshotAvailable("snapshot-id")
+	a.Assert().NoError(err)
+	a.Assert().True(available)
+
+	expectSnapshotStatus("failed")
+	available, err = a.Mocks.AWS.IsMultitenantDatabaseSnapshotAvailable("snapshot-id")
+	a.Assert().Error(err)
+	a.Assert().False(available)
+}
+
+func (a *AWSTestSuite) TestUpgradeMultitenantDatabaseEngine() {
+	database := &model.MultitenantDatabase{
+		RdsClusterID:        a.RDSClusterID,
+		TargetEngineVersion: "13.7",
+	}
+
+	gomock.InOrder(
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{
+				DBClusters: []*rds.DBCluster{{
+					DBClusterIdentifier: aws.String(a.RDSClusterID),
+					Engine:              aws.String("aurora-postgresql"),
+					EngineVersion:       aws.String("12.8"),
+					Status:              aws.String(DefaultRDSStatusAvailable),
+				}},
+			}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBEngineVersions(&rds.DescribeDBEngineVersionsInput{
+				Engine:        aws.String("aurora-postgresql"),
+				EngineVersion: aws.String("12.8"),
+			}).
+			Return(&rds.DescribeDBEngineVersionsOutput{
+				DBEngineVersions: []*rds.DBEngineVersion{{
+					ValidUpgradeTarget: []*rds.UpgradeTarget{
+						{EngineVersion: aws.String("12.9"), IsMajorVersionUpgrade: aws.Bool(false)},
+						{EngineVersion: aws.String("13.7"), IsMajorVersionUpgrade: aws.Bool(true)},
+					},
+				}},
+			}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			ModifyDBCluster(&rds.ModifyDBClusterInput{
+				DBClusterIdentifier:      aws.String(a.RDSClusterID),
+				EngineVersion:            aws.String("13.7"),
+				AllowMajorVersionUpgrade: aws.Bool(true),
+				ApplyImmediately:         aws.Bool(true),
+			}).
+			Return(&rds.ModifyDBClusterOutput{}, nil),
+	)
+
+	err := a.Mocks.AWS.UpgradeMultitenantDatabaseEngine(database, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestUpgradeMultitenantDatabaseEngineInvalidTarget() {
+	database := &model.MultitenantDatabase{
+		RdsClusterID:        a.RDSClusterID,
+		TargetEngineVersion: "9.6",
+	}
+
+	gomock.InOrder(
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{
+				DBClusters: []*rds.DBCluster{{
+					Engine:        aws.String("aurora-postgresql"),
+					EngineVersion: aws.String("12.8"),
+					Status:        aws.String(DefaultRDSStatusAvailable),
+				}},
+			}, nil),
+		a.Mocks.API.RDS.EXPECT().
+			DescribeDBEngineVersions(gomock.Any()).
+			Return(&rds.DescribeDBEngineVersionsOutput{
+				DBEngineVersions: []*rds.DBEngineVersion{{
+					ValidUpgradeTarget: []*rds.UpgradeTarget{
+						{EngineVersion: aws.String("13.7"), IsMajorVersionUpgrade: aws.Bool(true)},
+					},
+				}},
+			}, nil),
+	)
+	a.Mocks.API.RDS.EXPECT().ModifyDBCluster(gomock.Any()).Times(0)
+
+	err := a.Mocks.AWS.UpgradeMultitenantDatabaseEngine(database, testlib.MakeLogger(a.T()))
+	a.Assert().Error(err)
+	a.Assert().Equal("engine version 9.6 is not a valid upgrade target of aurora-postgresql 12.8", err.Error())
+}
+
+func (a *AWSTestSuite) TestIsMultitenantDatabaseEngineUpgraded() {
+	database := &model.MultitenantDatabase{
+		RdsClusterID:        a.RDSClusterID,
+		TargetEngineVersion: "13.7",
+	}
+
+	expectCluster := func(status, engineVersion string) *gomock.Call {
+		return a.Mocks.API.RDS.EXPECT().
+			DescribeDBClusters(gomock.Any()).
+			Return(&rds.DescribeDBClustersOutput{
+				DBClusters: []*rds.DBCluster{{
+					EngineVersion: aws.String(engineVersion),
+					Status:        aws.String(status),
+				}},
+			}, nil)
+	}
+	expectInstances := func(status, engineVersion string) *gomock.Call {
+		return a.Mocks.API.RDS.EXPECT().
+			DescribeDBInstances(gomock.Any()).
+			Return(&rds.DescribeDBInstancesOutput{
+				DBInstances: []*rds.DBInstance{
+					{DBInstanceStatus: aws.String(DefaultRDSStatusAvailable), EngineVersion: aws.String("13.7")},
+					{DBInstanceStatus: aws.String(status), EngineVersion: aws.String(engineVersion)},
+				},
+			}, nil)
+	}
+
+	gomock.InOrder(
+		expectCluster("upgrading", "12.8"),
+		expectCluster(DefaultRDSStatusAvailable, "13.7"),
+		expectInstances("upgrading", "12.8"),
+		expectCluster(DefaultRDSStatusAvailable, "13.7"),
+		expectInstances(DefaultRDSStatusAvailable, "13.7"),
+	)
+
+	upgraded, err := a.Mocks.AWS.IsMultitenantDatabaseEngineUpgraded(database)
+	a.Assert().NoError(err)
+	a.Assert().False(upgraded)
+
+	upgraded, err = a.Mocks.AWS.IsMultitenantDatabaseEngineUpgraded(database)
+	a.Assert().NoError(err)
+	a.Assert().False(upgraded)
+
+	upgraded, err = a.Mocks.AWS.IsMultitenantDatabaseEngineUpgraded(database)
+	a.Assert().NoError(err)
+	a.Assert().True(upgraded)
+}
@@ -329,6 +329,23 @@ func (a *AWSTestSuite) SetExpectCreateDBInstance() {
 	)
 }
 
+func (a *AWSTestSuite) TestRDSDatabaseMigrateOut() {
+	database := NewRDSDatabase(model.DatabaseEngineTypeMySQL, a.InstallationA.ID, a.Mocks.AWS)
+	logger := testlib.MakeLogger(a.T())
+
+	err := database.MigrateOut(nil, &model.InstallationDBMigrationOperation{
+		SourceDatabase:      model.InstallationDatabaseSingleTenantRDSMySQL,
+		DestinationDatabase: model.InstallationDatabaseMultiTenantRDSPostgres,
+	}, logger)
+	a.Assert().NoError(err)
+
+	err = database.MigrateOut(nil, &model.InstallationDBMigrationOperation{
+		SourceDatabase:      model.InstallationDatabaseSingleTenantRDSPostgres,
+		DestinationDatabase: model.InstallationDatabaseMultiTenantRDSPostgres,
+	}, logger)
+	a.Assert().Error(err)
+}
+
 // WARNING:
 // This test is meant to exercise the provisioning and teardown of an AWS RDS
 // database in a real AWS account. Only set the test env vars below if you wish
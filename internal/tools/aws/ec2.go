@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -97,6 +98,59 @@ func (a *Client) IsValidAMI(AMIImage string, logger log.FieldLogger) (bool, erro
 	return true, nil
 }
 
+// IsValidInstanceType checks if the provided instance type is offered in the
+// region of the client.
+func (a *Client) IsValidInstanceType(instanceType string, logger log.FieldLogger) (bool, error) {
+	out, err := a.Service().ec2.DescribeInstanceTypes(&ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []*string{aws.String(instanceType)},
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "InvalidInstanceType" {
+			return false, nil
+		}
+		return false, err
+	}
+	if len(out.InstanceTypes) == 0 {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// GetSpotInstancesMarkedForTermination returns the IDs of the given instances
+// which are spot instances that received an interruption notice.
+func (a *Client) GetSpotInstancesMarkedForTermination(instanceIDs []string, logger log.FieldLogger) ([]string, error) {
+	if len(instanceIDs) == 0 {
+		return nil, nil
+	}
+
+	out, err := a.Service().ec2.DescribeSpotInstanceRequests(&ec2.DescribeSpotInstanceRequestsInput{
+		Filters: []*ec2.Filter{
+			{
+				Name:   aws.String("instance-id"),
+				Values: aws.StringSlice(instanceIDs),
+			},
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to describe spot instance requests")
+	}
+
+	var markedForTermination []string
+	for _, request := range out.SpotInstanceRequests {
+		if request.Status == nil || request.InstanceId == nil {
+			continue
+		}
+		switch *request.Status.Code {
+		case SpotStatusMarkedForTermination, SpotStatusMarkedForStop:
+			logger.WithField("instance", *request.InstanceId).Debugf("Spot instance has status %s", *request.Status.Code)
+			markedForTermination = append(markedForTermination, *request.InstanceId)
+		}
+	}
+
+	return markedForTermination, nil
+}
+
 // GetVpcsWithFilters returns VPCs matching a given filter.
 func (a *Client) GetVpcsWithFilters(filters []*ec2.Filter) ([]*ec2.Vpc, error) {
 	vpcOutput, err := a.Service().ec2.DescribeVpcs(&ec2.DescribeVpcsInput{
@@ -9,6 +9,8 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/golang/mock/gomock"
 	"github.com/mattermost/mattermost-cloud/internal/testlib"
@@ -112,6 +114,72 @@ func (a *AWSTestSuite) TestIsValidAMIError() {
 	a.Assert().Equal("resource id not found", err.Error())
 }
 
+func (a *AWSTestSuite) TestIsValidInstanceType() {
+	a.Mocks.API.EC2.EXPECT().
+		DescribeInstanceTypes(gomock.Any()).
+		Return(&ec2.DescribeInstanceTypesOutput{
+			InstanceTypes: make([]*ec2.InstanceTypeInfo, 1),
+		}, nil)
+
+	ok, err := a.Mocks.AWS.IsValidInstanceType("m5.large", a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+	a.Assert().True(ok)
+}
+
+func (a *AWSTestSuite) TestIsValidInstanceTypeInvalid() {
+	a.Mocks.API.EC2.EXPECT().
+		DescribeInstanceTypes(gomock.Any()).
+		Return(nil, awserr.New("InvalidInstanceType", "invalid instance type", nil))
+
+	ok, err := a.Mocks.AWS.IsValidInstanceType("m5.huge", a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+	a.Assert().False(ok)
+}
+
+func (a *AWSTestSuite) TestIsValidInstanceTypeError() {
+	a.Mocks.API.EC2.EXPECT().
+		DescribeInstanceTypes(gomock.Any()).
+		Return(nil, errors.New("request failed"))
+
+	ok, err := a.Mocks.AWS.IsValidInstanceType("m5.large", a.Mocks.Log.Logger)
+	a.Assert().Error(err)
+	a.Assert().False(ok)
+}
+
+func (a *AWSTestSuite) TestGetSpotInstancesMarkedForTermination() {
+	a.Mocks.API.EC2.EXPECT().
+		DescribeSpotInstanceRequests(gomock.Any()).
+		Return(&ec2.DescribeSpotInstanceRequestsOutput{
+			SpotInstanceRequests: []*ec2.SpotInstanceRequest{
+				{InstanceId: aws.String("i-1"), Status: &ec2.SpotInstanceStatus{Code: aws.String("fulfilled")}},
+				{InstanceId: aws.String("i-2"), Status: &ec2.SpotInstanceStatus{Code: aws.String(SpotStatusMarkedForTermination)}},
+				{InstanceId: aws.String("i-3"), Status: &ec2.SpotInstanceStatus{Code: aws.String(SpotStatusMarkedForStop)}},
+			},
+		}, nil)
+	a.Mocks.Log.Logger.EXPECT().
+		WithField("instance", gomock.Any()).
+		Return(testlib.NewLoggerEntry()).Times(2)
+
+	instanceIDs, err := a.Mocks.AWS.GetSpotInstancesMarkedForTermination([]string{"i-1", "i-2", "i-3"}, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+	a.Assert().Equal([]string{"i-2", "i-3"}, instanceIDs)
+}
+
+func (a *AWSTestSuite) TestGetSpotInstancesMarkedForTerminationNoInstances() {
+	instanceIDs, err := a.Mocks.AWS.GetSpotInstancesMarkedForTermination(nil, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+	a.Assert().Empty(instanceIDs)
+}
+
+func (a *AWSTestSuite) TestGetSpotInstancesMarkedForTerminationError() {
+	a.Mocks.API.EC2.EXPECT().
+		DescribeSpotInstanceRequests(gomock.Any()).
+		Return(nil, errors.New("request failed"))
+
+	_, err := a.Mocks.AWS.GetSpotInstancesMarkedForTermination([]string{"i-1"}, a.Mocks.Log.Logger)
+	a.Assert().Error(err)
+}
+
 func TestVPCReal(t *testing.T) {
 	if os.Getenv("SUPER_AWS_VPC_TEST") == "" {
 		return
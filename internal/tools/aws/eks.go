@@ -0,0 +1,342 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// eksTokenPrefix is the prefix of the bearer tokens accepted by the EKS
+	// API servers.
+	eksTokenPrefix = "k8s-aws-v1."
+	// eksClusterIDHeader is the header identifying the EKS cluster in the
+	// presigned token request.
+	eksClusterIDHeader = "x-k8s-aws-id"
+	// eksTokenPresignExpiry is how long the presigned token request is valid.
+	eksTokenPresignExpiry = 60 * time.Second
+)
+
+// EKSClusterConfig describes an EKS cluster control plane.
+type EKSClusterConfig struct {
+	Name             string
+	Version          string
+	RoleARN          string
+	SubnetIDs        []string
+	SecurityGroupIDs []string
+	Tags             map[string]string
+}
+
+// EKSNodeGroupConfig describes a managed EKS node group.
+type EKSNodeGroupConfig struct {
+	Name         string
+	Version      string
+	InstanceType string
+	MinCount     int64
+	MaxCount     int64
+	RoleARN      string
+	SubnetIDs    []string
+	Tags         map[string]string
+}
+
+// EnsureEKSCluster creates the EKS cluster described by the config if it
+// doesn't exist yet and returns it.
+func (a *Client) EnsureEKSCluster(config EKSClusterConfig, logger log.FieldLogger) (*eks.Cluster, error) {
+	cluster, err := a.GetEKSCluster(config.Name)
+	if err != nil {
+		return nil, err
+	}
+	if cluster != nil {
+		logger.WithField("eks-cluster", config.Name).Debug("EKS cluster already exists")
+		return cluster, nil
+	}
+
+	input := &eks.CreateClusterInput{
+		Name:    aws.String(config.Name),
+		RoleArn: aws.String(config.RoleARN),
+		ResourcesVpcConfig: &eks.VpcConfigRequest{
+			SubnetIds:        aws.StringSlice(config.SubnetIDs),
+			SecurityGroupIds: aws.StringSlice(config.SecurityGroupIDs),
+		},
+		Tags: aws.StringMap(config.Tags),
+	}
+	if len(config.Version) != 0 {
+		input.Version = aws.String(config.Version)
+	}
+
+	out, err := a.Service().eks.CreateCluster(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create EKS cluster %s", config.Name)
+	}
+	logger.WithField("eks-cluster", config.Name).Info("EKS cluster created")
+
+	return out.Cluster, nil
+}
+
+// GetEKSCluster returns the EKS cluster with the given name or nil if it
+// doesn't exist.
+func (a *Client) GetEKSCluster(name string) (*eks.Cluster, error) {
+	out, err := a.Service().eks.DescribeCluster(&eks.DescribeClusterInput{
+		Name: aws.String(name),
+	})
+	if IsErrorCode(err, eks.ErrCodeResourceNotFoundException) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe EKS cluster %s", name)
+	}
+
+	return out.Cluster, nil
+}
+
+// WaitForEKSClusterActive waits until the EKS cluster is active and returns
+// it.
+func (a *Client) WaitForEKSClusterActive(name string, timeout time.Duration) (*eks.Cluster, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := a.Service().eks.WaitUntilClusterActiveWithContext(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed waiting for EKS cluster %s to become active", name)
+	}
+
+	return a.GetEKSCluster(name)
+}
+
+// UpdateEKSClusterVersion starts the upgrade of the EKS cluster control plane
+// to the given k8s version.
+func (a *Client) UpdateEKSClusterVersion(name, version string, logger log.FieldLogger) error {
+	out, err := a.Service().eks.UpdateClusterVersion(&eks.UpdateClusterVersionInput{
+		Name:    aws.String(name),
+		Version: aws.String(version),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update EKS cluster %s to version %s", name, version)
+	}
+	logger.WithFields(log.Fields{
+		"eks-cluster": name,
+		"update-id":   aws.StringValue(out.Update.Id),
+	}).Infof("EKS cluster update to version %s started", version)
+
+	return nil
+}
+
+// EnsureEKSNodeGroup creates the managed EKS node group described by the
+// config if it doesn't exist yet and returns it.
+func (a *Client) EnsureEKSNodeGroup(clusterName string, config EKSNodeGroupConfig, logger log.FieldLogger) (*eks.Nodegroup, error) {
+	nodeGroup, err := a.GetEKSNodeGroup(clusterName, config.Name)
+	if err != nil {
+		return nil, err
+	}
+	if nodeGroup != nil {
+		logger.WithField("eks-node-group", config.Name).Debug("EKS node group already exists")
+		return nodeGroup, nil
+	}
+
+	input := &eks.CreateNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(config.Name),
+		NodeRole:      aws.String(config.RoleARN),
+		InstanceTypes: aws.StringSlice([]string{config.InstanceType}),
+		Subnets:       aws.StringSlice(config.SubnetIDs),
+		ScalingConfig: &eks.NodegroupScalingConfig{
+			MinSize:     aws.Int64(config.MinCount),
+			MaxSize:     aws.Int64(config.MaxCount),
+			DesiredSize: aws.Int64(config.MinCount),
+		},
+		Tags: aws.StringMap(config.Tags),
+	}
+	if len(config.Version) != 0 {
+		input.Version = aws.String(config.Version)
+	}
+
+	out, err := a.Service().eks.CreateNodegroup(input)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create EKS node group %s", config.Name)
+	}
+	logger.WithField("eks-node-group", config.Name).Info("EKS node group created")
+
+	return out.Nodegroup, nil
+}
+
+// GetEKSNodeGroup returns the managed EKS node group with the given name or
+// nil if it doesn't exist.
+func (a *Client) GetEKSNodeGroup(clusterName, nodeGroupName string) (*eks.Nodegroup, error) {
+	out, err := a.Service().eks.DescribeNodegroup(&eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+	})
+	if IsErrorCode(err, eks.ErrCodeResourceNotFoundException) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to describe EKS node group %s", nodeGroupName)
+	}
+
+	return out.Nodegroup, nil
+}
+
+// WaitForEKSNodeGroupActive waits until the managed EKS node group is active
+// and returns it.
+func (a *Client) WaitForEKSNodeGroupActive(clusterName, nodeGroupName string, timeout time.Duration) (*eks.Nodegroup, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err := a.Service().eks.WaitUntilNodegroupActiveWithContext(ctx, &eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed waiting for EKS node group %s to become active", nodeGroupName)
+	}
+
+	return a.GetEKSNodeGroup(clusterName, nodeGroupName)
+}
+
+// UpdateEKSNodeGroupVersion starts the rolling upgrade of the nodes of the
+// managed EKS node group to the given k8s version.
+func (a *Client) UpdateEKSNodeGroupVersion(clusterName, nodeGroupName, version string, logger log.FieldLogger) error {
+	out, err := a.Service().eks.UpdateNodegroupVersion(&eks.UpdateNodegroupVersionInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+		Version:       aws.String(version),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update EKS node group %s to version %s", nodeGroupName, version)
+	}
+	logger.WithFields(log.Fields{
+		"eks-node-group": nodeGroupName,
+		"update-id":      aws.StringValue(out.Update.Id),
+	}).Infof("EKS node group update to version %s started", version)
+
+	return nil
+}
+
+// UpdateEKSNodeGroupScaling starts the update of the node counts of the
+// managed EKS node group.
+func (a *Client) UpdateEKSNodeGroupScaling(clusterName, nodeGroupName string, minCount, maxCount int64, logger log.FieldLogger) error {
+	out, err := a.Service().eks.UpdateNodegroupConfig(&eks.UpdateNodegroupConfigInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+		ScalingConfig: &eks.NodegroupScalingConfig{
+			MinSize:     aws.Int64(minCount),
+			MaxSize:     aws.Int64(maxCount),
+			DesiredSize: aws.Int64(minCount),
+		},
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to update scaling of EKS node group %s", nodeGroupName)
+	}
+	logger.WithFields(log.Fields{
+		"eks-node-group": nodeGroupName,
+		"update-id":      aws.StringValue(out.Update.Id),
+	}).Infof("EKS node group scaling update to min %d and max %d started", minCount, maxCount)
+
+	return nil
+}
+
+// EnsureEKSNodeGroupDeleted deletes the managed EKS node group and waits for
+// the deletion to complete.
+func (a *Client) EnsureEKSNodeGroupDeleted(clusterName, nodeGroupName string, timeout time.Duration, logger log.FieldLogger) error {
+	_, err := a.Service().eks.DeleteNodegroup(&eks.DeleteNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+	})
+	if IsErrorCode(err, eks.ErrCodeResourceNotFoundException) {
+		logger.WithField("eks-node-group", nodeGroupName).Debug("EKS node group already deleted")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete EKS node group %s", nodeGroupName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err = a.Service().eks.WaitUntilNodegroupDeletedWithContext(ctx, &eks.DescribeNodegroupInput{
+		ClusterName:   aws.String(clusterName),
+		NodegroupName: aws.String(nodeGroupName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed waiting for EKS node group %s to be deleted", nodeGroupName)
+	}
+	logger.WithField("eks-node-group", nodeGroupName).Info("EKS node group deleted")
+
+	return nil
+}
+
+// EnsureEKSClusterDeleted deletes all managed node groups of the EKS cluster,
+// then the cluster itself, and waits for the deletion to complete.
+func (a *Client) EnsureEKSClusterDeleted(name string, timeout time.Duration, logger log.FieldLogger) error {
+	var nodeGroupNames []string
+	err := a.Service().eks.ListNodegroupsPages(&eks.ListNodegroupsInput{
+		ClusterName: aws.String(name),
+	}, func(out *eks.ListNodegroupsOutput, lastPage bool) bool {
+		nodeGroupNames = append(nodeGroupNames, aws.StringValueSlice(out.Nodegroups)...)
+		return true
+	})
+	if IsErrorCode(err, eks.ErrCodeResourceNotFoundException) {
+		logger.WithField("eks-cluster", name).Debug("EKS cluster already deleted")
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to list node groups of EKS cluster %s", name)
+	}
+
+	for _, nodeGroupName := range nodeGroupNames {
+		err = a.EnsureEKSNodeGroupDeleted(name, nodeGroupName, timeout, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = a.Service().eks.DeleteCluster(&eks.DeleteClusterInput{
+		Name: aws.String(name),
+	})
+	if IsErrorCode(err, eks.ErrCodeResourceNotFoundException) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete EKS cluster %s", name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	err = a.Service().eks.WaitUntilClusterDeletedWithContext(ctx, &eks.DescribeClusterInput{
+		Name: aws.String(name),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed waiting for EKS cluster %s to be deleted", name)
+	}
+	logger.WithField("eks-cluster", name).Info("EKS cluster deleted")
+
+	return nil
+}
+
+// GetEKSClusterToken returns a bearer token authenticating the AWS identity
+// of the client against the API server of the EKS cluster. The token is a
+// presigned STS GetCallerIdentity request and is valid for 15 minutes.
+func (a *Client) GetEKSClusterToken(name string) (string, error) {
+	request, _ := a.Service().sts.GetCallerIdentityRequest(&sts.GetCallerIdentityInput{})
+	request.HTTPRequest.Header.Add(eksClusterIDHeader, name)
+
+	presignedURL, err := request.Presign(eksTokenPresignExpiry)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to presign caller identity request")
+	}
+
+	return eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presignedURL)), nil
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/eks"
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/pkg/errors"
+)
+
+const defaultEKSTestTimeout = time.Minute
+
+func (a *AWSTestSuite) TestEnsureEKSClusterCreate() {
+	config := EKSClusterConfig{
+		Name:             "cluster-eks",
+		Version:          "1.21",
+		RoleARN:          "arn:aws:iam::123456789012:role/eks-cluster",
+		SubnetIDs:        []string{"subnet-1", "subnet-2"},
+		SecurityGroupIDs: []string{"sg-1"},
+		Tags:             map[string]string{"CloudClusterID": a.ClusterA.ID},
+	}
+
+	gomock.InOrder(
+		a.Mocks.API.EKS.EXPECT().
+			DescribeCluster(&eks.DescribeClusterInput{Name: aws.String(config.Name)}).
+			Return(nil, awserr.New(eks.ErrCodeResourceNotFoundException, "not found", nil)).
+			Times(1),
+		a.Mocks.API.EKS.EXPECT().
+			CreateCluster(&eks.CreateClusterInput{
+				Name:    aws.String(config.Name),
+				Version: aws.String(config.Version),
+				RoleArn: aws.String(config.RoleARN),
+				ResourcesVpcConfig: &eks.VpcConfigRequest{
+					SubnetIds:        aws.StringSlice(config.SubnetIDs),
+					SecurityGroupIds: aws.StringSlice(config.SecurityGroupIDs),
+				},
+				Tags: aws.StringMap(config.Tags),
+			}).
+			Return(&eks.CreateClusterOutput{Cluster: &eks.Cluster{Name: aws.String(config.Name)}}, nil).
+			Times(1),
+		a.Mocks.Log.Logger.EXPECT().
+			WithField("eks-cluster", config.Name).
+			Return(testlib.NewLoggerEntry()).
+			Times(1),
+	)
+
+	cluster, err := a.Mocks.AWS.EnsureEKSCluster(config, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+	a.Assert().Equal(config.Name, *cluster.Name)
+}
+
+func (a *AWSTestSuite) TestEnsureEKSClusterExists() {
+	gomock.InOrder(
+		a.Mocks.API.EKS.EXPECT().
+			DescribeCluster(gomock.Any()).
+			Return(&eks.DescribeClusterOutput{Cluster: &eks.Cluster{
+				Name:   aws.String("cluster-eks"),
+				Status: aws.String(eks.ClusterStatusActive),
+			}}, nil).
+			Times(1),
+		a.Mocks.Log.Logger.EXPECT().
+			WithField("eks-cluster", "cluster-eks").
+			Return(testlib.NewLoggerEntry()).
+			Times(1),
+	)
+	a.Mocks.API.EKS.EXPECT().CreateCluster(gomock.Any()).Times(0)
+
+	cluster, err := a.Mocks.AWS.EnsureEKSCluster(EKSClusterConfig{Name: "cluster-eks"}, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+	a.Assert().Equal(eks.ClusterStatusActive, *cluster.Status)
+}
+
+func (a *AWSTestSuite) TestEnsureEKSClusterDescribeError() {
+	a.Mocks.API.EKS.EXPECT().
+		DescribeCluster(gomock.Any()).
+		Return(nil, errors.New("throttled")).
+		Times(1)
+
+	_, err := a.Mocks.AWS.EnsureEKSCluster(EKSClusterConfig{Name: "cluster-eks"}, a.Mocks.Log.Logger)
+	a.Assert().Error(err)
+	a.Assert().Contains(err.Error(), "failed to describe EKS cluster cluster-eks")
+}
+
+func (a *AWSTestSuite) TestEnsureEKSNodeGroupCreate() {
+	config := EKSNodeGroupConfig{
+		Name:         "nodes-m5-large",
+		InstanceType: "m5.large",
+		MinCount:     2,
+		MaxCount:     4,
+		RoleARN:      "arn:aws:iam::123456789012:role/eks-node",
+		SubnetIDs:    []string{"subnet-1"},
+	}
+
+	gomock.InOrder(
+		a.Mocks.API.EKS.EXPECT().
+			DescribeNodegroup(&eks.DescribeNodegroupInput{
+				ClusterName:   aws.String("cluster-eks"),
+				NodegroupName: aws.String(config.Name),
+			}).
+			Return(nil, awserr.New(eks.ErrCodeResourceNotFoundException, "not found", nil)).
+			Times(1),
+		a.Mocks.API.EKS.EXPECT().
+			CreateNodegroup(&eks.CreateNodegroupInput{
+				ClusterName:   aws.String("cluster-eks"),
+				NodegroupName: aws.String(config.Name),
+				NodeRole:      aws.String(config.RoleARN),
+				InstanceTypes: aws.StringSlice([]string{"m5.large"}),
+				Subnets:       aws.StringSlice(config.SubnetIDs),
+				ScalingConfig: &eks.NodegroupScalingConfig{
+					MinSize:     aws.Int64(2),
+					MaxSize:     aws.Int64(4),
+					DesiredSize: aws.Int64(2),
+				},
+				Tags: aws.StringMap(nil),
+			}).
+			Return(&eks.CreateNodegroupOutput{Nodegroup: &eks.Nodegroup{NodegroupName: aws.String(config.Name)}}, nil).
+			Times(1),
+		a.Mocks.Log.Logger.EXPECT().
+			WithField("eks-node-group", config.Name).
+			Return(testlib.NewLoggerEntry()).
+			Times(1),
+	)
+
+	nodeGroup, err := a.Mocks.AWS.EnsureEKSNodeGroup("cluster-eks", config, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+	a.Assert().Equal(config.Name, *nodeGroup.NodegroupName)
+}
+
+func (a *AWSTestSuite) TestUpdateEKSNodeGroupScaling() {
+	gomock.InOrder(
+		a.Mocks.API.EKS.EXPECT().
+			UpdateNodegroupConfig(&eks.UpdateNodegroupConfigInput{
+				ClusterName:   aws.String("cluster-eks"),
+				NodegroupName: aws.String("nodes"),
+				ScalingConfig: &eks.NodegroupScalingConfig{
+					MinSize:     aws.Int64(3),
+					MaxSize:     aws.Int64(6),
+					DesiredSize: aws.Int64(3),
+				},
+			}).
+			Return(&eks.UpdateNodegroupConfigOutput{Update: &eks.Update{Id: aws.String("update-1")}}, nil).
+			Times(1),
+		a.Mocks.Log.Logger.EXPECT().
+			WithFields(gomock.Any()).
+			Return(testlib.NewLoggerEntry()).
+			Times(1),
+	)
+
+	err := a.Mocks.AWS.UpdateEKSNodeGroupScaling("cluster-eks", "nodes", 3, 6, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestEnsureEKSClusterDeleted() {
+	gomock.InOrder(
+		a.Mocks.API.EKS.EXPECT().
+			ListNodegroupsPages(&eks.ListNodegroupsInput{ClusterName: aws.String("cluster-eks")}, gomock.Any()).
+			DoAndReturn(func(input *eks.ListNodegroupsInput, fn func(*eks.ListNodegroupsOutput, bool) bool) error {
+				fn(&eks.ListNodegroupsOutput{Nodegroups: aws.StringSlice([]string{"nodes"})}, true)
+				return nil
+			}).
+			Times(1),
+		a.Mocks.API.EKS.EXPECT().
+			DeleteNodegroup(&eks.DeleteNodegroupInput{
+				ClusterName:   aws.String("cluster-eks"),
+				NodegroupName: aws.String("nodes"),
+			}).
+			Return(&eks.DeleteNodegroupOutput{}, nil).
+			Times(1),
+		a.Mocks.API.EKS.EXPECT().
+			WaitUntilNodegroupDeletedWithContext(gomock.Any(), gomock.Any()).
+			Return(nil).
+			Times(1),
+		a.Mocks.Log.Logger.EXPECT().
+			WithField("eks-node-group", "nodes").
+			Return(testlib.NewLoggerEntry()).
+			Times(1),
+		a.Mocks.API.EKS.EXPECT().
+			DeleteCluster(&eks.DeleteClusterInput{Name: aws.String("cluster-eks")}).
+			Return(&eks.DeleteClusterOutput{}, nil).
+			Times(1),
+		a.Mocks.API.EKS.EXPECT().
+			WaitUntilClusterDeletedWithContext(gomock.Any(), &eks.DescribeClusterInput{Name: aws.String("cluster-eks")}).
+			Return(nil).
+			Times(1),
+		a.Mocks.Log.Logger.EXPECT().
+			WithField("eks-cluster", "cluster-eks").
+			Return(testlib.NewLoggerEntry()).
+			Times(1),
+	)
+
+	err := a.Mocks.AWS.EnsureEKSClusterDeleted("cluster-eks", defaultEKSTestTimeout, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestEnsureEKSClusterDeletedNotFound() {
+	gomock.InOrder(
+		a.Mocks.API.EKS.EXPECT().
+			ListNodegroupsPages(gomock.Any(), gomock.Any()).
+			Return(awserr.New(eks.ErrCodeResourceNotFoundException, "not found", nil)).
+			Times(1),
+		a.Mocks.Log.Logger.EXPECT().
+			WithField("eks-cluster", "cluster-eks").
+			Return(testlib.NewLoggerEntry()).
+			Times(1),
+	)
+	a.Mocks.API.EKS.EXPECT().DeleteCluster(gomock.Any()).Times(0)
+
+	err := a.Mocks.AWS.EnsureEKSClusterDeleted("cluster-eks", defaultEKSTestTimeout, a.Mocks.Log.Logger)
+	a.Assert().NoError(err)
+}
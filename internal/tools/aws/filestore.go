@@ -107,6 +107,34 @@ func (f *S3Filestore) GenerateFilestoreSpecAndSecret(store model.InstallationDat
 	return filestoreConfig, filestoreSecret, nil
 }
 
+// RotateAccessKey replaces the IAM access key of the S3 filestore user with a
+// new one that has been verified to access the bucket.
+func (f *S3Filestore) RotateAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	awsID := CloudID(f.installationID)
+
+	logger = logger.WithField("s3-bucket-name", awsID)
+
+	err := rotateFilestoreAccessKey(awsID, func(accessKey *IAMAccessKey) error {
+		return f.awsClient.verifyAccessKeyS3Access(accessKey, awsID, "", logger)
+	}, f.awsClient, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate AWS S3 filestore access key")
+	}
+
+	return nil
+}
+
+// RevokePreviousAccessKey deletes the IAM access keys of the S3 filestore
+// user that were replaced by a rotation.
+func (f *S3Filestore) RevokePreviousAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	err := revokePreviousFilestoreAccessKey(CloudID(f.installationID), f.awsClient, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke previous AWS S3 filestore access key")
+	}
+
+	return nil
+}
+
 // s3FilestoreProvision provisions an S3 filestore for an installation.
 func (f *S3Filestore) s3FilestoreProvision(installationID string, logger log.FieldLogger) error {
 	logger.Info("Provisioning AWS S3 filestore")
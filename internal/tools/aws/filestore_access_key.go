@@ -0,0 +1,309 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// accessKeyCheckObjectName is the name of the object written and read back to
+// verify that a new access key grants access to a filestore.
+const accessKeyCheckObjectName = ".cloud-access-key-check"
+
+// rotateFilestoreAccessKey rotates the IAM access key of an installation
+// filestore user following the Secrets Manager rotation steps. The previous
+// key stays active until it is revoked, so pods still using it keep working
+// while they roll:
+//  1. A new access key is created and stored as the pending version of the
+//     IAM access key secret.
+//  2. The pending access key is verified to grant access to the files of the
+//     installation.
+//  3. The pending version is promoted to the current version, which makes
+//     the old access key the previous version until it is revoked.
+//
+// New IAM access keys take a few seconds to become usable, so a failed
+// verification is not retried here. The pending version is reused when the
+// rotation is retried, and no new rotation is started while the previous
+// version was not revoked yet, so the key the pods use is never deleted by a
+// retry.
+func rotateFilestoreAccessKey(awsID string, verifyAccessKey func(accessKey *IAMAccessKey) error, client *Client, logger log.FieldLogger) error {
+	secretName := IAMSecretName(awsID)
+	logger = logger.WithFields(log.Fields{
+		"iam-user-name": awsID,
+		"secret-name":   secretName,
+	})
+
+	secret, err := client.Service().secretsManager.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe secret %s", secretName)
+	}
+
+	pendingVersionID := findSecretVersionWithStage(secret.VersionIdsToStages, secretVersionStagePending)
+	if pendingVersionID == "" && findSecretVersionWithStage(secret.VersionIdsToStages, secretVersionStagePrevious) != "" {
+		logger.Info("Previous filestore access key was not revoked yet; resuming the last rotation")
+		return nil
+	}
+
+	pendingVersionID, pendingKey, err := client.ensurePendingIAMAccessKeySecretVersion(awsID, pendingVersionID, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to ensure pending access key")
+	}
+	logger = logger.WithField("iam-access-key-id", pendingKey.ID)
+
+	err = verifyAccessKey(pendingKey)
+	if err != nil {
+		return errors.Wrap(err, "failed to verify pending access key")
+	}
+
+	err = promotePendingSecretVersion(secretName, pendingVersionID, client)
+	if err != nil {
+		return errors.Wrap(err, "failed to promote pending secret version")
+	}
+
+	logger.Info("Filestore access key rotated")
+
+	return nil
+}
+
+// revokePreviousFilestoreAccessKey deletes all access keys of an installation
+// filestore user except the current one and then removes the previous version
+// stage of the IAM access key secret.
+func revokePreviousFilestoreAccessKey(awsID string, client *Client, logger log.FieldLogger) error {
+	secretName := IAMSecretName(awsID)
+	logger = logger.WithFields(log.Fields{
+		"iam-user-name": awsID,
+		"secret-name":   secretName,
+	})
+
+	secret, err := client.Service().secretsManager.DescribeSecret(&secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretName),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to describe secret %s", secretName)
+	}
+
+	previousVersionID := findSecretVersionWithStage(secret.VersionIdsToStages, secretVersionStagePrevious)
+	if previousVersionID == "" {
+		logger.Debug("IAM access key secret has no previous version; assuming already revoked")
+		return nil
+	}
+
+	currentKey, err := client.secretsManagerGetIAMAccessKey(awsID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get current access key")
+	}
+
+	err = client.iamEnsureOtherAccessKeysDeleted(awsID, currentKey.ID, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete previous access keys")
+	}
+
+	_, err = client.Service().secretsManager.UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:            aws.String(secretName),
+		VersionStage:        aws.String(secretVersionStagePrevious),
+		RemoveFromVersionId: aws.String(previousVersionID),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to remove previous version stage from secret %s", secretName)
+	}
+
+	logger.Info("Previous filestore access key revoked")
+
+	return nil
+}
+
+// ensurePendingIAMAccessKeySecretVersion returns the pending version of an
+// IAM access key secret. If there is none yet, a new access key is created
+// and stored as the pending version.
+func (a *Client) ensurePendingIAMAccessKeySecretVersion(awsID, pendingVersionID string, logger log.FieldLogger) (string, *IAMAccessKey, error) {
+	secretName := IAMSecretName(awsID)
+
+	if pendingVersionID != "" {
+		pendingKey, err := a.secretsManagerGetIAMAccessKeyVersion(secretName, pendingVersionID)
+		if err != nil {
+			return "", nil, errors.Wrap(err, "failed to get pending secret version")
+		}
+
+		return pendingVersionID, pendingKey, nil
+	}
+
+	currentKey, err := a.secretsManagerGetIAMAccessKey(awsID)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to get current access key")
+	}
+
+	// Without a pending or previous version the current key is the only one
+	// that was handed to pods, so any other key was left behind by a rotation
+	// interrupted before storing it. IAM users can only have two access keys.
+	err = a.iamEnsureOtherAccessKeysDeleted(awsID, currentKey.ID, logger)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to delete stale access keys")
+	}
+
+	result, err := a.Service().iam.CreateAccessKey(&iam.CreateAccessKeyInput{
+		UserName: aws.String(awsID),
+	})
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to create access key")
+	}
+	pendingKey := &IAMAccessKey{
+		ID:     *result.AccessKey.AccessKeyId,
+		Secret: *result.AccessKey.SecretAccessKey,
+	}
+	logger.WithField("iam-access-key-id", pendingKey.ID).Info("AWS IAM user access key created")
+
+	pendingVersionID, err = a.secretsManagerPutPendingIAMAccessKeySecret(secretName, pendingKey)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to store pending access key")
+	}
+
+	return pendingVersionID, pendingKey, nil
+}
+
+// iamEnsureOtherAccessKeysDeleted deletes all access keys of an IAM user
+// except the given one.
+func (a *Client) iamEnsureOtherAccessKeysDeleted(awsID, keepAccessKeyID string, logger log.FieldLogger) error {
+	listResult, err := a.Service().iam.ListAccessKeys(&iam.ListAccessKeysInput{
+		UserName: aws.String(awsID),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, ak := range listResult.AccessKeyMetadata {
+		if *ak.AccessKeyId == keepAccessKeyID {
+			continue
+		}
+
+		_, err = a.Service().iam.DeleteAccessKey(&iam.DeleteAccessKeyInput{
+			AccessKeyId: ak.AccessKeyId,
+			UserName:    aws.String(awsID),
+		})
+		if err != nil {
+			return err
+		}
+
+		logger.WithField("iam-access-key-id", *ak.AccessKeyId).Info("AWS IAM user access key deleted")
+	}
+
+	return nil
+}
+
+// verifyAccessKeyS3Access writes, reads back and deletes an object under the
+// given path prefix using the given access key.
+func (a *Client) verifyAccessKeyS3Access(accessKey *IAMAccessKey, bucketName, pathPrefix string, logger log.FieldLogger) error {
+	sess, err := session.NewSession(a.config.Copy().WithCredentials(
+		credentials.NewStaticCredentials(accessKey.ID, accessKey.Secret, ""),
+	))
+	if err != nil {
+		return errors.Wrap(err, "failed to create AWS session")
+	}
+	s3Client := s3.New(sess)
+
+	key := path.Join(pathPrefix, accessKeyCheckObjectName)
+	content := []byte(accessKey.ID)
+
+	_, err = s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(content),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to write object")
+	}
+
+	result, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to read object")
+	}
+	defer result.Body.Close()
+
+	readContent, err := ioutil.ReadAll(result.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read object content")
+	}
+	if !bytes.Equal(content, readContent) {
+		return errors.New("read object content doesn't match written content")
+	}
+
+	_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to delete object")
+	}
+
+	logger.Debug("Verified access key can read and write filestore")
+
+	return nil
+}
+
+// secretsManagerPutPendingIAMAccessKeySecret stores an access key as the
+// pending version of an IAM access key secret and returns the version ID.
+func (a *Client) secretsManagerPutPendingIAMAccessKeySecret(secretName string, accessKey *IAMAccessKey) (string, error) {
+	err := accessKey.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(accessKey)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to marshal secrets manager payload")
+	}
+
+	result, err := a.Service().secretsManager.PutSecretValue(&secretsmanager.PutSecretValueInput{
+		SecretId:      aws.String(secretName),
+		SecretString:  aws.String(string(b)),
+		VersionStages: aws.StringSlice([]string{secretVersionStagePending}),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to update secrets manager secret")
+	}
+
+	return *result.VersionId, nil
+}
+
+// secretsManagerGetIAMAccessKeyVersion returns the access key stored in a
+// version of an IAM access key secret.
+func (a *Client) secretsManagerGetIAMAccessKeyVersion(secretName, versionID string) (*IAMAccessKey, error) {
+	result, err := a.Service().secretsManager.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId:  aws.String(secretName),
+		VersionId: aws.String(versionID),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get secrets manager secret")
+	}
+
+	var iamAccessKey *IAMAccessKey
+	err = json.Unmarshal([]byte(*result.SecretString), &iamAccessKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to marshal secrets manager payload")
+	}
+
+	err = iamAccessKey.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return iamAccessKey, nil
+}
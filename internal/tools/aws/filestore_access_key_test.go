@@ -0,0 +1,245 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/golang/mock/gomock"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/pkg/errors"
+)
+
+func (a *AWSTestSuite) TestRotateFilestoreAccessKey() {
+	awsID := CloudID(a.InstallationA.ID)
+	secretName := IAMSecretName(awsID)
+
+	for _, testCase := range []struct {
+		description     string
+		pendingVersion  bool
+		verifyErr       error
+		expectPromotion bool
+		expectErr       bool
+	}{
+		{
+			description:     "new access key",
+			expectPromotion: true,
+		},
+		{
+			description:     "pending access key of an earlier attempt",
+			pendingVersion:  true,
+			expectPromotion: true,
+		},
+		{
+			description: "pending access key not usable yet",
+			verifyErr:   errors.New("access denied"),
+			expectErr:   true,
+		},
+	} {
+		a.T().Run(testCase.description, func(t *testing.T) {
+			a.SetupTest()
+
+			versionIDsToStages := map[string][]*string{
+				"current": aws.StringSlice([]string{secretVersionStageCurrent}),
+			}
+			if testCase.pendingVersion {
+				versionIDsToStages["pending"] = aws.StringSlice([]string{secretVersionStagePending})
+			}
+			calls := []*gomock.Call{
+				a.Mocks.API.SecretsManager.EXPECT().
+					DescribeSecret(&secretsmanager.DescribeSecretInput{SecretId: aws.String(secretName)}).
+					Return(&secretsmanager.DescribeSecretOutput{VersionIdsToStages: versionIDsToStages}, nil),
+			}
+			if testCase.pendingVersion {
+				calls = append(calls, a.Mocks.API.SecretsManager.EXPECT().
+					GetSecretValue(&secretsmanager.GetSecretValueInput{
+						SecretId:  aws.String(secretName),
+						VersionId: aws.String("pending"),
+					}).
+					Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"ID":"new-key","Secret":"new-secret"}`)}, nil))
+			} else {
+				calls = append(calls,
+					a.Mocks.API.SecretsManager.EXPECT().
+						GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(secretName)}).
+						Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"ID":"current-key","Secret":"current-secret"}`)}, nil),
+					a.Mocks.API.IAM.EXPECT().
+						ListAccessKeys(&iam.ListAccessKeysInput{UserName: aws.String(awsID)}).
+						Return(&iam.ListAccessKeysOutput{
+							AccessKeyMetadata: []*iam.AccessKeyMetadata{
+								{AccessKeyId: aws.String("current-key")},
+								{AccessKeyId: aws.String("stale-key")},
+							},
+						}, nil),
+					a.Mocks.API.IAM.EXPECT().
+						DeleteAccessKey(&iam.DeleteAccessKeyInput{
+							AccessKeyId: aws.String("stale-key"),
+							UserName:    aws.String(awsID),
+						}).
+						Return(&iam.DeleteAccessKeyOutput{}, nil),
+					a.Mocks.API.IAM.EXPECT().
+						CreateAccessKey(&iam.CreateAccessKeyInput{UserName: aws.String(awsID)}).
+						Return(&iam.CreateAccessKeyOutput{
+							AccessKey: &iam.AccessKey{
+								AccessKeyId:     aws.String("new-key"),
+								SecretAccessKey: aws.String("new-secret"),
+							},
+						}, nil),
+					a.Mocks.API.SecretsManager.EXPECT().
+						PutSecretValue(&secretsmanager.PutSecretValueInput{
+							SecretId:      aws.String(secretName),
+							SecretString:  aws.String(`{"ID":"new-key","Secret":"new-secret"}`),
+							VersionStages: aws.StringSlice([]string{secretVersionStagePending}),
+						}).
+						Return(&secretsmanager.PutSecretValueOutput{VersionId: aws.String("pending")}, nil),
+				)
+			}
+			if testCase.expectPromotion {
+				calls = append(calls,
+					a.Mocks.API.SecretsManager.EXPECT().
+						DescribeSecret(gomock.Any()).
+						Return(&secretsmanager.DescribeSecretOutput{
+							VersionIdsToStages: map[string][]*string{
+								"current": aws.StringSlice([]string{secretVersionStageCurrent}),
+								"pending": aws.StringSlice([]string{secretVersionStagePending}),
+							},
+						}, nil),
+					a.Mocks.API.SecretsManager.EXPECT().
+						UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+							SecretId:            aws.String(secretName),
+							VersionStage:        aws.String(secretVersionStageCurrent),
+							MoveToVersionId:     aws.String("pending"),
+							RemoveFromVersionId: aws.String("current"),
+						}).
+						Return(&secretsmanager.UpdateSecretVersionStageOutput{}, nil),
+					a.Mocks.API.SecretsManager.EXPECT().
+						UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+							SecretId:            aws.String(secretName),
+							VersionStage:        aws.String(secretVersionStagePending),
+							RemoveFromVersionId: aws.String("pending"),
+						}).
+						Return(&secretsmanager.UpdateSecretVersionStageOutput{}, nil),
+				)
+			}
+			gomock.InOrder(calls...)
+
+			var verifiedKey *IAMAccessKey
+			err := rotateFilestoreAccessKey(awsID, func(accessKey *IAMAccessKey) error {
+				verifiedKey = accessKey
+				return testCase.verifyErr
+			}, a.Mocks.AWS, testlib.MakeLogger(t))
+			if testCase.expectErr {
+				a.Assert().Error(err)
+			} else {
+				a.Assert().NoError(err)
+			}
+			a.Assert().Equal(&IAMAccessKey{ID: "new-key", Secret: "new-secret"}, verifiedKey)
+		})
+	}
+}
+
+func (a *AWSTestSuite) TestRotateFilestoreAccessKeyPreviousNotRevoked() {
+	awsID := CloudID(a.InstallationA.ID)
+
+	// A rotation which was promoted but not revoked yet is not repeated, so
+	// the previous access key the pods may still use is not deleted.
+	a.Mocks.API.SecretsManager.EXPECT().
+		DescribeSecret(gomock.Any()).
+		Return(&secretsmanager.DescribeSecretOutput{
+			VersionIdsToStages: map[string][]*string{
+				"current":  aws.StringSlice([]string{secretVersionStageCurrent}),
+				"previous": aws.StringSlice([]string{secretVersionStagePrevious}),
+			},
+		}, nil).
+		Times(1)
+
+	err := rotateFilestoreAccessKey(awsID, func(accessKey *IAMAccessKey) error {
+		a.Fail("no access key must be verified")
+		return nil
+	}, a.Mocks.AWS, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestRevokePreviousFilestoreAccessKey() {
+	awsID := CloudID(a.InstallationA.ID)
+	secretName := IAMSecretName(awsID)
+
+	gomock.InOrder(
+		a.Mocks.API.SecretsManager.EXPECT().
+			DescribeSecret(&secretsmanager.DescribeSecretInput{SecretId: aws.String(secretName)}).
+			Return(&secretsmanager.DescribeSecretOutput{
+				VersionIdsToStages: map[string][]*string{
+					"current":  aws.StringSlice([]string{secretVersionStageCurrent}),
+					"previous": aws.StringSlice([]string{secretVersionStagePrevious}),
+				},
+			}, nil),
+		a.Mocks.API.SecretsManager.EXPECT().
+			GetSecretValue(&secretsmanager.GetSecretValueInput{SecretId: aws.String(secretName)}).
+			Return(&secretsmanager.GetSecretValueOutput{SecretString: aws.String(`{"ID":"current-key","Secret":"current-secret"}`)}, nil),
+		a.Mocks.API.IAM.EXPECT().
+			ListAccessKeys(&iam.ListAccessKeysInput{UserName: aws.String(awsID)}).
+			Return(&iam.ListAccessKeysOutput{
+				AccessKeyMetadata: []*iam.AccessKeyMetadata{
+					{AccessKeyId: aws.String("previous-key")},
+					{AccessKeyId: aws.String("current-key")},
+				},
+			}, nil),
+		a.Mocks.API.IAM.EXPECT().
+			DeleteAccessKey(&iam.DeleteAccessKeyInput{
+				AccessKeyId: aws.String("previous-key"),
+				UserName:    aws.String(awsID),
+			}).
+			Return(&iam.DeleteAccessKeyOutput{}, nil),
+		a.Mocks.API.SecretsManager.EXPECT().
+			UpdateSecretVersionStage(&secretsmanager.UpdateSecretVersionStageInput{
+				SecretId:            aws.String(secretName),
+				VersionStage:        aws.String(secretVersionStagePrevious),
+				RemoveFromVersionId: aws.String("previous"),
+			}).
+			Return(&secretsmanager.UpdateSecretVersionStageOutput{}, nil),
+	)
+
+	err := revokePreviousFilestoreAccessKey(awsID, a.Mocks.AWS, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestRevokePreviousFilestoreAccessKeyAlreadyRevoked() {
+	awsID := CloudID(a.InstallationA.ID)
+
+	a.Mocks.API.SecretsManager.EXPECT().
+		DescribeSecret(gomock.Any()).
+		Return(&secretsmanager.DescribeSecretOutput{
+			VersionIdsToStages: map[string][]*string{
+				"current": aws.StringSlice([]string{secretVersionStageCurrent}),
+			},
+		}, nil).
+		Times(1)
+	a.Mocks.API.IAM.EXPECT().DeleteAccessKey(gomock.Any()).Times(0)
+
+	err := revokePreviousFilestoreAccessKey(awsID, a.Mocks.AWS, testlib.MakeLogger(a.T()))
+	a.Assert().NoError(err)
+}
+
+func (a *AWSTestSuite) TestSecretsManagerPutPendingIAMAccessKeySecret() {
+	secretName := IAMSecretName(CloudID(a.InstallationA.ID))
+
+	a.Mocks.API.SecretsManager.EXPECT().
+		PutSecretValue(&secretsmanager.PutSecretValueInput{
+			SecretId:      aws.String(secretName),
+			SecretString:  aws.String(`{"ID":"new-key","Secret":"new-secret"}`),
+			VersionStages: aws.StringSlice([]string{secretVersionStagePending}),
+		}).
+		Return(&secretsmanager.PutSecretValueOutput{VersionId: aws.String("pending")}, nil).
+		Times(1)
+
+	versionID, err := a.Mocks.AWS.secretsManagerPutPendingIAMAccessKeySecret(secretName, &IAMAccessKey{ID: "new-key", Secret: "new-secret"})
+	a.Assert().NoError(err)
+	a.Assert().Equal("pending", versionID)
+
+	_, err = a.Mocks.AWS.secretsManagerPutPendingIAMAccessKeySecret(secretName, &IAMAccessKey{ID: "new-key"})
+	a.Assert().Error(err)
+}
@@ -133,6 +133,18 @@ func (f *BifrostFilestore) GenerateFilestoreSpecAndSecret(store model.Installati
 	return filestoreConfig, filestoreSecret, nil
 }
 
+// RotateAccessKey rotating access keys is not supported for bifrost
+// filestores, which don't use installation credentials.
+func (f *BifrostFilestore) RotateAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("rotating access keys is not supported for bifrost filestores")
+}
+
+// RevokePreviousAccessKey revoking access keys is not supported for bifrost
+// filestores, which don't use installation credentials.
+func (f *BifrostFilestore) RevokePreviousAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("revoking access keys is not supported for bifrost filestores")
+}
+
 // s3FilestoreProvision provisions a shared S3 filestore for an installation.
 func (f *BifrostFilestore) s3FilestoreProvision(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
 	awsID := CloudID(f.installationID)
@@ -146,6 +146,45 @@ func (f *S3MultitenantFilestore) GenerateFilestoreSpecAndSecret(store model.Inst
 	return filestoreConfig, filestoreSecret, nil
 }
 
+// RotateAccessKey replaces the IAM access key of the filestore user with a new
+// one that has been verified to access the installation directory of the
+// shared bucket.
+func (f *S3MultitenantFilestore) RotateAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	awsID := CloudID(f.installationID)
+
+	logger = logger.WithFields(log.Fields{
+		"awsID":          awsID,
+		"filestore-type": "s3-multitenant",
+	})
+
+	bucketName, err := f.awsClient.GetMultitenantBucketNameForInstallation(f.installationID, store)
+	if err != nil {
+		return errors.Wrap(err, "failed to find multitenant bucket")
+	}
+
+	logger = logger.WithField("s3-bucket-name", bucketName)
+
+	err = rotateFilestoreAccessKey(awsID, func(accessKey *IAMAccessKey) error {
+		return f.awsClient.verifyAccessKeyS3Access(accessKey, bucketName, f.installationID, logger)
+	}, f.awsClient, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate AWS multitenant S3 filestore access key")
+	}
+
+	return nil
+}
+
+// RevokePreviousAccessKey deletes the IAM access keys of the filestore user
+// that were replaced by a rotation.
+func (f *S3MultitenantFilestore) RevokePreviousAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	err := revokePreviousFilestoreAccessKey(CloudID(f.installationID), f.awsClient, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke previous AWS multitenant S3 filestore access key")
+	}
+
+	return nil
+}
+
 // s3FilestoreProvision provisions a shared S3 filestore for an installation.
 func (f *S3MultitenantFilestore) s3FilestoreProvision(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
 	awsID := CloudID(f.installationID)
@@ -103,12 +103,31 @@ func RDSMultitenantSecretName(id string) string {
 	return fmt.Sprintf("rds-multitenant-%s", id)
 }
 
+// RDSMultitenantClusterID formats the ID of a multitenant RDS cluster created
+// by the provisioner in a VPC.
+func RDSMultitenantClusterID(vpcID, id string) string {
+	return fmt.Sprintf("%s-%s-%s", RDSMultitenantDBClusterResourceNamePrefix, strings.TrimPrefix(vpcID, "vpc-"), id)
+}
+
+// RDSMultitenantUpgradeSnapshotID formats the ID of the snapshot taken of a
+// multitenant RDS cluster before its engine version is upgraded.
+func RDSMultitenantUpgradeSnapshotID(rdsClusterID string, timestamp int64) string {
+	return fmt.Sprintf("%s-upgrade-%d", rdsClusterID, timestamp)
+}
+
 // MattermostMultitenantDatabaseUsername formats the name of a Mattermost user for
 // use in a multitenant database.
 func MattermostMultitenantDatabaseUsername(installationID string) string {
 	return fmt.Sprintf("user_%s", installationID)
 }
 
+// MattermostMultitenantDatabaseAlternateUsername formats the name of the
+// Mattermost user used in a multitenant database in turn with the original
+// user when database credentials are rotated.
+func MattermostMultitenantDatabaseAlternateUsername(installationID string) string {
+	return fmt.Sprintf("alt_%s", installationID)
+}
+
 // RDSMultitenantPGBouncerSecretName formats the name of a secret used in a
 // multitenant PGBouncer RDS database.
 func RDSMultitenantPGBouncerSecretName(id string) string {
@@ -127,6 +146,13 @@ func MattermostPGBouncerDatabaseUsername(installationID string) string {
 	return fmt.Sprintf("id_%s", installationID)
 }
 
+// MattermostPGBouncerDatabaseAlternateUsername formats the name of the
+// Mattermost user used in a PGBouncer database in turn with the original user
+// when database credentials are rotated.
+func MattermostPGBouncerDatabaseAlternateUsername(installationID string) string {
+	return fmt.Sprintf("alt_id_%s", installationID)
+}
+
 // MattermostMultitenantS3Name formats the name of a Mattermost S3 multitenant
 // filestore bucket name.
 func MattermostMultitenantS3Name(environmentName, vpcID string) string {
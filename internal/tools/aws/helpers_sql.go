@@ -83,3 +83,32 @@ func dropSchemaIfExists(ctx context.Context, db SQLDatabaseManager, schemaName s
 
 	return nil
 }
+
+// ensureDatabaseUserActsAsOwner makes a Postgres user a member of the role
+// owning the installation database objects and switches its sessions to that
+// role, so that objects it creates stay accessible to both users.
+func ensureDatabaseUserActsAsOwner(ctx context.Context, db SQLDatabaseManager, username, owner string) error {
+	query := fmt.Sprintf("GRANT %s TO %s", owner, username)
+	_, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "failed to run grant owner role SQL command")
+	}
+
+	query = fmt.Sprintf("ALTER USER %s SET role = %s", username, owner)
+	_, err = db.QueryContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "failed to run set user role SQL command")
+	}
+
+	return nil
+}
+
+func deactivatePostgresUser(ctx context.Context, db SQLDatabaseManager, username string) error {
+	query := fmt.Sprintf("ALTER USER %s WITH NOLOGIN", username)
+	_, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return errors.Wrap(err, "failed to run disable user login SQL command")
+	}
+
+	return nil
+}
@@ -99,6 +99,7 @@ func (a *Client) rdsEnsureDBClusterCreated(
 	password,
 	kmsKeyID,
 	databaseType string,
+	tags []*rds.Tag,
 	logger log.FieldLogger) error {
 
 	var engine, engineVersion, sgTagValue string
@@ -164,7 +165,12 @@ func (a *Client) rdsEnsureDBClusterCreated(
 		StorageEncrypted:      aws.Bool(true),
 		DBSubnetGroupName:     aws.String(dbSubnetGroupName),
 		VpcSecurityGroupIds:   aws.StringSlice(dbSecurityGroupIDs),
-		KmsKeyId:              aws.String(kmsKeyID),
+		Tags:                  tags,
+	}
+	// Without a KMS key the cluster is encrypted with the default RDS key of
+	// the account.
+	if len(kmsKeyID) != 0 {
+		input.KmsKeyId = aws.String(kmsKeyID)
 	}
 
 	_, err = a.Service().rds.CreateDBCluster(input)
@@ -108,6 +108,24 @@ func (c *Cmd) RollingUpdateCluster(name string) error {
 	return nil
 }
 
+// RollingUpdateInstanceGroup invokes kops rolling-update cluster limited to
+// the given instance group, using the context of the created Cmd.
+func (c *Cmd) RollingUpdateInstanceGroup(name, igName string) error {
+	_, _, err := c.run(
+		"rolling-update",
+		"cluster",
+		arg("name", name),
+		arg("state", "s3://", c.s3StateStore),
+		arg("instance-group", igName),
+		"--yes",
+	)
+	if err != nil {
+		return errors.Wrapf(err, "failed to invoke kops rolling-update cluster for instance group %s", igName)
+	}
+
+	return nil
+}
+
 // UpdateCluster invokes kops update cluster, using the context of the created Cmd.
 func (c *Cmd) UpdateCluster(name, dir string) error {
 	_, _, err := c.run(
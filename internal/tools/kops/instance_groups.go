@@ -31,16 +31,59 @@ type InstanceGroupSpec struct {
 	MachineType string `json:"machineType"`
 	MinSize     int64  `json:"minSize"`
 	MaxSize     int64  `json:"maxSize"`
+
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+	Taints     []string          `json:"taints,omitempty"`
+	MaxPrice   *string           `json:"maxPrice,omitempty"`
+
+	MixedInstancesPolicy *MixedInstancesPolicySpec `json:"mixedInstancesPolicy,omitempty"`
+}
+
+// MixedInstancesPolicySpec is the mixed instances policy of a kops instance
+// group.
+type MixedInstancesPolicySpec struct {
+	Instances              []string `json:"instances,omitempty"`
+	OnDemandBase           *int64   `json:"onDemandBase,omitempty"`
+	OnDemandAboveBase      *int64   `json:"onDemandAboveBase,omitempty"`
+	SpotAllocationStrategy *string  `json:"spotAllocationStrategy,omitempty"`
+}
+
+// nodeGroupMetadata returns the worker node group metadata of the instance
+// group spec.
+func (spec InstanceGroupSpec) nodeGroupMetadata() model.KopsInstanceGroupMetadata {
+	nodeGroup := model.KopsInstanceGroupMetadata{
+		NodeInstanceType: spec.MachineType,
+		NodeMinCount:     spec.MinSize,
+		NodeMaxCount:     spec.MaxSize,
+		Labels:           spec.NodeLabels,
+		Taints:           spec.Taints,
+	}
+	if spec.MaxPrice != nil {
+		nodeGroup.MaxPrice = *spec.MaxPrice
+	}
+	if spec.MixedInstancesPolicy != nil {
+		nodeGroup.MixedInstancesPolicy = &model.KopsMixedInstancesPolicy{
+			Instances:         spec.MixedInstancesPolicy.Instances,
+			OnDemandBase:      spec.MixedInstancesPolicy.OnDemandBase,
+			OnDemandAboveBase: spec.MixedInstancesPolicy.OnDemandAboveBase,
+		}
+		if spec.MixedInstancesPolicy.SpotAllocationStrategy != nil {
+			nodeGroup.MixedInstancesPolicy.SpotAllocationStrategy = *spec.MixedInstancesPolicy.SpotAllocationStrategy
+		}
+	}
+
+	return nodeGroup
 }
 
 // UpdateMetadata updates KopsMetadata with the current values from kops state
 // store. This can be a bit tricky. We are attempting to correlate multiple kops
 // instance groups into a simplified set of metadata information. To do so, we
 // assume and check the following:
-// - There is one worker node instance group.
+// - There is one or more default worker node instance groups named "nodes*".
 // - There is one or more master instance groups.
 // - All of the cluster hosts are running the same AMI.
 // - All of the master nodes are running the same instance type.
+// Other worker node instance groups are stored as custom instance groups.
 // Note:
 // If any violations are found, we don't return an error as that is beyond the
 // scope of updating the metadata. Instead, warnings for each violation are
@@ -96,17 +139,9 @@ func (c *Cmd) UpdateMetadata(metadata *model.KopsMetadata) error {
 				nodeMachineType = ig.Spec.MachineType
 				nodeMinCount += ig.Spec.MinSize
 				nodeMaxCount += ig.Spec.MaxSize
-				metadata.NodeInstanceGroups[ig.Metadata.Name] = model.KopsInstanceGroupMetadata{
-					NodeInstanceType: ig.Spec.MachineType,
-					NodeMinCount:     ig.Spec.MinSize,
-					NodeMaxCount:     ig.Spec.MaxSize,
-				}
+				metadata.NodeInstanceGroups[ig.Metadata.Name] = ig.Spec.nodeGroupMetadata()
 			} else {
-				metadata.CustomInstanceGroups[ig.Metadata.Name] = model.KopsInstanceGroupMetadata{
-					NodeInstanceType: ig.Spec.MachineType,
-					NodeMinCount:     ig.Spec.MinSize,
-					NodeMaxCount:     ig.Spec.MaxSize,
-				}
+				metadata.CustomInstanceGroups[ig.Metadata.Name] = ig.Spec.nodeGroupMetadata()
 			}
 		default:
 			warning := fmt.Sprintf("Instance group %s has unknown role %s", ig.Metadata.Name, ig.Spec.Role)
@@ -138,7 +173,13 @@ func (c *Cmd) UpdateMetadata(metadata *model.KopsMetadata) error {
 	metadata.MasterInstanceType = masterMachineType
 	metadata.MasterCount = masterIGCount
 	metadata.NodeInstanceType = nodeMachineType
-	metadata.NodeMinCount = nodeMinCount
+	// The instance groups of clusters scaled by the cluster autoscaler run the
+	// desired worker node count, while the min count is kept as the bound.
+	if metadata.NodeDesiredCount > 0 {
+		metadata.NodeDesiredCount = nodeMinCount
+	} else {
+		metadata.NodeMinCount = nodeMinCount
+	}
 	metadata.NodeMaxCount = nodeMaxCount
 	metadata.Networking = GetCurrentCni(networking)
 	metadata.VPC = strings.Trim(vpc, "\"")
@@ -146,6 +187,28 @@ func (c *Cmd) UpdateMetadata(metadata *model.KopsMetadata) error {
 	return nil
 }
 
+// CreateInstanceGroup invokes kops create instancegroup, using the context of
+// the created Cmd, creating a worker node instance group in the given subnets.
+// The instance group is created with the kops defaults and is expected to be
+// adjusted with Replace afterwards.
+func (c *Cmd) CreateInstanceGroup(clusterName, igName string, subnets []string) error {
+	_, _, err := c.run(
+		"create",
+		"instancegroup",
+		igName,
+		arg("name", clusterName),
+		arg("state", "s3://", c.s3StateStore),
+		arg("role", "Node"),
+		commaArg("subnet", subnets),
+		arg("edit", "false"),
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to invoke kops create instancegroup")
+	}
+
+	return nil
+}
+
 // GetInstanceGroupsJSON invokes kops get instancegroup, using the context of the
 // created Cmd, and returns the unmarshaled response as []InstanceGroup.
 func (c *Cmd) GetInstanceGroupsJSON(clusterName string) ([]InstanceGroup, error) {
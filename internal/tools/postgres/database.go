@@ -0,0 +1,401 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package postgres
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mattermost/mattermost-cloud/internal/common"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultContextTimeout = 60 * time.Second
+
+// ExternalDatabase is a database hosted on an external PostgreSQL server. Each
+// installation gets its own logical database and user on the server.
+type ExternalDatabase struct {
+	installationID string
+	instanceID     string
+	server         *ServerConfig
+}
+
+// NewExternalDatabase returns a new instance of ExternalDatabase that
+// implements the database interface.
+func NewExternalDatabase(instanceID, installationID string, server *ServerConfig) *ExternalDatabase {
+	return &ExternalDatabase{
+		installationID: installationID,
+		instanceID:     instanceID,
+		server:         server,
+	}
+}
+
+// DatabaseName formats the name of the logical database of an installation.
+func DatabaseName(installationID string) string {
+	return fmt.Sprintf("cloud_%s", installationID)
+}
+
+// DatabaseUsername formats the name of the database user of an installation.
+func DatabaseUsername(installationID string) string {
+	return fmt.Sprintf("user_%s", installationID)
+}
+
+// DatabaseSecretName formats the name of the Kubernetes secret holding the
+// database connection strings of an installation.
+func DatabaseSecretName(installationID string) string {
+	return fmt.Sprintf("external-postgres-%s", installationID)
+}
+
+// IsValid returns if the given ExternalDatabase configuration is valid.
+func (d *ExternalDatabase) IsValid() error {
+	if len(d.installationID) == 0 {
+		return errors.New("installation ID is not set")
+	}
+	if d.server == nil {
+		return errors.New("no external postgres server is configured on the provisioning server")
+	}
+
+	return d.server.Validate()
+}
+
+// databasePassword derives the password of the installation database user
+// from the server password key, so that it doesn't need to be stored.
+func (d *ExternalDatabase) databasePassword() string {
+	mac := hmac.New(sha256.New, []byte(d.server.PasswordKey))
+	mac.Write([]byte(d.installationID))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Provision creates the installation database and user on the external
+// PostgreSQL server.
+func (d *ExternalDatabase) Provision(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	logger = logger.WithField("external-postgres-database", DatabaseName(d.installationID))
+	logger.Info("Provisioning external postgres database")
+
+	err := d.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "external postgres database configuration is invalid")
+	}
+
+	err = d.runProvisionSQLCommands(logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to run provisioning sql commands")
+	}
+
+	logger.Info("External postgres database provisioning complete")
+
+	return nil
+}
+
+// Teardown removes the installation database and user from the external
+// PostgreSQL server unless the data should be kept.
+func (d *ExternalDatabase) Teardown(store model.InstallationDatabaseStoreInterface, keepData bool, logger log.FieldLogger) error {
+	logger = logger.WithField("external-postgres-database", DatabaseName(d.installationID))
+	logger.Info("Tearing down external postgres database")
+
+	err := d.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "external postgres database configuration is invalid")
+	}
+
+	if keepData {
+		logger.Info("Keeping external postgres database and user")
+		return nil
+	}
+
+	err = d.cleanupDatabase(logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to clean up external postgres database")
+	}
+
+	logger.Info("External postgres database teardown complete")
+
+	return nil
+}
+
+// Snapshot is not supported for external PostgreSQL databases.
+func (d *ExternalDatabase) Snapshot(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("snapshots are not supported for external postgres databases")
+}
+
+// GenerateDatabaseSecret creates the k8s database spec and secret for
+// accessing the installation database on the external PostgreSQL server.
+func (d *ExternalDatabase) GenerateDatabaseSecret(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*corev1.Secret, error) {
+	err := d.IsValid()
+	if err != nil {
+		return nil, errors.Wrap(err, "external postgres database configuration is invalid")
+	}
+
+	databaseConnectionString, databaseReadReplicasString := d.server.MattermostConnStrings(
+		DatabaseName(d.installationID),
+		DatabaseUsername(d.installationID),
+		d.databasePassword(),
+	)
+
+	secretStringData := map[string]string{
+		"DB_CONNECTION_STRING":    databaseConnectionString,
+		"DB_CONNECTION_CHECK_URL": databaseConnectionString,
+	}
+	if len(databaseReadReplicasString) != 0 {
+		secretStringData["MM_SQLSETTINGS_DATASOURCEREPLICAS"] = databaseReadReplicasString
+	}
+
+	databaseSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: DatabaseSecretName(d.installationID),
+		},
+		StringData: secretStringData,
+	}
+
+	logger.Debug("External postgres database configuration generated for cluster installation")
+
+	return databaseSecret, nil
+}
+
+// RefreshResourceMetadata is a no-op as external PostgreSQL databases have no
+// resource metadata to keep up to date.
+func (d *ExternalDatabase) RefreshResourceMetadata(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return nil
+}
+
+// MigrateOut keeps the installation database in place so that the migration
+// can be rolled back. It is removed by TeardownMigrated.
+func (d *ExternalDatabase) MigrateOut(store model.InstallationDatabaseStoreInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	logger.Infof("Installation %s migrated out of external postgres database", d.installationID)
+
+	return nil
+}
+
+// MigrateTo creates the installation database and user on the external
+// PostgreSQL server for an already existing installation.
+func (d *ExternalDatabase) MigrateTo(store model.InstallationDatabaseStoreInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	err := d.Provision(store, logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to provision external postgres database")
+	}
+
+	logger.Infof("Installation %s migrated to external postgres database", d.installationID)
+
+	return nil
+}
+
+// TeardownMigrated removes the database the installation was migrated out of.
+func (d *ExternalDatabase) TeardownMigrated(store model.InstallationDatabaseStoreInterface, migrationOp *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	logger.Info("Tearing down migrated external postgres database")
+
+	err := d.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "external postgres database configuration is invalid")
+	}
+
+	return d.cleanupDatabase(logger)
+}
+
+// RollbackMigration removes the installation database from the external
+// PostgreSQL server and moves the installation back to its source multitenant
+// database.
+func (d *ExternalDatabase) RollbackMigration(store model.InstallationDatabaseStoreInterface, dbMigration *model.InstallationDBMigrationOperation, logger log.FieldLogger) error {
+	err := d.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "external postgres database configuration is invalid")
+	}
+
+	if dbMigration.SourceMultiTenant != nil {
+		err = d.restoreSourceMultitenantDatabase(store, dbMigration.SourceMultiTenant.DatabaseID, logger)
+		if err != nil {
+			return errors.Wrap(err, "failed to restore installation in source multitenant database")
+		}
+	}
+
+	err = d.cleanupDatabase(logger)
+	if err != nil {
+		return errors.Wrap(err, "failed to drop destination database")
+	}
+
+	logger.Infof("Installation %s migration to external postgres database rolled back", d.installationID)
+
+	return nil
+}
+
+// RotateCredentials is not supported for external PostgreSQL databases as
+// the password of the installation user is derived from the server password
+// key.
+func (d *ExternalDatabase) RotateCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("rotating credentials is not supported for external postgres databases")
+}
+
+// RevokePreviousCredentials is not supported for external PostgreSQL
+// databases.
+func (d *ExternalDatabase) RevokePreviousCredentials(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("revoking credentials is not supported for external postgres databases")
+}
+
+func (d *ExternalDatabase) restoreSourceMultitenantDatabase(store model.InstallationDatabaseStoreInterface, multitenantDatabaseID string, logger log.FieldLogger) error {
+	locked, err := store.LockMultitenantDatabase(multitenantDatabaseID, d.instanceID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to lock multitenant database %s", multitenantDatabaseID)
+	}
+	if !locked {
+		return errors.Errorf("failed to acquire lock for multitenant database %s", multitenantDatabaseID)
+	}
+	defer func() {
+		unlocked, err := store.UnlockMultitenantDatabase(multitenantDatabaseID, d.instanceID, true)
+		if err != nil {
+			logger.WithError(err).Error("failed to unlock multitenant database")
+		}
+		if !unlocked {
+			logger.Warn("failed to release lock for multitenant database")
+		}
+	}()
+
+	database, err := store.GetMultitenantDatabase(multitenantDatabaseID)
+	if err != nil {
+		return errors.Wrap(err, "failed to query for the multitenant database")
+	}
+	if database == nil {
+		return errors.Errorf("multitenant database %s not found", multitenantDatabaseID)
+	}
+
+	database.MigratedInstallations.Remove(d.installationID)
+	if !common.Contains(database.Installations, d.installationID) {
+		database.Installations.Add(d.installationID)
+	}
+
+	err = store.UpdateMultitenantDatabase(database)
+	if err != nil {
+		return errors.Wrap(err, "failed to update source multitenant database")
+	}
+
+	return nil
+}
+
+func (d *ExternalDatabase) connect() (*sql.DB, error) {
+	db, err := sql.Open("postgres", d.server.AdminConnString())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to external postgres server")
+	}
+
+	return db, nil
+}
+
+func closeConnection(db *sql.DB, logger log.FieldLogger) {
+	err := db.Close()
+	if err != nil {
+		logger.WithError(err).Error("Failed to close the connection with external postgres server")
+	}
+}
+
+func (d *ExternalDatabase) runProvisionSQLCommands(logger log.FieldLogger) error {
+	db, err := d.connect()
+	if err != nil {
+		return err
+	}
+	defer closeConnection(db, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultContextTimeout)
+	defer cancel()
+
+	databaseName := DatabaseName(d.installationID)
+	username := DatabaseUsername(d.installationID)
+
+	err = ensureUserIsCreated(ctx, db, username, d.databasePassword())
+	if err != nil {
+		return errors.Wrap(err, "failed to create Mattermost database user")
+	}
+
+	// The admin user needs to be a member of the installation user role to
+	// hand over database ownership when it isn't a superuser.
+	_, err = db.ExecContext(ctx, fmt.Sprintf("GRANT %s TO %s", pq.QuoteIdentifier(username), pq.QuoteIdentifier(d.server.Username)))
+	if err != nil {
+		return errors.Wrap(err, "failed to grant Mattermost database user role to admin user")
+	}
+
+	err = ensureDatabaseIsCreated(ctx, db, databaseName, username)
+	if err != nil {
+		return errors.Wrap(err, "failed to create Mattermost database")
+	}
+
+	return nil
+}
+
+func (d *ExternalDatabase) cleanupDatabase(logger log.FieldLogger) error {
+	db, err := d.connect()
+	if err != nil {
+		return err
+	}
+	defer closeConnection(db, logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultContextTimeout)
+	defer cancel()
+
+	databaseName := DatabaseName(d.installationID)
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(databaseName)))
+	if err != nil {
+		return errors.Wrapf(err, "failed to drop external postgres database %s", databaseName)
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("DROP USER IF EXISTS %s", pq.QuoteIdentifier(DatabaseUsername(d.installationID))))
+	if err != nil {
+		return errors.Wrap(err, "failed to delete installation database user")
+	}
+
+	return nil
+}
+
+func ensureUserIsCreated(ctx context.Context, db *sql.DB, username, password string) error {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = $1)", username).Scan(&exists)
+	if err != nil {
+		return errors.Wrap(err, "failed to check if user exists")
+	}
+
+	// The password is set on existing users as well so that a changed password
+	// key is applied on the next provisioning.
+	statement := "CREATE USER %s WITH PASSWORD %s"
+	if exists {
+		statement = "ALTER USER %s WITH PASSWORD %s"
+	}
+
+	// Placeholders are not supported in these statements, so make sure the
+	// password is not leaked into logs with the error.
+	_, err = db.ExecContext(ctx, fmt.Sprintf(statement, pq.QuoteIdentifier(username), pq.QuoteLiteral(password)))
+	if err != nil {
+		return errors.New("failed to run create user SQL command: error suppressed")
+	}
+
+	return nil
+}
+
+func ensureDatabaseIsCreated(ctx context.Context, db *sql.DB, databaseName, owner string) error {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_database WHERE datname = $1)", databaseName).Scan(&exists)
+	if err != nil {
+		return errors.Wrap(err, "failed to check if database exists")
+	}
+
+	statement := "CREATE DATABASE %s OWNER %s"
+	if exists {
+		statement = "ALTER DATABASE %s OWNER TO %s"
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf(statement, pq.QuoteIdentifier(databaseName), pq.QuoteIdentifier(owner)))
+	if err != nil {
+		return errors.Wrap(err, "failed to run create database SQL command")
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+// Package postgres manages Mattermost databases on PostgreSQL servers which
+// are not provisioned by a cloud provider.
+package postgres
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPort is the port used when the server secret doesn't specify one.
+	DefaultPort = 5432
+	// DefaultSSLMode is the SSL mode used when the server secret doesn't
+	// specify one.
+	DefaultSSLMode = "require"
+	// DefaultAdminDatabase is the database the provisioner connects to for
+	// managing installation databases and users.
+	DefaultAdminDatabase = "postgres"
+)
+
+// ServerConfig holds the location and the admin credentials of an external
+// PostgreSQL server. It is loaded from a secret which only the provisioning
+// server can read and is never exposed through the API.
+type ServerConfig struct {
+	Host          string `json:"host"`
+	Port          int    `json:"port,omitempty"`
+	ReaderHost    string `json:"readerHost,omitempty"`
+	AdminDatabase string `json:"adminDatabase,omitempty"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	SSLMode       string `json:"sslMode,omitempty"`
+	// PasswordKey is the key installation database user passwords are
+	// derived from.
+	PasswordKey string `json:"passwordKey"`
+}
+
+// NewServerConfigFromReader decodes a ServerConfig from an io.Reader with JSON
+// data and sets the defaults of missing optional values.
+func NewServerConfigFromReader(reader io.Reader) (*ServerConfig, error) {
+	var config ServerConfig
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&config)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode external postgres server config")
+	}
+
+	config.setDefaults()
+
+	err = config.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "external postgres server config is invalid")
+	}
+
+	return &config, nil
+}
+
+// NewServerConfigFromFile loads a ServerConfig from the secret file at the
+// given path.
+func NewServerConfigFromFile(path string) (*ServerConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open external postgres server secret")
+	}
+	defer file.Close()
+
+	return NewServerConfigFromReader(file)
+}
+
+func (c *ServerConfig) setDefaults() {
+	if c.Port == 0 {
+		c.Port = DefaultPort
+	}
+	if len(c.SSLMode) == 0 {
+		c.SSLMode = DefaultSSLMode
+	}
+	if len(c.AdminDatabase) == 0 {
+		c.AdminDatabase = DefaultAdminDatabase
+	}
+}
+
+// Validate returns an error if the server config is missing required values.
+func (c *ServerConfig) Validate() error {
+	if len(c.Host) == 0 {
+		return errors.New("host must be specified")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return errors.Errorf("port %d is invalid", c.Port)
+	}
+	if len(c.Username) == 0 {
+		return errors.New("username must be specified")
+	}
+	if len(c.Password) == 0 {
+		return errors.New("password must be specified")
+	}
+	if len(c.PasswordKey) == 0 {
+		return errors.New("passwordKey must be specified")
+	}
+
+	switch c.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		return errors.Errorf("sslMode %q is invalid", c.SSLMode)
+	}
+
+	return nil
+}
+
+// AdminConnString formats the connection string used by the provisioner for
+// managing installation databases and users.
+func (c *ServerConfig) AdminConnString() string {
+	return c.connString(c.Host, c.AdminDatabase, c.Username, c.Password)
+}
+
+// MattermostConnStrings formats the connection strings used by Mattermost
+// servers to access their database. The read replica connection string is
+// empty if the server has no reader host.
+func (c *ServerConfig) MattermostConnStrings(databaseName, username, password string) (string, string) {
+	dbConnection := c.connString(c.Host, databaseName, username, password)
+
+	var readReplicas string
+	if len(c.ReaderHost) != 0 {
+		readReplicas = c.connString(c.ReaderHost, databaseName, username, password)
+	}
+
+	return dbConnection, readReplicas
+}
+
+func (c *ServerConfig) connString(host, databaseName, username, password string) string {
+	connURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(username, password),
+		Host:     net.JoinHostPort(host, strconv.Itoa(c.Port)),
+		Path:     databaseName,
+		RawQuery: url.Values{"connect_timeout": {"10"}, "sslmode": {c.SSLMode}}.Encode(),
+	}
+
+	return connURL.String()
+}
@@ -0,0 +1,539 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package s3compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const userPolicyName = "mattermost-filestore"
+
+// Filestore is a filestore backed by an S3-compatible object storage server.
+type Filestore struct {
+	installationID string
+	server         *ServerConfig
+	newClients     func(server *ServerConfig) (s3iface.S3API, iamiface.IAMAPI, error)
+}
+
+// NewFilestore returns a new instance of Filestore that implements the
+// filestore interface.
+func NewFilestore(installationID string, server *ServerConfig) *Filestore {
+	return &Filestore{
+		installationID: installationID,
+		server:         server,
+		newClients:     newClients,
+	}
+}
+
+// newClients returns the S3 client of the server and its IAM client if
+// installations get scoped users.
+func newClients(server *ServerConfig) (s3iface.S3API, iamiface.IAMAPI, error) {
+	awsSession, err := session.NewSession(&aws.Config{
+		Region:           aws.String(server.Region),
+		Credentials:      credentials.NewStaticCredentials(server.AccessKeyID, server.SecretAccessKey, ""),
+		Endpoint:         aws.String(server.Endpoint),
+		S3ForcePathStyle: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create S3-compatible server session")
+	}
+
+	var iamClient iamiface.IAMAPI
+	if server.ScopedUsers() {
+		iamClient = iam.New(awsSession, &aws.Config{Endpoint: aws.String(server.IAMEndpoint)})
+	}
+
+	return s3.New(awsSession), iamClient, nil
+}
+
+// FilestoreUsername formats the name of the user of an installation.
+func FilestoreUsername(installationID string) string {
+	return fmt.Sprintf("mattermost-%s", installationID)
+}
+
+// FilestoreSecretName formats the name of the Kubernetes secret holding the
+// filestore credentials of an installation.
+func FilestoreSecretName(installationID string) string {
+	return fmt.Sprintf("%s-s3-compatible-access-key", installationID)
+}
+
+// IsValid returns if the given Filestore configuration is valid.
+func (f *Filestore) IsValid() error {
+	if len(f.installationID) == 0 {
+		return errors.New("installation ID is not set")
+	}
+	if f.server == nil {
+		return errors.New("no S3-compatible filestore server is configured on the provisioning server")
+	}
+
+	return f.server.Validate()
+}
+
+// bucketName returns the name of the bucket the installation files are in.
+func (f *Filestore) bucketName() string {
+	if len(f.server.Bucket) != 0 {
+		return f.server.Bucket
+	}
+
+	return f.server.BucketPrefix + f.installationID
+}
+
+func (f *Filestore) sharedBucket() bool {
+	return len(f.server.Bucket) != 0
+}
+
+func (f *Filestore) credentialsKey() string {
+	return fmt.Sprintf("%s/access-key.json", f.installationID)
+}
+
+// Provision creates the installation bucket and user on the S3-compatible
+// server.
+func (f *Filestore) Provision(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	err := f.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "S3-compatible filestore configuration is invalid")
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"filestore-type": "s3-compatible",
+		"bucket":         f.bucketName(),
+	})
+	logger.Info("Provisioning S3-compatible filestore")
+
+	s3Client, iamClient, err := f.newClients(f.server)
+	if err != nil {
+		return err
+	}
+
+	if f.sharedBucket() {
+		_, err = s3Client.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(f.bucketName())})
+		if err != nil {
+			return errors.Wrapf(err, "failed to find shared bucket %s", f.bucketName())
+		}
+	} else {
+		err = f.ensureBucketCreated(s3Client, logger)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !f.server.ScopedUsers() {
+		logger.Warn("No IAM endpoint is configured for the S3-compatible filestore server; installation will use the root credentials")
+		return nil
+	}
+
+	err = f.ensureUserCreated(iamClient, logger)
+	if err != nil {
+		return err
+	}
+
+	err = f.ensureAccessKeyCreated(s3Client, iamClient, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("S3-compatible filestore provisioning complete")
+
+	return nil
+}
+
+// Teardown removes the installation user and, unless the data should be kept,
+// the installation files from the S3-compatible server.
+func (f *Filestore) Teardown(keepData bool, store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	err := f.IsValid()
+	if err != nil {
+		return errors.Wrap(err, "S3-compatible filestore configuration is invalid")
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"filestore-type": "s3-compatible",
+		"bucket":         f.bucketName(),
+	})
+	logger.Info("Tearing down S3-compatible filestore")
+
+	s3Client, iamClient, err := f.newClients(f.server)
+	if err != nil {
+		return err
+	}
+
+	if f.server.ScopedUsers() {
+		err = f.ensureUserDeleted(iamClient, logger)
+		if err != nil {
+			return err
+		}
+
+		_, err = s3Client.DeleteObject(&s3.DeleteObjectInput{
+			Bucket: aws.String(f.server.CredentialsBucket),
+			Key:    aws.String(f.credentialsKey()),
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to delete stored access key")
+		}
+	}
+
+	if keepData {
+		logger.Info("S3-compatible filestore data was left intact due to the keep-data setting of this server")
+		return nil
+	}
+
+	err = f.ensureFilesDeleted(s3Client, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("S3-compatible filestore teardown complete")
+
+	return nil
+}
+
+// GenerateFilestoreSpecAndSecret creates the k8s filestore spec and secret for
+// accessing the installation files on the S3-compatible server.
+func (f *Filestore) GenerateFilestoreSpecAndSecret(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) (*model.FilestoreConfig, *corev1.Secret, error) {
+	err := f.IsValid()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "S3-compatible filestore configuration is invalid")
+	}
+
+	logger = logger.WithFields(log.Fields{
+		"filestore-type": "s3-compatible",
+		"bucket":         f.bucketName(),
+	})
+	logger.Debug("Generating S3-compatible filestore information")
+
+	accessKey := &accessKey{ID: f.server.AccessKeyID, Secret: f.server.SecretAccessKey}
+	if f.server.ScopedUsers() {
+		s3Client, _, err := f.newClients(f.server)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		accessKey, err = f.getStoredAccessKey(s3Client)
+		if err != nil {
+			return nil, nil, err
+		}
+		if accessKey == nil {
+			return nil, nil, errors.New("no access key is stored for the installation user")
+		}
+	}
+
+	filestoreSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: FilestoreSecretName(f.installationID),
+		},
+		StringData: map[string]string{
+			"accesskey": accessKey.ID,
+			"secretkey": accessKey.Secret,
+		},
+	}
+
+	filestoreConfig := &model.FilestoreConfig{
+		URL:    f.server.Host(),
+		Bucket: f.bucketName(),
+		Secret: filestoreSecret.Name,
+	}
+
+	logger.Debug("S3-compatible filestore configuration generated for cluster installation")
+
+	return filestoreConfig, filestoreSecret, nil
+}
+
+// RotateAccessKey rotating access keys is not supported for S3-compatible filestores.
+func (f *Filestore) RotateAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("rotating access keys is not supported for S3-compatible filestores")
+}
+
+// RevokePreviousAccessKey revoking access keys is not supported for S3-compatible filestores.
+func (f *Filestore) RevokePreviousAccessKey(store model.InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("revoking access keys is not supported for S3-compatible filestores")
+}
+
+func (f *Filestore) ensureBucketCreated(s3Client s3iface.S3API, logger log.FieldLogger) error {
+	input := &s3.CreateBucketInput{Bucket: aws.String(f.bucketName())}
+	if f.server.Region != DefaultRegion {
+		input.CreateBucketConfiguration = &s3.CreateBucketConfiguration{
+			LocationConstraint: aws.String(f.server.Region),
+		}
+	}
+
+	_, err := s3Client.CreateBucket(input)
+	if err != nil {
+		if isErrorCode(err, s3.ErrCodeBucketAlreadyOwnedByYou) {
+			logger.Debug("S3-compatible filestore bucket already created")
+			return nil
+		}
+		return errors.Wrapf(err, "failed to create bucket %s", f.bucketName())
+	}
+
+	logger.Debug("S3-compatible filestore bucket created")
+
+	return nil
+}
+
+// ensureFilesDeleted deletes the installation directory of a shared bucket or
+// the whole installation bucket.
+func (f *Filestore) ensureFilesDeleted(s3Client s3iface.S3API, logger log.FieldLogger) error {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(f.bucketName())}
+	if f.sharedBucket() {
+		input.Prefix = aws.String(f.installationID + "/")
+	}
+
+	for {
+		output, err := s3Client.ListObjectsV2(input)
+		if err != nil {
+			if isErrorCode(err, s3.ErrCodeNoSuchBucket) {
+				logger.Warn("S3-compatible filestore bucket could not be found; assuming already deleted")
+				return nil
+			}
+			return errors.Wrap(err, "failed to list installation files")
+		}
+
+		if len(output.Contents) != 0 {
+			var objects []*s3.ObjectIdentifier
+			for _, object := range output.Contents {
+				objects = append(objects, &s3.ObjectIdentifier{Key: object.Key})
+			}
+
+			_, err = s3Client.DeleteObjects(&s3.DeleteObjectsInput{
+				Bucket: input.Bucket,
+				Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to delete installation files")
+			}
+		}
+
+		if !aws.BoolValue(output.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = output.NextContinuationToken
+	}
+
+	if f.sharedBucket() {
+		return nil
+	}
+
+	_, err := s3Client.DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String(f.bucketName())})
+	if err != nil && !isErrorCode(err, s3.ErrCodeNoSuchBucket) {
+		return errors.Wrapf(err, "failed to delete bucket %s", f.bucketName())
+	}
+
+	logger.Debug("S3-compatible filestore bucket deleted")
+
+	return nil
+}
+
+func (f *Filestore) ensureUserCreated(iamClient iamiface.IAMAPI, logger log.FieldLogger) error {
+	username := aws.String(FilestoreUsername(f.installationID))
+
+	_, err := iamClient.GetUser(&iam.GetUserInput{UserName: username})
+	if err != nil {
+		if !isErrorCode(err, iam.ErrCodeNoSuchEntityException) {
+			return errors.Wrap(err, "failed to get installation user")
+		}
+
+		_, err = iamClient.CreateUser(&iam.CreateUserInput{UserName: username})
+		if err != nil {
+			return errors.Wrap(err, "failed to create installation user")
+		}
+		logger.Debug("S3-compatible filestore user created")
+	}
+
+	policy, err := json.Marshal(f.userPolicy())
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal user policy")
+	}
+
+	// Inline policies are replaced, which keeps them up to date.
+	_, err = iamClient.PutUserPolicy(&iam.PutUserPolicyInput{
+		UserName:       username,
+		PolicyName:     aws.String(userPolicyName),
+		PolicyDocument: aws.String(string(policy)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to put installation user policy")
+	}
+
+	return nil
+}
+
+func (f *Filestore) ensureAccessKeyCreated(s3Client s3iface.S3API, iamClient iamiface.IAMAPI, logger log.FieldLogger) error {
+	stored, err := f.getStoredAccessKey(s3Client)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		logger.Debug("S3-compatible filestore user access key already created")
+		return nil
+	}
+
+	output, err := iamClient.CreateAccessKey(&iam.CreateAccessKeyInput{
+		UserName: aws.String(FilestoreUsername(f.installationID)),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create installation user access key")
+	}
+
+	data, err := json.Marshal(&accessKey{
+		ID:     *output.AccessKey.AccessKeyId,
+		Secret: *output.AccessKey.SecretAccessKey,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal access key")
+	}
+
+	_, err = s3Client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(f.server.CredentialsBucket),
+		Key:    aws.String(f.credentialsKey()),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to store installation user access key")
+	}
+
+	logger.Debug("S3-compatible filestore user access key created")
+
+	return nil
+}
+
+func (f *Filestore) ensureUserDeleted(iamClient iamiface.IAMAPI, logger log.FieldLogger) error {
+	username := aws.String(FilestoreUsername(f.installationID))
+
+	accessKeys, err := iamClient.ListAccessKeys(&iam.ListAccessKeysInput{UserName: username})
+	if err != nil {
+		if isErrorCode(err, iam.ErrCodeNoSuchEntityException) {
+			logger.Warn("S3-compatible filestore user could not be found; assuming already deleted")
+			return nil
+		}
+		return errors.Wrap(err, "failed to list installation user access keys")
+	}
+	for _, key := range accessKeys.AccessKeyMetadata {
+		_, err = iamClient.DeleteAccessKey(&iam.DeleteAccessKeyInput{
+			UserName:    username,
+			AccessKeyId: key.AccessKeyId,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to delete installation user access key")
+		}
+	}
+
+	_, err = iamClient.DeleteUserPolicy(&iam.DeleteUserPolicyInput{
+		UserName:   username,
+		PolicyName: aws.String(userPolicyName),
+	})
+	if err != nil && !isErrorCode(err, iam.ErrCodeNoSuchEntityException) {
+		return errors.Wrap(err, "failed to delete installation user policy")
+	}
+
+	_, err = iamClient.DeleteUser(&iam.DeleteUserInput{UserName: username})
+	if err != nil && !isErrorCode(err, iam.ErrCodeNoSuchEntityException) {
+		return errors.Wrap(err, "failed to delete installation user")
+	}
+
+	logger.Debug("S3-compatible filestore user deleted")
+
+	return nil
+}
+
+// getStoredAccessKey returns the stored access key of the installation user
+// or nil if there is none.
+func (f *Filestore) getStoredAccessKey(s3Client s3iface.S3API) (*accessKey, error) {
+	output, err := s3Client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(f.server.CredentialsBucket),
+		Key:    aws.String(f.credentialsKey()),
+	})
+	if err != nil {
+		if isErrorCode(err, s3.ErrCodeNoSuchKey) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get stored access key")
+	}
+	defer output.Body.Close()
+
+	data, err := ioutil.ReadAll(output.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read stored access key")
+	}
+
+	var key accessKey
+	err = json.Unmarshal(data, &key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal stored access key")
+	}
+
+	return &key, nil
+}
+
+// userPolicy returns the policy which limits the installation user to its
+// files.
+func (f *Filestore) userPolicy() *policyDocument {
+	bucketResource := fmt.Sprintf("arn:aws:s3:::%s", f.bucketName())
+	objectsResource := bucketResource + "/*"
+
+	listStatement := policyStatement{
+		Effect:   "Allow",
+		Action:   []string{"s3:ListBucket"},
+		Resource: []string{bucketResource},
+	}
+	if f.sharedBucket() {
+		objectsResource = fmt.Sprintf("%s/%s/*", bucketResource, f.installationID)
+		listStatement.Condition = map[string]map[string][]string{
+			"StringLike": {"s3:prefix": {f.installationID + "/*"}},
+		}
+	}
+
+	return &policyDocument{
+		Version: "2012-10-17",
+		Statement: []policyStatement{
+			listStatement,
+			{
+				Effect:   "Allow",
+				Action:   []string{"s3:GetObject", "s3:PutObject", "s3:PutObjectAcl", "s3:DeleteObject"},
+				Resource: []string{objectsResource},
+			},
+		},
+	}
+}
+
+type accessKey struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+type policyDocument struct {
+	Version   string
+	Statement []policyStatement
+}
+
+type policyStatement struct {
+	Effect    string
+	Action    []string
+	Resource  []string
+	Condition map[string]map[string][]string `json:",omitempty"`
+}
+
+func isErrorCode(err error, code string) bool {
+	if aerr, ok := errors.Cause(err).(awserr.Error); ok {
+		return aerr.Code() == code
+	}
+
+	return false
+}
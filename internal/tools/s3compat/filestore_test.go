@@ -0,0 +1,224 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package s3compat
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/iam"
+	"github.com/aws/aws-sdk-go/service/iam/iamiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/golang/mock/gomock"
+	mockawssdk "github.com/mattermost/mattermost-cloud/internal/mocks/aws-sdk"
+	"github.com/mattermost/mattermost-cloud/internal/testlib"
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFilestore(t *testing.T, server *ServerConfig) (*Filestore, *mockawssdk.MockS3API, *mockawssdk.MockIAMAPI) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	s3Client := mockawssdk.NewMockS3API(ctrl)
+	iamClient := mockawssdk.NewMockIAMAPI(ctrl)
+
+	filestore := NewFilestore("installation1", server)
+	filestore.newClients = func(server *ServerConfig) (s3iface.S3API, iamiface.IAMAPI, error) {
+		if server.ScopedUsers() {
+			return s3Client, iamClient, nil
+		}
+		return s3Client, nil, nil
+	}
+
+	return filestore, s3Client, iamClient
+}
+
+func TestFilestoreProvision(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+
+	t.Run("no server", func(t *testing.T) {
+		err := NewFilestore("installation1", nil).Provision(nil, logger)
+		assert.Error(t, err)
+	})
+
+	t.Run("installation bucket with root credentials", func(t *testing.T) {
+		filestore, s3Client, _ := newTestFilestore(t, &ServerConfig{
+			Endpoint:        "https://minio.example.com",
+			Region:          "eu-west-1",
+			AccessKeyID:     "root",
+			SecretAccessKey: "secret",
+			BucketPrefix:    "mm-",
+		})
+
+		s3Client.EXPECT().
+			CreateBucket(&s3.CreateBucketInput{
+				Bucket:                    aws.String("mm-installation1"),
+				CreateBucketConfiguration: &s3.CreateBucketConfiguration{LocationConstraint: aws.String("eu-west-1")},
+			}).
+			Return(nil, awserr.New(s3.ErrCodeBucketAlreadyOwnedByYou, "", nil))
+
+		require.NoError(t, filestore.Provision(nil, logger))
+
+		config, secret, err := filestore.GenerateFilestoreSpecAndSecret(nil, logger)
+		require.NoError(t, err)
+		assert.Equal(t, &model.FilestoreConfig{
+			URL:    "minio.example.com",
+			Bucket: "mm-installation1",
+			Secret: "installation1-s3-compatible-access-key",
+		}, config)
+		assert.Equal(t, map[string]string{"accesskey": "root", "secretkey": "secret"}, secret.StringData)
+	})
+
+	t.Run("shared bucket with scoped user", func(t *testing.T) {
+		filestore, s3Client, iamClient := newTestFilestore(t, &ServerConfig{
+			Endpoint:          "https://rgw.example.com",
+			Region:            DefaultRegion,
+			AccessKeyID:       "root",
+			SecretAccessKey:   "secret",
+			Bucket:            "mattermost",
+			IAMEndpoint:       "https://rgw.example.com/iam",
+			CredentialsBucket: "provisioner",
+		})
+
+		var storedKey []byte
+		gomock.InOrder(
+			s3Client.EXPECT().
+				HeadBucket(&s3.HeadBucketInput{Bucket: aws.String("mattermost")}).
+				Return(&s3.HeadBucketOutput{}, nil),
+			iamClient.EXPECT().
+				GetUser(&iam.GetUserInput{UserName: aws.String("mattermost-installation1")}).
+				Return(nil, awserr.New(iam.ErrCodeNoSuchEntityException, "", nil)),
+			iamClient.EXPECT().
+				CreateUser(&iam.CreateUserInput{UserName: aws.String("mattermost-installation1")}).
+				Return(&iam.CreateUserOutput{}, nil),
+			iamClient.EXPECT().
+				PutUserPolicy(gomock.Any()).
+				Do(func(input *iam.PutUserPolicyInput) {
+					assert.Equal(t, userPolicyName, *input.PolicyName)
+					var policy policyDocument
+					require.NoError(t, json.Unmarshal([]byte(*input.PolicyDocument), &policy))
+					require.Len(t, policy.Statement, 2)
+					assert.Equal(t, []string{"installation1/*"}, policy.Statement[0].Condition["StringLike"]["s3:prefix"])
+					assert.Equal(t, []string{"arn:aws:s3:::mattermost/installation1/*"}, policy.Statement[1].Resource)
+				}).
+				Return(&iam.PutUserPolicyOutput{}, nil),
+			s3Client.EXPECT().
+				GetObject(&s3.GetObjectInput{Bucket: aws.String("provisioner"), Key: aws.String("installation1/access-key.json")}).
+				Return(nil, awserr.New(s3.ErrCodeNoSuchKey, "", nil)),
+			iamClient.EXPECT().
+				CreateAccessKey(&iam.CreateAccessKeyInput{UserName: aws.String("mattermost-installation1")}).
+				Return(&iam.CreateAccessKeyOutput{AccessKey: &iam.AccessKey{
+					AccessKeyId:     aws.String("installation-key"),
+					SecretAccessKey: aws.String("installation-secret"),
+				}}, nil),
+			s3Client.EXPECT().
+				PutObject(gomock.Any()).
+				Do(func(input *s3.PutObjectInput) {
+					assert.Equal(t, "provisioner", *input.Bucket)
+					var err error
+					storedKey, err = ioutil.ReadAll(input.Body)
+					require.NoError(t, err)
+				}).
+				Return(&s3.PutObjectOutput{}, nil),
+		)
+
+		require.NoError(t, filestore.Provision(nil, logger))
+
+		s3Client.EXPECT().
+			GetObject(&s3.GetObjectInput{Bucket: aws.String("provisioner"), Key: aws.String("installation1/access-key.json")}).
+			DoAndReturn(func(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+				return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(storedKey))}, nil
+			})
+
+		config, secret, err := filestore.GenerateFilestoreSpecAndSecret(nil, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "mattermost", config.Bucket)
+		assert.Equal(t, map[string]string{"accesskey": "installation-key", "secretkey": "installation-secret"}, secret.StringData)
+	})
+}
+
+func TestFilestoreTeardown(t *testing.T) {
+	logger := testlib.MakeLogger(t)
+
+	t.Run("keep data", func(t *testing.T) {
+		filestore, s3Client, iamClient := newTestFilestore(t, &ServerConfig{
+			Endpoint:          "https://rgw.example.com",
+			Region:            DefaultRegion,
+			AccessKeyID:       "root",
+			SecretAccessKey:   "secret",
+			Bucket:            "mattermost",
+			IAMEndpoint:       "https://rgw.example.com/iam",
+			CredentialsBucket: "provisioner",
+		})
+
+		username := aws.String("mattermost-installation1")
+		gomock.InOrder(
+			iamClient.EXPECT().
+				ListAccessKeys(&iam.ListAccessKeysInput{UserName: username}).
+				Return(&iam.ListAccessKeysOutput{AccessKeyMetadata: []*iam.AccessKeyMetadata{{AccessKeyId: aws.String("installation-key")}}}, nil),
+			iamClient.EXPECT().
+				DeleteAccessKey(&iam.DeleteAccessKeyInput{UserName: username, AccessKeyId: aws.String("installation-key")}).
+				Return(&iam.DeleteAccessKeyOutput{}, nil),
+			iamClient.EXPECT().
+				DeleteUserPolicy(&iam.DeleteUserPolicyInput{UserName: username, PolicyName: aws.String(userPolicyName)}).
+				Return(&iam.DeleteUserPolicyOutput{}, nil),
+			iamClient.EXPECT().
+				DeleteUser(&iam.DeleteUserInput{UserName: username}).
+				Return(&iam.DeleteUserOutput{}, nil),
+			s3Client.EXPECT().
+				DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String("provisioner"), Key: aws.String("installation1/access-key.json")}).
+				Return(&s3.DeleteObjectOutput{}, nil),
+		)
+
+		require.NoError(t, filestore.Teardown(true, nil, logger))
+	})
+
+	t.Run("delete installation bucket", func(t *testing.T) {
+		filestore, s3Client, _ := newTestFilestore(t, &ServerConfig{
+			Endpoint:        "https://minio.example.com",
+			Region:          DefaultRegion,
+			AccessKeyID:     "root",
+			SecretAccessKey: "secret",
+			BucketPrefix:    DefaultBucketPrefix,
+		})
+
+		gomock.InOrder(
+			s3Client.EXPECT().
+				ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String("mattermost-installation1")}).
+				Return(&s3.ListObjectsV2Output{
+					Contents:              []*s3.Object{{Key: aws.String("installation1/data/file1")}},
+					IsTruncated:           aws.Bool(true),
+					NextContinuationToken: aws.String("token"),
+				}, nil),
+			s3Client.EXPECT().
+				DeleteObjects(gomock.Any()).
+				Do(func(input *s3.DeleteObjectsInput) {
+					assert.Equal(t, []*s3.ObjectIdentifier{{Key: aws.String("installation1/data/file1")}}, input.Delete.Objects)
+				}).
+				Return(&s3.DeleteObjectsOutput{}, nil),
+			s3Client.EXPECT().
+				ListObjectsV2(&s3.ListObjectsV2Input{Bucket: aws.String("mattermost-installation1"), ContinuationToken: aws.String("token")}).
+				Return(&s3.ListObjectsV2Output{
+					Contents: []*s3.Object{{Key: aws.String("installation1/data/file2")}},
+				}, nil),
+			s3Client.EXPECT().
+				DeleteObjects(gomock.Any()).
+				Return(&s3.DeleteObjectsOutput{}, nil),
+			s3Client.EXPECT().
+				DeleteBucket(&s3.DeleteBucketInput{Bucket: aws.String("mattermost-installation1")}).
+				Return(&s3.DeleteBucketOutput{}, nil),
+		)
+
+		require.NoError(t, filestore.Teardown(false, nil, logger))
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+// Package s3compat manages Mattermost filestores on S3-compatible object
+// storage servers such as MinIO or Ceph RGW.
+package s3compat
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultRegion is the region used when the server secret doesn't
+	// specify one.
+	DefaultRegion = "us-east-1"
+	// DefaultBucketPrefix is the prefix of per-installation bucket names used
+	// when the server secret doesn't specify one.
+	DefaultBucketPrefix = "mattermost-"
+)
+
+// ServerConfig holds the location and the root credentials of an
+// S3-compatible object storage server. It is loaded from a secret which only
+// the provisioning server can read and is never exposed through the API.
+type ServerConfig struct {
+	// Endpoint is the HTTPS URL of the S3 API of the server.
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region,omitempty"`
+	AccessKeyID     string `json:"accessKeyID"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	// Bucket is a shared bucket installations get a directory in. Each
+	// installation gets its own bucket if it is not set.
+	Bucket       string `json:"bucket,omitempty"`
+	BucketPrefix string `json:"bucketPrefix,omitempty"`
+	// IAMEndpoint is the URL of an IAM-compatible API of the server used to
+	// create a user scoped to its files for each installation. Installations
+	// use the root credentials if it is not set.
+	IAMEndpoint string `json:"iamEndpoint,omitempty"`
+	// CredentialsBucket is the bucket the access keys of installation users
+	// are stored in. It is required with an IAM endpoint.
+	CredentialsBucket string `json:"credentialsBucket,omitempty"`
+}
+
+// NewServerConfigFromReader decodes a ServerConfig from an io.Reader with JSON
+// data and sets the defaults of missing optional values.
+func NewServerConfigFromReader(reader io.Reader) (*ServerConfig, error) {
+	var config ServerConfig
+	decoder := json.NewDecoder(reader)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&config)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode S3-compatible server config")
+	}
+
+	config.setDefaults()
+
+	err = config.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "S3-compatible server config is invalid")
+	}
+
+	return &config, nil
+}
+
+// NewServerConfigFromFile loads a ServerConfig from the secret file at the
+// given path.
+func NewServerConfigFromFile(path string) (*ServerConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open S3-compatible server secret")
+	}
+	defer file.Close()
+
+	return NewServerConfigFromReader(file)
+}
+
+func (c *ServerConfig) setDefaults() {
+	if len(c.Region) == 0 {
+		c.Region = DefaultRegion
+	}
+	if len(c.Bucket) == 0 && len(c.BucketPrefix) == 0 {
+		c.BucketPrefix = DefaultBucketPrefix
+	}
+}
+
+// Validate returns an error if the server config is missing required values.
+func (c *ServerConfig) Validate() error {
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse endpoint")
+	}
+	// Mattermost servers always connect to external filestores with SSL.
+	if endpoint.Scheme != "https" || len(endpoint.Host) == 0 {
+		return errors.Errorf("endpoint %q must be an HTTPS URL", c.Endpoint)
+	}
+	if len(c.AccessKeyID) == 0 {
+		return errors.New("accessKeyID must be specified")
+	}
+	if len(c.SecretAccessKey) == 0 {
+		return errors.New("secretAccessKey must be specified")
+	}
+	if len(c.IAMEndpoint) != 0 {
+		_, err = url.Parse(c.IAMEndpoint)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse iamEndpoint")
+		}
+		if len(c.CredentialsBucket) == 0 {
+			return errors.New("credentialsBucket must be specified with iamEndpoint")
+		}
+	}
+
+	return nil
+}
+
+// ScopedUsers returns true if installations get their own users.
+func (c *ServerConfig) ScopedUsers() bool {
+	return len(c.IAMEndpoint) != 0
+}
+
+// Host returns the host of the endpoint, which is how Mattermost servers are
+// configured to reach the S3 API.
+func (c *ServerConfig) Host() string {
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return ""
+	}
+
+	return endpoint.Host
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package s3compat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServerConfigFromReader(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		config, err := NewServerConfigFromReader(strings.NewReader(`{"endpoint":"https://minio.example.com:9000","accessKeyID":"root","secretAccessKey":"secret"}`))
+		require.NoError(t, err)
+		assert.Equal(t, &ServerConfig{
+			Endpoint:        "https://minio.example.com:9000",
+			Region:          DefaultRegion,
+			AccessKeyID:     "root",
+			SecretAccessKey: "secret",
+			BucketPrefix:    DefaultBucketPrefix,
+		}, config)
+		assert.Equal(t, "minio.example.com:9000", config.Host())
+		assert.False(t, config.ScopedUsers())
+	})
+
+	t.Run("shared bucket with scoped users", func(t *testing.T) {
+		config, err := NewServerConfigFromReader(strings.NewReader(`{"endpoint":"https://rgw.example.com","accessKeyID":"root","secretAccessKey":"secret","bucket":"mattermost","iamEndpoint":"https://rgw.example.com/iam","credentialsBucket":"provisioner"}`))
+		require.NoError(t, err)
+		assert.Empty(t, config.BucketPrefix)
+		assert.True(t, config.ScopedUsers())
+	})
+
+	for _, tc := range []struct {
+		name   string
+		secret string
+	}{
+		{"empty", ``},
+		{"unknown field", `{"endpoint":"https://minio.example.com","accessKeyID":"root","secretAccessKey":"secret","unknown":true}`},
+		{"http endpoint", `{"endpoint":"http://minio.example.com","accessKeyID":"root","secretAccessKey":"secret"}`},
+		{"no access key", `{"endpoint":"https://minio.example.com","secretAccessKey":"secret"}`},
+		{"no secret key", `{"endpoint":"https://minio.example.com","accessKeyID":"root"}`},
+		{"iam endpoint without credentials bucket", `{"endpoint":"https://minio.example.com","accessKeyID":"root","secretAccessKey":"secret","iamEndpoint":"https://minio.example.com/iam"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewServerConfigFromReader(strings.NewReader(tc.secret))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/mattermost/mattermost-cloud/internal/tools/aws"
+	"github.com/mattermost/mattermost-cloud/internal/tools/postgres"
+	"github.com/mattermost/mattermost-cloud/internal/tools/s3compat"
 	"github.com/mattermost/mattermost-cloud/model"
 )
 
@@ -83,8 +85,10 @@ func copyFile(source string, dest string) error {
 
 // ResourceUtil is used for calling any filestore type.
 type ResourceUtil struct {
-	awsClient  *aws.Client
-	instanceID string
+	awsClient        *aws.Client
+	instanceID       string
+	externalPostgres *postgres.ServerConfig
+	s3Compatible     *s3compat.ServerConfig
 }
 
 // NewResourceUtil returns a new instance of ResourceUtil.
@@ -95,6 +99,18 @@ func NewResourceUtil(instanceID string, awsClient *aws.Client) *ResourceUtil {
 	}
 }
 
+// SetExternalPostgresServer sets the external PostgreSQL server used for
+// external postgres installation databases.
+func (r *ResourceUtil) SetExternalPostgresServer(server *postgres.ServerConfig) {
+	r.externalPostgres = server
+}
+
+// SetS3CompatibleFilestoreServer sets the S3-compatible object storage server
+// used for s3-compatible installation filestores.
+func (r *ResourceUtil) SetS3CompatibleFilestoreServer(server *s3compat.ServerConfig) {
+	r.s3Compatible = server
+}
+
 // GetFilestore returns the Filestore interface that matches the installation.
 func (r *ResourceUtil) GetFilestore(installation *model.Installation) model.Filestore {
 	switch installation.Filestore {
@@ -106,6 +122,8 @@ func (r *ResourceUtil) GetFilestore(installation *model.Installation) model.File
 		return aws.NewS3MultitenantFilestore(installation.ID, r.awsClient)
 	case model.InstallationFilestoreBifrost:
 		return aws.NewBifrostFilestore(installation.ID, r.awsClient)
+	case model.InstallationFilestoreS3Compatible:
+		return s3compat.NewFilestore(installation.ID, r.s3Compatible)
 	}
 
 	// Warning: we should never get here as it would mean that we didn't match
@@ -133,6 +151,8 @@ func (r *ResourceUtil) GetDatabase(installationID, dbType string) model.Database
 		return aws.NewRDSMultitenantDatabase(model.DatabaseEngineTypePostgres, r.instanceID, installationID, r.awsClient)
 	case model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer:
 		return aws.NewRDSMultitenantPGBouncerDatabase(model.DatabaseEngineTypePostgresProxy, r.instanceID, installationID, r.awsClient)
+	case model.InstallationDatabaseExternalPostgres:
+		return postgres.NewExternalDatabase(r.instanceID, installationID, r.externalPostgres)
 	}
 
 	// Warning: we should never get here as it would mean that we didn't match
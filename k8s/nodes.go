@@ -0,0 +1,94 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package k8s
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// GetNodesWithLabel returns the nodes which have the given label set to the
+// given value.
+func (kc *KubeClient) GetNodesWithLabel(key, value string) ([]corev1.Node, error) {
+	ctx := context.TODO()
+	nodes, err := kc.Clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{
+		LabelSelector: key + "=" + value,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+
+	return nodes.Items, nil
+}
+
+// CordonNode marks the node as unschedulable so that no new pods are
+// scheduled on it.
+func (kc *KubeClient) CordonNode(nodeName string) error {
+	ctx := context.TODO()
+	node, err := kc.Clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to get node %s", nodeName)
+	}
+	if node.Spec.Unschedulable {
+		return nil
+	}
+
+	node.Spec.Unschedulable = true
+	_, err = kc.Clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "failed to cordon node %s", nodeName)
+	}
+
+	return nil
+}
+
+// EvictPodsFromNode evicts the pods running on the node which match the given
+// label selector. The evicted pods are recreated on other nodes by their
+// controllers. Pod disruption budgets are respected, so pods which can't be
+// evicted yet are skipped. The names of the evicted pods are returned.
+func (kc *KubeClient) EvictPodsFromNode(nodeName, labelSelector string) ([]string, error) {
+	ctx := context.TODO()
+	pods, err := kc.Clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list pods on node %s", nodeName)
+	}
+
+	var evicted []string
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != nodeName || pod.DeletionTimestamp != nil {
+			continue
+		}
+
+		err = kc.Clientset.PolicyV1beta1().Evictions(pod.Namespace).Evict(ctx, &policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		})
+		if k8sErrors.IsNotFound(err) {
+			continue
+		}
+		if k8sErrors.IsTooManyRequests(err) {
+			kc.logger.Warnf("Pod %s/%s can't be evicted yet because of its disruption budget", pod.Namespace, pod.Name)
+			continue
+		}
+		if err != nil {
+			return evicted, errors.Wrapf(err, "failed to evict pod %s/%s", pod.Namespace, pod.Name)
+		}
+		evicted = append(evicted, strings.Join([]string{pod.Namespace, pod.Name}, "/"))
+	}
+
+	return evicted, nil
+}
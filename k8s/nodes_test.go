@@ -0,0 +1,67 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNodes(t *testing.T) {
+	testClient := newTestKubeClient()
+	ctx := context.TODO()
+
+	for _, node := range []*corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{Name: "spot-node", Labels: map[string]string{"spot": "true"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "node"}},
+	} {
+		_, err := testClient.Clientset.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	t.Run("get nodes with label", func(t *testing.T) {
+		nodes, err := testClient.GetNodesWithLabel("spot", "true")
+		require.NoError(t, err)
+		require.Len(t, nodes, 1)
+		assert.Equal(t, "spot-node", nodes[0].Name)
+	})
+
+	t.Run("cordon node", func(t *testing.T) {
+		err := testClient.CordonNode("spot-node")
+		require.NoError(t, err)
+
+		node, err := testClient.Clientset.CoreV1().Nodes().Get(ctx, "spot-node", metav1.GetOptions{})
+		require.NoError(t, err)
+		assert.True(t, node.Spec.Unschedulable)
+
+		err = testClient.CordonNode("spot-node")
+		require.NoError(t, err)
+	})
+
+	t.Run("cordon unknown node", func(t *testing.T) {
+		err := testClient.CordonNode("unknown")
+		require.Error(t, err)
+	})
+
+	t.Run("evict pods from node", func(t *testing.T) {
+		for _, pod := range []*corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "mm-1", Namespace: "ns1", Labels: map[string]string{"installation-id": "1"}}, Spec: corev1.PodSpec{NodeName: "spot-node"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "mm-2", Namespace: "ns2", Labels: map[string]string{"installation-id": "2"}}, Spec: corev1.PodSpec{NodeName: "node"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "ns1"}, Spec: corev1.PodSpec{NodeName: "spot-node"}},
+		} {
+			_, err := testClient.Clientset.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+			require.NoError(t, err)
+		}
+
+		evicted, err := testClient.EvictPodsFromNode("spot-node", "installation-id")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"ns1/mm-1"}, evicted)
+	})
+}
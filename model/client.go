@@ -259,6 +259,42 @@ func (c *Client) UpgradeCluster(clusterID string, request *PatchUpgradeClusterRe
 	}
 }
 
+// UpgradeClusterUtility upgrades or previews the upgrade of a single utility
+// of a cluster.
+func (c *Client) UpgradeClusterUtility(clusterID, utility string, request *UpgradeClusterUtilityRequest) (*ClusterDTO, error) {
+	resp, err := c.doPut(c.buildURL("/api/cluster/%s/utilities/%s", clusterID, utility), request)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return ClusterDTOFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// GetClusterUtilityValues fetches the values of a cluster utility rendered
+// with the cluster context.
+func (c *Client) GetClusterUtilityValues(clusterID, utility string) (*ClusterUtilityValues, error) {
+	resp, err := c.doGet(c.buildURL("/api/cluster/%s/utilities/%s/values", clusterID, utility))
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterUtilityValuesFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
 // ResizeCluster resizes a cluster with a new size value.
 func (c *Client) ResizeCluster(clusterID string, request *PatchClusterSizeRequest) (*ClusterDTO, error) {
 	resp, err := c.doPut(c.buildURL("/api/cluster/%s/size", clusterID), request)
@@ -497,6 +533,42 @@ func (c *Client) HibernateInstallation(installationID string) (*InstallationDTO,
 	}
 }
 
+// RotateInstallationDatabaseCredentials requests a rotation of the database
+// credentials of an installation.
+func (c *Client) RotateInstallationDatabaseCredentials(installationID string) (*InstallationDTO, error) {
+	resp, err := c.doPost(c.buildURL("/api/installation/%s/database/rotate_credentials", installationID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return InstallationDTOFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// RotateInstallationFilestoreAccessKey requests a rotation of the filestore
+// access key of an installation.
+func (c *Client) RotateInstallationFilestoreAccessKey(installationID string) (*InstallationDTO, error) {
+	resp, err := c.doPost(c.buildURL("/api/installation/%s/filestore/rotate_access_key", installationID), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return InstallationDTOFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
 // WakeupInstallation wakes an installation from hibernation.
 func (c *Client) WakeupInstallation(installationID string, request *PatchInstallationRequest) (*InstallationDTO, error) {
 	resp, err := c.doPost(c.buildURL("/api/installation/%s/wakeup", installationID), request)
@@ -1168,6 +1240,24 @@ func (c *Client) UpdateMultitenantDatabase(databaseID string, request *PatchMult
 	}
 }
 
+// UpgradeMultitenantDatabase requests an engine version upgrade of a
+// multitenant database.
+func (c *Client) UpgradeMultitenantDatabase(databaseID string, request *UpgradeMultitenantDatabaseRequest) (*MultitenantDatabase, error) {
+	resp, err := c.doPost(c.buildURL("/api/databases/multitenant_database/%s/upgrade", databaseID), request)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusAccepted:
+		return MultitenantDatabaseFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
 // DeleteMultitenantDatabase marks multitenant database as deleted.
 func (c *Client) DeleteMultitenantDatabase(databaseID string, force bool) error {
 	u := c.buildURL("/api/databases/multitenant_database/%s?force=%t", databaseID, force)
@@ -1352,6 +1442,140 @@ func (c *Client) DeleteWebhook(webhookID string) error {
 	}
 }
 
+// CreateClusterTemplate requests the creation of a cluster template from the
+// configured provisioning server.
+func (c *Client) CreateClusterTemplate(request *CreateClusterTemplateRequest) (*ClusterTemplate, error) {
+	resp, err := c.doPost(c.buildURL("/api/cluster_templates"), request)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterTemplateFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// GetClusterTemplate fetches the cluster template from the configured
+// provisioning server.
+func (c *Client) GetClusterTemplate(templateID string) (*ClusterTemplate, error) {
+	resp, err := c.doGet(c.buildURL("/api/cluster_template/%s", templateID))
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterTemplateFromReader(resp.Body)
+
+	case http.StatusNotFound:
+		return nil, nil
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// GetClusterTemplates fetches the list of cluster templates from the
+// configured provisioning server.
+func (c *Client) GetClusterTemplates(request *GetClusterTemplatesRequest) ([]*ClusterTemplate, error) {
+	u, err := url.Parse(c.buildURL("/api/cluster_templates"))
+	if err != nil {
+		return nil, err
+	}
+
+	request.ApplyToURL(u)
+
+	resp, err := c.doGet(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterTemplatesFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// UpdateClusterTemplate updates the cluster template.
+func (c *Client) UpdateClusterTemplate(templateID string, request *PatchClusterTemplateRequest) (*ClusterTemplate, error) {
+	resp, err := c.doPut(c.buildURL("/api/cluster_template/%s", templateID), request)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterTemplateFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// DeleteClusterTemplate deletes the given cluster template.
+func (c *Client) DeleteClusterTemplate(templateID string) error {
+	resp, err := c.doDelete(c.buildURL("/api/cluster_template/%s", templateID))
+	if err != nil {
+		return err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+
+	default:
+		return errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// CreateClusterSize requests the creation of a cluster size from the
+// configured provisioning server.
+func (c *Client) CreateClusterSize(request *CreateClusterSizeRequest) (*ClusterSize, error) {
+	resp, err := c.doPost(c.buildURL("/api/cluster_sizes"), request)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterSizeFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
+// GetClusterSizes fetches the list of cluster sizes from the configured
+// provisioning server.
+func (c *Client) GetClusterSizes() ([]*ClusterSize, error) {
+	resp, err := c.doGet(c.buildURL("/api/cluster_sizes"))
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterSizesFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
 // LockAPIForCluster locks API changes for a given cluster.
 func (c *Client) LockAPIForCluster(clusterID string) error {
 	return c.makeSecurityCall("cluster", clusterID, "api", "lock")
@@ -1419,26 +1643,32 @@ func (c *Client) makeSecurityCall(resourceType, id, securityType, action string)
 
 }
 
-// MigrateClusterInstallation requests the migration of cluster installation(s) from the configured provisioning server.
-func (c *Client) MigrateClusterInstallation(request *MigrateClusterInstallationRequest) (*MigrateClusterInstallationResponse, error) {
-	resp, err := c.doPost(c.buildURL("/api/cluster_installations/migrate"), request)
+// CreateClusterMigration requests the migration of cluster installation(s) between clusters from the configured provisioning server.
+func (c *Client) CreateClusterMigration(request *CreateClusterMigrationRequest) (*ClusterMigrationOperation, error) {
+	resp, err := c.doPost(c.buildURL("/api/cluster_installations/migrations"), request)
 	if err != nil {
 		return nil, err
 	}
 	defer closeBody(resp)
 
 	switch resp.StatusCode {
-	case http.StatusOK:
-		return MigrateClusterInstallationResponseFromReader(resp.Body)
+	case http.StatusAccepted:
+		return NewClusterMigrationOperationFromReader(resp.Body)
 
 	default:
 		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
 	}
 }
 
-// MigrateDNS requests the migration of cluster installation(s) from the configured provisioning server.
-func (c *Client) MigrateDNS(request *MigrateClusterInstallationRequest) (*MigrateClusterInstallationResponse, error) {
-	resp, err := c.doPost(c.buildURL("/api/cluster_installations/migrate/dns"), request)
+// GetClusterMigrationOperations fetches the list of cluster migration operations from the configured provisioning server.
+func (c *Client) GetClusterMigrationOperations(request *GetClusterMigrationOperationsRequest) ([]*ClusterMigrationOperation, error) {
+	u, err := url.Parse(c.buildURL("/api/cluster_installations/migrations"))
+	if err != nil {
+		return nil, err
+	}
+	request.ApplyToURL(u)
+
+	resp, err := c.doGet(u.String())
 	if err != nil {
 		return nil, err
 	}
@@ -1446,16 +1676,16 @@ func (c *Client) MigrateDNS(request *MigrateClusterInstallationRequest) (*Migrat
 
 	switch resp.StatusCode {
 	case http.StatusOK:
-		return MigrateClusterInstallationResponseFromReader(resp.Body)
+		return NewClusterMigrationOperationsFromReader(resp.Body)
 
 	default:
 		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
 	}
 }
 
-// DeleteInActiveClusterInstallationsByCluster requests the deletion of inactive cluster installation(s) from the configured provisioning server.
-func (c *Client) DeleteInActiveClusterInstallationsByCluster(clusterID string) (*MigrateClusterInstallationResponse, error) {
-	resp, err := c.doDelete(c.buildURL("/api/cluster_installations/migrate/delete_inactive/%s", clusterID))
+// GetClusterMigrationOperation fetches the specified cluster migration operation from the configured provisioning server.
+func (c *Client) GetClusterMigrationOperation(id string) (*ClusterMigrationOperation, error) {
+	resp, err := c.doGet(c.buildURL("/api/cluster_installations/migration/%s", id))
 	if err != nil {
 		return nil, err
 	}
@@ -1463,33 +1693,36 @@ func (c *Client) DeleteInActiveClusterInstallationsByCluster(clusterID string) (
 
 	switch resp.StatusCode {
 	case http.StatusOK:
-		return MigrateClusterInstallationResponseFromReader(resp.Body)
+		return NewClusterMigrationOperationFromReader(resp.Body)
+
+	case http.StatusNotFound:
+		return nil, nil
 
 	default:
 		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
 	}
 }
 
-// DeleteInActiveClusterInstallationByID requests the deletion of specific inactive cluster installation from the configured provisioning server.
-func (c *Client) DeleteInActiveClusterInstallationByID(clusterInstallationID string) (*ClusterInstallation, error) {
-	resp, err := c.doDelete(c.buildURL("/api/cluster_installations/migrate/delete_inactive/cluster_installation/%s", clusterInstallationID))
+// RollbackClusterMigration requests the rollback of the cluster migration from the configured provisioning server.
+func (c *Client) RollbackClusterMigration(id string) (*ClusterMigrationOperation, error) {
+	resp, err := c.doPost(c.buildURL("/api/cluster_installations/migration/%s/rollback", id), nil)
 	if err != nil {
 		return nil, err
 	}
 	defer closeBody(resp)
 
 	switch resp.StatusCode {
-	case http.StatusOK:
-		return ClusterInstallationFromReader(resp.Body)
+	case http.StatusAccepted:
+		return NewClusterMigrationOperationFromReader(resp.Body)
 
 	default:
 		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
 	}
 }
 
-// SwitchClusterRoles requests the migration of cluster installation(s) from the configured provisioning server.
-func (c *Client) SwitchClusterRoles(request *MigrateClusterInstallationRequest) (*MigrateClusterInstallationResponse, error) {
-	resp, err := c.doPost(c.buildURL("/api/cluster_installations/migrate/switch_cluster_roles"), request)
+// DeleteInActiveClusterInstallationsByCluster requests the deletion of inactive cluster installation(s) from the configured provisioning server.
+func (c *Client) DeleteInActiveClusterInstallationsByCluster(clusterID string) (*MigrateClusterInstallationResponse, error) {
+	resp, err := c.doDelete(c.buildURL("/api/cluster_installations/migrate/delete_inactive/%s", clusterID))
 	if err != nil {
 		return nil, err
 	}
@@ -1504,6 +1737,23 @@ func (c *Client) SwitchClusterRoles(request *MigrateClusterInstallationRequest)
 	}
 }
 
+// DeleteInActiveClusterInstallationByID requests the deletion of specific inactive cluster installation from the configured provisioning server.
+func (c *Client) DeleteInActiveClusterInstallationByID(clusterInstallationID string) (*ClusterInstallation, error) {
+	resp, err := c.doDelete(c.buildURL("/api/cluster_installations/migrate/delete_inactive/cluster_installation/%s", clusterInstallationID))
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return ClusterInstallationFromReader(resp.Body)
+
+	default:
+		return nil, errors.Errorf("failed with status code %d", resp.StatusCode)
+	}
+}
+
 // ListStateChangeEvents request events lists from the configured provisioning server.
 func (c *Client) ListStateChangeEvents(request *ListStateChangeEventsRequest) ([]*StateChangeEventData, error) {
 	u, err := url.Parse(c.buildURL("/api/events/state_change"))
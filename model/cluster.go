@@ -24,6 +24,16 @@ const (
 	KubecostToken = "kubecost-token"
 )
 
+const (
+	// ProvisionerKops is the provisioner of clusters created with kops and
+	// terraform.
+	ProvisionerKops = "kops"
+	// ProvisionerEKS is the provisioner of clusters managed by AWS EKS. EKS
+	// clusters are provisioned without utilities and cannot host
+	// installations.
+	ProvisionerEKS = "eks"
+)
+
 // Cluster represents a Kubernetes cluster.
 type Cluster struct {
 	ID                      string
@@ -32,7 +42,9 @@ type Cluster struct {
 	ProviderMetadataAWS     *AWSMetadata
 	Provisioner             string
 	ProvisionerMetadataKops *KopsMetadata
+	ProvisionerMetadataEKS  *EKSMetadata
 	UtilityMetadata         *UtilityMetadata
+	UpgradeReport           *ClusterUpgradeReport
 	AllowInstallations      bool
 	CreateAt                int64
 	DeleteAt                int64
@@ -40,6 +52,7 @@ type Cluster struct {
 	LockAcquiredBy          *string
 	LockAcquiredAt          int64
 	Networking              string
+	ClusterTemplateID       string
 }
 
 // Clone returns a deep copy the cluster.
@@ -51,6 +64,11 @@ func (c *Cluster) Clone() *Cluster {
 	return &clone
 }
 
+// IsEKS returns true if the cluster is managed by EKS.
+func (c *Cluster) IsEKS() bool {
+	return c.Provisioner == ProvisionerEKS
+}
+
 // ToDTO expands cluster to ClusterDTO.
 func (c *Cluster) ToDTO(annotations []*Annotation) *ClusterDTO {
 	return &ClusterDTO{
@@ -33,22 +33,7 @@ type ClusterInstallationFilter struct {
 	IsActive       *bool
 }
 
-// MigrateClusterInstallationRequest describes the parameters used to compose migration request between two clusters.
-type MigrateClusterInstallationRequest struct {
-	InstallationID   string
-	SourceClusterID  string
-	TargetClusterID  string
-	DNSSwitch        bool
-	LockInstallation bool
-}
-
 const (
-	// OperationTypeMigration is used for CIs migration from source to target cluster.
-	OperationTypeMigration = "MigratingClusterInstallation"
-	// OperationTypeDNS is used for DNS Switch from source to target cluster.
-	OperationTypeDNS = "DNSSwitch"
-	// OperationTypeSwitchClusterRoles is used for Switching the tags between source & target clusters.
-	OperationTypeSwitchClusterRoles = "SwitchClusterRoles"
 	// OperationTypeDeletingInActiveCIs is used for Deleting InActive ClusterInstallations from source clusters.
 	OperationTypeDeletingInActiveCIs = "DeletingInActiveClusterInstallations"
 )
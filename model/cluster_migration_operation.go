@@ -0,0 +1,237 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// ClusterMigrationOperation contains information about migration of
+// installations from one cluster to another.
+type ClusterMigrationOperation struct {
+	ID string
+	// SourceClusterID is the cluster the installations are migrated from.
+	SourceClusterID string
+	// TargetClusterID is the cluster the installations are migrated to.
+	TargetClusterID string
+	// InstallationID limits the migration to a single installation. When
+	// empty, all installations of the source cluster are migrated.
+	InstallationID string
+	// LockInstallations controls if installations are locked while their DNS
+	// records are switched.
+	LockInstallations bool
+	// SwitchClusterRoles controls if the roles of the source and target
+	// clusters are switched once the migration is complete.
+	SwitchClusterRoles bool
+	// DNSSwitched is set once DNS records were switched to the target cluster.
+	DNSSwitched bool
+	// ClusterInstallations are the cluster installations taking part in the
+	// migration.
+	ClusterInstallations []ClusterMigrationClusterInstallation
+	RequestAt            int64
+	State                ClusterMigrationOperationState
+	CompleteAt           int64
+	DeleteAt             int64
+	LockAcquiredBy       *string
+	LockAcquiredAt       int64
+}
+
+// ClusterMigrationClusterInstallation pairs the source and target cluster
+// installations of a single migrated installation.
+type ClusterMigrationClusterInstallation struct {
+	InstallationID              string
+	SourceClusterInstallationID string
+	TargetClusterInstallationID string
+}
+
+// ClusterMigrationOperationState represents the state of cluster migration operation.
+type ClusterMigrationOperationState string
+
+const (
+	// ClusterMigrationStateRequested is requested cluster migration operation.
+	ClusterMigrationStateRequested ClusterMigrationOperationState = "cluster-migration-requested"
+	// ClusterMigrationStateWaitingForClusterInstallations is cluster migration operation waiting for target cluster installations to become stable.
+	ClusterMigrationStateWaitingForClusterInstallations ClusterMigrationOperationState = "cluster-migration-waiting-for-cluster-installations"
+	// ClusterMigrationStateSwitchingDNS is cluster migration operation that is switching installations DNS to the target cluster.
+	ClusterMigrationStateSwitchingDNS ClusterMigrationOperationState = "cluster-migration-switching-dns"
+	// ClusterMigrationStateWaitingForInstallations is cluster migration operation waiting for installations to become stable after the DNS switch.
+	ClusterMigrationStateWaitingForInstallations ClusterMigrationOperationState = "cluster-migration-waiting-for-installations"
+	// ClusterMigrationStateCleanup is cluster migration operation that is removing source cluster installations.
+	ClusterMigrationStateCleanup ClusterMigrationOperationState = "cluster-migration-cleanup"
+	// ClusterMigrationStateSucceeded is cluster migration operation that finished with success.
+	ClusterMigrationStateSucceeded ClusterMigrationOperationState = "cluster-migration-succeeded"
+	// ClusterMigrationStateRollbackRequested is cluster migration operation that is requested to be rolled back.
+	ClusterMigrationStateRollbackRequested ClusterMigrationOperationState = "cluster-migration-rollback-requested"
+	// ClusterMigrationStateRollbackFinished is cluster migration operation that was rolled back.
+	ClusterMigrationStateRollbackFinished ClusterMigrationOperationState = "cluster-migration-rollback-finished"
+	// ClusterMigrationStateFailing is cluster migration operation that is failing and is being reverted.
+	ClusterMigrationStateFailing ClusterMigrationOperationState = "cluster-migration-failing"
+	// ClusterMigrationStateFailed is cluster migration operation that failed.
+	ClusterMigrationStateFailed ClusterMigrationOperationState = "cluster-migration-failed"
+)
+
+// AllClusterMigrationOperationsStatesPendingWork is a list of all cluster migration operations states
+// that the supervisor will attempt to transition towards stable on the next "tick".
+var AllClusterMigrationOperationsStatesPendingWork = []ClusterMigrationOperationState{
+	ClusterMigrationStateRequested,
+	ClusterMigrationStateWaitingForClusterInstallations,
+	ClusterMigrationStateSwitchingDNS,
+	ClusterMigrationStateWaitingForInstallations,
+	ClusterMigrationStateCleanup,
+	ClusterMigrationStateRollbackRequested,
+	ClusterMigrationStateFailing,
+}
+
+// ClusterMigrationStatesAllowingRollback is a list of cluster migration
+// operation states from which rollback can be requested. Once source cluster
+// installations are cleaned up the migration can no longer be reverted.
+var ClusterMigrationStatesAllowingRollback = []ClusterMigrationOperationState{
+	ClusterMigrationStateRequested,
+	ClusterMigrationStateWaitingForClusterInstallations,
+	ClusterMigrationStateSwitchingDNS,
+	ClusterMigrationStateWaitingForInstallations,
+}
+
+// ClusterMigrationFilter describes the parameters used to constrain a set of cluster migration operations.
+type ClusterMigrationFilter struct {
+	Paging
+	IDs             []string
+	InstallationID  string
+	SourceClusterID string
+	TargetClusterID string
+	States          []ClusterMigrationOperationState
+}
+
+// CreateClusterMigrationRequest specifies parameters for a new cluster migration operation.
+type CreateClusterMigrationRequest struct {
+	SourceClusterID    string
+	TargetClusterID    string
+	InstallationID     string
+	LockInstallations  bool
+	SwitchClusterRoles bool
+}
+
+// GetClusterMigrationOperationsRequest describes the parameters to request
+// a list of cluster migration operations.
+type GetClusterMigrationOperationsRequest struct {
+	Paging
+	InstallationID  string
+	SourceClusterID string
+	TargetClusterID string
+	State           string
+}
+
+// ApplyToURL modifies the given url to include query string parameters for the request.
+func (request *GetClusterMigrationOperationsRequest) ApplyToURL(u *url.URL) {
+	q := u.Query()
+	q.Add("installation", request.InstallationID)
+	q.Add("source_cluster", request.SourceClusterID)
+	q.Add("target_cluster", request.TargetClusterID)
+	q.Add("state", request.State)
+	request.Paging.AddToQuery(q)
+
+	u.RawQuery = q.Encode()
+}
+
+// Validate validates the values of a cluster migration create request.
+func (request *CreateClusterMigrationRequest) Validate() error {
+	if len(request.SourceClusterID) == 0 {
+		return errors.New("missing mandatory source cluster in a migration request")
+	}
+	if len(request.TargetClusterID) == 0 {
+		return errors.New("missing mandatory target cluster in a migration request")
+	}
+	if request.SourceClusterID == request.TargetClusterID {
+		return errors.New("source and target clusters must be different")
+	}
+
+	return nil
+}
+
+// NewCreateClusterMigrationRequestFromReader will create a CreateClusterMigrationRequest from an io.Reader with JSON data.
+func NewCreateClusterMigrationRequestFromReader(reader io.Reader) (*CreateClusterMigrationRequest, error) {
+	var request CreateClusterMigrationRequest
+	err := json.NewDecoder(reader).Decode(&request)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode create cluster migration request")
+	}
+
+	err = request.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "create cluster migration request failed validation")
+	}
+
+	return &request, nil
+}
+
+// NewClusterMigrationOperationFromReader will create a ClusterMigrationOperation from an
+// io.Reader with JSON data.
+func NewClusterMigrationOperationFromReader(reader io.Reader) (*ClusterMigrationOperation, error) {
+	var operation ClusterMigrationOperation
+	err := json.NewDecoder(reader).Decode(&operation)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode ClusterMigrationOperation")
+	}
+
+	return &operation, nil
+}
+
+// NewClusterMigrationOperationsFromReader will create a slice of ClusterMigrationOperations from an
+// io.Reader with JSON data.
+func NewClusterMigrationOperationsFromReader(reader io.Reader) ([]*ClusterMigrationOperation, error) {
+	operations := []*ClusterMigrationOperation{}
+	err := json.NewDecoder(reader).Decode(&operations)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode ClusterMigrationOperations")
+	}
+
+	return operations, nil
+}
+
+// IsPendingWork returns true if the operation is not yet in a final state.
+func (o *ClusterMigrationOperation) IsPendingWork() bool {
+	for _, state := range AllClusterMigrationOperationsStatesPendingWork {
+		if o.State == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanBeRolledBack returns true if rollback can be requested for the operation.
+func (o *ClusterMigrationOperation) CanBeRolledBack() bool {
+	for _, state := range ClusterMigrationStatesAllowingRollback {
+		if o.State == state {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SourceClusterInstallationIDs returns IDs of all source cluster installations.
+func (o *ClusterMigrationOperation) SourceClusterInstallationIDs() []string {
+	ids := make([]string, 0, len(o.ClusterInstallations))
+	for _, ci := range o.ClusterInstallations {
+		ids = append(ids, ci.SourceClusterInstallationID)
+	}
+
+	return ids
+}
+
+// TargetClusterInstallationIDs returns IDs of all target cluster installations.
+func (o *ClusterMigrationOperation) TargetClusterInstallationIDs() []string {
+	ids := make([]string, 0, len(o.ClusterInstallations))
+	for _, ci := range o.ClusterInstallations {
+		ids = append(ids, ci.TargetClusterInstallationID)
+	}
+
+	return ids
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCreateClusterMigrationRequestFromReader(t *testing.T) {
+	t.Run("invalid", func(t *testing.T) {
+		request, err := NewCreateClusterMigrationRequestFromReader(bytes.NewReader([]byte(
+			"{test",
+		)))
+		require.Error(t, err)
+		require.Nil(t, request)
+	})
+
+	t.Run("missing target cluster", func(t *testing.T) {
+		request, err := NewCreateClusterMigrationRequestFromReader(bytes.NewReader([]byte(
+			`{"SourceClusterID": "source"}`,
+		)))
+		require.Error(t, err)
+		require.Nil(t, request)
+	})
+
+	t.Run("same clusters", func(t *testing.T) {
+		request, err := NewCreateClusterMigrationRequestFromReader(bytes.NewReader([]byte(
+			`{"SourceClusterID": "source", "TargetClusterID": "source"}`,
+		)))
+		require.Error(t, err)
+		require.Nil(t, request)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		request, err := NewCreateClusterMigrationRequestFromReader(bytes.NewReader([]byte(
+			`{"SourceClusterID": "source", "TargetClusterID": "target", "LockInstallations": true}`,
+		)))
+		require.NoError(t, err)
+		require.Equal(t, &CreateClusterMigrationRequest{
+			SourceClusterID:   "source",
+			TargetClusterID:   "target",
+			LockInstallations: true,
+		}, request)
+	})
+}
+
+func TestNewClusterMigrationOperationFromReader(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		operation, err := NewClusterMigrationOperationFromReader(bytes.NewReader([]byte(
+			"",
+		)))
+		require.NoError(t, err)
+		require.Equal(t, &ClusterMigrationOperation{}, operation)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		operation, err := NewClusterMigrationOperationFromReader(bytes.NewReader([]byte(
+			`{"ID":"id", "SourceClusterID": "source", "TargetClusterID": "target", "State": "cluster-migration-requested", "ClusterInstallations": [{"InstallationID": "installation"}]}`,
+		)))
+		require.NoError(t, err)
+		require.Equal(t, &ClusterMigrationOperation{
+			ID:              "id",
+			SourceClusterID: "source",
+			TargetClusterID: "target",
+			State:           ClusterMigrationStateRequested,
+			ClusterInstallations: []ClusterMigrationClusterInstallation{
+				{InstallationID: "installation"},
+			},
+		}, operation)
+	})
+}
+
+func TestNewClusterMigrationOperationsFromReader(t *testing.T) {
+	operations, err := NewClusterMigrationOperationsFromReader(bytes.NewReader([]byte(
+		`[{"ID":"id1"}, {"ID":"id2"}]`,
+	)))
+	require.NoError(t, err)
+	require.Equal(t, []*ClusterMigrationOperation{{ID: "id1"}, {ID: "id2"}}, operations)
+}
+
+func TestClusterMigrationOperation_States(t *testing.T) {
+	operation := &ClusterMigrationOperation{State: ClusterMigrationStateSwitchingDNS}
+	assert.True(t, operation.IsPendingWork())
+	assert.True(t, operation.CanBeRolledBack())
+
+	operation.State = ClusterMigrationStateCleanup
+	assert.True(t, operation.IsPendingWork())
+	assert.False(t, operation.CanBeRolledBack())
+
+	operation.State = ClusterMigrationStateRollbackFinished
+	assert.False(t, operation.IsPendingWork())
+	assert.False(t, operation.CanBeRolledBack())
+}
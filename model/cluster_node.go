@@ -0,0 +1,26 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import "strings"
+
+// ClusterNode is a k8s worker node of a cluster.
+type ClusterNode struct {
+	Name          string
+	InstanceID    string
+	NodeGroup     string
+	Unschedulable bool
+}
+
+// InstanceIDFromProviderID returns the cloud provider instance ID of a k8s
+// node provider ID, for example i-0123456789abcdef0 for
+// aws:///us-east-1a/i-0123456789abcdef0.
+func InstanceIDFromProviderID(providerID string) string {
+	if len(providerID) == 0 {
+		return ""
+	}
+
+	return providerID[strings.LastIndex(providerID, "/")+1:]
+}
@@ -22,6 +22,7 @@ const (
 // CreateClusterRequest specifies the parameters for a new cluster.
 type CreateClusterRequest struct {
 	Provider               string                         `json:"provider,omitempty"`
+	Provisioner            string                         `json:"provisioner,omitempty"`
 	Zones                  []string                       `json:"zones,omitempty"`
 	Version                string                         `json:"version,omitempty"`
 	KopsAMI                string                         `json:"kops-ami,omitempty"`
@@ -36,6 +37,11 @@ type CreateClusterRequest struct {
 	Annotations            []string                       `json:"annotations,omitempty"`
 	Networking             string                         `json:"networking,omitempty"`
 	VPC                    string                         `json:"vpc,omitempty"`
+	Template               string                         `json:"template,omitempty"`
+	Size                   string                         `json:"size,omitempty"`
+	AdditionalNodeGroups   KopsInstanceGroupsMetadata     `json:"additional-node-groups,omitempty"`
+	EKSClusterRoleARN      string                         `json:"eks-cluster-role-arn,omitempty"`
+	EKSNodeRoleARN         string                         `json:"eks-node-role-arn,omitempty"`
 }
 
 func (request *CreateClusterRequest) setUtilityDefaults(utilityName string) {
@@ -58,11 +64,159 @@ func (request *CreateClusterRequest) setUtilitiesDefaults() {
 	}
 }
 
+// Clone returns a deep copy of the cluster create request.
+func (request *CreateClusterRequest) Clone() *CreateClusterRequest {
+	var clone CreateClusterRequest
+	data, _ := json.Marshal(request)
+	json.Unmarshal(data, &clone)
+
+	return &clone
+}
+
+// Cluster returns the new cluster described by the request, which is ready to
+// be created by its provisioner.
+func (request *CreateClusterRequest) Cluster(clusterTemplateID string) *Cluster {
+	cluster := &Cluster{
+		Provider: request.Provider,
+		ProviderMetadataAWS: &AWSMetadata{
+			Zones: request.Zones,
+		},
+		Provisioner: ProvisionerKops,
+		ProvisionerMetadataKops: &KopsMetadata{
+			ChangeRequest: &KopsMetadataRequestedState{
+				Version:            request.Version,
+				AMI:                request.KopsAMI,
+				MasterInstanceType: request.MasterInstanceType,
+				MasterCount:        request.MasterCount,
+				NodeInstanceType:   request.NodeInstanceType,
+				NodeMinCount:       request.NodeMinCount,
+				NodeMaxCount:       request.NodeMaxCount,
+				Networking:         request.Networking,
+				VPC:                request.VPC,
+
+				AdditionalNodeGroups: request.AdditionalNodeGroups,
+			},
+		},
+
+		AllowInstallations: request.AllowInstallations,
+		APISecurityLock:    request.APISecurityLock,
+		State:              ClusterStateCreationRequested,
+		ClusterTemplateID:  clusterTemplateID,
+	}
+
+	if request.Provisioner == ProvisionerEKS {
+		cluster.Provisioner = ProvisionerEKS
+		cluster.ProvisionerMetadataKops = nil
+		cluster.ProvisionerMetadataEKS = &EKSMetadata{
+			ClusterRoleARN: request.EKSClusterRoleARN,
+			NodeRoleARN:    request.EKSNodeRoleARN,
+			ChangeRequest: &EKSMetadataRequestedState{
+				Version:          EKSVersion(request.Version),
+				NodeInstanceType: request.NodeInstanceType,
+				NodeMinCount:     request.NodeMinCount,
+				NodeMaxCount:     request.NodeMaxCount,
+				VPC:              request.VPC,
+			},
+		}
+	}
+
+	cluster.SetUtilityDesiredVersions(request.DesiredUtilityVersions)
+
+	return cluster
+}
+
+// ApplyClusterTemplate fills the values of the request which were not provided
+// with the values of the given cluster template. Provided values override the
+// template ones, utility chart versions and values are overridden separately
+// and annotations are added to the annotations of the template.
+func (request *CreateClusterRequest) ApplyClusterTemplate(template *ClusterTemplate) {
+	if template.Cluster == nil {
+		return
+	}
+	templateCluster := template.Cluster.Clone()
+
+	if len(request.Provider) == 0 {
+		request.Provider = templateCluster.Provider
+	}
+	if len(request.Provisioner) == 0 {
+		request.Provisioner = templateCluster.Provisioner
+	}
+	if len(request.Zones) == 0 {
+		request.Zones = templateCluster.Zones
+	}
+	if len(request.Version) == 0 {
+		request.Version = templateCluster.Version
+	}
+	if len(request.KopsAMI) == 0 {
+		request.KopsAMI = templateCluster.KopsAMI
+	}
+	if len(request.MasterInstanceType) == 0 {
+		request.MasterInstanceType = templateCluster.MasterInstanceType
+	}
+	if request.MasterCount == 0 {
+		request.MasterCount = templateCluster.MasterCount
+	}
+	if len(request.NodeInstanceType) == 0 {
+		request.NodeInstanceType = templateCluster.NodeInstanceType
+	}
+	if request.NodeMinCount == 0 {
+		request.NodeMinCount = templateCluster.NodeMinCount
+	}
+	if request.NodeMaxCount == 0 {
+		request.NodeMaxCount = templateCluster.NodeMaxCount
+	}
+	if len(request.Networking) == 0 {
+		request.Networking = templateCluster.Networking
+	}
+	if len(request.VPC) == 0 {
+		request.VPC = templateCluster.VPC
+	}
+	if request.AdditionalNodeGroups == nil {
+		request.AdditionalNodeGroups = templateCluster.AdditionalNodeGroups
+	}
+	if len(request.EKSClusterRoleARN) == 0 {
+		request.EKSClusterRoleARN = templateCluster.EKSClusterRoleARN
+	}
+	if len(request.EKSNodeRoleARN) == 0 {
+		request.EKSNodeRoleARN = templateCluster.EKSNodeRoleARN
+	}
+	request.AllowInstallations = request.AllowInstallations || templateCluster.AllowInstallations
+	request.APISecurityLock = request.APISecurityLock || templateCluster.APISecurityLock
+
+	if len(templateCluster.DesiredUtilityVersions) != 0 {
+		if request.DesiredUtilityVersions == nil {
+			request.DesiredUtilityVersions = make(map[string]*HelmUtilityVersion)
+		}
+		for utilityName, version := range templateCluster.DesiredUtilityVersions {
+			requestVersion, ok := request.DesiredUtilityVersions[utilityName]
+			if !ok || requestVersion == nil {
+				request.DesiredUtilityVersions[utilityName] = version
+				continue
+			}
+			if len(requestVersion.Chart) == 0 {
+				requestVersion.Chart = version.Chart
+			}
+			if len(requestVersion.ValuesPath) == 0 {
+				requestVersion.ValuesPath = version.ValuesPath
+			}
+		}
+	}
+
+	for _, annotation := range templateCluster.Annotations {
+		if !contains(request.Annotations, annotation) {
+			request.Annotations = append(request.Annotations, annotation)
+		}
+	}
+}
+
 // SetDefaults sets the default values for a cluster create request.
 func (request *CreateClusterRequest) SetDefaults() {
 	if len(request.Provider) == 0 {
 		request.Provider = ProviderAWS
 	}
+	if len(request.Provisioner) == 0 {
+		request.Provisioner = ProvisionerKops
+	}
 	if len(request.Version) == 0 {
 		request.Version = "latest"
 	}
@@ -110,7 +264,25 @@ func (request *CreateClusterRequest) Validate() error {
 	if request.NodeMaxCount != request.NodeMinCount {
 		return errors.Errorf("node min (%d) and max (%d) counts must match", request.NodeMinCount, request.NodeMaxCount)
 	}
-	// TODO: check zones and instance types?
+	if !ValidInstanceType(request.MasterInstanceType) {
+		return errors.Errorf("invalid master instance type %q", request.MasterInstanceType)
+	}
+	if !ValidInstanceType(request.NodeInstanceType) {
+		return errors.Errorf("invalid node instance type %q", request.NodeInstanceType)
+	}
+	err := request.AdditionalNodeGroups.validateAdditionalNodeGroups()
+	if err != nil {
+		return err
+	}
+	err = request.validateProvisioner()
+	if err != nil {
+		return err
+	}
+	err = validateRequestUtilityVersions(request.DesiredUtilityVersions)
+	if err != nil {
+		return err
+	}
+	// TODO: check zones?
 
 	if !contains(GetSupportedCniList(), request.Networking) {
 		return errors.Errorf("unsupported cluster networking option %s", request.Networking)
@@ -118,6 +290,34 @@ func (request *CreateClusterRequest) Validate() error {
 	return nil
 }
 
+// validateProvisioner validates the values specific to the requested
+// provisioner.
+func (request *CreateClusterRequest) validateProvisioner() error {
+	switch request.Provisioner {
+	case ProvisionerKops:
+		if len(request.EKSClusterRoleARN) != 0 || len(request.EKSNodeRoleARN) != 0 {
+			return errors.Errorf("EKS role ARNs cannot be set for %s clusters", request.Provisioner)
+		}
+	case ProvisionerEKS:
+		if len(request.EKSClusterRoleARN) == 0 {
+			return errors.New("EKS cluster role ARN must be set for EKS clusters")
+		}
+		if len(request.EKSNodeRoleARN) == 0 {
+			return errors.New("EKS node role ARN must be set for EKS clusters")
+		}
+		if len(request.AdditionalNodeGroups) != 0 {
+			return errors.New("additional node groups are not supported for EKS clusters")
+		}
+		if request.AllowInstallations {
+			return errors.New("installations are not supported on EKS clusters yet")
+		}
+	default:
+		return errors.Errorf("unsupported provisioner %s", request.Provisioner)
+	}
+
+	return nil
+}
+
 // GetSupportedCniList starting with three supported CNI networking options, we can add more as required
 func GetSupportedCniList() []string {
 	return []string{"amazon-vpc-routed-eni", "amazonvpc", "weave", "canal", "calico"}
@@ -142,6 +342,12 @@ func NewCreateClusterRequestFromReader(reader io.Reader) (*CreateClusterRequest,
 		return nil, errors.Wrap(err, "failed to decode create cluster request")
 	}
 
+	// Requests based on a cluster template or size are completed with their
+	// values before defaults are set and validation is performed.
+	if len(createClusterRequest.Template) != 0 || len(createClusterRequest.Size) != 0 {
+		return &createClusterRequest, nil
+	}
+
 	createClusterRequest.SetDefaults()
 	err = createClusterRequest.Validate()
 	if err != nil {
@@ -248,6 +454,20 @@ func (p *PatchUpgradeClusterRequest) Apply(metadata *KopsMetadata) bool {
 	return applied
 }
 
+// ApplyToEKS applies the patch to the given cluster's EKS metadata. Only the
+// k8s version can be upgraded on EKS clusters.
+func (p *PatchUpgradeClusterRequest) ApplyToEKS(metadata *EKSMetadata) bool {
+	if p.Version == nil || EKSVersion(*p.Version) == metadata.Version {
+		return false
+	}
+
+	metadata.ChangeRequest = &EKSMetadataRequestedState{
+		Version: EKSVersion(*p.Version),
+	}
+
+	return true
+}
+
 // NewUpgradeClusterRequestFromReader will create an UpgradeClusterRequest from an io.Reader with JSON data.
 func NewUpgradeClusterRequestFromReader(reader io.Reader) (*PatchUpgradeClusterRequest, error) {
 	var upgradeClusterRequest PatchUpgradeClusterRequest
@@ -265,7 +485,12 @@ func NewUpgradeClusterRequestFromReader(reader io.Reader) (*PatchUpgradeClusterR
 }
 
 // PatchClusterSizeRequest specifies the parameters for resizing a cluster.
+// When a size is provided, values which were not provided are taken from it.
+// When a node group is provided, the additional node group with that name is
+// resized instead of the default worker nodes.
 type PatchClusterSizeRequest struct {
+	Size             string  `json:"size,omitempty"`
+	NodeGroup        string  `json:"node-group,omitempty"`
 	NodeInstanceType *string `json:"node-instance-type,omitempty"`
 	NodeMinCount     *int64  `json:"node-min-count,omitempty"`
 	NodeMaxCount     *int64  `json:"node-max-count,omitempty"`
@@ -273,6 +498,9 @@ type PatchClusterSizeRequest struct {
 
 // Validate validates the values of a PatchClusterSizeRequest.
 func (p *PatchClusterSizeRequest) Validate() error {
+	if len(p.NodeGroup) != 0 && !nodeGroupNameRegex.MatchString(p.NodeGroup) {
+		return errors.Errorf("invalid node group name %q", p.NodeGroup)
+	}
 	if p.NodeInstanceType != nil && len(*p.NodeInstanceType) == 0 {
 		return errors.New("node instance type cannot be a blank value")
 	}
@@ -289,6 +517,10 @@ func (p *PatchClusterSizeRequest) Validate() error {
 
 // Apply applies the patch to the given cluster's kops metadata.
 func (p *PatchClusterSizeRequest) Apply(metadata *KopsMetadata) bool {
+	if len(p.NodeGroup) != 0 {
+		return p.applyToNodeGroup(metadata)
+	}
+
 	changes := &KopsMetadataRequestedState{}
 
 	var applied bool
@@ -312,6 +544,60 @@ func (p *PatchClusterSizeRequest) Apply(metadata *KopsMetadata) bool {
 	return applied
 }
 
+// ApplyToEKS applies the patch to the given cluster's EKS metadata.
+func (p *PatchClusterSizeRequest) ApplyToEKS(metadata *EKSMetadata) bool {
+	changes := &EKSMetadataRequestedState{}
+
+	var applied bool
+	if p.NodeInstanceType != nil && *p.NodeInstanceType != metadata.NodeInstanceType {
+		applied = true
+		changes.NodeInstanceType = *p.NodeInstanceType
+	}
+	if p.NodeMinCount != nil && *p.NodeMinCount != metadata.NodeMinCount {
+		applied = true
+		changes.NodeMinCount = *p.NodeMinCount
+	}
+	if p.NodeMaxCount != nil && *p.NodeMaxCount != metadata.NodeMaxCount {
+		applied = true
+		changes.NodeMaxCount = *p.NodeMaxCount
+	}
+
+	if applied {
+		metadata.ChangeRequest = changes
+	}
+
+	return applied
+}
+
+func (p *PatchClusterSizeRequest) applyToNodeGroup(metadata *KopsMetadata) bool {
+	nodeGroup, ok := metadata.GetNodeGroup(p.NodeGroup)
+	if !ok {
+		return false
+	}
+
+	var applied bool
+	if p.NodeInstanceType != nil && *p.NodeInstanceType != nodeGroup.NodeInstanceType {
+		applied = true
+		nodeGroup.NodeInstanceType = *p.NodeInstanceType
+	}
+	if p.NodeMinCount != nil && *p.NodeMinCount != nodeGroup.NodeMinCount {
+		applied = true
+		nodeGroup.NodeMinCount = *p.NodeMinCount
+	}
+	if p.NodeMaxCount != nil && *p.NodeMaxCount != nodeGroup.NodeMaxCount {
+		applied = true
+		nodeGroup.NodeMaxCount = *p.NodeMaxCount
+	}
+
+	if applied {
+		metadata.ChangeRequest = &KopsMetadataRequestedState{
+			AdditionalNodeGroups: KopsInstanceGroupsMetadata{p.NodeGroup: nodeGroup},
+		}
+	}
+
+	return applied
+}
+
 // NewResizeClusterRequestFromReader will create an PatchClusterSizeRequest from an io.Reader with JSON data.
 func NewResizeClusterRequestFromReader(reader io.Reader) (*PatchClusterSizeRequest, error) {
 	var patchClusterSizeRequest PatchClusterSizeRequest
@@ -342,5 +628,35 @@ func NewProvisionClusterRequestFromReader(reader io.Reader) (*ProvisionClusterRe
 		return nil, errors.Wrap(err, "failed to decode provision cluster request")
 	}
 
+	err = provisionClusterRequest.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "provision cluster request failed validation")
+	}
+
 	return &provisionClusterRequest, nil
 }
+
+// Validate validates the values of a cluster provision request.
+func (request *ProvisionClusterRequest) Validate() error {
+	return validateRequestUtilityVersions(request.DesiredUtilityVersions)
+}
+
+// validateRequestUtilityVersions validates the values paths of utility
+// versions provided in an API request. The default values paths configured
+// on the provisioning server are accepted as they are.
+func validateRequestUtilityVersions(versions map[string]*HelmUtilityVersion) error {
+	for utilityName, version := range versions {
+		if version == nil {
+			continue
+		}
+		if defaultVersion := DefaultUtilityVersions[utilityName]; defaultVersion != nil && version.ValuesPath == defaultVersion.ValuesPath {
+			continue
+		}
+		err := ValidateUtilityValuesRequestPath(version.ValuesPath)
+		if err != nil {
+			return errors.Wrapf(err, "invalid values path of utility %s", utilityName)
+		}
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/mattermost/mattermost-cloud/model"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateClusterRequestValid(t *testing.T) {
@@ -23,6 +24,14 @@ func TestCreateClusterRequestValid(t *testing.T) {
 		{"negative node counts", &model.CreateClusterRequest{NodeMinCount: -1, NodeMaxCount: -1}, true},
 		{"negative master count", &model.CreateClusterRequest{MasterCount: -1}, true},
 		{"mismatched node count", &model.CreateClusterRequest{NodeMinCount: 2, NodeMaxCount: 3}, true},
+		{"invalid provisioner", &model.CreateClusterRequest{Provisioner: "blah"}, true},
+		{"kops with EKS role ARNs", &model.CreateClusterRequest{EKSClusterRoleARN: "cluster-role"}, true},
+		{"eks", &model.CreateClusterRequest{Provisioner: model.ProvisionerEKS, EKSClusterRoleARN: "cluster-role", EKSNodeRoleARN: "node-role"}, false},
+		{"eks without node role ARN", &model.CreateClusterRequest{Provisioner: model.ProvisionerEKS, EKSClusterRoleARN: "cluster-role"}, true},
+		{"eks allowing installations", &model.CreateClusterRequest{Provisioner: model.ProvisionerEKS, EKSClusterRoleARN: "cluster-role", EKSNodeRoleARN: "node-role", AllowInstallations: true}, true},
+		{"remote values path", &model.CreateClusterRequest{DesiredUtilityVersions: map[string]*model.HelmUtilityVersion{model.NginxCanonicalName: {Chart: "4.0.18", ValuesPath: "https://example.com/nginx_values.yaml"}}}, false},
+		{"local values path", &model.CreateClusterRequest{DesiredUtilityVersions: map[string]*model.HelmUtilityVersion{model.NginxCanonicalName: {Chart: "4.0.18", ValuesPath: "/proc/self/environ"}}}, true},
+		{"local values file url", &model.CreateClusterRequest{DesiredUtilityVersions: map[string]*model.HelmUtilityVersion{model.NginxCanonicalName: {Chart: "4.0.18", ValuesPath: "file:///root/.aws/credentials"}}}, true},
 	}
 
 	for _, tc := range testCases {
@@ -144,6 +153,27 @@ func TestUpgradeClusterRequestApply(t *testing.T) {
 	}
 }
 
+func TestUpgradeClusterRequestApplyToEKS(t *testing.T) {
+	metadata := &model.EKSMetadata{Version: "1.21"}
+
+	assert.False(t, (&model.PatchUpgradeClusterRequest{}).ApplyToEKS(metadata))
+	assert.False(t, (&model.PatchUpgradeClusterRequest{Version: sToP("1.21.5")}).ApplyToEKS(metadata))
+	assert.Nil(t, metadata.ChangeRequest)
+
+	assert.True(t, (&model.PatchUpgradeClusterRequest{Version: sToP("1.22.1")}).ApplyToEKS(metadata))
+	assert.Equal(t, &model.EKSMetadataRequestedState{Version: "1.22"}, metadata.ChangeRequest)
+}
+
+func TestResizeClusterRequestApplyToEKS(t *testing.T) {
+	metadata := &model.EKSMetadata{NodeInstanceType: "m5.large", NodeMinCount: 2, NodeMaxCount: 2}
+
+	assert.False(t, (&model.PatchClusterSizeRequest{NodeInstanceType: sToP("m5.large"), NodeMinCount: i64oP(2)}).ApplyToEKS(metadata))
+	assert.Nil(t, metadata.ChangeRequest)
+
+	assert.True(t, (&model.PatchClusterSizeRequest{NodeInstanceType: sToP("m5.xlarge"), NodeMaxCount: i64oP(4)}).ApplyToEKS(metadata))
+	assert.Equal(t, &model.EKSMetadataRequestedState{NodeInstanceType: "m5.xlarge", NodeMaxCount: 4}, metadata.ChangeRequest)
+}
+
 func TestResizeClusterRequestValid(t *testing.T) {
 	var testCases = []struct {
 		testName     string
@@ -155,6 +185,8 @@ func TestResizeClusterRequestValid(t *testing.T) {
 		{"blank node type", &model.PatchClusterSizeRequest{NodeInstanceType: sToP("")}, true},
 		{"zero nodes", &model.PatchClusterSizeRequest{NodeMinCount: i64oP(0), NodeMaxCount: i64oP(0)}, true},
 		{"max lower than min", &model.PatchClusterSizeRequest{NodeMinCount: i64oP(5), NodeMaxCount: i64oP(2)}, true},
+		{"valid node group", &model.PatchClusterSizeRequest{NodeGroup: "memory", NodeMinCount: i64oP(2)}, false},
+		{"invalid node group", &model.PatchClusterSizeRequest{NodeGroup: "Memory_Group"}, true},
 	}
 
 	for _, tc := range testCases {
@@ -167,3 +199,85 @@ func TestResizeClusterRequestValid(t *testing.T) {
 		})
 	}
 }
+
+func TestResizeClusterRequestApplyNodeGroup(t *testing.T) {
+	newMetadata := func() *model.KopsMetadata {
+		return &model.KopsMetadata{
+			NodeInstanceType: "m5.large",
+			NodeMinCount:     2,
+			NodeMaxCount:     2,
+			CustomInstanceGroups: model.KopsInstanceGroupsMetadata{
+				"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 1, NodeMaxCount: 1, Taints: []string{"dedicated=memory:NoSchedule"}},
+			},
+		}
+	}
+
+	t.Run("unknown node group", func(t *testing.T) {
+		metadata := newMetadata()
+		request := &model.PatchClusterSizeRequest{NodeGroup: "compute", NodeMinCount: i64oP(3)}
+		assert.False(t, request.Apply(metadata))
+		assert.Nil(t, metadata.ChangeRequest)
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		metadata := newMetadata()
+		request := &model.PatchClusterSizeRequest{NodeGroup: "memory", NodeMinCount: i64oP(1)}
+		assert.False(t, request.Apply(metadata))
+		assert.Nil(t, metadata.ChangeRequest)
+	})
+
+	t.Run("resize node group", func(t *testing.T) {
+		metadata := newMetadata()
+		request := &model.PatchClusterSizeRequest{NodeGroup: "memory", NodeMinCount: i64oP(3), NodeMaxCount: i64oP(5)}
+		require.True(t, request.Apply(metadata))
+		require.NoError(t, metadata.ValidateChangeRequest())
+		assert.False(t, metadata.HasWorkerNodesChanges())
+		assert.Equal(t, model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 3, NodeMaxCount: 5, Taints: []string{"dedicated=memory:NoSchedule"}},
+		}, metadata.ChangeRequest.AdditionalNodeGroups)
+		assert.EqualValues(t, 1, metadata.CustomInstanceGroups["memory"].NodeMinCount)
+	})
+}
+
+func TestCreateClusterRequestApplyClusterTemplate(t *testing.T) {
+	template := &model.ClusterTemplate{
+		Cluster: &model.CreateClusterRequest{
+			Zones:            []string{"us-east-1a", "us-east-1b"},
+			Version:          "1.21.4",
+			NodeInstanceType: "m5.xlarge",
+			NodeMinCount:     4,
+			NodeMaxCount:     4,
+			Networking:       model.NetworkingAmazon,
+			DesiredUtilityVersions: map[string]*model.HelmUtilityVersion{
+				model.NginxCanonicalName:              {Chart: "4.0.0", ValuesPath: "nginx.yaml"},
+				model.PrometheusOperatorCanonicalName: {Chart: "18.0.0"},
+			},
+			Annotations: []string{"multi-tenant"},
+		},
+	}
+
+	request := &model.CreateClusterRequest{
+		Version:      "1.22.0",
+		NodeMinCount: 6,
+		NodeMaxCount: 6,
+		DesiredUtilityVersions: map[string]*model.HelmUtilityVersion{
+			model.NginxCanonicalName: {Chart: "4.1.0"},
+		},
+		Annotations: []string{"gpu", "multi-tenant"},
+	}
+	request.ApplyClusterTemplate(template)
+
+	assert.Equal(t, []string{"us-east-1a", "us-east-1b"}, request.Zones)
+	assert.Equal(t, "1.22.0", request.Version)
+	assert.Equal(t, "m5.xlarge", request.NodeInstanceType)
+	assert.Equal(t, int64(6), request.NodeMinCount)
+	assert.Equal(t, int64(6), request.NodeMaxCount)
+	assert.Equal(t, model.NetworkingAmazon, request.Networking)
+	assert.Equal(t, "4.1.0", request.DesiredUtilityVersions[model.NginxCanonicalName].Chart)
+	assert.Equal(t, "nginx.yaml", request.DesiredUtilityVersions[model.NginxCanonicalName].ValuesPath)
+	assert.Equal(t, "18.0.0", request.DesiredUtilityVersions[model.PrometheusOperatorCanonicalName].Chart)
+	assert.Equal(t, []string{"gpu", "multi-tenant"}, request.Annotations)
+
+	request.Zones[0] = "us-east-1c"
+	assert.Equal(t, "us-east-1a", template.Cluster.Zones[0])
+}
@@ -0,0 +1,225 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// instanceTypeRegex matches AWS EC2 instance types such as m5.large or
+// r5d.2xlarge.
+var instanceTypeRegex = regexp.MustCompile(`^[a-z][a-z0-9-]*\.[a-z0-9]+$`)
+
+// nodeGroupNameRegex matches valid names of additional node groups. The names
+// are used as kops instance group names and k8s label values.
+var nodeGroupNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// ClusterSize describes the master and worker nodes of a cluster. Besides the
+// default worker nodes, a size can define additional worker node groups, for
+// example with memory-optimized instances.
+type ClusterSize struct {
+	ID                   string `json:"ID,omitempty"`
+	Name                 string
+	MasterInstanceType   string
+	MasterCount          int64
+	NodeInstanceType     string
+	NodeMinCount         int64
+	NodeMaxCount         int64
+	AdditionalNodeGroups KopsInstanceGroupsMetadata `json:"AdditionalNodeGroups,omitempty"`
+	CreateAt             int64                      `json:"CreateAt,omitempty"`
+	DeleteAt             int64                      `json:"DeleteAt,omitempty"`
+}
+
+// ClusterSizeFilter describes the parameters used to constrain a set of
+// cluster sizes.
+type ClusterSizeFilter struct {
+	Paging
+	Name string
+}
+
+// IsDeleted returns whether the cluster size was marked as deleted or not.
+func (s *ClusterSize) IsDeleted() bool {
+	return s.DeleteAt != 0
+}
+
+// Validate validates the values of a cluster size.
+func (s *ClusterSize) Validate() error {
+	if len(s.Name) == 0 {
+		return errors.New("cluster size name cannot be empty")
+	}
+	if !ValidInstanceType(s.MasterInstanceType) {
+		return errors.Errorf("invalid master instance type %q", s.MasterInstanceType)
+	}
+	if s.MasterCount < 1 {
+		return errors.Errorf("master count (%d) must be 1 or greater", s.MasterCount)
+	}
+	err := validateNodeGroupSize(s.NodeInstanceType, s.NodeMinCount, s.NodeMaxCount)
+	if err != nil {
+		return err
+	}
+
+	return s.AdditionalNodeGroups.validateAdditionalNodeGroups()
+}
+
+// ApplyToCreateClusterRequest sets the values of the cluster size on the
+// request which were not provided.
+func (s *ClusterSize) ApplyToCreateClusterRequest(request *CreateClusterRequest) {
+	if len(request.MasterInstanceType) == 0 {
+		request.MasterInstanceType = s.MasterInstanceType
+	}
+	if request.MasterCount == 0 {
+		request.MasterCount = s.MasterCount
+	}
+	if len(request.NodeInstanceType) == 0 {
+		request.NodeInstanceType = s.NodeInstanceType
+	}
+	if request.NodeMinCount == 0 {
+		request.NodeMinCount = s.NodeMinCount
+	}
+	if request.NodeMaxCount == 0 {
+		request.NodeMaxCount = s.NodeMaxCount
+	}
+	if request.AdditionalNodeGroups == nil && len(s.AdditionalNodeGroups) != 0 {
+		request.AdditionalNodeGroups = s.AdditionalNodeGroups.Copy()
+	}
+}
+
+// ApplyToPatchClusterSizeRequest sets the worker node values of the cluster
+// size on the request which were not provided. When the request targets a
+// node group, the values of the node group of the size with the same name are
+// used.
+func (s *ClusterSize) ApplyToPatchClusterSizeRequest(request *PatchClusterSizeRequest) {
+	nodeInstanceType, nodeMinCount, nodeMaxCount := s.NodeInstanceType, s.NodeMinCount, s.NodeMaxCount
+	if len(request.NodeGroup) != 0 {
+		nodeGroup, ok := s.AdditionalNodeGroups[request.NodeGroup]
+		if !ok {
+			return
+		}
+		nodeInstanceType, nodeMinCount, nodeMaxCount = nodeGroup.NodeInstanceType, nodeGroup.NodeMinCount, nodeGroup.NodeMaxCount
+	}
+
+	if request.NodeInstanceType == nil {
+		request.NodeInstanceType = &nodeInstanceType
+	}
+	if request.NodeMinCount == nil {
+		request.NodeMinCount = &nodeMinCount
+	}
+	if request.NodeMaxCount == nil {
+		request.NodeMaxCount = &nodeMaxCount
+	}
+}
+
+// ValidInstanceType returns true if the given string has the format of an
+// instance type. Whether the instance type is offered by the cloud provider is
+// checked when the cluster is provisioned.
+func ValidInstanceType(instanceType string) bool {
+	return instanceTypeRegex.MatchString(instanceType)
+}
+
+func validateNodeGroupSize(instanceType string, minCount, maxCount int64) error {
+	if !ValidInstanceType(instanceType) {
+		return errors.Errorf("invalid node instance type %q", instanceType)
+	}
+	if minCount < 1 {
+		return errors.Errorf("node min count (%d) must be 1 or greater", minCount)
+	}
+	if maxCount < minCount {
+		return errors.Errorf("node max count (%d) can't be less than min count (%d)", maxCount, minCount)
+	}
+
+	return nil
+}
+
+// validateAdditionalNodeGroups ensures the node groups can be created next to
+// the default master and worker instance groups of a cluster.
+func (igm KopsInstanceGroupsMetadata) validateAdditionalNodeGroups() error {
+	for name, nodeGroup := range igm {
+		if !nodeGroupNameRegex.MatchString(name) {
+			return errors.Errorf("invalid node group name %q", name)
+		}
+		if strings.HasPrefix(name, "nodes") || strings.HasPrefix(name, "master") {
+			return errors.Errorf("node group name %q is reserved for default instance groups", name)
+		}
+		err := validateNodeGroupSize(nodeGroup.NodeInstanceType, nodeGroup.NodeMinCount, nodeGroup.NodeMaxCount)
+		if err != nil {
+			return errors.Wrapf(err, "invalid node group %s", name)
+		}
+		err = nodeGroup.validateScheduling()
+		if err != nil {
+			return errors.Wrapf(err, "invalid node group %s", name)
+		}
+	}
+
+	return nil
+}
+
+// Copy returns a deep copy of the instance groups metadata.
+func (igm KopsInstanceGroupsMetadata) Copy() KopsInstanceGroupsMetadata {
+	if igm == nil {
+		return nil
+	}
+	igmCopy := make(KopsInstanceGroupsMetadata, len(igm))
+	for name, ig := range igm {
+		igmCopy[name] = ig.copy()
+	}
+
+	return igmCopy
+}
+
+// NewClusterSizesFromReader will create a list of ClusterSizes from an
+// io.Reader with JSON data.
+func NewClusterSizesFromReader(reader io.Reader) ([]*ClusterSize, error) {
+	var sizes []*ClusterSize
+	err := json.NewDecoder(reader).Decode(&sizes)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode cluster sizes")
+	}
+
+	names := make(map[string]struct{}, len(sizes))
+	for _, size := range sizes {
+		err = size.Validate()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cluster size %s", size.Name)
+		}
+		if _, ok := names[size.Name]; ok {
+			return nil, errors.Errorf("duplicate cluster size name %s", size.Name)
+		}
+		names[size.Name] = struct{}{}
+	}
+
+	return sizes, nil
+}
+
+// ClusterSizeFromReader decodes a json-encoded cluster size from the given
+// io.Reader.
+func ClusterSizeFromReader(reader io.Reader) (*ClusterSize, error) {
+	clusterSize := ClusterSize{}
+	decoder := json.NewDecoder(reader)
+	err := decoder.Decode(&clusterSize)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return &clusterSize, nil
+}
+
+// ClusterSizesFromReader decodes a json-encoded list of cluster sizes from the
+// given io.Reader.
+func ClusterSizesFromReader(reader io.Reader) ([]*ClusterSize, error) {
+	clusterSizes := []*ClusterSize{}
+	decoder := json.NewDecoder(reader)
+
+	err := decoder.Decode(&clusterSizes)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return clusterSizes, nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// CreateClusterSizeRequest specifies the parameters for a new cluster size.
+type CreateClusterSizeRequest struct {
+	Name                 string
+	MasterInstanceType   string
+	MasterCount          int64
+	NodeInstanceType     string
+	NodeMinCount         int64
+	NodeMaxCount         int64
+	AdditionalNodeGroups KopsInstanceGroupsMetadata `json:"AdditionalNodeGroups,omitempty"`
+}
+
+// SetDefaults sets the default values for a cluster size create request.
+func (request *CreateClusterSizeRequest) SetDefaults() {
+	if request.MasterCount == 0 {
+		request.MasterCount = 1
+	}
+	if request.NodeMaxCount == 0 {
+		request.NodeMaxCount = request.NodeMinCount
+	}
+	for name, nodeGroup := range request.AdditionalNodeGroups {
+		if nodeGroup.NodeMaxCount == 0 {
+			nodeGroup.NodeMaxCount = nodeGroup.NodeMinCount
+			request.AdditionalNodeGroups[name] = nodeGroup
+		}
+	}
+}
+
+// ClusterSize returns the cluster size described by the request.
+func (request *CreateClusterSizeRequest) ClusterSize() *ClusterSize {
+	return &ClusterSize{
+		Name:                 request.Name,
+		MasterInstanceType:   request.MasterInstanceType,
+		MasterCount:          request.MasterCount,
+		NodeInstanceType:     request.NodeInstanceType,
+		NodeMinCount:         request.NodeMinCount,
+		NodeMaxCount:         request.NodeMaxCount,
+		AdditionalNodeGroups: request.AdditionalNodeGroups.Copy(),
+	}
+}
+
+// NewCreateClusterSizeRequestFromReader will create a CreateClusterSizeRequest
+// from an io.Reader with JSON data.
+func NewCreateClusterSizeRequestFromReader(reader io.Reader) (*CreateClusterSizeRequest, error) {
+	var createClusterSizeRequest CreateClusterSizeRequest
+	err := json.NewDecoder(reader).Decode(&createClusterSizeRequest)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode create cluster size request")
+	}
+
+	createClusterSizeRequest.SetDefaults()
+	err = createClusterSizeRequest.ClusterSize().Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "create cluster size request failed validation")
+	}
+
+	return &createClusterSizeRequest, nil
+}
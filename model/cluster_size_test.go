@@ -0,0 +1,167 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterSizeValidate(t *testing.T) {
+	validSize := func() *model.ClusterSize {
+		return &model.ClusterSize{
+			Name:               "size",
+			MasterInstanceType: "t3.medium",
+			MasterCount:        1,
+			NodeInstanceType:   "m5.large",
+			NodeMinCount:       2,
+			NodeMaxCount:       4,
+			AdditionalNodeGroups: model.KopsInstanceGroupsMetadata{
+				"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 1, NodeMaxCount: 1},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name        string
+		modify      func(*model.ClusterSize)
+		expectError bool
+	}{
+		{"valid", func(s *model.ClusterSize) {}, false},
+		{"no name", func(s *model.ClusterSize) { s.Name = "" }, true},
+		{"invalid master instance type", func(s *model.ClusterSize) { s.MasterInstanceType = "medium" }, true},
+		{"no masters", func(s *model.ClusterSize) { s.MasterCount = 0 }, true},
+		{"invalid node instance type", func(s *model.ClusterSize) { s.NodeInstanceType = "M5.large" }, true},
+		{"no nodes", func(s *model.ClusterSize) { s.NodeMinCount = 0 }, true},
+		{"max below min", func(s *model.ClusterSize) { s.NodeMaxCount = 1 }, true},
+		{"invalid node group name", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["Memory_1"] = s.AdditionalNodeGroups["memory"]
+		}, true},
+		{"reserved node group name", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["nodes-memory"] = s.AdditionalNodeGroups["memory"]
+		}, true},
+		{"invalid node group instance type", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["memory"] = model.KopsInstanceGroupMetadata{NodeInstanceType: "xlarge", NodeMinCount: 1, NodeMaxCount: 1}
+		}, true},
+		{"node group without nodes", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["memory"] = model.KopsInstanceGroupMetadata{NodeInstanceType: "r5.xlarge"}
+		}, true},
+		{"spot node group with labels and taints", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["spot"] = model.KopsInstanceGroupMetadata{
+				NodeInstanceType: "m5.large", NodeMinCount: 1, NodeMaxCount: 4, MaxPrice: "0.05",
+				Labels: map[string]string{"lifecycle": "spot"}, Taints: []string{"lifecycle=spot:NoSchedule"},
+			}
+		}, false},
+		{"node group with invalid max price", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["spot"] = model.KopsInstanceGroupMetadata{NodeInstanceType: "m5.large", NodeMinCount: 1, NodeMaxCount: 1, MaxPrice: "cheap"}
+		}, true},
+		{"node group with invalid label", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["spot"] = model.KopsInstanceGroupMetadata{NodeInstanceType: "m5.large", NodeMinCount: 1, NodeMaxCount: 1, Labels: map[string]string{"lifecycle": "on spot"}}
+		}, true},
+		{"node group with kops managed label", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["spot"] = model.KopsInstanceGroupMetadata{NodeInstanceType: "m5.large", NodeMinCount: 1, NodeMaxCount: 1, Labels: map[string]string{model.KopsInstanceGroupNodeLabel: "nodes"}}
+		}, true},
+		{"node group with invalid taint", func(s *model.ClusterSize) {
+			s.AdditionalNodeGroups["spot"] = model.KopsInstanceGroupMetadata{NodeInstanceType: "m5.large", NodeMinCount: 1, NodeMaxCount: 1, Taints: []string{"lifecycle=spot"}}
+		}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			size := validSize()
+			tc.modify(size)
+			if tc.expectError {
+				assert.Error(t, size.Validate())
+			} else {
+				assert.NoError(t, size.Validate())
+			}
+		})
+	}
+}
+
+func TestClusterSizeApplyToCreateClusterRequest(t *testing.T) {
+	size := &model.ClusterSize{
+		Name:               "size",
+		MasterInstanceType: "t3.large",
+		MasterCount:        3,
+		NodeInstanceType:   "m5.xlarge",
+		NodeMinCount:       4,
+		NodeMaxCount:       4,
+		AdditionalNodeGroups: model.KopsInstanceGroupsMetadata{
+			"memory": {NodeInstanceType: "r5.xlarge", NodeMinCount: 2, NodeMaxCount: 2},
+		},
+	}
+
+	request := &model.CreateClusterRequest{NodeMinCount: 6, NodeMaxCount: 6}
+	size.ApplyToCreateClusterRequest(request)
+	assert.Equal(t, "t3.large", request.MasterInstanceType)
+	assert.Equal(t, int64(3), request.MasterCount)
+	assert.Equal(t, "m5.xlarge", request.NodeInstanceType)
+	assert.Equal(t, int64(6), request.NodeMinCount)
+	assert.Equal(t, int64(6), request.NodeMaxCount)
+	assert.Equal(t, size.AdditionalNodeGroups, request.AdditionalNodeGroups)
+
+	request.AdditionalNodeGroups["compute"] = model.KopsInstanceGroupMetadata{}
+	assert.Len(t, size.AdditionalNodeGroups, 1)
+
+	nodeMaxCount := int64(8)
+	patchRequest := &model.PatchClusterSizeRequest{NodeMaxCount: &nodeMaxCount}
+	size.ApplyToPatchClusterSizeRequest(patchRequest)
+	assert.Equal(t, "m5.xlarge", *patchRequest.NodeInstanceType)
+	assert.Equal(t, int64(4), *patchRequest.NodeMinCount)
+	assert.Equal(t, int64(8), *patchRequest.NodeMaxCount)
+}
+
+func TestNewClusterSizesFromReader(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		sizes, err := model.NewClusterSizesFromReader(bytes.NewReader([]byte("")))
+		require.NoError(t, err)
+		assert.Empty(t, sizes)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		sizes, err := model.NewClusterSizesFromReader(bytes.NewReader([]byte(
+			`[{"Name": "small", "MasterInstanceType": "t3.medium", "MasterCount": 1, "NodeInstanceType": "m5.large", "NodeMinCount": 2, "NodeMaxCount": 2},
+			{"Name": "memory", "MasterInstanceType": "t3.large", "MasterCount": 1, "NodeInstanceType": "m5.large", "NodeMinCount": 2, "NodeMaxCount": 2,
+			"AdditionalNodeGroups": {"memory": {"NodeInstanceType": "r5.xlarge", "NodeMinCount": 2, "NodeMaxCount": 4}}}]`,
+		)))
+		require.NoError(t, err)
+		require.Len(t, sizes, 2)
+		assert.Equal(t, int64(4), sizes[1].AdditionalNodeGroups["memory"].NodeMaxCount)
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		_, err := model.NewClusterSizesFromReader(bytes.NewReader([]byte(
+			`[{"Name": "small", "MasterInstanceType": "t3.medium"}]`,
+		)))
+		require.Error(t, err)
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		_, err := model.NewClusterSizesFromReader(bytes.NewReader([]byte(
+			`[{"Name": "small", "MasterInstanceType": "t3.medium", "MasterCount": 1, "NodeInstanceType": "m5.large", "NodeMinCount": 2, "NodeMaxCount": 2},
+			{"Name": "small", "MasterInstanceType": "t3.medium", "MasterCount": 1, "NodeInstanceType": "m5.large", "NodeMinCount": 2, "NodeMaxCount": 2}]`,
+		)))
+		require.EqualError(t, err, "duplicate cluster size name small")
+	})
+}
+
+func TestNewCreateClusterSizeRequestFromReader(t *testing.T) {
+	request, err := model.NewCreateClusterSizeRequestFromReader(bytes.NewReader([]byte(
+		`{"Name": "small", "MasterInstanceType": "t3.medium", "NodeInstanceType": "m5.large", "NodeMinCount": 2,
+		"AdditionalNodeGroups": {"memory": {"NodeInstanceType": "r5.xlarge", "NodeMinCount": 3}}}`,
+	)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), request.MasterCount)
+	assert.Equal(t, int64(2), request.NodeMaxCount)
+	assert.Equal(t, int64(3), request.AdditionalNodeGroups["memory"].NodeMaxCount)
+
+	_, err = model.NewCreateClusterSizeRequestFromReader(bytes.NewReader([]byte(
+		`{"Name": "small", "MasterInstanceType": "t3.medium", "NodeInstanceType": "large", "NodeMinCount": 2}`,
+	)))
+	require.Error(t, err)
+}
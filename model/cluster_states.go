@@ -22,6 +22,12 @@ const (
 	ClusterStateUpgradeRequested = "upgrade-requested"
 	// ClusterStateUpgradeFailed is a cluster that failed to upgrade.
 	ClusterStateUpgradeFailed = "upgrade-failed"
+	// ClusterStateUtilityUpgradeRequested is a cluster in the process of
+	// upgrading single utilities.
+	ClusterStateUtilityUpgradeRequested = "utility-upgrade-requested"
+	// ClusterStateUtilityUpgradeFailed is a cluster that failed to upgrade
+	// a single utility.
+	ClusterStateUtilityUpgradeFailed = "utility-upgrade-failed"
 	// ClusterStateResizeRequested is a cluster in the process of resizing.
 	ClusterStateResizeRequested = "resize-requested"
 	// ClusterStateResizeFailed is a cluster that failed to resize.
@@ -46,6 +52,8 @@ var AllClusterStates = []string{
 	ClusterStateProvisioningFailed,
 	ClusterStateUpgradeRequested,
 	ClusterStateUpgradeFailed,
+	ClusterStateUtilityUpgradeRequested,
+	ClusterStateUtilityUpgradeFailed,
 	ClusterStateResizeRequested,
 	ClusterStateResizeFailed,
 	ClusterStateDeletionRequested,
@@ -63,6 +71,7 @@ var AllClusterStatesPendingWork = []string{
 	ClusterStateProvisioningRequested,
 	ClusterStateRefreshMetadata,
 	ClusterStateUpgradeRequested,
+	ClusterStateUtilityUpgradeRequested,
 	ClusterStateResizeRequested,
 	ClusterStateDeletionRequested,
 }
@@ -76,6 +85,7 @@ var AllClusterRequestStates = []string{
 	ClusterStateCreationRequested,
 	ClusterStateProvisioningRequested,
 	ClusterStateUpgradeRequested,
+	ClusterStateUtilityUpgradeRequested,
 	ClusterStateResizeRequested,
 	ClusterStateDeletionRequested,
 }
@@ -107,6 +117,11 @@ var (
 			ClusterStateUpgradeRequested,
 			ClusterStateUpgradeFailed,
 		},
+		ClusterStateUtilityUpgradeRequested: {
+			ClusterStateStable,
+			ClusterStateUtilityUpgradeRequested,
+			ClusterStateUtilityUpgradeFailed,
+		},
 		ClusterStateResizeRequested: {
 			ClusterStateStable,
 			ClusterStateResizeRequested,
@@ -119,6 +134,7 @@ var (
 			ClusterStateProvisioningFailed,
 			ClusterStateUpgradeRequested,
 			ClusterStateUpgradeFailed,
+			ClusterStateUtilityUpgradeFailed,
 			ClusterStateDeletionRequested,
 			ClusterStateDeletionFailed,
 		},
@@ -0,0 +1,60 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// ClusterTemplate is a named set of cluster parameters which can be used as a
+// base when creating new clusters.
+type ClusterTemplate struct {
+	ID          string
+	Name        string
+	Description string
+	Cluster     *CreateClusterRequest
+	CreateAt    int64
+	DeleteAt    int64
+}
+
+// ClusterTemplateFilter describes the parameters used to constrain a set of
+// cluster templates.
+type ClusterTemplateFilter struct {
+	Paging
+	Name string
+}
+
+// IsDeleted returns whether the cluster template was marked as deleted or not.
+func (t *ClusterTemplate) IsDeleted() bool {
+	return t.DeleteAt != 0
+}
+
+// ClusterTemplateFromReader decodes a json-encoded cluster template from the
+// given io.Reader.
+func ClusterTemplateFromReader(reader io.Reader) (*ClusterTemplate, error) {
+	clusterTemplate := ClusterTemplate{}
+	decoder := json.NewDecoder(reader)
+	err := decoder.Decode(&clusterTemplate)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return &clusterTemplate, nil
+}
+
+// ClusterTemplatesFromReader decodes a json-encoded list of cluster templates
+// from the given io.Reader.
+func ClusterTemplatesFromReader(reader io.Reader) ([]*ClusterTemplate, error) {
+	clusterTemplates := []*ClusterTemplate{}
+	decoder := json.NewDecoder(reader)
+
+	err := decoder.Decode(&clusterTemplates)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return clusterTemplates, nil
+}
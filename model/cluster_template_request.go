@@ -0,0 +1,148 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+// CreateClusterTemplateRequest specifies the parameters for a new cluster
+// template.
+type CreateClusterTemplateRequest struct {
+	Name        string
+	Description string
+	Cluster     *CreateClusterRequest
+}
+
+// Validate validates the values of a cluster template create request.
+func (request *CreateClusterTemplateRequest) Validate() error {
+	if len(request.Name) == 0 {
+		return errors.New("must specify name")
+	}
+
+	return validateClusterTemplateCluster(request.Cluster)
+}
+
+// NewCreateClusterTemplateRequestFromReader will create a
+// CreateClusterTemplateRequest from an io.Reader with JSON data.
+func NewCreateClusterTemplateRequestFromReader(reader io.Reader) (*CreateClusterTemplateRequest, error) {
+	var createClusterTemplateRequest CreateClusterTemplateRequest
+	err := json.NewDecoder(reader).Decode(&createClusterTemplateRequest)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode create cluster template request")
+	}
+
+	err = createClusterTemplateRequest.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cluster template create request")
+	}
+
+	return &createClusterTemplateRequest, nil
+}
+
+// PatchClusterTemplateRequest specifies the parameters for an updated cluster
+// template. A provided cluster replaces the cluster parameters of the template.
+type PatchClusterTemplateRequest struct {
+	Name        *string
+	Description *string
+	Cluster     *CreateClusterRequest
+}
+
+// Apply applies the patch to the given cluster template.
+func (p *PatchClusterTemplateRequest) Apply(template *ClusterTemplate) bool {
+	var applied bool
+
+	if p.Name != nil && *p.Name != template.Name {
+		applied = true
+		template.Name = *p.Name
+	}
+	if p.Description != nil && *p.Description != template.Description {
+		applied = true
+		template.Description = *p.Description
+	}
+	if p.Cluster != nil {
+		applied = true
+		template.Cluster = p.Cluster
+	}
+
+	return applied
+}
+
+// Validate validates the values of a cluster template patch request.
+func (p *PatchClusterTemplateRequest) Validate() error {
+	if p.Name != nil && len(*p.Name) == 0 {
+		return errors.New("provided name update value was blank")
+	}
+	if p.Cluster != nil {
+		return validateClusterTemplateCluster(p.Cluster)
+	}
+
+	return nil
+}
+
+// NewPatchClusterTemplateRequestFromReader will create a
+// PatchClusterTemplateRequest from an io.Reader with JSON data.
+func NewPatchClusterTemplateRequestFromReader(reader io.Reader) (*PatchClusterTemplateRequest, error) {
+	var patchClusterTemplateRequest PatchClusterTemplateRequest
+	err := json.NewDecoder(reader).Decode(&patchClusterTemplateRequest)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode patch cluster template request")
+	}
+
+	err = patchClusterTemplateRequest.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid patch cluster template request")
+	}
+
+	return &patchClusterTemplateRequest, nil
+}
+
+// GetClusterTemplatesRequest describes the parameters to request a list of
+// cluster templates.
+type GetClusterTemplatesRequest struct {
+	Paging
+	Name string
+}
+
+// ApplyToURL modifies the given url to include query string parameters for the request.
+func (request *GetClusterTemplatesRequest) ApplyToURL(u *url.URL) {
+	q := u.Query()
+	if len(request.Name) != 0 {
+		q.Add("name", request.Name)
+	}
+	request.Paging.AddToQuery(q)
+
+	u.RawQuery = q.Encode()
+}
+
+// validateClusterTemplateCluster ensures the cluster parameters of a template
+// result in a valid cluster create request once defaults are applied. The
+// parameters are stored without defaults, so templates follow changes of the
+// default values.
+func validateClusterTemplateCluster(cluster *CreateClusterRequest) error {
+	if cluster == nil {
+		return errors.New("must specify cluster parameters")
+	}
+	if len(cluster.Template) != 0 {
+		return errors.New("cluster template cannot reference another template")
+	}
+	_, err := AnnotationsFromStringSlice(cluster.Annotations)
+	if err != nil {
+		return errors.Wrap(err, "invalid annotations")
+	}
+
+	clusterWithDefaults := cluster.Clone()
+	clusterWithDefaults.SetDefaults()
+	err = clusterWithDefaults.Validate()
+	if err != nil {
+		return errors.Wrap(err, "invalid cluster parameters")
+	}
+
+	return nil
+}
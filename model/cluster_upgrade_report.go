@@ -0,0 +1,145 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+)
+
+const (
+	// ClusterUpgradeStatusInProgress is the status of a running upgrade.
+	ClusterUpgradeStatusInProgress = "in-progress"
+	// ClusterUpgradeStatusSucceeded is the status of a completed upgrade.
+	ClusterUpgradeStatusSucceeded = "succeeded"
+	// ClusterUpgradeStatusPreflightFailed is the status of an upgrade which
+	// was aborted because of failed pre-flight checks.
+	ClusterUpgradeStatusPreflightFailed = "preflight-failed"
+	// ClusterUpgradeStatusFailed is the status of an upgrade which failed
+	// after the pre-flight checks passed.
+	ClusterUpgradeStatusFailed = "failed"
+)
+
+const (
+	// ClusterUpgradeCheckDeprecatedAPIs checks that no installed Helm release
+	// uses an API removed in the target k8s version.
+	ClusterUpgradeCheckDeprecatedAPIs = "deprecated-apis"
+	// ClusterUpgradeCheckPodDisruptionBudgets checks that no
+	// PodDisruptionBudget blocks the draining of nodes.
+	ClusterUpgradeCheckPodDisruptionBudgets = "pod-disruption-budgets"
+	// ClusterUpgradeCheckSurgeCapacity checks that the remaining nodes can
+	// take the pods of a node replaced during the rolling update.
+	ClusterUpgradeCheckSurgeCapacity = "surge-capacity"
+)
+
+const (
+	// InstanceGroupUpgradeStatusPending is the status of an instance group
+	// which was not updated yet.
+	InstanceGroupUpgradeStatusPending = "pending"
+	// InstanceGroupUpgradeStatusUpdating is the status of an instance group
+	// whose nodes are being replaced.
+	InstanceGroupUpgradeStatusUpdating = "updating"
+	// InstanceGroupUpgradeStatusUpdated is the status of an instance group
+	// which was updated and passed the health validation.
+	InstanceGroupUpgradeStatusUpdated = "updated"
+	// InstanceGroupUpgradeStatusFailed is the status of an instance group
+	// which failed to update or the health validation after it.
+	InstanceGroupUpgradeStatusFailed = "failed"
+)
+
+// ClusterUpgradeReport is the report of the latest k8s upgrade of a cluster.
+type ClusterUpgradeReport struct {
+	FromVersion     string
+	ToVersion       string
+	Status          string
+	StartedAt       int64
+	CompletedAt     int64
+	PreflightChecks []ClusterUpgradeCheck
+	InstanceGroups  []ClusterUpgradeInstanceGroup
+	Error           string `json:"Error,omitempty"`
+}
+
+// ClusterUpgradeCheck is the result of a cluster upgrade pre-flight check.
+type ClusterUpgradeCheck struct {
+	Name    string
+	Passed  bool
+	Details []string `json:"Details,omitempty"`
+}
+
+// ClusterUpgradeInstanceGroup is the rolling update progress of an instance
+// group during a cluster upgrade.
+type ClusterUpgradeInstanceGroup struct {
+	Name        string
+	Role        string
+	Status      string
+	StartedAt   int64
+	CompletedAt int64
+	Error       string `json:"Error,omitempty"`
+}
+
+// NewClusterUpgradeReport creates a new in progress cluster upgrade report.
+func NewClusterUpgradeReport(fromVersion, toVersion string) *ClusterUpgradeReport {
+	return &ClusterUpgradeReport{
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Status:      ClusterUpgradeStatusInProgress,
+		StartedAt:   GetMillis(),
+	}
+}
+
+// AddPreflightCheck records the result of a pre-flight check.
+func (r *ClusterUpgradeReport) AddPreflightCheck(name string, details []string) {
+	r.PreflightChecks = append(r.PreflightChecks, ClusterUpgradeCheck{
+		Name:    name,
+		Passed:  len(details) == 0,
+		Details: details,
+	})
+}
+
+// PreflightPassed returns true if all pre-flight checks passed.
+func (r *ClusterUpgradeReport) PreflightPassed() bool {
+	for _, check := range r.PreflightChecks {
+		if !check.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// GetInstanceGroup returns the instance group with the given name.
+func (r *ClusterUpgradeReport) GetInstanceGroup(name string) *ClusterUpgradeInstanceGroup {
+	for i := range r.InstanceGroups {
+		if r.InstanceGroups[i].Name == name {
+			return &r.InstanceGroups[i]
+		}
+	}
+
+	return nil
+}
+
+// Complete marks the upgrade as completed with the given status.
+func (r *ClusterUpgradeReport) Complete(status string, err error) {
+	r.Status = status
+	r.CompletedAt = GetMillis()
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// NewClusterUpgradeReportFromRaw creates an instance of ClusterUpgradeReport
+// given the raw report.
+func NewClusterUpgradeReportFromRaw(reportBytes []byte) (*ClusterUpgradeReport, error) {
+	if len(reportBytes) == 0 || string(reportBytes) == "null" {
+		return nil, nil
+	}
+
+	var report ClusterUpgradeReport
+	err := json.Unmarshal(reportBytes, &report)
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterUpgradeReport(t *testing.T) {
+	report := model.NewClusterUpgradeReport("1.21.5", "1.22.4")
+	assert.Equal(t, model.ClusterUpgradeStatusInProgress, report.Status)
+	assert.NotZero(t, report.StartedAt)
+	assert.True(t, report.PreflightPassed())
+
+	report.AddPreflightCheck(model.ClusterUpgradeCheckDeprecatedAPIs, nil)
+	assert.True(t, report.PreflightPassed())
+	report.AddPreflightCheck(model.ClusterUpgradeCheckSurgeCapacity, []string{"not enough capacity"})
+	assert.False(t, report.PreflightPassed())
+	assert.True(t, report.PreflightChecks[0].Passed)
+	assert.False(t, report.PreflightChecks[1].Passed)
+
+	report.InstanceGroups = []model.ClusterUpgradeInstanceGroup{{Name: "nodes"}}
+	assert.Nil(t, report.GetInstanceGroup("masters"))
+	report.GetInstanceGroup("nodes").Status = model.InstanceGroupUpgradeStatusUpdated
+	assert.Equal(t, model.InstanceGroupUpgradeStatusUpdated, report.InstanceGroups[0].Status)
+
+	report.Complete(model.ClusterUpgradeStatusPreflightFailed, errors.New("pre-flight checks failed"))
+	assert.Equal(t, model.ClusterUpgradeStatusPreflightFailed, report.Status)
+	assert.Equal(t, "pre-flight checks failed", report.Error)
+	assert.NotZero(t, report.CompletedAt)
+}
+
+func TestNewClusterUpgradeReportFromRaw(t *testing.T) {
+	t.Run("nil payload", func(t *testing.T) {
+		report, err := model.NewClusterUpgradeReportFromRaw(nil)
+		require.NoError(t, err)
+		require.Nil(t, report)
+	})
+
+	t.Run("null payload", func(t *testing.T) {
+		report, err := model.NewClusterUpgradeReportFromRaw([]byte("null"))
+		require.NoError(t, err)
+		require.Nil(t, report)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		_, err := model.NewClusterUpgradeReportFromRaw([]byte(`{`))
+		require.Error(t, err)
+	})
+
+	t.Run("valid payload", func(t *testing.T) {
+		report, err := model.NewClusterUpgradeReportFromRaw([]byte(`{"ToVersion": "1.22.4"}`))
+		require.NoError(t, err)
+		require.Equal(t, "1.22.4", report.ToVersion)
+	})
+}
@@ -128,13 +128,16 @@ type UtilityGroupVersions struct {
 	Promtail            *HelmUtilityVersion
 	Kubecost            *HelmUtilityVersion
 	NodeProblemDetector *HelmUtilityVersion
+	// Additional holds the versions of the utilities of the utility
+	// registry by canonical name.
+	Additional map[string]*HelmUtilityVersion `json:"Additional,omitempty"`
 }
 
 // AsMap returns the UtilityGroupVersion represented as a map with the
 // canonical names for each utility as the keys and the members of the
 // struct making up the values
 func (h *UtilityGroupVersions) AsMap() map[string]*HelmUtilityVersion {
-	return map[string]*HelmUtilityVersion{
+	versions := map[string]*HelmUtilityVersion{
 		PrometheusOperatorCanonicalName:  h.PrometheusOperator,
 		ThanosCanonicalName:              h.Thanos,
 		NginxCanonicalName:               h.Nginx,
@@ -146,6 +149,11 @@ func (h *UtilityGroupVersions) AsMap() map[string]*HelmUtilityVersion {
 		KubecostCanonicalName:            h.Kubecost,
 		NodeProblemDetectorCanonicalName: h.NodeProblemDetector,
 	}
+	for utility, version := range h.Additional {
+		versions[utility] = version
+	}
+
+	return versions
 }
 
 // UtilityMetadata is a container struct for any metadata related to
@@ -153,6 +161,9 @@ func (h *UtilityGroupVersions) AsMap() map[string]*HelmUtilityVersion {
 type UtilityMetadata struct {
 	DesiredVersions UtilityGroupVersions
 	ActualVersions  UtilityGroupVersions
+	// UtilityUpgrades holds the latest single utility upgrade of each
+	// utility by canonical name.
+	UtilityUpgrades map[string]*UtilityUpgrade `json:"UtilityUpgrades,omitempty"`
 }
 
 // NewUtilityMetadata creates an instance of UtilityMetadata given the raw
@@ -252,7 +263,8 @@ func getUtilityVersion(versions UtilityGroupVersions, utility string) *HelmUtili
 // setUtilityVersion will assign the version in desiredVersion to the
 // utility whose name's string representation matches one of the known
 // utilities with a version field in utilityVersion struct in the
-// first argument
+// first argument, or to the additional versions for utilities of the
+// utility registry
 func setUtilityVersion(versions *UtilityGroupVersions, utility string, desiredVersion *HelmUtilityVersion) {
 	switch utility {
 	case PrometheusOperatorCanonicalName:
@@ -275,6 +287,18 @@ func setUtilityVersion(versions *UtilityGroupVersions, utility string, desiredVe
 		versions.Kubecost = desiredVersion
 	case NodeProblemDetectorCanonicalName:
 		versions.NodeProblemDetector = desiredVersion
+	default:
+		if desiredVersion == nil {
+			delete(versions.Additional, utility)
+			return
+		}
+		if GetRegisteredUtility(utility) == nil {
+			return
+		}
+		if versions.Additional == nil {
+			versions.Additional = map[string]*HelmUtilityVersion{}
+		}
+		versions.Additional[utility] = desiredVersion
 	}
 }
 
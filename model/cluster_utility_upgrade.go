@@ -0,0 +1,154 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// UtilityUpgradeStateRequested is a utility upgrade waiting to be
+	// processed by the cluster supervisor.
+	UtilityUpgradeStateRequested = "requested"
+	// UtilityUpgradeStatePreviewed is a utility upgrade whose Helm diff was
+	// computed without applying it.
+	UtilityUpgradeStatePreviewed = "previewed"
+	// UtilityUpgradeStateUpgraded is a utility upgrade which was applied.
+	UtilityUpgradeStateUpgraded = "upgraded"
+	// UtilityUpgradeStateRolledBack is a utility upgrade which failed and
+	// was rolled back to the previous actual version.
+	UtilityUpgradeStateRolledBack = "rolled-back"
+	// UtilityUpgradeStateFailed is a utility upgrade which failed and could
+	// not be rolled back.
+	UtilityUpgradeStateFailed = "failed"
+)
+
+// UtilityUpgrade is the state of the latest single utility upgrade
+// requested for a utility of a cluster.
+type UtilityUpgrade struct {
+	State       string
+	Preview     bool
+	FromVersion *HelmUtilityVersion `json:"FromVersion,omitempty"`
+	ToVersion   *HelmUtilityVersion
+	Diff        string `json:"Diff,omitempty"`
+	Error       string `json:"Error,omitempty"`
+	RequestedAt int64
+	CompletedAt int64
+}
+
+// Complete marks the utility upgrade as completed with the given state.
+func (u *UtilityUpgrade) Complete(state string, err error) {
+	u.State = state
+	u.CompletedAt = GetMillis()
+	if err != nil {
+		u.Error = err.Error()
+	}
+}
+
+// Failed returns true if the utility upgrade did not apply the new version.
+func (u *UtilityUpgrade) Failed() bool {
+	return u.State == UtilityUpgradeStateRolledBack || u.State == UtilityUpgradeStateFailed
+}
+
+// UpgradeClusterUtilityRequest specifies the parameters for upgrading a
+// single utility of a cluster.
+type UpgradeClusterUtilityRequest struct {
+	Chart      string `json:"chart,omitempty"`
+	ValuesPath string `json:"values-path,omitempty"`
+	// Preview computes the Helm diff of the upgrade without applying it.
+	Preview bool `json:"preview,omitempty"`
+}
+
+// Validate validates the values of a cluster utility upgrade request.
+func (r *UpgradeClusterUtilityRequest) Validate() error {
+	if len(r.Chart) == 0 {
+		return errors.New("chart version must not be empty")
+	}
+
+	return ValidateUtilityValuesRequestPath(r.ValuesPath)
+}
+
+// NewUpgradeClusterUtilityRequestFromReader will create an
+// UpgradeClusterUtilityRequest from an io.Reader with JSON data.
+func NewUpgradeClusterUtilityRequestFromReader(reader io.Reader) (*UpgradeClusterUtilityRequest, error) {
+	var upgradeUtilityRequest UpgradeClusterUtilityRequest
+	err := json.NewDecoder(reader).Decode(&upgradeUtilityRequest)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode upgrade cluster utility request")
+	}
+
+	err = upgradeUtilityRequest.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "upgrade cluster utility request failed validation")
+	}
+
+	return &upgradeUtilityRequest, nil
+}
+
+// IsValidUtilityName returns true if the given name is the canonical name of
+// a cluster utility.
+func IsValidUtilityName(name string) bool {
+	_, found := DefaultUtilityVersions[name]
+	return found
+}
+
+// RequestUtilityUpgrade records a pending upgrade of the given utility. The
+// values path of the currently deployed version is kept when none is
+// provided.
+func (c *Cluster) RequestUtilityUpgrade(utility string, request *UpgradeClusterUtilityRequest) *UtilityUpgrade {
+	if c.UtilityMetadata == nil {
+		c.UtilityMetadata = new(UtilityMetadata)
+	}
+	if c.UtilityMetadata.UtilityUpgrades == nil {
+		c.UtilityMetadata.UtilityUpgrades = map[string]*UtilityUpgrade{}
+	}
+
+	toVersion := &HelmUtilityVersion{Chart: request.Chart, ValuesPath: request.ValuesPath}
+	fromVersion := c.ActualUtilityVersion(utility)
+	if len(toVersion.ValuesPath) == 0 && fromVersion != nil {
+		toVersion.ValuesPath = fromVersion.ValuesPath
+	}
+
+	upgrade := &UtilityUpgrade{
+		State:       UtilityUpgradeStateRequested,
+		Preview:     request.Preview,
+		ToVersion:   toVersion,
+		RequestedAt: GetMillis(),
+	}
+	c.UtilityMetadata.UtilityUpgrades[utility] = upgrade
+
+	return upgrade
+}
+
+// PendingUtilityUpgrades returns the names of the utilities with a requested
+// upgrade, sorted by name.
+func (c *Cluster) PendingUtilityUpgrades() []string {
+	if c.UtilityMetadata == nil {
+		return nil
+	}
+
+	var pending []string
+	for utility, upgrade := range c.UtilityMetadata.UtilityUpgrades {
+		if upgrade != nil && upgrade.State == UtilityUpgradeStateRequested {
+			pending = append(pending, utility)
+		}
+	}
+	sort.Strings(pending)
+
+	return pending
+}
+
+// GetUtilityUpgrade returns the latest upgrade of the given utility.
+func (c *Cluster) GetUtilityUpgrade(utility string) *UtilityUpgrade {
+	if c.UtilityMetadata == nil {
+		return nil
+	}
+
+	return c.UtilityMetadata.UtilityUpgrades[utility]
+}
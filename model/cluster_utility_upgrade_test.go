@@ -0,0 +1,97 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUpgradeClusterUtilityRequestFromReader(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		request, err := NewUpgradeClusterUtilityRequestFromReader(bytes.NewReader([]byte(
+			`{"chart":"4.0.19","values-path":"https://example.com/nginx_values.yaml","preview":true}`,
+		)))
+		require.NoError(t, err)
+		assert.Equal(t, &UpgradeClusterUtilityRequest{
+			Chart:      "4.0.19",
+			ValuesPath: "https://example.com/nginx_values.yaml",
+			Preview:    true,
+		}, request)
+	})
+
+	t.Run("missing chart", func(t *testing.T) {
+		_, err := NewUpgradeClusterUtilityRequestFromReader(bytes.NewReader([]byte(`{"values-path":"values.yaml"}`)))
+		require.Error(t, err)
+	})
+
+	t.Run("local values path", func(t *testing.T) {
+		_, err := NewUpgradeClusterUtilityRequestFromReader(bytes.NewReader([]byte(`{"chart":"4.0.19","values-path":"file:///proc/self/environ"}`)))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		_, err := NewUpgradeClusterUtilityRequestFromReader(bytes.NewReader([]byte(`{`)))
+		require.Error(t, err)
+	})
+}
+
+func TestIsValidUtilityName(t *testing.T) {
+	assert.True(t, IsValidUtilityName(NginxCanonicalName))
+	assert.True(t, IsValidUtilityName(NodeProblemDetectorCanonicalName))
+	assert.False(t, IsValidUtilityName("unknown"))
+}
+
+func TestClusterRequestUtilityUpgrade(t *testing.T) {
+	t.Run("keeps actual values path", func(t *testing.T) {
+		cluster := &Cluster{}
+		err := cluster.SetUtilityActualVersion(NginxCanonicalName, &HelmUtilityVersion{Chart: "4.0.18", ValuesPath: "nginx_values.yaml"})
+		require.NoError(t, err)
+
+		upgrade := cluster.RequestUtilityUpgrade(NginxCanonicalName, &UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		assert.Equal(t, UtilityUpgradeStateRequested, upgrade.State)
+		assert.Equal(t, &HelmUtilityVersion{Chart: "4.0.19", ValuesPath: "nginx_values.yaml"}, upgrade.ToVersion)
+		assert.NotZero(t, upgrade.RequestedAt)
+		assert.Equal(t, upgrade, cluster.GetUtilityUpgrade(NginxCanonicalName))
+	})
+
+	t.Run("new values path", func(t *testing.T) {
+		cluster := &Cluster{}
+
+		upgrade := cluster.RequestUtilityUpgrade(PromtailCanonicalName, &UpgradeClusterUtilityRequest{Chart: "3.11.0", ValuesPath: "promtail_values.yaml", Preview: true})
+		assert.True(t, upgrade.Preview)
+		assert.Equal(t, &HelmUtilityVersion{Chart: "3.11.0", ValuesPath: "promtail_values.yaml"}, upgrade.ToVersion)
+	})
+
+	t.Run("pending upgrades", func(t *testing.T) {
+		cluster := &Cluster{}
+		assert.Empty(t, cluster.PendingUtilityUpgrades())
+		assert.Nil(t, cluster.GetUtilityUpgrade(NginxCanonicalName))
+
+		cluster.RequestUtilityUpgrade(PromtailCanonicalName, &UpgradeClusterUtilityRequest{Chart: "3.11.0"})
+		cluster.RequestUtilityUpgrade(NginxCanonicalName, &UpgradeClusterUtilityRequest{Chart: "4.0.19"})
+		cluster.RequestUtilityUpgrade(TeleportCanonicalName, &UpgradeClusterUtilityRequest{Chart: "6.2.9"}).
+			Complete(UtilityUpgradeStateUpgraded, nil)
+
+		assert.Equal(t, []string{NginxCanonicalName, PromtailCanonicalName}, cluster.PendingUtilityUpgrades())
+	})
+}
+
+func TestUtilityUpgradeComplete(t *testing.T) {
+	upgrade := &UtilityUpgrade{State: UtilityUpgradeStateRequested}
+	upgrade.Complete(UtilityUpgradeStateRolledBack, errors.New("timed out"))
+
+	assert.Equal(t, UtilityUpgradeStateRolledBack, upgrade.State)
+	assert.Equal(t, "timed out", upgrade.Error)
+	assert.NotZero(t, upgrade.CompletedAt)
+	assert.True(t, upgrade.Failed())
+
+	upgrade.Complete(UtilityUpgradeStateUpgraded, nil)
+	assert.False(t, upgrade.Failed())
+}
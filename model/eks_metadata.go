@@ -0,0 +1,89 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// EKSMetadata is the provisioner metadata stored in a model.Cluster for
+// clusters managed by EKS.
+type EKSMetadata struct {
+	Name             string
+	ARN              string
+	Version          string
+	ClusterRoleARN   string
+	NodeRoleARN      string
+	NodeGroupName    string
+	NodeInstanceType string
+	NodeMinCount     int64
+	NodeMaxCount     int64
+	VPC              string
+	ChangeRequest    *EKSMetadataRequestedState `json:"ChangeRequest,omitempty"`
+}
+
+// EKSMetadataRequestedState is the requested state for EKS metadata.
+type EKSMetadataRequestedState struct {
+	Version          string `json:"Version,omitempty"`
+	NodeInstanceType string `json:"NodeInstanceType,omitempty"`
+	NodeMinCount     int64  `json:"NodeMinCount,omitempty"`
+	NodeMaxCount     int64  `json:"NodeMaxCount,omitempty"`
+	VPC              string `json:"VPC,omitempty"`
+}
+
+// ValidateChangeRequest ensures that the ChangeRequest has at least one
+// actionable value.
+func (em *EKSMetadata) ValidateChangeRequest() error {
+	if em.ChangeRequest == nil {
+		return errors.New("the EKSMetadata ChangeRequest is nil")
+	}
+
+	if len(em.ChangeRequest.Version) == 0 &&
+		len(em.ChangeRequest.NodeInstanceType) == 0 &&
+		em.ChangeRequest.NodeMinCount == 0 &&
+		em.ChangeRequest.NodeMaxCount == 0 {
+		return errors.New("the EKSMetadata ChangeRequest has no change values set")
+	}
+
+	return nil
+}
+
+// ClearChangeRequest clears the EKS metadata change request.
+func (em *EKSMetadata) ClearChangeRequest() {
+	em.ChangeRequest = nil
+}
+
+// EKSVersion returns the EKS version, major.minor, of a cluster version. An
+// empty version is returned for "latest" which lets EKS pick its default
+// version.
+func EKSVersion(version string) string {
+	if len(version) == 0 || version == "latest" {
+		return ""
+	}
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return version
+	}
+
+	return strings.Join(parts[:2], ".")
+}
+
+// NewEKSMetadata creates an instance of EKSMetadata given the raw provisioner metadata.
+func NewEKSMetadata(metadataBytes []byte) (*EKSMetadata, error) {
+	if len(metadataBytes) == 0 || string(metadataBytes) == "null" {
+		return nil, nil
+	}
+
+	eksMetadata := EKSMetadata{}
+	err := json.Unmarshal(metadataBytes, &eksMetadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eksMetadata, nil
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewEKSMetadata(t *testing.T) {
+	t.Run("nil payload", func(t *testing.T) {
+		eksMetadata, err := model.NewEKSMetadata(nil)
+		require.NoError(t, err)
+		require.Nil(t, eksMetadata)
+	})
+
+	t.Run("invalid payload", func(t *testing.T) {
+		_, err := model.NewEKSMetadata([]byte(`{`))
+		require.Error(t, err)
+	})
+
+	t.Run("valid payload", func(t *testing.T) {
+		eksMetadata, err := model.NewEKSMetadata([]byte(`{"Name": "name"}`))
+		require.NoError(t, err)
+		require.Equal(t, "name", eksMetadata.Name)
+	})
+}
+
+func TestEKSValidateChangeRequest(t *testing.T) {
+	var em model.EKSMetadata
+
+	t.Run("nil ChangeRequest", func(t *testing.T) {
+		require.Error(t, em.ValidateChangeRequest())
+	})
+
+	t.Run("empty ChangeRequest", func(t *testing.T) {
+		em.ChangeRequest = &model.EKSMetadataRequestedState{}
+		require.Error(t, em.ValidateChangeRequest())
+	})
+
+	t.Run("valid ChangeRequest", func(t *testing.T) {
+		em.ChangeRequest.NodeInstanceType = "m5.large"
+		require.NoError(t, em.ValidateChangeRequest())
+	})
+}
+
+func TestEKSVersion(t *testing.T) {
+	assert.Equal(t, "", model.EKSVersion(""))
+	assert.Equal(t, "", model.EKSVersion("latest"))
+	assert.Equal(t, "1.21", model.EKSVersion("1.21"))
+	assert.Equal(t, "1.21", model.EKSVersion("1.21.2"))
+}
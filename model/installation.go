@@ -20,7 +20,8 @@ const (
 	// DefaultDatabaseWeight is the default weight of a small or average-sized
 	// installation that isn't hibernating.
 	DefaultDatabaseWeight float64 = 1
-	// HibernatingDatabaseWeight is the weight of a hibernating installation.
+	// HibernatingDatabaseWeight is the factor applied to the database weight
+	// of a hibernating installation.
 	HibernatingDatabaseWeight float64 = .75
 )
 
@@ -47,8 +48,17 @@ type Installation struct {
 	APISecurityLock            bool
 	LockAcquiredBy             *string
 	LockAcquiredAt             int64
-	GroupOverrides             map[string]string           `json:"GroupOverrides,omitempty"`
-	SingleTenantDatabaseConfig *SingleTenantDatabaseConfig `json:"SingleTenantDatabaseConfig,omitempty"`
+	GroupOverrides             map[string]string               `json:"GroupOverrides,omitempty"`
+	SingleTenantDatabaseConfig *SingleTenantDatabaseConfig     `json:"SingleTenantDatabaseConfig,omitempty"`
+	Placement                  *InstallationPlacement          `json:"Placement,omitempty"`
+	DatabaseMetrics            *InstallationDatabaseMetrics    `json:"DatabaseMetrics,omitempty"`
+	DatabasePoolConfig         *InstallationDatabasePoolConfig `json:"DatabasePoolConfig,omitempty"`
+	// DatabaseCredentialsRotatedAt is the time the database credentials of
+	// the installation were last rotated. It is 0 if they were never rotated.
+	DatabaseCredentialsRotatedAt int64 `json:"DatabaseCredentialsRotatedAt,omitempty"`
+	// FilestoreAccessKeyRotatedAt is the time the filestore access key of the
+	// installation was last rotated. It is 0 if it was never rotated.
+	FilestoreAccessKeyRotatedAt int64 `json:"FilestoreAccessKeyRotatedAt,omitempty"`
 
 	// configconfigMergedWithGroup is set when the installation configuration
 	// has been overridden with group configuration. This value can then be
@@ -109,16 +119,25 @@ func (i *Installation) DeletionDateString() string {
 	return TimeFromMillis(i.DeleteAt).Format("Jan 2 2006")
 }
 
-// GetDatabaseWeight returns a value corresponding to the
-// TODO: maybe consider installation size in the future as well?
+// GetDatabaseWeight returns a value corresponding to the load the installation
+// puts on a shared database. The weight of the installation size is used unless
+// the observed database metrics of the installation show a higher load.
 func (i *Installation) GetDatabaseWeight() float64 {
+	weight := DefaultDatabaseWeight
+	if sizeWeight, ok := installationSizeDatabaseWeights[i.Size]; ok {
+		weight = sizeWeight
+	}
+	if metricsWeight := i.DatabaseMetrics.Weight(); metricsWeight > weight {
+		weight = metricsWeight
+	}
+
 	if i.State == InstallationStateHibernationRequested ||
 		i.State == InstallationStateHibernationInProgress ||
 		i.State == InstallationStateHibernating {
-		return HibernatingDatabaseWeight
+		return weight * HibernatingDatabaseWeight
 	}
 
-	return DefaultDatabaseWeight
+	return weight
 }
 
 // IsInGroup returns if the installation is in a group or not.
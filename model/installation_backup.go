@@ -128,7 +128,8 @@ func EnsureBackupRestoreCompatible(installation *Installation) error {
 	var errs []string
 
 	if installation.Database != InstallationDatabaseMultiTenantRDSPostgres &&
-		installation.Database != InstallationDatabaseSingleTenantRDSPostgres {
+		installation.Database != InstallationDatabaseSingleTenantRDSPostgres &&
+		installation.Database != InstallationDatabaseExternalPostgres {
 		errs = append(errs, fmt.Sprintf("invalid installation database, backup-restore is supported only for Postgres database, the database type is %q", installation.Database))
 	}
 
@@ -84,6 +84,13 @@ func TestEnsureBackupRestoreCompatible(t *testing.T) {
 				Filestore: InstallationFilestoreBifrost,
 			},
 		},
+		{
+			description: "valid external postgres installation",
+			installation: &Installation{
+				Database:  InstallationDatabaseExternalPostgres,
+				Filestore: InstallationFilestoreBifrost,
+			},
+		},
 		{
 			description: "invalid db",
 			installation: &Installation{
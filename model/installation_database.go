@@ -30,6 +30,9 @@ const (
 	// InstallationDatabaseMultiTenantRDSPostgresPGBouncer is a PostgreSQL
 	// multitenant database hosted via Amazon RDS that has pooled connections.
 	InstallationDatabaseMultiTenantRDSPostgresPGBouncer = "aws-multitenant-rds-postgres-pgbouncer"
+	// InstallationDatabaseExternalPostgres is a PostgreSQL database hosted on
+	// an external PostgreSQL server configured on the provisioning server.
+	InstallationDatabaseExternalPostgres = "external-postgres"
 
 	// DatabaseEngineTypeMySQL is a MySQL database.
 	DatabaseEngineTypeMySQL = "mysql"
@@ -40,6 +43,19 @@ const (
 	DatabaseEngineTypePostgresProxy = "postgres-proxy"
 )
 
+// InstallationDatabaseEngineType returns the database engine type backing the
+// given installation database.
+func InstallationDatabaseEngineType(database string) string {
+	switch database {
+	case InstallationDatabaseMysqlOperator,
+		InstallationDatabaseSingleTenantRDSMySQL,
+		InstallationDatabaseMultiTenantRDSMySQL:
+		return DatabaseEngineTypeMySQL
+	}
+
+	return DatabaseEngineTypePostgres
+}
+
 // Database is the interface for managing Mattermost databases.
 type Database interface {
 	Provision(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error
@@ -51,6 +67,8 @@ type Database interface {
 	MigrateTo(store InstallationDatabaseStoreInterface, dbMigration *InstallationDBMigrationOperation, logger log.FieldLogger) error
 	TeardownMigrated(store InstallationDatabaseStoreInterface, migrationOp *InstallationDBMigrationOperation, logger log.FieldLogger) error
 	RollbackMigration(store InstallationDatabaseStoreInterface, dbMigration *InstallationDBMigrationOperation, logger log.FieldLogger) error
+	RotateCredentials(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error
+	RevokePreviousCredentials(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error
 }
 
 // InstallationDatabaseStoreInterface is the interface necessary for SQLStore
@@ -84,6 +102,8 @@ type InstallationDatabaseStoreInterface interface {
 type ClusterUtilityDatabaseStoreInterface interface {
 	GetMultitenantDatabases(filter *MultitenantDatabaseFilter) ([]*MultitenantDatabase, error)
 	GetLogicalDatabases(filter *LogicalDatabaseFilter) ([]*LogicalDatabase, error)
+	GetDatabaseSchemas(filter *DatabaseSchemaFilter) ([]*DatabaseSchema, error)
+	GetInstallation(installationID string, includeGroupConfig, includeGroupConfigOverrides bool) (*Installation, error)
 }
 
 // MysqlOperatorDatabase is a database backed by the MySQL operator.
@@ -138,6 +158,16 @@ func (d *MysqlOperatorDatabase) RollbackMigration(store InstallationDatabaseStor
 	return errors.New("rolling back db migration is not supported for MySQL Operator")
 }
 
+// RotateCredentials rotating credentials is not supported for MySQL Operator managed database.
+func (d *MysqlOperatorDatabase) RotateCredentials(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("rotating credentials is not supported for MySQL Operator")
+}
+
+// RevokePreviousCredentials revoking credentials is not supported for MySQL Operator managed database.
+func (d *MysqlOperatorDatabase) RevokePreviousCredentials(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("revoking credentials is not supported for MySQL Operator")
+}
+
 // GenerateDatabaseSecret creates the k8s database spec and secret for
 // accessing the MySQL operator database.
 func (d *MysqlOperatorDatabase) GenerateDatabaseSecret(store InstallationDatabaseStoreInterface, logger log.FieldLogger) (*corev1.Secret, error) {
@@ -158,6 +188,7 @@ func IsSupportedDatabase(database string) bool {
 	case InstallationDatabaseMultiTenantRDSMySQL:
 	case InstallationDatabaseMultiTenantRDSPostgres:
 	case InstallationDatabaseMultiTenantRDSPostgresPGBouncer:
+	case InstallationDatabaseExternalPostgres:
 	case InstallationDatabaseMysqlOperator:
 	default:
 		return false
@@ -196,3 +227,9 @@ func IsMultiTenantRDS(database string) bool {
 
 	return true
 }
+
+// SupportsCredentialRotation returns true if the database credentials of
+// installations using the given database can be rotated.
+func SupportsCredentialRotation(database string) bool {
+	return IsMultiTenantRDS(database)
+}
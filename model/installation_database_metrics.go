@@ -0,0 +1,77 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+)
+
+const (
+	// DatabaseWeightSizeBytes is the database size of an average installation
+	// with the default database weight.
+	DatabaseWeightSizeBytes int64 = 1 << 30
+	// DatabaseWeightConnections is the number of database connections of an
+	// average installation with the default database weight.
+	DatabaseWeightConnections int64 = 20
+	// DatabaseWeightQueriesPerSecond is the database query load of an average
+	// installation with the default database weight.
+	DatabaseWeightQueriesPerSecond float64 = 10
+)
+
+// installationSizeDatabaseWeights are the database weights of installation
+// sizes which are expected to put more load on the database than an average
+// installation.
+var installationSizeDatabaseWeights = map[string]float64{
+	mmv1alpha1.Size5000String:  2,
+	mmv1alpha1.Size10000String: 4,
+	mmv1alpha1.Size25000String: 8,
+}
+
+// InstallationDatabaseMetrics contains the observed database usage of an
+// installation.
+type InstallationDatabaseMetrics struct {
+	// SizeBytes is the size of the installation database.
+	SizeBytes int64
+	// Connections is the number of open connections to the installation
+	// database.
+	Connections int64
+	// QueriesPerSecond is the rate of transactions on the installation database
+	// since the previous collection.
+	QueriesPerSecond float64
+	// TransactionCount is the total number of transactions reported by the
+	// database, used to calculate QueriesPerSecond on the next collection.
+	TransactionCount int64
+	// CollectAt is the time the metrics were collected at.
+	CollectAt int64
+}
+
+// Weight returns the database weight corresponding to the observed metrics.
+// The dimension with the highest load relative to an average installation is
+// used.
+func (m *InstallationDatabaseMetrics) Weight() float64 {
+	if m == nil {
+		return 0
+	}
+
+	weight := float64(m.SizeBytes) / float64(DatabaseWeightSizeBytes)
+	if connectionsWeight := float64(m.Connections) / float64(DatabaseWeightConnections); connectionsWeight > weight {
+		weight = connectionsWeight
+	}
+	if queriesWeight := m.QueriesPerSecond / DatabaseWeightQueriesPerSecond; queriesWeight > weight {
+		weight = queriesWeight
+	}
+
+	return weight
+}
+
+// ToJSON marshals database metrics to JSON if they are not nil.
+func (m *InstallationDatabaseMetrics) ToJSON() ([]byte, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
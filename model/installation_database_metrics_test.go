@@ -0,0 +1,81 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	mmv1alpha1 "github.com/mattermost/mattermost-operator/apis/mattermost/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallationDatabaseMetricsWeight(t *testing.T) {
+	var nilMetrics *model.InstallationDatabaseMetrics
+	assert.Equal(t, float64(0), nilMetrics.Weight())
+
+	assert.Equal(t, float64(3), (&model.InstallationDatabaseMetrics{
+		SizeBytes: 3 * model.DatabaseWeightSizeBytes,
+	}).Weight())
+	assert.Equal(t, float64(2), (&model.InstallationDatabaseMetrics{
+		SizeBytes:   model.DatabaseWeightSizeBytes,
+		Connections: 2 * model.DatabaseWeightConnections,
+	}).Weight())
+	assert.Equal(t, float64(5), (&model.InstallationDatabaseMetrics{
+		Connections:      model.DatabaseWeightConnections,
+		QueriesPerSecond: 5 * model.DatabaseWeightQueriesPerSecond,
+	}).Weight())
+}
+
+func TestInstallationGetDatabaseWeight(t *testing.T) {
+	for _, testCase := range []struct {
+		description    string
+		installation   *model.Installation
+		expectedWeight float64
+	}{
+		{
+			"default",
+			&model.Installation{Size: mmv1alpha1.Size100String, State: model.InstallationStateStable},
+			model.DefaultDatabaseWeight,
+		},
+		{
+			"hibernating",
+			&model.Installation{Size: mmv1alpha1.Size100String, State: model.InstallationStateHibernating},
+			model.HibernatingDatabaseWeight,
+		},
+		{
+			"large size",
+			&model.Installation{Size: mmv1alpha1.Size10000String, State: model.InstallationStateStable},
+			4,
+		},
+		{
+			"large size hibernating",
+			&model.Installation{Size: mmv1alpha1.Size10000String, State: model.InstallationStateHibernating},
+			4 * model.HibernatingDatabaseWeight,
+		},
+		{
+			"metrics below size weight",
+			&model.Installation{
+				Size:            mmv1alpha1.Size5000String,
+				State:           model.InstallationStateStable,
+				DatabaseMetrics: &model.InstallationDatabaseMetrics{SizeBytes: model.DatabaseWeightSizeBytes},
+			},
+			2,
+		},
+		{
+			"metrics above size weight",
+			&model.Installation{
+				Size:            mmv1alpha1.Size100String,
+				State:           model.InstallationStateStable,
+				DatabaseMetrics: &model.InstallationDatabaseMetrics{Connections: 3 * model.DatabaseWeightConnections},
+			},
+			3,
+		},
+	} {
+		t.Run(testCase.description, func(t *testing.T) {
+			assert.Equal(t, testCase.expectedWeight, testCase.installation.GetDatabaseWeight())
+		})
+	}
+}
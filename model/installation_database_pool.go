@@ -0,0 +1,85 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// InstallationDatabasePoolConfig overrides the PgBouncer pool settings of an
+// installation using a proxied multitenant database. Pools are kept per
+// installation database user, so the settings only apply to the installation.
+type InstallationDatabasePoolConfig struct {
+	// PoolSize is the number of server connections PgBouncer keeps open for
+	// the installation. The default pool size is used when not set.
+	PoolSize int `json:"PoolSize,omitempty"`
+	// MaxConnections is the maximum number of server connections the
+	// installation may use. There is no installation limit when not set.
+	MaxConnections int `json:"MaxConnections,omitempty"`
+}
+
+// Validate validates the database pool config.
+func (c *InstallationDatabasePoolConfig) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	if c.PoolSize < 0 {
+		return errors.Errorf("pool size must be 0 or higher (was %d)", c.PoolSize)
+	}
+	if c.MaxConnections < 0 {
+		return errors.Errorf("max connections must be 0 or higher (was %d)", c.MaxConnections)
+	}
+	if c.PoolSize != 0 && c.PoolSize < GetMinPoolSize() {
+		return errors.Errorf("pool size (%d) must not be lower than the min pool size (%d)", c.PoolSize, GetMinPoolSize())
+	}
+	if c.MaxConnections != 0 && c.PoolSize > c.MaxConnections {
+		return errors.Errorf("pool size (%d) must not be higher than max connections (%d)", c.PoolSize, c.MaxConnections)
+	}
+
+	return nil
+}
+
+// ValidateForLogicalDatabase validates that the pool settings leave enough
+// server connections of a logical database for the min pools of the other
+// installations sharing it.
+func (c *InstallationDatabasePoolConfig) ValidateForLogicalDatabase(maxInstallationsPerLogicalDatabase int64) error {
+	if c == nil {
+		return nil
+	}
+
+	available := GetMaxDatabaseConnectionsPerPool() - int(maxInstallationsPerLogicalDatabase-1)*GetMinPoolSize()
+	if c.PoolSize > available {
+		return errors.Errorf("pool size (%d) must not be higher than %d with up to %d installations per logical database", c.PoolSize, available, maxInstallationsPerLogicalDatabase)
+	}
+	if c.MaxConnections > available {
+		return errors.Errorf("max connections (%d) must not be higher than %d with up to %d installations per logical database", c.MaxConnections, available, maxInstallationsPerLogicalDatabase)
+	}
+
+	return nil
+}
+
+// IsEmpty returns true if no pool settings are overridden.
+func (c *InstallationDatabasePoolConfig) IsEmpty() bool {
+	return c == nil || c.PoolSize == 0 && c.MaxConnections == 0
+}
+
+// ToJSON marshals the database pool config to JSON if it is not nil.
+func (c *InstallationDatabasePoolConfig) ToJSON() ([]byte, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return json.Marshal(c)
+}
+
+// Equals returns true if both configs override the same pool settings.
+func (c *InstallationDatabasePoolConfig) Equals(other *InstallationDatabasePoolConfig) bool {
+	if c.IsEmpty() || other.IsEmpty() {
+		return c.IsEmpty() && other.IsEmpty()
+	}
+	return *c == *other
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallationDatabasePoolConfigValidate(t *testing.T) {
+	model.SetMinPoolSize(2)
+	defer model.SetMinPoolSize(1)
+
+	var testCases = []struct {
+		testName    string
+		expectError bool
+		config      *model.InstallationDatabasePoolConfig
+	}{
+		{"nil", false, nil},
+		{"empty", false, &model.InstallationDatabasePoolConfig{}},
+		{"pool size only", false, &model.InstallationDatabasePoolConfig{PoolSize: 4}},
+		{"max connections only", false, &model.InstallationDatabasePoolConfig{MaxConnections: 1}},
+		{"pool size and max connections", false, &model.InstallationDatabasePoolConfig{PoolSize: 4, MaxConnections: 4}},
+		{"negative pool size", true, &model.InstallationDatabasePoolConfig{PoolSize: -1}},
+		{"negative max connections", true, &model.InstallationDatabasePoolConfig{MaxConnections: -1}},
+		{"pool size lower than min pool size", true, &model.InstallationDatabasePoolConfig{PoolSize: 1}},
+		{"pool size higher than max connections", true, &model.InstallationDatabasePoolConfig{PoolSize: 5, MaxConnections: 4}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			if tc.expectError {
+				assert.Error(t, tc.config.Validate())
+				return
+			}
+
+			assert.NoError(t, tc.config.Validate())
+		})
+	}
+}
+
+func TestInstallationDatabasePoolConfigValidateForLogicalDatabase(t *testing.T) {
+	model.SetMinPoolSize(1)
+	require.NoError(t, model.SetMaxDatabaseConnectionsPerPool(20))
+
+	var nilConfig *model.InstallationDatabasePoolConfig
+	assert.NoError(t, nilConfig.ValidateForLogicalDatabase(10))
+
+	config := &model.InstallationDatabasePoolConfig{PoolSize: 11, MaxConnections: 11}
+	assert.NoError(t, config.ValidateForLogicalDatabase(10))
+	assert.Error(t, config.ValidateForLogicalDatabase(11))
+
+	config = &model.InstallationDatabasePoolConfig{MaxConnections: 20}
+	assert.NoError(t, config.ValidateForLogicalDatabase(1))
+	assert.Error(t, config.ValidateForLogicalDatabase(2))
+}
+
+func TestInstallationDatabasePoolConfigEquals(t *testing.T) {
+	var nilConfig *model.InstallationDatabasePoolConfig
+
+	assert.True(t, nilConfig.Equals(nil))
+	assert.True(t, nilConfig.Equals(&model.InstallationDatabasePoolConfig{}))
+	assert.True(t, (&model.InstallationDatabasePoolConfig{PoolSize: 5}).Equals(&model.InstallationDatabasePoolConfig{PoolSize: 5}))
+	assert.False(t, (&model.InstallationDatabasePoolConfig{PoolSize: 5}).Equals(nil))
+	assert.False(t, (&model.InstallationDatabasePoolConfig{PoolSize: 5}).Equals(&model.InstallationDatabasePoolConfig{PoolSize: 5, MaxConnections: 10}))
+}
@@ -47,6 +47,7 @@ func TestIsSupportedDatabase(t *testing.T) {
 		{model.InstallationDatabaseMultiTenantRDSMySQL, true},
 		{model.InstallationDatabaseMultiTenantRDSPostgres, true},
 		{model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer, true},
+		{model.InstallationDatabaseExternalPostgres, true},
 	}
 
 	for _, tc := range testCases {
@@ -99,3 +100,25 @@ func TestIsMultiTenantDatabase(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportsCredentialRotation(t *testing.T) {
+	var testCases = []struct {
+		database        string
+		expectSupported bool
+	}{
+		{"", false},
+		{model.InstallationDatabaseMysqlOperator, false},
+		{model.InstallationDatabaseSingleTenantRDSMySQL, false},
+		{model.InstallationDatabaseSingleTenantRDSPostgres, false},
+		{model.InstallationDatabaseMultiTenantRDSMySQL, true},
+		{model.InstallationDatabaseMultiTenantRDSPostgres, true},
+		{model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer, true},
+		{model.InstallationDatabaseExternalPostgres, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.database, func(t *testing.T) {
+			assert.Equal(t, tc.expectSupported, model.SupportsCredentialRotation(tc.database))
+		})
+	}
+}
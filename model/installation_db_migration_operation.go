@@ -21,7 +21,7 @@ type InstallationDBMigrationOperation struct {
 	SourceDatabase string
 	// DestinationDatabase is database type to which migration will be performed.
 	DestinationDatabase string
-	// For now only supported migration is from multi-tenant DB to multi-tenant DB.
+	// SourceMultiTenant is empty when migrating out of a single tenant database.
 	SourceMultiTenant                    *MultiTenantDBMigrationData `json:"SourceMultiTenant,omitempty"`
 	DestinationMultiTenant               *MultiTenantDBMigrationData `json:"DestinationMultiTenant,omitempty"`
 	BackupID                             string
@@ -45,6 +45,12 @@ const (
 	InstallationDBMigrationStateRequested InstallationDBMigrationOperationState = "installation-db-migration-requested"
 	// InstallationDBMigrationStateBackupInProgress is DB migration operation waiting for backup to complete.
 	InstallationDBMigrationStateBackupInProgress InstallationDBMigrationOperationState = "installation-db-migration-installation-backup-in-progress"
+	// InstallationDBMigrationStateEngineMigrationSetup is DB migration operation that is preparing the destination database for engine migration.
+	InstallationDBMigrationStateEngineMigrationSetup InstallationDBMigrationOperationState = "installation-db-migration-engine-migration-setup"
+	// InstallationDBMigrationStateEngineMigrationInProgress is DB migration operation waiting for the data to be migrated between database engines.
+	InstallationDBMigrationStateEngineMigrationInProgress InstallationDBMigrationOperationState = "installation-db-migration-engine-migration-in-progress"
+	// InstallationDBMigrationStateVerifyingRowCounts is DB migration operation comparing row counts of migrated tables between the databases.
+	InstallationDBMigrationStateVerifyingRowCounts InstallationDBMigrationOperationState = "installation-db-migration-verifying-row-counts"
 	// InstallationDBMigrationStateDatabaseSwitch is DB migration operation that is switching to new database.
 	InstallationDBMigrationStateDatabaseSwitch InstallationDBMigrationOperationState = "installation-db-migration-database switch"
 	// InstallationDBMigrationStateRefreshSecrets is DB migration operation that is refreshing secrets.
@@ -80,6 +86,9 @@ const (
 var AllInstallationDBMigrationOperationsStatesPendingWork = []InstallationDBMigrationOperationState{
 	InstallationDBMigrationStateRequested,
 	InstallationDBMigrationStateBackupInProgress,
+	InstallationDBMigrationStateEngineMigrationSetup,
+	InstallationDBMigrationStateEngineMigrationInProgress,
+	InstallationDBMigrationStateVerifyingRowCounts,
 	InstallationDBMigrationStateDatabaseSwitch,
 	InstallationDBMigrationStateRefreshSecrets,
 	InstallationDBMigrationStateTriggerRestoration,
@@ -123,6 +132,12 @@ func NewDBMigrationOperationsFromReader(reader io.Reader) ([]*InstallationDBMigr
 	return dBMigrationOperations, nil
 }
 
+// IsEngineMigration returns true if the migration moves installation data
+// between different database engines, for example from MySQL to PostgreSQL.
+func (b InstallationDBMigrationOperation) IsEngineMigration() bool {
+	return InstallationDatabaseEngineType(b.SourceDatabase) != InstallationDatabaseEngineType(b.DestinationDatabase)
+}
+
 // ValidTransitionState returns whether an installation backup can be transitioned into
 // the new state or not based on its current state.
 func (b InstallationDBMigrationOperation) ValidTransitionState(newState InstallationDBMigrationOperationState) bool {
@@ -112,3 +112,41 @@ func TestInstallationDBMigrationOperation_ValidTransitionState(t *testing.T) {
 		})
 	}
 }
+
+func TestInstallationDBMigrationOperation_IsEngineMigration(t *testing.T) {
+	for _, testCase := range []struct {
+		source      string
+		destination string
+		expected    bool
+	}{
+		{
+			source:      InstallationDatabaseMultiTenantRDSPostgres,
+			destination: InstallationDatabaseMultiTenantRDSPostgres,
+			expected:    false,
+		},
+		{
+			source:      InstallationDatabaseMultiTenantRDSPostgres,
+			destination: InstallationDatabaseExternalPostgres,
+			expected:    false,
+		},
+		{
+			source:      InstallationDatabaseMultiTenantRDSMySQL,
+			destination: InstallationDatabaseMultiTenantRDSPostgres,
+			expected:    true,
+		},
+		{
+			source:      InstallationDatabaseSingleTenantRDSMySQL,
+			destination: InstallationDatabaseMultiTenantRDSPostgres,
+			expected:    true,
+		},
+	} {
+		t.Run(testCase.source+" to "+testCase.destination, func(t *testing.T) {
+			dbMigration := &InstallationDBMigrationOperation{
+				SourceDatabase:      testCase.source,
+				DestinationDatabase: testCase.destination,
+			}
+
+			assert.Equal(t, testCase.expected, dbMigration.IsEngineMigration())
+		})
+	}
+}
@@ -5,6 +5,7 @@
 package model
 
 import (
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 
 	corev1 "k8s.io/api/core/v1"
@@ -22,6 +23,10 @@ const (
 	// InstallationFilestoreBifrost is a filestore hosted via a shared Amazon S3
 	// bucket using the bifrost gateway.
 	InstallationFilestoreBifrost = "bifrost"
+	// InstallationFilestoreS3Compatible is a filestore hosted on an
+	// S3-compatible object storage server configured on the provisioning
+	// server.
+	InstallationFilestoreS3Compatible = "s3-compatible"
 )
 
 // Filestore is the interface for managing Mattermost filestores.
@@ -29,6 +34,8 @@ type Filestore interface {
 	Provision(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error
 	Teardown(keepData bool, store InstallationDatabaseStoreInterface, logger log.FieldLogger) error
 	GenerateFilestoreSpecAndSecret(store InstallationDatabaseStoreInterface, logger log.FieldLogger) (*FilestoreConfig, *corev1.Secret, error)
+	RotateAccessKey(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error
+	RevokePreviousAccessKey(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error
 }
 
 // FilestoreConfig represent universal configuration of the File store.
@@ -69,6 +76,16 @@ func (f *MinioOperatorFilestore) GenerateFilestoreSpecAndSecret(store Installati
 	return nil, nil, nil
 }
 
+// RotateAccessKey rotating access keys is not supported for MinIO operator filestores.
+func (f *MinioOperatorFilestore) RotateAccessKey(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("rotating access keys is not supported for MinIO operator")
+}
+
+// RevokePreviousAccessKey revoking access keys is not supported for MinIO operator filestores.
+func (f *MinioOperatorFilestore) RevokePreviousAccessKey(store InstallationDatabaseStoreInterface, logger log.FieldLogger) error {
+	return errors.New("revoking access keys is not supported for MinIO operator")
+}
+
 // InternalFilestore returns true if the installation's filestore is internal
 // to the kubernetes cluster it is running on.
 func (i *Installation) InternalFilestore() bool {
@@ -80,5 +97,13 @@ func IsSupportedFilestore(filestore string) bool {
 	return filestore == InstallationFilestoreMinioOperator ||
 		filestore == InstallationFilestoreAwsS3 ||
 		filestore == InstallationFilestoreMultiTenantAwsS3 ||
-		filestore == InstallationFilestoreBifrost
+		filestore == InstallationFilestoreBifrost ||
+		filestore == InstallationFilestoreS3Compatible
+}
+
+// SupportsAccessKeyRotation returns true if the access key of installations
+// using the given filestore can be rotated.
+func SupportsAccessKeyRotation(filestore string) bool {
+	return filestore == InstallationFilestoreAwsS3 ||
+		filestore == InstallationFilestoreMultiTenantAwsS3
 }
@@ -42,6 +42,7 @@ func TestIsSupportedFilestore(t *testing.T) {
 		{"unknown", false},
 		{model.InstallationFilestoreMinioOperator, true},
 		{model.InstallationFilestoreAwsS3, true},
+		{model.InstallationFilestoreS3Compatible, true},
 	}
 
 	for _, tc := range testCases {
@@ -50,3 +51,23 @@ func TestIsSupportedFilestore(t *testing.T) {
 		})
 	}
 }
+
+func TestSupportsAccessKeyRotation(t *testing.T) {
+	var testCases = []struct {
+		filestore       string
+		expectSupported bool
+	}{
+		{"", false},
+		{model.InstallationFilestoreMinioOperator, false},
+		{model.InstallationFilestoreAwsS3, true},
+		{model.InstallationFilestoreMultiTenantAwsS3, true},
+		{model.InstallationFilestoreBifrost, false},
+		{model.InstallationFilestoreS3Compatible, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.filestore, func(t *testing.T) {
+			assert.Equal(t, tc.expectSupported, model.SupportsAccessKeyRotation(tc.filestore))
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// InstallationPlacement contains rules constraining which clusters an
+// installation can be scheduled on.
+type InstallationPlacement struct {
+	// RequiredClusterAnnotations are annotations that a cluster must have for
+	// the installation to be scheduled on it.
+	RequiredClusterAnnotations []string `json:"RequiredClusterAnnotations,omitempty"`
+	// PreferredClusterAnnotations are annotations that are used to order
+	// compatible clusters. Clusters matching more preferred annotations are
+	// tried first.
+	PreferredClusterAnnotations []string `json:"PreferredClusterAnnotations,omitempty"`
+	// OwnerAntiAffinity prevents the installation from sharing a cluster with
+	// installations belonging to other owners.
+	OwnerAntiAffinity bool `json:"OwnerAntiAffinity,omitempty"`
+	// NodeGroup is the name of the additional worker node group the
+	// installation pods are scheduled on. Only clusters with the node group
+	// are compatible with the installation.
+	NodeGroup string `json:"NodeGroup,omitempty"`
+}
+
+// Validate validates the installation placement rules.
+func (p *InstallationPlacement) Validate() error {
+	if p == nil {
+		return nil
+	}
+
+	_, err := AnnotationsFromStringSlice(p.RequiredClusterAnnotations)
+	if err != nil {
+		return errors.Wrap(err, "invalid required cluster annotations")
+	}
+	_, err = AnnotationsFromStringSlice(p.PreferredClusterAnnotations)
+	if err != nil {
+		return errors.Wrap(err, "invalid preferred cluster annotations")
+	}
+	if len(p.NodeGroup) != 0 && !nodeGroupNameRegex.MatchString(p.NodeGroup) {
+		return errors.Errorf("invalid node group name %q", p.NodeGroup)
+	}
+
+	return nil
+}
+
+// IsEmpty returns true if no placement rules are set.
+func (p *InstallationPlacement) IsEmpty() bool {
+	return p == nil ||
+		len(p.RequiredClusterAnnotations) == 0 &&
+			len(p.PreferredClusterAnnotations) == 0 &&
+			!p.OwnerAntiAffinity &&
+			len(p.NodeGroup) == 0
+}
+
+// ToJSON marshals placement rules to JSON if they are not nil.
+func (p *InstallationPlacement) ToJSON() ([]byte, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
+// HasRequiredClusterAnnotations returns true if the provided cluster
+// annotations contain all required cluster annotations.
+func (p *InstallationPlacement) HasRequiredClusterAnnotations(clusterAnnotations []*Annotation) bool {
+	if p == nil {
+		return true
+	}
+
+	names := annotationNameSet(clusterAnnotations)
+	for _, required := range p.RequiredClusterAnnotations {
+		if _, ok := names[required]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RequiresClusterAnnotation returns true if the given annotation name is one
+// of the required cluster annotations.
+func (p *InstallationPlacement) RequiresClusterAnnotation(name string) bool {
+	if p == nil {
+		return false
+	}
+	for _, required := range p.RequiredClusterAnnotations {
+		if required == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PreferredClusterAnnotationsScore returns the number of preferred cluster
+// annotations present in the provided cluster annotations.
+func (p *InstallationPlacement) PreferredClusterAnnotationsScore(clusterAnnotations []*Annotation) int {
+	if p == nil {
+		return 0
+	}
+
+	names := annotationNameSet(clusterAnnotations)
+	var score int
+	for _, preferred := range p.PreferredClusterAnnotations {
+		if _, ok := names[preferred]; ok {
+			score++
+		}
+	}
+
+	return score
+}
+
+// ConflictsWithOwner returns true if owner anti-affinity prevents installations
+// of the two owners from sharing a cluster.
+func (p *InstallationPlacement) ConflictsWithOwner(ownerID, otherOwnerID string) bool {
+	if p == nil || !p.OwnerAntiAffinity {
+		return false
+	}
+
+	return ownerID != otherOwnerID
+}
+
+// GetNodeGroup returns the node group the installation is scheduled on, or an
+// empty string if the installation uses the default worker nodes.
+func (p *InstallationPlacement) GetNodeGroup() string {
+	if p == nil {
+		return ""
+	}
+
+	return p.NodeGroup
+}
+
+// ClusterHasNodeGroup returns true if the cluster provides the node group
+// required by the placement rules.
+func (p *InstallationPlacement) ClusterHasNodeGroup(cluster *Cluster) bool {
+	nodeGroup := p.GetNodeGroup()
+	if len(nodeGroup) == 0 {
+		return true
+	}
+	if cluster.ProvisionerMetadataKops == nil {
+		return false
+	}
+	_, ok := cluster.ProvisionerMetadataKops.GetNodeGroup(nodeGroup)
+
+	return ok
+}
+
+func annotationNameSet(annotations []*Annotation) map[string]struct{} {
+	names := make(map[string]struct{}, len(annotations))
+	for _, annotation := range annotations {
+		names[annotation.Name] = struct{}{}
+	}
+
+	return names
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstallationPlacementValidate(t *testing.T) {
+	var nilPlacement *model.InstallationPlacement
+	require.NoError(t, nilPlacement.Validate())
+
+	require.NoError(t, (&model.InstallationPlacement{
+		RequiredClusterAnnotations:  []string{"compliance-hipaa"},
+		PreferredClusterAnnotations: []string{"region-eu"},
+		OwnerAntiAffinity:           true,
+	}).Validate())
+
+	require.Error(t, (&model.InstallationPlacement{
+		RequiredClusterAnnotations: []string{"Invalid Annotation"},
+	}).Validate())
+	require.Error(t, (&model.InstallationPlacement{
+		PreferredClusterAnnotations: []string{"a"},
+	}).Validate())
+	require.NoError(t, (&model.InstallationPlacement{NodeGroup: "memory"}).Validate())
+	require.Error(t, (&model.InstallationPlacement{NodeGroup: "Memory Group"}).Validate())
+}
+
+func TestInstallationPlacementRules(t *testing.T) {
+	clusterAnnotations := []*model.Annotation{
+		{Name: "compliance-hipaa"}, {Name: "region-eu"},
+	}
+
+	t.Run("nil placement", func(t *testing.T) {
+		var placement *model.InstallationPlacement
+		assert.True(t, placement.IsEmpty())
+		assert.True(t, placement.HasRequiredClusterAnnotations(nil))
+		assert.False(t, placement.RequiresClusterAnnotation("compliance-hipaa"))
+		assert.Equal(t, 0, placement.PreferredClusterAnnotationsScore(clusterAnnotations))
+		assert.False(t, placement.ConflictsWithOwner("owner1", "owner2"))
+	})
+
+	t.Run("required annotations", func(t *testing.T) {
+		placement := &model.InstallationPlacement{
+			RequiredClusterAnnotations: []string{"compliance-hipaa", "region-eu"},
+		}
+		assert.False(t, placement.IsEmpty())
+		assert.True(t, placement.HasRequiredClusterAnnotations(clusterAnnotations))
+		assert.False(t, placement.HasRequiredClusterAnnotations(clusterAnnotations[:1]))
+		assert.True(t, placement.RequiresClusterAnnotation("region-eu"))
+		assert.False(t, placement.RequiresClusterAnnotation("region-us"))
+	})
+
+	t.Run("preferred annotations", func(t *testing.T) {
+		placement := &model.InstallationPlacement{
+			PreferredClusterAnnotations: []string{"region-eu", "compliance-hipaa", "dedicated"},
+		}
+		assert.Equal(t, 2, placement.PreferredClusterAnnotationsScore(clusterAnnotations))
+		assert.Equal(t, 0, placement.PreferredClusterAnnotationsScore(nil))
+	})
+
+	t.Run("owner anti-affinity", func(t *testing.T) {
+		placement := &model.InstallationPlacement{OwnerAntiAffinity: true}
+		assert.True(t, placement.ConflictsWithOwner("owner1", "owner2"))
+		assert.False(t, placement.ConflictsWithOwner("owner1", "owner1"))
+
+		placement.OwnerAntiAffinity = false
+		assert.False(t, placement.ConflictsWithOwner("owner1", "owner2"))
+	})
+
+	t.Run("node group", func(t *testing.T) {
+		cluster := &model.Cluster{
+			ProvisionerMetadataKops: &model.KopsMetadata{
+				CustomInstanceGroups: model.KopsInstanceGroupsMetadata{"memory": {}},
+			},
+		}
+
+		var nilPlacement *model.InstallationPlacement
+		assert.Empty(t, nilPlacement.GetNodeGroup())
+		assert.True(t, nilPlacement.ClusterHasNodeGroup(cluster))
+
+		placement := &model.InstallationPlacement{NodeGroup: "memory"}
+		assert.False(t, placement.IsEmpty())
+		assert.True(t, placement.ClusterHasNodeGroup(cluster))
+		assert.False(t, placement.ClusterHasNodeGroup(&model.Cluster{}))
+
+		placement.NodeGroup = "compute"
+		assert.False(t, placement.ClusterHasNodeGroup(cluster))
+	})
+}
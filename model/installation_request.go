@@ -54,6 +54,12 @@ type CreateInstallationRequest struct {
 	Annotations     []string
 	// SingleTenantDatabaseConfig is ignored if Database is not single tenant mysql or postgres.
 	SingleTenantDatabaseConfig SingleTenantDatabaseRequest
+	// Placement contains optional rules constraining which clusters the
+	// installation can be scheduled on.
+	Placement *InstallationPlacement `json:"Placement,omitempty"`
+	// DatabasePoolConfig overrides the database pool settings of the
+	// installation. It is only supported with proxied multitenant databases.
+	DatabasePoolConfig *InstallationDatabasePoolConfig `json:"DatabasePoolConfig,omitempty"`
 }
 
 // https://man7.org/linux/man-pages/man7/hostname.7.html
@@ -125,6 +131,16 @@ func (request *CreateInstallationRequest) Validate() error {
 		}
 	}
 
+	err = request.Placement.Validate()
+	if err != nil {
+		return errors.Wrap(err, "invalid placement rules")
+	}
+
+	err = validateDatabasePoolConfig(request.DatabasePoolConfig, request.Database)
+	if err != nil {
+		return err
+	}
+
 	if IsSingleTenantRDS(request.Database) {
 		err = request.SingleTenantDatabaseConfig.Validate()
 		if err != nil {
@@ -256,6 +272,9 @@ type PatchInstallationRequest struct {
 	License       *string
 	PriorityEnv   EnvVarMap
 	MattermostEnv EnvVarMap
+	// DatabasePoolConfig replaces the database pool settings of the
+	// installation. An empty config removes the overrides.
+	DatabasePoolConfig *InstallationDatabasePoolConfig `json:"DatabasePoolConfig,omitempty"`
 }
 
 // Validate validates the values of a installation patch request.
@@ -272,12 +291,38 @@ func (p *PatchInstallationRequest) Validate() error {
 			return errors.Wrap(err, "invalid size")
 		}
 	}
+	err := p.DatabasePoolConfig.Validate()
+	if err != nil {
+		return errors.Wrap(err, "invalid database pool config")
+	}
 	// EnvVarMap validation is skipped as all configurations of this now imply
 	// a specific patch action should be taken.
 
 	return nil
 }
 
+// validateDatabasePoolConfig validates database pool overrides against the
+// installation database type and the default number of installations sharing
+// a logical database.
+func validateDatabasePoolConfig(config *InstallationDatabasePoolConfig, database string) error {
+	if config.IsEmpty() {
+		return nil
+	}
+	if database != InstallationDatabaseMultiTenantRDSPostgresPGBouncer {
+		return errors.Errorf("database pool config is not supported with database %s", database)
+	}
+	err := config.Validate()
+	if err != nil {
+		return errors.Wrap(err, "invalid database pool config")
+	}
+	err = config.ValidateForLogicalDatabase(GetDefaultProxyDatabaseMaxInstallationsPerLogicalDatabase())
+	if err != nil {
+		return errors.Wrap(err, "invalid database pool config")
+	}
+
+	return nil
+}
+
 // Apply applies the patch to the given installation.
 func (p *PatchInstallationRequest) Apply(installation *Installation) bool {
 	var applied bool
@@ -312,6 +357,19 @@ func (p *PatchInstallationRequest) Apply(installation *Installation) bool {
 			applied = true
 		}
 	}
+	if p.DatabasePoolConfig != nil {
+		var poolConfig *InstallationDatabasePoolConfig
+		if !p.DatabasePoolConfig.IsEmpty() {
+			poolConfig = &InstallationDatabasePoolConfig{
+				PoolSize:       p.DatabasePoolConfig.PoolSize,
+				MaxConnections: p.DatabasePoolConfig.MaxConnections,
+			}
+		}
+		if !installation.DatabasePoolConfig.Equals(poolConfig) {
+			applied = true
+			installation.DatabasePoolConfig = poolConfig
+		}
+	}
 
 	return applied
 }
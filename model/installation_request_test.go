@@ -214,6 +214,27 @@ func TestCreateInstallationRequestValid(t *testing.T) {
 		model.SetRequireAnnotatedInstallations(false)
 	})
 
+	t.Run("database pool config", func(t *testing.T) {
+		model.SetMinPoolSize(1)
+		require.NoError(t, model.SetMaxDatabaseConnectionsPerPool(20))
+
+		request := &model.CreateInstallationRequest{
+			OwnerID:            "owner1",
+			DNS:                "domain4321.com",
+			Database:           model.InstallationDatabaseMultiTenantRDSPostgresPGBouncer,
+			DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5, MaxConnections: 10},
+		}
+		request.SetDefaults()
+		assert.NoError(t, request.Validate())
+
+		request.DatabasePoolConfig.MaxConnections = 12
+		assert.Error(t, request.Validate())
+
+		request.DatabasePoolConfig.MaxConnections = 10
+		request.Database = model.InstallationDatabaseMultiTenantRDSPostgres
+		assert.Error(t, request.Validate())
+	})
+
 	t.Run("convert DNS to lowercase", func(t *testing.T) {
 		request := &model.CreateInstallationRequest{
 			OwnerID: "owner1",
@@ -300,6 +321,13 @@ func TestPatchInstallationRequestValid(t *testing.T) {
 				Image: sToP("image1"),
 			},
 		},
+		{
+			"invalid database pool config",
+			true,
+			&model.PatchInstallationRequest{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 10, MaxConnections: 5},
+			},
+		},
 		{
 			"invalid image only",
 			true,
@@ -444,6 +472,41 @@ func TestPatchInstallationRequestApply(t *testing.T) {
 				},
 			},
 		},
+		{
+			"database pool config only",
+			true,
+			&model.PatchInstallationRequest{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5},
+			},
+			&model.Installation{},
+			&model.Installation{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5},
+			},
+		},
+		{
+			"database pool config only, no changes",
+			false,
+			&model.PatchInstallationRequest{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5},
+			},
+			&model.Installation{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5},
+			},
+			&model.Installation{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5},
+			},
+		},
+		{
+			"database pool config only, remove overrides",
+			true,
+			&model.PatchInstallationRequest{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{},
+			},
+			&model.Installation{
+				DatabasePoolConfig: &model.InstallationDatabasePoolConfig{PoolSize: 5},
+			},
+			&model.Installation{},
+		},
 		{
 			"mattermost env only, patch installation env with new key",
 			true,
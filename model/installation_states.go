@@ -73,6 +73,28 @@ const (
 	InstallationStateDBMigrationFailed = "db-migration-failed"
 	// InstallationStateDNSMigrationHibernating is an hibernated installation that is being migrated to different cluster.
 	InstallationStateDNSMigrationHibernating = "dns-migration-hibernated"
+	// InstallationStateDBCredentialRotationRequested is an installation whose
+	// database credentials are about to be rotated.
+	InstallationStateDBCredentialRotationRequested = "db-credential-rotation-requested"
+	// InstallationStateDBCredentialRotationInProgress is an installation that
+	// is waiting for its pods to use rotated database credentials.
+	InstallationStateDBCredentialRotationInProgress = "db-credential-rotation-in-progress"
+	// InstallationStateDBCredentialRotationFailed is an installation for which
+	// database credential rotation failed.
+	InstallationStateDBCredentialRotationFailed = "db-credential-rotation-failed"
+	// InstallationStateFilestoreAccessKeyRotationRequested is an installation
+	// whose filestore access key is about to be rotated.
+	InstallationStateFilestoreAccessKeyRotationRequested = "filestore-access-key-rotation-requested"
+	// InstallationStateFilestoreAccessKeyRotationRefreshingSecrets is an
+	// installation whose new filestore access key was stored and whose pods
+	// are about to be rolled to use it.
+	InstallationStateFilestoreAccessKeyRotationRefreshingSecrets = "filestore-access-key-rotation-refreshing-secrets"
+	// InstallationStateFilestoreAccessKeyRotationInProgress is an installation
+	// that is waiting for its pods to use a rotated filestore access key.
+	InstallationStateFilestoreAccessKeyRotationInProgress = "filestore-access-key-rotation-in-progress"
+	// InstallationStateFilestoreAccessKeyRotationFailed is an installation for
+	// which filestore access key rotation failed.
+	InstallationStateFilestoreAccessKeyRotationFailed = "filestore-access-key-rotation-failed"
 )
 
 const (
@@ -112,6 +134,13 @@ var AllInstallationStates = []string{
 	InstallationStateDBRestorationFailed,
 	InstallationStateDBMigrationFailed,
 	InstallationStateDNSMigrationHibernating,
+	InstallationStateDBCredentialRotationRequested,
+	InstallationStateDBCredentialRotationInProgress,
+	InstallationStateDBCredentialRotationFailed,
+	InstallationStateFilestoreAccessKeyRotationRequested,
+	InstallationStateFilestoreAccessKeyRotationRefreshingSecrets,
+	InstallationStateFilestoreAccessKeyRotationInProgress,
+	InstallationStateFilestoreAccessKeyRotationFailed,
 }
 
 // AllInstallationStatesPendingWork is a list of all installation states that
@@ -135,6 +164,11 @@ var AllInstallationStatesPendingWork = []string{
 	InstallationStateDeletionInProgress,
 	InstallationStateDeletionFinalCleanup,
 	InstallationStateDNSMigrationHibernating,
+	InstallationStateDBCredentialRotationRequested,
+	InstallationStateDBCredentialRotationInProgress,
+	InstallationStateFilestoreAccessKeyRotationRequested,
+	InstallationStateFilestoreAccessKeyRotationRefreshingSecrets,
+	InstallationStateFilestoreAccessKeyRotationInProgress,
 }
 
 // AllInstallationRequestStates is a list of all states that an installation can
@@ -149,6 +183,8 @@ var AllInstallationRequestStates = []string{
 	InstallationStateUpdateRequested,
 	InstallationStateDeletionRequested,
 	InstallationStateDNSMigrationHibernating,
+	InstallationStateDBCredentialRotationRequested,
+	InstallationStateFilestoreAccessKeyRotationRequested,
 }
 
 // ValidTransitionState returns whether an installation can be transitioned into
@@ -201,6 +237,8 @@ var (
 			InstallationStateDeletionInProgress,
 			InstallationStateDeletionFinalCleanup,
 			InstallationStateDeletionFailed,
+			InstallationStateDBCredentialRotationFailed,
+			InstallationStateFilestoreAccessKeyRotationFailed,
 		},
 		InstallationStateDBRestorationInProgress: {
 			InstallationStateHibernating,
@@ -211,6 +249,14 @@ var (
 		InstallationStateDNSMigrationHibernating: {
 			InstallationStateHibernating,
 		},
+		InstallationStateDBCredentialRotationRequested: {
+			InstallationStateStable,
+			InstallationStateDBCredentialRotationFailed,
+		},
+		InstallationStateFilestoreAccessKeyRotationRequested: {
+			InstallationStateStable,
+			InstallationStateFilestoreAccessKeyRotationFailed,
+		},
 	}
 )
 
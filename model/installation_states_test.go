@@ -43,6 +43,26 @@ func TestInstallation_ValidTransitionState(t *testing.T) {
 			newState: InstallationStateUpdateRequested,
 			isValid:  false,
 		},
+		{
+			oldState: InstallationStateDBCredentialRotationFailed,
+			newState: InstallationStateDBCredentialRotationRequested,
+			isValid:  true,
+		},
+		{
+			oldState: InstallationStateHibernating,
+			newState: InstallationStateDBCredentialRotationRequested,
+			isValid:  false,
+		},
+		{
+			oldState: InstallationStateStable,
+			newState: InstallationStateFilestoreAccessKeyRotationRequested,
+			isValid:  true,
+		},
+		{
+			oldState: InstallationStateDBCredentialRotationFailed,
+			newState: InstallationStateFilestoreAccessKeyRotationRequested,
+			isValid:  false,
+		},
 	} {
 		t.Run(testCase.oldState+" to "+testCase.newState, func(t *testing.T) {
 			installation := Installation{State: testCase.oldState}
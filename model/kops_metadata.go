@@ -22,6 +22,8 @@ type KopsMetadata struct {
 	NodeInstanceType     string
 	NodeMinCount         int64
 	NodeMaxCount         int64
+	NodeDesiredCount     int64 `json:"NodeDesiredCount,omitempty"`
+	AutoscaledAt         int64 `json:"AutoscaledAt,omitempty"`
 	VPC                  string
 	Networking           string
 	MasterInstanceGroups KopsInstanceGroupsMetadata
@@ -40,6 +42,18 @@ type KopsInstanceGroupMetadata struct {
 	NodeInstanceType string
 	NodeMinCount     int64
 	NodeMaxCount     int64
+	// Labels are the k8s labels of the nodes of a worker instance group.
+	Labels map[string]string `json:"Labels,omitempty"`
+	// Taints are the k8s taints of the nodes of a worker instance group in
+	// the format key=value:effect.
+	Taints []string `json:"Taints,omitempty"`
+	// MaxPrice is the maximum hourly price paid for the nodes of a worker
+	// instance group. Instance groups with a max price use spot instances
+	// instead of on-demand instances.
+	MaxPrice string `json:"MaxPrice,omitempty"`
+	// MixedInstancesPolicy lets a worker instance group combine several
+	// instance types and on-demand and spot instances.
+	MixedInstancesPolicy *KopsMixedInstancesPolicy `json:"MixedInstancesPolicy,omitempty"`
 }
 
 // KopsMetadataRequestedState is the requested state for kops metadata.
@@ -51,8 +65,14 @@ type KopsMetadataRequestedState struct {
 	NodeInstanceType   string `json:"NodeInstanceType,omitempty"`
 	NodeMinCount       int64  `json:"NodeMinCount,omitempty"`
 	NodeMaxCount       int64  `json:"NodeMaxCount,omitempty"`
+	NodeDesiredCount   int64  `json:"NodeDesiredCount,omitempty"`
 	Networking         string `json:"Networking,omitempty"`
 	VPC                string `json:"VPC,omitempty"`
+
+	// AdditionalNodeGroups are the worker node groups to create next to the
+	// default worker nodes when a cluster is created, and the existing node
+	// groups to resize when a cluster is resized.
+	AdditionalNodeGroups KopsInstanceGroupsMetadata `json:"AdditionalNodeGroups,omitempty"`
 }
 
 // RotatorMetadata is the metadata for the Rotator tool
@@ -85,26 +105,131 @@ func (km *KopsMetadata) ValidateChangeRequest() error {
 		len(km.ChangeRequest.NodeInstanceType) == 0 &&
 		km.MasterCount == 0 &&
 		km.NodeMinCount == 0 &&
-		km.NodeMaxCount == 0 {
+		km.NodeMaxCount == 0 &&
+		km.ChangeRequest.NodeDesiredCount == 0 &&
+		len(km.ChangeRequest.AdditionalNodeGroups) == 0 {
 		return errors.New("the KopsMetadata ChangeRequest has no change values set")
 	}
 
 	return nil
 }
 
+// HasWorkerNodesChanges returns true if the ChangeRequest changes the default
+// worker nodes of the cluster.
+func (km *KopsMetadata) HasWorkerNodesChanges() bool {
+	return len(km.ChangeRequest.NodeInstanceType) != 0 ||
+		km.ChangeRequest.NodeMinCount != 0 ||
+		km.ChangeRequest.NodeMaxCount != 0 ||
+		km.ChangeRequest.NodeDesiredCount != 0
+}
+
+// GetWorkerNodeCount returns the number of default worker nodes the cluster
+// is running. NodeMinCount and NodeMaxCount are the bounds the cluster
+// autoscaler keeps the worker node count within, and NodeDesiredCount is the
+// count it set.
+func (km *KopsMetadata) GetWorkerNodeCount() int64 {
+	if km.NodeDesiredCount > 0 {
+		return km.NodeDesiredCount
+	}
+
+	return km.NodeMinCount
+}
+
+// getWorkerNodeResizeTarget returns the number of default worker nodes the
+// cluster runs once the ChangeRequest is applied. A new NodeMinCount resets
+// the worker node count set by the cluster autoscaler.
+func (km *KopsMetadata) getWorkerNodeResizeTarget() int64 {
+	if km.ChangeRequest.NodeDesiredCount > 0 {
+		return km.ChangeRequest.NodeDesiredCount
+	}
+	if km.ChangeRequest.NodeMinCount > 0 {
+		return km.ChangeRequest.NodeMinCount
+	}
+
+	return km.GetWorkerNodeCount()
+}
+
+// ApplyWorkerNodeCountChangeRequest records the worker node counts of the
+// ChangeRequest once the cluster was resized.
+func (km *KopsMetadata) ApplyWorkerNodeCountChangeRequest() {
+	if km.ChangeRequest == nil {
+		return
+	}
+
+	if km.ChangeRequest.NodeMinCount > 0 {
+		km.NodeMinCount = km.ChangeRequest.NodeMinCount
+		km.NodeDesiredCount = 0
+	}
+	if km.ChangeRequest.NodeMaxCount > 0 {
+		km.NodeMaxCount = km.ChangeRequest.NodeMaxCount
+	}
+	if km.ChangeRequest.NodeDesiredCount > 0 {
+		km.NodeDesiredCount = km.ChangeRequest.NodeDesiredCount
+	}
+	if km.NodeDesiredCount == km.NodeMinCount {
+		km.NodeDesiredCount = 0
+	}
+}
+
+// GetNodeGroup returns the additional worker node group with the given name
+// and whether it exists.
+func (km *KopsMetadata) GetNodeGroup(name string) (KopsInstanceGroupMetadata, bool) {
+	nodeGroup, ok := km.CustomInstanceGroups[name]
+	return nodeGroup.copy(), ok
+}
+
 // GetWorkerNodesResizeChanges calculates instance group resizing based on the
-// current ChangeRequest.
+// current ChangeRequest. If the ChangeRequest also sets a NodeMaxCount, or the
+// worker node count is set by the cluster autoscaler, the additional capacity
+// is spread across the instance groups so that the total max node count of
+// the cluster is preserved.
 func (km *KopsMetadata) GetWorkerNodesResizeChanges() KopsInstanceGroupsMetadata {
-	difference := km.ChangeRequest.NodeMinCount - km.NodeMinCount
+	difference := km.getWorkerNodeResizeTarget() - km.GetWorkerNodeCount()
 
+	changes := km.NodeInstanceGroups
 	if difference < 0 {
-		return km.getDecreasedWorkerNodesResizeChanges(difference)
+		changes = km.getDecreasedWorkerNodesResizeChanges(difference)
 	}
 	if difference > 0 {
-		return km.getIncreasedWorkerNodesResizeChanges(difference)
+		changes = km.getIncreasedWorkerNodesResizeChanges(difference)
+	}
+
+	if km.ChangeRequest.NodeMaxCount > 0 {
+		changes = changes.withDistributedNodeMaxCount(km.ChangeRequest.NodeMaxCount)
+	} else if km.ChangeRequest.NodeDesiredCount > 0 || km.NodeDesiredCount > 0 {
+		changes = changes.withDistributedNodeMaxCount(km.NodeMaxCount)
+	}
+
+	return changes
+}
+
+// withDistributedNodeMaxCount returns a copy of the instance groups with the
+// max node counts set so that they sum up to the given total. Each instance
+// group max is never lower than its min.
+func (igm KopsInstanceGroupsMetadata) withDistributedNodeMaxCount(total int64) KopsInstanceGroupsMetadata {
+	changes := KopsInstanceGroupsMetadata{}
+	spare := total
+	for key, ig := range igm {
+		ig.NodeMaxCount = ig.NodeMinCount
+		changes[key] = ig
+		spare -= ig.NodeMinCount
+	}
+
+	orderedKeys := changes.getStableIterationOrder()
+	for spare > 0 && len(orderedKeys) > 0 {
+		for _, key := range orderedKeys {
+			ig := changes[key]
+			ig.NodeMaxCount++
+			changes[key] = ig
+
+			spare--
+			if spare == 0 {
+				break
+			}
+		}
 	}
 
-	return km.NodeInstanceGroups
+	return changes
 }
 
 func (km *KopsMetadata) getIncreasedWorkerNodesResizeChanges(count int64) KopsInstanceGroupsMetadata {
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -365,6 +366,160 @@ func TestGetWorkerNodesResizeChanges(t *testing.T) {
 				},
 			},
 		},
+		{
+			"add one, preserve max, two node groups",
+			model.KopsMetadata{
+				NodeMinCount: 2,
+				NodeMaxCount: 6,
+				NodeInstanceGroups: model.KopsInstanceGroupsMetadata{
+					"node-ig1": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 1,
+						NodeMaxCount: 3,
+					},
+					"node-ig2": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 1,
+						NodeMaxCount: 3,
+					},
+				},
+				ChangeRequest: &model.KopsMetadataRequestedState{
+					NodeMinCount: 3,
+					NodeMaxCount: 6,
+				},
+			},
+			model.KopsInstanceGroupsMetadata{
+				"node-ig1": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 2,
+					NodeMaxCount: 4,
+				},
+				"node-ig2": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 1,
+					NodeMaxCount: 2,
+				},
+			},
+		},
+		{
+			"remove one, preserve max, two node groups",
+			model.KopsMetadata{
+				NodeMinCount: 4,
+				NodeMaxCount: 6,
+				NodeInstanceGroups: model.KopsInstanceGroupsMetadata{
+					"node-ig1": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 2,
+						NodeMaxCount: 3,
+					},
+					"node-ig2": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 2,
+						NodeMaxCount: 3,
+					},
+				},
+				ChangeRequest: &model.KopsMetadataRequestedState{
+					NodeMinCount: 3,
+					NodeMaxCount: 6,
+				},
+			},
+			model.KopsInstanceGroupsMetadata{
+				"node-ig1": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 2,
+					NodeMaxCount: 4,
+				},
+				"node-ig2": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 1,
+					NodeMaxCount: 2,
+				},
+			},
+		},
+		{
+			"autoscale up, keep max, two node groups",
+			model.KopsMetadata{
+				NodeMinCount: 2,
+				NodeMaxCount: 6,
+				NodeInstanceGroups: model.KopsInstanceGroupsMetadata{
+					"node-ig1": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 1,
+						NodeMaxCount: 3,
+					},
+					"node-ig2": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 1,
+						NodeMaxCount: 3,
+					},
+				},
+				ChangeRequest: &model.KopsMetadataRequestedState{
+					NodeDesiredCount: 3,
+				},
+			},
+			model.KopsInstanceGroupsMetadata{
+				"node-ig1": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 2,
+					NodeMaxCount: 4,
+				},
+				"node-ig2": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 1,
+					NodeMaxCount: 2,
+				},
+			},
+		},
+		{
+			"autoscale down, keep max, two node groups",
+			model.KopsMetadata{
+				NodeMinCount:     2,
+				NodeMaxCount:     6,
+				NodeDesiredCount: 4,
+				NodeInstanceGroups: model.KopsInstanceGroupsMetadata{
+					"node-ig1": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 2,
+						NodeMaxCount: 3,
+					},
+					"node-ig2": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 2,
+						NodeMaxCount: 3,
+					},
+				},
+				ChangeRequest: &model.KopsMetadataRequestedState{
+					NodeDesiredCount: 3,
+				},
+			},
+			model.KopsInstanceGroupsMetadata{
+				"node-ig1": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 2,
+					NodeMaxCount: 4,
+				},
+				"node-ig2": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 1,
+					NodeMaxCount: 2,
+				},
+			},
+		},
+		{
+			"new min count resets autoscaled count",
+			model.KopsMetadata{
+				NodeMinCount:     2,
+				NodeMaxCount:     6,
+				NodeDesiredCount: 4,
+				NodeInstanceGroups: model.KopsInstanceGroupsMetadata{
+					"node-ig1": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 2,
+						NodeMaxCount: 3,
+					},
+					"node-ig2": model.KopsInstanceGroupMetadata{
+						NodeMinCount: 2,
+						NodeMaxCount: 3,
+					},
+				},
+				ChangeRequest: &model.KopsMetadataRequestedState{
+					NodeMinCount: 3,
+				},
+			},
+			model.KopsInstanceGroupsMetadata{
+				"node-ig1": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 2,
+					NodeMaxCount: 4,
+				},
+				"node-ig2": model.KopsInstanceGroupMetadata{
+					NodeMinCount: 1,
+					NodeMaxCount: 2,
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -373,3 +528,59 @@ func TestGetWorkerNodesResizeChanges(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyWorkerNodeCountChangeRequest(t *testing.T) {
+	var testCases = []struct {
+		testName          string
+		metadata          model.KopsMetadata
+		expectedMin       int64
+		expectedMax       int64
+		expectedDesired   int64
+		expectedNodeCount int64
+	}{
+		{
+			"no change request",
+			model.KopsMetadata{NodeMinCount: 2, NodeMaxCount: 6, NodeDesiredCount: 4},
+			2, 6, 4, 4,
+		},
+		{
+			"autoscaled",
+			model.KopsMetadata{
+				NodeMinCount:  2,
+				NodeMaxCount:  6,
+				ChangeRequest: &model.KopsMetadataRequestedState{NodeDesiredCount: 5},
+			},
+			2, 6, 5, 5,
+		},
+		{
+			"autoscaled to min count",
+			model.KopsMetadata{
+				NodeMinCount:     2,
+				NodeMaxCount:     6,
+				NodeDesiredCount: 4,
+				ChangeRequest:    &model.KopsMetadataRequestedState{NodeDesiredCount: 2},
+			},
+			2, 6, 0, 2,
+		},
+		{
+			"resized",
+			model.KopsMetadata{
+				NodeMinCount:     2,
+				NodeMaxCount:     6,
+				NodeDesiredCount: 4,
+				ChangeRequest:    &model.KopsMetadataRequestedState{NodeMinCount: 3, NodeMaxCount: 8},
+			},
+			3, 8, 0, 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.testName, func(t *testing.T) {
+			tc.metadata.ApplyWorkerNodeCountChangeRequest()
+			assert.Equal(t, tc.expectedMin, tc.metadata.NodeMinCount)
+			assert.Equal(t, tc.expectedMax, tc.metadata.NodeMaxCount)
+			assert.Equal(t, tc.expectedDesired, tc.metadata.NodeDesiredCount)
+			assert.Equal(t, tc.expectedNodeCount, tc.metadata.GetWorkerNodeCount())
+		})
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// KopsInstanceGroupNodeLabel is the k8s node label set by kops to the name of
+// the instance group of the node.
+const KopsInstanceGroupNodeLabel = "kops.k8s.io/instancegroup"
+
+// SpotWorkerNodeLabel is the k8s node label of worker nodes of instance groups
+// using spot instances. The spot termination handler runs on these nodes.
+const SpotWorkerNodeLabel = "node-role.kubernetes.io/spot-worker"
+
+const (
+	// SpotAllocationStrategyLowestPrice launches spot instances from the
+	// pools with the lowest price.
+	SpotAllocationStrategyLowestPrice = "lowest-price"
+	// SpotAllocationStrategyCapacityOptimized launches spot instances from the
+	// pools with the most available capacity, lowering the interruption rate.
+	SpotAllocationStrategyCapacityOptimized = "capacity-optimized"
+)
+
+// KopsMixedInstancesPolicy describes how the instances of a worker instance
+// group are split between instance types and on-demand and spot instances.
+type KopsMixedInstancesPolicy struct {
+	// Instances are the instance types which can be launched in addition to
+	// the instance type of the instance group.
+	Instances []string `json:"Instances,omitempty"`
+	// OnDemandBase is the number of instances which are always on-demand
+	// instances.
+	OnDemandBase *int64 `json:"OnDemandBase,omitempty"`
+	// OnDemandAboveBase is the percentage of on-demand instances above the
+	// base. The remaining instances are spot instances. Defaults to 100.
+	OnDemandAboveBase *int64 `json:"OnDemandAboveBase,omitempty"`
+	// SpotAllocationStrategy is the strategy used to pick spot instance pools.
+	SpotAllocationStrategy string `json:"SpotAllocationStrategy,omitempty"`
+}
+
+// UsesSpotInstances returns true if the policy launches spot instances.
+func (p *KopsMixedInstancesPolicy) UsesSpotInstances() bool {
+	return p != nil && p.OnDemandAboveBase != nil && *p.OnDemandAboveBase < 100
+}
+
+// Validate validates the mixed instances policy.
+func (p *KopsMixedInstancesPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, instanceType := range p.Instances {
+		if !ValidInstanceType(instanceType) {
+			return errors.Errorf("invalid mixed instances policy instance type %q", instanceType)
+		}
+	}
+	if p.OnDemandBase != nil && *p.OnDemandBase < 0 {
+		return errors.Errorf("on-demand base (%d) cannot be negative", *p.OnDemandBase)
+	}
+	if p.OnDemandAboveBase != nil && (*p.OnDemandAboveBase < 0 || *p.OnDemandAboveBase > 100) {
+		return errors.Errorf("on-demand above base (%d) must be a percentage between 0 and 100", *p.OnDemandAboveBase)
+	}
+	switch p.SpotAllocationStrategy {
+	case "", SpotAllocationStrategyLowestPrice, SpotAllocationStrategyCapacityOptimized:
+	default:
+		return errors.Errorf("invalid spot allocation strategy %q", p.SpotAllocationStrategy)
+	}
+
+	return nil
+}
+
+func (p *KopsMixedInstancesPolicy) copy() *KopsMixedInstancesPolicy {
+	if p == nil {
+		return nil
+	}
+	policy := *p
+	if p.Instances != nil {
+		policy.Instances = append([]string{}, p.Instances...)
+	}
+	if p.OnDemandBase != nil {
+		onDemandBase := *p.OnDemandBase
+		policy.OnDemandBase = &onDemandBase
+	}
+	if p.OnDemandAboveBase != nil {
+		onDemandAboveBase := *p.OnDemandAboveBase
+		policy.OnDemandAboveBase = &onDemandAboveBase
+	}
+
+	return &policy
+}
+
+const (
+	// NodeGroupTaintEffectNoSchedule prevents new pods without a matching
+	// toleration from being scheduled on the node.
+	NodeGroupTaintEffectNoSchedule = "NoSchedule"
+	// NodeGroupTaintEffectPreferNoSchedule avoids scheduling new pods without
+	// a matching toleration on the node.
+	NodeGroupTaintEffectPreferNoSchedule = "PreferNoSchedule"
+	// NodeGroupTaintEffectNoExecute evicts running pods without a matching
+	// toleration from the node.
+	NodeGroupTaintEffectNoExecute = "NoExecute"
+)
+
+// NodeGroupTaint is a parsed k8s taint of the nodes of a node group.
+type NodeGroupTaint struct {
+	Key    string
+	Value  string
+	Effect string
+}
+
+// ParseNodeGroupTaint parses a taint in the kops format key=value:effect. The
+// value is optional.
+func ParseNodeGroupTaint(taint string) (*NodeGroupTaint, error) {
+	keyValue, effect := taint, ""
+	if i := strings.LastIndex(taint, ":"); i != -1 {
+		keyValue, effect = taint[:i], taint[i+1:]
+	}
+	switch effect {
+	case NodeGroupTaintEffectNoSchedule, NodeGroupTaintEffectPreferNoSchedule, NodeGroupTaintEffectNoExecute:
+	default:
+		return nil, errors.Errorf("taint %q has invalid effect %q", taint, effect)
+	}
+
+	key, value := keyValue, ""
+	if i := strings.Index(keyValue, "="); i != -1 {
+		key, value = keyValue[:i], keyValue[i+1:]
+	}
+	if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+		return nil, errors.Errorf("taint %q has invalid key: %s", taint, strings.Join(errs, ", "))
+	}
+	if errs := validation.IsValidLabelValue(value); len(errs) != 0 {
+		return nil, errors.Errorf("taint %q has invalid value: %s", taint, strings.Join(errs, ", "))
+	}
+
+	return &NodeGroupTaint{Key: key, Value: value, Effect: effect}, nil
+}
+
+// validateScheduling validates the labels, taints, max price and mixed
+// instances policy of a worker node group.
+func (ig KopsInstanceGroupMetadata) validateScheduling() error {
+	for key, value := range ig.Labels {
+		if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+			return errors.Errorf("invalid label key %q: %s", key, strings.Join(errs, ", "))
+		}
+		if key == KopsInstanceGroupNodeLabel || key == SpotWorkerNodeLabel {
+			return errors.Errorf("label %s is managed by the provisioner", key)
+		}
+		if errs := validation.IsValidLabelValue(value); len(errs) != 0 {
+			return errors.Errorf("invalid value of label %s: %s", key, strings.Join(errs, ", "))
+		}
+	}
+	for _, taint := range ig.Taints {
+		_, err := ParseNodeGroupTaint(taint)
+		if err != nil {
+			return err
+		}
+	}
+	if len(ig.MaxPrice) != 0 {
+		maxPrice, err := strconv.ParseFloat(ig.MaxPrice, 64)
+		if err != nil || maxPrice <= 0 {
+			return errors.Errorf("max price %q must be a positive number", ig.MaxPrice)
+		}
+	}
+
+	return ig.MixedInstancesPolicy.Validate()
+}
+
+// IsSpot returns true if the instance group uses spot instances, either
+// exclusively or as part of a mixed instances policy.
+func (ig KopsInstanceGroupMetadata) IsSpot() bool {
+	if ig.MixedInstancesPolicy != nil {
+		return ig.MixedInstancesPolicy.UsesSpotInstances()
+	}
+
+	return len(ig.MaxPrice) != 0
+}
+
+// HasSpotNodeGroups returns true if any of the instance groups uses spot
+// instances.
+func (igm KopsInstanceGroupsMetadata) HasSpotNodeGroups() bool {
+	for _, ig := range igm {
+		if ig.IsSpot() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copy returns a deep copy of the instance group metadata.
+func (ig KopsInstanceGroupMetadata) copy() KopsInstanceGroupMetadata {
+	if ig.Labels != nil {
+		labels := make(map[string]string, len(ig.Labels))
+		for key, value := range ig.Labels {
+			labels[key] = value
+		}
+		ig.Labels = labels
+	}
+	if ig.Taints != nil {
+		ig.Taints = append([]string{}, ig.Taints...)
+	}
+	ig.MixedInstancesPolicy = ig.MixedInstancesPolicy.copy()
+
+	return ig
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model_test
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost-cloud/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNodeGroupTaint(t *testing.T) {
+	for _, tc := range []struct {
+		taint    string
+		expected *model.NodeGroupTaint
+	}{
+		{"dedicated=memory:NoSchedule", &model.NodeGroupTaint{Key: "dedicated", Value: "memory", Effect: "NoSchedule"}},
+		{"example.com/spot:PreferNoSchedule", &model.NodeGroupTaint{Key: "example.com/spot", Effect: "PreferNoSchedule"}},
+		{"lifecycle=spot:NoExecute", &model.NodeGroupTaint{Key: "lifecycle", Value: "spot", Effect: "NoExecute"}},
+		{"dedicated=memory", nil},
+		{"dedicated=memory:Never", nil},
+		{"=memory:NoSchedule", nil},
+		{"dedicated=in memory:NoSchedule", nil},
+	} {
+		t.Run(tc.taint, func(t *testing.T) {
+			taint, err := model.ParseNodeGroupTaint(tc.taint)
+			if tc.expected == nil {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, taint)
+		})
+	}
+}
+
+func TestKopsMixedInstancesPolicy(t *testing.T) {
+	t.Run("validate", func(t *testing.T) {
+		for _, tc := range []struct {
+			description string
+			policy      *model.KopsMixedInstancesPolicy
+			valid       bool
+		}{
+			{"nil", nil, true},
+			{"valid", &model.KopsMixedInstancesPolicy{Instances: []string{"m5.large", "m5a.large"}, OnDemandBase: i64oP(1), OnDemandAboveBase: i64oP(20), SpotAllocationStrategy: model.SpotAllocationStrategyCapacityOptimized}, true},
+			{"invalid instance type", &model.KopsMixedInstancesPolicy{Instances: []string{"m5 large"}}, false},
+			{"negative on-demand base", &model.KopsMixedInstancesPolicy{OnDemandBase: i64oP(-1)}, false},
+			{"on-demand above base over 100", &model.KopsMixedInstancesPolicy{OnDemandAboveBase: i64oP(101)}, false},
+			{"invalid spot allocation strategy", &model.KopsMixedInstancesPolicy{SpotAllocationStrategy: "cheapest"}, false},
+		} {
+			t.Run(tc.description, func(t *testing.T) {
+				err := tc.policy.Validate()
+				if tc.valid {
+					assert.NoError(t, err)
+				} else {
+					assert.Error(t, err)
+				}
+			})
+		}
+	})
+
+	t.Run("is spot", func(t *testing.T) {
+		assert.False(t, model.KopsInstanceGroupMetadata{}.IsSpot())
+		assert.True(t, model.KopsInstanceGroupMetadata{MaxPrice: "0.05"}.IsSpot())
+		assert.False(t, model.KopsInstanceGroupMetadata{MixedInstancesPolicy: &model.KopsMixedInstancesPolicy{OnDemandAboveBase: i64oP(100)}}.IsSpot())
+		assert.True(t, model.KopsInstanceGroupMetadata{MixedInstancesPolicy: &model.KopsMixedInstancesPolicy{OnDemandAboveBase: i64oP(0)}}.IsSpot())
+
+		assert.True(t, model.KopsInstanceGroupsMetadata{
+			"nodes":      {},
+			"nodes-spot": {MaxPrice: "0.05"},
+		}.HasSpotNodeGroups())
+		assert.False(t, model.KopsInstanceGroupsMetadata{"nodes": {}}.HasSpotNodeGroups())
+	})
+}
+
+func TestInstanceIDFromProviderID(t *testing.T) {
+	assert.Equal(t, "i-0123456789abcdef0", model.InstanceIDFromProviderID("aws:///us-east-1a/i-0123456789abcdef0"))
+	assert.Equal(t, "", model.InstanceIDFromProviderID(""))
+}
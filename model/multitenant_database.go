@@ -99,7 +99,9 @@ type MultitenantDatabase struct {
 	ReaderEndpoint                     string
 	Installations                      MultitenantDatabaseInstallations
 	MigratedInstallations              MultitenantDatabaseInstallations
-	MaxInstallationsPerLogicalDatabase int64 `json:"MaxInstallationsPerLogicalDatabase,omitempty"`
+	MaxInstallationsPerLogicalDatabase int64  `json:"MaxInstallationsPerLogicalDatabase,omitempty"`
+	TargetEngineVersion                string `json:"TargetEngineVersion,omitempty"`
+	UpgradeSnapshotID                  string `json:"UpgradeSnapshotID,omitempty"`
 	CreateAt                           int64
 	DeleteAt                           int64
 	LockAcquiredBy                     *string
@@ -147,6 +149,19 @@ func (d *MultitenantDatabase) GetReaderEndpoint() string {
 	return d.WriterEndpoint
 }
 
+// IsUpgrading returns true if the engine version of the multitenant database
+// is being upgraded or its last upgrade failed. Such databases don't accept
+// new installations.
+func (d *MultitenantDatabase) IsUpgrading() bool {
+	return contains(AllMultitenantDatabaseUpgradeStates, d.State)
+}
+
+// ValidUpgradeState returns true if an engine version upgrade can be
+// requested for the multitenant database.
+func (d *MultitenantDatabase) ValidUpgradeState() bool {
+	return d.State == DatabaseStateStable || d.State == DatabaseStateUpgradeFailed
+}
+
 // MultitenantDatabaseInstallations is the list of installation IDs that belong
 // to a given MultitenantDatabase.
 type MultitenantDatabaseInstallations []string
@@ -190,6 +205,7 @@ type MultitenantDatabaseFilter struct {
 	MigratedInstallationID string
 	VpcID                  string
 	DatabaseType           string
+	States                 []string
 	MaxInstallationsLimit  int
 }
 
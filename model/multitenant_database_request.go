@@ -76,6 +76,38 @@ func NewPatchMultitenantDatabaseRequestFromReader(reader io.Reader) (*PatchMulti
 	return &patchMultitenantDatabaseRequest, nil
 }
 
+// UpgradeMultitenantDatabaseRequest specifies the parameters for an engine
+// version upgrade of a multitenant database.
+type UpgradeMultitenantDatabaseRequest struct {
+	EngineVersion string
+}
+
+// Validate validates the values of a multitenant database upgrade request.
+func (request *UpgradeMultitenantDatabaseRequest) Validate() error {
+	if len(request.EngineVersion) == 0 {
+		return errors.New("EngineVersion must not be empty")
+	}
+
+	return nil
+}
+
+// NewUpgradeMultitenantDatabaseRequestFromReader will create an
+// UpgradeMultitenantDatabaseRequest from an io.Reader with JSON data.
+func NewUpgradeMultitenantDatabaseRequestFromReader(reader io.Reader) (*UpgradeMultitenantDatabaseRequest, error) {
+	var upgradeMultitenantDatabaseRequest UpgradeMultitenantDatabaseRequest
+	err := json.NewDecoder(reader).Decode(&upgradeMultitenantDatabaseRequest)
+	if err != nil && err != io.EOF {
+		return nil, errors.Wrap(err, "failed to decode upgrade multitenant database request")
+	}
+
+	err = upgradeMultitenantDatabaseRequest.Validate()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid upgrade multitenant database request")
+	}
+
+	return &upgradeMultitenantDatabaseRequest, nil
+}
+
 // GetLogicalDatabasesRequest describes the parameters to request a list of
 // logical databases.
 type GetLogicalDatabasesRequest struct {
@@ -9,4 +9,37 @@ const (
 	DatabaseStateStable = "stable"
 	// DatabaseStateProvisioningRequested is an database that requires provisioning.
 	DatabaseStateProvisioningRequested = "provisioning-requested"
+	// DatabaseStateCreationRequested is an database whose infrastructure is
+	// being created.
+	DatabaseStateCreationRequested = "creation-requested"
+	// DatabaseStateUpgradeRequested is a database whose engine version should
+	// be upgraded.
+	DatabaseStateUpgradeRequested = "upgrade-requested"
+	// DatabaseStateUpgradeSnapshotInProgress is a database whose snapshot is
+	// being taken before its engine version is upgraded.
+	DatabaseStateUpgradeSnapshotInProgress = "upgrade-snapshot-in-progress"
+	// DatabaseStateUpgradeInProgress is a database whose engine version is
+	// being upgraded.
+	DatabaseStateUpgradeInProgress = "upgrade-in-progress"
+	// DatabaseStateUpgradeFailed is a database whose engine version upgrade
+	// failed.
+	DatabaseStateUpgradeFailed = "upgrade-failed"
 )
+
+// AllMultitenantDatabaseUpgradeStatesPendingWork is a list of all multitenant
+// database states that the upgrade supervisor will attempt to transition
+// towards stable on the next "tick".
+var AllMultitenantDatabaseUpgradeStatesPendingWork = []string{
+	DatabaseStateUpgradeRequested,
+	DatabaseStateUpgradeSnapshotInProgress,
+	DatabaseStateUpgradeInProgress,
+}
+
+// AllMultitenantDatabaseUpgradeStates is a list of all states a multitenant
+// database can be in while its engine version is upgraded.
+var AllMultitenantDatabaseUpgradeStates = []string{
+	DatabaseStateUpgradeRequested,
+	DatabaseStateUpgradeSnapshotInProgress,
+	DatabaseStateUpgradeInProgress,
+	DatabaseStateUpgradeFailed,
+}
@@ -5,10 +5,12 @@
 package model
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMultitenantDatabaseInstallationsCountContainsAndAdd(t *testing.T) {
@@ -85,3 +87,47 @@ func TestMultitenantDatabaseInstallationsRemove(t *testing.T) {
 		})
 	}
 }
+
+func TestMultitenantDatabaseUpgradeStates(t *testing.T) {
+	var testCases = []struct {
+		state              string
+		expectedUpgrading  bool
+		expectedCanUpgrade bool
+	}{
+		{DatabaseStateStable, false, true},
+		{DatabaseStateProvisioningRequested, false, false},
+		{DatabaseStateCreationRequested, false, false},
+		{DatabaseStateUpgradeRequested, true, false},
+		{DatabaseStateUpgradeSnapshotInProgress, true, false},
+		{DatabaseStateUpgradeInProgress, true, false},
+		{DatabaseStateUpgradeFailed, true, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.state, func(t *testing.T) {
+			database := &MultitenantDatabase{State: tc.state}
+			assert.Equal(t, tc.expectedUpgrading, database.IsUpgrading())
+			assert.Equal(t, tc.expectedCanUpgrade, database.ValidUpgradeState())
+		})
+	}
+}
+
+func TestNewUpgradeMultitenantDatabaseRequestFromReader(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		request, err := NewUpgradeMultitenantDatabaseRequestFromReader(bytes.NewReader([]byte(`{"EngineVersion":"13.7"}`)))
+		require.NoError(t, err)
+		assert.Equal(t, &UpgradeMultitenantDatabaseRequest{EngineVersion: "13.7"}, request)
+	})
+
+	t.Run("empty engine version", func(t *testing.T) {
+		request, err := NewUpgradeMultitenantDatabaseRequestFromReader(bytes.NewReader([]byte(`{}`)))
+		require.Error(t, err)
+		assert.Nil(t, request)
+	})
+
+	t.Run("invalid json", func(t *testing.T) {
+		request, err := NewUpgradeMultitenantDatabaseRequestFromReader(bytes.NewReader([]byte(`{test`)))
+		require.Error(t, err)
+		assert.Nil(t, request)
+	})
+}
@@ -0,0 +1,15 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+// SupervisorTask tracks a periodic task which must be run by at most one
+// provisioning server at a time.
+type SupervisorTask struct {
+	ID string
+	// LastRunAt is the time the task was last started.
+	LastRunAt      int64
+	LockAcquiredBy *string
+	LockAcquiredAt int64
+}
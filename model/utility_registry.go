@@ -0,0 +1,273 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"io"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// UtilityReadinessKindDeployment checks that a Deployment has all of its
+	// replicas ready.
+	UtilityReadinessKindDeployment = "Deployment"
+	// UtilityReadinessKindDaemonSet checks that a DaemonSet has a ready pod
+	// on every scheduled node.
+	UtilityReadinessKindDaemonSet = "DaemonSet"
+	// UtilityReadinessKindStatefulSet checks that a StatefulSet has all of
+	// its replicas ready.
+	UtilityReadinessKindStatefulSet = "StatefulSet"
+
+	// DefaultUtilityReadinessTimeoutSeconds is the readiness check timeout
+	// used when none is configured.
+	DefaultUtilityReadinessTimeoutSeconds = 300
+)
+
+var utilityNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// UtilityDefinition declares a Helm based cluster utility which is installed
+// by the utility group without utility specific code.
+type UtilityDefinition struct {
+	// Name is the canonical name of the utility.
+	Name string `json:"name"`
+	// Repository is the Helm repository hosting the chart.
+	Repository UtilityRepository `json:"repository"`
+	// Chart is the chart reference, for example "jetstack/cert-manager".
+	Chart string `json:"chart"`
+	// ReleaseName is the name of the Helm release. Defaults to the name.
+	ReleaseName string `json:"releaseName,omitempty"`
+	// Namespace is the namespace the release is deployed in.
+	Namespace string `json:"namespace"`
+	// DefaultVersion is the version deployed on new clusters.
+	DefaultVersion HelmUtilityVersion `json:"defaultVersion"`
+	// ValuesTemplate is inline Helm values YAML applied over the values
+	// file of the deployed version. It is rendered as a Go template with
+	// the UtilityValuesContext of the cluster.
+	ValuesTemplate string `json:"valuesTemplate,omitempty"`
+	// DependsOn are the names of the utilities which must be deployed
+	// before this one.
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// Readiness is the check run after the release is deployed.
+	Readiness *UtilityReadinessCheck `json:"readiness,omitempty"`
+}
+
+// UtilityRepository is a Helm chart repository.
+type UtilityRepository struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// UtilityReadinessCheck is a workload which must be ready for a utility to
+// be considered deployed.
+type UtilityReadinessCheck struct {
+	Kind           string `json:"kind"`
+	Name           string `json:"name"`
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty"`
+}
+
+// Timeout returns the readiness check timeout in seconds.
+func (c *UtilityReadinessCheck) Timeout() int {
+	if c.TimeoutSeconds <= 0 {
+		return DefaultUtilityReadinessTimeoutSeconds
+	}
+
+	return c.TimeoutSeconds
+}
+
+// Release returns the name of the Helm release of the utility.
+func (d *UtilityDefinition) Release() string {
+	if len(d.ReleaseName) != 0 {
+		return d.ReleaseName
+	}
+
+	return d.Name
+}
+
+// Validate validates a utility definition.
+func (d *UtilityDefinition) Validate() error {
+	if !utilityNameRegex.MatchString(d.Name) {
+		return errors.Errorf("utility name %q must consist of lower case alphanumeric characters or '-'", d.Name)
+	}
+	if _, builtin := defaultUtilityValuesFileNames[d.Name]; builtin {
+		return errors.Errorf("utility name %q is reserved for a builtin utility", d.Name)
+	}
+	if len(d.Repository.Name) == 0 || len(d.Repository.URL) == 0 {
+		return errors.Errorf("utility %s must have a repository name and URL", d.Name)
+	}
+	if len(d.Chart) == 0 {
+		return errors.Errorf("utility %s must have a chart", d.Name)
+	}
+	if len(d.Namespace) == 0 {
+		return errors.Errorf("utility %s must have a namespace", d.Name)
+	}
+	if len(d.DefaultVersion.Chart) == 0 {
+		return errors.Errorf("utility %s must have a default chart version", d.Name)
+	}
+	err := ValidateUtilityValuesPath(d.DefaultVersion.ValuesPath)
+	if err != nil {
+		return errors.Wrapf(err, "utility %s default values path is invalid", d.Name)
+	}
+	if d.ValuesTemplate != "" {
+		rendered, err := RenderUtilityValues(d.ValuesTemplate, sampleUtilityValuesContext)
+		if err != nil {
+			return errors.Wrapf(err, "utility %s values template is invalid", d.Name)
+		}
+		var values map[string]interface{}
+		err = yaml.Unmarshal([]byte(rendered), &values)
+		if err != nil {
+			return errors.Wrapf(err, "utility %s values template is not valid YAML", d.Name)
+		}
+	}
+	if d.Readiness != nil {
+		switch d.Readiness.Kind {
+		case UtilityReadinessKindDeployment, UtilityReadinessKindDaemonSet, UtilityReadinessKindStatefulSet:
+		default:
+			return errors.Errorf("utility %s readiness check kind %q is not supported", d.Name, d.Readiness.Kind)
+		}
+		if len(d.Readiness.Name) == 0 {
+			return errors.Errorf("utility %s readiness check must have a name", d.Name)
+		}
+	}
+
+	return nil
+}
+
+// UtilityRegistryConfig is the configuration file format of the utility
+// registry.
+type UtilityRegistryConfig struct {
+	Utilities []*UtilityDefinition `json:"utilities"`
+}
+
+// NewUtilityRegistryConfigFromReader parses a YAML or JSON utility registry
+// configuration.
+func NewUtilityRegistryConfigFromReader(reader io.Reader) (*UtilityRegistryConfig, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read utility registry config")
+	}
+
+	var config UtilityRegistryConfig
+	err = yaml.UnmarshalStrict(data, &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decode utility registry config")
+	}
+
+	return &config, nil
+}
+
+// registeredUtilities are the registered utility definitions in deployment
+// order.
+var registeredUtilities []*UtilityDefinition
+
+// SetUtilityRegistry validates the given utility definitions and replaces
+// the registered utilities with them. The default versions of the utilities
+// are deployed on new clusters.
+func SetUtilityRegistry(definitions []*UtilityDefinition) error {
+	ordered, err := orderUtilityDefinitions(definitions)
+	if err != nil {
+		return err
+	}
+
+	for _, definition := range registeredUtilities {
+		delete(DefaultUtilityVersions, definition.Name)
+	}
+	for _, definition := range ordered {
+		defaultVersion := definition.DefaultVersion
+		DefaultUtilityVersions[definition.Name] = &defaultVersion
+	}
+	registeredUtilities = ordered
+
+	return nil
+}
+
+// RegisteredUtilities returns the registered utility definitions, ordered so
+// that every utility comes after its dependencies.
+func RegisteredUtilities() []*UtilityDefinition {
+	return registeredUtilities
+}
+
+// GetRegisteredUtility returns the registered utility definition with the
+// given name, or nil if there is none.
+func GetRegisteredUtility(name string) *UtilityDefinition {
+	for _, definition := range registeredUtilities {
+		if definition.Name == name {
+			return definition
+		}
+	}
+
+	return nil
+}
+
+// orderUtilityDefinitions validates the definitions and sorts them so that
+// every utility comes after its dependencies. Builtin utilities are always
+// deployed first, so they are valid dependencies too.
+func orderUtilityDefinitions(definitions []*UtilityDefinition) ([]*UtilityDefinition, error) {
+	byName := make(map[string]*UtilityDefinition, len(definitions))
+	for _, definition := range definitions {
+		err := definition.Validate()
+		if err != nil {
+			return nil, err
+		}
+		if _, found := byName[definition.Name]; found {
+			return nil, errors.Errorf("utility %s is defined more than once", definition.Name)
+		}
+		byName[definition.Name] = definition
+	}
+
+	for _, definition := range definitions {
+		for _, dependency := range definition.DependsOn {
+			_, builtin := defaultUtilityValuesFileNames[dependency]
+			_, registered := byName[dependency]
+			if !builtin && !registered {
+				return nil, errors.Errorf("utility %s depends on unknown utility %s", definition.Name, dependency)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(definitions))
+	ordered := make([]*UtilityDefinition, 0, len(definitions))
+
+	var visit func(definition *UtilityDefinition) error
+	visit = func(definition *UtilityDefinition) error {
+		switch state[definition.Name] {
+		case visited:
+			return nil
+		case visiting:
+			return errors.Errorf("utility %s has a circular dependency", definition.Name)
+		}
+
+		state[definition.Name] = visiting
+		for _, dependency := range definition.DependsOn {
+			if dependencyDefinition, registered := byName[dependency]; registered {
+				err := visit(dependencyDefinition)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		state[definition.Name] = visited
+		ordered = append(ordered, definition)
+
+		return nil
+	}
+
+	for _, definition := range definitions {
+		err := visit(definition)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return ordered, nil
+}
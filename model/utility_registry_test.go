@@ -0,0 +1,188 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUtilityDefinition(name string, dependsOn ...string) *UtilityDefinition {
+	return &UtilityDefinition{
+		Name:           name,
+		Repository:     UtilityRepository{Name: "jetstack", URL: "https://charts.jetstack.io"},
+		Chart:          "jetstack/" + name,
+		Namespace:      name,
+		DefaultVersion: HelmUtilityVersion{Chart: "1.0.0"},
+		DependsOn:      dependsOn,
+	}
+}
+
+func TestUtilityDefinitionValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		modify      func(definition *UtilityDefinition)
+		expectError bool
+	}{
+		{"valid", func(definition *UtilityDefinition) {}, false},
+		{"invalid name", func(definition *UtilityDefinition) { definition.Name = "Cert_Manager" }, true},
+		{"builtin name", func(definition *UtilityDefinition) { definition.Name = NginxCanonicalName }, true},
+		{"no repository URL", func(definition *UtilityDefinition) { definition.Repository.URL = "" }, true},
+		{"no chart", func(definition *UtilityDefinition) { definition.Chart = "" }, true},
+		{"no namespace", func(definition *UtilityDefinition) { definition.Namespace = "" }, true},
+		{"no default version", func(definition *UtilityDefinition) { definition.DefaultVersion.Chart = "" }, true},
+		{"valid values template", func(definition *UtilityDefinition) { definition.ValuesTemplate = "installCRDs: true\n" }, false},
+		{"invalid values template", func(definition *UtilityDefinition) { definition.ValuesTemplate = "installCRDs: [" }, true},
+		{"values template with context", func(definition *UtilityDefinition) {
+			definition.ValuesTemplate = "clusterResourceNamespace: {{ .ClusterID }}\nextraArgs: [--cidr={{ .VPCCIDR }}]\n"
+		}, false},
+		{"values template with unknown field", func(definition *UtilityDefinition) { definition.ValuesTemplate = "name: {{ .Unknown }}\n" }, true},
+		{"valid readiness", func(definition *UtilityDefinition) {
+			definition.Readiness = &UtilityReadinessCheck{Kind: UtilityReadinessKindDeployment, Name: "cert-manager"}
+		}, false},
+		{"invalid readiness kind", func(definition *UtilityDefinition) {
+			definition.Readiness = &UtilityReadinessCheck{Kind: "Job", Name: "cert-manager"}
+		}, true},
+		{"readiness without name", func(definition *UtilityDefinition) {
+			definition.Readiness = &UtilityReadinessCheck{Kind: UtilityReadinessKindDaemonSet}
+		}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			definition := newTestUtilityDefinition("cert-manager")
+			tc.modify(definition)
+
+			err := definition.Validate()
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUtilityDefinitionDefaults(t *testing.T) {
+	definition := newTestUtilityDefinition("cert-manager")
+	assert.Equal(t, "cert-manager", definition.Release())
+	definition.ReleaseName = "cm"
+	assert.Equal(t, "cm", definition.Release())
+
+	check := &UtilityReadinessCheck{}
+	assert.Equal(t, DefaultUtilityReadinessTimeoutSeconds, check.Timeout())
+	check.TimeoutSeconds = 60
+	assert.Equal(t, 60, check.Timeout())
+}
+
+func TestOrderUtilityDefinitions(t *testing.T) {
+	t.Run("dependencies first", func(t *testing.T) {
+		ordered, err := orderUtilityDefinitions([]*UtilityDefinition{
+			newTestUtilityDefinition("external-dns", "cert-manager", NginxCanonicalName),
+			newTestUtilityDefinition("cert-manager"),
+			newTestUtilityDefinition("velero"),
+		})
+		require.NoError(t, err)
+		require.Len(t, ordered, 3)
+		assert.Equal(t, "cert-manager", ordered[0].Name)
+		assert.Equal(t, "external-dns", ordered[1].Name)
+		assert.Equal(t, "velero", ordered[2].Name)
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		_, err := orderUtilityDefinitions([]*UtilityDefinition{
+			newTestUtilityDefinition("cert-manager"),
+			newTestUtilityDefinition("cert-manager"),
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown dependency", func(t *testing.T) {
+		_, err := orderUtilityDefinitions([]*UtilityDefinition{
+			newTestUtilityDefinition("external-dns", "cert-manager"),
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("circular dependency", func(t *testing.T) {
+		_, err := orderUtilityDefinitions([]*UtilityDefinition{
+			newTestUtilityDefinition("a", "b"),
+			newTestUtilityDefinition("b", "c"),
+			newTestUtilityDefinition("c", "a"),
+		})
+		assert.Error(t, err)
+	})
+}
+
+func TestSetUtilityRegistry(t *testing.T) {
+	defer SetUtilityRegistry(nil)
+
+	err := SetUtilityRegistry([]*UtilityDefinition{newTestUtilityDefinition("cert-manager")})
+	require.NoError(t, err)
+	assert.NotNil(t, GetRegisteredUtility("cert-manager"))
+	assert.Equal(t, &HelmUtilityVersion{Chart: "1.0.0"}, DefaultUtilityVersions["cert-manager"])
+	assert.True(t, IsValidUtilityName("cert-manager"))
+
+	t.Run("invalid registry is not applied", func(t *testing.T) {
+		err = SetUtilityRegistry([]*UtilityDefinition{newTestUtilityDefinition("velero", "unknown")})
+		require.Error(t, err)
+		assert.NotNil(t, GetRegisteredUtility("cert-manager"))
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		err = SetUtilityRegistry([]*UtilityDefinition{newTestUtilityDefinition("velero")})
+		require.NoError(t, err)
+		assert.Nil(t, GetRegisteredUtility("cert-manager"))
+		assert.NotContains(t, DefaultUtilityVersions, "cert-manager")
+		assert.Contains(t, DefaultUtilityVersions, "velero")
+		assert.Len(t, RegisteredUtilities(), 1)
+	})
+
+	t.Run("desired versions", func(t *testing.T) {
+		versions := &UtilityGroupVersions{}
+		setUtilityVersion(versions, "velero", &HelmUtilityVersion{Chart: "2.0.0"})
+		setUtilityVersion(versions, "unregistered", &HelmUtilityVersion{Chart: "2.0.0"})
+		assert.Equal(t, &HelmUtilityVersion{Chart: "2.0.0"}, versions.AsMap()["velero"])
+		assert.NotContains(t, versions.AsMap(), "unregistered")
+
+		setUtilityVersion(versions, "velero", nil)
+		assert.NotContains(t, versions.AsMap(), "velero")
+	})
+}
+
+func TestNewUtilityRegistryConfigFromReader(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		config, err := NewUtilityRegistryConfigFromReader(bytes.NewReader([]byte(`
+utilities:
+- name: cert-manager
+  repository:
+    name: jetstack
+    url: https://charts.jetstack.io
+  chart: jetstack/cert-manager
+  namespace: cert-manager
+  defaultVersion:
+    chart: 1.8.0
+  valuesTemplate: |
+    installCRDs: true
+  readiness:
+    kind: Deployment
+    name: cert-manager
+`)))
+		require.NoError(t, err)
+		require.Len(t, config.Utilities, 1)
+		definition := config.Utilities[0]
+		assert.Equal(t, "cert-manager", definition.Name)
+		assert.Equal(t, "1.8.0", definition.DefaultVersion.Chart)
+		assert.Equal(t, "installCRDs: true\n", definition.ValuesTemplate)
+		assert.Equal(t, UtilityReadinessKindDeployment, definition.Readiness.Kind)
+		assert.NoError(t, definition.Validate())
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := NewUtilityRegistryConfigFromReader(bytes.NewReader([]byte(`{"utilities":[{"name":"a","unknown":true}]}`)))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,328 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// UtilityValuesTemplateSuffix is the file name suffix of utility values files
+// which are rendered as Go templates with the cluster context. Other values
+// files are used as they are, since they may contain Helm or Prometheus
+// template expressions of their own.
+const UtilityValuesTemplateSuffix = ".tmpl"
+
+// The values path of a utility version selects where its values file is
+// fetched from by URL scheme:
+//
+//	nginx_values.yaml, file:///etc/values/nginx_values.yaml
+//	https://example.com/values/nginx_values.yaml
+//	git+https://github.com/org/values.git//dev/nginx_values.yaml?ref=main
+//	git+ssh://git@github.com/org/values.git//dev/nginx_values.yaml?ref=main
+//	s3://bucket/dev/nginx_values.yaml?region=us-east-1
+//
+// Any of them may carry the expected checksum of the values file, for
+// example "?checksum=sha256:<hex>".
+const (
+	// UtilityValuesSchemeFile is the scheme of local values files.
+	UtilityValuesSchemeFile = "file"
+	// UtilityValuesSchemeHTTP is the scheme of values files served over HTTP.
+	UtilityValuesSchemeHTTP = "http"
+	// UtilityValuesSchemeHTTPS is the scheme of values files served over
+	// HTTPS, including the Gitlab API.
+	UtilityValuesSchemeHTTPS = "https"
+	// UtilityValuesSchemeGitHTTPS is the scheme of values files in a Git
+	// repository cloned over HTTPS.
+	UtilityValuesSchemeGitHTTPS = "git+https"
+	// UtilityValuesSchemeGitSSH is the scheme of values files in a Git
+	// repository cloned over SSH.
+	UtilityValuesSchemeGitSSH = "git+ssh"
+	// UtilityValuesSchemeS3 is the scheme of values files stored as S3
+	// objects.
+	UtilityValuesSchemeS3 = "s3"
+
+	// UtilityValuesChecksumParameter is the query parameter of a values path
+	// holding the expected checksum of the values file.
+	UtilityValuesChecksumParameter = "checksum"
+	// UtilityValuesGitPathSeparator separates the repository from the path
+	// of the values file in Git values paths.
+	UtilityValuesGitPathSeparator = "//"
+)
+
+// ValidateUtilityValuesPath validates that the values path has a supported
+// scheme and a valid checksum, if any.
+func ValidateUtilityValuesPath(valuesPath string) error {
+	if len(valuesPath) == 0 {
+		return nil
+	}
+
+	location, checksum, err := SplitUtilityValuesChecksum(valuesPath)
+	if err != nil {
+		return err
+	}
+	if len(checksum) != 0 {
+		_, _, err = parseUtilityValuesChecksum(checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch location.Scheme {
+	case "", UtilityValuesSchemeFile:
+		if len(location.Path) == 0 {
+			return errors.Errorf("values path %s has no file path", valuesPath)
+		}
+	case UtilityValuesSchemeHTTP, UtilityValuesSchemeHTTPS:
+		if len(location.Host) == 0 {
+			return errors.Errorf("values path %s has no host", valuesPath)
+		}
+	case UtilityValuesSchemeGitHTTPS, UtilityValuesSchemeGitSSH:
+		if len(location.Host) == 0 {
+			return errors.Errorf("values path %s has no host", valuesPath)
+		}
+		parts := strings.SplitN(location.Path, UtilityValuesGitPathSeparator, 2)
+		if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+			return errors.Errorf("git values path %s must separate the repository and the file path with %q", valuesPath, UtilityValuesGitPathSeparator)
+		}
+	case UtilityValuesSchemeS3:
+		if len(location.Host) == 0 || len(strings.TrimPrefix(location.Path, "/")) == 0 {
+			return errors.Errorf("S3 values path %s must have a bucket and a key", valuesPath)
+		}
+	default:
+		return errors.Errorf("unsupported values path scheme %q", location.Scheme)
+	}
+
+	return nil
+}
+
+// utilityValuesDirectory is the local directory from which values files of
+// utility versions provided in API requests may be read.
+var utilityValuesDirectory string
+
+// SetUtilityValuesDirectory is used to define the local directory from which
+// values files of utility versions provided in API requests may be read.
+func SetUtilityValuesDirectory(val string) {
+	utilityValuesDirectory = val
+}
+
+// GetUtilityValuesDirectory returns the value of utilityValuesDirectory.
+func GetUtilityValuesDirectory() string {
+	return utilityValuesDirectory
+}
+
+// ValidateUtilityValuesRequestPath validates a values path provided in an
+// API request. Local values files are rejected unless they are under the
+// configured utility values directory, as they are read from the filesystem
+// of the provisioning server.
+func ValidateUtilityValuesRequestPath(valuesPath string) error {
+	err := ValidateUtilityValuesPath(valuesPath)
+	if err != nil {
+		return err
+	}
+	if len(valuesPath) != 0 && !IsUtilityValuesPathAllowed(valuesPath) {
+		return errors.Errorf("local values path %s is not under the utility values directory", valuesPath)
+	}
+
+	return nil
+}
+
+// IsUtilityValuesPathAllowed returns true if the values file may be read on
+// behalf of an API caller, which is the case for remote values files and for
+// local values files under the configured utility values directory.
+func IsUtilityValuesPathAllowed(valuesPath string) bool {
+	location, err := url.Parse(valuesPath)
+	if err != nil {
+		return false
+	}
+	if location.Scheme != "" && location.Scheme != UtilityValuesSchemeFile {
+		return true
+	}
+	if len(utilityValuesDirectory) == 0 || len(location.Path) == 0 {
+		return false
+	}
+
+	directory, err := filepath.Abs(utilityValuesDirectory)
+	if err != nil {
+		return false
+	}
+	path, err := filepath.Abs(location.Path)
+	if err != nil {
+		return false
+	}
+	relative, err := filepath.Rel(directory, path)
+	if err != nil {
+		return false
+	}
+
+	return relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator))
+}
+
+// SplitUtilityValuesChecksum parses the values path, returning it without
+// its checksum parameter along with the checksum.
+func SplitUtilityValuesChecksum(valuesPath string) (*url.URL, string, error) {
+	location, err := url.Parse(valuesPath)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to parse values path")
+	}
+
+	query := location.Query()
+	checksum := query.Get(UtilityValuesChecksumParameter)
+	if len(checksum) != 0 {
+		query.Del(UtilityValuesChecksumParameter)
+		location.RawQuery = query.Encode()
+	}
+
+	return location, checksum, nil
+}
+
+// VerifyUtilityValuesChecksum verifies that the values file data matches the
+// checksum, formatted as "<algorithm>:<hex digest>".
+func VerifyUtilityValuesChecksum(checksum string, data []byte) error {
+	hasher, expected, err := parseUtilityValuesChecksum(checksum)
+	if err != nil {
+		return err
+	}
+
+	hasher.Write(data)
+	actual := hasher.Sum(nil)
+	if !bytes.Equal(actual, expected) {
+		return errors.Errorf("values file checksum %s does not match expected checksum %s", hex.EncodeToString(actual), hex.EncodeToString(expected))
+	}
+
+	return nil
+}
+
+func parseUtilityValuesChecksum(checksum string) (hash.Hash, []byte, error) {
+	parts := strings.SplitN(checksum, ":", 2)
+	if len(parts) != 2 {
+		return nil, nil, errors.Errorf("checksum %q must be formatted as <algorithm>:<hex digest>", checksum)
+	}
+
+	var hasher hash.Hash
+	switch parts[0] {
+	case "sha256":
+		hasher = sha256.New()
+	case "sha512":
+		hasher = sha512.New()
+	default:
+		return nil, nil, errors.Errorf("unsupported checksum algorithm %q", parts[0])
+	}
+
+	expected, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode checksum")
+	}
+	if len(expected) != hasher.Size() {
+		return nil, nil, errors.Errorf("%s checksum must be %d bytes long", parts[0], hasher.Size())
+	}
+
+	return hasher, expected, nil
+}
+
+// UtilityValuesContext is the cluster context utility values templates are
+// rendered with, for example: {{ .ClusterID }} or {{ .VPCCIDR }}.
+type UtilityValuesContext struct {
+	ClusterID             string
+	VPCCIDR               string
+	PrivateDomain         string
+	Environment           string
+	CertificateARN        string
+	PrivateCertificateARN string
+}
+
+// ClusterUtilityValues are the rendered Helm values of a cluster utility.
+type ClusterUtilityValues struct {
+	ClusterID  string
+	Utility    string
+	ValuesPath string
+	Templated  bool
+	// Values are left empty for local values files outside of the utility
+	// values directory, so that the contents of other files on the
+	// provisioning server are never returned.
+	Values         string
+	ValuesOverride string `json:"ValuesOverride,omitempty"`
+	Context        *UtilityValuesContext
+}
+
+// ClusterUtilityValuesFromReader decodes a json-encoded ClusterUtilityValues
+// from the given io.Reader.
+func ClusterUtilityValuesFromReader(reader io.Reader) (*ClusterUtilityValues, error) {
+	values := ClusterUtilityValues{}
+	decoder := json.NewDecoder(reader)
+	err := decoder.Decode(&values)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	return &values, nil
+}
+
+// sampleUtilityValuesContext is used to validate values templates before
+// they are rendered for a cluster.
+var sampleUtilityValuesContext = &UtilityValuesContext{
+	ClusterID:             "sample-cluster-id",
+	VPCCIDR:               "10.0.0.0/16",
+	PrivateDomain:         "internal.example.com",
+	Environment:           "dev",
+	CertificateARN:        "arn:aws:acm:us-east-1:000000000000:certificate/sample",
+	PrivateCertificateARN: "arn:aws:acm:us-east-1:000000000000:certificate/private-sample",
+}
+
+// IsUtilityValuesTemplate returns true if the values file at the given local
+// path or URL is a values template.
+func IsUtilityValuesTemplate(valuesPath string) bool {
+	filePath := valuesPath
+	if u, err := url.Parse(valuesPath); err == nil && len(u.Scheme) != 0 {
+		filePath = u.Path
+	}
+
+	// Gitlab API URLs end with the raw suffix after the escaped file path.
+	for _, element := range strings.Split(filePath, "/") {
+		if strings.HasSuffix(element, UtilityValuesTemplateSuffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasUtilityValuesTemplateActions returns true if the values contain template
+// actions which need the cluster context to be rendered.
+func HasUtilityValuesTemplateActions(values string) bool {
+	return strings.Contains(values, "{{")
+}
+
+// RenderUtilityValues renders the given values template with the cluster
+// context. Referencing an unknown context field is an error.
+func RenderUtilityValues(values string, context *UtilityValuesContext) (string, error) {
+	if context == nil {
+		return "", errors.New("no context to render the utility values with")
+	}
+
+	tmpl, err := template.New("values").Option("missingkey=error").Parse(values)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse utility values template")
+	}
+
+	var rendered bytes.Buffer
+	err = tmpl.Execute(&rendered, context)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to render utility values template")
+	}
+
+	return rendered.String(), nil
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2015-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+//
+
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsUtilityValuesTemplate(t *testing.T) {
+	for _, tc := range []struct {
+		valuesPath string
+		expected   bool
+	}{
+		{"", false},
+		{"nginx_values.yaml", false},
+		{"/tmp/nginx_values.yaml.tmpl", true},
+		{"https://example.com/values/nginx_values.yaml", false},
+		{"https://example.com/values/nginx_values.yaml.tmpl", true},
+		{"https://gitlab.example.com/api/v4/projects/1/repository/files/dev%2Fnginx_values.yaml.tmpl/raw?ref=master", true},
+		{"https://gitlab.example.com/api/v4/projects/1/repository/files/dev%2Fnginx_values.yaml/raw?ref=master", false},
+	} {
+		t.Run(tc.valuesPath, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsUtilityValuesTemplate(tc.valuesPath))
+		})
+	}
+}
+
+func TestRenderUtilityValues(t *testing.T) {
+	valuesContext := &UtilityValuesContext{
+		ClusterID:             "cluster1",
+		VPCCIDR:               "10.0.0.0/16",
+		PrivateDomain:         "internal.example.com",
+		Environment:           "test",
+		CertificateARN:        "arn:cert",
+		PrivateCertificateARN: "arn:private-cert",
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		values, err := RenderUtilityValues(
+			"cluster: {{ .ClusterID }}\ncidr: {{ .VPCCIDR }}\nhost: grafana.{{ .PrivateDomain }}\nenv: {{ .Environment }}\ncerts: [{{ .CertificateARN }}, {{ .PrivateCertificateARN }}]\n",
+			valuesContext,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "cluster: cluster1\ncidr: 10.0.0.0/16\nhost: grafana.internal.example.com\nenv: test\ncerts: [arn:cert, arn:private-cert]\n", values)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := RenderUtilityValues("cluster: {{ .Unknown }}\n", valuesContext)
+		assert.Error(t, err)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := RenderUtilityValues("cluster: {{ .ClusterID\n", valuesContext)
+		assert.Error(t, err)
+	})
+
+	t.Run("no context", func(t *testing.T) {
+		_, err := RenderUtilityValues("cluster: {{ .ClusterID }}\n", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("template actions", func(t *testing.T) {
+		assert.True(t, HasUtilityValuesTemplateActions("cluster: {{ .ClusterID }}"))
+		assert.False(t, HasUtilityValuesTemplateActions("cluster: static"))
+	})
+}
+
+func TestValidateUtilityValuesPath(t *testing.T) {
+	validChecksum := "sha256:" + strings.Repeat("ab", 32)
+
+	for _, tc := range []struct {
+		valuesPath  string
+		expectError bool
+	}{
+		{"", false},
+		{"helm-charts/nginx_values.yaml", false},
+		{"file:///etc/values/nginx_values.yaml", false},
+		{"https://gitlab.example.com/api/v4/projects/1/repository/files/nginx_values.yaml/raw?ref=master", false},
+		{"https://example.com/nginx_values.yaml?checksum=" + validChecksum, false},
+		{"git+https://github.com/org/values.git//dev/nginx_values.yaml?ref=main", false},
+		{"git+ssh://git@github.com/org/values.git//nginx_values.yaml", false},
+		{"s3://bucket/dev/nginx_values.yaml?region=us-east-1", false},
+		{"file://", true},
+		{"https:///nginx_values.yaml", true},
+		{"git+https://github.com/org/values.git/nginx_values.yaml", true},
+		{"s3://bucket", true},
+		{"ftp://example.com/nginx_values.yaml", true},
+		{"https://example.com/nginx_values.yaml?checksum=md5:abcd", true},
+		{"https://example.com/nginx_values.yaml?checksum=sha256:abcd", true},
+		{"https://example.com/nginx_values.yaml?checksum=sha256", true},
+	} {
+		t.Run(tc.valuesPath, func(t *testing.T) {
+			err := ValidateUtilityValuesPath(tc.valuesPath)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateUtilityValuesRequestPath(t *testing.T) {
+	for _, tc := range []struct {
+		valuesPath  string
+		expectError bool
+	}{
+		{"", false},
+		{"https://example.com/nginx_values.yaml", false},
+		{"git+https://github.com/org/values.git//dev/nginx_values.yaml?ref=main", false},
+		{"s3://bucket/dev/nginx_values.yaml?region=us-east-1", false},
+		{"helm-charts/nginx_values.yaml", true},
+		{"/proc/self/environ", true},
+		{"file:///root/.aws/credentials", true},
+		{"ftp://example.com/nginx_values.yaml", true},
+		{"helm-charts/../helm-charts/nginx_values.yaml", true},
+	} {
+		t.Run(tc.valuesPath, func(t *testing.T) {
+			err := ValidateUtilityValuesRequestPath(tc.valuesPath)
+			if tc.expectError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestIsUtilityValuesPathAllowed(t *testing.T) {
+	SetUtilityValuesDirectory("helm-charts")
+	defer SetUtilityValuesDirectory("")
+
+	for _, tc := range []struct {
+		valuesPath string
+		expected   bool
+	}{
+		{"https://example.com/nginx_values.yaml", true},
+		{"s3://bucket/dev/nginx_values.yaml?region=us-east-1", true},
+		{"helm-charts/nginx_values.yaml", true},
+		{"./helm-charts/dev/nginx_values.yaml?checksum=sha256:abc", true},
+		{"helm-charts/../go.mod", false},
+		{"helm-charts-other/nginx_values.yaml", false},
+		{"/proc/self/environ", false},
+		{"file:///root/.aws/credentials", false},
+	} {
+		t.Run(tc.valuesPath, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsUtilityValuesPathAllowed(tc.valuesPath))
+		})
+	}
+}
+
+func TestUtilityValuesChecksum(t *testing.T) {
+	data := []byte("replicaCount: 2\n")
+	sum256 := sha256.Sum256(data)
+	sum512 := sha512.Sum512(data)
+
+	location, checksum, err := SplitUtilityValuesChecksum("https://example.com/values.yaml?ref=main&checksum=sha256:" + hex.EncodeToString(sum256[:]))
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/values.yaml?ref=main", location.String())
+	assert.Equal(t, "sha256:"+hex.EncodeToString(sum256[:]), checksum)
+
+	assert.NoError(t, VerifyUtilityValuesChecksum(checksum, data))
+	assert.NoError(t, VerifyUtilityValuesChecksum("sha512:"+hex.EncodeToString(sum512[:]), data))
+	assert.Error(t, VerifyUtilityValuesChecksum(checksum, []byte("replicaCount: 3\n")))
+	assert.Error(t, VerifyUtilityValuesChecksum("sha1:abcd", data))
+}
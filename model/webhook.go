@@ -26,6 +26,8 @@ const (
 	TypeInstallationDBRestoration ResourceType = "installation_db_restoration_operation"
 	// TypeInstallationDBMigration is the string value that represents an installation db migration operation.
 	TypeInstallationDBMigration ResourceType = "installation_db_migration_operation"
+	// TypeClusterMigration is the string value that represents a cluster migration operation.
+	TypeClusterMigration ResourceType = "cluster_migration_operation"
 )
 
 // String converts ResourceType to string.